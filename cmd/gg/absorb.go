@@ -0,0 +1,455 @@
+// Copyright 2021 The gg Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gg-scm.io/pkg/git"
+	"gg-scm.io/tool/internal/flag"
+)
+
+const absorbSynopsis = "fold working copy changes into the commits that introduced the lines"
+
+func absorb(ctx context.Context, cc *cmdContext, args []string) error {
+	f := flag.NewFlagSet(true, "gg absorb [-n] [UPSTREAM]", absorbSynopsis+`
+
+	Each changed region (hunk) of a modified file in the working copy is
+	compared against the commits after the branching point of the given
+	upstream revision (defaults to the current branch's upstream). If
+	every line that the hunk changes or deletes was last modified by the
+	same one of those commits, then the hunk is folded into that commit.
+	The commits are then rewritten with an automatic rebase. If the rebase
+	stops because of a conflict, resolve it and run
+	`+"`gg rebase --continue`"+`.
+
+	Hunks that only add lines, that touch lines from more than one commit,
+	or that touch lines from commits outside the range are left in the
+	working copy. New, deleted, and binary files are never absorbed.
+
+	`+"`-n`"+` shows which commit each hunk would be folded into without
+	changing anything.`)
+	dryRun := f.Bool("n", false, "show what would be absorbed without changing anything")
+	f.Alias("n", "dry-run")
+	if err := f.Parse(args); flag.IsHelp(err) {
+		f.Help(cc.stdout)
+		return nil
+	} else if err != nil {
+		return usagef("%v", err)
+	}
+	if f.NArg() > 1 {
+		return usagef("no more than one ancestor should be given")
+	}
+	upstream := f.Arg(0)
+	if strings.HasPrefix(upstream, "-") {
+		return errors.New("upstream ref cannot start with a dash")
+	}
+	if upstream == "" {
+		upstream = "@{upstream}"
+	}
+	head, err := cc.git.Head(ctx)
+	if err != nil {
+		return err
+	}
+	base, err := cc.git.MergeBase(ctx, upstream, head.Commit.String())
+	if err != nil {
+		return err
+	}
+	stack, err := absorbStack(ctx, cc.git, base, head.Commit)
+	if err != nil {
+		return err
+	}
+	if len(stack) == 0 {
+		return fmt.Errorf("absorb: no commits after %v", base.Short())
+	}
+	topDir, err := cc.git.WorkTree(ctx)
+	if err != nil {
+		return err
+	}
+	topGit := cc.git.WithDir(topDir)
+	hunks, err := workingCopyHunks(ctx, topGit)
+	if err != nil {
+		return err
+	}
+	if len(hunks) == 0 {
+		return errors.New("absorb: no modified files")
+	}
+
+	// Find the commit that last touched the lines of each hunk.
+	targets := make(map[git.Hash][]*diffHunk)
+	skipped := 0
+	for _, h := range hunks {
+		target, ok, err := absorbTarget(ctx, topGit, h)
+		if err != nil {
+			return err
+		}
+		if _, inStack := stack[target]; !ok || !inStack {
+			skipped++
+			continue
+		}
+		targets[target] = append(targets[target], h)
+	}
+
+	// Report the plan, newest commit first.
+	out := bufio.NewWriter(cc.stdout)
+	order := make([]*absorbCommit, 0, len(targets))
+	for target := range targets {
+		order = append(order, stack[target])
+	}
+	sort.Slice(order, func(i, j int) bool {
+		return order[i].index < order[j].index
+	})
+	for _, c := range order {
+		fmt.Fprintf(out, "%v %s\n", c.hash.Short(), c.summary)
+		counts := make(map[git.TopPath]int)
+		var paths []git.TopPath
+		for _, h := range targets[c.hash] {
+			if counts[h.path] == 0 {
+				paths = append(paths, h.path)
+			}
+			counts[h.path]++
+		}
+		for _, p := range paths {
+			fmt.Fprintf(out, "    %s (%s)\n", p, pluralize(counts[p], "hunk", "hunks"))
+		}
+	}
+	if skipped > 0 {
+		fmt.Fprintf(out, "%s left in the working copy\n", pluralize(skipped, "hunk", "hunks"))
+	}
+	if err := out.Flush(); err != nil {
+		return err
+	}
+	if len(order) == 0 {
+		return errors.New("absorb: no changes can be absorbed")
+	}
+	if *dryRun {
+		return nil
+	}
+
+	// Commit each commit's hunks as a fixup commit on top of HEAD, then let
+	// an autosquash rebase move them into place.
+	tip, err := commitAbsorbFixups(ctx, cc, topGit, head.Commit, order, targets)
+	if err != nil {
+		return err
+	}
+	if err := topGit.Run(ctx, "update-ref", "-m", "gg absorb", "HEAD", tip.String(), head.Commit.String()); err != nil {
+		return err
+	}
+	var absorbedPaths []string
+	seen := make(map[git.TopPath]bool)
+	for _, c := range order {
+		for _, h := range targets[c.hash] {
+			if !seen[h.path] {
+				seen[h.path] = true
+				absorbedPaths = append(absorbedPaths, git.LiteralPath(h.path.String()).String())
+			}
+		}
+	}
+	if err := topGit.Run(ctx, append([]string{"reset", "-q", tip.String(), "--"}, absorbedPaths...)...); err != nil {
+		return err
+	}
+	rebaseArgs := []string{"rebase", "-i", "--autosquash", "--autostash", "--onto=" + base.String(), "--no-fork-point", "--", base.String()}
+	err = cc.git.Runner().RunGit(ctx, &git.Invocation{
+		Dir:    cc.dir,
+		Args:   rebaseArgs,
+		Env:    append(cc.env[:len(cc.env):len(cc.env)], "GIT_SEQUENCE_EDITOR=:"),
+		Stdin:  cc.stdin,
+		Stdout: cc.stdout,
+		Stderr: cc.stderr,
+	})
+	if err == nil {
+		return nil
+	}
+
+	// Put HEAD back where it was. The changes never left the working copy.
+	rebaseErr := err
+	if op, err := readWorkTreeOperation(ctx, cc.git); err != nil {
+		return fmt.Errorf("absorb: git rebase: %w (could not check for rebase in progress: %v)", rebaseErr, err)
+	} else if op == rebaseOperation {
+		if err := cc.git.Run(ctx, "rebase", "--abort"); err != nil {
+			return fmt.Errorf("absorb: git rebase: %w (could not abort rebase: %v)", rebaseErr, err)
+		}
+	}
+	if err := topGit.Run(ctx, "update-ref", "-m", "gg absorb", "HEAD", head.Commit.String()); err != nil {
+		return fmt.Errorf("absorb: git rebase: %w (could not reset to %v: %v)", rebaseErr, head.Commit, err)
+	}
+	if err := topGit.Run(ctx, append([]string{"reset", "-q", head.Commit.String(), "--"}, absorbedPaths...)...); err != nil {
+		return fmt.Errorf("absorb: git rebase: %w (could not reset to %v: %v)", rebaseErr, head.Commit, err)
+	}
+	return fmt.Errorf("absorb: git rebase: %w; nothing absorbed", rebaseErr)
+}
+
+// absorbCommit is a commit that changes can be absorbed into.
+type absorbCommit struct {
+	hash    git.Hash
+	summary string
+	index   int // position in the stack, with 0 being the newest commit
+}
+
+// absorbStack returns the commits reachable from head but not from base.
+func absorbStack(ctx context.Context, g *git.Git, base, head git.Hash) (map[git.Hash]*absorbCommit, error) {
+	commits, err := g.Log(ctx, git.LogOptions{
+		Revs: []string{base.String() + ".." + head.String()},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("absorb: %w", err)
+	}
+	stack := make(map[git.Hash]*absorbCommit)
+	for commits.Next() {
+		c := commits.CommitInfo()
+		if len(c.Parents) > 1 {
+			commits.Close()
+			return nil, fmt.Errorf("absorb: %v is a merge commit", c.SHA1().Short())
+		}
+		stack[c.SHA1()] = &absorbCommit{
+			hash:    c.SHA1(),
+			summary: c.Summary(),
+			index:   len(stack),
+		}
+	}
+	if err := commits.Close(); err != nil {
+		return nil, fmt.Errorf("absorb: %w", err)
+	}
+	return stack, nil
+}
+
+// A diffHunk is a hunk from a diff without context lines.
+type diffHunk struct {
+	path     git.TopPath
+	oldStart int
+	oldCount int
+	newCount int
+	// lines holds the hunk's removed and added lines, each with its
+	// leading '-' or '+' and trailing newline, and any
+	// "\ No newline at end of file" markers.
+	lines []string
+}
+
+// workingCopyHunks returns the hunks of the modified files in the
+// working copy, compared to HEAD. g must be run from the top of the
+// working copy.
+func workingCopyHunks(ctx context.Context, g *git.Git) ([]*diffHunk, error) {
+	status, err := g.DiffStatus(ctx, git.DiffStatusOptions{
+		Commit1:        git.Head.String(),
+		DisableRenames: true,
+	})
+	if err != nil {
+		return nil, err
+	}
+	var hunks []*diffHunk
+	for _, ent := range status {
+		if ent.Code != git.DiffStatusModified {
+			continue
+		}
+		out, err := g.Output(ctx, "diff", "-U0", "--no-color", "--no-ext-diff", "--no-textconv",
+			git.Head.String(), "--", git.LiteralPath(ent.Name.String()).String())
+		if err != nil {
+			return nil, err
+		}
+		fileHunks, err := parseZeroContextDiff(ent.Name, out)
+		if err != nil {
+			return nil, fmt.Errorf("absorb: %s: %w", ent.Name, err)
+		}
+		hunks = append(hunks, fileHunks...)
+	}
+	return hunks, nil
+}
+
+var hunkHeaderRegexp = regexp.MustCompile(`^@@ -([0-9]+)(?:,([0-9]+))? \+([0-9]+)(?:,([0-9]+))? @@`)
+
+// parseZeroContextDiff parses the hunks of a single file's diff generated
+// with -U0.
+func parseZeroContextDiff(path git.TopPath, diff string) ([]*diffHunk, error) {
+	var hunks []*diffHunk
+	var curr *diffHunk
+	for len(diff) > 0 {
+		var line string
+		if i := strings.IndexByte(diff, '\n'); i != -1 {
+			line, diff = diff[:i+1], diff[i+1:]
+		} else {
+			line, diff = diff+"\n", ""
+		}
+		if strings.HasPrefix(line, "@@ ") {
+			m := hunkHeaderRegexp.FindStringSubmatch(line)
+			if m == nil {
+				return nil, fmt.Errorf("malformed hunk header %q", strings.TrimSuffix(line, "\n"))
+			}
+			curr = &diffHunk{
+				path:     path,
+				oldStart: atoiDefault(m[1], 0),
+				oldCount: atoiDefault(m[2], 1),
+				newCount: atoiDefault(m[4], 1),
+			}
+			hunks = append(hunks, curr)
+			continue
+		}
+		if curr == nil {
+			// File header.
+			continue
+		}
+		switch line[0] {
+		case '-', '+', '\\':
+			curr.lines = append(curr.lines, line)
+		default:
+			return nil, fmt.Errorf("unexpected line %q in hunk", strings.TrimSuffix(line, "\n"))
+		}
+	}
+	return hunks, nil
+}
+
+// atoiDefault parses s as a decimal integer, returning def if s is empty.
+func atoiDefault(s string, def int) int {
+	if s == "" {
+		return def
+	}
+	n, err := strconv.Atoi(s)
+	if err != nil {
+		return def
+	}
+	return n
+}
+
+// absorbTarget returns the commit that last changed all the lines that
+// h changes or deletes. ok is false if h only adds lines or if the lines
+// were last changed by different commits.
+func absorbTarget(ctx context.Context, g *git.Git, h *diffHunk) (_ git.Hash, ok bool, _ error) {
+	if h.oldCount == 0 {
+		return git.Hash{}, false, nil
+	}
+	lineRange := fmt.Sprintf("%d,%d", h.oldStart, h.oldStart+h.oldCount-1)
+	out, err := g.Output(ctx, "blame", "--line-porcelain", "-L", lineRange, git.Head.String(), "--", h.path.String())
+	if err != nil {
+		return git.Hash{}, false, fmt.Errorf("absorb: %w", err)
+	}
+	lines, err := parseBlamePorcelain(out)
+	if err != nil {
+		return git.Hash{}, false, fmt.Errorf("absorb: %w", err)
+	}
+	if len(lines) == 0 {
+		return git.Hash{}, false, nil
+	}
+	target := lines[0].commit
+	for _, line := range lines[1:] {
+		if line.commit != target {
+			return git.Hash{}, false, nil
+		}
+	}
+	return target, true, nil
+}
+
+// commitAbsorbFixups creates a "fixup!" commit for each commit in order
+// containing its hunks, starting from parent. It returns the last commit
+// created. The index and working copy are not changed.
+func commitAbsorbFixups(ctx context.Context, cc *cmdContext, g *git.Git, parent git.Hash, order []*absorbCommit, targets map[git.Hash][]*diffHunk) (git.Hash, error) {
+	idx, err := newTempIndex(cc, g, "absorb")
+	if err != nil {
+		return git.Hash{}, err
+	}
+	defer idx.close()
+	if _, err := idx.run(ctx, nil, "read-tree", parent.String()); err != nil {
+		return git.Hash{}, err
+	}
+	// applied tracks the hunks already applied to each file so that later
+	// hunks can be shifted by the number of lines they added or removed.
+	applied := make(map[git.TopPath][]*diffHunk)
+	for i := len(order) - 1; i >= 0; i-- {
+		c := order[i]
+		patch := new(strings.Builder)
+		var lastPath git.TopPath
+		for _, h := range targets[c.hash] {
+			if h.path != lastPath {
+				a := quotePatchPath("a/" + h.path.String())
+				b := quotePatchPath("b/" + h.path.String())
+				fmt.Fprintf(patch, "diff --git %s %s\n--- %s\n+++ %s\n", a, b, a, b)
+				lastPath = h.path
+			}
+			start := h.oldStart
+			for _, prev := range applied[h.path] {
+				if prev.oldStart < h.oldStart {
+					start += prev.newCount - prev.oldCount
+				}
+			}
+			newStart := start
+			if h.newCount == 0 {
+				newStart--
+			}
+			fmt.Fprintf(patch, "@@ -%d,%d +%d,%d @@\n", start, h.oldCount, newStart, h.newCount)
+			for _, line := range h.lines {
+				patch.WriteString(line)
+			}
+		}
+		if _, err := idx.run(ctx, strings.NewReader(patch.String()), "apply", "--cached", "--unidiff-zero", "-"); err != nil {
+			return git.Hash{}, err
+		}
+		// Line numbers within a patch refer to the file before the patch,
+		// so only record the hunks once the whole patch is applied.
+		for _, h := range targets[c.hash] {
+			applied[h.path] = append(applied[h.path], h)
+		}
+		tree, err := idx.run(ctx, nil, "write-tree")
+		if err != nil {
+			return git.Hash{}, err
+		}
+		commit, err := idx.run(ctx, nil, "commit-tree", tree, "-p", parent.String(), "-m", "fixup! "+c.hash.String())
+		if err != nil {
+			return git.Hash{}, err
+		}
+		parent, err = git.ParseHash(commit)
+		if err != nil {
+			return git.Hash{}, fmt.Errorf("absorb: %w", err)
+		}
+	}
+	return parent, nil
+}
+
+// quotePatchPath quotes a path for a patch header the way Git does if it
+// contains special characters.
+func quotePatchPath(p string) string {
+	if !strings.ContainsAny(p, "\"\\\t\n") {
+		return p
+	}
+	sb := new(strings.Builder)
+	sb.WriteByte('"')
+	for i := 0; i < len(p); i++ {
+		switch c := p[i]; c {
+		case '"', '\\':
+			sb.WriteByte('\\')
+			sb.WriteByte(c)
+		case '\t':
+			sb.WriteString(`\t`)
+		case '\n':
+			sb.WriteString(`\n`)
+		default:
+			sb.WriteByte(c)
+		}
+	}
+	sb.WriteByte('"')
+	return sb.String()
+}
+
+// pluralize returns n followed by the singular or plural noun.
+func pluralize(n int, singular, plural string) string {
+	if n == 1 {
+		return "1 " + singular
+	}
+	return strconv.Itoa(n) + " " + plural
+}
@@ -0,0 +1,219 @@
+// Copyright 2021 The gg Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"testing"
+
+	"gg-scm.io/pkg/git"
+	"gg-scm.io/tool/internal/filesystem"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestAbsorb(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	env, err := newTestEnv(ctx, t)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := env.initRepoWithHistory(ctx, "."); err != nil {
+		t.Fatal(err)
+	}
+	if err := env.root.Apply(filesystem.Write("foo.txt", "1\n2\n3\n4\n5\n6\n7\n8\n9\n")); err != nil {
+		t.Fatal(err)
+	}
+	if err := env.addFiles(ctx, "foo.txt"); err != nil {
+		t.Fatal(err)
+	}
+	if err := env.git.CommitAll(ctx, "add foo", git.CommitOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	err = env.git.NewBranch(ctx, "topic", git.BranchOptions{
+		StartPoint: "main",
+		Track:      true,
+		Checkout:   true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := env.root.Apply(filesystem.Write("foo.txt", "1\ntwo\n3\n4\n5\n6\n7\n8\n9\n")); err != nil {
+		t.Fatal(err)
+	}
+	if err := env.git.CommitAll(ctx, "spell out two", git.CommitOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := env.root.Apply(filesystem.Write("foo.txt", "1\ntwo\n3\n4\n5\n6\n7\neight\n9\n")); err != nil {
+		t.Fatal(err)
+	}
+	if err := env.git.CommitAll(ctx, "spell out eight", git.CommitOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	head, err := env.git.Head(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Change lines from both commits and a line from the upstream.
+	const workingCopy = "1\nTWO\n3\n4\nFIVE\n6\n7\nEIGHT\nEIGHT AND A HALF\n9\n"
+	if err := env.root.Apply(filesystem.Write("foo.txt", workingCopy)); err != nil {
+		t.Fatal(err)
+	}
+
+	out, err := env.gg(ctx, env.root.String(), "absorb", "-n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"spell out two\n    foo.txt (1 hunk)\n", "spell out eight\n    foo.txt (1 hunk)\n", "1 hunk left in the working copy\n"} {
+		if !bytes.Contains(out, []byte(want)) {
+			t.Errorf("absorb -n output does not contain %q. Output:\n%s", want, out)
+		}
+	}
+	if r, err := env.git.Head(ctx); err != nil {
+		t.Fatal(err)
+	} else if r.Commit != head.Commit {
+		t.Errorf("after absorb -n, HEAD = %v; want %v", r.Commit, head.Commit)
+	}
+
+	if _, err := env.gg(ctx, env.root.String(), "absorb"); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"spell out eight\n", "spell out two\n"}
+	var got []string
+	commits, err := env.git.Log(ctx, git.LogOptions{Revs: []string{"main..HEAD"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for commits.Next() {
+		got = append(got, commits.CommitInfo().Message)
+	}
+	if err := commits.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("commits after absorb (-want +got):\n%s", diff)
+	}
+	if data, err := catBlob(ctx, env.git, "HEAD~", "foo.txt"); err != nil {
+		t.Error(err)
+	} else if want := "1\nTWO\n3\n4\n5\n6\n7\n8\n9\n"; string(data) != want {
+		t.Errorf("foo.txt @ HEAD~ = %q; want %q", data, want)
+	}
+	if data, err := catBlob(ctx, env.git, "HEAD", "foo.txt"); err != nil {
+		t.Error(err)
+	} else if want := "1\nTWO\n3\n4\n5\n6\n7\nEIGHT\nEIGHT AND A HALF\n9\n"; string(data) != want {
+		t.Errorf("foo.txt @ HEAD = %q; want %q", data, want)
+	}
+	if got, err := env.root.ReadFile("foo.txt"); err != nil {
+		t.Error(err)
+	} else if got != workingCopy {
+		t.Errorf("foo.txt in working copy = %q; want %q", got, workingCopy)
+	}
+}
+
+func TestAbsorb_RebaseFails(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	env, err := newTestEnv(ctx, t)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := env.initRepoWithHistory(ctx, "."); err != nil {
+		t.Fatal(err)
+	}
+	err = env.git.NewBranch(ctx, "topic", git.BranchOptions{
+		StartPoint: "main",
+		Track:      true,
+		Checkout:   true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := env.root.Apply(filesystem.Write("foo.txt", "1\n2\n3\n")); err != nil {
+		t.Fatal(err)
+	}
+	if err := env.addFiles(ctx, "foo.txt"); err != nil {
+		t.Fatal(err)
+	}
+	if err := env.git.CommitAll(ctx, "add foo", git.CommitOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	head, err := env.git.Head(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	const workingCopy = "1\nTWO\n3\n"
+	if err := env.root.Apply(filesystem.Write("foo.txt", workingCopy)); err != nil {
+		t.Fatal(err)
+	}
+	// Make every rebase fail before it starts.
+	hookPath := env.root.FromSlash(".git/hooks/pre-rebase")
+	if err := env.root.Apply(filesystem.Write(".git/hooks/pre-rebase", "#!/bin/sh\nexit 1\n")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chmod(hookPath, 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := env.gg(ctx, env.root.String(), "absorb"); err == nil {
+		t.Fatal("absorb succeeded even though rebase failed")
+	}
+	if got, err := env.git.Head(ctx); err != nil {
+		t.Error(err)
+	} else if got.Commit != head.Commit || got.Ref != head.Ref {
+		t.Errorf("after failed absorb, HEAD = %v (%v); want %v (%v)", got.Commit, got.Ref, head.Commit, head.Ref)
+	}
+	if got, err := env.root.ReadFile("foo.txt"); err != nil {
+		t.Error(err)
+	} else if got != workingCopy {
+		t.Errorf("foo.txt in working copy = %q; want %q", got, workingCopy)
+	}
+	if diff, err := env.git.Output(ctx, "diff", "--cached", "--name-only"); err != nil {
+		t.Error(err)
+	} else if diff != "" {
+		t.Errorf("after failed absorb, staged changes = %q; want none", diff)
+	}
+}
+
+func TestParseZeroContextDiff(t *testing.T) {
+	const diff = "diff --git a/foo.txt b/foo.txt\n" +
+		"index e931c27..a880648 100644\n" +
+		"--- a/foo.txt\n" +
+		"+++ b/foo.txt\n" +
+		"@@ -2 +2 @@\n" +
+		"-two\n" +
+		"+TWO\n" +
+		"@@ -5,2 +4,0 @@ two\n" +
+		"-5\n" +
+		"-6\n" +
+		"@@ -9,0 +8,2 @@\n" +
+		"+10\n" +
+		"+11\n" +
+		"\\ No newline at end of file\n"
+	got, err := parseZeroContextDiff("foo.txt", diff)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []*diffHunk{
+		{path: "foo.txt", oldStart: 2, oldCount: 1, newCount: 1, lines: []string{"-two\n", "+TWO\n"}},
+		{path: "foo.txt", oldStart: 5, oldCount: 2, newCount: 0, lines: []string{"-5\n", "-6\n"}},
+		{path: "foo.txt", oldStart: 9, oldCount: 0, newCount: 2, lines: []string{"+10\n", "+11\n", "\\ No newline at end of file\n"}},
+	}
+	if diff := cmp.Diff(want, got, cmp.AllowUnexported(diffHunk{})); diff != "" {
+		t.Errorf("parseZeroContextDiff(...) (-want +got):\n%s", diff)
+	}
+}
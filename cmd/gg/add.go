@@ -16,6 +16,8 @@ package main
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"os"
 	"path/filepath"
 
@@ -26,37 +28,59 @@ import (
 const addSynopsis = "add the specified files on the next commit"
 
 func add(ctx context.Context, cc *cmdContext, args []string) error {
-	f := flag.NewFlagSet(true, "gg add FILE [...]", addSynopsis+`
+	f := flag.NewFlagSet(true, "gg add FILE [...]\n"+
+		"gg add -i [FILE [...]]", addSynopsis+`
 
 	Mark files to be tracked under version control and added at the next
 	commit. If `+"`add`"+` is run on a file X and X is ignored, it will be
 	tracked. However, adding a directory with ignored files will not track
 	the ignored files.
 
-	`+"`add`"+` also marks merge conflicts as resolved like `+"`git add`.")
+	`+"`add`"+` also marks merge conflicts as resolved like `+"`git add`"+`.
+
+	With `+"`-i`"+`, add opens an editor listing the untracked, modified,
+	and unmerged files (optionally limited to the given files). Change
+	the word at the start of each line to pick what happens to the file:
+	`+"`add`"+` tracks an untracked file or stages a tracked file,
+	`+"`patch`"+` lets you choose which hunks to stage with
+	`+"`git add -p`"+`, and `+"`skip`"+` leaves the file alone.
+
+	`+filePatternHelp)
+	interactive := f.Bool("i", false, "choose files to add interactively")
+	f.Alias("i", "interactive")
 	if err := f.Parse(args); flag.IsHelp(err) {
 		f.Help(cc.stdout)
 		return nil
 	} else if err != nil {
 		return usagef("%v", err)
 	}
-	if f.NArg() == 0 {
+	fileArgs, err := expandFilePatterns(ctx, cc, f.Args())
+	if err != nil {
+		return err
+	}
+	if *interactive {
+		return addInteractive(ctx, cc, fileArgs.pathspecs())
+	}
+	if fileArgs.isEmpty() {
 		return usagef("must pass one or more files to add")
 	}
 
-	// Group arguments into files and directories.
-	files := make([]string, 0, f.NArg())
-	dirs := make([]string, 0, f.NArg())
-	for _, a := range args {
+	// Group arguments into files and directories. Like directories,
+	// glob patterns should not include ignored files.
+	paths := fileArgs.files()
+	files := make([]git.Pathspec, 0, len(paths))
+	dirs := make([]git.Pathspec, 0, len(paths)+len(fileArgs.globs))
+	for _, a := range paths {
 		if !filepath.IsAbs(a) {
 			a = filepath.Join(cc.dir, a)
 		}
 		if isdir(a) {
-			dirs = append(dirs, a)
+			dirs = append(dirs, git.LiteralPath(a))
 		} else {
-			files = append(files, a)
+			files = append(files, git.LiteralPath(a))
 		}
 	}
+	dirs = append(dirs, fileArgs.globs...)
 	// Files can be explicit adds of ignored files.
 	untrackedFiles, unmerged1, err := findAddFiles(ctx, cc.git, files, true)
 	if err != nil {
@@ -67,6 +91,12 @@ func add(ctx context.Context, cc *cmdContext, args []string) error {
 	if err != nil {
 		return err
 	}
+	untracked := make([]git.TopPath, 0, len(untrackedFiles)+len(untrackedDirs))
+	untracked = append(untracked, untrackedFiles...)
+	untracked = append(untracked, untrackedDirs...)
+	if err := warnLargeFiles(ctx, cc, untracked); err != nil {
+		fmt.Fprintln(cc.stderr, "gg:", err)
+	}
 	// Untracked files coming from file arguments should be marked with
 	// intent to add.
 	if len(untrackedFiles) > 0 {
@@ -113,6 +143,78 @@ func add(ctx context.Context, cc *cmdContext, args []string) error {
 	return nil
 }
 
+// addActions are the choices presented by `gg add -i`.
+var addActions = []selectAction{
+	{"skip", "leave the file alone"},
+	{"add", "track the file or stage its changes"},
+	{"patch", "choose hunks to stage with git add -p"},
+}
+
+// addInteractive implements `gg add -i`.
+func addInteractive(ctx context.Context, cc *cmdContext, pathspecs []git.Pathspec) error {
+	st, err := cc.git.Status(ctx, git.StatusOptions{
+		Pathspecs:      pathspecs,
+		DisableRenames: true,
+	})
+	if err != nil {
+		return err
+	}
+	var items []selectItem
+	for _, ent := range st {
+		switch {
+		case ent.Code.IsUntracked():
+			items = append(items, selectItem{name: ent.Name, code: '?'})
+		case ent.Code.IsUnmerged():
+			items = append(items, selectItem{name: ent.Name, code: 'U'})
+		case ent.Code.IsModified():
+			items = append(items, selectItem{name: ent.Name, code: 'M'})
+		}
+	}
+	if len(items) == 0 {
+		return errors.New("no untracked or modified files")
+	}
+	items, err = selectFiles(ctx, cc, "ADD_FILES", addActions, items)
+	if err != nil {
+		return err
+	}
+
+	var intentToAdd, stage []git.Pathspec
+	var patch []string
+	for _, item := range items {
+		switch item.action {
+		case "add":
+			if item.code == '?' {
+				intentToAdd = append(intentToAdd, item.name.Pathspec())
+			} else {
+				stage = append(stage, item.name.Pathspec())
+			}
+		case "patch":
+			if item.code == '?' {
+				// git add -p only considers tracked files.
+				intentToAdd = append(intentToAdd, item.name.Pathspec())
+			}
+			patch = append(patch, item.name.Pathspec().String())
+		}
+	}
+	if len(intentToAdd) > 0 {
+		if err := cc.git.Add(ctx, intentToAdd, git.AddOptions{IntentToAdd: true}); err != nil {
+			return err
+		}
+	}
+	if len(stage) > 0 {
+		if err := cc.git.Add(ctx, stage, git.AddOptions{}); err != nil {
+			return err
+		}
+	}
+	if len(patch) > 0 {
+		patchArgs := append([]string{"add", "--patch", "--"}, patch...)
+		if err := cc.interactiveGit(ctx, patchArgs...); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func isdir(name string) bool {
 	info, err := os.Stat(name)
 	return err == nil && info.IsDir()
@@ -120,16 +222,12 @@ func isdir(name string) bool {
 
 // findAddFiles finds the files described by the arguments and groups
 // them based on how they should be handled by add.
-func findAddFiles(ctx context.Context, g *git.Git, args []string, includeIgnored bool) (untracked, unmerged []git.TopPath, _ error) {
-	if len(args) == 0 {
+func findAddFiles(ctx context.Context, g *git.Git, pathspecs []git.Pathspec, includeIgnored bool) (untracked, unmerged []git.TopPath, _ error) {
+	if len(pathspecs) == 0 {
 		return nil, nil, nil
 	}
-	statusArgs := make([]git.Pathspec, len(args))
-	for i := range args {
-		statusArgs[i] = git.LiteralPath(args[i])
-	}
 	st, err := g.Status(ctx, git.StatusOptions{
-		Pathspecs:      statusArgs,
+		Pathspecs:      pathspecs,
 		IncludeIgnored: includeIgnored,
 	})
 	if err != nil {
@@ -16,9 +16,11 @@ package main
 
 import (
 	"context"
+	"fmt"
 	"testing"
 
 	"gg-scm.io/pkg/git"
+	"gg-scm.io/tool/internal/escape"
 	"gg-scm.io/tool/internal/filesystem"
 	"github.com/google/go-cmp/cmp"
 )
@@ -49,6 +51,47 @@ func TestAdd(t *testing.T) {
 	}
 }
 
+func TestAdd_Interactive(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	env, err := newTestEnv(ctx, t)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := env.initEmptyRepo(ctx, "."); err != nil {
+		t.Fatal(err)
+	}
+	err = env.root.Apply(
+		filesystem.Write("bar.txt", dummyContent),
+		filesystem.Write("foo.txt", dummyContent),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cmd, err := env.editorCmd([]byte("skip ? bar.txt\na ? foo.txt\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := env.writeConfig([]byte(fmt.Sprintf("[core]\neditor = %s\n", escape.GitConfig(cmd)))); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := env.gg(ctx, env.root.String(), "add", "-i"); err != nil {
+		t.Error("gg:", err)
+	}
+	st, err := env.git.Status(ctx, git.StatusOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []git.StatusEntry{
+		{Code: git.StatusCode{' ', 'A'}, Name: "foo.txt"},
+		{Code: git.StatusCode{'?', '?'}, Name: "bar.txt"},
+	}
+	if diff := cmp.Diff(want, st); diff != "" {
+		t.Errorf("status (-want +got):\n%s", diff)
+	}
+}
+
 func TestAdd_DoesNotStageModified(t *testing.T) {
 	t.Parallel()
 	ctx := context.Background()
@@ -0,0 +1,253 @@
+// Copyright 2021 The gg Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"gg-scm.io/pkg/git"
+	"gg-scm.io/pkg/git/object"
+	"gg-scm.io/tool/internal/flag"
+)
+
+const amendSynopsis = "fold changes into the working directory's parent or an earlier commit"
+
+func amend(ctx context.Context, cc *cmdContext, args []string) error {
+	f := flag.NewFlagSet(true, "gg amend [-m MSG | -e | --no-edit] [--author=PERSON] [--date=DATE] [--coauthor=PERSON [...]] [-S] [FILE [...]]\n"+
+		"gg amend --to=REV [-S] [FILE [...]]", amendSynopsis+`
+
+	Without `+"`--to`"+`, this is the same as `+"`gg commit --amend`"+`: the
+	changes to the given files (or all outstanding changes) are folded
+	into the working directory's parent and the editor is opened to
+	change the commit message. `+"`--no-edit`"+` keeps the message as-is.
+
+	`+"`--author`"+` and `+"`--date`"+` change the commit's author and author
+	date. The author is either of the form "Name <email>" or an alias or
+	email address from the co-author roster described in
+	`+"`gg help commit`"+`. The date is either `+"`now`"+` or a date like
+	`+"`2006-01-02`"+`, `+"`2006-01-02 15:04:05`"+`, or
+	`+"`2006-01-02T15:04:05-07:00`"+`, in local time unless a zone is given.
+	If `+"`-e`"+`, `+"`--no-edit`"+`, `+"`--author`"+`, or `+"`--date`"+` is
+	passed without any files, only the commit's metadata is changed:
+	outstanding changes in the working copy are left alone.
+
+	With `+"`--to`"+`, the changes to the given files (or all outstanding
+	changes) are folded into the given commit, which must be an ancestor
+	of the working directory's parent. The commits after it are then
+	rebased on top of the amended commit. The commit message of the
+	amended commit is kept as-is. If rebasing the descendants results in
+	conflicts, nothing is changed and the changes are left in the working
+	copy.
+
+	`+filePatternHelp+`
+
+	`+"`-S`"+` signs the amended commit (and with `+"`--to`"+`, the rebased
+	commits), as if the `+"`commit.gpgSign`"+` setting were true.`)
+	msg := f.String("m", "", "use text as commit `message`")
+	edit := f.Bool("e", false, "edit the commit message without changing files")
+	f.Alias("e", "edit")
+	noEdit := f.Bool("no-edit", false, "keep the commit message")
+	author := f.String("author", "", "set the commit's `person`")
+	date := f.String("date", "", "set the commit's author `date`")
+	coauthorArgs := f.MultiString("coauthor", "credit `person` as a co-author")
+	to := f.String("to", "", "`rev`ision to fold changes into")
+	sign := f.Bool("S", false, "sign the commit")
+	f.Alias("S", "sign")
+	if err := f.Parse(args); flag.IsHelp(err) {
+		f.Help(cc.stdout)
+		return nil
+	} else if err != nil {
+		return usagef("%v", err)
+	}
+	cc, err := withSigning(cc, *sign)
+	if err != nil {
+		return err
+	}
+	fileArgs, err := expandFilePatterns(ctx, cc, f.Args())
+	if err != nil {
+		return err
+	}
+	pathspecs := fileArgs.pathspecs()
+	if *to == "" {
+		if *msg != "" && *edit {
+			return usagef("can't pass both -m and -e")
+		}
+		if *noEdit && (*msg != "" || *edit) {
+			return usagef("can't pass --no-edit with -m or -e")
+		}
+		opts := amendOptions{
+			keepMessage:  *noEdit || (*msg == "" && !*edit && (*author != "" || *date != "")),
+			metadataOnly: len(pathspecs) == 0 && (*edit || *noEdit || *author != "" || *date != ""),
+		}
+		if *author != "" {
+			idents, err := resolveCoauthors(ctx, cc, []string{*author})
+			if err != nil {
+				return err
+			}
+			opts.author = object.User(idents[0])
+		}
+		if *date != "" {
+			t, err := parseDate(*date, time.Now())
+			if err != nil {
+				return usagef("--date: %v", err)
+			}
+			opts.authorTime = t
+		}
+		coauthors, err := resolveCoauthors(ctx, cc, *coauthorArgs)
+		if err != nil {
+			return err
+		}
+		return doAmend(ctx, cc, *msg, pathspecs, coauthors, opts)
+	}
+	if *msg != "" {
+		return usagef("can't pass -m with --to")
+	}
+	if len(*coauthorArgs) > 0 {
+		return usagef("can't pass --coauthor with --to")
+	}
+	if *edit || *noEdit || *author != "" || *date != "" {
+		return usagef("can't pass -e, --no-edit, --author, or --date with --to")
+	}
+	return amendTo(ctx, cc, *to, pathspecs)
+}
+
+// parseDate parses a date given on the command line: either "now" or one
+// of a few common layouts. Dates without a zone are in local time.
+func parseDate(s string, now time.Time) (time.Time, error) {
+	if s == "now" {
+		return now, nil
+	}
+	layouts := []string{
+		time.RFC3339,
+		"2006-01-02 15:04:05 -0700",
+		"2006-01-02T15:04:05",
+		"2006-01-02 15:04:05",
+		"2006-01-02 15:04",
+		"2006-01-02",
+	}
+	for _, layout := range layouts {
+		if t, err := time.ParseInLocation(layout, s, time.Local); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("unrecognized date %q", s)
+}
+
+// amendTo folds the working copy changes matching the given pathspecs
+// into the given ancestor of HEAD and rebases the intervening commits.
+func amendTo(ctx context.Context, cc *cmdContext, rev string, pathspecs []git.Pathspec) error {
+	if strings.HasPrefix(rev, "-") {
+		return errors.New("revision cannot start with a dash")
+	}
+	target, err := cc.git.ParseRev(ctx, rev)
+	if err != nil {
+		return err
+	}
+	head, err := cc.git.Head(ctx)
+	if err != nil {
+		return err
+	}
+	if target.Commit == head.Commit {
+		return doAmend(ctx, cc, "", pathspecs, nil, amendOptions{keepMessage: true})
+	}
+	if isAncestor, err := cc.git.IsAncestor(ctx, target.Commit.String(), head.Commit.String()); err != nil {
+		return err
+	} else if !isAncestor {
+		return fmt.Errorf("%s is not an ancestor of the working directory", rev)
+	}
+	if err := checkNotInUpstream(ctx, cc, target.Commit, rev); err != nil {
+		return err
+	}
+	targetInfo, err := cc.git.CommitInfo(ctx, target.Commit.String())
+	if err != nil {
+		return err
+	}
+	if len(targetInfo.Parents) > 1 {
+		return errors.New("cannot amend a merge, use `git commit --amend`")
+	}
+	merges, err := cc.git.Output(ctx, "rev-list", "--merges", target.Commit.String()+".."+head.Commit.String())
+	if err != nil {
+		return err
+	}
+	if strings.TrimSpace(merges) != "" {
+		return fmt.Errorf("cannot amend %s: merges exist between it and the working directory", rev)
+	}
+
+	status, err := cc.git.Status(ctx, git.StatusOptions{
+		Pathspecs: pathspecs,
+	})
+	if err != nil {
+		return err
+	}
+	hasChanges, err := verifyNoMissingOrUnmerged(status)
+	if err != nil {
+		return err
+	}
+	if !hasChanges {
+		return errors.New("nothing changed")
+	}
+
+	// Save the index so that it can be put back if the rebase fails.
+	index, err := cc.git.Output(ctx, "write-tree")
+	if err != nil {
+		return err
+	}
+	index = strings.TrimSuffix(index, "\n")
+
+	// Commit the changes as a fixup commit, then let an autosquash rebase
+	// fold it into the target and restack the descendants.
+	fixupMsg := "fixup! " + target.Commit.String() + "\n"
+	if len(pathspecs) > 0 {
+		err = cc.git.CommitFiles(ctx, fixupMsg, pathspecs, git.CommitOptions{})
+	} else {
+		err = cc.git.CommitAll(ctx, fixupMsg, git.CommitOptions{})
+	}
+	if err != nil {
+		return err
+	}
+	rebaseArgs := []string{
+		"--autostash",
+		"--no-fork-point",
+	}
+	if len(targetInfo.Parents) == 0 {
+		rebaseArgs = append(rebaseArgs, "--root")
+	} else {
+		rebaseArgs = append(rebaseArgs, "--", targetInfo.Parents[0].String())
+	}
+	rebaseErr := runRebase(ctx, cc, true, rebaseArgs...)
+	if rebaseErr == nil {
+		return nil
+	}
+
+	// Undo the fixup commit so that the changes are back in the working copy.
+	if op, err := readWorkTreeOperation(ctx, cc.git); err != nil {
+		return fmt.Errorf("%w (could not check for rebase in progress: %v)", rebaseErr, err)
+	} else if op == rebaseOperation {
+		if err := cc.git.Run(ctx, "rebase", "--abort"); err != nil {
+			return fmt.Errorf("%w (could not abort rebase: %v)", rebaseErr, err)
+		}
+	}
+	if err := cc.git.Run(ctx, "reset", "--quiet", "--soft", head.Commit.String()); err != nil {
+		return fmt.Errorf("%w (could not reset to %v: %v)", rebaseErr, head.Commit, err)
+	}
+	if err := cc.git.Run(ctx, "read-tree", index); err != nil {
+		return fmt.Errorf("%w (could not restore index: %v)", rebaseErr, err)
+	}
+	return fmt.Errorf("amend %s: %w; changes left in working copy", rev, rebaseErr)
+}
@@ -0,0 +1,329 @@
+// Copyright 2021 The gg Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"gg-scm.io/pkg/git"
+	"gg-scm.io/tool/internal/escape"
+	"gg-scm.io/tool/internal/filesystem"
+)
+
+func TestAmend_To(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	env, err := newTestEnv(ctx, t)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := env.initEmptyRepo(ctx, "."); err != nil {
+		t.Fatal(err)
+	}
+	const (
+		fooOld = "foo old\n"
+		fooNew = "foo new\n"
+		barOld = "bar old\n"
+		barNew = "bar new\n"
+	)
+	if err := env.root.Apply(filesystem.Write("foo.txt", fooOld)); err != nil {
+		t.Fatal(err)
+	}
+	if err := env.addFiles(ctx, "foo.txt"); err != nil {
+		t.Fatal(err)
+	}
+	const firstMsg = "first\n"
+	if err := env.git.Commit(ctx, firstMsg, git.CommitOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := env.root.Apply(filesystem.Write("bar.txt", barOld)); err != nil {
+		t.Fatal(err)
+	}
+	if err := env.addFiles(ctx, "bar.txt"); err != nil {
+		t.Fatal(err)
+	}
+	const secondMsg = "second\n"
+	if err := env.git.Commit(ctx, secondMsg, git.CommitOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	err = env.root.Apply(
+		filesystem.Write("foo.txt", fooNew),
+		filesystem.Write("bar.txt", barNew),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := env.gg(ctx, env.root.String(), "amend", "--to=HEAD~", "foo.txt"); err != nil {
+		t.Fatal(err)
+	}
+
+	if data, err := catBlob(ctx, env.git, "HEAD~", "foo.txt"); err != nil {
+		t.Error(err)
+	} else if string(data) != fooNew {
+		t.Errorf("foo.txt @ HEAD~ = %q; want %q", data, fooNew)
+	}
+	if err := objectExists(ctx, env.git, "HEAD~", "bar.txt"); err == nil {
+		t.Error("bar.txt exists @ HEAD~")
+	}
+	if data, err := catBlob(ctx, env.git, "HEAD", "bar.txt"); err != nil {
+		t.Error(err)
+	} else if string(data) != barOld {
+		t.Errorf("bar.txt @ HEAD = %q; want %q", data, barOld)
+	}
+	if info, err := env.git.CommitInfo(ctx, "HEAD~"); err != nil {
+		t.Error(err)
+	} else {
+		if info.Message != firstMsg {
+			t.Errorf("HEAD~ message = %q; want %q", info.Message, firstMsg)
+		}
+		if len(info.Parents) != 0 {
+			t.Errorf("HEAD~ parents = %v; want []", info.Parents)
+		}
+	}
+	if info, err := env.git.CommitInfo(ctx, "HEAD"); err != nil {
+		t.Error(err)
+	} else if info.Message != secondMsg {
+		t.Errorf("HEAD message = %q; want %q", info.Message, secondMsg)
+	}
+	if data, err := env.root.ReadFile("bar.txt"); err != nil {
+		t.Error(err)
+	} else if data != barNew {
+		t.Errorf("bar.txt in working copy = %q; want %q", data, barNew)
+	}
+}
+
+func TestAmend_ToConflict(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	env, err := newTestEnv(ctx, t)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := env.initEmptyRepo(ctx, "."); err != nil {
+		t.Fatal(err)
+	}
+	if err := env.root.Apply(filesystem.Write("foo.txt", "first\n")); err != nil {
+		t.Fatal(err)
+	}
+	if err := env.addFiles(ctx, "foo.txt"); err != nil {
+		t.Fatal(err)
+	}
+	if err := env.git.Commit(ctx, "first", git.CommitOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := env.root.Apply(filesystem.Write("foo.txt", "second\n")); err != nil {
+		t.Fatal(err)
+	}
+	if err := env.git.CommitAll(ctx, "second", git.CommitOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	head, err := env.git.Head(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Amending this into the first commit conflicts with the second commit.
+	const local = "local\n"
+	if err := env.root.Apply(filesystem.Write("foo.txt", local)); err != nil {
+		t.Fatal(err)
+	}
+	// A staged file that isn't being amended should stay staged.
+	if err := env.root.Apply(filesystem.Write("bar.txt", "bar\n")); err != nil {
+		t.Fatal(err)
+	}
+	if err := env.addFiles(ctx, "bar.txt"); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := env.gg(ctx, env.root.String(), "amend", "--to=HEAD~", "foo.txt"); err == nil {
+		t.Fatal("amend --to with conflicting change did not return an error")
+	}
+
+	if got, err := env.git.Head(ctx); err != nil {
+		t.Error(err)
+	} else if got.Commit != head.Commit || got.Ref != head.Ref {
+		t.Errorf("after failed amend, HEAD = %v (%v); want %v (%v)", got.Commit, got.Ref, head.Commit, head.Ref)
+	}
+	if op, err := readWorkTreeOperation(ctx, env.git); err != nil {
+		t.Error(err)
+	} else if op != noOperation {
+		t.Errorf("after failed amend, operation in progress = %v; want none", op)
+	}
+	if data, err := env.root.ReadFile("foo.txt"); err != nil {
+		t.Error(err)
+	} else if data != local {
+		t.Errorf("foo.txt in working copy = %q; want %q", data, local)
+	}
+	if staged, err := env.git.Output(ctx, "ls-files", "--cached", "--", "bar.txt"); err != nil {
+		t.Error(err)
+	} else if staged == "" {
+		t.Error("after failed amend, bar.txt is no longer staged")
+	}
+}
+
+func TestAmend_ToHead(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	env, err := newTestEnv(ctx, t)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := env.initEmptyRepo(ctx, "."); err != nil {
+		t.Fatal(err)
+	}
+	if err := env.root.Apply(filesystem.Write("foo.txt", "old\n")); err != nil {
+		t.Fatal(err)
+	}
+	if err := env.addFiles(ctx, "foo.txt"); err != nil {
+		t.Fatal(err)
+	}
+	const msg = "initial import\n"
+	if err := env.git.Commit(ctx, msg, git.CommitOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	const fooNew = "new\n"
+	if err := env.root.Apply(filesystem.Write("foo.txt", fooNew)); err != nil {
+		t.Fatal(err)
+	}
+	// The message should be kept, so the editor should not be used.
+	cmd, err := env.editorCmd([]byte("edited\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := env.writeConfig([]byte(fmt.Sprintf("[core]\neditor = %s\n", escape.GitConfig(cmd)))); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := env.gg(ctx, env.root.String(), "amend", "--to=HEAD", "glob:*.txt"); err != nil {
+		t.Fatal(err)
+	}
+
+	if data, err := catBlob(ctx, env.git, "HEAD", "foo.txt"); err != nil {
+		t.Error(err)
+	} else if string(data) != fooNew {
+		t.Errorf("foo.txt @ HEAD = %q; want %q", data, fooNew)
+	}
+	if info, err := env.git.CommitInfo(ctx, "HEAD"); err != nil {
+		t.Error(err)
+	} else {
+		if info.Message != msg {
+			t.Errorf("HEAD message = %q; want %q", info.Message, msg)
+		}
+		if len(info.Parents) != 0 {
+			t.Errorf("HEAD parents = %v; want []", info.Parents)
+		}
+	}
+}
+
+func TestAmend_Metadata(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	env, err := newTestEnv(ctx, t)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := env.initRepoWithHistory(ctx, "."); err != nil {
+		t.Fatal(err)
+	}
+	before, err := env.git.CommitInfo(ctx, "HEAD")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// An outstanding change that should not be folded in.
+	if err := env.root.Apply(filesystem.Write("foo.txt", dummyContent)); err != nil {
+		t.Fatal(err)
+	}
+	if err := env.addFiles(ctx, "foo.txt"); err != nil {
+		t.Fatal(err)
+	}
+
+	const author = "Jane Doe <jane@example.com>"
+	_, err = env.gg(ctx, env.root.String(), "amend", "--author="+author, "--date=2021-03-04T05:06:07Z")
+	if err != nil {
+		t.Fatal(err)
+	}
+	after, err := env.git.CommitInfo(ctx, "HEAD")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(after.Author) != author {
+		t.Errorf("author = %q; want %q", after.Author, author)
+	}
+	if want := time.Date(2021, time.March, 4, 5, 6, 7, 0, time.UTC); !after.AuthorTime.Equal(want) {
+		t.Errorf("author time = %v; want %v", after.AuthorTime, want)
+	}
+	if after.Message != before.Message {
+		t.Errorf("message = %q; want %q", after.Message, before.Message)
+	}
+	if after.Tree != before.Tree {
+		t.Error("amend --author changed the commit's files")
+	}
+	if err := objectExists(ctx, env.git, "HEAD", "foo.txt"); err == nil {
+		t.Error("foo.txt was folded into the commit")
+	}
+
+	if _, err := env.gg(ctx, env.root.String(), "amend", "--no-edit"); err != nil {
+		t.Fatal(err)
+	}
+	if err := objectExists(ctx, env.git, "HEAD", "foo.txt"); err == nil {
+		t.Error("amend --no-edit without files folded foo.txt into the commit")
+	}
+	if _, err := env.gg(ctx, env.root.String(), "amend", "--no-edit", "foo.txt"); err != nil {
+		t.Fatal(err)
+	}
+	if err := objectExists(ctx, env.git, "HEAD", "foo.txt"); err != nil {
+		t.Error(err)
+	}
+	if info, err := env.git.CommitInfo(ctx, "HEAD"); err != nil {
+		t.Error(err)
+	} else if info.Message != before.Message {
+		t.Errorf("message after --no-edit = %q; want %q", info.Message, before.Message)
+	}
+
+	if _, err := env.gg(ctx, env.root.String(), "amend", "-m", "foo", "--no-edit"); err == nil {
+		t.Error("amend -m foo --no-edit did not return an error")
+	} else if !isUsage(err) {
+		t.Errorf("amend -m foo --no-edit: %v; want usage error", err)
+	}
+}
+
+func TestParseDate(t *testing.T) {
+	now := time.Date(2021, time.June, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		s    string
+		want time.Time
+	}{
+		{"now", now},
+		{"2021-03-04T05:06:07Z", time.Date(2021, time.March, 4, 5, 6, 7, 0, time.UTC)},
+		{"2021-03-04 05:06:07 +0100", time.Date(2021, time.March, 4, 4, 6, 7, 0, time.UTC)},
+		{"2021-03-04", time.Date(2021, time.March, 4, 0, 0, 0, 0, time.Local)},
+	}
+	for _, test := range tests {
+		got, err := parseDate(test.s, now)
+		if err != nil {
+			t.Errorf("parseDate(%q, now): %v", test.s, err)
+			continue
+		}
+		if !got.Equal(test.want) {
+			t.Errorf("parseDate(%q, now) = %v; want %v", test.s, got, test.want)
+		}
+	}
+	if _, err := parseDate("yesterday", now); err == nil {
+		t.Error("parseDate(\"yesterday\", now) did not return an error")
+	}
+}
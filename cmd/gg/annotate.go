@@ -0,0 +1,307 @@
+// Copyright 2021 The gg Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"gg-scm.io/pkg/git"
+	"gg-scm.io/tool/internal/flag"
+	"gg-scm.io/tool/internal/terminal"
+)
+
+const annotateSynopsis = "show changeset information by line for each file"
+
+// defaultIgnoreRevsFilename is the conventional name of the file at the
+// top of the working copy that lists commits for annotate to skip.
+const defaultIgnoreRevsFilename = ".git-blame-ignore-revs"
+
+// ignoreRevsVersion is the first version of Git whose blame accepts
+// --ignore-rev and --ignore-revs-file.
+var ignoreRevsVersion = gitVersion{2, 23, 0}
+
+func annotate(ctx context.Context, cc *cmdContext, args []string) error {
+	f := flag.NewFlagSet(true, "gg annotate [-r REV] [-w] [--date=STYLE] [--ignore-rev=REV [...]] [--ignore-revs-file=FILE | --no-ignore-revs] FILE", annotateSynopsis+`
+
+aliases: blame
+
+	Show the commit that last changed each line of the file as of the
+	given revision (defaults to HEAD, plus any uncommitted changes).
+	Each line is prefixed with the commit's abbreviated hash, its author,
+	the date it was authored, and the line number. When color is enabled,
+	the first line of each run of lines from the same commit is
+	highlighted. The `+"`color.ggannotate`"+` setting controls color, and
+	`+"`color.ggannotate.boundary`"+` sets the highlight color. Dates are
+	shown in the style given by `+"`--date`"+` or the `+"`gg.dateFormat`"+`
+	setting.
+
+	Commits listed in the file named by the `+"`blame.ignoreRevsFile`"+`
+	configuration setting are skipped, and their changes are attributed
+	to earlier commits. If the setting is not present, then a
+	`+"`"+defaultIgnoreRevsFilename+"`"+` file at the top of the working copy is
+	used instead. This is useful for hiding mass reformatting commits.
+	`+"`--ignore-revs-file`"+` reads a different file,
+	`+"`--ignore-rev`"+` skips additional commits, and `+"`--no-ignore-revs`"+`
+	stops gg from reading any ignore file.`)
+	r := f.String("r", "", "annotate the file as of the given `rev`ision")
+	date := f.String("date", "", "date display `style`: 'default', 'relative', 'iso', or 'local' (defaults to gg.dateFormat)")
+	ignoreRevs := f.MultiString("ignore-rev", "skip changes made by `rev`ision")
+	ignoreRevsFile := f.String("ignore-revs-file", "", "skip the revisions listed in `file`")
+	ignoreWhitespace := f.Bool("w", false, "ignore whitespace when finding where lines came from")
+	noIgnoreRevs := f.Bool("no-ignore-revs", false, "do not skip the commits listed in ignore files")
+	if err := f.Parse(args); flag.IsHelp(err) {
+		f.Help(cc.stdout)
+		return nil
+	} else if err != nil {
+		return usagef("%v", err)
+	}
+	if f.NArg() != 1 {
+		return usagef("must pass exactly one file to annotate")
+	}
+	if strings.HasPrefix(*r, "-") {
+		return usagef("revisions must not start with '-'")
+	}
+	if *noIgnoreRevs && *ignoreRevsFile != "" {
+		return usagef("can't pass both --ignore-revs-file and --no-ignore-revs")
+	}
+
+	blameArgs := []string{"blame", "--line-porcelain"}
+	if *ignoreWhitespace {
+		blameArgs = append(blameArgs, "-w")
+	}
+	if *noIgnoreRevs || *ignoreRevsFile != "" || len(*ignoreRevs) > 0 {
+		v, err := queryGitVersion(ctx, cc.git)
+		if err != nil {
+			return err
+		}
+		if v.less(ignoreRevsVersion) {
+			return fmt.Errorf("ignoring revisions requires Git %v or later (found %v)", ignoreRevsVersion, v)
+		}
+	}
+	switch {
+	case *noIgnoreRevs:
+		// An empty file name clears the list from configuration.
+		blameArgs = append(blameArgs, "--ignore-revs-file=")
+	case *ignoreRevsFile != "":
+		// Clear the list from configuration first, so only the given file
+		// is used.
+		blameArgs = append(blameArgs, "--ignore-revs-file=", "--ignore-revs-file="+cc.abs(*ignoreRevsFile))
+	default:
+		ignoreFile, err := defaultIgnoreRevsFile(ctx, cc)
+		if err != nil {
+			return err
+		}
+		if ignoreFile != "" {
+			// Older versions of Git can't skip commits, so the conventional
+			// file is silently ignored.
+			if v, err := queryGitVersion(ctx, cc.git); err == nil && !v.less(ignoreRevsVersion) {
+				blameArgs = append(blameArgs, "--ignore-revs-file="+ignoreFile)
+			}
+		}
+	}
+	for _, rev := range *ignoreRevs {
+		blameArgs = append(blameArgs, "--ignore-rev="+rev)
+	}
+	if *r != "" {
+		blameArgs = append(blameArgs, *r)
+	}
+	blameArgs = append(blameArgs, "--", f.Arg(0))
+	out, err := cc.git.Output(ctx, blameArgs...)
+	if err != nil {
+		return err
+	}
+	lines, err := parseBlamePorcelain(out)
+	if err != nil {
+		return err
+	}
+
+	cfg, err := cc.git.ReadConfig(ctx)
+	if err != nil {
+		return err
+	}
+	style, err := dateStyle(cfg, *date)
+	if err != nil {
+		return err
+	}
+	var boundaryColor []byte
+	colorize, err := cfg.ColorBool("color.ggannotate", cc.isTerminal())
+	if err != nil {
+		fmt.Fprintln(cc.stderr, "gg:", err)
+	} else if colorize {
+		boundaryColor, err = cfg.Color("color.ggannotate.boundary", "yellow")
+		if err != nil {
+			fmt.Fprintln(cc.stderr, "gg:", err)
+		}
+	}
+	now := time.Now()
+	dates := make([]string, len(lines))
+	authorWidth, dateWidth, linenoWidth := 0, 0, 0
+	for i, line := range lines {
+		if n := utf8.RuneCountInString(line.author); n > authorWidth {
+			authorWidth = n
+		}
+		dates[i] = style.Format(line.authorTime, now)
+		if n := utf8.RuneCountInString(dates[i]); n > dateWidth {
+			dateWidth = n
+		}
+		if n := len(strconv.Itoa(line.lineno)); n > linenoWidth {
+			linenoWidth = n
+		}
+	}
+	for i, line := range lines {
+		boundary := i == 0 || line.commit != lines[i-1].commit
+		authorPad := strings.Repeat(" ", authorWidth-utf8.RuneCountInString(line.author))
+		datePad := strings.Repeat(" ", dateWidth-utf8.RuneCountInString(dates[i]))
+		header := fmt.Sprintf("%v %s%s %s%s", line.commit.Short(), line.author, authorPad, dates[i], datePad)
+		if boundary && len(boundaryColor) > 0 {
+			if _, err := cc.stdout.Write(boundaryColor); err != nil {
+				return err
+			}
+			if _, err := fmt.Fprint(cc.stdout, header); err != nil {
+				return err
+			}
+			if err := terminal.ResetTextStyle(cc.stdout); err != nil {
+				return err
+			}
+		} else if _, err := fmt.Fprint(cc.stdout, header); err != nil {
+			return err
+		}
+		if _, err := fmt.Fprintf(cc.stdout, " %*d: %s\n", linenoWidth, line.lineno, line.text); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// blameLine is a line of a file along with the commit that last changed it.
+type blameLine struct {
+	commit     git.Hash
+	author     string
+	authorTime time.Time
+	lineno     int // 1-based line number in the annotated file
+	text       string
+}
+
+// parseBlamePorcelain parses the output of `git blame --line-porcelain`.
+// It is shared by annotate and absorb.
+func parseBlamePorcelain(out string) ([]blameLine, error) {
+	var lines []blameLine
+	var curr *blameLine
+	var unixTime int64
+	var loc *time.Location
+	for len(out) > 0 {
+		var line string
+		if i := strings.IndexByte(out, '\n'); i != -1 {
+			line, out = out[:i], out[i+1:]
+		} else {
+			line, out = out, ""
+		}
+		if curr == nil {
+			// Header: "HASH ORIG_LINE FINAL_LINE [NUM_LINES]".
+			fields := strings.Fields(line)
+			if len(fields) < 3 {
+				return nil, fmt.Errorf("parse blame: invalid header %q", line)
+			}
+			h, err := git.ParseHash(fields[0])
+			if err != nil {
+				return nil, fmt.Errorf("parse blame: %w", err)
+			}
+			lineno, err := strconv.Atoi(fields[2])
+			if err != nil {
+				return nil, fmt.Errorf("parse blame: invalid header %q", line)
+			}
+			curr = &blameLine{commit: h, lineno: lineno}
+			unixTime = 0
+			loc = time.UTC
+			continue
+		}
+		if strings.HasPrefix(line, "\t") {
+			curr.text = line[1:]
+			curr.authorTime = time.Unix(unixTime, 0).In(loc)
+			lines = append(lines, *curr)
+			curr = nil
+			continue
+		}
+		key, value := line, ""
+		if i := strings.IndexByte(line, ' '); i != -1 {
+			key, value = line[:i], line[i+1:]
+		}
+		switch key {
+		case "author":
+			curr.author = value
+		case "author-time":
+			t, err := strconv.ParseInt(value, 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("parse blame: invalid author-time %q", value)
+			}
+			unixTime = t
+		case "author-tz":
+			loc = parseBlameTimeZone(value)
+		}
+	}
+	if curr != nil {
+		return nil, fmt.Errorf("parse blame: missing content for line %d", curr.lineno)
+	}
+	return lines, nil
+}
+
+// parseBlameTimeZone parses a time zone offset like "-0700".
+// It returns UTC if the offset is malformed.
+func parseBlameTimeZone(tz string) *time.Location {
+	if len(tz) != 5 || (tz[0] != '+' && tz[0] != '-') {
+		return time.UTC
+	}
+	hours, err1 := strconv.Atoi(tz[1:3])
+	minutes, err2 := strconv.Atoi(tz[3:])
+	if err1 != nil || err2 != nil {
+		return time.UTC
+	}
+	offset := (hours*60 + minutes) * 60
+	if tz[0] == '-' {
+		offset = -offset
+	}
+	return time.FixedZone(tz, offset)
+}
+
+// defaultIgnoreRevsFile returns the path to the working copy's
+// .git-blame-ignore-revs file if it exists and blame.ignoreRevsFile is not
+// configured. Otherwise, it returns the empty string and Git uses its
+// configuration as-is.
+func defaultIgnoreRevsFile(ctx context.Context, cc *cmdContext) (string, error) {
+	cfg, err := cc.git.ReadConfig(ctx)
+	if err != nil {
+		return "", err
+	}
+	if cfg.Value("blame.ignoreRevsFile") != "" {
+		return "", nil
+	}
+	workTree, err := cc.git.WorkTree(ctx)
+	if err != nil {
+		// Bare repository.
+		return "", nil
+	}
+	path := filepath.Join(workTree, defaultIgnoreRevsFilename)
+	if _, err := os.Stat(path); err != nil {
+		return "", nil
+	}
+	return path, nil
+}
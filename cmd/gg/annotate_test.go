@@ -0,0 +1,193 @@
+// Copyright 2021 The gg Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"gg-scm.io/tool/internal/filesystem"
+)
+
+func TestAnnotate_IgnoreRevs(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	env, err := newTestEnv(ctx, t)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v, err := queryGitVersion(ctx, env.git); err != nil {
+		t.Fatal(err)
+	} else if v.less(ignoreRevsVersion) {
+		t.Skipf("Git %v does not support --ignore-rev", v)
+	}
+	if err := env.initRepoWithHistory(ctx, "repo"); err != nil {
+		t.Fatal(err)
+	}
+	if err := env.root.Apply(filesystem.Write("repo/foo.go", "foo(x,y)\nbar\n")); err != nil {
+		t.Fatal(err)
+	}
+	if err := env.addFiles(ctx, "repo/foo.go"); err != nil {
+		t.Fatal(err)
+	}
+	original, err := env.newCommit(ctx, "repo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := env.root.Apply(filesystem.Write("repo/foo.go", "foo(x, y)\nbar\n")); err != nil {
+		t.Fatal(err)
+	}
+	reformat, err := env.newCommit(ctx, "repo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	repoDir := env.root.FromSlash("repo")
+
+	// firstLineCommit returns the abbreviated commit hash that annotate
+	// attributes the first line of foo.go to.
+	firstLineCommit := func(args ...string) string {
+		t.Helper()
+		out, err := env.gg(ctx, repoDir, append([]string{"annotate"}, args...)...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		fields := strings.Fields(string(out))
+		if len(fields) == 0 {
+			t.Fatal("annotate printed nothing")
+		}
+		return fields[0]
+	}
+	commitName := func(h string) string {
+		switch {
+		case strings.HasPrefix(original.String(), h):
+			return "original"
+		case strings.HasPrefix(reformat.String(), h):
+			return "reformat"
+		default:
+			return h
+		}
+	}
+
+	if got := commitName(firstLineCommit("foo.go")); got != "reformat" {
+		t.Errorf("annotate foo.go attributes first line to %s; want reformat", got)
+	}
+	if got := commitName(firstLineCommit("--ignore-rev="+reformat.String(), "foo.go")); got != "original" {
+		t.Errorf("annotate --ignore-rev=REFORMAT foo.go attributes first line to %s; want original", got)
+	}
+
+	if err := env.root.Apply(filesystem.Write("repo/.git-blame-ignore-revs", "# Reformat\n"+reformat.String()+"\n")); err != nil {
+		t.Fatal(err)
+	}
+	if got := commitName(firstLineCommit("foo.go")); got != "original" {
+		t.Errorf("with %s, annotate foo.go attributes first line to %s; want original", defaultIgnoreRevsFilename, got)
+	}
+	if got := commitName(firstLineCommit("--no-ignore-revs", "foo.go")); got != "reformat" {
+		t.Errorf("annotate --no-ignore-revs foo.go attributes first line to %s; want reformat", got)
+	}
+	if got := commitName(firstLineCommit("-r", original.String(), "foo.go")); got != "original" {
+		t.Errorf("annotate -r ORIGINAL foo.go attributes first line to %s; want original", got)
+	}
+}
+
+func TestAnnotate_DateFormat(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	env, err := newTestEnv(ctx, t)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := env.writeConfig([]byte("[gg]\ndateFormat = relative\n")); err != nil {
+		t.Fatal(err)
+	}
+	if err := env.initRepoWithHistory(ctx, "repo"); err != nil {
+		t.Fatal(err)
+	}
+	repoDir := env.root.FromSlash("repo")
+
+	out, err := env.gg(ctx, repoDir, "annotate", "foo.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Contains(out, []byte(" ago ")) {
+		t.Errorf("with gg.dateFormat = relative, annotate output does not contain a relative date. Output:\n%s", out)
+	}
+	out, err = env.gg(ctx, repoDir, "annotate", "--date=iso", "foo.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(out, []byte(" ago ")) {
+		t.Errorf("annotate --date=iso output contains a relative date. Output:\n%s", out)
+	}
+	if _, err := env.gg(ctx, repoDir, "annotate", "--date=bogus", "foo.txt"); err == nil {
+		t.Error("annotate --date=bogus did not return error")
+	} else if !isUsage(err) {
+		t.Errorf("annotate --date=bogus returned non-usage error: %v", err)
+	}
+}
+
+func TestParseBlamePorcelain(t *testing.T) {
+	const hash1 = "0123456789abcdef0123456789abcdef01234567"
+	const hash2 = "89abcdef0123456789abcdef0123456789abcdef"
+	out := hash1 + " 1 1 2\n" +
+		"author Alice\n" +
+		"author-mail <alice@example.com>\n" +
+		"author-time 1600000000\n" +
+		"author-tz -0700\n" +
+		"summary First\n" +
+		"boundary\n" +
+		"filename foo.txt\n" +
+		"\tHello\n" +
+		hash1 + " 2 2\n" +
+		"author Alice\n" +
+		"author-time 1600000000\n" +
+		"author-tz -0700\n" +
+		"filename foo.txt\n" +
+		"\t\tindented\n" +
+		hash2 + " 2 3 1\n" +
+		"author Bob\n" +
+		"author-time 1700000000\n" +
+		"author-tz +0000\n" +
+		"previous " + hash1 + " foo.txt\n" +
+		"filename foo.txt\n" +
+		"\tWorld\n"
+	lines, err := parseBlamePorcelain(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []struct {
+		commit string
+		author string
+		date   string
+		lineno int
+		text   string
+	}{
+		{hash1, "Alice", "2020-09-13", 1, "Hello"},
+		{hash1, "Alice", "2020-09-13", 2, "\tindented"},
+		{hash2, "Bob", "2023-11-14", 3, "World"},
+	}
+	if len(lines) != len(want) {
+		t.Fatalf("parseBlamePorcelain(...) returned %d lines; want %d", len(lines), len(want))
+	}
+	for i, line := range lines {
+		w := want[i]
+		if line.commit.String() != w.commit || line.author != w.author || line.authorTime.Format("2006-01-02") != w.date || line.lineno != w.lineno || line.text != w.text {
+			t.Errorf("lines[%d] = {%v %q %v %d %q}; want {%s %q %s %d %q}",
+				i, line.commit, line.author, line.authorTime.Format("2006-01-02"), line.lineno, line.text,
+				w.commit, w.author, w.date, w.lineno, w.text)
+		}
+	}
+}
@@ -0,0 +1,106 @@
+// Copyright 2021 The gg Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"gg-scm.io/pkg/git"
+	"gg-scm.io/tool/internal/flag"
+)
+
+const archiveSynopsis = "create an archive of a revision"
+
+func archive(ctx context.Context, cc *cmdContext, args []string) error {
+	f := flag.NewFlagSet(true, "gg archive [-r REV] [-t TYPE] [--prefix=DIR] [-o FILE] [PATHSPEC [...]]", archiveSynopsis+`
+
+	archive writes the files at the given revision (HEAD by default) to a
+	tar or zip archive. Only the files matching the pathspecs are included
+	if any are given. Like Git, running archive from a subdirectory only
+	includes files in that subdirectory.
+
+	The archive is written to the file named by `+"`-o`"+` or to stdout if
+	`+"`-o`"+` is not given or is `+"`-`"+`. The type is one of "tar",
+	"tgz", or "zip". If `+"`-t`"+` is not given, the type is inferred
+	from the output file's extension, falling back to "tar".
+
+	`+"`--prefix`"+` puts all files in the archive under the given
+	directory, which is useful for release archives:
+
+		gg archive -r v1.0.0 --prefix=myproject-1.0.0 -o myproject-1.0.0.tar.gz`)
+	rev := f.String("r", git.Head.String(), "`rev`ision to archive")
+	typ := f.String("t", "", "archive `type`: tar, tgz, or zip")
+	f.Alias("t", "type")
+	prefix := f.String("prefix", "", "`dir`ectory to put files under in the archive")
+	output := f.String("o", "-", "write the archive to `file`")
+	f.Alias("o", "output")
+	if err := f.Parse(args); flag.IsHelp(err) {
+		f.Help(cc.stdout)
+		return nil
+	} else if err != nil {
+		return usagef("%v", err)
+	}
+	format := *typ
+	if format == "" {
+		format = archiveTypeFromFilename(*output)
+	}
+	switch format {
+	case "tar", "zip":
+	case "tgz", "tar.gz":
+		format = "tgz"
+	default:
+		return usagef("unknown archive type %q (must be tar, tgz, or zip)", *typ)
+	}
+	r, err := cc.git.ParseRev(ctx, *rev)
+	if err != nil {
+		return err
+	}
+	archiveArgs := []string{"archive", "--format=" + format}
+	if *prefix != "" {
+		p := strings.TrimSuffix(*prefix, "/") + "/"
+		archiveArgs = append(archiveArgs, "--prefix="+p)
+	}
+	if *output != "-" {
+		archiveArgs = append(archiveArgs, "--output="+cc.abs(*output))
+	}
+	archiveArgs = append(archiveArgs, r.Commit.String(), "--")
+	archiveArgs = append(archiveArgs, f.Args()...)
+	err = cc.git.Runner().RunGit(ctx, &git.Invocation{
+		Args:   archiveArgs,
+		Dir:    cc.dir,
+		Stdout: cc.stdout,
+		Stderr: cc.stderr,
+	})
+	if err != nil {
+		return fmt.Errorf("archive %s: %w", *rev, err)
+	}
+	return nil
+}
+
+// archiveTypeFromFilename returns the archive type implied by a filename's
+// extension, defaulting to "tar".
+func archiveTypeFromFilename(name string) string {
+	lower := strings.ToLower(name)
+	switch {
+	case strings.HasSuffix(lower, ".zip"):
+		return "zip"
+	case strings.HasSuffix(lower, ".tar.gz"), strings.HasSuffix(lower, ".tgz"):
+		return "tgz"
+	default:
+		return "tar"
+	}
+}
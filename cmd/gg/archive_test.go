@@ -0,0 +1,94 @@
+// Copyright 2021 The gg Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"context"
+	"io"
+	"sort"
+	"testing"
+
+	"gg-scm.io/tool/internal/filesystem"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestArchive(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	env, err := newTestEnv(ctx, t)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := env.initEmptyRepo(ctx, "."); err != nil {
+		t.Fatal(err)
+	}
+	err = env.root.Apply(
+		filesystem.Write("foo.txt", "foo\n"),
+		filesystem.Write("docs/bar.txt", "bar\n"),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := env.addFiles(ctx, "foo.txt", "docs/bar.txt"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := env.newCommit(ctx, "."); err != nil {
+		t.Fatal(err)
+	}
+
+	out, err := env.gg(ctx, env.root.String(), "archive", "--prefix=proj-1.0", "docs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var tarNames []string
+	tr := tar.NewReader(bytes.NewReader(out))
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		if hdr.Typeflag == tar.TypeReg {
+			tarNames = append(tarNames, hdr.Name)
+		}
+	}
+	if diff := cmp.Diff([]string{"proj-1.0/docs/bar.txt"}, tarNames); diff != "" {
+		t.Errorf("tar archive files (-want +got):\n%s", diff)
+	}
+
+	if _, err := env.gg(ctx, env.root.String(), "archive", "-o", "out.zip"); err != nil {
+		t.Fatal(err)
+	}
+	zr, err := zip.OpenReader(env.root.FromSlash("out.zip"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer zr.Close()
+	var zipNames []string
+	for _, f := range zr.File {
+		if !f.FileInfo().IsDir() {
+			zipNames = append(zipNames, f.Name)
+		}
+	}
+	sort.Strings(zipNames)
+	if diff := cmp.Diff([]string{"docs/bar.txt", "foo.txt"}, zipNames); diff != "" {
+		t.Errorf("zip archive files (-want +got):\n%s", diff)
+	}
+}
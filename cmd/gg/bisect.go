@@ -0,0 +1,307 @@
+// Copyright 2021 The gg Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"gg-scm.io/pkg/git"
+	"gg-scm.io/tool/internal/flag"
+)
+
+const bisectSynopsis = "find the commit that introduced a bug by binary search"
+
+// bisectStateFilename is the name of the file in the Git directory where
+// gg records an in-progress bisection's test command and result.
+const bisectStateFilename = "gg-bisect"
+
+func bisect(ctx context.Context, cc *cmdContext, args []string) error {
+	f := flag.NewFlagSet(true, "gg bisect [--good REV [...]] [--bad REV] [--skip REV [...]] [--run CMD]\n"+
+		"gg bisect --reset", bisectSynopsis+`
+
+	Start a bisection by naming a bad revision that has the bug and at
+	least one good revision that does not. gg checks out a commit halfway
+	between them. Test it, then mark it with `+"`--good HEAD`"+`,
+	`+"`--bad HEAD`"+`, or `+"`--skip HEAD`"+` if it can't be tested.
+	Each step halves the number of commits left, and gg reports how many
+	steps remain. Running `+"`gg bisect`"+` with no flags shows the
+	current status.
+
+	With `+"`--run`"+`, gg marks commits automatically by running the given
+	shell command at each step: an exit code of 0 marks the commit good,
+	125 skips it, and any other code between 1 and 127 marks it bad. The
+	command is remembered, so later invocations continue running it.
+
+	Once the first bad commit is found, gg records it. `+"`--reset`"+`
+	ends the bisection, returns the working copy to where it was before
+	the bisection started, and shows the recorded result.`)
+	good := f.MultiString("good", "mark `rev`ision as not having the bug")
+	bad := f.String("bad", "", "mark `rev`ision as having the bug")
+	skip := f.MultiString("skip", "mark `rev`ision as untestable")
+	run := f.String("run", "", "shell `command` that tests each commit")
+	reset := f.Bool("reset", false, "end the bisection")
+	if err := f.Parse(args); flag.IsHelp(err) {
+		f.Help(cc.stdout)
+		return nil
+	} else if err != nil {
+		return usagef("%v", err)
+	}
+	if f.NArg() > 0 {
+		return usagef("bisect takes no arguments")
+	}
+	if *reset && (len(*good) > 0 || *bad != "" || len(*skip) > 0 || *run != "") {
+		return usagef("can't pass other flags with --reset")
+	}
+	for _, rev := range append(append([]string{*bad}, *good...), *skip...) {
+		if strings.HasPrefix(rev, "-") {
+			return usagef("revisions must not start with '-'")
+		}
+	}
+	gitDir, err := cc.git.GitDir(ctx)
+	if err != nil {
+		return err
+	}
+	statePath := filepath.Join(gitDir, bisectStateFilename)
+	if *reset {
+		return resetBisect(ctx, cc, gitDir, statePath)
+	}
+
+	state, err := readBisectState(statePath)
+	if err != nil {
+		return err
+	}
+	if _, err := os.Stat(filepath.Join(gitDir, "BISECT_START")); os.IsNotExist(err) {
+		if *bad == "" || len(*good) == 0 {
+			return errors.New("no bisection in progress; pass --bad and --good to start one")
+		}
+		state = new(bisectState)
+		startArgs := []string{"bisect", "start", *bad}
+		startArgs = append(startArgs, *good...)
+		startArgs = append(startArgs, "--")
+		if _, err := cc.git.Output(ctx, startArgs...); err != nil {
+			return err
+		}
+		*good = nil
+		*bad = ""
+	} else if err != nil {
+		return err
+	}
+	for _, rev := range *good {
+		if _, err := cc.git.Output(ctx, "bisect", "good", rev); err != nil {
+			return err
+		}
+	}
+	for _, rev := range *skip {
+		if _, err := cc.git.Output(ctx, "bisect", "skip", rev); err != nil {
+			return err
+		}
+	}
+	if *bad != "" {
+		if _, err := cc.git.Output(ctx, "bisect", "bad", *bad); err != nil {
+			return err
+		}
+	}
+	if *run != "" {
+		state.command = *run
+	}
+	if err := writeBisectState(statePath, state); err != nil {
+		return err
+	}
+	if state.command != "" {
+		_, remaining, err := bisectCandidates(ctx, cc.git)
+		if err != nil {
+			return err
+		}
+		if len(remaining) > 1 {
+			// git bisect run stops on its own once the first bad commit
+			// is found.
+			if err := cc.interactiveGit(ctx, "bisect", "run", "sh", "-c", state.command); err != nil {
+				return err
+			}
+		}
+	}
+	return bisectStatus(ctx, cc, statePath, state)
+}
+
+// bisectCandidates returns the commit currently marked bad and the
+// commits that may be the first bad commit. It returns a zero hash if no
+// commit has been marked bad and no candidates if no commit has been
+// marked good.
+func bisectCandidates(ctx context.Context, g *git.Git) (bad git.Hash, candidates []git.Hash, _ error) {
+	refs, err := g.ListRefs(ctx)
+	if err != nil {
+		return git.Hash{}, nil, err
+	}
+	bad, hasBad := refs["refs/bisect/bad"]
+	if !hasBad {
+		return git.Hash{}, nil, nil
+	}
+	revs := []string{bad.String()}
+	for ref, h := range refs {
+		if strings.HasPrefix(ref.String(), "refs/bisect/good-") {
+			revs = append(revs, "^"+h.String())
+		}
+	}
+	if len(revs) == 1 {
+		return bad, nil, nil
+	}
+	candidates, err = revList(ctx, g, revs)
+	if err != nil {
+		return git.Hash{}, nil, err
+	}
+	return bad, candidates, nil
+}
+
+// bisectStatus prints the progress of the current bisection and records
+// the first bad commit once it is known.
+func bisectStatus(ctx context.Context, cc *cmdContext, statePath string, state *bisectState) error {
+	badHash, remaining, err := bisectCandidates(ctx, cc.git)
+	if err != nil {
+		return err
+	}
+	if badHash == (git.Hash{}) {
+		fmt.Fprintln(cc.stderr, "gg: waiting for a bad revision (pass --bad)")
+		return nil
+	}
+	if len(remaining) == 0 {
+		fmt.Fprintln(cc.stderr, "gg: waiting for a good revision (pass --good)")
+		return nil
+	}
+	if len(remaining) == 1 {
+		state.result = badHash
+		if err := writeBisectState(statePath, state); err != nil {
+			return err
+		}
+		summary, err := cc.git.Output(ctx, "show", "-s", "--format=%s", badHash.String(), "--")
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintf(cc.stdout, "The first bad commit is %v %s\nRun `gg bisect --reset` to finish.\n", badHash.Short(), strings.TrimSpace(summary))
+		return err
+	}
+	head, err := cc.git.Head(ctx)
+	if err != nil {
+		return err
+	}
+	steps := bisectSteps(len(remaining))
+	stepWord := "steps"
+	if steps == 1 {
+		stepWord = "step"
+	}
+	_, err = fmt.Fprintf(cc.stdout, "Testing %v: %d commits left (~%d %s remaining)\n",
+		head.Commit.Short(), len(remaining), steps, stepWord)
+	return err
+}
+
+// bisectSteps returns the approximate number of tests needed to find
+// the first bad commit among n candidates.
+func bisectSteps(n int) int {
+	steps := 0
+	for n > 1 {
+		n = (n + 1) / 2
+		steps++
+	}
+	return steps
+}
+
+// resetBisect ends the bisection in progress, if any, and removes the
+// state file.
+func resetBisect(ctx context.Context, cc *cmdContext, gitDir, statePath string) error {
+	state, err := readBisectState(statePath)
+	if err != nil {
+		return err
+	}
+	if _, err := os.Stat(filepath.Join(gitDir, "BISECT_START")); err == nil {
+		if _, err := cc.git.Output(ctx, "bisect", "reset"); err != nil {
+			return err
+		}
+	} else if !os.IsNotExist(err) {
+		return err
+	}
+	if err := os.Remove(statePath); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("bisect: %w", err)
+	}
+	if state.result != (git.Hash{}) {
+		_, err := fmt.Fprintf(cc.stdout, "The first bad commit was %v\n", state.result)
+		return err
+	}
+	return nil
+}
+
+// bisectState is the information that gg records about a bisection.
+type bisectState struct {
+	command string   // test command, or empty if testing manually
+	result  git.Hash // first bad commit, or zero if not yet found
+}
+
+// readBisectState reads the bisection state file. If the file does not
+// exist, readBisectState returns an empty state.
+func readBisectState(path string) (*bisectState, error) {
+	state := new(bisectState)
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return state, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read bisect state: %w", err)
+	}
+	defer f.Close()
+	s := bufio.NewScanner(f)
+	for s.Scan() {
+		key, value := s.Text(), ""
+		if i := strings.IndexByte(key, ' '); i != -1 {
+			key, value = key[:i], key[i+1:]
+		}
+		switch key {
+		case "run":
+			state.command, err = strconv.Unquote(value)
+			if err != nil {
+				return nil, fmt.Errorf("read bisect state: run: %w", err)
+			}
+		case "result":
+			state.result, err = git.ParseHash(value)
+			if err != nil {
+				return nil, fmt.Errorf("read bisect state: %w", err)
+			}
+		}
+	}
+	if err := s.Err(); err != nil {
+		return nil, fmt.Errorf("read bisect state: %w", err)
+	}
+	return state, nil
+}
+
+// writeBisectState replaces the bisection state file.
+func writeBisectState(path string, state *bisectState) error {
+	var sb strings.Builder
+	if state.command != "" {
+		sb.WriteString("run " + strconv.Quote(state.command) + "\n")
+	}
+	if state.result != (git.Hash{}) {
+		sb.WriteString("result " + state.result.String() + "\n")
+	}
+	if err := ioutil.WriteFile(path, []byte(sb.String()), 0o666); err != nil {
+		return fmt.Errorf("write bisect state: %w", err)
+	}
+	return nil
+}
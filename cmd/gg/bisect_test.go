@@ -0,0 +1,79 @@
+// Copyright 2021 The gg Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"testing"
+
+	"gg-scm.io/pkg/git"
+	"gg-scm.io/tool/internal/filesystem"
+)
+
+func TestBisectRun(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	env, err := newTestEnv(ctx, t)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := env.initEmptyRepo(ctx, "repo"); err != nil {
+		t.Fatal(err)
+	}
+	var commits []git.Hash
+	for i := 0; i < 8; i++ {
+		content := "ok\n"
+		if i >= 5 {
+			content = "bug\n"
+		}
+		if err := env.root.Apply(filesystem.Write("repo/foo.txt", content+strconv.Itoa(i)+"\n")); err != nil {
+			t.Fatal(err)
+		}
+		if err := env.addFiles(ctx, "repo/foo.txt"); err != nil {
+			t.Fatal(err)
+		}
+		c, err := env.newCommit(ctx, "repo")
+		if err != nil {
+			t.Fatal(err)
+		}
+		commits = append(commits, c)
+	}
+	repoPath := env.root.FromSlash("repo")
+
+	out, err := env.gg(ctx, repoPath, "bisect", "--bad", "HEAD", "--good", commits[0].String(), "--run", "! grep -q bug foo.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "The first bad commit is " + commits[5].Short(); !strings.Contains(string(out), want) {
+		t.Errorf("gg bisect output:\n%s\nwant to contain %q", out, want)
+	}
+
+	out, err = env.gg(ctx, repoPath, "bisect", "--reset")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := string(out), "The first bad commit was "+commits[5].String()+"\n"; got != want {
+		t.Errorf("gg bisect --reset = %q; want %q", got, want)
+	}
+	head, err := env.git.WithDir(repoPath).Head(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if head.Ref != "refs/heads/main" || head.Commit != commits[7] {
+		t.Errorf("after reset, HEAD = %v (%v); want refs/heads/main (%v)", head.Ref, head.Commit, commits[7])
+	}
+}
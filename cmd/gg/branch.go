@@ -15,11 +15,16 @@
 package main
 
 import (
+	"bytes"
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"sort"
 	"strings"
+	"time"
 
 	"gg-scm.io/pkg/git"
 	"gg-scm.io/pkg/git/object"
@@ -30,7 +35,10 @@ import (
 const branchSynopsis = "list or manage branches"
 
 func branch(ctx context.Context, cc *cmdContext, args []string) error {
-	f := flag.NewFlagSet(true, "gg branch [-d] [-f] [-r REV] [NAME [...]]", branchSynopsis+`
+	f := flag.NewFlagSet(true, "gg branch [-f] [-r REV] [NAME [...]]\n"+
+		"gg branch -d [-f] [--remote] NAME [...]\n"+
+		"gg branch [-v] [--sort=ORDER] [--date=STYLE] [--verify]\n"+
+		"gg branch --edit-description [NAME]", branchSynopsis+`
 
 	Branches are references to commits to help track lines of
 	development. Branches are unversioned and can be moved, renamed, and
@@ -40,14 +48,42 @@ func branch(ctx context.Context, cc *cmdContext, args []string) error {
 	When a commit is made, the active branch will advance to the new
 	commit. A plain `+"`gg update`"+` will also advance an active branch, if
 	possible. If the revision specifies a branch with an upstream, then
-	any new branch will use the named branch's upstream.`)
+	any new branch will use the named branch's upstream.
+
+	`+"`-d`"+` deletes the named branches. gg refuses to delete a branch
+	whose commits are not in its upstream (or in another local branch if
+	it has no upstream) unless `+"`-f`"+` is given. `+"`--remote`"+` also
+	deletes the branch of the same name from the repository that
+	`+"`gg push`"+` sends the branch to.
+
+	`+"`--edit-description`"+` opens an editor to change the description of
+	the named branch (or the current branch if none is given). The
+	description is stored in the `+"`branch.<name>.description`"+`
+	configuration setting, is shown when listing branches, and is used
+	as the start of the body for `+"`gg requestpull`"+`.
+
+	When listing, `+"`--verify`"+` checks the signature of each branch's
+	commit using Git's configured signing programs and shows whether it
+	is good, bad, or unknown along with the signer.
+
+	`+"`-v`"+` shows each branch's upstream, how many commits it is ahead
+	of and behind its upstream, and its open pull request, if any. Open
+	pull requests are looked up on GitHub for the `+"`origin`"+` remote if
+	you have logged in with `+"`gg github-login`"+`. The results are
+	cached for a few minutes to keep listing fast.`)
 	delete := f.Bool("d", false, "delete the given branches")
 	f.Alias("d", "delete")
+	editDescription := f.Bool("edit-description", false, "edit the description of the branch")
 	force := f.Bool("f", false, "force")
 	f.Alias("f", "force")
+	remote := f.Bool("remote", false, "with -d, also delete the branches from the push destination")
 	rev := f.String("r", "", "`rev`ision to place branches on")
 	ord := branchSortOrder{key: branchSortDate, dir: descending}
 	f.Var(&ord, "sort", "sort `order` when listing: 'name' or 'date'. May be prefixed by '-' for descending.")
+	date := f.String("date", "", "show author dates when listing in the given `style`: 'default', 'relative', 'iso', or 'local' (defaults to gg.dateFormat)")
+	verify := f.Bool("verify", false, "show the signature status of each branch's commit when listing")
+	verbose := f.Bool("v", false, "show upstream and pull request information when listing")
+	f.Alias("v", "verbose")
 	if err := f.Parse(args); flag.IsHelp(err) {
 		f.Help(cc.stdout)
 		return nil
@@ -55,6 +91,14 @@ func branch(ctx context.Context, cc *cmdContext, args []string) error {
 		return usagef("%v", err)
 	}
 	switch {
+	case *editDescription:
+		if *delete || *force || *rev != "" || *verify || *verbose || *remote {
+			return usagef("can't pass other options with --edit-description")
+		}
+		if f.NArg() > 1 {
+			return usagef("can only edit the description of one branch")
+		}
+		return editBranchDescription(ctx, cc, f.Arg(0))
 	case *delete:
 		if f.NArg() == 0 {
 			return usagef("must pass branch names to delete")
@@ -62,7 +106,12 @@ func branch(ctx context.Context, cc *cmdContext, args []string) error {
 		if *rev != "" {
 			return usagef("can't pass -r for delete")
 		}
-		return deleteBranches(ctx, cc.git, f.Args(), *force)
+		if *verify || *verbose {
+			return usagef("can't pass --verify or -v for delete")
+		}
+		return deleteBranches(ctx, cc, f.Args(), *force, *remote)
+	case *remote:
+		return usagef("can't pass --remote without -d")
 	case f.NArg() == 0:
 		// List
 		if *force {
@@ -71,14 +120,26 @@ func branch(ctx context.Context, cc *cmdContext, args []string) error {
 		if *rev != "" {
 			return usagef("can't pass -r without branch names")
 		}
-		return listBranches(ctx, cc, ord)
+		return listBranches(ctx, cc, ord, *date, *verify, *verbose)
 	default:
 		// Create or update
+		if *verify || *verbose {
+			return usagef("can't pass --verify or -v with branch names")
+		}
 		for _, b := range f.Args() {
 			if strings.HasPrefix(b, "-") {
 				return fmt.Errorf("invalid branch name %q", b)
 			}
 		}
+		if *rev == "" {
+			unborn, err := unbornBranch(ctx, cc.git)
+			if err != nil {
+				return err
+			}
+			if unborn != "" {
+				return renameUnbornBranch(ctx, cc.git, unborn, f.Args())
+			}
+		}
 		target := git.Head.String()
 		if *rev != "" {
 			target = *rev
@@ -130,7 +191,31 @@ func branch(ctx context.Context, cc *cmdContext, args []string) error {
 	return nil
 }
 
-func listBranches(ctx context.Context, cc *cmdContext, ord branchSortOrder) error {
+// branchListsOnly reports whether the arguments to gg branch only list
+// branches.
+func branchListsOnly(args []string) bool {
+	flags, npos := scanArgs(args, "r", "sort", "date")
+	return npos == 0 && !flags["d"] && !flags["delete"] && !flags["edit-description"]
+}
+
+// renameUnbornBranch points HEAD at a new branch when the current branch
+// does not have any commits yet. There is no commit for the new branch to
+// point to, so the new branch will be created by the first commit.
+func renameUnbornBranch(ctx context.Context, g *git.Git, unborn string, names []string) error {
+	if len(names) > 1 {
+		return fmt.Errorf("%s has no commits yet; can only switch to one new branch", unborn)
+	}
+	ref := git.BranchRef(names[0])
+	if !ref.IsValid() {
+		return fmt.Errorf("invalid branch name %q", names[0])
+	}
+	if _, err := g.ParseRev(ctx, ref.String()); err == nil {
+		return fmt.Errorf("branch %q already exists (use `gg update %s` to switch to it)", names[0], names[0])
+	}
+	return g.Run(ctx, "symbolic-ref", "HEAD", ref.String())
+}
+
+func listBranches(ctx context.Context, cc *cmdContext, ord branchSortOrder, dateFlag string, verify, verbose bool) error {
 	// Get color settings. Most errors can be ignored without impacting
 	// the command output.
 	var (
@@ -141,7 +226,13 @@ func listBranches(ctx context.Context, cc *cmdContext, ord branchSortOrder) erro
 	if err != nil {
 		return err
 	}
-	colorize, err := cfg.ColorBool("color.branch", terminal.IsTerminal(cc.stdout))
+	// Dates are only shown when asked for.
+	showDate := dateFlag != "" || cfg.Value("gg.dateFormat") != ""
+	style, err := dateStyle(cfg, dateFlag)
+	if err != nil {
+		return err
+	}
+	colorize, err := cfg.ColorBool("color.branch", cc.isTerminal())
 	if err != nil {
 		fmt.Fprintln(cc.stderr, "gg:", err)
 	} else if colorize {
@@ -194,12 +285,33 @@ func listBranches(ctx context.Context, cc *cmdContext, ord branchSortOrder) erro
 	default:
 		panic("unknown sort order")
 	}
+	var sigs map[git.Hash]commitSignature
+	if verify {
+		hashes := make([]git.Hash, 0, len(branches))
+		for _, b := range branches {
+			hashes = append(hashes, refs[b])
+		}
+		sigs, err = verifyCommitSignatures(ctx, cc, hashes)
+		if err != nil {
+			return err
+		}
+	}
+	var gcfg *ggConfig
+	var prs map[string]gitHubPullRequest
+	if verbose {
+		gcfg, err = readGGConfig(ctx, cc.git)
+		if err != nil {
+			return err
+		}
+		prs = cachedOpenPullRequests(ctx, cc, cfg, gcfg, true)
+	}
 
 	if colorize {
 		if err := terminal.ResetTextStyle(cc.stdout); err != nil {
 			return err
 		}
 	}
+	now := time.Now()
 	for i, b := range branches {
 		if i > 0 {
 			fmt.Fprintln(cc.stdout)
@@ -209,7 +321,41 @@ func listBranches(ctx context.Context, cc *cmdContext, ord branchSortOrder) erro
 			color, marker = currentColor, '*'
 		}
 		commit := commits[refs[b]]
-		_, err := fmt.Fprintf(cc.stdout, "%s%c %-30s %s %s\n    %s\n", color, marker, b.Branch(), refs[b].Short(), commit.Author.Name(), commit.Summary())
+		date := ""
+		if showDate {
+			date = " (" + style.Format(commit.AuthorTime, now) + ")"
+		}
+		_, err := fmt.Fprintf(cc.stdout, "%s%c %-30s %s %s%s\n    %s\n", color, marker, b.Branch(), refs[b].Short(), commit.Author.Name(), date, commit.Summary())
+		if err != nil {
+			return err
+		}
+		if desc := branchDescriptionSummary(cfg, b.Branch()); desc != "" {
+			if _, err := fmt.Fprintf(cc.stdout, "    description: %s\n", desc); err != nil {
+				return err
+			}
+		}
+		if verbose {
+			if err := writeBranchTracking(ctx, cc, cfg, gcfg, b.Branch(), prs); err != nil {
+				return err
+			}
+		}
+		if verify {
+			if _, err := fmt.Fprintf(cc.stdout, "    signature:   %v\n", sigs[refs[b]]); err != nil {
+				return err
+			}
+		}
+		if colorize {
+			if err := terminal.ResetTextStyle(cc.stdout); err != nil {
+				return err
+			}
+		}
+	}
+	if _, exists := refs[headRef]; headRef.IsBranch() && !exists {
+		// The current branch has no commits yet.
+		if len(branches) > 0 {
+			fmt.Fprintln(cc.stdout)
+		}
+		_, err := fmt.Fprintf(cc.stdout, "%s* %-30s (no commits yet)\n", currentColor, headRef.Branch())
 		if err != nil {
 			return err
 		}
@@ -222,6 +368,145 @@ func listBranches(ctx context.Context, cc *cmdContext, ord branchSortOrder) erro
 	return nil
 }
 
+// writeBranchTracking prints the upstream and open pull request of the
+// branch for gg branch -v.
+func writeBranchTracking(ctx context.Context, cc *cmdContext, cfg *git.Config, gcfg *ggConfig, name string, prs map[string]gitHubPullRequest) error {
+	if upstream := branchUpstream(cfg, name); upstream != "" {
+		status, err := describeUpstreamDistance(ctx, cc.git, name)
+		if err != nil {
+			return err
+		}
+		if _, err := fmt.Fprintf(cc.stdout, "    upstream:    %s (%s)\n", upstream, status); err != nil {
+			return err
+		}
+	}
+	pr, ok := branchPullRequest(cfg, gcfg, name, prs)
+	if !ok {
+		return nil
+	}
+	_, err := fmt.Fprintf(cc.stdout, "    pull:        #%d %s\n", pr.Number, pr.HTMLURL)
+	return err
+}
+
+// describeUpstreamDistance returns how far the branch is ahead of and
+// behind its upstream, like "2 ahead, 0 behind", or "gone" if the
+// upstream branch no longer exists.
+func describeUpstreamDistance(ctx context.Context, g *git.Git, name string) (string, error) {
+	if _, err := g.ParseRev(ctx, name+"@{upstream}"); err != nil {
+		return "gone", nil
+	}
+	counts, err := g.Output(ctx, "rev-list", "--left-right", "--count", name+"@{upstream}..."+git.BranchRef(name).String())
+	if err != nil {
+		return "", err
+	}
+	var behind, ahead int
+	if _, err := fmt.Sscan(counts, &behind, &ahead); err != nil {
+		return "", fmt.Errorf("parse rev-list counts: %w", err)
+	}
+	return fmt.Sprintf("%d ahead, %d behind", ahead, behind), nil
+}
+
+// branchPullRequest finds the branch's pull request in a map returned by
+// cachedOpenPullRequests.
+func branchPullRequest(cfg *git.Config, gcfg *ggConfig, name string, prs map[string]gitHubPullRequest) (gitHubPullRequest, bool) {
+	if len(prs) == 0 {
+		return gitHubPullRequest{}, false
+	}
+	headRemote, err := inferPushRepo(cfg, name)
+	if err != nil {
+		return gitHubPullRequest{}, false
+	}
+	headURL := cfg.Value("remote." + headRemote + ".pushurl")
+	if headURL == "" {
+		headURL = cfg.Value("remote." + headRemote + ".url")
+	}
+	_, headOwner, _ := parsePullRequestRemoteURL(gcfg, headURL)
+	pr, ok := prs[headOwner+":"+name]
+	return pr, ok
+}
+
+// branchPullRequestsCacheAge is how long gg branch -v uses its saved list
+// of open pull requests before asking GitHub again.
+const branchPullRequestsCacheAge = 5 * time.Minute
+
+// cachedOpenPullRequests returns the open GitHub pull requests for the
+// "origin" remote, keyed by head label ("owner:branch"). The list is
+// saved in the gg cache directory so that listing branches usually
+// doesn't need to talk to GitHub. If refresh is true and the saved list
+// is out of date, cachedOpenPullRequests fetches a new one. Any errors
+// are logged to stderr and cachedOpenPullRequests returns the most recent
+// list it has, if any. It returns nil if origin is not a GitHub repository
+// or if it needs to refresh and the user has not logged into GitHub.
+func cachedOpenPullRequests(ctx context.Context, cc *cmdContext, cfg *git.Config, gcfg *ggConfig, refresh bool) map[string]gitHubPullRequest {
+	forge, owner, repo := parsePullRequestRemoteURL(gcfg, cfg.Value("remote.origin.url"))
+	gh, ok := forge.(gitHubForge)
+	if !ok {
+		return nil
+	}
+	cacheKey := sha256.Sum256([]byte(gh.host + "/" + owner + "/" + repo))
+	cacheName := "pulls/" + hex.EncodeToString(cacheKey[:])
+	var cache struct {
+		Fetched time.Time
+		Pulls   []gitHubPullRequest
+	}
+	if f, err := cc.xdgDirs.openCache(cacheName); err == nil {
+		err = json.NewDecoder(f).Decode(&cache)
+		f.Close()
+		if err != nil {
+			cache.Fetched = time.Time{}
+			cache.Pulls = nil
+		}
+	}
+	if refresh && time.Since(cache.Fetched) >= branchPullRequestsCacheAge {
+		token, err := cc.xdgDirs.readConfig(gh.tokenFilename())
+		if err != nil {
+			// Not logged into GitHub.
+			return nil
+		}
+		pulls, err := listOpenPullRequests(ctx, cc.httpClient, listPullRequestsParams{
+			authToken: string(bytes.TrimSpace(token)),
+			apiRoot:   gh.apiRoot,
+			owner:     owner,
+			repo:      repo,
+		})
+		if err != nil {
+			fmt.Fprintln(cc.stderr, "gg:", err)
+		} else {
+			cache.Fetched = time.Now()
+			cache.Pulls = pulls
+			if err := writeJSONCache(cc.xdgDirs, cacheName, cache); err != nil {
+				fmt.Fprintln(cc.stderr, "gg:", err)
+			}
+		}
+	}
+	prs := make(map[string]gitHubPullRequest, len(cache.Pulls))
+	for _, pr := range cache.Pulls {
+		prs[pr.Head.Label] = pr
+	}
+	return prs
+}
+
+// writeJSONCache replaces the named cache file with the JSON encoding of v.
+func writeJSONCache(x *xdgDirs, name string, v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("create cache %s: %w", name, err)
+	}
+	f, err := x.createCache(name)
+	if err != nil {
+		return err
+	}
+	_, writeErr := f.Write(data)
+	closeErr := f.Close()
+	if writeErr != nil {
+		return fmt.Errorf("create cache %s: %w", name, writeErr)
+	}
+	if closeErr != nil {
+		return fmt.Errorf("create cache %s: %w", name, closeErr)
+	}
+	return nil
+}
+
 func refsCommitInfo(ctx context.Context, g *git.Git, refs map[git.Ref]git.Hash) (map[git.Hash]*object.Commit, error) {
 	if len(refs) == 0 {
 		return nil, nil
@@ -256,7 +541,12 @@ func refsCommitInfo(ctx context.Context, g *git.Git, refs map[git.Ref]git.Hash)
 	return commits, err
 }
 
-func deleteBranches(ctx context.Context, g *git.Git, branchNames []string, force bool) error {
+// deleteBranches deletes the given local branches. Unless force is true,
+// deleteBranches refuses to delete branches that have commits not found
+// in their upstream or, if they have no upstream, in another local branch.
+// If remote is true, deleteBranches also deletes the branches from their
+// push destinations.
+func deleteBranches(ctx context.Context, cc *cmdContext, branchNames []string, force, remote bool) error {
 	branchRefs := make([]git.Ref, 0, len(branchNames))
 	for _, name := range branchNames {
 		r := git.BranchRef(name)
@@ -265,7 +555,7 @@ func deleteBranches(ctx context.Context, g *git.Git, branchNames []string, force
 		}
 		branchRefs = append(branchRefs, r)
 	}
-	head, err := g.Head(ctx)
+	head, err := cc.git.Head(ctx)
 	if err != nil {
 		return err
 	}
@@ -276,18 +566,58 @@ func deleteBranches(ctx context.Context, g *git.Git, branchNames []string, force
 			}
 		}
 	}
-	allRefs, err := g.ListRefs(ctx)
+	allRefs, err := cc.git.ListRefs(ctx)
+	if err != nil {
+		return err
+	}
+	for _, ref := range branchRefs {
+		if _, exists := allRefs[ref]; !exists {
+			return fmt.Errorf("branch %q does not exist", ref.Branch())
+		}
+	}
+	cfg, err := cc.git.ReadConfig(ctx)
 	if err != nil {
 		return err
 	}
 	if !force {
 		for _, thisRef := range branchRefs {
-			others, err := branchesContaining(ctx, g, allRefs[thisRef].String())
+			useUpstream := true
+			if remote {
+				// Being merged into the upstream doesn't help if the
+				// upstream is also being deleted.
+				dstRepo, err := inferPushRepo(cfg, thisRef.Branch())
+				if err != nil {
+					return err
+				}
+				useUpstream = branchUpstream(cfg, thisRef.Branch()) != dstRepo+"/"+thisRef.Branch()
+			}
+			if err := checkBranchMerged(ctx, cc.git, cfg, thisRef, allRefs[thisRef], useUpstream); err != nil {
+				return err
+			}
+		}
+	}
+	if remote {
+		// Group the branches by destination so that each repository is
+		// only contacted once.
+		var dstRepos []string
+		remoteBranches := make(map[string][]string)
+		for _, ref := range branchRefs {
+			dstRepo, err := inferPushRepo(cfg, ref.Branch())
 			if err != nil {
 				return err
 			}
-			if len(others) <= 1 {
-				return fmt.Errorf("changes in branch %q are not merged into other branches; use --force to delete", thisRef.Branch())
+			if _, tracked := allRefs[git.Ref("refs/remotes/"+dstRepo+"/"+ref.Branch())]; !tracked {
+				fmt.Fprintf(cc.stderr, "gg: branch %q not found in %s; only deleting locally\n", ref.Branch(), dstRepo)
+				continue
+			}
+			if _, seen := remoteBranches[dstRepo]; !seen {
+				dstRepos = append(dstRepos, dstRepo)
+			}
+			remoteBranches[dstRepo] = append(remoteBranches[dstRepo], ref.Branch())
+		}
+		for _, dstRepo := range dstRepos {
+			if err := deleteRemoteBranches(ctx, cc, dstRepo, remoteBranches[dstRepo]); err != nil {
+				return err
 			}
 		}
 	}
@@ -295,12 +625,104 @@ func deleteBranches(ctx context.Context, g *git.Git, branchNames []string, force
 	for _, ref := range branchRefs {
 		muts[ref] = git.DeleteRefIfMatches(allRefs[ref].String())
 	}
-	if err := g.MutateRefs(ctx, muts); err != nil {
+	if err := cc.git.MutateRefs(ctx, muts); err != nil {
+		return err
+	}
+	return nil
+}
+
+// checkBranchMerged returns an error if the branch's commit is not
+// reachable from the branch's upstream or, if the branch has no upstream
+// or useUpstream is false, from any other local branch.
+func checkBranchMerged(ctx context.Context, g *git.Git, cfg *git.Config, ref git.Ref, commit git.Hash, useUpstream bool) error {
+	if upstream := branchUpstream(cfg, ref.Branch()); useUpstream && upstream != "" {
+		upstreamRev := ref.Branch() + "@{upstream}"
+		if _, err := g.ParseRev(ctx, upstreamRev); err == nil {
+			merged, err := g.IsAncestor(ctx, commit.String(), upstreamRev)
+			if err != nil {
+				return err
+			}
+			if !merged {
+				return fmt.Errorf("changes in branch %q are not merged into %s; use --force to delete", ref.Branch(), upstream)
+			}
+			return nil
+		}
+	}
+	others, err := branchesContaining(ctx, g, commit.String())
+	if err != nil {
 		return err
 	}
+	if len(others) <= 1 {
+		return fmt.Errorf("changes in branch %q are not merged into other branches; use --force to delete", ref.Branch())
+	}
 	return nil
 }
 
+// editBranchDescription opens an editor on the description of the
+// given branch and saves the result. An empty name uses the currently
+// checked out branch.
+func editBranchDescription(ctx context.Context, cc *cmdContext, name string) error {
+	if name == "" {
+		name = currentBranch(ctx, cc)
+		if name == "" {
+			return errors.New("no branch currently checked out; please specify a branch name")
+		}
+	} else if !git.BranchRef(name).IsValid() {
+		return fmt.Errorf("invalid branch name %q", name)
+	}
+	if _, err := cc.git.ParseRev(ctx, git.BranchRef(name).String()); err != nil {
+		return fmt.Errorf("branch %q: %w", name, err)
+	}
+	cfg, err := cc.git.ReadConfig(ctx)
+	if err != nil {
+		return err
+	}
+	commentChar, err := cfg.CommentChar()
+	if err != nil {
+		return err
+	}
+	msgBuf := new(bytes.Buffer)
+	msgBuf.WriteString(branchDescription(cfg, name))
+	if msgBuf.Len() > 0 && !bytes.HasSuffix(msgBuf.Bytes(), []byte("\n")) {
+		msgBuf.WriteByte('\n')
+	}
+	fmt.Fprintf(msgBuf, "\n%s Please edit the description for the branch\n", commentChar)
+	fmt.Fprintf(msgBuf, "%s   %s\n", commentChar, name)
+	fmt.Fprintf(msgBuf, "%s Lines starting with '%s' will be ignored.\n", commentChar, commentChar)
+	edited, err := cc.editor.open(ctx, "BRANCH_DESCRIPTION", msgBuf.Bytes())
+	if err != nil {
+		return err
+	}
+	desc := cleanupMessage(string(edited), commentChar)
+	key := "branch." + name + ".description"
+	if desc == "" {
+		if cfg.Value(key) == "" {
+			return nil
+		}
+		return cc.git.Run(ctx, "config", "--local", "--unset", key)
+	}
+	return cc.git.Run(ctx, "config", "--local", key, desc)
+}
+
+// branchDescription returns the description of the given branch or the
+// empty string if the branch has no description.
+func branchDescription(cfg *git.Config, name string) string {
+	if name == "" {
+		return ""
+	}
+	return strings.TrimSpace(cfg.Value("branch." + name + ".description"))
+}
+
+// branchDescriptionSummary returns the first line of the given branch's
+// description.
+func branchDescriptionSummary(cfg *git.Config, name string) string {
+	desc := branchDescription(cfg, name)
+	if i := strings.IndexByte(desc, '\n'); i != -1 {
+		desc = strings.TrimSpace(desc[:i])
+	}
+	return desc
+}
+
 func branchUpstream(cfg *git.Config, name string) string {
 	// TODO(soon): Remove this function; the branch command should copy
 	// the configuration directly.
@@ -15,10 +15,13 @@
 package main
 
 import (
+	"bytes"
 	"context"
+	"fmt"
 	"testing"
 
 	"gg-scm.io/pkg/git"
+	"gg-scm.io/tool/internal/escape"
 	"gg-scm.io/tool/internal/filesystem"
 )
 
@@ -110,6 +113,45 @@ func TestBranch_Upstream(t *testing.T) {
 	}
 }
 
+func TestBranch_EditDescription(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	env, err := newTestEnv(ctx, t)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := env.initRepoWithHistory(ctx, "."); err != nil {
+		t.Fatal(err)
+	}
+	const wantDescription = "Add the frobnicator\n\nThis is a longer explanation."
+	cmd, err := env.editorCmd([]byte(wantDescription + "\n# comment\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	config := fmt.Sprintf("[core]\neditor = %s\n", escape.GitConfig(cmd))
+	if err := env.writeConfig([]byte(config)); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := env.gg(ctx, env.root.String(), "branch", "--edit-description"); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := env.git.ReadConfig(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := branchDescription(cfg, "main"); got != wantDescription {
+		t.Errorf("branch.main.description = %q; want %q", got, wantDescription)
+	}
+	out, err := env.gg(ctx, env.root.String(), "branch")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Contains(out, []byte("description: Add the frobnicator\n")) {
+		t.Errorf("branch output does not contain description. Output:\n%s", out)
+	}
+}
+
 func TestBranch_Delete(t *testing.T) {
 	t.Parallel()
 	ctx := context.Background()
@@ -214,6 +256,62 @@ func TestBranch_Delete(t *testing.T) {
 	})
 }
 
+func TestBranch_DeleteRemote(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	env, err := newTestEnv(ctx, t)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := env.initRepoWithHistory(ctx, "repo1"); err != nil {
+		t.Fatal(err)
+	}
+	if err := env.git.Run(ctx, "clone", "repo1", "repo2"); err != nil {
+		t.Fatal(err)
+	}
+	repoPath2 := env.root.FromSlash("repo2")
+	git2 := env.git.WithDir(repoPath2)
+	if _, err := env.gg(ctx, repoPath2, "branch", "foo"); err != nil {
+		t.Fatal(err)
+	}
+	if err := env.root.Apply(filesystem.Write("repo2/foo.txt", dummyContent)); err != nil {
+		t.Fatal(err)
+	}
+	if err := env.addFiles(ctx, "repo2/foo.txt"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := env.newCommit(ctx, "repo2"); err != nil {
+		t.Fatal(err)
+	}
+	if err := git2.Run(ctx, "push", "--quiet", "origin", "foo"); err != nil {
+		t.Fatal(err)
+	}
+	if err := git2.CheckoutBranch(ctx, "main", git.CheckoutOptions{}); err != nil {
+		t.Fatal(err)
+	}
+
+	// foo has a commit that isn't in its upstream, origin/main.
+	if _, err := env.gg(ctx, repoPath2, "branch", "--delete", "--remote", "foo"); err == nil {
+		t.Error("gg branch --delete --remote did not return an error for unmerged branch")
+	} else if isUsage(err) {
+		t.Error(err)
+	}
+	if _, err := git2.ParseRev(ctx, "refs/heads/foo"); err != nil {
+		t.Error("refs/heads/foo deleted after failed delete:", err)
+	}
+
+	if _, err := env.gg(ctx, repoPath2, "branch", "--delete", "--force", "--remote", "foo"); err != nil {
+		t.Fatal(err)
+	}
+	if r, err := git2.ParseRev(ctx, "refs/heads/foo"); err == nil {
+		t.Errorf("refs/heads/foo = %v; should not exist", r.Commit)
+	}
+	git1 := env.git.WithDir(env.root.FromSlash("repo1"))
+	if r, err := git1.ParseRev(ctx, "refs/heads/foo"); err == nil {
+		t.Errorf("refs/heads/foo in origin = %v; should not exist", r.Commit)
+	}
+}
+
 func TestBranch_ListNewRepo(t *testing.T) {
 	t.Parallel()
 	ctx := context.Background()
@@ -230,7 +328,110 @@ func TestBranch_ListNewRepo(t *testing.T) {
 	if err != nil {
 		t.Error(err)
 	}
-	if len(out) > 0 {
-		t.Errorf("stdout = %q; want \"\"", out)
+	if want := fmt.Sprintf("* %-30s (no commits yet)\n", "main"); string(out) != want {
+		t.Errorf("stdout = %q; want %q", out, want)
+	}
+}
+
+func TestBranch_NewRepo(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	env, err := newTestEnv(ctx, t)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := env.initEmptyRepo(ctx, "."); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := env.gg(ctx, env.root.String(), "branch", "feature"); err != nil {
+		t.Fatal(err)
+	}
+	if ref, err := env.git.HeadRef(ctx); err != nil {
+		t.Fatal(err)
+	} else if ref != git.BranchRef("feature") {
+		t.Errorf("HEAD = %v; want refs/heads/feature", ref)
+	}
+	if err := env.root.Apply(filesystem.Write("foo.txt", dummyContent)); err != nil {
+		t.Fatal(err)
+	}
+	if err := env.addFiles(ctx, "foo.txt"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := env.newCommit(ctx, "."); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := env.git.ParseRev(ctx, "refs/heads/feature"); err != nil {
+		t.Error("after first commit:", err)
+	}
+	if _, err := env.git.ParseRev(ctx, "refs/heads/main"); err == nil {
+		t.Error("main was created by first commit")
+	}
+}
+
+func TestBranch_ListDate(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	env, err := newTestEnv(ctx, t)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := env.initRepoWithHistory(ctx, "."); err != nil {
+		t.Fatal(err)
+	}
+
+	out, err := env.gg(ctx, env.root.String(), "branch")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(out, []byte(")\n")) {
+		t.Errorf("branch output contains a date without --date. Output:\n%s", out)
+	}
+
+	out, err = env.gg(ctx, env.root.String(), "branch", "--date=relative")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Contains(out, []byte(" ago)\n")) {
+		t.Errorf("branch --date=relative output does not contain relative date. Output:\n%s", out)
+	}
+
+	if _, err := env.gg(ctx, env.root.String(), "branch", "--date=bogus"); err == nil {
+		t.Error("branch --date=bogus did not return an error")
+	} else if !isUsage(err) {
+		t.Errorf("branch --date=bogus error = %v; want usage", err)
+	}
+}
+
+func TestBranch_ListVerbose(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	env, err := newTestEnv(ctx, t)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := env.initRepoWithHistory(ctx, "repo1"); err != nil {
+		t.Fatal(err)
+	}
+	if err := env.git.Run(ctx, "clone", "repo1", "repo2"); err != nil {
+		t.Fatal(err)
+	}
+	if err := env.root.Apply(filesystem.Write("repo2/foo.txt", "local change\n")); err != nil {
+		t.Fatal(err)
+	}
+	if err := env.addFiles(ctx, "repo2/foo.txt"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := env.newCommit(ctx, "repo2"); err != nil {
+		t.Fatal(err)
+	}
+
+	out, err := env.gg(ctx, env.root.FromSlash("repo2"), "branch", "-v")
+	if err != nil {
+		t.Fatal(err)
+	}
+	const want = "    upstream:    origin/main (1 ahead, 0 behind)\n"
+	if !bytes.Contains(out, []byte(want)) {
+		t.Errorf("branch -v output:\n%s\nwant to contain %q", out, want)
 	}
 }
@@ -0,0 +1,179 @@
+// Copyright 2021 The gg Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"runtime"
+	"strconv"
+	"strings"
+
+	"gg-scm.io/pkg/git"
+	"gg-scm.io/tool/internal/escape"
+	"gg-scm.io/tool/internal/flag"
+	"gg-scm.io/tool/internal/sigterm"
+)
+
+const browseSynopsis = "open the repository's web page"
+
+func browse(ctx context.Context, cc *cmdContext, args []string) error {
+	f := flag.NewFlagSet(true, "gg browse [-n] [--remote NAME] [-r REV] [FILE[:LINE]]\n"+
+		"gg browse [-n] [--remote NAME] --pr", browseSynopsis+`
+
+	browse opens the web page of the repository on GitHub, GitLab, or
+	Gitea in your web browser. With `+"`-r`"+`, it opens the page of the
+	given commit. With a file argument, it opens the file as of the
+	revision given by `+"`-r`"+` (defaults to the working copy's commit),
+	optionally scrolled to a line. `+"`--pr`"+` opens the list of open pull
+	requests from the current branch. The commit must already be pushed
+	for the page to exist.
+
+	The page is opened in the browser named by the `+"`BROWSER`"+`
+	environment variable or else the system's default browser. Pass
+	`+"`-n`"+` to print the URL instead.
+
+	The repository is found from the URL of the `+"`origin`"+` remote (or
+	the only remote) unless `+"`--remote`"+` is given. Self-hosted
+	instances are recognized using the same configuration settings as
+	`+"`gg requestpull`"+`.`)
+	printOnly := f.Bool("n", false, "print the URL instead of opening it")
+	f.Alias("n", "print")
+	pr := f.Bool("pr", false, "open the pull requests for the current branch")
+	remoteFlag := f.String("remote", "", "`name` of the remote to browse")
+	rev := f.String("r", "", "`rev`ision to show")
+	if err := f.Parse(args); flag.IsHelp(err) {
+		f.Help(cc.stdout)
+		return nil
+	} else if err != nil {
+		return usagef("%v", err)
+	}
+	if f.NArg() > 1 {
+		return usagef("can only pass one file")
+	}
+	if *pr && (*rev != "" || f.NArg() > 0) {
+		return usagef("can't pass -r or a file with --pr")
+	}
+	cfg, err := cc.git.ReadConfig(ctx)
+	if err != nil {
+		return err
+	}
+	remote := *remoteFlag
+	if remote == "" {
+		remote = defaultForgeRemote(cfg)
+	}
+	if cfg.ListRemotes()[remote] == nil {
+		return fmt.Errorf("no remote named %q found", remote)
+	}
+	remoteURL := cfg.Value("remote." + remote + ".url")
+	gcfg, err := readGGConfig(ctx, cc.git)
+	if err != nil {
+		return err
+	}
+	pages := newForgeWebPages(gcfg, remoteURL)
+	if pages == nil {
+		return fmt.Errorf("%s is not a GitHub, GitLab, or Gitea repository", remoteURL)
+	}
+
+	var u string
+	switch {
+	case *pr:
+		branch := currentBranch(ctx, cc)
+		if branch == "" {
+			return errors.New("no branch checked out")
+		}
+		u = pages.pullRequests(branch)
+	case f.NArg() > 0:
+		name, line := splitFileLine(f.Arg(0))
+		worktree, err := cc.git.WorkTree(ctx)
+		if err != nil {
+			return err
+		}
+		path, err := worktreeRelativePath(cc, worktree, name)
+		if err != nil {
+			return err
+		}
+		r := *rev
+		if r == "" {
+			r = git.Head.String()
+		}
+		c, err := cc.git.ParseRev(ctx, r)
+		if err != nil {
+			return err
+		}
+		u = pages.file(c.Commit, path, line)
+	case *rev != "":
+		c, err := cc.git.ParseRev(ctx, *rev)
+		if err != nil {
+			return err
+		}
+		u = pages.commit(c.Commit)
+	default:
+		u = pages.base
+	}
+	if *printOnly {
+		_, err := fmt.Fprintln(cc.stdout, u)
+		return err
+	}
+	return openBrowser(ctx, cc, u)
+}
+
+// splitFileLine splits a "FILE:LINE" argument. If the argument does not
+// end in a line number, line is zero.
+func splitFileLine(arg string) (name string, line int) {
+	i := strings.LastIndexByte(arg, ':')
+	if i == -1 {
+		return arg, 0
+	}
+	n, err := strconv.Atoi(arg[i+1:])
+	if err != nil || n <= 0 {
+		return arg, 0
+	}
+	return arg[:i], n
+}
+
+// openBrowser opens u in the browser named by the BROWSER environment
+// variable or the system's default browser.
+func openBrowser(ctx context.Context, cc *cmdContext, u string) error {
+	var c *exec.Cmd
+	if browser := getenv(cc.env, "BROWSER"); browser != "" {
+		var err error
+		c, err = bashCommand(cc.git.Exe(), browser+" "+escape.Bash(u))
+		if err != nil {
+			return fmt.Errorf("open browser: %w", err)
+		}
+	} else {
+		switch runtime.GOOS {
+		case "darwin":
+			c = exec.Command("open", u)
+		case "windows":
+			c = exec.Command("rundll32", "url.dll,FileProtocolHandler", u)
+		default:
+			c = exec.Command("xdg-open", u)
+		}
+	}
+	c.Stdout = cc.stdout
+	c.Stderr = cc.stderr
+	c.Env = cc.env
+	if len(c.Env) == 0 {
+		c.Env = []string{} // force empty
+	}
+	if err := sigterm.Run(ctx, c); err != nil {
+		return fmt.Errorf("open browser: %w (run with -n to print the URL)", err)
+	}
+	return nil
+}
@@ -0,0 +1,69 @@
+// Copyright 2021 The gg Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"testing"
+
+	"gg-scm.io/tool/internal/filesystem"
+)
+
+func TestBrowse(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	env, err := newTestEnv(ctx, t)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := env.initEmptyRepo(ctx, "repo"); err != nil {
+		t.Fatal(err)
+	}
+	if err := env.root.Apply(filesystem.Write("repo/dir/foo.txt", dummyContent)); err != nil {
+		t.Fatal(err)
+	}
+	if err := env.addFiles(ctx, "repo/dir/foo.txt"); err != nil {
+		t.Fatal(err)
+	}
+	commit, err := env.newCommit(ctx, "repo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	repoPath := env.root.FromSlash("repo")
+	if err := env.git.WithDir(repoPath).Run(ctx, "remote", "add", "origin", "https://github.com/example/foo.git"); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		dir  string
+		args []string
+		want string
+	}{
+		{dir: "repo", args: nil, want: "https://github.com/example/foo\n"},
+		{dir: "repo", args: []string{"-r", "HEAD"}, want: "https://github.com/example/foo/commit/" + commit.String() + "\n"},
+		{dir: "repo/dir", args: []string{"foo.txt:3"}, want: "https://github.com/example/foo/blob/" + commit.String() + "/dir/foo.txt#L3\n"},
+	}
+	for _, test := range tests {
+		args := append([]string{"browse", "-n"}, test.args...)
+		out, err := env.gg(ctx, env.root.FromSlash(test.dir), args...)
+		if err != nil {
+			t.Errorf("gg %q: %v", args, err)
+			continue
+		}
+		if got := string(out); got != test.want {
+			t.Errorf("gg %q = %q; want %q", args, got, test.want)
+		}
+	}
+}
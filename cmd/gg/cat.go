@@ -18,6 +18,7 @@ import (
 	"context"
 	"fmt"
 	"io"
+	"strings"
 
 	"gg-scm.io/pkg/git"
 	"gg-scm.io/tool/internal/flag"
@@ -26,11 +27,20 @@ import (
 const catSynopsis = "output the current or given revision of files"
 
 func cat(ctx context.Context, cc *cmdContext, args []string) error {
-	f := flag.NewFlagSet(true, "gg cat [-r REV] FILE [...]", catSynopsis+`
+	f := flag.NewFlagSet(true, "gg cat [-r REV] [--smudge | --raw] FILE [...]", catSynopsis+`
 
 	Print the specified files as they were at the given revision. If no
-	revision is given, HEAD is used.`)
+	revision is given, HEAD is used.
+
+	Files that have a `+"`filter`"+` attribute (like files stored with
+	Git LFS) are run through their smudge filters, so the output is the
+	same as what would be checked out. Attributes are read from the
+	working copy. `+"`--raw`"+` prints the content as stored in the
+	repository instead, and `+"`--smudge`"+` runs the filters and
+	end-of-line conversions for every file.`)
 	r := f.String("r", git.Head.String(), "print the `rev`ision")
+	smudge := f.Bool("smudge", false, "always run smudge filters and end-of-line conversion")
+	raw := f.Bool("raw", false, "print files as stored, without running filters")
 	if err := f.Parse(args); flag.IsHelp(err) {
 		f.Help(cc.stdout)
 		return nil
@@ -40,39 +50,94 @@ func cat(ctx context.Context, cc *cmdContext, args []string) error {
 	if f.NArg() == 0 {
 		return usagef("must pass one or more files to cat")
 	}
+	if *smudge && *raw {
+		return usagef("can't pass both --smudge and --raw")
+	}
 	rev, err := cc.git.ParseRev(ctx, *r)
 	if err != nil {
 		return err
 	}
+	mode := catAuto
+	if *smudge {
+		mode = catSmudge
+	} else if *raw {
+		mode = catRaw
+	}
+	topPaths := make([]git.TopPath, 0, f.NArg())
 	for _, arg := range f.Args() {
-		if err := catFile(ctx, cc, rev, arg); err != nil {
+		p, err := catPath(ctx, cc.git, rev, arg)
+		if err != nil {
+			return err
+		}
+		topPaths = append(topPaths, p)
+	}
+	var filtered map[git.TopPath]bool
+	if mode == catAuto {
+		filtered, err = filterAttributes(ctx, cc, topPaths)
+		if err != nil {
+			return err
+		}
+	}
+	for i, p := range topPaths {
+		fileMode := mode
+		if fileMode == catAuto && filtered[p] {
+			fileMode = catSmudge
+		}
+		if err := catFile(ctx, cc, rev, f.Arg(i), p, fileMode); err != nil {
 			return err
 		}
 	}
 	return nil
 }
 
-func catFile(ctx context.Context, cc *cmdContext, rev *git.Rev, path string) error {
-	// Find path relative to top of repository.
-	paths, err := cc.git.ListTree(ctx, rev.Commit.String(), git.ListTreeOptions{
+// catMode determines whether catFile runs filters on file content.
+type catMode int
+
+const (
+	catAuto   catMode = iota // filter files that have a filter attribute
+	catSmudge                // always filter
+	catRaw                   // never filter
+)
+
+// catPath finds the path of a file in rev relative to the top of the
+// repository.
+func catPath(ctx context.Context, g *git.Git, rev *git.Rev, path string) (git.TopPath, error) {
+	paths, err := g.ListTree(ctx, rev.Commit.String(), git.ListTreeOptions{
 		NameOnly:  true,
 		Recursive: true,
 		Pathspecs: []git.Pathspec{git.LiteralPath(path)},
 	})
 	if err != nil {
-		return err
+		return "", err
 	}
 	if len(paths) == 0 {
-		return fmt.Errorf("%s does not exist at %v", path, rev.Commit)
+		return "", fmt.Errorf("%s does not exist at %v", path, rev.Commit)
 	}
 	if len(paths) > 1 {
-		return fmt.Errorf("%s names multiple paths at %v", path, rev.Commit)
+		return "", fmt.Errorf("%s names multiple paths at %v", path, rev.Commit)
 	}
 	var topPath git.TopPath
 	for p := range paths {
 		// Guaranteed to be one iteration.
 		topPath = p
 	}
+	return topPath, nil
+}
+
+// catFile prints the file at topPath in rev. mode must not be catAuto.
+func catFile(ctx context.Context, cc *cmdContext, rev *git.Rev, path string, topPath git.TopPath, mode catMode) error {
+	if mode == catSmudge {
+		err := cc.git.Runner().RunGit(ctx, &git.Invocation{
+			Args:   []string{"cat-file", "--filters", rev.Commit.String() + ":" + topPath.String()},
+			Dir:    cc.dir,
+			Stdout: cc.stdout,
+			Stderr: cc.stderr,
+		})
+		if err != nil {
+			return fmt.Errorf("cat %s: %w", path, err)
+		}
+		return nil
+	}
 
 	// Send file to stdout.
 	r, err := cc.git.Cat(ctx, rev.Commit.String(), git.TopPath(topPath))
@@ -89,3 +154,42 @@ func catFile(ctx context.Context, cc *cmdContext, rev *git.Rev, path string) err
 	}
 	return nil
 }
+
+// filterAttributes reports which of the files have a filter driver set
+// in the repository's attributes, using a single git check-attr. In a
+// bare repository, there is no working copy to read attributes from, so
+// Git only uses the repository-wide attributes.
+func filterAttributes(ctx context.Context, cc *cmdContext, paths []git.TopPath) (map[git.TopPath]bool, error) {
+	// check-attr interprets paths relative to the current directory.
+	dir := cc.dir
+	if top, err := cc.git.WorkTree(ctx); err == nil {
+		dir = top
+	}
+	stdin := new(strings.Builder)
+	for _, p := range paths {
+		stdin.WriteString(p.String())
+		stdin.WriteByte(0)
+	}
+	stdout := new(strings.Builder)
+	err := cc.git.Runner().RunGit(ctx, &git.Invocation{
+		Args:   []string{"check-attr", "-z", "--stdin", "filter"},
+		Dir:    dir,
+		Stdin:  strings.NewReader(stdin.String()),
+		Stdout: stdout,
+		Stderr: cc.stderr,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("check attributes: %w", err)
+	}
+	// Output is a sequence of NUL-terminated (path, attribute, value) triples.
+	fields := strings.Split(stdout.String(), "\x00")
+	filtered := make(map[git.TopPath]bool, len(paths))
+	for i := 0; i+2 < len(fields); i += 3 {
+		switch fields[i+2] {
+		case "unspecified", "unset", "set":
+		default:
+			filtered[git.TopPath(fields[i])] = true
+		}
+	}
+	return filtered, nil
+}
@@ -110,3 +110,69 @@ func TestCat(t *testing.T) {
 		})
 	}
 }
+
+func TestCat_Filters(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	env, err := newTestEnv(ctx, t)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := env.writeConfig([]byte("[filter \"upper\"]\nsmudge = tr a-z A-Z\n")); err != nil {
+		t.Fatal(err)
+	}
+	if err := env.initEmptyRepo(ctx, "."); err != nil {
+		t.Fatal(err)
+	}
+	err = env.root.Apply(
+		filesystem.Write(".gitattributes", "*.up filter=upper\n"),
+		filesystem.Write("foo.up", "hello\n"),
+		filesystem.Write("bar.txt", "hello\n"),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := env.addFiles(ctx, "."); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := env.newCommit(ctx, "."); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		args []string
+		want string
+	}{
+		{args: []string{"foo.up"}, want: "HELLO\n"},
+		{args: []string{"--raw", "foo.up"}, want: "hello\n"},
+		{args: []string{"bar.txt"}, want: "hello\n"},
+		{args: []string{"--smudge", "bar.txt"}, want: "hello\n"},
+		{args: []string{"foo.up", "bar.txt"}, want: "HELLO\nhello\n"},
+	}
+	for _, test := range tests {
+		out, err := env.gg(ctx, env.root.String(), append([]string{"cat"}, test.args...)...)
+		if err != nil {
+			t.Errorf("gg cat %q: %v", test.args, err)
+			continue
+		}
+		if string(out) != test.want {
+			t.Errorf("gg cat %q = %q; want %q", test.args, out, test.want)
+		}
+	}
+
+	// A bare repository has no working copy, so its attributes come from
+	// the repository-wide attributes file.
+	if err := env.git.Run(ctx, "clone", "--quiet", "--bare", ".", "bare.git"); err != nil {
+		t.Fatal(err)
+	}
+	if err := env.root.Apply(filesystem.Write("bare.git/info/attributes", "*.up filter=upper\n")); err != nil {
+		t.Fatal(err)
+	}
+	out, err := env.gg(ctx, env.root.FromSlash("bare.git"), "cat", "foo.up")
+	if err != nil {
+		t.Fatal("gg cat in bare repository:", err)
+	}
+	if want := "HELLO\n"; string(out) != want {
+		t.Errorf("gg cat foo.up in bare repository = %q; want %q", out, want)
+	}
+}
@@ -21,14 +21,31 @@ import (
 
 	"gg-scm.io/pkg/git"
 	"gg-scm.io/tool/internal/flag"
+	"gg-scm.io/tool/internal/terminal"
 )
 
 const cloneSynopsis = "make a copy of an existing repository"
 
 func clone(ctx context.Context, cc *cmdContext, args []string) error {
-	f := flag.NewFlagSet(true, "gg clone [-b BRANCH] SOURCE [DEST]", cloneSynopsis)
+	f := flag.NewFlagSet(true, "gg clone [-b BRANCH] [--depth N | --shallow-since DATE] [--filter SPEC] [--bare] SOURCE [DEST]", cloneSynopsis+`
+
+	Every branch in the source repository gets a local branch of the same
+	name that tracks it, so `+"`gg pull`"+` and `+"`gg push`"+` work on any
+	branch without further setup.
+
+	`+"`--depth`"+` creates a shallow clone with the given number of
+	commits of history on each branch, and `+"`--shallow-since`"+` creates
+	a shallow clone with the commits after a date. Pass `+"`--deepen`"+` or
+	`+"`--unshallow`"+` to `+"`gg pull`"+` to fetch more history later.
+	`+"`--filter=blob:none`"+` creates a partial clone that downloads file
+	contents on demand. `+"`--bare`"+` creates a repository without a
+	working copy that has the source's branches as its own.`)
 	branch := f.String("b", git.Head.String(), "`branch` to check out")
 	f.Alias("b", "branch")
+	depth := f.Int("depth", 0, "truncate history to the given `number` of commits")
+	shallowSince := f.String("shallow-since", "", "truncate history to commits after `date`")
+	filter := f.String("filter", "", "only fetch objects matching the partial clone filter `spec` (e.g. blob:none)")
+	bare := f.Bool("bare", false, "create a bare repository")
 	gerrit := f.Bool("gerrit", false, "install Gerrit hook")
 	gerritHookURL := f.String("gerrit-hook-url", commitMsgHookDefaultURL, "URL of hook script to download")
 	if err := f.Parse(args); flag.IsHelp(err) {
@@ -43,22 +60,54 @@ func clone(ctx context.Context, cc *cmdContext, args []string) error {
 	if f.NArg() > 2 {
 		return usagef("can't pass more than one destination")
 	}
+	if *depth < 0 {
+		return usagef("--depth must not be negative")
+	}
+	if *depth > 0 && *shallowSince != "" {
+		return usagef("can't pass both --depth and --shallow-since")
+	}
 	src, dst := f.Arg(0), f.Arg(1)
 	if dst == "" {
 		dst = defaultCloneDest(src)
-	}
-	if *branch == git.Head.String() {
-		err := cc.interactiveGit(ctx, "clone", "--", src, dst)
-		if err != nil {
-			return err
-		}
-	} else {
-		err := cc.interactiveGit(ctx, "clone", "--branch="+*branch, "--", src, dst)
-		if err != nil {
-			return err
+		if *bare {
+			dst += ".git"
 		}
 	}
+	cloneArgs := []string{"clone"}
+	if terminal.IsTerminal(cc.stderr) {
+		cloneArgs = append(cloneArgs, "--progress")
+	}
+	if *branch != git.Head.String() {
+		cloneArgs = append(cloneArgs, "--branch="+*branch)
+	}
+	if *depth > 0 {
+		cloneArgs = append(cloneArgs, fmt.Sprintf("--depth=%d", *depth))
+	}
+	if *shallowSince != "" {
+		cloneArgs = append(cloneArgs, "--shallow-since="+*shallowSince)
+	}
+	if *depth > 0 || *shallowSince != "" {
+		// Shallow clones imply --single-branch, but gg wants all branches.
+		cloneArgs = append(cloneArgs, "--no-single-branch")
+	}
+	if *filter != "" {
+		cloneArgs = append(cloneArgs, "--filter="+*filter)
+	}
+	if *bare {
+		cloneArgs = append(cloneArgs, "--bare")
+	}
+	cloneArgs = append(cloneArgs, "--", src, dst)
+	if err := cc.interactiveGit(ctx, cloneArgs...); err != nil {
+		return err
+	}
 	cc = cc.withDir(dst)
+	if *bare {
+		// Bare clones copy the source's branches directly.
+		if *gerrit {
+			return installGerritHook(ctx, cc, *gerritHookURL, false)
+		}
+		return nil
+	}
 	refs, err := cc.git.ListRefs(ctx)
 	if err != nil {
 		return err
@@ -87,6 +136,7 @@ func clone(ctx context.Context, cc *cmdContext, args []string) error {
 			if err != nil {
 				return fmt.Errorf("mirroring local branch %q: %v", name, err)
 			}
+			fmt.Fprintf(cc.stderr, "gg: created branch %s tracking origin/%s\n", name, name)
 		}
 	}
 	if *gerrit {
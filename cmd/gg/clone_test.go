@@ -146,6 +146,47 @@ func TestClone_Branch(t *testing.T) {
 	}
 }
 
+func TestClone_Bare(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	env, err := newTestEnv(ctx, t)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := env.initRepoWithHistory(ctx, "repoA"); err != nil {
+		t.Fatal(err)
+	}
+	gitA := env.git.WithDir(env.root.FromSlash("repoA"))
+	head, err := gitA.Head(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := gitA.NewBranch(ctx, "foo", git.BranchOptions{}); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := env.gg(ctx, env.root.String(), "clone", "--bare", "repoA"); err != nil {
+		t.Fatal(err)
+	}
+	gitB := env.git.WithDir(env.root.FromSlash("repoA.git"))
+	for _, ref := range []string{"refs/heads/main", "refs/heads/foo"} {
+		if r, err := gitB.ParseRev(ctx, ref); err != nil {
+			t.Error(err)
+		} else if r.Commit != head.Commit {
+			t.Errorf("%s = %s; want %s", ref, r.Commit, head.Commit)
+		}
+	}
+	cfg, err := gitB.ReadConfig(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bare, err := cfg.Bool("core.bare"); err != nil {
+		t.Error(err)
+	} else if !bare {
+		t.Error("core.bare = false; want true")
+	}
+}
+
 func TestDefaultCloneDest(t *testing.T) {
 	tests := []struct {
 		url  string
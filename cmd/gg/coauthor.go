@@ -0,0 +1,158 @@
+// Copyright 2021 The gg Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// coauthorsFilename is the name of the co-author roster file inside the
+// gg configuration directory. The gg.coauthors setting overrides it.
+const coauthorsFilename = "coauthors"
+
+// coauthorTrailer is the commit message trailer used to credit co-authors.
+const coauthorTrailer = "Co-authored-by"
+
+// rosterEntry is a single line of a co-author roster file.
+type rosterEntry struct {
+	alias string
+	ident string // "Name <email>"
+}
+
+// resolveCoauthors converts --coauthor arguments into "Name <email>"
+// identities. Arguments that are not already in that form are looked up
+// in the co-author roster by alias or email address.
+func resolveCoauthors(ctx context.Context, cc *cmdContext, args []string) ([]string, error) {
+	var roster []rosterEntry
+	var rosterPath string
+	var idents []string
+	for _, arg := range args {
+		arg = strings.TrimSpace(arg)
+		if isIdent(arg) {
+			idents = append(idents, arg)
+			continue
+		}
+		if rosterPath == "" {
+			var err error
+			roster, rosterPath, err = readCoauthorRoster(ctx, cc)
+			if err != nil {
+				return nil, fmt.Errorf("co-author %q: %w", arg, err)
+			}
+		}
+		ident := lookupCoauthor(roster, arg)
+		if ident == "" {
+			return nil, fmt.Errorf("co-author %q not found in %s", arg, rosterPath)
+		}
+		idents = append(idents, ident)
+	}
+	return idents, nil
+}
+
+// isIdent reports whether s has the form "Name <email>".
+func isIdent(s string) bool {
+	i := strings.IndexByte(s, '<')
+	return i > 0 && strings.HasSuffix(s, ">") && strings.TrimSpace(s[:i]) != ""
+}
+
+// lookupCoauthor finds the identity in the roster with the given alias
+// or email address. It returns the empty string if no entry matches.
+func lookupCoauthor(roster []rosterEntry, key string) string {
+	for _, ent := range roster {
+		if ent.alias == key {
+			return ent.ident
+		}
+	}
+	for _, ent := range roster {
+		i := strings.LastIndexByte(ent.ident, '<')
+		if strings.EqualFold(ent.ident[i+1:len(ent.ident)-1], key) {
+			return ent.ident
+		}
+	}
+	return ""
+}
+
+// readCoauthorRoster reads the file named by gg.coauthors or the
+// default roster file in the gg configuration directory. It also returns
+// the path of the file for use in messages.
+func readCoauthorRoster(ctx context.Context, cc *cmdContext) ([]rosterEntry, string, error) {
+	cfg, err := readGGConfig(ctx, cc.git)
+	if err != nil {
+		return nil, "", err
+	}
+	var data []byte
+	path := cfg.trusted().Path("gg.coauthors")
+	if path != "" {
+		path = cc.abs(path)
+		data, err = ioutil.ReadFile(path)
+	} else {
+		path = filepath.Join("$XDG_CONFIG_HOME", configDirname, coauthorsFilename)
+		data, err = cc.xdgDirs.readConfig(coauthorsFilename)
+		if os.IsNotExist(err) {
+			return nil, path, fmt.Errorf("no co-author roster (create %s or set gg.coauthors)", path)
+		}
+	}
+	if err != nil {
+		return nil, path, err
+	}
+	roster, err := parseCoauthorRoster(data)
+	if err != nil {
+		return nil, path, fmt.Errorf("%s: %w", path, err)
+	}
+	return roster, path, nil
+}
+
+// parseCoauthorRoster parses a co-author roster file. Each non-blank line
+// that does not start with '#' has an alias followed by an identity:
+//
+//	jdoe Jane Doe <jane@example.com>
+func parseCoauthorRoster(data []byte) ([]rosterEntry, error) {
+	var roster []rosterEntry
+	s := bufio.NewScanner(bytes.NewReader(data))
+	for lineno := 1; s.Scan(); lineno++ {
+		line := strings.TrimSpace(s.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		i := strings.IndexAny(line, " \t")
+		if i == -1 {
+			return nil, fmt.Errorf("line %d: missing identity after alias", lineno)
+		}
+		ent := rosterEntry{
+			alias: line[:i],
+			ident: strings.TrimSpace(line[i+1:]),
+		}
+		if !isIdent(ent.ident) {
+			return nil, fmt.Errorf("line %d: %q is not of the form \"Name <email>\"", lineno, ent.ident)
+		}
+		roster = append(roster, ent)
+	}
+	if err := s.Err(); err != nil {
+		return nil, err
+	}
+	return roster, nil
+}
+
+// addCoauthorTrailers appends a Co-authored-by trailer to the message
+// for each identity that the message does not already credit.
+func addCoauthorTrailers(msg string, idents []string) string {
+	return addTrailers(msg, coauthorTrailer, idents)
+}
@@ -0,0 +1,125 @@
+// Copyright 2021 The gg Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"testing"
+
+	"gg-scm.io/tool/internal/filesystem"
+)
+
+func TestCommit_Coauthor(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	env, err := newTestEnv(ctx, t)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := env.initRepoWithHistory(ctx, "."); err != nil {
+		t.Fatal(err)
+	}
+	const roster = "# Team roster\n" +
+		"jdoe Jane Doe <jane@example.com>\n"
+	if err := env.topDir.Apply(filesystem.Write("xdgconfig/gg/coauthors", roster)); err != nil {
+		t.Fatal(err)
+	}
+	if err := env.root.Apply(filesystem.Write("foo.txt", dummyContent)); err != nil {
+		t.Fatal(err)
+	}
+	if err := env.trackFiles(ctx, "foo.txt"); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = env.gg(ctx, env.root.String(), "commit",
+		"-m", "Pair on foo",
+		"--coauthor=jdoe",
+		"--coauthor=Alex Smith <alex@example.com>")
+	if err != nil {
+		t.Fatal(err)
+	}
+	info, err := env.git.CommitInfo(ctx, "HEAD")
+	if err != nil {
+		t.Fatal(err)
+	}
+	const want = "Pair on foo\n\n" +
+		"Co-authored-by: Jane Doe <jane@example.com>\n" +
+		"Co-authored-by: Alex Smith <alex@example.com>\n"
+	if info.Message != want {
+		t.Errorf("message = %q; want %q", info.Message, want)
+	}
+
+	if _, err := env.gg(ctx, env.root.String(), "commit", "-m", "nope", "--coauthor=nobody"); err == nil {
+		t.Error("gg commit --coauthor=nobody did not return an error")
+	}
+}
+
+func TestAddCoauthorTrailers(t *testing.T) {
+	tests := []struct {
+		msg    string
+		idents []string
+		want   string
+	}{
+		{
+			msg:  "Summary\n",
+			want: "Summary\n",
+		},
+		{
+			msg:    "Summary\n",
+			idents: []string{"Jane Doe <jane@example.com>"},
+			want:   "Summary\n\nCo-authored-by: Jane Doe <jane@example.com>\n",
+		},
+		{
+			msg:    "Summary\n\nFixes #123\n",
+			idents: []string{"Jane Doe <jane@example.com>"},
+			want:   "Summary\n\nFixes #123\n\nCo-authored-by: Jane Doe <jane@example.com>\n",
+		},
+		{
+			msg:    "Summary\n\nBody.\n\nSigned-off-by: Alex <alex@example.com>\n",
+			idents: []string{"Jane Doe <jane@example.com>"},
+			want:   "Summary\n\nBody.\n\nSigned-off-by: Alex <alex@example.com>\nCo-authored-by: Jane Doe <jane@example.com>\n",
+		},
+		{
+			msg:    "Summary\n\nCo-authored-by: Jane Doe <jane@example.com>\n",
+			idents: []string{"Jane Doe <jane@example.com>"},
+			want:   "Summary\n\nCo-authored-by: Jane Doe <jane@example.com>\n",
+		},
+	}
+	for _, test := range tests {
+		if got := addCoauthorTrailers(test.msg, test.idents); got != test.want {
+			t.Errorf("addCoauthorTrailers(%q, %q) = %q; want %q", test.msg, test.idents, got, test.want)
+		}
+	}
+}
+
+func TestParseCoauthorRoster(t *testing.T) {
+	roster, err := parseCoauthorRoster([]byte("# comment\n\njdoe  Jane Doe <jane@example.com>\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := lookupCoauthor(roster, "jdoe"); got != "Jane Doe <jane@example.com>" {
+		t.Errorf("lookupCoauthor(roster, \"jdoe\") = %q; want \"Jane Doe <jane@example.com>\"", got)
+	}
+	if got := lookupCoauthor(roster, "JANE@example.com"); got != "Jane Doe <jane@example.com>" {
+		t.Errorf("lookupCoauthor(roster, \"JANE@example.com\") = %q; want \"Jane Doe <jane@example.com>\"", got)
+	}
+	if got := lookupCoauthor(roster, "alex"); got != "" {
+		t.Errorf("lookupCoauthor(roster, \"alex\") = %q; want \"\"", got)
+	}
+
+	if _, err := parseCoauthorRoster([]byte("jdoe\n")); err == nil {
+		t.Error("parseCoauthorRoster accepted line without identity")
+	}
+}
@@ -0,0 +1,541 @@
+// Copyright 2021 The gg Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"gg-scm.io/tool/internal/flag"
+)
+
+// A command is a gg subcommand.
+type command struct {
+	name    string
+	aliases []string
+	run     func(ctx context.Context, cc *cmdContext, args []string) error
+
+	// synopsis is the one-line description listed in gg --help.
+	// Commands without a synopsis are left out of the list.
+	synopsis string
+	// basic is true if gg --help lists the command under the basic
+	// commands instead of the advanced ones.
+	basic bool
+
+	// modifiesRepo is true if the command can change refs, the index, or
+	// the working copy and so must hold the repository lock.
+	modifiesRepo bool
+	// readOnlyArgs reports whether the given arguments only ask the command
+	// to list information, so that it can run without the repository lock
+	// even though modifiesRepo is true. It may be nil.
+	readOnlyArgs func(args []string) bool
+	// paged is true if the command's output is sent through a pager by
+	// default. Other commands can be paged by setting pager.<command> to true.
+	paged bool
+	// subcommands lists the subcommands that have their own flags.
+	subcommands []string
+	// hidden is true if the command is left out of help --json.
+	hidden bool
+}
+
+// commands is the list of commands that dispatch accepts, sorted by name
+// with hidden commands at the end.
+// help has no run function: dispatch calls it directly, since it needs
+// the global flags.
+var commands = []*command{
+	{
+		name:         "absorb",
+		synopsis:     absorbSynopsis,
+		run:          absorb,
+		modifiesRepo: true,
+	},
+	{
+		name:         "add",
+		synopsis:     addSynopsis,
+		basic:        true,
+		run:          add,
+		modifiesRepo: true,
+	},
+	{
+		name:         "addremove",
+		run:          addRemove,
+		modifiesRepo: true,
+	},
+	{
+		name:         "amend",
+		synopsis:     amendSynopsis,
+		run:          amend,
+		modifiesRepo: true,
+	},
+	{
+		name:     "annotate",
+		aliases:  []string{"blame"},
+		synopsis: annotateSynopsis,
+		basic:    true,
+		run:      annotate,
+		paged:    true,
+	},
+	{
+		name:     "archive",
+		synopsis: archiveSynopsis,
+		run:      archive,
+	},
+	{
+		name:         "backout",
+		synopsis:     backoutSynopsis,
+		run:          backout,
+		modifiesRepo: true,
+	},
+	{
+		name:         "bisect",
+		synopsis:     bisectSynopsis,
+		run:          bisect,
+		modifiesRepo: true,
+	},
+	{
+		name:         "branch",
+		synopsis:     branchSynopsis,
+		basic:        true,
+		run:          branch,
+		modifiesRepo: true,
+		readOnlyArgs: branchListsOnly,
+	},
+	{
+		name:     "browse",
+		synopsis: browseSynopsis,
+		run:      browse,
+	},
+	{
+		name:     "cat",
+		synopsis: catSynopsis,
+		basic:    true,
+		run:      cat,
+	},
+	{
+		name:     "clone",
+		synopsis: cloneSynopsis,
+		basic:    true,
+		run:      clone,
+	},
+	{
+		name:         "commit",
+		aliases:      []string{"ci"},
+		synopsis:     commitSynopsis,
+		basic:        true,
+		run:          commit,
+		modifiesRepo: true,
+	},
+	{
+		name:         "copy",
+		aliases:      []string{"cp"},
+		synopsis:     copySynopsis,
+		basic:        true,
+		run:          copy_,
+		modifiesRepo: true,
+	},
+	{
+		name:     "diff",
+		synopsis: diffSynopsis,
+		basic:    true,
+		run:      diff,
+		paged:    true,
+	},
+	{
+		name:     "doctor",
+		synopsis: doctorSynopsis,
+		run:      doctor,
+	},
+	{
+		name:         "evolve",
+		synopsis:     evolveSynopsis,
+		run:          evolve,
+		modifiesRepo: true,
+	},
+	{
+		name:     "export",
+		synopsis: exportSynopsis,
+		run:      export,
+	},
+	{
+		name:     "files",
+		aliases:  []string{"manifest"},
+		synopsis: filesSynopsis,
+		run:      files,
+	},
+	{
+		name:         "fold",
+		aliases:      []string{"squash"},
+		synopsis:     foldSynopsis,
+		run:          fold,
+		modifiesRepo: true,
+	},
+	{
+		name:         "forget",
+		synopsis:     forgetSynopsis,
+		run:          forget,
+		modifiesRepo: true,
+	},
+	{
+		name:         "fork",
+		synopsis:     forkSynopsis,
+		run:          fork,
+		modifiesRepo: true,
+	},
+	{
+		name:     "gerrithook",
+		synopsis: gerrithookSynopsis,
+		run:      gerrithook,
+	},
+	{
+		name:     "github-login",
+		synopsis: gitHubLoginSynopsis,
+		run:      gitHubLogin,
+	},
+	{
+		name:         "graft",
+		aliases:      []string{"cherry-pick"},
+		synopsis:     graftSynopsis,
+		run:          graft,
+		modifiesRepo: true,
+	},
+	{
+		name:     "grep",
+		synopsis: grepSynopsis,
+		run:      grep,
+		paged:    true,
+	},
+	{
+		name: "help",
+	},
+	{
+		name:         "histedit",
+		synopsis:     histeditSynopsis,
+		run:          histedit,
+		modifiesRepo: true,
+	},
+	{
+		name:     "identify",
+		aliases:  []string{"id"},
+		synopsis: identifySynopsis,
+		basic:    true,
+		run:      identify,
+	},
+	{
+		name:         "ignore",
+		synopsis:     ignoreSynopsis,
+		run:          ignore,
+		modifiesRepo: true,
+	},
+	{
+		name:         "import",
+		synopsis:     importSynopsis,
+		run:          import_,
+		modifiesRepo: true,
+	},
+	{
+		name:         "incoming",
+		aliases:      []string{"in"},
+		synopsis:     incomingSynopsis,
+		run:          incoming,
+		modifiesRepo: true,
+		paged:        true,
+	},
+	{
+		name:     "init",
+		synopsis: initSynopsis,
+		basic:    true,
+		run:      init_,
+	},
+	{
+		name:         "land",
+		synopsis:     landSynopsis,
+		run:          land,
+		modifiesRepo: true,
+	},
+	{
+		name:     "log",
+		aliases:  []string{"history"},
+		synopsis: logSynopsis,
+		basic:    true,
+		run:      log,
+		paged:    true,
+	},
+	{
+		name:     "mail",
+		synopsis: mailSynopsis,
+		run:      mail,
+	},
+	{
+		name:         "merge",
+		synopsis:     mergeSynopsis,
+		basic:        true,
+		run:          merge,
+		modifiesRepo: true,
+	},
+	{
+		name:         "migrate-default-branch",
+		synopsis:     migrateDefaultBranchSynopsis,
+		run:          migrateDefaultBranch,
+		modifiesRepo: true,
+	},
+	{
+		name:     "outgoing",
+		aliases:  []string{"out"},
+		synopsis: outgoingSynopsis,
+		run:      outgoing,
+		paged:    true,
+	},
+	{
+		name:     "precommit",
+		synopsis: precommitSynopsis,
+		run:      precommit,
+	},
+	{
+		name:         "prune-branches",
+		synopsis:     pruneBranchesSynopsis,
+		run:          pruneBranches,
+		modifiesRepo: true,
+	},
+	{
+		name:         "prune-refs",
+		synopsis:     pruneRefsSynopsis,
+		run:          pruneRefs,
+		modifiesRepo: true,
+	},
+	{
+		name:         "pull",
+		synopsis:     pullSynopsis,
+		basic:        true,
+		run:          pull,
+		modifiesRepo: true,
+	},
+	{
+		name:         "purge",
+		aliases:      []string{"clean"},
+		synopsis:     purgeSynopsis,
+		run:          purge,
+		modifiesRepo: true,
+	},
+	{
+		name:         "push",
+		synopsis:     pushSynopsis,
+		basic:        true,
+		run:          push,
+		modifiesRepo: true,
+	},
+	{
+		name:         "rebase",
+		synopsis:     rebaseSynopsis,
+		run:          rebase,
+		modifiesRepo: true,
+	},
+	{
+		name:         "remove",
+		aliases:      []string{"rm"},
+		synopsis:     removeSynopsis,
+		basic:        true,
+		run:          remove,
+		modifiesRepo: true,
+	},
+	{
+		name:         "rename",
+		aliases:      []string{"mv", "move"},
+		synopsis:     renameSynopsis,
+		basic:        true,
+		run:          rename,
+		modifiesRepo: true,
+	},
+	{
+		name:     "requestpull",
+		aliases:  []string{"pr"},
+		synopsis: requestPullSynopsis,
+		basic:    true,
+		run:      requestPull,
+	},
+	{
+		name:         "resolve",
+		synopsis:     resolveSynopsis,
+		run:          resolve,
+		modifiesRepo: true,
+	},
+	{
+		name:         "restack",
+		synopsis:     restackSynopsis,
+		run:          restack,
+		modifiesRepo: true,
+	},
+	{
+		name:         "revert",
+		synopsis:     revertSynopsis,
+		basic:        true,
+		run:          revert,
+		modifiesRepo: true,
+	},
+	{
+		name:         "reviewed-by",
+		synopsis:     reviewedBySynopsis,
+		run:          reviewedBy,
+		modifiesRepo: true,
+	},
+	{
+		name:         "shelve",
+		synopsis:     shelveSynopsis,
+		run:          shelve,
+		modifiesRepo: true,
+	},
+	{
+		name:     "show",
+		synopsis: showSynopsis,
+		basic:    true,
+		run:      show,
+		paged:    true,
+	},
+	{
+		name:         "sparse",
+		synopsis:     sparseSynopsis,
+		run:          sparse,
+		modifiesRepo: true,
+	},
+	{
+		name:         "stack",
+		synopsis:     stackSynopsis,
+		run:          stack,
+		modifiesRepo: true,
+		subcommands:  []string{"submit"},
+	},
+	{
+		name:     "stats",
+		synopsis: statsSynopsis,
+		run:      stats,
+	},
+	{
+		name:     "status",
+		aliases:  []string{"st", "check"},
+		synopsis: statusSynopsis,
+		basic:    true,
+		run:      status,
+		paged:    true,
+	},
+	{
+		name:     "summary",
+		aliases:  []string{"sum"},
+		synopsis: summarySynopsis,
+		basic:    true,
+		run:      summary,
+	},
+	{
+		name:         "tag",
+		synopsis:     tagSynopsis,
+		basic:        true,
+		run:          tag,
+		modifiesRepo: true,
+		readOnlyArgs: tagListsOnly,
+	},
+	{
+		name:         "tested-by",
+		synopsis:     testedBySynopsis,
+		run:          testedBy,
+		modifiesRepo: true,
+	},
+	{
+		name:         "uncommit",
+		synopsis:     uncommitSynopsis,
+		run:          uncommit,
+		modifiesRepo: true,
+	},
+	{
+		name:         "unshelve",
+		synopsis:     unshelveSynopsis,
+		run:          unshelve,
+		modifiesRepo: true,
+	},
+	{
+		name:         "update",
+		aliases:      []string{"up", "checkout", "co"},
+		synopsis:     updateSynopsis,
+		basic:        true,
+		run:          update,
+		modifiesRepo: true,
+	},
+	{
+		name:     "upstream",
+		synopsis: upstreamSynopsis,
+		run:      upstream,
+	},
+	{
+		name: "version",
+		run:  showVersion,
+	},
+	{
+		name:     "watch",
+		synopsis: watchSynopsis,
+		run:      watch,
+	},
+	{
+		name:         "worktree",
+		synopsis:     worktreeSynopsis,
+		run:          worktree,
+		modifiesRepo: true,
+		subcommands:  []string{"add", "list", "remove"},
+	},
+	{
+		name:   "ez",
+		run:    ez,
+		hidden: true,
+	},
+}
+
+// lookupCommand returns the command with the given name or alias,
+// or nil if there is no such command.
+func lookupCommand(name string) *command {
+	for _, cmd := range commands {
+		if cmd.name == name {
+			return cmd
+		}
+		for _, alias := range cmd.aliases {
+			if alias == name {
+				return cmd
+			}
+		}
+	}
+	return nil
+}
+
+// needsLock reports whether running the command with the given arguments
+// must hold the repository lock.
+func (cmd *command) needsLock(args []string) bool {
+	return cmd.modifiesRepo && (cmd.readOnlyArgs == nil || !cmd.readOnlyArgs(args))
+}
+
+// canonicalCommandName returns the name of the command with the given
+// name or alias, like "commit" for "ci". Unknown names are returned as-is.
+func canonicalCommandName(name string) string {
+	if cmd := lookupCommand(name); cmd != nil {
+		return cmd.name
+	}
+	return name
+}
+
+func ez(ctx context.Context, cc *cmdContext, args []string) error {
+	f := flag.NewFlagSet(true, "gg ez [-re=0]", "")
+	re := f.Bool("re", true, "rematch")
+	f.Parse(args)
+	if *re {
+		fmt.Fprintln(cc.stdout, "lol")
+	} else {
+		fmt.Fprintln(cc.stdout, ":(")
+	}
+	return nil
+}
@@ -23,16 +23,18 @@ import (
 	"path/filepath"
 	"sort"
 	"strings"
+	"time"
 	"unicode"
 
 	"gg-scm.io/pkg/git"
+	"gg-scm.io/pkg/git/object"
 	"gg-scm.io/tool/internal/flag"
 )
 
 const commitSynopsis = "commit the specified files or all outstanding changes"
 
 func commit(ctx context.Context, cc *cmdContext, args []string) error {
-	f := flag.NewFlagSet(true, "gg commit [--amend] [-m MSG] [FILE [...]]", commitSynopsis+`
+	f := flag.NewFlagSet(true, "gg commit [--amend | --fixup=REV | --squash=REV] [-m MSG] [--coauthor=PERSON [...]] [--no-check] [--only=PATTERN | FILE [...]] [--exclude=PATTERN] [-S]", commitSynopsis+`
 
 aliases: ci
 
@@ -40,33 +42,162 @@ aliases: ci
 	are given, then all changes reported by `+"`gg status`"+` will be
 	committed.
 
+	`+filePatternHelp+`
+
 	Unlike Git, gg does not require you to stage your changes into the
 	index. This approximates the behavior of `+"`git commit -a`"+`, but
-	this command will only change the index if the commit succeeds.`)
+	this command will only change the index if the commit succeeds.
+
+	Before committing, any checks configured for `+"`gg precommit run`"+`
+	are run against the files being committed. If a check fails, then
+	the commit is not created.
+
+	`+"`--coauthor`"+` adds a Co-authored-by trailer to the commit message.
+	Its argument is either an identity like "Jane Doe <jane@example.com>"
+	or an alias or email address from the co-author roster. The roster
+	is read from the file named by the `+"`gg.coauthors`"+` setting, or
+	from $XDG_CONFIG_HOME/gg/coauthors by default. Each line of the
+	roster is an alias followed by an identity:
+
+		jdoe Jane Doe <jane@example.com>
+
+	`+"`--fixup`"+` creates a commit with a "fixup!" subject naming the
+	given revision. `+"`gg histedit`"+` and `+"`gg rebase --autosquash`"+`
+	squash such commits into the revision they name and keep that
+	revision's message. `+"`--squash`"+` is similar, but creates a
+	"squash!" commit whose message is appended to the revision's message
+	when squashed. The revision must be an ancestor of the working copy
+	and must not be in the current branch's upstream.
+
+	`+"`--only`"+` and `+"`--exclude`"+` restrict the files committed to those
+	that match or do not match a pattern, like `+"`--exclude='*.pb.go'`"+`.
+	Patterns are Git pathspecs relative to the current directory, so `+"`*`"+`
+	matches across directory separators. Both may be given multiple times.
+
+	`+"`-S`"+` signs the commit using Git's configured signing program, as
+	if the `+"`commit.gpgSign`"+` setting were true. When that setting is
+	true, commits that gg creates are always signed.`)
 	amend := f.Bool("amend", false, "amend the parent of the working directory")
 	msg := f.String("m", "", "use text as commit `message`")
+	coauthorArgs := f.MultiString("coauthor", "credit `person` as a co-author")
+	noCheck := f.Bool("no-check", false, "skip configured checks")
+	fixup := f.String("fixup", "", "create a commit to be squashed into `rev`ision, keeping its message")
+	squash := f.String("squash", "", "create a commit to be squashed into `rev`ision, adding to its message")
+	only := f.MultiString("only", "commit only files matching `pattern`")
+	exclude := f.MultiString("exclude", "do not commit files matching `pattern`")
+	sign := f.Bool("S", false, "sign the commit")
+	f.Alias("S", "sign")
 	if err := f.Parse(args); flag.IsHelp(err) {
 		f.Help(cc.stdout)
 		return nil
 	} else if err != nil {
 		return usagef("%v", err)
 	}
+	cc, err := withSigning(cc, *sign)
+	if err != nil {
+		return err
+	}
+	var subject string
+	switch {
+	case *fixup != "" && *squash != "":
+		return usagef("can't pass both --fixup and --squash")
+	case *fixup != "":
+		if *amend {
+			return usagef("can't pass both --amend and --fixup")
+		}
+		if *msg != "" {
+			return usagef("can't pass both -m and --fixup")
+		}
+		var err error
+		subject, err = autosquashSubject(ctx, cc, "fixup!", *fixup)
+		if err != nil {
+			return err
+		}
+		*msg = subject
+	case *squash != "":
+		if *amend {
+			return usagef("can't pass both --amend and --squash")
+		}
+		var err error
+		subject, err = autosquashSubject(ctx, cc, "squash!", *squash)
+		if err != nil {
+			return err
+		}
+		if *msg != "" {
+			*msg = subject + "\n\n" + *msg
+		}
+	}
 
 	// Get status on files. First level of assurance is to stop empty commits.
 	// This status info may get used for interactive commit message template.
-	var pathspecs []git.Pathspec
-	for _, arg := range f.Args() {
-		pathspecs = append(pathspecs, git.LiteralPath(arg))
+	if len(*only) > 0 && f.NArg() > 0 {
+		return usagef("can't pass both --only and files")
+	}
+	fileArgs, err := expandFilePatterns(ctx, cc, f.Args())
+	if err != nil {
+		return err
+	}
+	pathspecs := fileArgs.pathspecs()
+	for _, pattern := range *only {
+		pathspecs = append(pathspecs, git.Pathspec(pattern))
+	}
+	for _, pattern := range *exclude {
+		pathspecs = append(pathspecs, excludePathspec(pattern))
+	}
+	coauthors, err := resolveCoauthors(ctx, cc, *coauthorArgs)
+	if err != nil {
+		return err
+	}
+	if !*noCheck {
+		if err := runChecks(ctx, cc, pathspecs); err != nil {
+			return fmt.Errorf("%w (use --no-check to skip)", err)
+		}
 	}
 	if *amend {
-		return doAmend(ctx, cc, *msg, pathspecs)
+		return doAmend(ctx, cc, *msg, pathspecs, coauthors, amendOptions{})
 	}
-	return doCommit(ctx, cc, *msg, pathspecs)
+	return doCommit(ctx, cc, *msg, subject, pathspecs, coauthors)
 }
 
 const commitMsgFilename = "COMMIT_MSG"
 
-func doCommit(ctx context.Context, cc *cmdContext, msg string, pathspecs []git.Pathspec) error {
+// excludePathspec returns a pathspec that excludes the files matching
+// pattern. A pathspec list consisting only of exclusions matches every
+// other file.
+func excludePathspec(pattern string) git.Pathspec {
+	return git.Pathspec(":(exclude)" + pattern)
+}
+
+// autosquashSubject returns the subject line for a commit that autosquash
+// will squash into rev. prefix is either "fixup!" or "squash!". rev must be
+// an ancestor of HEAD that is not in the current branch's upstream, since
+// squashing into it would rewrite published history.
+func autosquashSubject(ctx context.Context, cc *cmdContext, prefix string, rev string) (string, error) {
+	if strings.HasPrefix(rev, "-") {
+		return "", fmt.Errorf("revision cannot start with '-'")
+	}
+	target, err := cc.git.CommitInfo(ctx, rev)
+	if err != nil {
+		return "", err
+	}
+	isAncestor, err := cc.git.IsAncestor(ctx, target.SHA1().String(), git.Head.String())
+	if err != nil {
+		return "", err
+	}
+	if !isAncestor {
+		return "", fmt.Errorf("%s is not an ancestor of the working copy", rev)
+	}
+	if err := checkNotInUpstream(ctx, cc, target.SHA1(), rev); err != nil {
+		return "", err
+	}
+	return prefix + " " + target.Summary(), nil
+}
+
+// doCommit creates a new commit. coauthors is a list of identities to
+// add as Co-authored-by trailers to the commit message. If msg is empty,
+// the editor is opened with the message initialized to subject (if not
+// empty), the merge message, or the commit template, in that order.
+func doCommit(ctx context.Context, cc *cmdContext, msg string, subject string, pathspecs []git.Pathspec, coauthors []string) error {
 	// Get status on files. First level of assurance is to stop empty commits.
 	// This status info may get used for interactive commit message template.
 	status, err := cc.git.Status(ctx, git.StatusOptions{
@@ -80,6 +211,12 @@ func doCommit(ctx context.Context, cc *cmdContext, msg string, pathspecs []git.P
 		return err
 	}
 	if !hasChanges {
+		if unborn, _ := unbornBranch(ctx, cc.git); unborn != "" {
+			return fmt.Errorf("nothing to commit: %s has no commits yet (use `gg add` to track files)", unborn)
+		}
+		if len(pathspecs) > 0 && isSparseCheckout(ctx, cc.git) {
+			return errors.New("nothing changed (files outside the sparse checkout must be included with `gg sparse` first)")
+		}
 		return errors.New("nothing changed")
 	}
 	// Reuse the information from the status call.
@@ -104,12 +241,34 @@ func doCommit(ctx context.Context, cc *cmdContext, msg string, pathspecs []git.P
 			return err
 		}
 		msgBuf := new(bytes.Buffer)
-		msgBuf.Write(maybeMergeMessage(ctx, cc.git))
+		var hookArgs []string
+		if subject != "" {
+			msgBuf.WriteString(subject + "\n\n")
+			hookArgs = []string{"message"}
+		} else if mergeMsg := maybeMergeMessage(ctx, cc.git); len(mergeMsg) > 0 {
+			msgBuf.Write(mergeMsg)
+			hookArgs = []string{"merge"}
+		} else {
+			initialMsg, err := readCommitTemplate(ctx, cc)
+			if err != nil {
+				return err
+			}
+			msgBuf.Write(initialMsg)
+			if initialMsg != nil {
+				hookArgs = []string{"template"}
+			}
+		}
 		err = commitMessageTemplate(ctx, cc.git, diffStatus, msgBuf, commentChar)
 		if err != nil {
 			return err
 		}
-		editorOut, err := cc.editor.open(ctx, commitMsgFilename, msgBuf.Bytes())
+		// Like git commit, let the prepare-commit-msg hook fill in the
+		// message before the user edits it.
+		initialMsg, err := runMessageHook(ctx, cc, "prepare-commit-msg", msgBuf.Bytes(), hookArgs...)
+		if err != nil {
+			return err
+		}
+		editorOut, err := cc.editor.open(ctx, commitMsgFilename, initialMsg)
 		if err != nil {
 			return err
 		}
@@ -117,12 +276,19 @@ func doCommit(ctx context.Context, cc *cmdContext, msg string, pathspecs []git.P
 	} else {
 		msg = cleanupMessage(msg, "")
 	}
+	msg = addCoauthorTrailers(msg, coauthors)
+	msg, err = addChangeID(ctx, cc, msg)
+	if err != nil {
+		return err
+	}
 
 	// Commit as appropriate.
 	if len(pathspecs) > 0 {
-		return cc.git.CommitFiles(ctx, msg, pathspecs, git.CommitOptions{})
+		err = cc.git.CommitFiles(ctx, msg, pathspecs, git.CommitOptions{})
+	} else {
+		err = cc.git.CommitAll(ctx, msg, git.CommitOptions{})
 	}
-	return cc.git.CommitAll(ctx, msg, git.CommitOptions{})
+	return signingError(ctx, cc, err)
 }
 
 func maybeMergeMessage(ctx context.Context, g *git.Git) []byte {
@@ -137,17 +303,56 @@ func maybeMergeMessage(ctx context.Context, g *git.Git) []byte {
 	return mergeMsg
 }
 
-func doAmend(ctx context.Context, cc *cmdContext, msg string, pathspecs []git.Pathspec) error {
-
-	// Get status on files (may get used for interactive commit message template).
-	status, err := cc.git.Status(ctx, git.StatusOptions{
-		Pathspecs: pathspecs,
-	})
+// readCommitTemplate returns the contents of the file named by the
+// commit.template configuration setting or nil if it is not set.
+func readCommitTemplate(ctx context.Context, cc *cmdContext) ([]byte, error) {
+	cfg, err := readGGConfig(ctx, cc.git)
 	if err != nil {
-		return err
+		return nil, err
 	}
-	if _, err := verifyNoMissingOrUnmerged(status); err != nil {
-		return err
+	path := cfg.trusted().Path("commit.template")
+	if path == "" {
+		return nil, nil
+	}
+	msg, err := ioutil.ReadFile(cc.abs(path))
+	if err != nil {
+		return nil, fmt.Errorf("read commit template: %w", err)
+	}
+	return msg, nil
+}
+
+// amendOptions holds the optional behavior of doAmend.
+type amendOptions struct {
+	// keepMessage uses the commit's existing message instead of opening
+	// the editor when no message is given.
+	keepMessage bool
+	// metadataOnly changes only the commit's message and author,
+	// ignoring any changes in the working copy.
+	metadataOnly bool
+	// author and authorTime replace the commit's author and author
+	// date if not zero.
+	author     object.User
+	authorTime time.Time
+}
+
+// doAmend amends the working directory's parent commit. coauthors is a
+// list of identities to add as Co-authored-by trailers to the commit
+// message.
+func doAmend(ctx context.Context, cc *cmdContext, msg string, pathspecs []git.Pathspec, coauthors []string, opts amendOptions) error {
+	if !opts.metadataOnly {
+		// Get status on files (may get used for interactive commit message template).
+		status, err := cc.git.Status(ctx, git.StatusOptions{
+			Pathspecs: pathspecs,
+		})
+		if err != nil {
+			return err
+		}
+		if _, err := verifyNoMissingOrUnmerged(status); err != nil {
+			return err
+		}
+	}
+	if unborn, err := unbornBranch(ctx, cc.git); err == nil && unborn != "" {
+		return fmt.Errorf("nothing to amend: %s has no commits yet", unborn)
 	}
 	commitInfo, err := cc.git.CommitInfo(ctx, "HEAD")
 	if err != nil {
@@ -165,16 +370,29 @@ func doAmend(ctx context.Context, cc *cmdContext, msg string, pathspecs []git.Pa
 	default:
 		return errors.New("cannot amend a merge, use `git commit --amend`")
 	}
-	diffStatus, err := amendedDiffStatus(ctx, cc.git, base.String(), pathspecs)
-	if err != nil {
-		return err
-	}
-	if len(diffStatus) == 0 {
-		return errors.New("amend would create an empty commit")
+	var diffStatus []git.DiffStatusEntry
+	if opts.metadataOnly {
+		diffStatus, err = cc.git.DiffStatus(ctx, git.DiffStatusOptions{Commit1: base.String(), Commit2: "HEAD"})
+		if err != nil {
+			return err
+		}
+	} else {
+		diffStatus, err = amendedDiffStatus(ctx, cc.git, base.String(), pathspecs)
+		if err != nil {
+			return err
+		}
+		if len(diffStatus) == 0 {
+			return errors.New("amend would create an empty commit")
+		}
 	}
 
 	// Get message from user.
-	if msg == "" {
+	switch {
+	case msg != "":
+		msg = cleanupMessage(msg, "")
+	case opts.keepMessage:
+		msg = commitInfo.Message
+	default:
 		// Open message in editor.
 		cfg, err := cc.git.ReadConfig(ctx)
 		if err != nil {
@@ -190,20 +408,54 @@ func doAmend(ctx context.Context, cc *cmdContext, msg string, pathspecs []git.Pa
 		if err != nil {
 			return err
 		}
-		editorOut, err := cc.editor.open(ctx, commitMsgFilename, msgBuf.Bytes())
+		initialMsg, err := runMessageHook(ctx, cc, "prepare-commit-msg", msgBuf.Bytes(), "commit", "HEAD")
+		if err != nil {
+			return err
+		}
+		editorOut, err := cc.editor.open(ctx, commitMsgFilename, initialMsg)
 		if err != nil {
 			return err
 		}
 		msg = cleanupMessage(string(editorOut), commentChar)
-	} else {
-		msg = cleanupMessage(msg, "")
+	}
+	msg = addCoauthorTrailers(msg, coauthors)
+	msg, err = addChangeID(ctx, cc, msg)
+	if err != nil {
+		return err
 	}
 
 	// Amend as appropriate.
+	if opts.metadataOnly {
+		c := &object.Commit{
+			Tree:       commitInfo.Tree,
+			Parents:    commitInfo.Parents,
+			Author:     commitInfo.Author,
+			AuthorTime: commitInfo.AuthorTime,
+			Message:    msg,
+		}
+		if opts.author != "" {
+			c.Author = opts.author
+		}
+		if !opts.authorTime.IsZero() {
+			c.AuthorTime = opts.authorTime
+		}
+		amended, err := writeCommit(ctx, cc, c)
+		if err != nil {
+			return err
+		}
+		return replaceCommit(ctx, cc, commitInfo.SHA1(), amended)
+	}
+	amendOpts := git.AmendOptions{
+		Message:    msg,
+		Author:     opts.author,
+		AuthorTime: opts.authorTime,
+	}
 	if len(pathspecs) > 0 {
-		return cc.git.AmendFiles(ctx, pathspecs, git.AmendOptions{Message: msg})
+		err = cc.git.AmendFiles(ctx, pathspecs, amendOpts)
+	} else {
+		err = cc.git.AmendAll(ctx, amendOpts)
 	}
-	return cc.git.AmendAll(ctx, git.AmendOptions{Message: msg})
+	return signingError(ctx, cc, err)
 }
 
 func amendedDiffStatus(ctx context.Context, g *git.Git, baseRev string, pathspecs []git.Pathspec) ([]git.DiffStatusEntry, error) {
@@ -216,7 +468,9 @@ func amendedDiffStatus(ctx context.Context, g *git.Git, baseRev string, pathspec
 	if err != nil {
 		return nil, err
 	}
-	// TODO(someday): If we evaluated pathspecs in-process, this DiffStatus would be unnecessary.
+	// Filtering base in-process would make this DiffStatus unnecessary, but
+	// it would cost a subprocess to find the current directory's prefix and
+	// would have to reimplement every kind of pathspec magic Git supports.
 	filterBase, err := g.DiffStatus(ctx, git.DiffStatusOptions{Commit1: baseRev, Commit2: "HEAD", Pathspecs: pathspecs})
 	if err != nil {
 		return nil, err
@@ -285,6 +539,9 @@ func commitMessageTemplate(ctx context.Context, g *git.Git, status []git.DiffSta
 	} else if b := headRef.Branch(); b != "" {
 		buf.WriteString("branch ")
 		buf.WriteString(b)
+		if unborn, _ := unbornBranch(ctx, g); unborn != "" {
+			buf.WriteString(" (initial commit)")
+		}
 	} else {
 		buf.WriteString(headRef.String())
 	}
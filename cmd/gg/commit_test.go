@@ -19,10 +19,13 @@ import (
 	"context"
 	"fmt"
 	"io/ioutil"
+	"os/exec"
 	"path/filepath"
+	"strings"
 	"testing"
 
 	"gg-scm.io/pkg/git"
+	"gg-scm.io/tool/internal/escape"
 	"gg-scm.io/tool/internal/filesystem"
 	"github.com/google/go-cmp/cmp"
 )
@@ -210,6 +213,78 @@ func TestCommit_Selective(t *testing.T) {
 	}
 }
 
+func TestCommit_OnlyExclude(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	env, err := newTestEnv(ctx, t)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := env.initEmptyRepo(ctx, "."); err != nil {
+		t.Fatal(err)
+	}
+	err = env.root.Apply(
+		filesystem.Write("foo.go", "package foo\n"),
+		filesystem.Write("gen/foo.pb.go", "package gen\n"),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := env.addFiles(ctx, "foo.go", "gen/foo.pb.go"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := env.newCommit(ctx, "."); err != nil {
+		t.Fatal(err)
+	}
+	const (
+		fooNew = "package foo // changed\n"
+		genNew = "package gen // regenerated\n"
+	)
+	err = env.root.Apply(
+		filesystem.Write("foo.go", fooNew),
+		filesystem.Write("gen/foo.pb.go", genNew),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := env.gg(ctx, env.root.String(), "commit", "-m", "bad", "--only=*.go", "foo.go"); err == nil {
+		t.Error("commit --only with files did not return an error")
+	} else if !isUsage(err) {
+		t.Errorf("commit --only with files returned non-usage error: %v", err)
+	}
+
+	// Commit everything except generated files.
+	if _, err := env.gg(ctx, env.root.String(), "commit", "-m", "change foo", "--exclude=*.pb.go"); err != nil {
+		t.Fatal(err)
+	}
+	if data, err := catBlob(ctx, env.git, "HEAD", "foo.go"); err != nil {
+		t.Error(err)
+	} else if string(data) != fooNew {
+		t.Errorf("foo.go = %q; want %q", data, fooNew)
+	}
+	if data, err := catBlob(ctx, env.git, "HEAD", "gen/foo.pb.go"); err != nil {
+		t.Error(err)
+	} else if string(data) == genNew {
+		t.Error("gen/foo.pb.go was committed despite --exclude")
+	}
+
+	// Nothing is left after excluding generated files.
+	if _, err := env.gg(ctx, env.root.String(), "commit", "-m", "nothing", "--exclude=*.pb.go"); err == nil {
+		t.Error("commit --exclude with only excluded changes did not return an error")
+	}
+
+	// Commit just the generated files.
+	if _, err := env.gg(ctx, env.root.String(), "commit", "-m", "regenerate", "--only=gen/*"); err != nil {
+		t.Fatal(err)
+	}
+	if data, err := catBlob(ctx, env.git, "HEAD", "gen/foo.pb.go"); err != nil {
+		t.Error(err)
+	} else if string(data) != genNew {
+		t.Errorf("gen/foo.pb.go = %q; want %q", data, genNew)
+	}
+}
+
 func TestCommit_SelectiveWrongFile(t *testing.T) {
 	// Regression test for https://github.com/gg-scm/gg/issues/63
 
@@ -612,6 +687,148 @@ func TestCommit_NoChanges(t *testing.T) {
 	}
 }
 
+func TestCommit_FixupSquash(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	env, err := newTestEnv(ctx, t)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := env.initRepoWithHistory(ctx, "."); err != nil {
+		t.Fatal(err)
+	}
+	err = env.git.NewBranch(ctx, "topic", git.BranchOptions{
+		StartPoint: "main",
+		Track:      true,
+		Checkout:   true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := env.root.Apply(filesystem.Write("foo.txt", "Hello\n")); err != nil {
+		t.Fatal(err)
+	}
+	if err := env.addFiles(ctx, "foo.txt"); err != nil {
+		t.Fatal(err)
+	}
+	if err := env.git.CommitAll(ctx, "add foo\n\nThis is the body.", git.CommitOptions{}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Targets in the upstream are rejected.
+	if err := env.root.Apply(filesystem.Write("foo.txt", "Hello, World!\n")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := env.gg(ctx, env.root.String(), "commit", "--fixup=main"); err == nil {
+		t.Error("commit --fixup=main did not return an error")
+	} else if isUsage(err) {
+		t.Errorf("commit --fixup=main returned usage error: %v", err)
+	}
+	if _, err := env.gg(ctx, env.root.String(), "commit", "--fixup=HEAD", "--squash=HEAD"); err == nil {
+		t.Error("commit --fixup --squash did not return an error")
+	} else if !isUsage(err) {
+		t.Errorf("commit --fixup --squash returned non-usage error: %v", err)
+	}
+
+	if _, err := env.gg(ctx, env.root.String(), "commit", "--fixup=HEAD"); err != nil {
+		t.Fatal(err)
+	}
+	if info, err := env.git.CommitInfo(ctx, "HEAD"); err != nil {
+		t.Fatal(err)
+	} else if got, want := info.Message, "fixup! add foo\n"; got != want {
+		t.Errorf("commit --fixup message = %q; want %q", got, want)
+	}
+
+	if err := env.root.Apply(filesystem.Write("foo.txt", "Hello, gg!\n")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := env.gg(ctx, env.root.String(), "commit", "--squash=HEAD~", "-m", "Say hello to gg."); err != nil {
+		t.Fatal(err)
+	}
+	if info, err := env.git.CommitInfo(ctx, "HEAD"); err != nil {
+		t.Fatal(err)
+	} else if got, want := info.Message, "squash! add foo\n\nSay hello to gg.\n"; got != want {
+		t.Errorf("commit --squash message = %q; want %q", got, want)
+	}
+}
+
+func TestCommit_ChangeID(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	env, err := newTestEnv(ctx, t)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := env.initRepoWithHistory(ctx, "."); err != nil {
+		t.Fatal(err)
+	}
+	if err := env.git.Run(ctx, "config", "gg.gerrit.changeId", "true"); err != nil {
+		t.Fatal(err)
+	}
+	if err := env.root.Apply(filesystem.Write("foo.txt", "Hello\n")); err != nil {
+		t.Fatal(err)
+	}
+	if err := env.addFiles(ctx, "foo.txt"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := env.gg(ctx, env.root.String(), "commit", "-m", "add foo"); err != nil {
+		t.Fatal(err)
+	}
+	info, err := env.git.CommitInfo(ctx, "HEAD")
+	if err != nil {
+		t.Fatal(err)
+	}
+	id := findChangeID(info.Message)
+	if len(id) != 41 || !strings.HasPrefix(id, "I") {
+		t.Fatalf("commit message = %q; want a Change-Id trailer", info.Message)
+	}
+	if !strings.HasPrefix(info.Message, "add foo\n\nChange-Id: ") {
+		t.Errorf("commit message = %q; want Change-Id in new paragraph", info.Message)
+	}
+
+	// Amending keeps the same change ID.
+	if err := env.root.Apply(filesystem.Write("foo.txt", "Hello, World!\n")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := env.gg(ctx, env.root.String(), "commit", "--amend", "-m", "add foo\n\nChange-Id: "+id); err != nil {
+		t.Fatal(err)
+	}
+	if info, err := env.git.CommitInfo(ctx, "HEAD"); err != nil {
+		t.Fatal(err)
+	} else if got := findChangeID(info.Message); got != id {
+		t.Errorf("after amend, Change-Id = %q; want %q", got, id)
+	}
+}
+
+func TestCommit_EmptyRepo(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	env, err := newTestEnv(ctx, t)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := env.initEmptyRepo(ctx, "."); err != nil {
+		t.Fatal(err)
+	}
+	if err := env.root.Apply(filesystem.Write("foo.txt", dummyContent)); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, args := range [][]string{{"commit", "-m", "initial"}, {"commit", "--amend", "-m", "initial"}} {
+		_, err := env.gg(ctx, env.root.String(), args...)
+		if err == nil {
+			t.Errorf("gg %q did not return an error", args)
+			continue
+		}
+		if isUsage(err) {
+			t.Errorf("gg %q returned usage error: %v", args, err)
+		}
+		if !strings.Contains(err.Error(), "no commits yet") {
+			t.Errorf("gg %q error = %v; want to mention \"no commits yet\"", args, err)
+		}
+	}
+}
+
 func TestCommit_AmendJustMessage(t *testing.T) {
 	t.Parallel()
 	ctx := context.Background()
@@ -1205,3 +1422,67 @@ func objectExists(ctx context.Context, g *git.Git, rev string, path git.TopPath)
 	}
 	return nil
 }
+
+func TestCommit_Sign(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	env, err := newTestEnv(ctx, t)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v, err := queryGitVersion(ctx, env.git); err != nil {
+		t.Fatal(err)
+	} else if v.less(gitVersion{2, 34, 0}) {
+		t.Skipf("Git %v does not support SSH signing", v)
+	}
+	sshKeygen, err := exec.LookPath("ssh-keygen")
+	if err != nil {
+		t.Skip("ssh-keygen not found")
+	}
+	keyPath := env.topDir.FromSlash("signing_key")
+	keygen := exec.Command(sshKeygen, "-q", "-t", "ed25519", "-N", "", "-f", keyPath)
+	if out, err := keygen.CombinedOutput(); err != nil {
+		t.Fatalf("ssh-keygen: %v\n%s", err, out)
+	}
+	config := fmt.Sprintf("[gpg]\nformat = ssh\n[gpg \"ssh\"]\nprogram = %s\n[user]\nsigningKey = %s\n",
+		escape.GitConfig(sshKeygen), escape.GitConfig(keyPath))
+	if err := env.writeConfig([]byte(config)); err != nil {
+		t.Fatal(err)
+	}
+	if err := env.initEmptyRepo(ctx, "."); err != nil {
+		t.Fatal(err)
+	}
+	if err := env.root.Apply(filesystem.Write("foo.txt", dummyContent)); err != nil {
+		t.Fatal(err)
+	}
+	if err := env.addFiles(ctx, "foo.txt"); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := env.gg(ctx, env.root.String(), "commit", "-S", "-m", "signed"); err != nil {
+		t.Fatal(err)
+	}
+	raw, err := env.git.Output(ctx, "cat-file", "commit", "HEAD")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(raw, "\ngpgsig ") {
+		t.Errorf("commit is not signed:\n%s", raw)
+	}
+
+	// Signing failures should point at the configuration.
+	config = "[gpg]\nformat = ssh\n[user]\nsigningKey = " + escape.GitConfig(env.topDir.FromSlash("missing_key")) + "\n"
+	if err := env.writeConfig([]byte(config)); err != nil {
+		t.Fatal(err)
+	}
+	if err := env.root.Apply(filesystem.Write("foo.txt", "changed\n")); err != nil {
+		t.Fatal(err)
+	}
+	_, err = env.gg(ctx, env.root.String(), "commit", "-S", "-m", "not signed")
+	if err == nil {
+		t.Fatal("commit -S with a missing key did not return an error")
+	}
+	if !strings.Contains(err.Error(), "commit signing is enabled") {
+		t.Errorf("commit -S error = %q; want it to mention signing", err)
+	}
+}
@@ -0,0 +1,85 @@
+// Copyright 2021 The gg Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gg-scm.io/tool/internal/escape"
+	"gg-scm.io/tool/internal/sigterm"
+)
+
+// runMessageHook runs the repository's hook with the given name (either
+// prepare-commit-msg or commit-msg) on msg and returns the message as
+// the hook left it. The hook receives the path to a file holding the
+// message followed by args, just as it would from git commit. If the
+// hook does not exist or is not executable, msg is returned unchanged.
+func runMessageHook(ctx context.Context, cc *cmdContext, name string, msg []byte, args ...string) ([]byte, error) {
+	cfg, err := cc.git.ReadConfig(ctx)
+	if err != nil {
+		return nil, err
+	}
+	path, err := hookPath(ctx, cfg, cc.git, name)
+	if err != nil {
+		return nil, err
+	}
+	if info, err := os.Stat(path); err != nil || info.IsDir() || info.Mode()&0o111 == 0 {
+		return msg, nil
+	}
+	gitDir, err := cc.git.GitDir(ctx)
+	if err != nil {
+		return nil, err
+	}
+	msgPath := filepath.Join(gitDir, "COMMIT_EDITMSG")
+	if err := ioutil.WriteFile(msgPath, msg, 0o666); err != nil {
+		return nil, fmt.Errorf("%s hook: %w", name, err)
+	}
+	// Hooks are usually shell scripts, so run them the way the editor is run.
+	line := new(strings.Builder)
+	line.WriteString(escape.Bash(path))
+	for _, arg := range append([]string{msgPath}, args...) {
+		line.WriteString(" ")
+		line.WriteString(escape.Bash(arg))
+	}
+	c, err := bashCommand(cc.git.Exe(), line.String())
+	if err != nil {
+		return nil, fmt.Errorf("%s hook: %w", name, err)
+	}
+	// Like git, run hooks from the top of the working copy with their
+	// output sent to stderr.
+	c.Dir = cc.dir
+	if top, err := cc.git.WorkTree(ctx); err == nil {
+		c.Dir = top
+	}
+	c.Env = cc.env
+	if len(c.Env) == 0 {
+		c.Env = []string{} // force empty
+	}
+	c.Stdout = cc.stderr
+	c.Stderr = cc.stderr
+	if err := sigterm.Run(ctx, c); err != nil {
+		return nil, fmt.Errorf("%s hook failed: %w", name, err)
+	}
+	newMsg, err := ioutil.ReadFile(msgPath)
+	if err != nil {
+		return nil, fmt.Errorf("%s hook: %w", name, err)
+	}
+	return newMsg, nil
+}
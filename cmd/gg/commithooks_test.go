@@ -0,0 +1,211 @@
+// Copyright 2021 The gg Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"runtime"
+	"strings"
+	"testing"
+
+	"gg-scm.io/pkg/git"
+	"gg-scm.io/tool/internal/escape"
+	"gg-scm.io/tool/internal/filesystem"
+)
+
+func TestCommitMsgHook(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("hook is a shell script")
+	}
+	t.Parallel()
+	ctx := context.Background()
+	env, err := newTestEnv(ctx, t)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := env.initRepoWithHistory(ctx, "."); err != nil {
+		t.Fatal(err)
+	}
+	if err := env.root.Apply(filesystem.Write("a.txt", dummyContent)); err != nil {
+		t.Fatal(err)
+	}
+	if err := env.addFiles(ctx, "a.txt"); err != nil {
+		t.Fatal(err)
+	}
+	if err := env.git.CommitAll(ctx, "add a.txt", git.CommitOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	hookPath := env.root.FromSlash(".git/hooks/commit-msg")
+	const hook = "#!/bin/sh\nprintf 'Hook-Ran: yes\\n' >> \"$1\"\n"
+	if err := ioutil.WriteFile(hookPath, []byte(hook), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	// gg reviewed-by writes its commit with git commit-tree, which does
+	// not run hooks on its own.
+	const jane = "Jane Doe <jane@example.com>"
+	if _, err := env.gg(ctx, env.root.String(), "reviewed-by", jane); err != nil {
+		t.Fatal(err)
+	}
+	head, err := env.git.CommitInfo(ctx, "HEAD")
+	if err != nil {
+		t.Fatal(err)
+	}
+	const want = "add a.txt\n\nReviewed-by: " + jane + "\nHook-Ran: yes\n"
+	if head.Message != want {
+		t.Errorf("message = %q; want %q", head.Message, want)
+	}
+
+	// A failing hook stops the commit.
+	if err := ioutil.WriteFile(hookPath, []byte("#!/bin/sh\nexit 1\n"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := env.gg(ctx, env.root.String(), "tested-by", jane); err == nil {
+		t.Error("tested-by with failing commit-msg hook did not return an error")
+	} else if isUsage(err) {
+		t.Error(err)
+	}
+	if got, err := env.git.CommitInfo(ctx, "HEAD"); err != nil {
+		t.Fatal(err)
+	} else if got.SHA1() != head.SHA1() {
+		t.Errorf("HEAD = %v after failed tested-by; want %v", got.SHA1(), head.SHA1())
+	}
+}
+
+func TestPrepareCommitMsgHookRunsOnce(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("hook is a shell script")
+	}
+	t.Parallel()
+	ctx := context.Background()
+	env, err := newTestEnv(ctx, t)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := env.initRepoWithHistory(ctx, "."); err != nil {
+		t.Fatal(err)
+	}
+	if err := env.root.Apply(filesystem.Write("a.txt", dummyContent)); err != nil {
+		t.Fatal(err)
+	}
+	if err := env.addFiles(ctx, "a.txt"); err != nil {
+		t.Fatal(err)
+	}
+	hookPath := env.root.FromSlash(".git/hooks/prepare-commit-msg")
+	const hook = "#!/bin/sh\nprintf 'Hook-Ran: yes\\n' >> \"$1\"\n"
+	if err := ioutil.WriteFile(hookPath, []byte(hook), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := env.gg(ctx, env.root.String(), "commit", "-m", "add a.txt"); err != nil {
+		t.Fatal(err)
+	}
+	head, err := env.git.CommitInfo(ctx, "HEAD")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := strings.Count(head.Message, "Hook-Ran: yes"); n != 1 {
+		t.Errorf("message = %q; want hook to have run once (ran %d times)", head.Message, n)
+	}
+}
+
+func TestPrepareCommitMsgHook_WriteCommit(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("hook is a shell script")
+	}
+	t.Parallel()
+	ctx := context.Background()
+	env, err := newTestEnv(ctx, t)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := env.initRepoWithHistory(ctx, "."); err != nil {
+		t.Fatal(err)
+	}
+	if err := env.root.Apply(filesystem.Write("a.txt", dummyContent)); err != nil {
+		t.Fatal(err)
+	}
+	if err := env.addFiles(ctx, "a.txt"); err != nil {
+		t.Fatal(err)
+	}
+	if err := env.git.CommitAll(ctx, "add a.txt", git.CommitOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	const prepareHook = "#!/bin/sh\nprintf 'Source: %s\\n' \"$2\" >> \"$1\"\n"
+	if err := ioutil.WriteFile(env.root.FromSlash(".git/hooks/prepare-commit-msg"), []byte(prepareHook), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	const commitMsgHook = "#!/bin/sh\nprintf 'Commit-Msg: yes\\n' >> \"$1\"\n"
+	if err := ioutil.WriteFile(env.root.FromSlash(".git/hooks/commit-msg"), []byte(commitMsgHook), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	const jane = "Jane Doe <jane@example.com>"
+	if _, err := env.gg(ctx, env.root.String(), "reviewed-by", jane); err != nil {
+		t.Fatal(err)
+	}
+	head, err := env.git.CommitInfo(ctx, "HEAD")
+	if err != nil {
+		t.Fatal(err)
+	}
+	const want = "add a.txt\n\nReviewed-by: " + jane + "\nSource: message\nCommit-Msg: yes\n"
+	if head.Message != want {
+		t.Errorf("message = %q; want %q", head.Message, want)
+	}
+}
+
+func TestPrepareCommitMsgHook_Editor(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("hook is a shell script")
+	}
+	t.Parallel()
+	ctx := context.Background()
+	env, err := newTestEnv(ctx, t)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := env.initRepoWithHistory(ctx, "."); err != nil {
+		t.Fatal(err)
+	}
+	if err := env.root.Apply(filesystem.Write("a.txt", dummyContent)); err != nil {
+		t.Fatal(err)
+	}
+	if err := env.addFiles(ctx, "a.txt"); err != nil {
+		t.Fatal(err)
+	}
+	const hook = "#!/bin/sh\nprintf 'Source: %s %s\\n' \"$2\" \"$3\" >> \"$1\"\n"
+	if err := ioutil.WriteFile(env.root.FromSlash(".git/hooks/prepare-commit-msg"), []byte(hook), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	// The editor saves a copy of the message it was given.
+	captured := env.topDir.FromSlash("captured")
+	editor := "capture() { cp \"$1\" " + escape.Bash(captured) + "; }; capture"
+	if err := env.writeConfig([]byte(fmt.Sprintf("[core]\neditor = %s\n", escape.GitConfig(editor)))); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := env.gg(ctx, env.root.String(), "amend"); err != nil {
+		t.Fatal(err)
+	}
+	got, err := ioutil.ReadFile(captured)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(got), "\nSource: commit HEAD\n") {
+		t.Errorf("editor was given %q; want prepare-commit-msg hook to have run with commit HEAD", got)
+	}
+}
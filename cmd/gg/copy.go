@@ -0,0 +1,200 @@
+// Copyright 2021 The gg Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gg-scm.io/pkg/git"
+	"gg-scm.io/tool/internal/flag"
+)
+
+const copySynopsis = "copy files and add the copies on the next commit"
+
+func copy_(ctx context.Context, cc *cmdContext, args []string) error {
+	f := flag.NewFlagSet(true, "gg copy [--after] [-f] SOURCE [...] DEST", copySynopsis+`
+
+	Copy the tracked files in the sources to the destination and add the
+	copies. Copying a directory copies the tracked files inside it. If
+	more than one source is given, the destination must be an existing
+	directory. The working copy versions of the files are copied, so
+	uncommitted changes are included.
+
+	`+"`--after`"+` records a copy that already happened by adding the
+	destination, which must exist. Like Git, gg detects copies by
+	comparing file contents.
+
+	aliases: cp`)
+	after := f.Bool("after", false, "record a copy that has already occurred")
+	force := f.Bool("f", false, "overwrite existing files")
+	f.Alias("f", "force")
+	if err := f.Parse(args); flag.IsHelp(err) {
+		f.Help(cc.stdout)
+		return nil
+	} else if err != nil {
+		return usagef("%v", err)
+	}
+	if f.NArg() < 2 {
+		return usagef("must pass a source and a destination")
+	}
+	if *after && *force {
+		return usagef("can't pass -f with --after")
+	}
+	srcs, dst := f.Args()[:f.NArg()-1], f.Arg(f.NArg()-1)
+	targets, err := copyTargets(ctx, cc, srcs, dst, *after)
+	if err != nil {
+		return err
+	}
+	if *after {
+		for i, t := range targets {
+			if _, err := os.Lstat(cc.abs(t)); err != nil {
+				return fmt.Errorf("%s was not copied to %s: %w", srcs[i], t, err)
+			}
+		}
+	} else {
+		for i, src := range srcs {
+			if err := copyTracked(ctx, cc, src, targets[i], *force); err != nil {
+				return err
+			}
+		}
+	}
+	pathspecs := make([]git.Pathspec, 0, len(targets))
+	for _, t := range targets {
+		pathspecs = append(pathspecs, git.LiteralPath(t))
+	}
+	return cc.git.Add(ctx, pathspecs, git.AddOptions{})
+}
+
+// copyTargets returns the path that each source is copied or moved to.
+// As with cp and mv, the sources are placed inside dst if dst is an
+// existing directory, and there must only be one source otherwise.
+// If after is true, the copy or move has already happened, so a single
+// source directory may have become dst.
+func copyTargets(ctx context.Context, cc *cmdContext, srcs []string, dst string, after bool) ([]string, error) {
+	if !isdir(cc.abs(dst)) {
+		if len(srcs) > 1 {
+			return nil, fmt.Errorf("%s is not a directory", dst)
+		}
+		return []string{dst}, nil
+	}
+	targets := make([]string, 0, len(srcs))
+	for _, src := range srcs {
+		targets = append(targets, filepath.Join(dst, filepath.Base(src)))
+	}
+	if after && len(srcs) == 1 {
+		if _, err := os.Lstat(cc.abs(targets[0])); os.IsNotExist(err) {
+			isDir, err := isTrackedDir(ctx, cc.git, srcs[0])
+			if err != nil {
+				return nil, err
+			}
+			if !isDir {
+				return nil, fmt.Errorf("%s was not copied to %s", srcs[0], targets[0])
+			}
+			return []string{dst}, nil
+		}
+	}
+	return targets, nil
+}
+
+// isTrackedDir reports whether path names a directory containing
+// tracked files, even if it no longer exists in the working copy.
+func isTrackedDir(ctx context.Context, g *git.Git, path string) (bool, error) {
+	out, err := g.Output(ctx, "ls-files", "-z", "--", git.LiteralPath(path).String())
+	if err != nil {
+		return false, err
+	}
+	path = filepath.Clean(path)
+	for _, name := range strings.Split(out, "\x00") {
+		if name != "" && filepath.FromSlash(name) != path {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// copyTracked copies the tracked files at src (a file or directory) to dst.
+func copyTracked(ctx context.Context, cc *cmdContext, src, dst string, force bool) error {
+	out, err := cc.git.Output(ctx, "ls-files", "-z", "--", git.LiteralPath(src).String())
+	if err != nil {
+		return err
+	}
+	var files []string
+	for _, name := range strings.Split(out, "\x00") {
+		if name != "" {
+			files = append(files, filepath.FromSlash(name))
+		}
+	}
+	if len(files) == 0 {
+		return fmt.Errorf("%s is not tracked", src)
+	}
+	srcRoot := filepath.Clean(src)
+	for _, name := range files {
+		rel, err := filepath.Rel(srcRoot, name)
+		if err != nil {
+			return err
+		}
+		if err := copyFile(cc.abs(name), cc.abs(filepath.Join(dst, rel)), force); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// copyFile copies a regular file or symlink, keeping its permissions.
+func copyFile(src, dst string, force bool) (err error) {
+	info, err := os.Lstat(src)
+	if err != nil {
+		return err
+	}
+	if _, err := os.Lstat(dst); err == nil {
+		if !force {
+			return fmt.Errorf("%s already exists (use -f to overwrite)", dst)
+		}
+		if err := os.Remove(dst); err != nil {
+			return err
+		}
+	}
+	if err := os.MkdirAll(filepath.Dir(dst), 0o777); err != nil {
+		return err
+	}
+	if info.Mode()&os.ModeSymlink != 0 {
+		target, err := os.Readlink(src)
+		if err != nil {
+			return err
+		}
+		return os.Symlink(target, dst)
+	}
+	r, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	w, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_EXCL, info.Mode().Perm())
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if closeErr := w.Close(); err == nil {
+			err = closeErr
+		}
+	}()
+	_, err = io.Copy(w, r)
+	return err
+}
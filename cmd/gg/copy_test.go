@@ -0,0 +1,97 @@
+// Copyright 2021 The gg Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"testing"
+
+	"gg-scm.io/pkg/git"
+	"gg-scm.io/tool/internal/filesystem"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestCopy(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	env, err := newTestEnv(ctx, t)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := env.initEmptyRepo(ctx, "."); err != nil {
+		t.Fatal(err)
+	}
+	err = env.root.Apply(
+		filesystem.Write("foo.txt", "foo\n"),
+		filesystem.Write("dir/a.txt", "a\n"),
+		filesystem.Write("dir/b.txt", "b\n"),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := env.addFiles(ctx, "foo.txt", "dir/a.txt", "dir/b.txt"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := env.newCommit(ctx, "."); err != nil {
+		t.Fatal(err)
+	}
+	// Untracked files are not copied along with their directory.
+	if err := env.root.Apply(filesystem.Write("dir/untracked.txt", "?\n")); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := env.gg(ctx, env.root.String(), "cp", "foo.txt", "copy.txt"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := env.gg(ctx, env.root.String(), "copy", "dir", "dir2"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := env.gg(ctx, env.root.String(), "copy", "foo.txt", "copy.txt"); err == nil {
+		t.Error("copy over an existing file did not return an error")
+	}
+	if err := env.root.Apply(filesystem.Write("manual.txt", "foo\n")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := env.gg(ctx, env.root.String(), "copy", "--after", "foo.txt", "manual.txt"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := env.gg(ctx, env.root.String(), "copy", "--after", "foo.txt", "dir2"); err == nil {
+		t.Error("copy --after of a file into a directory that does not contain it did not return an error")
+	}
+
+	if got, err := env.root.ReadFile("dir2/a.txt"); err != nil {
+		t.Error(err)
+	} else if got != "a\n" {
+		t.Errorf("dir2/a.txt = %q; want %q", got, "a\n")
+	}
+	st, err := env.git.Status(ctx, git.StatusOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := make(map[git.TopPath]string)
+	for _, ent := range st {
+		got[ent.Name] = string(ent.Code[:])
+	}
+	want := map[git.TopPath]string{
+		"copy.txt":          "A ",
+		"dir2/a.txt":        "A ",
+		"dir2/b.txt":        "A ",
+		"manual.txt":        "A ",
+		"dir/untracked.txt": "??",
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("status (-want +got):\n%s", diff)
+	}
+}
@@ -0,0 +1,93 @@
+// Copyright 2021 The gg Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"gg-scm.io/pkg/git"
+)
+
+// commandDefaults returns the default arguments for the named command from
+// the gg.defaults.<command> setting (for example, "[gg.defaults] commit = -v").
+// These arguments are inserted before the arguments given on the command line.
+// cfg is the user's Git configuration: defaults in a workspace configuration
+// file are never consulted.
+func commandDefaults(cfg *git.Config, name string) ([]string, error) {
+	name = canonicalCommandName(name)
+	value := cfg.Value("gg.defaults." + name)
+	if value == "" {
+		return nil, nil
+	}
+	args, err := splitDefaultArgs(value)
+	if err != nil {
+		return nil, fmt.Errorf("gg.defaults.%s: %w", name, err)
+	}
+	return args, nil
+}
+
+// splitDefaultArgs splits a gg.defaults value into arguments. Arguments are
+// separated by whitespace and may be quoted with single or double quotes.
+// A backslash escapes the next character outside of single quotes.
+func splitDefaultArgs(s string) ([]string, error) {
+	var args []string
+	sb := new(strings.Builder)
+	inArg := false
+	var quote byte
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case quote == '\'':
+			if c == '\'' {
+				quote = 0
+			} else {
+				sb.WriteByte(c)
+			}
+		case c == '\\':
+			if i+1 >= len(s) {
+				return nil, fmt.Errorf("trailing backslash in %q", s)
+			}
+			i++
+			sb.WriteByte(s[i])
+			inArg = true
+		case quote == '"':
+			if c == '"' {
+				quote = 0
+			} else {
+				sb.WriteByte(c)
+			}
+		case c == '\'' || c == '"':
+			quote = c
+			inArg = true
+		case c == ' ' || c == '\t' || c == '\n':
+			if inArg {
+				args = append(args, sb.String())
+				sb.Reset()
+				inArg = false
+			}
+		default:
+			sb.WriteByte(c)
+			inArg = true
+		}
+	}
+	if quote != 0 {
+		return nil, fmt.Errorf("unterminated quote in %q", s)
+	}
+	if inArg {
+		args = append(args, sb.String())
+	}
+	return args, nil
+}
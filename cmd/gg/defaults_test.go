@@ -0,0 +1,108 @@
+// Copyright 2021 The gg Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"gg-scm.io/tool/internal/filesystem"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestCommandDefaults(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	env, err := newTestEnv(ctx, t)
+	if err != nil {
+		t.Fatal(err)
+	}
+	config := "[gg.defaults]\n" +
+		"\tcommit = -m 'from defaults'\n" +
+		"\tidentify = --bogus\n"
+	if err := env.writeConfig([]byte(config)); err != nil {
+		t.Fatal(err)
+	}
+	if err := env.initRepoWithHistory(ctx, "repo"); err != nil {
+		t.Fatal(err)
+	}
+	// Defaults in the workspace configuration file are ignored.
+	if err := env.root.Apply(filesystem.Write("repo/.ggconfig", "[gg \"defaults\"]\n\tstatus = --bogus\n")); err != nil {
+		t.Fatal(err)
+	}
+	if err := env.addFiles(ctx, "repo/.ggconfig"); err != nil {
+		t.Fatal(err)
+	}
+	repoDir := env.root.FromSlash("repo")
+
+	if _, err := env.gg(ctx, repoDir, "ci"); err != nil {
+		t.Fatal(err)
+	}
+	info, err := env.git.WithDir(repoDir).CommitInfo(ctx, "HEAD")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := strings.TrimSpace(info.Message), "from defaults"; got != want {
+		t.Errorf("commit message = %q; want %q", got, want)
+	}
+
+	if _, err := env.gg(ctx, repoDir, "identify"); err == nil {
+		t.Error("gg identify with bad defaults did not return an error")
+	}
+	if _, err := env.gg(ctx, repoDir, "--ignore-defaults", "identify"); err != nil {
+		t.Error("gg --ignore-defaults identify:", err)
+	}
+	if _, err := env.gg(ctx, repoDir, "status"); err != nil {
+		t.Error("gg status:", err)
+	}
+}
+
+func TestSplitDefaultArgs(t *testing.T) {
+	tests := []struct {
+		s       string
+		want    []string
+		wantErr bool
+	}{
+		{s: "", want: nil},
+		{s: "  ", want: nil},
+		{s: "-v", want: []string{"-v"}},
+		{s: " -v  --stat ", want: []string{"-v", "--stat"}},
+		{s: `-m "hello world"`, want: []string{"-m", "hello world"}},
+		{s: `-m 'a "b"'`, want: []string{"-m", `a "b"`}},
+		{s: `-m a\ b`, want: []string{"-m", "a b"}},
+		{s: `--base=""`, want: []string{"--base="}},
+		{s: `""`, want: []string{""}},
+		{s: `-m 'it\'s'`, wantErr: true},
+		{s: `-m "foo`, wantErr: true},
+		{s: `foo\`, wantErr: true},
+	}
+	for _, test := range tests {
+		got, err := splitDefaultArgs(test.s)
+		if err != nil {
+			if !test.wantErr {
+				t.Errorf("splitDefaultArgs(%q): %v", test.s, err)
+			}
+			continue
+		}
+		if test.wantErr {
+			t.Errorf("splitDefaultArgs(%q) = %q, <nil>; want error", test.s, got)
+			continue
+		}
+		if !cmp.Equal(got, test.want) {
+			t.Errorf("splitDefaultArgs(%q) = %q; want %q", test.s, got, test.want)
+		}
+	}
+}
@@ -25,7 +25,15 @@ import (
 const diffSynopsis = "diff repository (or selected files)"
 
 func diff(ctx context.Context, cc *cmdContext, args []string) error {
-	f := flag.NewFlagSet(true, "gg diff [--stat] [-c REV | -r REV1 [-r REV2]] [FILE [...]]", diffSynopsis)
+	f := flag.NewFlagSet(true, "gg diff [--stat | --word-diff] [--color-moved] [-c REV | -r REV1 [-r REV2]] [FILE [...]]", diffSynopsis+`
+
+	`+"`--word-diff`"+` shows changed words within lines instead of whole
+	changed lines. `+"`--color-moved`"+` colors lines that were moved
+	rather than added or removed.
+
+	Output is colored when writing to a terminal, unless the
+	`+"`color.diff`"+` setting says otherwise. Without color, word
+	changes are marked with [-removed-] and {+added+}.`)
 	ignoreSpaceChange := f.Bool("b", false, "ignore changes in amount of whitespace")
 	f.Alias("b", "ignore-space-change")
 	ignoreBlankLines := f.Bool("B", false, "ignore changes whose lines are all blank")
@@ -42,19 +50,48 @@ func diff(ctx context.Context, cc *cmdContext, args []string) error {
 	renames := f.String("M", "50%", "report new files with the set `percent`age of similarity to a removed file as renamed")
 	copies := f.String("C", "50%", "report new files with the set `percent`age of similarity as copied")
 	copiesUnmodified := f.Bool("copies-unmodified", true, "whether to check unmodified files when detecting copies (can be expensive)")
+	wordDiff := f.Bool("word-diff", false, "show changed words instead of changed lines")
+	colorMoved := f.Bool("color-moved", false, "color moved lines differently from added and removed lines")
 	if err := f.Parse(args); flag.IsHelp(err) {
 		f.Help(cc.stdout)
 		return nil
 	} else if err != nil {
 		return usagef("%v", err)
 	}
+	if *stat && *wordDiff {
+		return usagef("can't pass both --stat and --word-diff")
+	}
+	cfg, err := cc.git.ReadConfig(ctx)
+	if err != nil {
+		return err
+	}
+	colorize, err := cfg.ColorBool("color.diff", cc.isTerminal())
+	if err != nil {
+		fmt.Fprintln(cc.stderr, "gg:", err)
+	}
 	var diffArgs []string
 	diffArgs = append(diffArgs, "diff")
+	if colorize {
+		diffArgs = append(diffArgs, "--color=always")
+	} else {
+		diffArgs = append(diffArgs, "--color=never")
+	}
 	if *stat {
 		diffArgs = append(diffArgs, "--stat")
 	} else {
 		diffArgs = append(diffArgs, fmt.Sprintf("-U%d", *ncontext))
 	}
+	if *wordDiff {
+		if colorize {
+			diffArgs = append(diffArgs, "--word-diff=color")
+		} else {
+			diffArgs = append(diffArgs, "--word-diff=plain")
+		}
+	}
+	if *colorMoved && colorize {
+		// Moved lines can only be shown with color.
+		diffArgs = append(diffArgs, "--color-moved=default")
+	}
 	if *ignoreSpaceChange {
 		diffArgs = append(diffArgs, "--ignore-space-change")
 	}
@@ -64,6 +64,39 @@ func TestDiff(t *testing.T) {
 	}
 }
 
+func TestDiff_WordDiff(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	env, err := newTestEnv(ctx, t)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := env.initEmptyRepo(ctx, "."); err != nil {
+		t.Fatal(err)
+	}
+	if err := env.root.Apply(filesystem.Write("foo.txt", "Hello, World!\n")); err != nil {
+		t.Fatal(err)
+	}
+	if err := env.addFiles(ctx, "foo.txt"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := env.newCommit(ctx, "."); err != nil {
+		t.Fatal(err)
+	}
+	if err := env.root.Apply(filesystem.Write("foo.txt", "Goodbye, World!\n")); err != nil {
+		t.Fatal(err)
+	}
+
+	out, err := env.gg(ctx, env.root.String(), "diff", "--word-diff")
+	if err != nil {
+		t.Error(err)
+	}
+	const want = "[-Hello,-]{+Goodbye,+} World!"
+	if !bytes.Contains(out, []byte(want)) {
+		t.Errorf("diff does not contain %q. Output:\n%s", want, out)
+	}
+}
+
 func TestDiff_NoChange(t *testing.T) {
 	t.Parallel()
 	ctx := context.Background()
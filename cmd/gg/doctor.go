@@ -0,0 +1,174 @@
+// Copyright 2021 The gg Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"gg-scm.io/tool/internal/flag"
+)
+
+const doctorSynopsis = "check the environment for common problems"
+
+func doctor(ctx context.Context, cc *cmdContext, args []string) error {
+	f := flag.NewFlagSet(true, "gg doctor", doctorSynopsis+`
+
+	Checks that the Git installation, editor, and saved credentials are
+	usable by gg and prints suggested fixes for any problems found. When
+	run inside a repository, it also checks the repository's state.`)
+	if err := f.Parse(args); flag.IsHelp(err) {
+		f.Help(cc.stdout)
+		return nil
+	} else if err != nil {
+		return usagef("%v", err)
+	}
+	if f.NArg() > 0 {
+		return usagef("no arguments expected")
+	}
+
+	d := &doctorReport{cc: cc}
+	version := d.checkGitVersion(ctx)
+	d.checkEditor(ctx)
+	d.checkGitHubToken()
+	if gitDir, err := cc.git.GitDir(ctx); err == nil {
+		d.checkFSMonitor(ctx, version)
+		d.checkIndexLock(gitDir)
+	}
+	if d.problems > 0 {
+		return fmt.Errorf("found %d problem(s)", d.problems)
+	}
+	return nil
+}
+
+// doctorReport accumulates the results of the checks run by gg doctor.
+type doctorReport struct {
+	cc       *cmdContext
+	problems int
+}
+
+func (d *doctorReport) ok(format string, args ...interface{}) {
+	fmt.Fprintf(d.cc.stdout, "ok:      %s\n", fmt.Sprintf(format, args...))
+}
+
+func (d *doctorReport) note(msg, fix string) {
+	fmt.Fprintf(d.cc.stdout, "note:    %s\n", msg)
+	if fix != "" {
+		fmt.Fprintf(d.cc.stdout, "         %s\n", fix)
+	}
+}
+
+func (d *doctorReport) problem(msg, fix string) {
+	d.problems++
+	fmt.Fprintf(d.cc.stdout, "problem: %s\n", msg)
+	if fix != "" {
+		fmt.Fprintf(d.cc.stdout, "         fix: %s\n", fix)
+	}
+}
+
+func (d *doctorReport) checkGitVersion(ctx context.Context) gitVersion {
+	version, err := queryGitVersion(ctx, d.cc.git)
+	if err != nil {
+		d.problem(fmt.Sprintf("could not determine Git version: %v", err),
+			"make sure Git is installed and on your PATH, or pass --git")
+		return gitVersion{}
+	}
+	if version.less(minGitVersion) {
+		d.problem(fmt.Sprintf("Git %v is older than the minimum supported version %v", version, minGitVersion),
+			"upgrade Git (see https://git-scm.com/downloads)")
+		return version
+	}
+	d.ok("Git %v (%s)", version, d.cc.git.Exe())
+	return version
+}
+
+func (d *doctorReport) checkEditor(ctx context.Context) {
+	out, err := d.cc.git.Output(ctx, "var", "GIT_EDITOR")
+	if err != nil {
+		d.problem(fmt.Sprintf("could not determine editor: %v", err),
+			"git config --global core.editor EDITOR")
+		return
+	}
+	editor := strings.TrimSpace(out)
+	fields := strings.Fields(editor)
+	if len(fields) == 0 {
+		d.problem("no editor configured", "git config --global core.editor EDITOR")
+		return
+	}
+	if _, err := exec.LookPath(strings.Trim(fields[0], `"'`)); err != nil {
+		d.problem(fmt.Sprintf("editor %q not found", editor),
+			"git config --global core.editor EDITOR")
+		return
+	}
+	d.ok("editor %s", editor)
+}
+
+func (d *doctorReport) checkGitHubToken() {
+	var path string
+	var info os.FileInfo
+	for _, dir := range d.cc.xdgDirs.configPaths() {
+		p := filepath.Join(dir, configDirname, gitHubTokenFilename)
+		if fi, err := os.Stat(p); err == nil {
+			path, info = p, fi
+			break
+		}
+	}
+	if path == "" {
+		d.note("not logged into GitHub", "run `gg github-login` to use `gg requestpull`")
+		return
+	}
+	if runtime.GOOS != "windows" && info.Mode().Perm()&0077 != 0 {
+		d.problem(fmt.Sprintf("GitHub token %s is accessible by other users", path),
+			"chmod 600 "+path)
+		return
+	}
+	d.ok("GitHub token %s", path)
+}
+
+// fsmonitorVersion is the first version of Git with a built-in
+// file system monitor.
+var fsmonitorVersion = gitVersion{2, 36, 0}
+
+func (d *doctorReport) checkFSMonitor(ctx context.Context, version gitVersion) {
+	cfg, err := d.cc.git.ReadConfig(ctx)
+	if err != nil {
+		d.problem(fmt.Sprintf("could not read configuration: %v", err), "")
+		return
+	}
+	if v := cfg.Value("core.fsmonitor"); v != "" {
+		d.ok("file system monitor enabled (core.fsmonitor = %s)", v)
+		return
+	}
+	if version.less(fsmonitorVersion) {
+		return
+	}
+	d.note("file system monitor not enabled",
+		"for large repositories, `git config core.fsmonitor true` can speed up `gg status`")
+}
+
+func (d *doctorReport) checkIndexLock(gitDir string) {
+	lockPath := filepath.Join(gitDir, "index.lock")
+	if _, err := os.Stat(lockPath); err == nil {
+		d.problem(fmt.Sprintf("index is locked (%s exists)", lockPath),
+			"if no other Git process is running, remove "+lockPath)
+		return
+	}
+	d.ok("index is not locked")
+}
@@ -0,0 +1,164 @@
+// Copyright 2021 The gg Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"runtime"
+	"testing"
+
+	"gg-scm.io/tool/internal/filesystem"
+)
+
+func TestDoctor_IndexLock(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	env, err := newTestEnv(ctx, t)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := env.initRepoWithHistory(ctx, "."); err != nil {
+		t.Fatal(err)
+	}
+	if err := env.root.Apply(filesystem.Write(".git/index.lock", "")); err != nil {
+		t.Fatal(err)
+	}
+
+	out, err := env.gg(ctx, env.root.String(), "doctor")
+	if err == nil {
+		t.Error("gg doctor did not return an error")
+	} else if isUsage(err) {
+		t.Error(err)
+	}
+	if !bytes.Contains(out, []byte("problem: index is locked")) {
+		t.Errorf("output does not report index lock. Output:\n%s", out)
+	}
+}
+
+func TestGitTooOld(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("fake Git executable is a shell script")
+	}
+	t.Parallel()
+	ctx := context.Background()
+	env, err := newTestEnv(ctx, t)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := env.initRepoWithHistory(ctx, "."); err != nil {
+		t.Fatal(err)
+	}
+	oldGit := env.topDir.FromSlash("oldgit")
+	if err := ioutil.WriteFile(oldGit, []byte("#!/bin/sh\necho 'git version 2.1.0'\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, args := range [][]string{{"status"}, {"version", "--check"}} {
+		_, err := env.gg(ctx, env.root.String(), append([]string{"--git=" + oldGit}, args...)...)
+		if err == nil {
+			t.Errorf("gg %q did not return an error", args)
+			continue
+		}
+		var envErr *gitEnvError
+		if !errors.As(err, &envErr) {
+			t.Errorf("gg %q = %v; want *gitEnvError", args, err)
+			continue
+		}
+		if want := (gitVersion{2, 1, 0}); envErr.version != want {
+			t.Errorf("gg %q reported version %v; want %v", args, envErr.version, want)
+		}
+	}
+}
+
+func TestVersionCheck(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	env, err := newTestEnv(ctx, t)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out, err := env.gg(ctx, env.root.String(), "version", "--check")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Contains(out, []byte("minimum supported version "+minGitVersion.String())) {
+		t.Errorf("output does not mention minimum Git version. Output:\n%s", out)
+	}
+}
+
+func TestVersionJSON(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	env, err := newTestEnv(ctx, t)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out, err := env.gg(ctx, env.root.String(), "version", "--json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got versionJSON
+	if err := json.Unmarshal(out, &got); err != nil {
+		t.Fatalf("%v; output:\n%s", err, out)
+	}
+	if got.Go != runtime.Version() {
+		t.Errorf("go = %q; want %q", got.Go, runtime.Version())
+	}
+	if got.Git.MinimumVersion != minGitVersion.String() {
+		t.Errorf("git.minimumVersion = %q; want %q", got.Git.MinimumVersion, minGitVersion)
+	}
+	if !got.Git.Supported || got.Git.Version == "" {
+		t.Errorf("git = %+v; want supported version", got.Git)
+	}
+	if got.LatestRelease != nil {
+		t.Errorf("latestRelease = %+v without --check-update", got.LatestRelease)
+	}
+}
+
+func TestParseGitVersion(t *testing.T) {
+	tests := []struct {
+		s       string
+		want    gitVersion
+		wantErr bool
+	}{
+		{s: "git version 2.30.1\n", want: gitVersion{2, 30, 1}},
+		{s: "git version 2.24.3 (Apple Git-128)\n", want: gitVersion{2, 24, 3}},
+		{s: "git version 2.31.1.windows.1\n", want: gitVersion{2, 31, 1}},
+		{s: "git version 2.32.rc0\n", want: gitVersion{2, 32, 0}},
+		{s: "git version 2.17\n", want: gitVersion{2, 17, 0}},
+		{s: "hg version 5.0\n", wantErr: true},
+		{s: "git version x.y\n", wantErr: true},
+	}
+	for _, test := range tests {
+		got, err := parseGitVersion(test.s)
+		if err != nil {
+			if !test.wantErr {
+				t.Errorf("parseGitVersion(%q) = _, %v; want %v, <nil>", test.s, err, test.want)
+			}
+			continue
+		}
+		if test.wantErr {
+			t.Errorf("parseGitVersion(%q) = %v, <nil>; want error", test.s, got)
+			continue
+		}
+		if got != test.want {
+			t.Errorf("parseGitVersion(%q) = %v, <nil>; want %v, <nil>", test.s, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,290 @@
+// Copyright 2021 The gg Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gg-scm.io/pkg/git"
+	"gg-scm.io/tool/internal/flag"
+	"gg-scm.io/tool/internal/revset"
+)
+
+const exportSynopsis = "write commits as patches"
+
+// Names of files in a review bundle directory.
+const (
+	reviewManifestFilename    = "manifest.json"
+	reviewPullRequestFilename = "pull-request.txt"
+	reviewPatchDir            = "patches"
+)
+
+// reviewManifest is the machine-readable description of a review bundle.
+type reviewManifest struct {
+	Version int    `json:"version"`
+	Branch  string `json:"branch,omitempty"`
+	Base    string `json:"base"`
+	Head    string `json:"head"`
+	// Commits is the list of commits in the bundle, oldest first.
+	Commits []reviewManifestCommit `json:"commits"`
+}
+
+type reviewManifestCommit struct {
+	Hash    string `json:"hash"`
+	Author  string `json:"author"`
+	Subject string `json:"subject"`
+	// Patch is the slash-separated path of the commit's patch file,
+	// relative to the bundle directory.
+	Patch string `json:"patch"`
+}
+
+func export(ctx context.Context, cc *cmdContext, args []string) error {
+	f := flag.NewFlagSet(true, "gg export [-r REV [...]] [-o DIR]\n"+
+		"gg export --review [-o DIR] [--base=REV] [BRANCH]", exportSynopsis+`
+
+	Write the given revisions (defaults to HEAD) as patches in the mbox
+	format used by `+"`git format-patch`"+`, suitable for sending by email
+	and applying with `+"`gg import`"+`. `+"`-r`"+` may be given multiple
+	times and accepts ranges of the form `+"`A..B`"+`. The patches are
+	written to stdout unless `+"`-o`"+` names a directory to write one
+	file per patch into. Merge commits are skipped.
+
+	`+revsetHelp+` For example, `+"`gg export -r 'draft()'`"+` exports
+	every commit that hasn't been pushed.
+
+	With `+"`--review`"+`, write the commits on the given branch (defaults to the one currently
+	checked out) that are not in its upstream to a directory so they can be
+	attached to an issue or sent by email. The directory contains one patch
+	per commit, a `+"`"+reviewManifestFilename+"`"+` file describing the stack
+	of commits, and a `+"`"+reviewPullRequestFilename+"`"+` file with the pull
+	request message that `+"`gg requestpull`"+` would use. Merge commits
+	cannot be exported.
+
+	Use `+"`gg import --review`"+` to apply the bundle in another repository.`)
+	base := f.String("base", "", "export commits after the given `rev`ision (defaults to the branch's upstream)")
+	output := f.String("o", "", "`dir`ectory to write to (defaults to stdout, or BRANCH.review with --review)")
+	f.Alias("o", "output")
+	review := f.Bool("review", false, "write a review bundle")
+	revs := f.MultiString("r", "`rev`ision, range, or revision set to export")
+	if err := f.Parse(args); flag.IsHelp(err) {
+		f.Help(cc.stdout)
+		return nil
+	} else if err != nil {
+		return usagef("%v", err)
+	}
+	if !*review {
+		if *base != "" || f.NArg() > 0 {
+			return usagef("--base and BRANCH can only be used with --review")
+		}
+		if len(*revs) == 0 {
+			*revs = []string{git.Head.String()}
+		}
+		return exportPatches(ctx, cc, *revs, *output)
+	}
+	if len(*revs) > 0 {
+		return usagef("can't pass -r with --review")
+	}
+	if f.NArg() > 1 {
+		return usagef("only one branch allowed")
+	}
+	if strings.HasPrefix(*base, "-") {
+		return usagef("revisions must not start with '-'")
+	}
+	branch := f.Arg(0)
+	if branch == "" {
+		branch = currentBranch(ctx, cc)
+		if branch == "" {
+			return errors.New("no branch currently checked out; please specify a branch")
+		}
+	}
+	tip, err := cc.git.ParseRev(ctx, git.BranchRef(branch).String())
+	if err != nil {
+		return err
+	}
+	if *base == "" {
+		*base = branch + "@{upstream}"
+	}
+	baseHash, err := cc.git.MergeBase(ctx, *base, tip.Commit.String())
+	if err != nil {
+		return err
+	}
+	if *output == "" {
+		*output = strings.ReplaceAll(branch, "/", "-") + ".review"
+	}
+	dir := cc.abs(*output)
+	manifest, err := writeReviewBundle(ctx, cc, dir, baseHash, tip.Commit)
+	if err != nil {
+		return err
+	}
+	manifest.Branch = branch
+	manifestJSON, err := json.MarshalIndent(manifest, "", "\t")
+	if err != nil {
+		return fmt.Errorf("export: %w", err)
+	}
+	manifestJSON = append(manifestJSON, '\n')
+	if err := ioutil.WriteFile(filepath.Join(dir, reviewManifestFilename), manifestJSON, 0o666); err != nil {
+		return fmt.Errorf("export: %w", err)
+	}
+
+	cfg, err := cc.git.ReadConfig(ctx)
+	if err != nil {
+		return err
+	}
+	title, body, err := inferPullRequestMessage(ctx, cc.git, baseHash.String(), tip.Commit.String())
+	if err != nil {
+		return err
+	}
+	if desc := branchDescription(cfg, branch); desc != "" {
+		body = strings.TrimSpace(desc + "\n\n" + body)
+	}
+	msg := title + "\n"
+	if body != "" {
+		msg += "\n" + body + "\n"
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, reviewPullRequestFilename), []byte(msg), 0o666); err != nil {
+		return fmt.Errorf("export: %w", err)
+	}
+	_, err = fmt.Fprintf(cc.stdout, "Exported %d commit(s) to %s\n", len(manifest.Commits), *output)
+	return err
+}
+
+// exportPatches writes the commits named by revs as patches, either to
+// stdout if dir is empty or as numbered files in dir.
+func exportPatches(ctx context.Context, cc *cmdContext, revs []string, dir string) error {
+	// Export each commit selected by a revision set as if it had been
+	// given on its own.
+	var expanded []string
+	for _, rev := range revs {
+		if !revset.IsExpression(rev) {
+			expanded = append(expanded, rev)
+			continue
+		}
+		commits, err := listRevset(ctx, cc.git, rev, true)
+		if err != nil {
+			return err
+		}
+		if len(commits) == 0 {
+			return fmt.Errorf("export %s: no commits selected", rev)
+		}
+		for _, c := range commits {
+			expanded = append(expanded, c.String())
+		}
+	}
+
+	next := 1
+	for _, rev := range expanded {
+		if strings.HasPrefix(rev, "-") {
+			return usagef("revisions must not start with '-'")
+		}
+		args := []string{"format-patch"}
+		if dir == "" {
+			args = append(args, "--stdout")
+		} else {
+			// Number patches across all the ranges.
+			args = append(args, "--output-directory="+cc.abs(dir), fmt.Sprintf("--start-number=%d", next))
+		}
+		if strings.Contains(rev, "..") {
+			args = append(args, rev)
+		} else {
+			args = append(args, "-1", rev)
+		}
+		args = append(args, "--")
+		if dir == "" {
+			err := cc.git.Runner().RunGit(ctx, &git.Invocation{
+				Args:   args,
+				Dir:    cc.dir,
+				Stdout: cc.stdout,
+				Stderr: cc.stderr,
+			})
+			if err != nil {
+				return fmt.Errorf("export %s: %w", rev, err)
+			}
+			continue
+		}
+		out, err := cc.git.Output(ctx, args...)
+		if err != nil {
+			return fmt.Errorf("export %s: %w", rev, err)
+		}
+		for _, p := range strings.Split(strings.TrimSuffix(out, "\n"), "\n") {
+			if p == "" {
+				continue
+			}
+			if _, err := fmt.Fprintln(cc.stdout, p); err != nil {
+				return err
+			}
+			next++
+		}
+	}
+	return nil
+}
+
+// writeReviewBundle creates dir and writes patches for the commits in
+// base..head into it. It returns a manifest describing the patches.
+func writeReviewBundle(ctx context.Context, cc *cmdContext, dir string, base, head git.Hash) (*reviewManifest, error) {
+	manifest := &reviewManifest{
+		Version: 1,
+		Base:    base.String(),
+		Head:    head.String(),
+	}
+	commits, err := cc.git.Log(ctx, git.LogOptions{
+		Revs:    []string{base.String() + ".." + head.String()},
+		Reverse: true,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("export: %w", err)
+	}
+	for commits.Next() {
+		c := commits.CommitInfo()
+		if len(c.Parents) > 1 {
+			commits.Close()
+			return nil, fmt.Errorf("export: %v is a merge commit", c.SHA1().Short())
+		}
+		manifest.Commits = append(manifest.Commits, reviewManifestCommit{
+			Hash:    c.SHA1().String(),
+			Author:  string(c.Author),
+			Subject: c.Summary(),
+		})
+	}
+	if err := commits.Close(); err != nil {
+		return nil, fmt.Errorf("export: %w", err)
+	}
+	if len(manifest.Commits) == 0 {
+		return nil, fmt.Errorf("export: no commits after %v", base.Short())
+	}
+
+	if err := os.Mkdir(dir, 0o777); err != nil {
+		return nil, fmt.Errorf("export: %w", err)
+	}
+	patchDir := filepath.Join(dir, reviewPatchDir)
+	out, err := cc.git.Output(ctx, "format-patch", "--output-directory="+patchDir, base.String()+".."+head.String())
+	if err != nil {
+		return nil, fmt.Errorf("export: %w", err)
+	}
+	patches := strings.Split(strings.TrimSuffix(out, "\n"), "\n")
+	if len(patches) != len(manifest.Commits) {
+		return nil, fmt.Errorf("export: git format-patch wrote %d patches for %d commits", len(patches), len(manifest.Commits))
+	}
+	for i, p := range patches {
+		manifest.Commits[i].Patch = reviewPatchDir + "/" + filepath.Base(p)
+	}
+	return manifest, nil
+}
@@ -0,0 +1,197 @@
+// Copyright 2021 The gg Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"gg-scm.io/pkg/git"
+	"gg-scm.io/tool/internal/filesystem"
+)
+
+func TestExportImport_Review(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	env, err := newTestEnv(ctx, t)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := env.initRepoWithHistory(ctx, "origin"); err != nil {
+		t.Fatal(err)
+	}
+	if err := env.git.Run(ctx, "clone", "--quiet", "origin", "local"); err != nil {
+		t.Fatal(err)
+	}
+	localDir := env.root.FromSlash("local")
+	localGit := env.git.WithDir(localDir)
+	err = localGit.NewBranch(ctx, "feature", git.BranchOptions{
+		StartPoint: "origin/main",
+		Track:      true,
+		Checkout:   true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"a.txt", "b.txt"} {
+		if err := env.root.Apply(filesystem.Write("local/"+name, dummyContent)); err != nil {
+			t.Fatal(err)
+		}
+		if err := env.addFiles(ctx, "local/"+name); err != nil {
+			t.Fatal(err)
+		}
+		if err := localGit.Commit(ctx, "Add "+name+"\n\nDetails about "+name, git.CommitOptions{}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if _, err := env.gg(ctx, localDir, "export", "--review", "-o", "../bundle"); err != nil {
+		t.Fatal(err)
+	}
+	manifestJSON, err := ioutil.ReadFile(env.root.FromSlash("bundle/" + reviewManifestFilename))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var manifest reviewManifest
+	if err := json.Unmarshal(manifestJSON, &manifest); err != nil {
+		t.Fatal(err)
+	}
+	if manifest.Branch != "feature" {
+		t.Errorf("manifest branch = %q; want \"feature\"", manifest.Branch)
+	}
+	if len(manifest.Commits) != 2 {
+		t.Fatalf("manifest has %d commits; want 2", len(manifest.Commits))
+	}
+	if got, want := manifest.Commits[0].Subject, "Add a.txt"; got != want {
+		t.Errorf("manifest.Commits[0].Subject = %q; want %q", got, want)
+	}
+	prMessage, err := ioutil.ReadFile(env.root.FromSlash("bundle/" + reviewPullRequestFilename))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(string(prMessage), "Add a.txt\n\n") || !strings.Contains(string(prMessage), "* Add b.txt") {
+		t.Errorf("%s = %q; want title \"Add a.txt\" and bullet for b.txt", reviewPullRequestFilename, prMessage)
+	}
+
+	// Apply the bundle to a fresh clone.
+	if err := env.git.Run(ctx, "clone", "--quiet", "origin", "other"); err != nil {
+		t.Fatal(err)
+	}
+	otherDir := env.root.FromSlash("other")
+	if _, err := env.gg(ctx, otherDir, "import", "--review", "../bundle"); err != nil {
+		t.Fatal(err)
+	}
+	otherGit := env.git.WithDir(otherDir)
+	head, err := otherGit.Head(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if head.Ref != git.BranchRef("feature") {
+		t.Errorf("after import, HEAD = %v; want refs/heads/feature", head.Ref)
+	}
+	for i, rev := range []string{"HEAD~1", "HEAD"} {
+		info, err := otherGit.CommitInfo(ctx, rev)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got, want := info.Summary(), manifest.Commits[i].Subject; got != want {
+			t.Errorf("%s summary = %q; want %q", rev, got, want)
+		}
+	}
+	if base, err := otherGit.ParseRev(ctx, "HEAD~2"); err != nil {
+		t.Error(err)
+	} else if base.Commit.String() != manifest.Base {
+		t.Errorf("HEAD~2 = %v; want %s", base.Commit, manifest.Base)
+	}
+
+	if _, err := env.gg(ctx, otherDir, "import", "--review", "../bundle"); err == nil {
+		t.Error("importing a bundle onto an existing branch did not return an error")
+	}
+}
+
+func TestExportImport_Patches(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	env, err := newTestEnv(ctx, t)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := env.initRepoWithHistory(ctx, "origin"); err != nil {
+		t.Fatal(err)
+	}
+	if err := env.git.Run(ctx, "clone", "--quiet", "origin", "local"); err != nil {
+		t.Fatal(err)
+	}
+	localDir := env.root.FromSlash("local")
+	localGit := env.git.WithDir(localDir)
+	for _, name := range []string{"a.txt", "b.txt", "c.txt"} {
+		if err := env.root.Apply(filesystem.Write("local/"+name, dummyContent)); err != nil {
+			t.Fatal(err)
+		}
+		if err := env.addFiles(ctx, "local/"+name); err != nil {
+			t.Fatal(err)
+		}
+		if err := localGit.Commit(ctx, "Add "+name, git.CommitOptions{}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Export the first commit on its own and the last one as a range.
+	out, err := env.gg(ctx, localDir, "export", "-r", "HEAD~2", "-r", "HEAD~..HEAD", "-o", "../patches")
+	if err != nil {
+		t.Fatal(err)
+	}
+	patches := strings.Split(strings.TrimSuffix(string(out), "\n"), "\n")
+	if len(patches) != 2 {
+		t.Fatalf("export wrote %q; want 2 patch files", patches)
+	}
+	if !strings.HasPrefix(filepath.Base(patches[1]), "0002-") {
+		t.Errorf("second patch = %q; want numbered 0002", patches[1])
+	}
+	stdout, err := env.gg(ctx, localDir, "export")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(stdout), "Subject: [PATCH] Add c.txt") {
+		t.Errorf("export to stdout = %q; want patch for HEAD", stdout)
+	}
+
+	if err := env.git.Run(ctx, "clone", "--quiet", "origin", "other"); err != nil {
+		t.Fatal(err)
+	}
+	otherDir := env.root.FromSlash("other")
+	if _, err := env.gg(ctx, otherDir, append([]string{"import"}, patches...)...); err != nil {
+		t.Fatal(err)
+	}
+	otherGit := env.git.WithDir(otherDir)
+	for rev, want := range map[string]string{"HEAD~1": "Add a.txt", "HEAD": "Add c.txt"} {
+		info, err := otherGit.CommitInfo(ctx, rev)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := info.Summary(); got != want {
+			t.Errorf("%s summary = %q; want %q", rev, got, want)
+		}
+	}
+	if exists, err := env.root.Exists("other/b.txt"); err != nil {
+		t.Error(err)
+	} else if exists {
+		t.Error("other/b.txt exists after import; want only exported commits applied")
+	}
+}
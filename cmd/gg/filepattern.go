@@ -0,0 +1,164 @@
+// Copyright 2021 The gg Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"gg-scm.io/pkg/git"
+)
+
+// filePatternHelp describes the pattern prefixes that expandFilePatterns
+// understands for commands' help text.
+const filePatternHelp = `FILE arguments may also be Mercurial-style patterns. ` + "`glob:PATTERN`" + `
+	matches files with a shell-style glob relative to the current
+	directory, where ` + "`**`" + ` matches any number of directories, like
+	` + "`glob:**/*.go`" + `. ` + "`re:REGEX`" + ` matches files whose path from the top
+	of the working copy matches a regular expression anchored at the
+	start, like ` + "`re:.*_test\\.go$`" + `.`
+
+// Mercurial-style pattern prefixes for FILE arguments.
+const (
+	globPatternPrefix   = "glob:"
+	regexpPatternPrefix = "re:"
+)
+
+// fileArgs is the result of expanding FILE arguments.
+type fileArgs struct {
+	paths   []string       // arguments that are not patterns
+	matches []string       // files matched by re: patterns, relative to the current directory
+	globs   []git.Pathspec // glob: patterns as Git pathspecs
+}
+
+// files returns the arguments that name files or directories.
+func (fa *fileArgs) files() []string {
+	files := make([]string, 0, len(fa.paths)+len(fa.matches))
+	files = append(files, fa.paths...)
+	files = append(files, fa.matches...)
+	return files
+}
+
+// pathspecs returns Git pathspecs that match the same files as the
+// arguments, treating non-pattern arguments as literal paths.
+func (fa *fileArgs) pathspecs() []git.Pathspec {
+	pathspecs := make([]git.Pathspec, 0, len(fa.paths)+len(fa.matches)+len(fa.globs))
+	for _, p := range fa.files() {
+		pathspecs = append(pathspecs, git.LiteralPath(p))
+	}
+	return append(pathspecs, fa.globs...)
+}
+
+// isEmpty reports whether there are no arguments.
+func (fa *fileArgs) isEmpty() bool {
+	return len(fa.paths)+len(fa.matches)+len(fa.globs) == 0
+}
+
+// expandFilePatterns interprets the FILE arguments that start with "glob:"
+// or "re:". glob: patterns are converted to Git glob pathspecs. re:
+// patterns are replaced by the paths of the tracked and untracked (but not
+// ignored) files that they match, relative to the current directory. It is
+// an error for a re: pattern to not match any files.
+func expandFilePatterns(ctx context.Context, cc *cmdContext, args []string) (*fileArgs, error) {
+	fa := new(fileArgs)
+	var regexps []string
+	for _, arg := range args {
+		switch {
+		case strings.HasPrefix(arg, globPatternPrefix):
+			pattern := arg[len(globPatternPrefix):]
+			if pattern == "" {
+				return nil, usagef("%s: empty pattern", arg)
+			}
+			fa.globs = append(fa.globs, git.Pathspec(":(glob)"+pattern))
+		case strings.HasPrefix(arg, regexpPatternPrefix):
+			regexps = append(regexps, arg)
+		default:
+			fa.paths = append(fa.paths, arg)
+		}
+	}
+	if len(regexps) == 0 {
+		return fa, nil
+	}
+
+	prefix, err := cc.git.Output(ctx, "rev-parse", "--show-prefix")
+	if err != nil {
+		return nil, err
+	}
+	prefix = strings.TrimSuffix(strings.TrimSuffix(prefix, "\n"), "/")
+	candidates, err := patternCandidates(ctx, cc.git)
+	if err != nil {
+		return nil, err
+	}
+	for _, arg := range regexps {
+		re, err := regexp.Compile("^(?:" + arg[len(regexpPatternPrefix):] + ")")
+		if err != nil {
+			return nil, usagef("%s: %v", arg, err)
+		}
+		n := 0
+		for _, name := range candidates {
+			if !re.MatchString(name) {
+				continue
+			}
+			rel, err := filepath.Rel(filepath.FromSlash(prefix), filepath.FromSlash(name))
+			if err != nil {
+				return nil, err
+			}
+			fa.matches = append(fa.matches, rel)
+			n++
+		}
+		if n == 0 {
+			return nil, fmt.Errorf("%s: no matching files", arg)
+		}
+	}
+	return fa, nil
+}
+
+// patternCandidates returns the slash-separated paths, relative to the
+// top of the working copy, of the files that patterns can match: tracked
+// files, untracked files that aren't ignored, and files that have been
+// removed from the index. The paths are sorted.
+func patternCandidates(ctx context.Context, g *git.Git) ([]string, error) {
+	out, err := g.Output(ctx, "ls-files", "-z", "--cached", "--others", "--exclude-standard", "--full-name", "--", ":/")
+	if err != nil {
+		return nil, err
+	}
+	seen := make(map[string]struct{})
+	var names []string
+	addName := func(name string) {
+		if _, dup := seen[name]; name == "" || dup {
+			return
+		}
+		seen[name] = struct{}{}
+		names = append(names, name)
+	}
+	for _, name := range strings.Split(out, "\x00") {
+		addName(name)
+	}
+	st, err := g.Status(ctx, git.StatusOptions{DisableRenames: true})
+	if err != nil {
+		return nil, err
+	}
+	for _, ent := range st {
+		if ent.Code.IsRemoved() {
+			addName(ent.Name.String())
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
@@ -0,0 +1,87 @@
+// Copyright 2021 The gg Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"testing"
+
+	"gg-scm.io/tool/internal/filesystem"
+)
+
+func TestFilePatterns(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	env, err := newTestEnv(ctx, t)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := env.initEmptyRepo(ctx, "."); err != nil {
+		t.Fatal(err)
+	}
+	names := []string{"README", "main.go", "sub/lib.go", "sub/lib_test.go"}
+	for _, name := range names {
+		if err := env.root.Apply(filesystem.Write(name, "original\n")); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := env.addFiles(ctx, names...); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := env.newCommit(ctx, "."); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range names {
+		if err := env.root.Apply(filesystem.Write(name, "changed\n")); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := env.root.Apply(filesystem.Write("sub/new_test.go", "new\n")); err != nil {
+		t.Fatal(err)
+	}
+
+	// re: patterns are matched from the top of the working copy.
+	if _, err := env.gg(ctx, env.root.FromSlash("sub"), "add", `re:.*_test\.go$`); err != nil {
+		t.Fatal(err)
+	}
+	if out, err := env.git.Output(ctx, "ls-files", "sub/new_test.go"); err != nil {
+		t.Fatal(err)
+	} else if out != "sub/new_test.go\n" {
+		t.Errorf("after add re:, sub/new_test.go is not tracked")
+	}
+
+	// glob: patterns are relative to the current directory.
+	if _, err := env.gg(ctx, env.root.FromSlash("sub"), "commit", "-m", "tests", "glob:*_test.go"); err != nil {
+		t.Fatal(err)
+	}
+	if out, err := env.git.Output(ctx, "diff", "--name-only", "HEAD~", "HEAD"); err != nil {
+		t.Fatal(err)
+	} else if want := "sub/lib_test.go\nsub/new_test.go\n"; out != want {
+		t.Errorf("committed files = %q; want %q", out, want)
+	}
+
+	if _, err := env.gg(ctx, env.root.String(), "revert", "--no-backup", "glob:**/*.go"); err != nil {
+		t.Fatal(err)
+	}
+	if out, err := env.git.Output(ctx, "diff", "--name-only", "HEAD"); err != nil {
+		t.Fatal(err)
+	} else if want := "README\n"; out != want {
+		t.Errorf("after revert glob:**/*.go, changed files = %q; want %q", out, want)
+	}
+
+	if _, err := env.gg(ctx, env.root.String(), "status", "re:nothing"); err == nil {
+		t.Error("status with pattern that matches nothing did not return an error")
+	}
+}
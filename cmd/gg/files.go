@@ -0,0 +1,79 @@
+// Copyright 2021 The gg Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bufio"
+	"context"
+	"sort"
+	"strings"
+
+	"gg-scm.io/pkg/git"
+	"gg-scm.io/tool/internal/flag"
+)
+
+const filesSynopsis = "list tracked files"
+
+func files(ctx context.Context, cc *cmdContext, args []string) error {
+	f := flag.NewFlagSet(true, "gg files [-r REV] [-0] [PATTERN [...]]", filesSynopsis+`
+
+	List the files tracked at the given revision (defaults to HEAD),
+	sorted by name. If patterns are given, only files matching at least
+	one of them are listed. Patterns are Git pathspecs relative to the
+	current directory, so `+"`'*.go'`"+` matches Go files in any
+	subdirectory. Paths are printed relative to the top of the working
+	copy.
+
+	`+"`-0`"+` ends each path with a NUL byte instead of a newline, for use
+	with `+"`xargs -0`"+`. `+"`gg manifest`"+` is an alias for files.`)
+	rev := f.String("r", git.Head.String(), "`rev`ision to list files from")
+	nul := f.Bool("0", false, "end paths with NUL bytes instead of newlines")
+	f.Alias("0", "print0")
+	if err := f.Parse(args); flag.IsHelp(err) {
+		f.Help(cc.stdout)
+		return nil
+	} else if err != nil {
+		return usagef("%v", err)
+	}
+	r, err := cc.git.ParseRev(ctx, *rev)
+	if err != nil {
+		return err
+	}
+	// git ls-tree doesn't expand wildcards in pathspecs, so diff the
+	// revision against the empty tree instead.
+	diffArgs := []string{"diff-tree", "-r", "-z", "--name-only", "--no-renames", emptyTreeHash, r.Commit.String(), "--"}
+	diffArgs = append(diffArgs, f.Args()...)
+	listing, err := cc.git.Output(ctx, diffArgs...)
+	if err != nil {
+		return err
+	}
+	var paths []string
+	for _, p := range strings.Split(listing, "\x00") {
+		if p != "" {
+			paths = append(paths, p)
+		}
+	}
+	sort.Strings(paths)
+	end := byte('\n')
+	if *nul {
+		end = 0
+	}
+	out := bufio.NewWriter(cc.stdout)
+	for _, p := range paths {
+		out.WriteString(p)
+		out.WriteByte(end)
+	}
+	return out.Flush()
+}
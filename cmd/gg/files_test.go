@@ -0,0 +1,83 @@
+// Copyright 2021 The gg Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"testing"
+
+	"gg-scm.io/tool/internal/filesystem"
+)
+
+func TestFiles(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	env, err := newTestEnv(ctx, t)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := env.initEmptyRepo(ctx, "."); err != nil {
+		t.Fatal(err)
+	}
+	err = env.root.Apply(
+		filesystem.Write("b.go", dummyContent),
+		filesystem.Write("a.txt", dummyContent),
+		filesystem.Write("sub/c.go", dummyContent),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := env.addFiles(ctx, "a.txt", "b.go", "sub/c.go"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := env.newCommit(ctx, "."); err != nil {
+		t.Fatal(err)
+	}
+	// Uncommitted files are not listed.
+	if err := env.root.Apply(filesystem.Write("d.go", dummyContent)); err != nil {
+		t.Fatal(err)
+	}
+	if err := env.addFiles(ctx, "d.go"); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		args []string
+		want string
+	}{
+		{
+			args: []string{"files"},
+			want: "a.txt\nb.go\nsub/c.go\n",
+		},
+		{
+			args: []string{"files", "*.go"},
+			want: "b.go\nsub/c.go\n",
+		},
+		{
+			args: []string{"manifest", "-0", "sub"},
+			want: "sub/c.go\x00",
+		},
+	}
+	for _, test := range tests {
+		out, err := env.gg(ctx, env.root.String(), test.args...)
+		if err != nil {
+			t.Errorf("gg %q: %v", test.args, err)
+			continue
+		}
+		if string(out) != test.want {
+			t.Errorf("gg %q = %q; want %q", test.args, out, test.want)
+		}
+	}
+}
@@ -0,0 +1,247 @@
+// Copyright 2021 The gg Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"gg-scm.io/pkg/git"
+	"gg-scm.io/pkg/git/object"
+	"gg-scm.io/tool/internal/flag"
+)
+
+const foldSynopsis = "combine a range of commits into one"
+
+func fold(ctx context.Context, cc *cmdContext, args []string) error {
+	f := flag.NewFlagSet(true, "gg fold [-m MSG] --from=REV\n"+
+		"gg fold [-m MSG] -r FIRST::LAST", foldSynopsis+`
+
+	fold replaces a contiguous range of commits with a single commit that
+	has their combined changes. Commits after the range are rebased on top
+	of the new commit.
+
+	`+"`--from`"+` folds the given revision and every commit after it up to
+	the working copy's parent. `+"`-r FIRST::LAST`"+` folds FIRST, LAST, and
+	the commits between them. LAST must be an ancestor of the working
+	copy's parent. The range may not contain merges or commits that are
+	already in the current branch's upstream.
+
+	The new commit keeps the author of the first commit in the range.
+	Unless `+"`-m`"+` is given, the editor is opened with the messages of
+	all the folded commits.`)
+	from := f.String("from", "", "first `rev`ision to fold into the working copy's parent")
+	rangeArg := f.String("r", "", "`range` of revisions to fold")
+	msg := f.String("m", "", "use text as commit `message`")
+	if err := f.Parse(args); flag.IsHelp(err) {
+		f.Help(cc.stdout)
+		return nil
+	} else if err != nil {
+		return usagef("%v", err)
+	}
+	if f.NArg() > 0 {
+		return usagef("fold takes no arguments")
+	}
+	if (*from == "") == (*rangeArg == "") {
+		return usagef("must pass exactly one of --from or -r")
+	}
+	first, last := *from, git.Head.String()
+	if *rangeArg != "" {
+		i := strings.Index(*rangeArg, "::")
+		if i == -1 {
+			return usagef("-r must be a range of the form FIRST::LAST")
+		}
+		first, last = (*rangeArg)[:i], (*rangeArg)[i+len("::"):]
+		if first == "" || last == "" {
+			return usagef("-r must be a range of the form FIRST::LAST")
+		}
+	}
+	if strings.HasPrefix(first, "-") || strings.HasPrefix(last, "-") {
+		return errors.New("revision cannot start with a dash")
+	}
+	return foldRange(ctx, cc, first, last, *msg)
+}
+
+// foldRange replaces the commits from first to last (inclusive) with a
+// single commit and rebases any descendants of last onto it.
+func foldRange(ctx context.Context, cc *cmdContext, first, last string, msg string) error {
+	head, err := cc.git.Head(ctx)
+	if err != nil {
+		return err
+	}
+	firstInfo, err := cc.git.CommitInfo(ctx, first)
+	if err != nil {
+		return err
+	}
+	lastInfo, err := cc.git.CommitInfo(ctx, last)
+	if err != nil {
+		return err
+	}
+	firstHash, lastHash := firstInfo.SHA1(), lastInfo.SHA1()
+	if firstHash == lastHash {
+		return errors.New("nothing to fold: range contains a single commit")
+	}
+	if isAncestor, err := cc.git.IsAncestor(ctx, firstHash.String(), lastHash.String()); err != nil {
+		return err
+	} else if !isAncestor {
+		return fmt.Errorf("%s is not an ancestor of %s", first, last)
+	}
+	if isAncestor, err := cc.git.IsAncestor(ctx, lastHash.String(), head.Commit.String()); err != nil {
+		return err
+	} else if !isAncestor {
+		return fmt.Errorf("%s is not an ancestor of the working copy", last)
+	}
+	if len(firstInfo.Parents) > 1 {
+		return errors.New("cannot fold a merge")
+	}
+	merges, err := cc.git.Output(ctx, "rev-list", "--merges", firstHash.String()+".."+head.Commit.String())
+	if err != nil {
+		return err
+	}
+	if strings.TrimSpace(merges) != "" {
+		return fmt.Errorf("cannot fold %s: merges exist between it and the working copy", first)
+	}
+	if err := checkNotInUpstream(ctx, cc, firstHash, first); err != nil {
+		return err
+	}
+
+	revs := []string{lastHash.String()}
+	if len(firstInfo.Parents) > 0 {
+		revs = append(revs, "^"+firstInfo.Parents[0].String())
+	}
+	log, err := cc.git.Log(ctx, git.LogOptions{Revs: revs, Reverse: true})
+	if err != nil {
+		return err
+	}
+	var messages []string
+	for log.Next() {
+		messages = append(messages, log.CommitInfo().Message)
+	}
+	if err := log.Close(); err != nil {
+		return err
+	}
+
+	if msg != "" {
+		msg = cleanupMessage(msg, "")
+	} else {
+		cfg, err := cc.git.ReadConfig(ctx)
+		if err != nil {
+			return err
+		}
+		commentChar, err := cfg.CommentChar()
+		if err != nil {
+			return err
+		}
+		msgBuf := new(bytes.Buffer)
+		fmt.Fprintf(msgBuf, "%s This is a combination of %d commits.\n", commentChar, len(messages))
+		for i, m := range messages {
+			if i > 0 {
+				msgBuf.WriteString("\n")
+			}
+			fmt.Fprintf(msgBuf, "%s Commit message #%d:\n%s\n", commentChar, i+1, strings.TrimRight(m, "\n"))
+		}
+		editorOut, err := cc.editor.open(ctx, commitMsgFilename, msgBuf.Bytes())
+		if err != nil {
+			return err
+		}
+		msg = cleanupMessage(string(editorOut), commentChar)
+	}
+	if msg == "" {
+		return errors.New("aborting fold due to empty commit message")
+	}
+
+	folded, err := writeCommit(ctx, cc, &object.Commit{
+		Tree:       lastInfo.Tree,
+		Parents:    firstInfo.Parents,
+		Author:     firstInfo.Author,
+		AuthorTime: firstInfo.AuthorTime,
+		Message:    msg,
+	})
+	if err != nil {
+		return fmt.Errorf("fold: %w", err)
+	}
+	return replaceCommit(ctx, cc, lastHash, folded)
+}
+
+// writeCommit creates a commit object with c's tree, parents, author,
+// and message. The committer is the current user. Like git commit -m,
+// writeCommit runs the repository's prepare-commit-msg and commit-msg hooks
+// on the message and signs the commit if commit.gpgSign is set.
+func writeCommit(ctx context.Context, cc *cmdContext, c *object.Commit) (git.Hash, error) {
+	msg, err := runMessageHook(ctx, cc, "prepare-commit-msg", []byte(c.Message), "message")
+	if err != nil {
+		return git.Hash{}, err
+	}
+	msg, err = runMessageHook(ctx, cc, "commit-msg", msg)
+	if err != nil {
+		return git.Hash{}, err
+	}
+	sign, err := signingEnabled(ctx, cc)
+	if err != nil {
+		return git.Hash{}, err
+	}
+	args := []string{"commit-tree", c.Tree.String()}
+	for _, p := range c.Parents {
+		args = append(args, "-p", p.String())
+	}
+	if sign {
+		// Unlike git commit, git commit-tree ignores commit.gpgSign.
+		args = append(args, "-S")
+	}
+	args = append(args, "-F", "-")
+	stdout := new(strings.Builder)
+	stderr := new(bytes.Buffer)
+	err = cc.git.Runner().RunGit(ctx, &git.Invocation{
+		Args: args,
+		Dir:  cc.dir,
+		Env: append(cc.env[:len(cc.env):len(cc.env)],
+			"GIT_AUTHOR_NAME="+c.Author.Name(),
+			"GIT_AUTHOR_EMAIL="+c.Author.Email(),
+			"GIT_AUTHOR_DATE="+fmt.Sprintf("@%d %s", c.AuthorTime.Unix(), c.AuthorTime.Format("-0700")),
+		),
+		Stdin:  bytes.NewReader(msg),
+		Stdout: stdout,
+		Stderr: stderr,
+	})
+	if err != nil {
+		return git.Hash{}, signingError(ctx, cc, fmt.Errorf("git commit-tree: %w: %s", err, bytes.TrimSpace(stderr.Bytes())))
+	}
+	return git.ParseHash(strings.TrimSpace(stdout.String()))
+}
+
+// replaceCommit replaces old, which must be HEAD or one of its ancestors,
+// with replacement. replacement must have the same tree as old. Commits
+// after old are rebased onto replacement.
+func replaceCommit(ctx context.Context, cc *cmdContext, old, replacement git.Hash) error {
+	head, err := cc.git.Head(ctx)
+	if err != nil {
+		return err
+	}
+	if old == head.Commit {
+		// Since the trees are the same, only the ref needs to move.
+		return cc.git.Run(ctx, "update-ref", "-m", "gg: replace "+old.Short(), git.Head.String(), replacement.String(), old.String())
+	}
+	return cc.interactiveGit(ctx,
+		"rebase",
+		"--autostash",
+		"--no-fork-point",
+		"--onto="+replacement.String(),
+		"--",
+		old.String(),
+	)
+}
@@ -0,0 +1,106 @@
+// Copyright 2021 The gg Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"testing"
+
+	"gg-scm.io/pkg/git"
+	"gg-scm.io/tool/internal/filesystem"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestFold(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	env, err := newTestEnv(ctx, t)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := env.initRepoWithHistory(ctx, "."); err != nil {
+		t.Fatal(err)
+	}
+	err = env.git.NewBranch(ctx, "topic", git.BranchOptions{
+		StartPoint: "main",
+		Track:      true,
+		Checkout:   true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"a.txt", "b.txt", "c.txt", "d.txt"} {
+		if err := env.root.Apply(filesystem.Write(name, dummyContent)); err != nil {
+			t.Fatal(err)
+		}
+		if err := env.addFiles(ctx, name); err != nil {
+			t.Fatal(err)
+		}
+		if err := env.git.CommitAll(ctx, "add "+name, git.CommitOptions{}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if _, err := env.gg(ctx, env.root.String(), "fold", "-m", "add b and c", "-r", "HEAD~2::HEAD~1"); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"add d.txt\n", "add b and c\n", "add a.txt\n"}
+	if got, err := topicMessages(ctx, env.git); err != nil {
+		t.Fatal(err)
+	} else if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("commits after fold -r (-want +got):\n%s", diff)
+	}
+	for _, name := range []string{"b.txt", "c.txt"} {
+		if _, err := catBlob(ctx, env.git, "HEAD~1", git.TopPath(name)); err != nil {
+			t.Errorf("%s @ HEAD~1: %v", name, err)
+		}
+	}
+
+	if _, err := env.gg(ctx, env.root.String(), "squash", "-m", "add everything", "--from", "HEAD~2"); err != nil {
+		t.Fatal(err)
+	}
+	want = []string{"add everything\n"}
+	if got, err := topicMessages(ctx, env.git); err != nil {
+		t.Fatal(err)
+	} else if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("commits after squash --from (-want +got):\n%s", diff)
+	}
+	for _, name := range []string{"a.txt", "b.txt", "c.txt", "d.txt"} {
+		if _, err := catBlob(ctx, env.git, "HEAD", git.TopPath(name)); err != nil {
+			t.Errorf("%s @ HEAD: %v", name, err)
+		}
+	}
+
+	if _, err := env.gg(ctx, env.root.String(), "fold", "--from", "HEAD~1"); err == nil {
+		t.Error("fold --from HEAD~1 with a commit from main did not return an error")
+	}
+}
+
+// topicMessages returns the messages of the commits in main..HEAD,
+// newest first.
+func topicMessages(ctx context.Context, g *git.Git) ([]string, error) {
+	commits, err := g.Log(ctx, git.LogOptions{Revs: []string{"main..HEAD"}})
+	if err != nil {
+		return nil, err
+	}
+	var messages []string
+	for commits.Next() {
+		messages = append(messages, commits.CommitInfo().Message)
+	}
+	if err := commits.Close(); err != nil {
+		return nil, err
+	}
+	return messages, nil
+}
@@ -0,0 +1,180 @@
+// Copyright 2021 The gg Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"gg-scm.io/pkg/git"
+)
+
+// defaultForgeRemote returns the name of the remote whose web pages gg
+// links to: "origin", or the repository's only remote.
+func defaultForgeRemote(cfg *git.Config) string {
+	remote := "origin"
+	if remotes := cfg.ListRemotes(); len(remotes) == 1 {
+		for name := range remotes {
+			remote = name
+		}
+	}
+	return remote
+}
+
+// splitRemoteURL returns the host name and the slash-separated path
+// (without a leading slash) of a Git remote URL. It understands both
+// URLs and scp-like "[user@]host:path" syntax.
+func splitRemoteURL(u string) (host, path string) {
+	if strings.Contains(u, "://") {
+		uu, err := url.Parse(u)
+		if err != nil || uu.RawQuery != "" || uu.Fragment != "" {
+			return "", ""
+		}
+		switch uu.Scheme {
+		case "https", "http", "ssh", "git":
+			return uu.Hostname(), strings.TrimPrefix(uu.Path, "/")
+		default:
+			return "", ""
+		}
+	}
+	i := strings.IndexByte(u, ':')
+	if i == -1 || strings.ContainsRune(u[:i], '/') {
+		// Local path.
+		return "", ""
+	}
+	host = u[:i]
+	if at := strings.LastIndexByte(host, '@'); at != -1 {
+		host = host[at+1:]
+	}
+	return host, u[i+1:]
+}
+
+// pullRequestForge is a code hosting service that gg can send pull
+// requests to.
+type pullRequestForge interface {
+	// String returns the name of the service, like "GitHub".
+	String() string
+
+	// readToken returns the user's saved API token for the service.
+	readToken(ctx context.Context, cc *cmdContext) ([]byte, error)
+
+	// createPullRequest creates a pull request and returns its URL.
+	// If the pull request was created but a subsequent step failed,
+	// createPullRequest returns both the URL and an error.
+	createPullRequest(ctx context.Context, client *http.Client, params pullRequestParams) (prURL string, _ error)
+}
+
+// parsePullRequestRemoteURL returns the pull request service that hosts
+// the repository at the given Git remote URL, along with the repository's
+// owner and name. The gg.github.<host>.apiurl, gg.gitlabHost, and
+// gg.giteaHost configuration settings map the host names of self-hosted
+// instances to their service. These settings are only read from the
+// user's Git configuration, never the workspace file, so that a cloned
+// repository can't direct the user's tokens to another host.
+// parsePullRequestRemoteURL returns a nil forge if the remote is not hosted
+// on a known service.
+func parsePullRequestRemoteURL(cfg *ggConfig, u string) (_ pullRequestForge, owner, repo string) {
+	if owner, repo := parseGitHubRemoteURL(u); owner != "" {
+		return gitHubForge{host: "github.com"}, owner, repo
+	}
+	if forge, owner, repo := parseGitHubEnterpriseRemoteURL(cfg, u); owner != "" {
+		return forge, owner, repo
+	}
+	if host, namespace, project := parseGitLabRemoteURL(cfg.gitOnly().Values("gg.gitlabHost"), u); host != "" {
+		return gitLabForge{host: host}, namespace, project
+	}
+	if host, owner, repo := parseGiteaRemoteURL(cfg.gitOnly().Values("gg.giteaHost"), u); host != "" {
+		return giteaForge{host: host}, owner, repo
+	}
+	return nil, "", ""
+}
+
+// forgeWebPages builds the URLs of a hosted repository's web pages.
+type forgeWebPages struct {
+	forge pullRequestForge
+	// base is the URL of the repository's home page, without a trailing slash.
+	base string
+}
+
+// newForgeWebPages returns the web pages of the repository at the given
+// Git remote URL, or nil if the remote is not hosted on a known service.
+func newForgeWebPages(cfg *ggConfig, remoteURL string) *forgeWebPages {
+	forge, owner, repo := parsePullRequestRemoteURL(cfg, remoteURL)
+	var host string
+	switch forge := forge.(type) {
+	case gitHubForge:
+		host = forge.host
+	case gitLabForge:
+		host = forge.host
+	case giteaForge:
+		host = forge.host
+	default:
+		return nil
+	}
+	return &forgeWebPages{
+		forge: forge,
+		base:  "https://" + host + "/" + owner + "/" + repo,
+	}
+}
+
+// commit returns the URL of the page showing the given commit.
+func (p *forgeWebPages) commit(h git.Hash) string {
+	return p.commitPrefix() + h.String()
+}
+
+// commitPrefix returns the URL prefix of commit pages. Appending a full
+// hex-encoded commit hash to the prefix gives the commit's URL.
+func (p *forgeWebPages) commitPrefix() string {
+	if _, ok := p.forge.(gitLabForge); ok {
+		return p.base + "/-/commit/"
+	}
+	return p.base + "/commit/"
+}
+
+// file returns the URL of the page showing a file as of the given commit.
+// If line is positive, the URL points to that line.
+func (p *forgeWebPages) file(h git.Hash, path git.TopPath, line int) string {
+	escapedPath := (&url.URL{Path: path.String()}).EscapedPath()
+	var u string
+	switch p.forge.(type) {
+	case gitLabForge:
+		u = p.base + "/-/blob/" + h.String() + "/" + escapedPath
+	case giteaForge:
+		u = p.base + "/src/commit/" + h.String() + "/" + escapedPath
+	default:
+		u = p.base + "/blob/" + h.String() + "/" + escapedPath
+	}
+	if line > 0 {
+		u += "#L" + strconv.Itoa(line)
+	}
+	return u
+}
+
+// pullRequests returns the URL of the page listing the open pull requests
+// from the given branch. Gitea cannot filter by branch, so its URL lists all
+// open pull requests.
+func (p *forgeWebPages) pullRequests(branch string) string {
+	switch p.forge.(type) {
+	case gitLabForge:
+		return p.base + "/-/merge_requests?" + url.Values{"source_branch": {branch}}.Encode()
+	case giteaForge:
+		return p.base + "/pulls"
+	default:
+		return p.base + "/pulls?" + url.Values{"q": {"is:pr is:open head:" + branch}}.Encode()
+	}
+}
@@ -0,0 +1,102 @@
+// Copyright 2021 The gg Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"testing"
+
+	"gg-scm.io/pkg/git"
+)
+
+func TestForgeWebPages(t *testing.T) {
+	cfg := &ggConfig{
+		entries: []configEntry{
+			{key: normalizeConfigKey("gg.giteaHost"), value: "gitea.example.com"},
+		},
+	}
+	const hash = "3b4c6e8a9f1d2e5b7c0a4d6f8e1b3c5a7d9f0e2b"
+	h, err := git.ParseHash(hash)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		url      string
+		base     string
+		commit   string
+		file     string
+		requests string
+	}{
+		{
+			url:      "git@github.com:example/foo.git",
+			base:     "https://github.com/example/foo",
+			commit:   "https://github.com/example/foo/commit/" + hash,
+			file:     "https://github.com/example/foo/blob/" + hash + "/dir/a%20b.go#L12",
+			requests: "https://github.com/example/foo/pulls?q=is%3Apr+is%3Aopen+head%3Afeature",
+		},
+		{
+			url:      "https://gitlab.com/group/sub/foo.git",
+			base:     "https://gitlab.com/group/sub/foo",
+			commit:   "https://gitlab.com/group/sub/foo/-/commit/" + hash,
+			file:     "https://gitlab.com/group/sub/foo/-/blob/" + hash + "/dir/a%20b.go#L12",
+			requests: "https://gitlab.com/group/sub/foo/-/merge_requests?source_branch=feature",
+		},
+		{
+			url:      "https://gitea.example.com/example/foo.git",
+			base:     "https://gitea.example.com/example/foo",
+			commit:   "https://gitea.example.com/example/foo/commit/" + hash,
+			file:     "https://gitea.example.com/example/foo/src/commit/" + hash + "/dir/a%20b.go#L12",
+			requests: "https://gitea.example.com/example/foo/pulls",
+		},
+	}
+	for _, test := range tests {
+		p := newForgeWebPages(cfg, test.url)
+		if p == nil {
+			t.Errorf("newForgeWebPages(cfg, %q) = <nil>", test.url)
+			continue
+		}
+		if p.base != test.base {
+			t.Errorf("newForgeWebPages(cfg, %q).base = %q; want %q", test.url, p.base, test.base)
+		}
+		if got := p.commit(h); got != test.commit {
+			t.Errorf("newForgeWebPages(cfg, %q).commit(...) = %q; want %q", test.url, got, test.commit)
+		}
+		if got := p.file(h, "dir/a b.go", 12); got != test.file {
+			t.Errorf("newForgeWebPages(cfg, %q).file(...) = %q; want %q", test.url, got, test.file)
+		}
+		if got := p.pullRequests("feature"); got != test.requests {
+			t.Errorf("newForgeWebPages(cfg, %q).pullRequests(\"feature\") = %q; want %q", test.url, got, test.requests)
+		}
+	}
+	if p := newForgeWebPages(cfg, "https://example.com/foo.git"); p != nil {
+		t.Errorf("newForgeWebPages(cfg, \"https://example.com/foo.git\").base = %q; want <nil>", p.base)
+	}
+}
+
+func TestParsePullRequestRemoteURL_WorkspaceHosts(t *testing.T) {
+	// Forge hosts declared in the workspace file must be ignored, or a
+	// cloned repository could send the user's token to any host.
+	cfg := &ggConfig{
+		entries: []configEntry{
+			{key: normalizeConfigKey("gg.gitlabHost"), value: "gitlab.example.com", workspace: true},
+			{key: normalizeConfigKey("gg.giteaHost"), value: "gitea.example.com", workspace: true},
+		},
+	}
+	if forge, _, _ := parsePullRequestRemoteURL(cfg, "https://gitlab.example.com/example/foo.git"); forge != nil {
+		t.Errorf("workspace gg.gitlabHost: forge = %v; want <nil>", forge)
+	}
+	if forge, _, _ := parsePullRequestRemoteURL(cfg, "https://gitea.example.com/example/foo.git"); forge != nil {
+		t.Errorf("workspace gg.giteaHost: forge = %v; want <nil>", forge)
+	}
+}
@@ -0,0 +1,73 @@
+// Copyright 2021 The gg Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"gg-scm.io/pkg/git"
+	"gg-scm.io/tool/internal/flag"
+)
+
+const forgetSynopsis = "stop tracking the specified files"
+
+func forget(ctx context.Context, cc *cmdContext, args []string) error {
+	f := flag.NewFlagSet(true, "gg forget FILE [...]", forgetSynopsis+`
+
+	Mark files to no longer be tracked on the next commit, leaving them in
+	the working copy. Forgetting a directory forgets all the tracked files
+	inside it. Unlike `+"`gg remove`"+`, the files are not deleted, so
+	they show up as untracked files afterward unless they are ignored.`)
+	if err := f.Parse(args); flag.IsHelp(err) {
+		f.Help(cc.stdout)
+		return nil
+	} else if err != nil {
+		return usagef("%v", err)
+	}
+	if f.NArg() == 0 {
+		return usagef("must pass one or more files to forget")
+	}
+	var names []string
+	for _, arg := range f.Args() {
+		out, err := cc.git.Output(ctx, "ls-files", "-z", "--", git.LiteralPath(arg).String())
+		if err != nil {
+			return err
+		}
+		n := len(names)
+		for _, name := range strings.Split(out, "\x00") {
+			if name != "" {
+				names = append(names, name)
+			}
+		}
+		if len(names) == n {
+			return fmt.Errorf("%s is not tracked", arg)
+		}
+	}
+	rmArgs := []string{"rm", "--cached", "-r", "--quiet", "--"}
+	for _, arg := range f.Args() {
+		rmArgs = append(rmArgs, git.LiteralPath(arg).String())
+	}
+	if err := cc.git.Run(ctx, rmArgs...); err != nil {
+		return err
+	}
+	for _, name := range names {
+		if _, err := fmt.Fprintf(cc.stdout, "forgot %s\n", name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,89 @@
+// Copyright 2021 The gg Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"testing"
+
+	"gg-scm.io/pkg/git"
+	"gg-scm.io/tool/internal/filesystem"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestForget(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	env, err := newTestEnv(ctx, t)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := env.initEmptyRepo(ctx, "."); err != nil {
+		t.Fatal(err)
+	}
+	err = env.root.Apply(
+		filesystem.Write("foo.txt", "foo\n"),
+		filesystem.Write("dir/a.txt", "a\n"),
+		filesystem.Write("dir/b.txt", "b\n"),
+		filesystem.Write("keep.txt", "keep\n"),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := env.addFiles(ctx, "foo.txt", "dir/a.txt", "dir/b.txt", "keep.txt"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := env.newCommit(ctx, "."); err != nil {
+		t.Fatal(err)
+	}
+
+	out, err := env.gg(ctx, env.root.String(), "forget", "foo.txt", "dir")
+	if err != nil {
+		t.Fatal(err)
+	}
+	const wantOut = "forgot foo.txt\nforgot dir/a.txt\nforgot dir/b.txt\n"
+	if got := string(out); got != wantOut {
+		t.Errorf("forget output = %q; want %q", got, wantOut)
+	}
+	for _, name := range []string{"foo.txt", "dir/a.txt", "dir/b.txt"} {
+		if exists, err := env.root.Exists(name); err != nil {
+			t.Error(err)
+		} else if !exists {
+			t.Errorf("%s was deleted by forget", name)
+		}
+	}
+	st, err := env.git.Status(ctx, git.StatusOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := make(map[git.TopPath]string)
+	for _, ent := range st {
+		if ent.Code[0] == 'D' {
+			got[ent.Name] = string(ent.Code[:])
+		}
+	}
+	want := map[git.TopPath]string{
+		"foo.txt":   "D ",
+		"dir/a.txt": "D ",
+		"dir/b.txt": "D ",
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("removed files in status (-want +got):\n%s", diff)
+	}
+
+	if _, err := env.gg(ctx, env.root.String(), "forget", "untracked.txt"); err == nil {
+		t.Error("forget of untracked file did not return an error")
+	}
+}
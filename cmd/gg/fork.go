@@ -0,0 +1,182 @@
+// Copyright 2021 The gg Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"gg-scm.io/tool/internal/flag"
+)
+
+const forkSynopsis = "fork the origin repository on GitHub"
+
+func fork(ctx context.Context, cc *cmdContext, args []string) error {
+	f := flag.NewFlagSet(true, "gg fork [--org ORG] [--remote NAME]", forkSynopsis+`
+
+	fork creates a copy of the GitHub repository that the `+"`origin`"+`
+	remote points to, adds the copy as a new remote, and sets
+	`+"`remote.pushDefault`"+` to the new remote. Afterward, `+"`gg push`"+`
+	sends branches to the fork and `+"`gg requestpull`"+` opens pull
+	requests from the fork against `+"`origin`"+`.
+
+	The new remote is named after the account that owns the fork unless
+	`+"`--remote`"+` is given. Its URL uses the same protocol (HTTPS or SSH)
+	as `+"`origin`"+`. If you already have a fork, GitHub returns it
+	instead of creating another one.
+
+	fork uses the same GitHub authorization as `+"`gg requestpull`"+`.`)
+	org := f.String("org", "", "create the fork in the given `organization` instead of your account")
+	remoteName := f.String("remote", "", "`name` of the remote to add (defaults to the fork's owner)")
+	if err := f.Parse(args); flag.IsHelp(err) {
+		f.Help(cc.stdout)
+		return nil
+	} else if err != nil {
+		return usagef("%v", err)
+	}
+	if f.NArg() > 0 {
+		return usagef("fork takes no arguments")
+	}
+	if strings.HasPrefix(*remoteName, "-") {
+		return usagef("remote name cannot start with '-'")
+	}
+	cfg, err := cc.git.ReadConfig(ctx)
+	if err != nil {
+		return err
+	}
+	if cfg.ListRemotes()["origin"] == nil {
+		return errors.New("no remote named \"origin\" found")
+	}
+	originURL := cfg.Value("remote.origin.url")
+	gcfg, err := readGGConfig(ctx, cc.git)
+	if err != nil {
+		return err
+	}
+	forge, owner, repo := parsePullRequestRemoteURL(gcfg, originURL)
+	gh, ok := forge.(gitHubForge)
+	if !ok {
+		return fmt.Errorf("%s is not a GitHub repository", originURL)
+	}
+	token, err := gh.readToken(ctx, cc)
+	if err != nil {
+		return err
+	}
+	repoFork, err := createGitHubFork(ctx, cc.httpClient, forkParams{
+		authToken:    string(token),
+		apiRoot:      gh.apiRoot,
+		owner:        owner,
+		repo:         repo,
+		organization: *org,
+	})
+	if err != nil {
+		return err
+	}
+
+	forkURL := repoFork.SSHURL
+	if strings.HasPrefix(originURL, "https://") || forkURL == "" {
+		forkURL = repoFork.CloneURL
+	}
+	name := *remoteName
+	if name == "" {
+		name = repoFork.Owner.Login
+	}
+	if name == "" {
+		return fmt.Errorf("fork %s has no owner; pass --remote", repoFork.HTMLURL)
+	}
+	if cfg.ListRemotes()[name] != nil {
+		if existing := cfg.Value("remote." + name + ".url"); existing != forkURL {
+			return fmt.Errorf("remote %q already exists with URL %s; pass --remote to choose another name", name, existing)
+		}
+	} else if err := cc.git.Run(ctx, "remote", "add", "--", name, forkURL); err != nil {
+		return err
+	}
+	if err := cc.git.Run(ctx, "config", "--local", "remote.pushDefault", name); err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(cc.stdout, "Forked %s/%s to %s\nPushing to remote %q by default\n", owner, repo, repoFork.HTMLURL, name)
+	return err
+}
+
+type forkParams struct {
+	authToken string
+	apiRoot   string // empty for github.com
+
+	owner        string
+	repo         string
+	organization string // empty for the authenticated user
+}
+
+// gitHubRepository is the subset of a GitHub REST API repository object
+// that gg uses.
+type gitHubRepository struct {
+	FullName string `json:"full_name"`
+	HTMLURL  string `json:"html_url"`
+	CloneURL string `json:"clone_url"`
+	SSHURL   string `json:"ssh_url"`
+	Owner    struct {
+		Login string
+	}
+}
+
+// createGitHubFork asks GitHub to fork a repository. GitHub creates forks
+// asynchronously, so the fork's contents may not be available immediately.
+func createGitHubFork(ctx context.Context, client *http.Client, params forkParams) (*gitHubRepository, error) {
+	if params.authToken == "" {
+		return nil, errors.New("fork repository: missing authentication token")
+	}
+	if params.owner == "" || params.repo == "" {
+		return nil, errors.New("fork repository: missing repository owner or name")
+	}
+	reqBody := map[string]interface{}{}
+	if params.organization != "" {
+		reqBody["organization"] = params.organization
+	}
+	reqBodyJSON, err := json.Marshal(reqBody)
+	if err != nil {
+		return nil, fmt.Errorf("fork %s/%s: %w", params.owner, params.repo, err)
+	}
+	apiURL := gitHubAPIURL(params.apiRoot, fmt.Sprintf("repos/%s/%s/forks",
+		url.PathEscape(params.owner), url.PathEscape(params.repo)))
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, apiURL, bytes.NewReader(reqBodyJSON))
+	if err != nil {
+		return nil, fmt.Errorf("fork %s/%s: %w", params.owner, params.repo, err)
+	}
+	req.Header.Set("User-Agent", userAgentString())
+	req.Header.Set("Accept", "application/vnd.github.v3+json")
+	req.Header.Set("Authorization", "token "+params.authToken)
+	req.Header.Set("Content-Type", "application/json; charset=utf-8")
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("fork %s/%s: %w", params.owner, params.repo, err)
+	}
+	defer resp.Body.Close()
+	switch resp.StatusCode {
+	case http.StatusOK, http.StatusCreated, http.StatusAccepted:
+	default:
+		err := parseGitHubErrorResponse(resp)
+		return nil, fmt.Errorf("fork %s/%s: %w", params.owner, params.repo, err)
+	}
+	respDoc := new(gitHubRepository)
+	if err := json.NewDecoder(resp.Body).Decode(respDoc); err != nil {
+		return nil, fmt.Errorf("fork %s/%s: parsing response: %w", params.owner, params.repo, err)
+	}
+	return respDoc, nil
+}
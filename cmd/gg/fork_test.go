@@ -0,0 +1,92 @@
+// Copyright 2021 The gg Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestFork(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	env, err := newTestEnv(ctx, t)
+	if err != nil {
+		t.Fatal(err)
+	}
+	const authToken = "xyzzy12345"
+	if err := env.writeGitHubAuth([]byte(authToken + "\n")); err != nil {
+		t.Fatal(err)
+	}
+	if err := env.initEmptyRepo(ctx, "local"); err != nil {
+		t.Fatal(err)
+	}
+	localDir := env.root.FromSlash("local")
+	localGit := env.git.WithDir(localDir)
+	if err := localGit.Run(ctx, "remote", "add", "origin", "https://github.com/example/foo.git"); err != nil {
+		t.Fatal(err)
+	}
+
+	var forkRequests int
+	api := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/repos/example/foo/forks" {
+			t.Errorf("Unexpected request %s %s", r.Method, r.URL.Path)
+			http.NotFound(w, r)
+			return
+		}
+		if got, want := r.Header.Get("Authorization"), "token "+authToken; got != want {
+			t.Errorf("Authorization = %q; want %q", got, want)
+		}
+		forkRequests++
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		w.WriteHeader(http.StatusAccepted)
+		w.Write([]byte(`{"full_name": "octocat/foo", ` +
+			`"html_url": "https://github.com/octocat/foo", ` +
+			`"clone_url": "https://github.com/octocat/foo.git", ` +
+			`"ssh_url": "git@github.com:octocat/foo.git", ` +
+			`"owner": {"login": "octocat"}}`))
+	})
+	fakeGitHub := httptest.NewServer(api)
+	t.Cleanup(fakeGitHub.Close)
+	fakeGitHubTransport := &http.Transport{
+		DialTLS: func(network, addr string) (net.Conn, error) {
+			hostport := strings.TrimPrefix(fakeGitHub.URL, "http://")
+			return net.Dial("tcp", hostport)
+		},
+	}
+	t.Cleanup(fakeGitHubTransport.CloseIdleConnections)
+	env.roundTripper = fakeGitHubTransport
+
+	if _, err := env.gg(ctx, localDir, "fork"); err != nil {
+		t.Fatal(err)
+	}
+	if forkRequests != 1 {
+		t.Errorf("GitHub received %d fork requests; want 1", forkRequests)
+	}
+	cfg, err := localGit.ReadConfig(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := cfg.Value("remote.octocat.url"), "https://github.com/octocat/foo.git"; got != want {
+		t.Errorf("remote.octocat.url = %q; want %q", got, want)
+	}
+	if got, want := cfg.Value("remote.pushDefault"), "octocat"; got != want {
+		t.Errorf("remote.pushDefault = %q; want %q", got, want)
+	}
+}
@@ -16,6 +16,7 @@ package main
 
 import (
 	"context"
+	"crypto/rand"
 	"crypto/sha256"
 	"encoding/hex"
 	"errors"
@@ -24,6 +25,7 @@ import (
 	"net/http"
 	"os"
 	"path/filepath"
+	"strings"
 
 	"gg-scm.io/tool/internal/flag"
 )
@@ -182,6 +184,42 @@ func uninstallGerritHook(ctx context.Context, cc *cmdContext) error {
 	return nil
 }
 
+// addChangeID appends a Gerrit Change-Id trailer to msg if the
+// gg.gerrit.changeId setting is true and msg does not have one already.
+// This does the same job as the hook installed by gerrithook.
+func addChangeID(ctx context.Context, cc *cmdContext, msg string) (string, error) {
+	if strings.TrimSpace(msg) == "" || findChangeID(msg) != "" {
+		return msg, nil
+	}
+	if strings.HasPrefix(msg, "fixup! ") || strings.HasPrefix(msg, "squash! ") {
+		// Like the hook, leave commits that will be squashed alone.
+		return msg, nil
+	}
+	cfg, err := readGGConfig(ctx, cc.git)
+	if err != nil {
+		return "", err
+	}
+	if enabled, err := cfg.Bool("gg.gerrit.changeId"); err != nil {
+		return "", err
+	} else if !enabled {
+		return msg, nil
+	}
+	id, err := newChangeID()
+	if err != nil {
+		return "", err
+	}
+	return addTrailers(msg, "Change-Id", []string{id}), nil
+}
+
+// newChangeID returns a random Gerrit change ID.
+func newChangeID() (string, error) {
+	var buf [20]byte
+	if _, err := rand.Read(buf[:]); err != nil {
+		return "", fmt.Errorf("generate change ID: %w", err)
+	}
+	return "I" + hex.EncodeToString(buf[:]), nil
+}
+
 type valuer interface {
 	Value(string) string
 	Bool(string) (bool, error)
@@ -193,6 +231,12 @@ type gitDirs interface {
 }
 
 func commitMsgHookPath(ctx context.Context, cfg valuer, g gitDirs) (string, error) {
+	return hookPath(ctx, cfg, g, "commit-msg")
+}
+
+// hookPath returns the path of the repository hook with the given name,
+// taking core.hooksPath into account. The hook may not exist.
+func hookPath(ctx context.Context, cfg valuer, g gitDirs, name string) (string, error) {
 	// TODO(someday): Move hook directory path logic into internal/git.
 
 	path := cfg.Value("core.hooksPath")
@@ -201,10 +245,10 @@ func commitMsgHookPath(ctx context.Context, cfg valuer, g gitDirs) (string, erro
 		if err != nil {
 			return "", err
 		}
-		return filepath.Join(commonDir, "hooks", "commit-msg"), nil
+		return filepath.Join(commonDir, "hooks", name), nil
 	}
 	if filepath.IsAbs(path) {
-		return path, nil
+		return filepath.Join(path, name), nil
 	}
 	if bare, err := cfg.Bool("core.bare"); err != nil {
 		return "", err
@@ -213,13 +257,13 @@ func commitMsgHookPath(ctx context.Context, cfg valuer, g gitDirs) (string, erro
 		if err != nil {
 			return "", err
 		}
-		return filepath.Join(commonDir, path, "commit-msg"), nil
+		return filepath.Join(commonDir, path, name), nil
 	}
 	topDir, err := g.WorkTree(ctx)
 	if err != nil {
 		return "", err
 	}
-	return filepath.Join(topDir, path, "commit-msg"), nil
+	return filepath.Join(topDir, path, name), nil
 }
 
 type limitedReader struct {
@@ -178,7 +178,7 @@ func TestCommitMsgHookPath(t *testing.T) {
 		{
 			name: "HooksPathAbsolute",
 			cfg:  dummyConfig{"core.hooksPath": other},
-			want: other,
+			want: filepath.Join(other, "commit-msg"),
 		},
 		{
 			name: "HooksPathRelative",
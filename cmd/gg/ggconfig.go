@@ -0,0 +1,223 @@
+// Copyright 2021 The gg Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gg-scm.io/pkg/git"
+)
+
+// workspaceConfigFilename is the name of the file at the root of a working
+// copy that holds project-level gg settings. It uses the same syntax as Git
+// configuration files so that it can be checked into the repository.
+const workspaceConfigFilename = ".ggconfig"
+
+// ggConfig is the configuration for gg-specific settings. It layers the
+// workspace configuration file below the user's Git configuration, so a
+// setting in any Git configuration file (system, global, or local) takes
+// precedence over the same setting in the workspace configuration file.
+//
+// Settings from the workspace configuration file only affect gg's own
+// behavior: they are not passed to the Git subprocesses that gg runs.
+type ggConfig struct {
+	// entries is the list of configuration entries in increasing order
+	// of precedence.
+	entries []configEntry
+	// workTree is the absolute path to the top of the working copy.
+	// It is empty for bare repositories.
+	workTree string
+}
+
+type configEntry struct {
+	key       string // normalized with normalizeConfigKey
+	value     string
+	noValue   bool // whether the key was given without "=", as opposed to an empty value
+	workspace bool // whether the entry came from the workspace file
+}
+
+// readGGConfig reads the Git configuration and the workspace configuration
+// file, if present.
+func readGGConfig(ctx context.Context, g *git.Git) (*ggConfig, error) {
+	cfg := new(ggConfig)
+	if workTree, err := g.WorkTree(ctx); err == nil {
+		cfg.workTree = workTree
+		path := filepath.Join(workTree, workspaceConfigFilename)
+		if _, err := os.Stat(path); err == nil {
+			// Includes are not followed, since the file comes from the
+			// repository's contents.
+			out, err := g.Output(ctx, "config", "-z", "--list", "--no-includes", "--file", path)
+			if err != nil {
+				return nil, fmt.Errorf("read %s: %w", workspaceConfigFilename, err)
+			}
+			cfg.entries = parseConfigList(out, true)
+		} else if !os.IsNotExist(err) {
+			return nil, fmt.Errorf("read %s: %w", workspaceConfigFilename, err)
+		}
+	}
+	out, err := g.Output(ctx, "config", "-z", "--list")
+	if err != nil {
+		return nil, fmt.Errorf("read config: %w", err)
+	}
+	cfg.entries = append(cfg.entries, parseConfigList(out, false)...)
+	return cfg, nil
+}
+
+// parseConfigList parses the output of `git config -z --list`.
+func parseConfigList(out string, workspace bool) []configEntry {
+	var entries []configEntry
+	for _, ent := range strings.Split(out, "\x00") {
+		if ent == "" {
+			continue
+		}
+		key, value, noValue := ent, "", true
+		if i := strings.IndexByte(ent, '\n'); i != -1 {
+			key, value, noValue = ent[:i], ent[i+1:], false
+		}
+		entries = append(entries, configEntry{
+			key:       normalizeConfigKey(key),
+			value:     value,
+			noValue:   noValue,
+			workspace: workspace,
+		})
+	}
+	return entries
+}
+
+// normalizeConfigKey lowercases the section and variable names of a
+// configuration key, leaving any subsection name intact.
+func normalizeConfigKey(key string) string {
+	i := strings.IndexByte(key, '.')
+	j := strings.LastIndexByte(key, '.')
+	if i == -1 {
+		return strings.ToLower(key)
+	}
+	return strings.ToLower(key[:i]) + key[i:j] + strings.ToLower(key[j:])
+}
+
+// lookup returns the entry with the highest precedence for the given key.
+func (cfg *ggConfig) lookup(key string) (configEntry, bool) {
+	key = normalizeConfigKey(key)
+	for i := len(cfg.entries) - 1; i >= 0; i-- {
+		if cfg.entries[i].key == key {
+			return cfg.entries[i], true
+		}
+	}
+	return configEntry{}, false
+}
+
+// Value returns the value of the given key or the empty string if the key
+// is not set.
+func (cfg *ggConfig) Value(key string) string {
+	ent, _ := cfg.lookup(key)
+	return ent.value
+}
+
+// Values returns all the values of a multi-valued key in increasing order
+// of precedence.
+func (cfg *ggConfig) Values(key string) []string {
+	key = normalizeConfigKey(key)
+	var values []string
+	for _, ent := range cfg.entries {
+		if ent.key == key {
+			values = append(values, ent.value)
+		}
+	}
+	return values
+}
+
+// Bool returns the value of the given key interpreted as a boolean,
+// using the same rules as Git. It returns false if the key is not set.
+func (cfg *ggConfig) Bool(key string) (bool, error) {
+	ent, ok := cfg.lookup(key)
+	if !ok {
+		return false, nil
+	}
+	if ent.noValue {
+		// A key with no "=" is true.
+		return true, nil
+	}
+	switch strings.ToLower(ent.value) {
+	case "true", "yes", "on", "1":
+		return true, nil
+	case "", "false", "no", "off", "0":
+		return false, nil
+	default:
+		return false, fmt.Errorf("config %s: invalid boolean %q", key, ent.value)
+	}
+}
+
+// Path returns the value of the given key interpreted as a path.
+// Relative paths from the workspace configuration file are resolved
+// relative to the top of the working copy.
+func (cfg *ggConfig) Path(key string) string {
+	ent, ok := cfg.lookup(key)
+	if !ok || ent.value == "" {
+		return ""
+	}
+	path := ent.value
+	if strings.HasPrefix(path, "~/") {
+		if home, err := os.UserHomeDir(); err == nil {
+			return filepath.Join(home, filepath.FromSlash(path[2:]))
+		}
+		return path
+	}
+	if ent.workspace && !filepath.IsAbs(path) {
+		return filepath.Join(cfg.workTree, filepath.FromSlash(path))
+	}
+	return path
+}
+
+// gitOnly returns the configuration without the workspace entries.
+// Settings that decide where the user's credentials are sent must only
+// come from the user's Git configuration.
+func (cfg *ggConfig) gitOnly() *ggConfig {
+	gitCfg := &ggConfig{workTree: cfg.workTree}
+	for _, ent := range cfg.entries {
+		if !ent.workspace {
+			gitCfg.entries = append(gitCfg.entries, ent)
+		}
+	}
+	return gitCfg
+}
+
+// trusted returns the configuration without the workspace entries,
+// unless the user has opted in to trusting the workspace configuration
+// file by setting gg.trustWorkspace to true in their Git configuration.
+// Settings that run commands or read files must use the trusted
+// configuration, since the workspace file comes from whoever wrote the
+// repository's contents.
+func (cfg *ggConfig) trusted() *ggConfig {
+	gitCfg := cfg.gitOnly()
+	if trust, err := gitCfg.Bool("gg.trustWorkspace"); err == nil && trust {
+		return cfg
+	}
+	return gitCfg
+}
+
+// isProtectedBranch reports whether the given branch is listed in the
+// gg.protectedBranch configuration setting.
+func (cfg *ggConfig) isProtectedBranch(branch string) bool {
+	for _, b := range cfg.Values("gg.protectedBranch") {
+		if strings.TrimPrefix(b, "refs/heads/") == branch {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,123 @@
+// Copyright 2021 The gg Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"gg-scm.io/tool/internal/filesystem"
+)
+
+func TestReadGGConfig(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	env, err := newTestEnv(ctx, t)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := env.initRepoWithHistory(ctx, "."); err != nil {
+		t.Fatal(err)
+	}
+	const workspaceConfig = "[gg]\n" +
+		"\tpullRequestBase = dev\n" +
+		"\tprotectedBranch = main\n" +
+		"\tprotectedBranch = release\n" +
+		"\tflagOn\n" +
+		"\tflagEmpty =\n" +
+		"[gg \"check\"]\n" +
+		"\tvet = go vet ./...\n" +
+		"\tlint = golint\n" +
+		"[commit]\n" +
+		"\ttemplate = misc/commit_template.txt\n"
+	if err := env.root.Apply(filesystem.Write(workspaceConfigFilename, workspaceConfig)); err != nil {
+		t.Fatal(err)
+	}
+	userConfig := "[gg]\n" +
+		"\tpullRequestBase = mine\n" +
+		"[gg \"check\"]\n" +
+		"\tlint =\n"
+	if err := env.writeConfig([]byte(userConfig)); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, err := readGGConfig(ctx, env.git)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := cfg.Value("gg.pullRequestBase"), "mine"; got != want {
+		t.Errorf("gg.pullRequestBase = %q; want %q", got, want)
+	}
+	for _, b := range []string{"main", "release"} {
+		if !cfg.isProtectedBranch(b) {
+			t.Errorf("isProtectedBranch(%q) = false; want true", b)
+		}
+	}
+	if cfg.isProtectedBranch("feature") {
+		t.Error("isProtectedBranch(\"feature\") = true; want false")
+	}
+	if got, err := cfg.Bool("gg.flagOn"); err != nil || !got {
+		t.Errorf("cfg.Bool(\"gg.flagOn\") = %t, %v; want true, <nil>", got, err)
+	}
+	if got, err := cfg.Bool("gg.flagEmpty"); err != nil || got {
+		t.Errorf("cfg.Bool(\"gg.flagEmpty\") = %t, %v; want false, <nil>", got, err)
+	}
+	checks := readChecks(cfg)
+	if len(checks) != 1 || checks[0].name != "vet" || checks[0].command != "go vet ./..." {
+		t.Errorf("checks = %+v; want [{vet go vet ./...}]", checks)
+	}
+	wantTemplate := env.root.FromSlash("misc/commit_template.txt")
+	if got := cfg.Path("commit.template"); filepath.Clean(got) != filepath.Clean(wantTemplate) {
+		t.Errorf("commit.template = %q; want %q", got, wantTemplate)
+	}
+
+	// Checks and paths from the workspace file must not be used until
+	// the user trusts the repository.
+	if checks := readChecks(cfg.trusted()); len(checks) != 0 {
+		t.Errorf("untrusted checks = %+v; want none", checks)
+	}
+	if got := cfg.trusted().Path("commit.template"); got != "" {
+		t.Errorf("untrusted commit.template = %q; want \"\"", got)
+	}
+	if err := env.writeConfig([]byte(userConfig + "[gg]\n\ttrustWorkspace = true\n")); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err = readGGConfig(ctx, env.git)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if checks := readChecks(cfg.trusted()); len(checks) != 1 || checks[0].name != "vet" {
+		t.Errorf("trusted checks = %+v; want [{vet go vet ./...}]", checks)
+	}
+}
+
+func TestNormalizeConfigKey(t *testing.T) {
+	tests := []struct {
+		key  string
+		want string
+	}{
+		{"core.bare", "core.bare"},
+		{"gg.pullRequestBase", "gg.pullrequestbase"},
+		{"GG.Check.Vet", "gg.Check.vet"},
+		{"branch.Feature.X.remote", "branch.Feature.X.remote"},
+		{"nodot", "nodot"},
+	}
+	for _, test := range tests {
+		if got := normalizeConfigKey(test.key); got != test.want {
+			t.Errorf("normalizeConfigKey(%q) = %q; want %q", test.key, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,283 @@
+// Copyright 2021 The gg Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+)
+
+const giteaTokenFilename = "gitea_token"
+
+// giteaForge is a self-hosted Gitea or Forgejo instance listed in the
+// gg.giteaHost configuration setting. Forgejo shares Gitea's API.
+type giteaForge struct {
+	host string
+}
+
+func (f giteaForge) String() string {
+	return "Gitea"
+}
+
+// apiURL returns the URL of the given Gitea API v1 endpoint.
+func (f giteaForge) apiURL(path string) string {
+	return "https://" + f.host + "/api/v1/" + path
+}
+
+// tokenFilename returns the name of the file in the gg configuration
+// directory that stores the token for the instance.
+func (f giteaForge) tokenFilename() string {
+	return giteaTokenFilename + "." + f.host
+}
+
+// readToken reads the saved Gitea access token.
+func (f giteaForge) readToken(ctx context.Context, cc *cmdContext) ([]byte, error) {
+	filename := f.tokenFilename()
+	token, err := cc.xdgDirs.readConfig(filename)
+	if os.IsNotExist(err) {
+		return nil, fmt.Errorf("no Gitea token found. Generate an access token with repository write access "+
+			"at https://%s/user/settings/applications and save it to $XDG_CONFIG_HOME/gg/%s",
+			f.host, filename)
+	}
+	if err != nil {
+		return nil, err
+	}
+	token = bytes.TrimSpace(token)
+	if len(token) == 0 {
+		return nil, fmt.Errorf("Gitea token in %s is empty", filename)
+	}
+	return token, nil
+}
+
+// createPullRequest creates a Gitea pull request and requests reviews
+// from params.reviewers.
+func (f giteaForge) createPullRequest(ctx context.Context, client *http.Client, params pullRequestParams) (prURL string, _ error) {
+	if params.authToken == "" {
+		return "", errors.New("create pull request: missing authentication token")
+	}
+	if params.baseOwner == "" || params.baseRepo == "" {
+		return "", errors.New("create pull request: missing base owner or repository name")
+	}
+	if params.baseBranch == "" {
+		return "", errors.New("create pull request: missing base branch")
+	}
+	if params.headOwner == "" || params.headBranch == "" {
+		return "", errors.New("create pull request: missing head branch or owner")
+	}
+	if params.title == "" {
+		return "", errors.New("create pull request: missing title")
+	}
+	repoPath := "repos/" + url.PathEscape(params.baseOwner) + "/" + url.PathEscape(params.baseRepo)
+
+	title := params.title
+	if params.draft {
+		// Gitea marks pull requests with this title prefix as work in progress.
+		title = "WIP: " + title
+	}
+	head := params.headBranch
+	if params.headOwner != params.baseOwner {
+		head = params.headOwner + ":" + params.headBranch
+	}
+	reqBody := map[string]interface{}{
+		"base":  params.baseBranch,
+		"head":  head,
+		"title": title,
+	}
+	if params.body != "" {
+		reqBody["body"] = params.body
+	}
+	var respDoc struct {
+		Number  uint64
+		HTMLURL string `json:"html_url"`
+	}
+	err := f.do(ctx, client, params.authToken, http.MethodPost, repoPath+"/pulls", reqBody, &respDoc)
+	if err != nil {
+		return "", fmt.Errorf("create pull request for %s/%s: %w", params.baseOwner, params.baseRepo, err)
+	}
+	if len(params.reviewers) > 0 {
+		reviewPath := fmt.Sprintf("%s/pulls/%d/requested_reviewers", repoPath, respDoc.Number)
+		reviewBody := map[string]interface{}{"reviewers": params.reviewers}
+		if err := f.do(ctx, client, params.authToken, http.MethodPost, reviewPath, reviewBody, nil); err != nil {
+			return respDoc.HTMLURL, fmt.Errorf("add pull request reviewers to %s/%s/pulls/%d: %w", params.baseOwner, params.baseRepo, respDoc.Number, err)
+		}
+	}
+	return respDoc.HTMLURL, nil
+}
+
+// listGiteaPullRequestsLimit is the number of pull requests requested
+// from Gitea per page.
+const listGiteaPullRequestsLimit = 50
+
+// findOpenPullRequest returns the open pull request for the target's
+// branch. Gitea can't filter pull requests by head branch, so
+// findOpenPullRequest pages through all of the open pull requests.
+func (f giteaForge) findOpenPullRequest(ctx context.Context, client *http.Client, authToken string, t *pullRequestTarget) (*landingPullRequest, error) {
+	repoPath := "repos/" + url.PathEscape(t.baseOwner) + "/" + url.PathEscape(t.baseRepo)
+	for page := 1; ; page++ {
+		var prs []struct {
+			Number  uint64
+			HTMLURL string `json:"html_url"`
+			Head    struct {
+				Ref  string
+				SHA  string
+				Repo *struct {
+					Owner struct {
+						Login string
+					}
+				}
+			}
+			Base struct {
+				Ref string
+			}
+		}
+		path := fmt.Sprintf("%s/pulls?state=open&page=%d&limit=%d", repoPath, page, listGiteaPullRequestsLimit)
+		if err := f.do(ctx, client, authToken, http.MethodGet, path, nil, &prs); err != nil {
+			return nil, fmt.Errorf("find pull request for %s in %s/%s: %w", t.branch, t.baseOwner, t.baseRepo, err)
+		}
+		for _, pr := range prs {
+			if pr.Head.Ref != t.branch || pr.Head.Repo == nil || !strings.EqualFold(pr.Head.Repo.Owner.Login, t.headOwner) {
+				continue
+			}
+			return &landingPullRequest{
+				number:  pr.Number,
+				url:     pr.HTMLURL,
+				headSHA: pr.Head.SHA,
+				baseRef: pr.Base.Ref,
+			}, nil
+		}
+		if len(prs) == 0 {
+			return nil, fmt.Errorf("no open pull request for %s in %s/%s", t.branch, t.baseOwner, t.baseRepo)
+		}
+	}
+}
+
+// commitChecks returns the commit statuses for a commit in the target's
+// base repository.
+func (f giteaForge) commitChecks(ctx context.Context, client *http.Client, authToken string, t *pullRequestTarget, sha string) ([]commitCheck, error) {
+	var statusDoc struct {
+		Statuses []struct {
+			Status  string
+			Context string
+		}
+	}
+	path := "repos/" + url.PathEscape(t.baseOwner) + "/" + url.PathEscape(t.baseRepo) + "/commits/" + url.PathEscape(sha) + "/status"
+	if err := f.do(ctx, client, authToken, http.MethodGet, path, nil, &statusDoc); err != nil {
+		return nil, fmt.Errorf("get commit status for %s/%s@%s: %w", t.baseOwner, t.baseRepo, sha, err)
+	}
+	checks := make([]commitCheck, 0, len(statusDoc.Statuses))
+	for _, status := range statusDoc.Statuses {
+		checks = append(checks, commitCheck{name: status.Context, state: status.Status})
+	}
+	return checks, nil
+}
+
+// mergePullRequest merges a Gitea pull request.
+func (f giteaForge) mergePullRequest(ctx context.Context, client *http.Client, authToken string, t *pullRequestTarget, pr *landingPullRequest, method, sha string) error {
+	reqBody := map[string]interface{}{
+		"Do":             method,
+		"head_commit_id": sha,
+	}
+	path := fmt.Sprintf("repos/%s/%s/pulls/%d/merge", url.PathEscape(t.baseOwner), url.PathEscape(t.baseRepo), pr.number)
+	if err := f.do(ctx, client, authToken, http.MethodPost, path, reqBody, nil); err != nil {
+		return fmt.Errorf("merge %s: %w", pr.url, err)
+	}
+	return nil
+}
+
+// do sends a request to the Gitea API and decodes the JSON response
+// into respDoc if it is not nil. reqBody is encoded as JSON if it is not nil.
+func (f giteaForge) do(ctx context.Context, client *http.Client, authToken string, method, path string, reqBody interface{}, respDoc interface{}) error {
+	var body io.Reader
+	if reqBody != nil {
+		reqBodyJSON, err := json.Marshal(reqBody)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(reqBodyJSON)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, f.apiURL(path), body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("User-Agent", userAgentString())
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("Authorization", "token "+authToken)
+	if reqBody != nil {
+		req.Header.Set("Content-Type", "application/json; charset=utf-8")
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated {
+		return parseGiteaErrorResponse(resp)
+	}
+	if respDoc == nil {
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(respDoc); err != nil {
+		return fmt.Errorf("parsing response: %w", err)
+	}
+	return nil
+}
+
+func parseGiteaErrorResponse(resp *http.Response) error {
+	t, _, err := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if err != nil || t != "application/json" {
+		return fmt.Errorf("Gitea API HTTP %s", resp.Status)
+	}
+	var payload struct {
+		Message string
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&payload); err != nil || payload.Message == "" {
+		return fmt.Errorf("Gitea API HTTP %s", resp.Status)
+	}
+	return fmt.Errorf("Gitea API HTTP %s: %s", resp.Status, payload.Message)
+}
+
+// parseGiteaRemoteURL returns the owner and name of a Gitea repository at
+// the given Git remote URL. hosts lists the host names of Gitea instances.
+func parseGiteaRemoteURL(hosts []string, u string) (host, owner, repo string) {
+	host, path := splitRemoteURL(u)
+	if host == "" || !containsFold(hosts, host) {
+		return "", "", ""
+	}
+	path = strings.TrimSuffix(path, ".git")
+	i := strings.IndexByte(path, '/')
+	if i <= 0 || i == len(path)-1 || strings.Contains(path[i+1:], "/") {
+		return "", "", ""
+	}
+	return strings.ToLower(host), path[:i], path[i+1:]
+}
+
+// containsFold reports whether list contains s, ignoring case.
+func containsFold(list []string, s string) bool {
+	for _, elem := range list {
+		if strings.EqualFold(elem, s) {
+			return true
+		}
+	}
+	return false
+}
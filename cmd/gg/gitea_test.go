@@ -0,0 +1,312 @@
+// Copyright 2021 The gg Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"gg-scm.io/pkg/git"
+	"gg-scm.io/tool/internal/filesystem"
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+)
+
+func TestRequestPull_Gitea(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	env, err := newTestEnv(ctx, t)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := env.writeConfig([]byte("[gg]\ngiteaHost = git.example.com\n")); err != nil {
+		t.Fatal(err)
+	}
+	const authToken = "xyzzy12345"
+	if err := env.topDir.Apply(filesystem.Write("xdgconfig/gg/gitea_token.git.example.com", authToken+"\n")); err != nil {
+		t.Fatal(err)
+	}
+	api := &fakeGiteaAPI{
+		errorer:        t,
+		permittedToken: authToken,
+	}
+	fakeGitea := httptest.NewServer(api)
+	t.Cleanup(fakeGitea.Close)
+	fakeGiteaTransport := &http.Transport{
+		DialTLS: func(network, addr string) (net.Conn, error) {
+			hostport := strings.TrimPrefix(fakeGitea.URL, "http://")
+			return net.Dial("tcp", hostport)
+		},
+	}
+	t.Cleanup(fakeGiteaTransport.CloseIdleConnections)
+	env.roundTripper = fakeGiteaTransport
+
+	if err := env.initRepoWithHistory(ctx, "origin"); err != nil {
+		t.Fatal(err)
+	}
+	if err := env.git.Run(ctx, "clone", "--quiet", "origin", "local"); err != nil {
+		t.Fatal(err)
+	}
+	localDir := env.root.FromSlash("local")
+	localGit := env.git.WithDir(localDir)
+	err = localGit.NewBranch(ctx, "feature", git.BranchOptions{
+		StartPoint: "origin/main",
+		Track:      true,
+		Checkout:   true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := localGit.Run(ctx, "remote", "set-url", "origin", "git@git.example.com:example/foo.git"); err != nil {
+		t.Fatal(err)
+	}
+	if err := localGit.Run(ctx, "remote", "add", "forkremote", "https://git.example.com/exampleuser/foo.git"); err != nil {
+		t.Fatal(err)
+	}
+	if err := localGit.Run(ctx, "config", "branch.feature.pushRemote", "forkremote"); err != nil {
+		t.Fatal(err)
+	}
+	if err := env.root.Apply(filesystem.Write("local/blah.txt", dummyContent)); err != nil {
+		t.Fatal(err)
+	}
+	if err := env.addFiles(ctx, "local/blah.txt"); err != nil {
+		t.Fatal(err)
+	}
+	if err := localGit.Commit(ctx, "Commit title\n\nCommit description", git.CommitOptions{}); err != nil {
+		t.Fatal(err)
+	}
+
+	out, err := env.gg(ctx, localDir, "requestpull", "--edit=0", "--draft", "-R", "alice,bob")
+	if err != nil {
+		t.Fatal(err)
+	}
+	const wantURL = "https://git.example.com/example/foo/pulls/1"
+	if !strings.Contains(string(out), wantURL) {
+		t.Errorf("output = %q; want to contain %q", out, wantURL)
+	}
+	api.mu.Lock()
+	prs := api.prs
+	api.mu.Unlock()
+	if len(prs) != 1 {
+		t.Fatalf("created %d pull requests; want 1", len(prs))
+	}
+	want := fakeGiteaPullRequest{
+		repo:      "example/foo",
+		base:      "main",
+		head:      "exampleuser:feature",
+		title:     "WIP: Commit title",
+		body:      "Commit description",
+		reviewers: []string{"alice", "bob"},
+	}
+	diff := cmp.Diff(want, prs[0], cmp.AllowUnexported(fakeGiteaPullRequest{}), cmpopts.EquateEmpty())
+	if diff != "" {
+		t.Errorf("pull request (-want +got):\n%s", diff)
+	}
+}
+
+func TestParseGiteaRemoteURL(t *testing.T) {
+	hosts := []string{"git.example.com"}
+	tests := []struct {
+		url   string
+		host  string
+		owner string
+		repo  string
+	}{
+		{url: "https://git.example.com/example/foo.git", host: "git.example.com", owner: "example", repo: "foo"},
+		{url: "git@Git.Example.com:example/foo", host: "git.example.com", owner: "example", repo: "foo"},
+		{url: "ssh://git@git.example.com:2222/example/foo.git", host: "git.example.com", owner: "example", repo: "foo"},
+		{url: "https://git.example.com/example/sub/foo.git"},
+		{url: "https://git.example.com/foo.git"},
+		{url: "https://git.example.com/example/"},
+		{url: "https://gitea.com/example/foo.git"},
+		{url: "/path/to/repo"},
+	}
+	for _, test := range tests {
+		host, owner, repo := parseGiteaRemoteURL(hosts, test.url)
+		if host != test.host || owner != test.owner || repo != test.repo {
+			t.Errorf("parseGiteaRemoteURL(%q, %q) = %q, %q, %q; want %q, %q, %q",
+				hosts, test.url, host, owner, repo, test.host, test.owner, test.repo)
+		}
+	}
+}
+
+// fakeGiteaAPI is a fake implementation of the subset of the Gitea API
+// that gg uses to create and merge pull requests.
+type fakeGiteaAPI struct {
+	errorer        interface{ Errorf(string, ...interface{}) }
+	permittedToken string
+	commitStatuses map[string]string // commit hash to status
+
+	mu  sync.Mutex
+	prs []fakeGiteaPullRequest
+	// onMerge is called with mu held when a pull request is merged.
+	onMerge func(fakeGiteaPullRequest) error
+}
+
+type fakeGiteaPullRequest struct {
+	repo      string
+	base      string
+	head      string // "branch" or "owner:branch"
+	headSHA   string
+	title     string
+	body      string
+	reviewers []string
+
+	merged      bool
+	mergeMethod string
+}
+
+func (api *fakeGiteaAPI) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if got, want := r.Header.Get("Authorization"), "token "+api.permittedToken; got != want {
+		api.errorer.Errorf("%s %s: Authorization = %q; want %q", r.Method, r.URL.Path, got, want)
+		api.writeJSON(w, http.StatusUnauthorized, map[string]string{"message": "token is required"})
+		return
+	}
+	parts := strings.Split(strings.TrimPrefix(r.URL.Path, "/api/v1/"), "/")
+	switch {
+	case r.Method == http.MethodPost && len(parts) == 4 && parts[0] == "repos" && parts[3] == "pulls":
+		var req struct {
+			Base  string
+			Head  string
+			Title string
+			Body  string
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			api.writeJSON(w, http.StatusUnprocessableEntity, map[string]string{"message": err.Error()})
+			return
+		}
+		api.mu.Lock()
+		api.prs = append(api.prs, fakeGiteaPullRequest{
+			repo:  parts[1] + "/" + parts[2],
+			base:  req.Base,
+			head:  req.Head,
+			title: req.Title,
+			body:  req.Body,
+		})
+		num := len(api.prs)
+		api.mu.Unlock()
+		api.writeJSON(w, http.StatusCreated, map[string]interface{}{
+			"number":   num,
+			"html_url": "https://" + r.Host + "/" + parts[1] + "/" + parts[2] + "/pulls/" + strconv.Itoa(num),
+		})
+	case r.Method == http.MethodPost && len(parts) == 6 && parts[0] == "repos" && parts[3] == "pulls" && parts[5] == "requested_reviewers":
+		num, err := strconv.Atoi(parts[4])
+		var req struct {
+			Reviewers []string
+		}
+		if err == nil {
+			err = json.NewDecoder(r.Body).Decode(&req)
+		}
+		if err != nil {
+			api.writeJSON(w, http.StatusUnprocessableEntity, map[string]string{"message": err.Error()})
+			return
+		}
+		api.mu.Lock()
+		defer api.mu.Unlock()
+		if num < 1 || num > len(api.prs) {
+			api.writeJSON(w, http.StatusNotFound, map[string]string{"message": "pull request does not exist"})
+			return
+		}
+		api.prs[num-1].reviewers = append(api.prs[num-1].reviewers, req.Reviewers...)
+		api.writeJSON(w, http.StatusCreated, []interface{}{})
+	case r.Method == http.MethodGet && len(parts) == 4 && parts[0] == "repos" && parts[3] == "pulls":
+		var prs []interface{}
+		if r.URL.Query().Get("page") == "1" {
+			api.mu.Lock()
+			for i, pr := range api.prs {
+				if pr.merged || pr.repo != parts[1]+"/"+parts[2] {
+					continue
+				}
+				headOwner, headRef := parts[1], pr.head
+				if j := strings.IndexByte(pr.head, ':'); j != -1 {
+					headOwner, headRef = pr.head[:j], pr.head[j+1:]
+				}
+				prs = append(prs, map[string]interface{}{
+					"number":   i + 1,
+					"html_url": "https://" + r.Host + "/" + pr.repo + "/pulls/" + strconv.Itoa(i+1),
+					"head": map[string]interface{}{
+						"ref":  headRef,
+						"sha":  pr.headSHA,
+						"repo": map[string]interface{}{"owner": map[string]string{"login": headOwner}},
+					},
+					"base": map[string]string{"ref": pr.base},
+				})
+			}
+			api.mu.Unlock()
+		}
+		api.writeJSON(w, http.StatusOK, prs)
+	case r.Method == http.MethodGet && len(parts) == 6 && parts[0] == "repos" && parts[3] == "commits" && parts[5] == "status":
+		var statuses []map[string]string
+		if status := api.commitStatuses[parts[4]]; status != "" {
+			statuses = append(statuses, map[string]string{"context": "ci", "status": status})
+		}
+		api.writeJSON(w, http.StatusOK, map[string]interface{}{"statuses": statuses})
+	case r.Method == http.MethodPost && len(parts) == 6 && parts[0] == "repos" && parts[3] == "pulls" && parts[5] == "merge":
+		num, err := strconv.Atoi(parts[4])
+		var req struct {
+			Do           string
+			HeadCommitID string `json:"head_commit_id"`
+		}
+		if err == nil {
+			err = json.NewDecoder(r.Body).Decode(&req)
+		}
+		if err != nil {
+			api.writeJSON(w, http.StatusUnprocessableEntity, map[string]string{"message": err.Error()})
+			return
+		}
+		api.mu.Lock()
+		defer api.mu.Unlock()
+		if num < 1 || num > len(api.prs) || api.prs[num-1].merged {
+			api.writeJSON(w, http.StatusNotFound, map[string]string{"message": "pull request does not exist"})
+			return
+		}
+		pr := &api.prs[num-1]
+		if req.HeadCommitID != pr.headSHA {
+			api.writeJSON(w, http.StatusConflict, map[string]string{"message": "head out of date"})
+			return
+		}
+		if api.onMerge != nil {
+			if err := api.onMerge(*pr); err != nil {
+				api.writeJSON(w, http.StatusInternalServerError, map[string]string{"message": err.Error()})
+				return
+			}
+		}
+		pr.merged = true
+		pr.mergeMethod = req.Do
+		w.WriteHeader(http.StatusOK)
+	default:
+		api.errorer.Errorf("unexpected request %s %s", r.Method, r.URL)
+		api.writeJSON(w, http.StatusNotFound, map[string]string{"message": "not found"})
+	}
+}
+
+func (api *fakeGiteaAPI) writeJSON(w http.ResponseWriter, statusCode int, v interface{}) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		api.errorer.Errorf("%v", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(statusCode)
+	w.Write(data)
+}
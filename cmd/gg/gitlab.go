@@ -0,0 +1,331 @@
+// Copyright 2021 The gg Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+)
+
+const gitLabTokenFilename = "gitlab_token"
+
+// gitLabForge is a GitLab instance, either gitlab.com or a self-hosted
+// instance listed in the gg.gitlabHost configuration setting.
+type gitLabForge struct {
+	host string
+}
+
+func (f gitLabForge) String() string {
+	return "GitLab"
+}
+
+// apiURL returns the URL of the given GitLab REST API v4 endpoint.
+func (f gitLabForge) apiURL(path string) string {
+	return "https://" + f.host + "/api/v4/" + path
+}
+
+// tokenFilename returns the name of the file in the gg configuration
+// directory that stores the token for the instance. Self-hosted instances
+// each have their own token so that one instance never sees another's.
+func (f gitLabForge) tokenFilename() string {
+	if f.host == "gitlab.com" {
+		return gitLabTokenFilename
+	}
+	return gitLabTokenFilename + "." + f.host
+}
+
+// readToken reads the saved GitLab personal access token.
+func (f gitLabForge) readToken(ctx context.Context, cc *cmdContext) ([]byte, error) {
+	filename := f.tokenFilename()
+	token, err := cc.xdgDirs.readConfig(filename)
+	if os.IsNotExist(err) {
+		return nil, fmt.Errorf("no GitLab token found. Create a personal access token with the \"api\" scope "+
+			"at https://%s/-/profile/personal_access_tokens and save it to $XDG_CONFIG_HOME/gg/%s",
+			f.host, filename)
+	}
+	if err != nil {
+		return nil, err
+	}
+	token = bytes.TrimSpace(token)
+	if len(token) == 0 {
+		return nil, fmt.Errorf("GitLab token in %s is empty", filename)
+	}
+	return token, nil
+}
+
+// createPullRequest creates a GitLab merge request. Merge requests from a
+// fork are created in the fork's project and target the base project.
+func (f gitLabForge) createPullRequest(ctx context.Context, client *http.Client, params pullRequestParams) (prURL string, _ error) {
+	if params.authToken == "" {
+		return "", errors.New("create merge request: missing authentication token")
+	}
+	if params.baseOwner == "" || params.baseRepo == "" {
+		return "", errors.New("create merge request: missing base namespace or project name")
+	}
+	if params.baseBranch == "" {
+		return "", errors.New("create merge request: missing base branch")
+	}
+	if params.headOwner == "" || params.headRepo == "" || params.headBranch == "" {
+		return "", errors.New("create merge request: missing head branch or project")
+	}
+	if params.title == "" {
+		return "", errors.New("create merge request: missing title")
+	}
+	baseProject := params.baseOwner + "/" + params.baseRepo
+	headProject := params.headOwner + "/" + params.headRepo
+
+	title := params.title
+	if params.draft {
+		title = "Draft: " + title
+	}
+	reqBody := map[string]interface{}{
+		"source_branch": params.headBranch,
+		"target_branch": params.baseBranch,
+		"title":         title,
+	}
+	if params.body != "" {
+		reqBody["description"] = params.body
+	}
+	if headProject != baseProject {
+		var project struct {
+			ID uint64
+		}
+		err := f.do(ctx, client, params.authToken, http.MethodGet, "projects/"+url.PathEscape(baseProject), nil, &project)
+		if err != nil {
+			return "", fmt.Errorf("create merge request for %s: %w", baseProject, err)
+		}
+		reqBody["target_project_id"] = project.ID
+		reqBody["allow_collaboration"] = !params.disableMaintainerEdits
+	}
+	if len(params.reviewers) > 0 {
+		var ids []uint64
+		for _, r := range params.reviewers {
+			id, err := f.userID(ctx, client, params.authToken, r)
+			if err != nil {
+				return "", fmt.Errorf("create merge request for %s: %w", baseProject, err)
+			}
+			ids = append(ids, id)
+		}
+		reqBody["reviewer_ids"] = ids
+	}
+
+	var respDoc struct {
+		WebURL string `json:"web_url"`
+	}
+	err := f.do(ctx, client, params.authToken, http.MethodPost, "projects/"+url.PathEscape(headProject)+"/merge_requests", reqBody, &respDoc)
+	if err != nil {
+		return "", fmt.Errorf("create merge request for %s: %w", baseProject, err)
+	}
+	return respDoc.WebURL, nil
+}
+
+// findOpenPullRequest returns the open merge request for the target's
+// branch. Merge requests live in the target project, even when their
+// source branch is in a fork.
+func (f gitLabForge) findOpenPullRequest(ctx context.Context, client *http.Client, authToken string, t *pullRequestTarget) (*landingPullRequest, error) {
+	baseProject := t.baseOwner + "/" + t.baseRepo
+	headProject := t.headOwner + "/" + t.headRepo
+	var mrs []struct {
+		IID             uint64
+		WebURL          string `json:"web_url"`
+		SHA             string
+		TargetBranch    string `json:"target_branch"`
+		SourceProjectID uint64 `json:"source_project_id"`
+		TargetProjectID uint64 `json:"target_project_id"`
+	}
+	path := "projects/" + url.PathEscape(baseProject) + "/merge_requests?state=opened&source_branch=" + url.QueryEscape(t.branch)
+	if err := f.do(ctx, client, authToken, http.MethodGet, path, nil, &mrs); err != nil {
+		return nil, fmt.Errorf("find merge request for %s in %s: %w", t.branch, baseProject, err)
+	}
+	var headProjectID uint64
+	if headProject != baseProject {
+		var project struct {
+			ID uint64
+		}
+		err := f.do(ctx, client, authToken, http.MethodGet, "projects/"+url.PathEscape(headProject), nil, &project)
+		if err != nil {
+			return nil, fmt.Errorf("find merge request for %s in %s: %w", t.branch, baseProject, err)
+		}
+		headProjectID = project.ID
+	}
+	for _, mr := range mrs {
+		if headProjectID == 0 && mr.SourceProjectID != mr.TargetProjectID ||
+			headProjectID != 0 && mr.SourceProjectID != headProjectID {
+			continue
+		}
+		return &landingPullRequest{
+			number:  mr.IID,
+			url:     mr.WebURL,
+			headSHA: mr.SHA,
+			baseRef: mr.TargetBranch,
+		}, nil
+	}
+	return nil, fmt.Errorf("no open merge request for %s in %s", t.branch, baseProject)
+}
+
+// commitChecks returns the latest status of each CI job for a commit in
+// the target project.
+func (f gitLabForge) commitChecks(ctx context.Context, client *http.Client, authToken string, t *pullRequestTarget, sha string) ([]commitCheck, error) {
+	baseProject := t.baseOwner + "/" + t.baseRepo
+	var statuses []struct {
+		Name   string
+		Status string
+	}
+	path := "projects/" + url.PathEscape(baseProject) + "/repository/commits/" + url.PathEscape(sha) + "/statuses"
+	if err := f.do(ctx, client, authToken, http.MethodGet, path, nil, &statuses); err != nil {
+		return nil, fmt.Errorf("get commit statuses for %s@%s: %w", baseProject, sha, err)
+	}
+	checks := make([]commitCheck, 0, len(statuses))
+	for _, status := range statuses {
+		checks = append(checks, commitCheck{name: status.Name, state: status.Status})
+	}
+	return checks, nil
+}
+
+// mergePullRequest merges a GitLab merge request. GitLab projects choose
+// between merge commits and fast-forward merges in their settings, so the
+// "rebase" method is not supported.
+func (f gitLabForge) mergePullRequest(ctx context.Context, client *http.Client, authToken string, t *pullRequestTarget, pr *landingPullRequest, method, sha string) error {
+	baseProject := t.baseOwner + "/" + t.baseRepo
+	if method == "rebase" {
+		return fmt.Errorf("merge %s: GitLab does not support the rebase method", pr.url)
+	}
+	reqBody := map[string]interface{}{
+		"sha":    sha,
+		"squash": method == "squash",
+	}
+	var respDoc struct {
+		State string
+	}
+	path := fmt.Sprintf("projects/%s/merge_requests/%d/merge", url.PathEscape(baseProject), pr.number)
+	if err := f.do(ctx, client, authToken, http.MethodPut, path, reqBody, &respDoc); err != nil {
+		return fmt.Errorf("merge %s: %w", pr.url, err)
+	}
+	return nil
+}
+
+// userID returns the numeric ID of the GitLab user with the given username.
+func (f gitLabForge) userID(ctx context.Context, client *http.Client, authToken string, username string) (uint64, error) {
+	var users []struct {
+		ID uint64
+	}
+	err := f.do(ctx, client, authToken, http.MethodGet, "users?username="+url.QueryEscape(username), nil, &users)
+	if err != nil {
+		return 0, fmt.Errorf("look up user %s: %w", username, err)
+	}
+	if len(users) == 0 {
+		return 0, fmt.Errorf("look up user %s: no such user", username)
+	}
+	return users[0].ID, nil
+}
+
+// do sends a request to the GitLab API and decodes the JSON response
+// into respDoc. reqBody is encoded as JSON if it is not nil.
+func (f gitLabForge) do(ctx context.Context, client *http.Client, authToken string, method, path string, reqBody interface{}, respDoc interface{}) error {
+	var body io.Reader
+	if reqBody != nil {
+		reqBodyJSON, err := json.Marshal(reqBody)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(reqBodyJSON)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, f.apiURL(path), body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("User-Agent", userAgentString())
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("Private-Token", authToken)
+	if reqBody != nil {
+		req.Header.Set("Content-Type", "application/json; charset=utf-8")
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated {
+		return parseGitLabErrorResponse(resp)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(respDoc); err != nil {
+		return fmt.Errorf("parsing response: %w", err)
+	}
+	return nil
+}
+
+func parseGitLabErrorResponse(resp *http.Response) error {
+	t, _, err := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if err != nil || t != "application/json" {
+		return fmt.Errorf("GitLab API HTTP %s", resp.Status)
+	}
+	// GitLab reports errors as either a "message" (which may be a string,
+	// a list, or an object of field errors) or an "error" string.
+	var payload struct {
+		Message json.RawMessage
+		Error   string
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&payload); err != nil {
+		return fmt.Errorf("GitLab API HTTP %s", resp.Status)
+	}
+	var msg string
+	if len(payload.Message) > 0 {
+		if err := json.Unmarshal(payload.Message, &msg); err != nil {
+			msg = string(payload.Message)
+		}
+	} else {
+		msg = payload.Error
+	}
+	if msg == "" {
+		return fmt.Errorf("GitLab API HTTP %s", resp.Status)
+	}
+	return fmt.Errorf("GitLab API HTTP %s: %s", resp.Status, msg)
+}
+
+// parseGitLabRemoteURL returns the namespace and project name of a GitLab
+// repository at the given Git remote URL. hosts lists the host names of
+// self-hosted GitLab instances in addition to gitlab.com.
+func parseGitLabRemoteURL(hosts []string, u string) (host, namespace, project string) {
+	host, path := splitRemoteURL(u)
+	if !isGitLabHost(hosts, host) {
+		return "", "", ""
+	}
+	path = strings.TrimSuffix(path, ".git")
+	if path == "" || strings.HasPrefix(path, "/") || strings.HasSuffix(path, "/") {
+		return "", "", ""
+	}
+	// GitLab permits nested groups, so the namespace may have any depth.
+	i := strings.LastIndexByte(path, '/')
+	if i == -1 {
+		return "", "", ""
+	}
+	return strings.ToLower(host), path[:i], path[i+1:]
+}
+
+func isGitLabHost(hosts []string, host string) bool {
+	if host == "" {
+		return false
+	}
+	return strings.EqualFold(host, "gitlab.com") || containsFold(hosts, host)
+}
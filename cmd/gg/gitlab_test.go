@@ -0,0 +1,335 @@
+// Copyright 2021 The gg Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"gg-scm.io/pkg/git"
+	"gg-scm.io/tool/internal/filesystem"
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+)
+
+func TestRequestPull_GitLab(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name        string
+		config      string
+		upstreamURL string
+		forkURL     string
+		args        []string
+
+		want fakeMergeRequest
+	}{
+		{
+			name:        "NestedGroup",
+			upstreamURL: "https://gitlab.com/example/sub/foo.git",
+			args:        []string{"--draft", "-R", "alice"},
+			want: fakeMergeRequest{
+				sourceProject: "example/sub/foo",
+				sourceBranch:  "feature",
+				targetBranch:  "main",
+				title:         "Draft: Commit title",
+				description:   "Commit description",
+				reviewerIDs:   []uint64{42},
+			},
+		},
+		{
+			name:        "SelfHostedFork",
+			config:      "[gg]\ngitlabHost = git.example.com\n",
+			upstreamURL: "git@git.example.com:example/foo.git",
+			forkURL:     "https://git.example.com/exampleuser/foo.git",
+			want: fakeMergeRequest{
+				sourceProject:      "exampleuser/foo",
+				targetProjectID:    7,
+				sourceBranch:       "feature",
+				targetBranch:       "main",
+				title:              "Commit title",
+				description:        "Commit description",
+				allowCollaboration: true,
+			},
+		},
+	}
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			ctx := context.Background()
+			env, err := newTestEnv(ctx, t)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := env.writeConfig([]byte(test.config)); err != nil {
+				t.Fatal(err)
+			}
+			const authToken = "xyzzy12345"
+			err = env.topDir.Apply(
+				filesystem.Write("xdgconfig/gg/gitlab_token", authToken+"\n"),
+				filesystem.Write("xdgconfig/gg/gitlab_token.git.example.com", authToken+"\n"),
+			)
+			if err != nil {
+				t.Fatal(err)
+			}
+			api := &fakeGitLabAPI{
+				errorer:        t,
+				permittedToken: authToken,
+				projects:       map[string]uint64{"example/foo": 7},
+				users:          map[string]uint64{"alice": 42},
+			}
+			fakeGitLab := httptest.NewServer(api)
+			t.Cleanup(fakeGitLab.Close)
+			fakeGitLabTransport := &http.Transport{
+				DialTLS: func(network, addr string) (net.Conn, error) {
+					hostport := strings.TrimPrefix(fakeGitLab.URL, "http://")
+					return net.Dial("tcp", hostport)
+				},
+			}
+			t.Cleanup(fakeGitLabTransport.CloseIdleConnections)
+			env.roundTripper = fakeGitLabTransport
+
+			if err := env.initRepoWithHistory(ctx, "origin"); err != nil {
+				t.Fatal(err)
+			}
+			if err := env.git.Run(ctx, "clone", "--quiet", "origin", "local"); err != nil {
+				t.Fatal(err)
+			}
+			localDir := env.root.FromSlash("local")
+			localGit := env.git.WithDir(localDir)
+			err = localGit.NewBranch(ctx, "feature", git.BranchOptions{
+				StartPoint: "origin/main",
+				Track:      true,
+				Checkout:   true,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := localGit.Run(ctx, "remote", "set-url", "origin", test.upstreamURL); err != nil {
+				t.Fatal(err)
+			}
+			if test.forkURL != "" {
+				if err := localGit.Run(ctx, "remote", "add", "forkremote", test.forkURL); err != nil {
+					t.Fatal(err)
+				}
+				if err := localGit.Run(ctx, "config", "branch.feature.pushRemote", "forkremote"); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if err := env.root.Apply(filesystem.Write("local/blah.txt", dummyContent)); err != nil {
+				t.Fatal(err)
+			}
+			if err := env.addFiles(ctx, "local/blah.txt"); err != nil {
+				t.Fatal(err)
+			}
+			if err := localGit.Commit(ctx, "Commit title\n\nCommit description", git.CommitOptions{}); err != nil {
+				t.Fatal(err)
+			}
+
+			args := append([]string{"requestpull", "--edit=0"}, test.args...)
+			out, err := env.gg(ctx, localDir, args...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !strings.Contains(string(out), "Created pull request at https://") {
+				t.Errorf("output = %q; want to contain merge request URL", out)
+			}
+			api.mu.Lock()
+			mrs := api.mrs
+			api.mu.Unlock()
+			if len(mrs) != 1 {
+				t.Fatalf("created %d merge requests; want 1", len(mrs))
+			}
+			diff := cmp.Diff(test.want, mrs[0], cmp.AllowUnexported(fakeMergeRequest{}), cmpopts.EquateEmpty())
+			if diff != "" {
+				t.Errorf("merge request (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestRequestPull_GitLabUnsupported(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	env, err := newTestEnv(ctx, t)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := env.initRepoWithHistory(ctx, "origin"); err != nil {
+		t.Fatal(err)
+	}
+	if err := env.git.Run(ctx, "clone", "--quiet", "origin", "local"); err != nil {
+		t.Fatal(err)
+	}
+	localDir := env.root.FromSlash("local")
+	localGit := env.git.WithDir(localDir)
+	err = localGit.NewBranch(ctx, "feature", git.BranchOptions{
+		StartPoint: "origin/main",
+		Track:      true,
+		Checkout:   true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := localGit.Run(ctx, "remote", "set-url", "origin", "https://gitlab.com/example/foo.git"); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, arg := range []string{"--sync", "--ready", "--status"} {
+		_, err := env.gg(ctx, localDir, "requestpull", arg)
+		if err == nil {
+			t.Errorf("gg requestpull %s on GitLab did not return an error", arg)
+			continue
+		}
+		if isUsage(err) {
+			t.Errorf("gg requestpull %s on GitLab returned usage error: %v", arg, err)
+		}
+		if want := "does not support GitLab"; !strings.Contains(err.Error(), want) {
+			t.Errorf("gg requestpull %s on GitLab error = %v; want to contain %q", arg, err, want)
+		}
+	}
+}
+
+func TestParseGitLabRemoteURL(t *testing.T) {
+	hosts := []string{"git.example.com"}
+	tests := []struct {
+		url       string
+		host      string
+		namespace string
+		project   string
+	}{
+		{url: "https://gitlab.com/example/foo.git", host: "gitlab.com", namespace: "example", project: "foo"},
+		{url: "https://gitlab.com/example/sub/foo", host: "gitlab.com", namespace: "example/sub", project: "foo"},
+		{url: "git@gitlab.com:example/foo.git", host: "gitlab.com", namespace: "example", project: "foo"},
+		{url: "ssh://git@GitLab.com/example/foo.git", host: "gitlab.com", namespace: "example", project: "foo"},
+		{url: "https://git.example.com/example/foo.git", host: "git.example.com", namespace: "example", project: "foo"},
+		{url: "https://gitlab.com/foo.git"},
+		{url: "https://gitlab.com/example/"},
+		{url: "https://github.com/example/foo.git"},
+		{url: "https://gitlab.example.org/example/foo.git"},
+		{url: "/path/to/repo"},
+	}
+	for _, test := range tests {
+		host, namespace, project := parseGitLabRemoteURL(hosts, test.url)
+		if host != test.host || namespace != test.namespace || project != test.project {
+			t.Errorf("parseGitLabRemoteURL(%q, %q) = %q, %q, %q; want %q, %q, %q",
+				hosts, test.url, host, namespace, project, test.host, test.namespace, test.project)
+		}
+	}
+}
+
+// fakeGitLabAPI is a fake implementation of the subset of the GitLab REST
+// API that gg uses to create merge requests.
+type fakeGitLabAPI struct {
+	errorer        interface{ Errorf(string, ...interface{}) }
+	permittedToken string
+	projects       map[string]uint64 // project path to ID
+	users          map[string]uint64 // username to ID
+
+	mu  sync.Mutex
+	mrs []fakeMergeRequest
+}
+
+type fakeMergeRequest struct {
+	sourceProject      string
+	targetProjectID    uint64
+	sourceBranch       string
+	targetBranch       string
+	title              string
+	description        string
+	reviewerIDs        []uint64
+	allowCollaboration bool
+}
+
+func (api *fakeGitLabAPI) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if got := r.Header.Get("Private-Token"); got != api.permittedToken {
+		api.errorer.Errorf("%s %s: Private-Token = %q; want %q", r.Method, r.URL.Path, got, api.permittedToken)
+		api.writeJSON(w, http.StatusUnauthorized, map[string]string{"message": "401 Unauthorized"})
+		return
+	}
+	// Project paths are escaped into a single path segment.
+	p := strings.TrimPrefix(r.URL.EscapedPath(), "/api/v4/")
+	switch {
+	case r.Method == http.MethodGet && p == "users":
+		var users []map[string]uint64
+		if id, ok := api.users[r.URL.Query().Get("username")]; ok {
+			users = append(users, map[string]uint64{"id": id})
+		}
+		api.writeJSON(w, http.StatusOK, users)
+	case r.Method == http.MethodGet && strings.HasPrefix(p, "projects/") && strings.Count(p, "/") == 1:
+		project, _ := url.PathUnescape(strings.TrimPrefix(p, "projects/"))
+		id, ok := api.projects[project]
+		if !ok {
+			api.writeJSON(w, http.StatusNotFound, map[string]string{"message": "404 Project Not Found"})
+			return
+		}
+		api.writeJSON(w, http.StatusOK, map[string]interface{}{"id": id, "path_with_namespace": project})
+	case r.Method == http.MethodPost && strings.HasPrefix(p, "projects/") && strings.HasSuffix(p, "/merge_requests"):
+		project, _ := url.PathUnescape(strings.TrimSuffix(strings.TrimPrefix(p, "projects/"), "/merge_requests"))
+		var req struct {
+			SourceBranch       string   `json:"source_branch"`
+			TargetBranch       string   `json:"target_branch"`
+			TargetProjectID    uint64   `json:"target_project_id"`
+			Title              string   `json:"title"`
+			Description        string   `json:"description"`
+			ReviewerIDs        []uint64 `json:"reviewer_ids"`
+			AllowCollaboration bool     `json:"allow_collaboration"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			api.writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+			return
+		}
+		api.mu.Lock()
+		api.mrs = append(api.mrs, fakeMergeRequest{
+			sourceProject:      project,
+			targetProjectID:    req.TargetProjectID,
+			sourceBranch:       req.SourceBranch,
+			targetBranch:       req.TargetBranch,
+			title:              req.Title,
+			description:        req.Description,
+			reviewerIDs:        req.ReviewerIDs,
+			allowCollaboration: req.AllowCollaboration,
+		})
+		iid := len(api.mrs)
+		api.mu.Unlock()
+		api.writeJSON(w, http.StatusCreated, map[string]interface{}{
+			"iid":     iid,
+			"web_url": "https://" + r.Host + "/" + project + "/-/merge_requests/" + strconv.Itoa(iid),
+		})
+	default:
+		api.errorer.Errorf("unexpected request %s %s", r.Method, r.URL)
+		api.writeJSON(w, http.StatusNotFound, map[string]string{"message": "404 Not Found"})
+	}
+}
+
+func (api *fakeGitLabAPI) writeJSON(w http.ResponseWriter, statusCode int, v interface{}) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		api.errorer.Errorf("%v", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(statusCode)
+	w.Write(data)
+}
@@ -0,0 +1,128 @@
+// Copyright 2021 The gg Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"gg-scm.io/pkg/git"
+)
+
+// gitVersion is a Git release version number.
+type gitVersion struct {
+	major, minor, patch int
+}
+
+// minGitVersion is the oldest version of Git that gg is tested against.
+var minGitVersion = gitVersion{2, 17, 1}
+
+// queryGitVersion runs `git --version` and parses its output.
+func queryGitVersion(ctx context.Context, g *git.Git) (gitVersion, error) {
+	out, err := g.Output(ctx, "--version")
+	if err != nil {
+		return gitVersion{}, err
+	}
+	return parseGitVersion(out)
+}
+
+// parseGitVersion parses the output of `git --version`, like
+// "git version 2.30.1" or "git version 2.24.3 (Apple Git-128)".
+func parseGitVersion(s string) (gitVersion, error) {
+	const prefix = "git version "
+	s = strings.TrimSpace(s)
+	if !strings.HasPrefix(s, prefix) {
+		return gitVersion{}, fmt.Errorf("parse git version %q: missing %q prefix", s, prefix)
+	}
+	s = s[len(prefix):]
+	if i := strings.IndexByte(s, ' '); i != -1 {
+		s = s[:i]
+	}
+	parts := strings.SplitN(s, ".", 4)
+	if len(parts) < 2 {
+		return gitVersion{}, fmt.Errorf("parse git version %q: not enough components", s)
+	}
+	var nums [3]int
+	for i := 0; i < len(nums) && i < len(parts); i++ {
+		n, err := strconv.Atoi(parts[i])
+		if err != nil || n < 0 {
+			if i == 2 {
+				// Some builds use suffixes like "2.31.rc0". Treat as x.y.0.
+				break
+			}
+			return gitVersion{}, fmt.Errorf("parse git version %q: invalid component %q", s, parts[i])
+		}
+		nums[i] = n
+	}
+	return gitVersion{nums[0], nums[1], nums[2]}, nil
+}
+
+// less reports whether v is an older version than v2.
+func (v gitVersion) less(v2 gitVersion) bool {
+	if v.major != v2.major {
+		return v.major < v2.major
+	}
+	if v.minor != v2.minor {
+		return v.minor < v2.minor
+	}
+	return v.patch < v2.patch
+}
+
+// String formats the version as "major.minor.patch".
+func (v gitVersion) String() string {
+	return fmt.Sprintf("%d.%d.%d", v.major, v.minor, v.patch)
+}
+
+// gitInstallURL is the page that users are directed to
+// when Git needs to be installed or upgraded.
+const gitInstallURL = "https://git-scm.com/downloads"
+
+// gitEnvError is returned when the Git installation is missing or
+// cannot be used by gg.
+type gitEnvError struct {
+	exe     string     // empty if Git could not be found
+	version gitVersion // zero if the version could not be determined
+	err     error      // underlying error, if any
+}
+
+// checkGitVersion verifies that the Git executable is at least minGitVersion.
+// It returns a *gitEnvError if Git cannot be run or is too old.
+func checkGitVersion(ctx context.Context, g *git.Git) (gitVersion, error) {
+	version, err := queryGitVersion(ctx, g)
+	if err != nil {
+		return gitVersion{}, &gitEnvError{exe: g.Exe(), err: err}
+	}
+	if version.less(minGitVersion) {
+		return version, &gitEnvError{exe: g.Exe(), version: version}
+	}
+	return version, nil
+}
+
+func (e *gitEnvError) Error() string {
+	switch {
+	case e.exe == "":
+		return fmt.Sprintf("git not found (%v); install Git %v or later from %s, or pass --git", e.err, minGitVersion, gitInstallURL)
+	case e.err != nil:
+		return fmt.Sprintf("could not run %s (%v); make sure Git %v or later is installed from %s", e.exe, e.err, minGitVersion, gitInstallURL)
+	default:
+		return fmt.Sprintf("%s is Git %v, but gg requires %v or later; upgrade from %s", e.exe, e.version, minGitVersion, gitInstallURL)
+	}
+}
+
+func (e *gitEnvError) Unwrap() error {
+	return e.err
+}
@@ -0,0 +1,338 @@
+// Copyright 2021 The gg Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gg-scm.io/pkg/git"
+	"gg-scm.io/pkg/git/object"
+	"gg-scm.io/tool/internal/flag"
+)
+
+const graftSynopsis = "copy commits from other branches onto the current branch"
+
+func graft(ctx context.Context, cc *cmdContext, args []string) error {
+	f := flag.NewFlagSet(true, "gg graft [--log] [-r] REV [...]\n"+
+		"gg graft --continue\n"+
+		"gg graft --abort", graftSynopsis+`
+
+	graft applies the changes from each of the given revisions on top of
+	the working copy's parent as new commits, keeping the original author
+	and message. An argument of the form `+"`A..B`"+` grafts the commits
+	reachable from B but not A, oldest first. Merge commits in ranges are
+	skipped.
+
+	Revisions whose changes are already present in the working copy
+	produce no changes and are skipped. With `+"`--log`"+`, a line of the
+	form "(grafted from HASH)" is added to each new commit's message.
+
+	If a graft produces conflicts, graft stops so that they can be
+	resolved. `+"`--continue`"+` commits the resolved changes and grafts
+	the remaining revisions. `+"`--abort`"+` returns the current branch to
+	where it was before the graft started. `+"`gg cherry-pick`"+` is an
+	alias for graft.`)
+	revs := f.MultiString("r", "`rev`ision to graft")
+	logFlag := f.Bool("log", false, "record the original commit in each message")
+	continue_ := f.Bool("continue", false, "continue an interrupted graft")
+	abort := f.Bool("abort", false, "abort an interrupted graft")
+	if err := f.Parse(args); flag.IsHelp(err) {
+		f.Help(cc.stdout)
+		return nil
+	} else if err != nil {
+		return usagef("%v", err)
+	}
+	*revs = append(*revs, f.Args()...)
+	switch {
+	case *continue_ && *abort:
+		return usagef("can't specify both --abort and --continue")
+	case (*continue_ || *abort) && (*logFlag || len(*revs) > 0):
+		return usagef("can't specify other options with --abort or --continue")
+	case *abort:
+		return abortGraft(ctx, cc)
+	case *continue_:
+		return continueGraft(ctx, cc)
+	case len(*revs) == 0:
+		return usagef("must pass at least one revision")
+	}
+
+	if _, err := readGraftState(ctx, cc.git); err == nil {
+		return errors.New("graft already in progress (use gg graft --continue or --abort)")
+	} else if !os.IsNotExist(err) {
+		return err
+	}
+	if clean, err := isClean(ctx, cc.git); err != nil {
+		return err
+	} else if !clean {
+		return errors.New("working copy has uncommitted changes; commit or shelve them first")
+	}
+	head, err := cc.git.Head(ctx)
+	if err != nil {
+		return err
+	}
+	state := &graftState{orig: head.Commit, log: *logFlag}
+	for _, rev := range *revs {
+		commits, err := resolveGraftRev(ctx, cc.git, rev)
+		if err != nil {
+			return err
+		}
+		state.picks = append(state.picks, commits...)
+	}
+	if len(state.picks) == 0 {
+		return errors.New("no commits to graft")
+	}
+	return runGraft(ctx, cc, state)
+}
+
+// resolveGraftRev returns the commits named by a graft argument, oldest
+// first.
+func resolveGraftRev(ctx context.Context, g *git.Git, rev string) ([]git.Hash, error) {
+	if strings.HasPrefix(rev, "-") {
+		return nil, fmt.Errorf("revision %q cannot start with a dash", rev)
+	}
+	if strings.Contains(rev, "...") {
+		return nil, fmt.Errorf("graft %s: symmetric difference ranges are not supported", rev)
+	}
+	if strings.Contains(rev, "..") {
+		out, err := g.Output(ctx, "rev-list", "--reverse", "--no-merges", rev, "--")
+		if err != nil {
+			return nil, err
+		}
+		var commits []git.Hash
+		for _, line := range strings.Fields(out) {
+			h, err := git.ParseHash(line)
+			if err != nil {
+				return nil, fmt.Errorf("graft %s: %w", rev, err)
+			}
+			commits = append(commits, h)
+		}
+		return commits, nil
+	}
+	c, err := g.CommitInfo(ctx, rev)
+	if err != nil {
+		return nil, err
+	}
+	if len(c.Parents) > 1 {
+		return nil, fmt.Errorf("cannot graft %s: it is a merge commit", rev)
+	}
+	return []git.Hash{c.SHA1()}, nil
+}
+
+// runGraft grafts the commits in state.picks in order. If a graft
+// produces conflicts, runGraft saves the state for a later --continue and
+// returns an error.
+func runGraft(ctx context.Context, cc *cmdContext, state *graftState) error {
+	for len(state.picks) > 0 {
+		c := state.picks[0]
+		if err := cc.git.Run(ctx, "cherry-pick", "--no-commit", c.String()); err != nil {
+			if err := writeGraftState(ctx, cc.git, state); err != nil {
+				return err
+			}
+			return fmt.Errorf("conflicts while grafting %v; resolve them, then run gg graft --continue (or gg graft --abort)", c.Short())
+		}
+		if err := commitGraft(ctx, cc, c, state.log); err != nil {
+			return err
+		}
+		state.picks = state.picks[1:]
+	}
+	return removeGraftState(ctx, cc.git)
+}
+
+// commitGraft commits the index as a copy of the given commit. If the
+// index has no changes from HEAD, then commitGraft reports that the
+// commit is skipped.
+func commitGraft(ctx context.Context, cc *cmdContext, c git.Hash, log bool) error {
+	info, err := cc.git.CommitInfo(ctx, c.String())
+	if err != nil {
+		return err
+	}
+	head, err := cc.git.CommitInfo(ctx, git.Head.String())
+	if err != nil {
+		return err
+	}
+	out, err := cc.git.Output(ctx, "write-tree")
+	if err != nil {
+		return err
+	}
+	tree, err := git.ParseHash(strings.TrimSpace(out))
+	if err != nil {
+		return fmt.Errorf("graft %v: %w", c.Short(), err)
+	}
+	if err := removeCherryPickHead(ctx, cc.git); err != nil {
+		return err
+	}
+	if tree == head.Tree {
+		fmt.Fprintf(cc.stderr, "gg: skipping %v (%s): no changes\n", c.Short(), info.Summary())
+		return nil
+	}
+	msg := info.Message
+	if log {
+		msg = strings.TrimRight(msg, "\n") + "\n\n(grafted from " + c.String() + ")\n"
+	}
+	grafted, err := writeCommit(ctx, cc, &object.Commit{
+		Tree:       tree,
+		Parents:    []git.Hash{head.SHA1()},
+		Author:     info.Author,
+		AuthorTime: info.AuthorTime,
+		Message:    msg,
+	})
+	if err != nil {
+		return err
+	}
+	return cc.git.Run(ctx, "update-ref", "-m", "gg graft: "+info.Summary(), git.Head.String(), grafted.String(), head.SHA1().String())
+}
+
+func continueGraft(ctx context.Context, cc *cmdContext) error {
+	state, err := readGraftState(ctx, cc.git)
+	if os.IsNotExist(err) {
+		return errors.New("no graft in progress")
+	}
+	if err != nil {
+		return err
+	}
+	status, err := cc.git.Status(ctx, git.StatusOptions{})
+	if err != nil {
+		return err
+	}
+	hasChanges, err := verifyNoMissingOrUnmerged(status)
+	if err != nil {
+		return err
+	}
+	if hasChanges {
+		if err := cc.git.StageTracked(ctx); err != nil {
+			return err
+		}
+	}
+	if err := commitGraft(ctx, cc, state.picks[0], state.log); err != nil {
+		return err
+	}
+	state.picks = state.picks[1:]
+	return runGraft(ctx, cc, state)
+}
+
+func abortGraft(ctx context.Context, cc *cmdContext) error {
+	state, err := readGraftState(ctx, cc.git)
+	if os.IsNotExist(err) {
+		return errors.New("no graft in progress")
+	}
+	if err != nil {
+		return err
+	}
+	if err := cc.git.Run(ctx, "reset", "--merge", state.orig.String()); err != nil {
+		return err
+	}
+	return removeGraftState(ctx, cc.git)
+}
+
+// graftStateFilename is the name of the file in the Git directory that
+// records an interrupted graft.
+const graftStateFilename = "gg-graft"
+
+// graftState is the progress of a graft.
+type graftState struct {
+	// orig is the commit that HEAD pointed to before the graft started.
+	orig git.Hash
+	// log is true if the grafted commit messages name their originals.
+	log bool
+	// picks is the list of commits left to graft. If the graft was
+	// interrupted, the first element is the commit that had conflicts.
+	picks []git.Hash
+}
+
+// readGraftState reads the state of an interrupted graft. It returns an
+// error satisfying os.IsNotExist if there is no graft in progress.
+func readGraftState(ctx context.Context, g *git.Git) (*graftState, error) {
+	gitDir, err := g.GitDir(ctx)
+	if err != nil {
+		return nil, err
+	}
+	data, err := ioutil.ReadFile(filepath.Join(gitDir, graftStateFilename))
+	if err != nil {
+		return nil, err
+	}
+	state := new(graftState)
+	for _, line := range strings.Split(strings.TrimSpace(string(data)), "\n") {
+		fields := strings.Fields(line)
+		switch {
+		case len(fields) == 2 && fields[0] == "orig":
+			state.orig, err = git.ParseHash(fields[1])
+		case len(fields) == 2 && fields[0] == "pick":
+			var h git.Hash
+			h, err = git.ParseHash(fields[1])
+			state.picks = append(state.picks, h)
+		case len(fields) == 1 && fields[0] == "log":
+			state.log = true
+		default:
+			err = fmt.Errorf("unknown line %q", line)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("read graft state: %w", err)
+		}
+	}
+	if state.orig == (git.Hash{}) || len(state.picks) == 0 {
+		return nil, errors.New("read graft state: incomplete")
+	}
+	return state, nil
+}
+
+func writeGraftState(ctx context.Context, g *git.Git, state *graftState) error {
+	gitDir, err := g.GitDir(ctx)
+	if err != nil {
+		return err
+	}
+	sb := new(strings.Builder)
+	fmt.Fprintf(sb, "orig %v\n", state.orig)
+	if state.log {
+		sb.WriteString("log\n")
+	}
+	for _, c := range state.picks {
+		fmt.Fprintf(sb, "pick %v\n", c)
+	}
+	if err := ioutil.WriteFile(filepath.Join(gitDir, graftStateFilename), []byte(sb.String()), 0666); err != nil {
+		return fmt.Errorf("save graft state: %w", err)
+	}
+	return nil
+}
+
+func removeGraftState(ctx context.Context, g *git.Git) error {
+	gitDir, err := g.GitDir(ctx)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(filepath.Join(gitDir, graftStateFilename)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// removeCherryPickHead removes the CHERRY_PICK_HEAD file that Git leaves
+// behind when `git cherry-pick --no-commit` has conflicts, so that Git
+// does not think a cherry-pick is still in progress.
+func removeCherryPickHead(ctx context.Context, g *git.Git) error {
+	gitDir, err := g.GitDir(ctx)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(filepath.Join(gitDir, "CHERRY_PICK_HEAD")); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,179 @@
+// Copyright 2021 The gg Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"gg-scm.io/pkg/git"
+	"gg-scm.io/tool/internal/filesystem"
+)
+
+func TestGraft(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	env, err := newTestEnv(ctx, t)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := env.initRepoWithHistory(ctx, "."); err != nil {
+		t.Fatal(err)
+	}
+	err = env.git.NewBranch(ctx, "topic", git.BranchOptions{Checkout: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := env.root.Apply(filesystem.Write("foo.txt", "foo\n")); err != nil {
+		t.Fatal(err)
+	}
+	if err := env.addFiles(ctx, "foo.txt"); err != nil {
+		t.Fatal(err)
+	}
+	c1, err := env.newCommit(ctx, ".")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := env.root.Apply(filesystem.Write("bar.txt", "bar\n")); err != nil {
+		t.Fatal(err)
+	}
+	if err := env.addFiles(ctx, "bar.txt"); err != nil {
+		t.Fatal(err)
+	}
+	c2, err := env.newCommit(ctx, ".")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := env.git.CheckoutBranch(ctx, "main", git.CheckoutOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	mainTip, err := env.git.Head(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Graft the first commit on its own, then the whole range. The first
+	// commit should be skipped the second time.
+	if _, err := env.gg(ctx, env.root.String(), "graft", "--log", c1.String()); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := env.gg(ctx, env.root.String(), "cherry-pick", "main.."+c2.String()); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"foo.txt", "bar.txt"} {
+		if exists, err := env.root.Exists(name); err != nil {
+			t.Error(err)
+		} else if !exists {
+			t.Errorf("%s does not exist after graft", name)
+		}
+	}
+	n, err := countCommits(ctx, env.git, mainTip.Commit.String()+"..HEAD")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 2 {
+		t.Errorf("graft created %d commits; want 2", n)
+	}
+	first, err := env.git.CommitInfo(ctx, "HEAD~")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "(grafted from " + c1.String() + ")"; !strings.Contains(first.Message, want) {
+		t.Errorf("first grafted commit message = %q; want to contain %q", first.Message, want)
+	}
+	if first.SHA1() == c1 {
+		t.Error("graft reused original commit; want a copy")
+	}
+}
+
+func TestGraft_Conflict(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	env, err := newTestEnv(ctx, t)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := env.initEmptyRepo(ctx, "."); err != nil {
+		t.Fatal(err)
+	}
+	if err := env.root.Apply(filesystem.Write("foo.txt", "original\n")); err != nil {
+		t.Fatal(err)
+	}
+	if err := env.addFiles(ctx, "foo.txt"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := env.newCommit(ctx, "."); err != nil {
+		t.Fatal(err)
+	}
+	err = env.git.NewBranch(ctx, "topic", git.BranchOptions{Checkout: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := env.root.Apply(filesystem.Write("foo.txt", "topic\n")); err != nil {
+		t.Fatal(err)
+	}
+	topicCommit, err := env.newCommit(ctx, ".")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := env.git.CheckoutBranch(ctx, "main", git.CheckoutOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := env.root.Apply(filesystem.Write("foo.txt", "main\n")); err != nil {
+		t.Fatal(err)
+	}
+	mainCommit, err := env.newCommit(ctx, ".")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := env.gg(ctx, env.root.String(), "graft", topicCommit.String()); err == nil {
+		t.Fatal("graft with conflicts did not return an error")
+	}
+	if _, err := env.gg(ctx, env.root.String(), "graft", "--abort"); err != nil {
+		t.Fatal(err)
+	}
+	if head, err := env.git.Head(ctx); err != nil {
+		t.Fatal(err)
+	} else if head.Commit != mainCommit {
+		t.Errorf("after graft --abort, HEAD = %v; want %v", head.Commit, mainCommit)
+	}
+
+	if _, err := env.gg(ctx, env.root.String(), "graft", topicCommit.String()); err == nil {
+		t.Fatal("graft with conflicts did not return an error")
+	}
+	if err := env.root.Apply(filesystem.Write("foo.txt", "resolved\n")); err != nil {
+		t.Fatal(err)
+	}
+	if err := env.addFiles(ctx, "foo.txt"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := env.gg(ctx, env.root.String(), "graft", "--continue"); err != nil {
+		t.Fatal(err)
+	}
+	info, err := env.git.CommitInfo(ctx, "HEAD")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(info.Parents) != 1 || info.Parents[0] != mainCommit {
+		t.Errorf("after graft --continue, HEAD~ = %v; want %v", info.Parents, mainCommit)
+	}
+	if got, err := env.root.ReadFile("foo.txt"); err != nil {
+		t.Error(err)
+	} else if got != "resolved\n" {
+		t.Errorf("foo.txt = %q; want %q", got, "resolved\n")
+	}
+}
@@ -0,0 +1,257 @@
+// Copyright 2021 The gg Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"gg-scm.io/pkg/git"
+	"gg-scm.io/tool/internal/flag"
+	"gg-scm.io/tool/internal/terminal"
+)
+
+const grepSynopsis = "search for a pattern in files or history"
+
+// errNoMatches is returned by grep when the pattern was not found.
+var errNoMatches = errors.New("no matches found")
+
+func grep(ctx context.Context, cc *cmdContext, args []string) error {
+	f := flag.NewFlagSet(true, "gg grep [-i] [-F] [-l] [-0] [-r REV | --all-history] PATTERN [FILE [...]]", grepSynopsis+`
+
+	grep searches the tracked files in the working copy for lines matching
+	a POSIX extended regular expression and prints each match as
+	FILE:LINE:TEXT. With `+"`-r`"+`, the files are searched as of the
+	given revision instead, and each match is prefixed with the revision.
+
+	`+"`--all-history`"+` searches the changes made by every commit
+	reachable from HEAD instead of file contents. It prints HASH:FILE
+	for each file in which the commit added or removed a matching line
+	(or, with `+"`-F`"+`, changed the number of occurrences of the
+	string). This finds where a pattern was introduced or removed.
+
+	`+"`-0`"+` ends each file name with a NUL byte instead of a colon or
+	newline, for use by other programs. Output is colored
+	when writing to a terminal, unless the `+"`color.grep`"+` setting says
+	otherwise.
+
+	grep exits with a non-zero status if no matches are found.`)
+	ignoreCase := f.Bool("i", false, "ignore case when matching")
+	f.Alias("i", "ignore-case")
+	fixed := f.Bool("F", false, "interpret the pattern as a fixed string")
+	f.Alias("F", "fixed-strings")
+	filesOnly := f.Bool("l", false, "only print the names of files with matches")
+	f.Alias("l", "files-with-matches")
+	nul := f.Bool("0", false, "end file names with NUL bytes")
+	f.Alias("0", "print0")
+	rev := f.String("r", "", "search files as of the given `rev`ision")
+	allHistory := f.Bool("all-history", false, "search the changes in every commit")
+	if err := f.Parse(args); flag.IsHelp(err) {
+		f.Help(cc.stdout)
+		return nil
+	} else if err != nil {
+		return usagef("%v", err)
+	}
+	if f.NArg() == 0 {
+		return usagef("must pass a pattern")
+	}
+	if *rev != "" && *allHistory {
+		return usagef("can't pass both -r and --all-history")
+	}
+	if *filesOnly && *allHistory {
+		return usagef("can't pass both -l and --all-history")
+	}
+	if strings.HasPrefix(*rev, "-") {
+		return usagef("revisions must not start with '-'")
+	}
+	pattern := f.Arg(0)
+	pathspecs := f.Args()[1:]
+	cfg, err := cc.git.ReadConfig(ctx)
+	if err != nil {
+		return err
+	}
+	colorize, err := cfg.ColorBool("color.grep", cc.isTerminal() && !*nul)
+	if err != nil {
+		fmt.Fprintln(cc.stderr, "gg:", err)
+	}
+
+	if *allHistory {
+		var hashColor, fileColor []byte
+		if colorize {
+			hashColor, err = cfg.Color("color.diff.commit", "yellow")
+			if err != nil {
+				fmt.Fprintln(cc.stderr, "gg:", err)
+			}
+			fileColor, err = cfg.Color("color.grep.filename", "magenta")
+			if err != nil {
+				fmt.Fprintln(cc.stderr, "gg:", err)
+			}
+		}
+		matches, err := grepHistory(ctx, cc.git, pattern, pathspecs, *ignoreCase, *fixed)
+		if err != nil {
+			return err
+		}
+		if len(matches) == 0 {
+			return errNoMatches
+		}
+		for _, m := range matches {
+			if err := m.write(cc.stdout, *nul, hashColor, fileColor); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	grepArgs := []string{"grep", "-n", "-I"}
+	if colorize {
+		grepArgs = append(grepArgs, "--color=always")
+	} else {
+		grepArgs = append(grepArgs, "--color=never")
+	}
+	if *nul {
+		grepArgs = append(grepArgs, "-z")
+	}
+	if *ignoreCase {
+		grepArgs = append(grepArgs, "-i")
+	}
+	if *fixed {
+		grepArgs = append(grepArgs, "-F")
+	} else {
+		grepArgs = append(grepArgs, "-E")
+	}
+	if *filesOnly {
+		grepArgs = append(grepArgs, "-l")
+	}
+	grepArgs = append(grepArgs, "-e", pattern)
+	if *rev != "" {
+		grepArgs = append(grepArgs, *rev)
+	}
+	grepArgs = append(grepArgs, "--")
+	grepArgs = append(grepArgs, pathspecs...)
+	stdout := &countingWriter{w: cc.stdout}
+	stderr := new(bytes.Buffer)
+	err = cc.git.Runner().RunGit(ctx, &git.Invocation{
+		Dir:    cc.dir,
+		Args:   grepArgs,
+		Env:    cc.env,
+		Stdout: stdout,
+		Stderr: io.MultiWriter(cc.stderr, stderr),
+	})
+	if err != nil && stdout.n == 0 && stderr.Len() == 0 {
+		// git grep exits with status 1 and no output if there are no matches.
+		return errNoMatches
+	}
+	if err != nil {
+		return fmt.Errorf("git grep: %w", err)
+	}
+	return nil
+}
+
+// historyMatch is a file in which a commit changed lines matching
+// a pattern.
+type historyMatch struct {
+	commit git.Hash
+	file   git.TopPath
+}
+
+func (m historyMatch) write(w io.Writer, nul bool, hashColor, fileColor []byte) error {
+	eol := "\n"
+	if nul {
+		eol = "\x00"
+	}
+	if _, err := w.Write(hashColor); err != nil {
+		return err
+	}
+	if _, err := io.WriteString(w, m.commit.Short()); err != nil {
+		return err
+	}
+	if len(hashColor) > 0 {
+		if err := terminal.ResetTextStyle(w); err != nil {
+			return err
+		}
+	}
+	if _, err := io.WriteString(w, ":"); err != nil {
+		return err
+	}
+	if _, err := w.Write(fileColor); err != nil {
+		return err
+	}
+	if _, err := io.WriteString(w, m.file.String()); err != nil {
+		return err
+	}
+	if len(fileColor) > 0 {
+		if err := terminal.ResetTextStyle(w); err != nil {
+			return err
+		}
+	}
+	_, err := io.WriteString(w, eol)
+	return err
+}
+
+// grepHistory finds the commits reachable from HEAD that add or remove
+// lines matching pattern, newest first. If fixed is true, it instead finds
+// the commits that change the number of occurrences of pattern. Only the
+// files that match are reported for each commit.
+func grepHistory(ctx context.Context, g *git.Git, pattern string, pathspecs []string, ignoreCase, fixed bool) ([]historyMatch, error) {
+	logArgs := []string{"log", "-z", "--format=commit %H", "--name-only", "--no-renames"}
+	if fixed {
+		logArgs = append(logArgs, "-S"+pattern)
+	} else {
+		logArgs = append(logArgs, "--extended-regexp", "-G"+pattern)
+	}
+	if ignoreCase {
+		logArgs = append(logArgs, "--regexp-ignore-case")
+	}
+	logArgs = append(logArgs, "--")
+	logArgs = append(logArgs, pathspecs...)
+	out, err := g.Output(ctx, logArgs...)
+	if err != nil {
+		return nil, fmt.Errorf("search history: %w", err)
+	}
+	// Output is of the form "commit HASH\x00\nFILE\x00FILE\x00commit HASH...".
+	var matches []historyMatch
+	var curr git.Hash
+	for _, tok := range strings.Split(out, "\x00") {
+		tok = strings.TrimPrefix(tok, "\n")
+		if tok == "" {
+			continue
+		}
+		if strings.HasPrefix(tok, "commit ") {
+			curr, err = git.ParseHash(strings.TrimPrefix(tok, "commit "))
+			if err != nil {
+				return nil, fmt.Errorf("search history: %w", err)
+			}
+			continue
+		}
+		matches = append(matches, historyMatch{commit: curr, file: git.TopPath(tok)})
+	}
+	return matches, nil
+}
+
+// countingWriter counts the number of bytes written to an underlying writer.
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (cw *countingWriter) Write(p []byte) (int, error) {
+	n, err := cw.w.Write(p)
+	cw.n += int64(n)
+	return n, err
+}
@@ -0,0 +1,79 @@
+// Copyright 2021 The gg Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"testing"
+
+	"gg-scm.io/tool/internal/filesystem"
+)
+
+func TestGrep(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	env, err := newTestEnv(ctx, t)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := env.initEmptyRepo(ctx, "repo"); err != nil {
+		t.Fatal(err)
+	}
+	if err := env.root.Apply(filesystem.Write("repo/foo.txt", "apple\nbanana\n")); err != nil {
+		t.Fatal(err)
+	}
+	if err := env.addFiles(ctx, "repo/foo.txt"); err != nil {
+		t.Fatal(err)
+	}
+	first, err := env.newCommit(ctx, "repo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := env.root.Apply(filesystem.Write("repo/foo.txt", "apple\ncherry\n")); err != nil {
+		t.Fatal(err)
+	}
+	second, err := env.newCommit(ctx, "repo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	repoPath := env.root.FromSlash("repo")
+
+	tests := []struct {
+		args []string
+		want string
+	}{
+		{args: []string{"grep", "an+a"}, want: ""},
+		{args: []string{"grep", "cherry"}, want: "foo.txt:2:cherry\n"},
+		{args: []string{"grep", "-r", first.String(), "banana"}, want: first.String() + ":foo.txt:2:banana\n"},
+		{args: []string{"grep", "-0", "-l", "apple"}, want: "foo.txt\x00"},
+		{args: []string{"grep", "--all-history", "banana"}, want: second.Short() + ":foo.txt\n" + first.Short() + ":foo.txt\n"},
+	}
+	for _, test := range tests {
+		out, err := env.gg(ctx, repoPath, test.args...)
+		if test.want == "" {
+			if err == nil {
+				t.Errorf("gg %q = %q, <nil>; want error", test.args, out)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("gg %q: %v", test.args, err)
+			continue
+		}
+		if got := string(out); got != test.want {
+			t.Errorf("gg %q = %q; want %q", test.args, got, test.want)
+		}
+	}
+}
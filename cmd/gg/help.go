@@ -0,0 +1,173 @@
+// Copyright 2021 The gg Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"gg-scm.io/tool/internal/flag"
+)
+
+const helpSynopsis = "show help for a command"
+
+func help(ctx context.Context, cc *cmdContext, globalFlags *flag.FlagSet, args []string) error {
+	f := flag.NewFlagSet(true, "gg help [COMMAND]\n"+
+		"gg help --json", helpSynopsis+`
+
+	With `+"`--json`"+`, help prints a JSON object describing the global
+	options and every command: its name, aliases, synopsis, usage,
+	description, options, and subcommands. Tools like completion
+	generators can use it to stay in sync with gg.`)
+	jsonFlag := f.Bool("json", false, "print all commands as JSON")
+	if err := f.Parse(args); flag.IsHelp(err) {
+		f.Help(cc.stdout)
+		return nil
+	} else if err != nil {
+		return usagef("%v", err)
+	}
+	if *jsonFlag {
+		if f.NArg() > 0 {
+			return usagef("can't pass a command with --json")
+		}
+		return writeCommandCatalog(ctx, cc, globalFlags)
+	}
+	if f.NArg() == 0 {
+		globalFlags.Help(cc.stdout)
+		return nil
+	}
+	if f.NArg() > 1 {
+		return usagef("help [command]")
+	}
+	return dispatch(ctx, cc, globalFlags, f.Arg(0), []string{"--help"})
+}
+
+// catalogCommand is the JSON representation of a command in the output
+// of help --json.
+type catalogCommand struct {
+	Name        string            `json:"name"`
+	Aliases     []string          `json:"aliases,omitempty"`
+	Synopsis    string            `json:"synopsis"`
+	Usage       []string          `json:"usage"`
+	Description string            `json:"description,omitempty"`
+	Flags       []catalogFlag     `json:"flags"`
+	Subcommands []*catalogCommand `json:"subcommands,omitempty"`
+}
+
+// catalogFlag is the JSON representation of a command-line flag in the
+// output of help --json.
+type catalogFlag struct {
+	Name     string   `json:"name"`
+	Aliases  []string `json:"aliases,omitempty"`
+	Arg      string   `json:"arg,omitempty"`
+	Usage    string   `json:"usage"`
+	Default  string   `json:"default,omitempty"`
+	Repeated bool     `json:"repeated,omitempty"`
+}
+
+// writeCommandCatalog writes the JSON description of every command to
+// cc.stdout.
+func writeCommandCatalog(ctx context.Context, cc *cmdContext, globalFlags *flag.FlagSet) error {
+	var catalog struct {
+		Flags    []catalogFlag     `json:"flags"`
+		Commands []*catalogCommand `json:"commands"`
+	}
+	catalog.Flags = catalogFlags(globalFlags.Describe())
+	for _, c := range commands {
+		if c.hidden {
+			continue
+		}
+		cmd, err := describeCommand(ctx, cc, globalFlags, c.name, nil)
+		if err != nil {
+			return err
+		}
+		cmd.Aliases = append([]string(nil), c.aliases...)
+		sort.Strings(cmd.Aliases)
+		for _, sub := range c.subcommands {
+			subcmd, err := describeCommand(ctx, cc, globalFlags, c.name, []string{sub})
+			if err != nil {
+				return err
+			}
+			subcmd.Name = sub
+			cmd.Subcommands = append(cmd.Subcommands, subcmd)
+		}
+		catalog.Commands = append(catalog.Commands, cmd)
+	}
+	out, err := json.MarshalIndent(catalog, "", "\t")
+	if err != nil {
+		return err
+	}
+	out = append(out, '\n')
+	_, err = cc.stdout.Write(out)
+	return err
+}
+
+// describeCommand runs the named command with the given arguments
+// followed by --help and returns its help.
+func describeCommand(ctx context.Context, cc *cmdContext, globalFlags *flag.FlagSet, name string, args []string) (*catalogCommand, error) {
+	rec := new(helpRecorder)
+	helpCC := *cc
+	helpCC.stdout = rec
+	if err := dispatch(ctx, &helpCC, globalFlags, name, append(args, "--help")); err != nil {
+		return nil, fmt.Errorf("describe %s: %w", strings.Join(append([]string{name}, args...), " "), err)
+	}
+	if rec.desc == nil {
+		return nil, fmt.Errorf("describe %s: no help recorded", strings.Join(append([]string{name}, args...), " "))
+	}
+	cmd := &catalogCommand{
+		Name:  name,
+		Usage: strings.Split(rec.desc.Usage, "\n"),
+		Flags: catalogFlags(rec.desc),
+	}
+	// Command descriptions start with the synopsis line.
+	cmd.Synopsis = rec.desc.Description
+	if i := strings.IndexByte(cmd.Synopsis, '\n'); i != -1 {
+		cmd.Synopsis = cmd.Synopsis[:i]
+		cmd.Description = strings.TrimSpace(rec.desc.Description[i+1:])
+	}
+	return cmd, nil
+}
+
+func catalogFlags(desc *flag.Description) []catalogFlag {
+	flags := make([]catalogFlag, 0, len(desc.Flags))
+	for _, fd := range desc.Flags {
+		flags = append(flags, catalogFlag{
+			Name:     fd.Name,
+			Aliases:  fd.Aliases,
+			Arg:      fd.Arg,
+			Usage:    fd.Usage,
+			Default:  fd.Default,
+			Repeated: fd.Repeated,
+		})
+	}
+	return flags
+}
+
+// helpRecorder is a flag.HelpRecorder that saves the last help it receives.
+// Any other output is discarded.
+type helpRecorder struct {
+	desc *flag.Description
+}
+
+func (rec *helpRecorder) Write(p []byte) (int, error) {
+	return len(p), nil
+}
+
+func (rec *helpRecorder) RecordHelp(desc *flag.Description) {
+	rec.desc = desc
+}
@@ -0,0 +1,131 @@
+// Copyright 2021 The gg Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+)
+
+func TestHelp_JSON(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	env, err := newTestEnv(ctx, t)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out, err := env.gg(ctx, env.root.String(), "help", "--json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var catalog struct {
+		Flags    []catalogFlag
+		Commands []*catalogCommand
+	}
+	if err := json.Unmarshal(out, &catalog); err != nil {
+		t.Fatalf("%v; output:\n%s", err, out)
+	}
+	visible := 0
+	for _, cmd := range commands {
+		if !cmd.hidden {
+			visible++
+		}
+	}
+	if len(catalog.Commands) != visible {
+		t.Errorf("len(commands) = %d; want %d", len(catalog.Commands), visible)
+	}
+	if !hasCatalogFlag(catalog.Flags, "git") {
+		t.Error("global flags do not include -git")
+	}
+	commands := make(map[string]*catalogCommand)
+	for _, cmd := range catalog.Commands {
+		commands[cmd.Name] = cmd
+	}
+
+	commit := commands["commit"]
+	if commit == nil {
+		t.Fatal("commit not in catalog")
+	}
+	if commit.Synopsis != commitSynopsis {
+		t.Errorf("commit synopsis = %q; want %q", commit.Synopsis, commitSynopsis)
+	}
+	if len(commit.Aliases) != 1 || commit.Aliases[0] != "ci" {
+		t.Errorf("commit aliases = %q; want [\"ci\"]", commit.Aliases)
+	}
+	if !hasCatalogFlag(commit.Flags, "amend") {
+		t.Error("commit flags do not include -amend")
+	}
+
+	requestPull := commands["requestpull"]
+	if requestPull == nil {
+		t.Fatal("requestpull not in catalog")
+	}
+	if !hasCatalogFlag(requestPull.Flags, "status") {
+		t.Error("requestpull flags do not include -status")
+	}
+
+	stack := commands["stack"]
+	if stack == nil {
+		t.Fatal("stack not in catalog")
+	}
+	foundSubmit := false
+	for _, sub := range stack.Subcommands {
+		if sub.Name == "submit" {
+			foundSubmit = true
+			if sub.Synopsis != stackSubmitSynopsis {
+				t.Errorf("stack submit synopsis = %q; want %q", sub.Synopsis, stackSubmitSynopsis)
+			}
+		}
+	}
+	if !foundSubmit {
+		t.Error("stack subcommands do not include submit")
+	}
+}
+
+func TestCommands(t *testing.T) {
+	names := make(map[string]string)
+	for i, cmd := range commands {
+		if !cmd.hidden && i > 0 && !commands[i-1].hidden && commands[i-1].name >= cmd.name {
+			t.Errorf("command %q is listed after %q; want sorted by name", cmd.name, commands[i-1].name)
+		}
+		if (cmd.run == nil) != (cmd.name == "help") {
+			t.Errorf("command %q: run == nil is %t", cmd.name, cmd.run == nil)
+		}
+		for _, name := range append([]string{cmd.name}, cmd.aliases...) {
+			if other, dup := names[name]; dup {
+				t.Errorf("%q is used by both %q and %q", name, other, cmd.name)
+			}
+			names[name] = cmd.name
+		}
+	}
+	for _, name := range []string{"commit", "ci"} {
+		if cmd := lookupCommand(name); cmd == nil || cmd.name != "commit" {
+			t.Errorf("lookupCommand(%q) = %v; want commit", name, cmd)
+		}
+	}
+	if cmd := lookupCommand("bogus"); cmd != nil {
+		t.Errorf("lookupCommand(\"bogus\") = %v; want <nil>", cmd)
+	}
+}
+
+func hasCatalogFlag(flags []catalogFlag, name string) bool {
+	for _, f := range flags {
+		if f.Name == name {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,184 @@
+// Copyright 2021 The gg Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strings"
+
+	"gg-scm.io/pkg/git"
+	"gg-scm.io/tool/internal/escape"
+)
+
+// histeditWithPreview runs `git rebase` with the given arguments, appending
+// a graph of the commits in base..HEAD to the plan before the sequence
+// editor opens it.
+func histeditWithPreview(ctx context.Context, cc *cmdContext, base git.Hash, rebaseArgs []string) error {
+	cfg, err := cc.git.ReadConfig(ctx)
+	if err != nil {
+		return err
+	}
+	commentChar, err := cfg.CommentChar()
+	if err != nil || len(commentChar) != 1 {
+		// Git picks the comment character for the plan file itself.
+		// Don't risk writing lines it won't ignore.
+		return cc.interactiveGit(ctx, rebaseArgs...)
+	}
+	editor, err := sequenceEditor(ctx, cc, cfg)
+	if err != nil {
+		return err
+	}
+	preview, err := histeditPreview(ctx, cc, base, commentChar)
+	if err != nil {
+		return err
+	}
+	previewFile, err := ioutil.TempFile(cc.editor.tempRoot, "gg_histedit_preview")
+	if err != nil {
+		return fmt.Errorf("histedit preview: %w", err)
+	}
+	previewPath := previewFile.Name()
+	defer os.Remove(previewPath)
+	_, err = previewFile.WriteString(preview)
+	closeErr := previewFile.Close()
+	if err != nil {
+		return fmt.Errorf("histedit preview: %w", err)
+	}
+	if closeErr != nil {
+		return fmt.Errorf("histedit preview: %w", closeErr)
+	}
+
+	// Git runs the sequence editor with the shell and appends the plan
+	// file's path as an argument.
+	editorCmd := fmt.Sprintf(`gg_histedit() { cat %s >> "$1" && %s "$1"; }; gg_histedit`,
+		escape.Bash(previewPath), editor)
+	env := append(cc.env[:len(cc.env):len(cc.env)], "GIT_SEQUENCE_EDITOR="+editorCmd)
+	err = cc.git.Runner().RunGit(ctx, &git.Invocation{
+		Dir:    cc.dir,
+		Args:   rebaseArgs,
+		Env:    env,
+		Stdin:  cc.stdin,
+		Stdout: cc.stdout,
+		Stderr: cc.stderr,
+	})
+	if err != nil {
+		return fmt.Errorf("git %s: %w", rebaseArgs[0], err)
+	}
+	return nil
+}
+
+// sequenceEditor returns the shell command Git would use to edit
+// the rebase plan.
+func sequenceEditor(ctx context.Context, cc *cmdContext, cfg *git.Config) (string, error) {
+	if e := getenv(cc.env, "GIT_SEQUENCE_EDITOR"); e != "" {
+		return e, nil
+	}
+	if e := cfg.Value("sequence.editor"); e != "" {
+		return e, nil
+	}
+	out, err := cc.git.Output(ctx, "var", "GIT_EDITOR")
+	if err != nil {
+		return "", fmt.Errorf("find sequence editor: %w", err)
+	}
+	return strings.TrimSuffix(out, "\n"), nil
+}
+
+// histeditPreview returns a comment section for the histedit plan that
+// shows a graph of the commits in base..HEAD along with the refs that
+// point to them. Each line starts with commentChar.
+func histeditPreview(ctx context.Context, cc *cmdContext, base git.Hash, commentChar string) (string, error) {
+	entries, err := listLogEntries(ctx, cc.git, &logFlags{rev: []string{base.String() + "..HEAD"}}, "")
+	if err != nil {
+		return "", fmt.Errorf("histedit preview: %w", err)
+	}
+	if len(entries) == 0 {
+		return "", nil
+	}
+	commits, err := readCommits(ctx, cc, entries)
+	if err != nil {
+		return "", fmt.Errorf("histedit preview: %w", err)
+	}
+	refs, err := cc.git.ListRefs(ctx)
+	if err != nil {
+		return "", fmt.Errorf("histedit preview: %w", err)
+	}
+	labels := make(map[git.Hash][]string)
+	for ref, hash := range refs {
+		switch {
+		case ref.IsBranch():
+			labels[hash] = append(labels[hash], ref.Branch())
+		case strings.HasPrefix(ref.String(), "refs/remotes/") && !strings.HasSuffix(ref.String(), "/HEAD"):
+			labels[hash] = append(labels[hash], strings.TrimPrefix(ref.String(), "refs/remotes/"))
+		case ref.IsTag():
+			labels[hash] = append(labels[hash], "tag: "+ref.Tag())
+		}
+	}
+	var head git.Hash
+	var headBranch string
+	if rev, err := cc.git.Head(ctx); err == nil {
+		head = rev.Commit
+		headBranch = rev.Ref.Branch()
+	}
+
+	shown := make(map[git.Hash]bool, len(entries))
+	for _, ent := range entries {
+		shown[ent.hash] = true
+	}
+	graph := new(logGraph)
+	graphBuf := new(bytes.Buffer)
+	var otherBranches []string
+	for _, ent := range entries {
+		line := ent.hash.Short() + " " + commits[ent.hash].Summary()
+		if names := labels[ent.hash]; len(names) > 0 {
+			sort.Strings(names)
+			line += " (" + strings.Join(names, ", ") + ")"
+		}
+		for ref, hash := range refs {
+			if hash == ent.hash && ref.IsBranch() && ref.Branch() != headBranch {
+				otherBranches = append(otherBranches, ref.Branch())
+			}
+		}
+		node := 'o'
+		if ent.hash == head {
+			node = '@'
+		}
+		var parents []git.Hash
+		for _, p := range ent.parents {
+			if shown[p] {
+				parents = append(parents, p)
+			}
+		}
+		graph.write(graphBuf, ent.hash, parents, node, line)
+	}
+
+	sb := new(strings.Builder)
+	fmt.Fprintf(sb, "%s\n%s Commits being edited (newest first):\n%s\n", commentChar, commentChar, commentChar)
+	for _, line := range strings.SplitAfter(strings.TrimSuffix(graphBuf.String(), "\n"), "\n") {
+		sb.WriteString(commentChar + " " + line)
+	}
+	sb.WriteString("\n")
+	if len(otherBranches) > 0 {
+		sort.Strings(otherBranches)
+		fmt.Fprintf(sb, "%s\n%s These branches will keep pointing to the old commits:\n", commentChar, commentChar)
+		for _, b := range otherBranches {
+			fmt.Fprintf(sb, "%s   %s\n", commentChar, b)
+		}
+	}
+	return sb.String(), nil
+}
@@ -49,7 +49,8 @@ aliases: id
 		return usagef("identify takes no arguments")
 	}
 
-	dir, err := cc.git.GitDir(ctx)
+	// The database is shared by all of the repository's working trees.
+	dir, err := cc.git.CommonDir(ctx)
 	if err != nil {
 		return err
 	}
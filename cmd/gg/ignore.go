@@ -0,0 +1,257 @@
+// Copyright 2021 The gg Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gg-scm.io/pkg/git"
+	"gg-scm.io/tool/internal/flag"
+)
+
+const ignoreSynopsis = "ignore files or explain why files are ignored"
+
+func ignore(ctx context.Context, cc *cmdContext, args []string) error {
+	f := flag.NewFlagSet(true, "gg ignore [--global] PATTERN [...]\n"+
+		"gg ignore --list\n"+
+		"gg ignore --check FILE [...]", ignoreSynopsis+`
+
+	ignore adds the given patterns to the `+"`.gitignore`"+` file at the
+	top of the working copy, skipping any that are already there. With
+	`+"`--global`"+`, the patterns are added to the user's global ignore
+	file (`+"`core.excludesFile`"+`) instead, which applies to every
+	repository. Patterns use `+"`.gitignore`"+` syntax.
+
+	`+"`--list`"+` prints every ignore rule in effect for the working copy
+	along with the file and line it comes from. Rules listed later take
+	precedence over earlier ones.
+
+	`+"`--check`"+` explains whether each of the given files is ignored,
+	and if so, which rule ignores it. Tracked files are never ignored.`)
+	global := f.Bool("global", false, "add patterns to the global ignore file")
+	list := f.Bool("list", false, "list the ignore rules in effect")
+	check := f.Bool("check", false, "explain why files are ignored")
+	if err := f.Parse(args); flag.IsHelp(err) {
+		f.Help(cc.stdout)
+		return nil
+	} else if err != nil {
+		return usagef("%v", err)
+	}
+	switch {
+	case *list && *check:
+		return usagef("can't pass both --list and --check")
+	case *list:
+		if f.NArg() > 0 || *global {
+			return usagef("--list takes no arguments")
+		}
+		return listIgnoreRules(ctx, cc)
+	case *check:
+		if *global {
+			return usagef("can't pass both --global and --check")
+		}
+		if f.NArg() == 0 {
+			return usagef("must pass one or more files to check")
+		}
+		return checkIgnore(ctx, cc, f.Args())
+	}
+	if f.NArg() == 0 {
+		return usagef("must pass one or more patterns to ignore")
+	}
+	var path string
+	if *global {
+		var err error
+		path, err = globalIgnoreFile(ctx, cc)
+		if err != nil {
+			return err
+		}
+	} else {
+		top, err := cc.git.WorkTree(ctx)
+		if err != nil {
+			return err
+		}
+		path = filepath.Join(top, ".gitignore")
+	}
+	return appendIgnorePatterns(path, f.Args())
+}
+
+// globalIgnoreFile returns the path of the user's global ignore file:
+// core.excludesFile if set, or Git's default location otherwise.
+func globalIgnoreFile(ctx context.Context, cc *cmdContext) (string, error) {
+	if out, err := cc.git.Output(ctx, "config", "--path", "core.excludesFile"); err == nil {
+		if path := strings.TrimSuffix(out, "\n"); path != "" {
+			return path, nil
+		}
+	}
+	if cc.xdgDirs.configHome == "" {
+		return "", errors.New("can't find global ignore file: core.excludesFile, XDG_CONFIG_HOME, and HOME not set")
+	}
+	return filepath.Join(cc.xdgDirs.configHome, "git", "ignore"), nil
+}
+
+// appendIgnorePatterns adds the patterns that the ignore file at path
+// doesn't already have to the end of the file, creating it if needed.
+func appendIgnorePatterns(path string, patterns []string) error {
+	existing, err := ioutil.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	have := make(map[string]bool)
+	for _, line := range strings.Split(string(existing), "\n") {
+		have[strings.TrimSpace(line)] = true
+	}
+	buf := new(bytes.Buffer)
+	if len(existing) > 0 && !bytes.HasSuffix(existing, []byte("\n")) {
+		buf.WriteByte('\n')
+	}
+	added := 0
+	for _, pattern := range patterns {
+		if pattern == "" || strings.ContainsAny(pattern, "\n") {
+			return fmt.Errorf("invalid pattern %q", pattern)
+		}
+		if have[pattern] {
+			continue
+		}
+		have[pattern] = true
+		buf.WriteString(pattern)
+		buf.WriteByte('\n')
+		added++
+	}
+	if added == 0 {
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o777); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o666)
+	if err != nil {
+		return err
+	}
+	_, writeErr := f.Write(buf.Bytes())
+	closeErr := f.Close()
+	if writeErr != nil {
+		return writeErr
+	}
+	return closeErr
+}
+
+// listIgnoreRules implements `gg ignore --list`.
+func listIgnoreRules(ctx context.Context, cc *cmdContext) error {
+	top, err := cc.git.WorkTree(ctx)
+	if err != nil {
+		return err
+	}
+	// Sources are listed from lowest to highest precedence.
+	var sources []string
+	if global, err := globalIgnoreFile(ctx, cc); err == nil {
+		sources = append(sources, global)
+	}
+	commonDir, err := cc.git.CommonDir(ctx)
+	if err != nil {
+		return err
+	}
+	sources = append(sources, filepath.Join(commonDir, "info", "exclude"))
+	out, err := cc.git.WithDir(top).Output(ctx, "ls-files", "-z", "--cached", "--others", "--exclude-standard", "--", ":(glob)**/.gitignore")
+	if err != nil {
+		return err
+	}
+	for _, name := range strings.Split(out, "\x00") {
+		if name != "" {
+			sources = append(sources, filepath.Join(top, filepath.FromSlash(name)))
+		}
+	}
+	for _, src := range sources {
+		if err := printIgnoreFile(cc, top, src); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// printIgnoreFile prints the rules in an ignore file prefixed by their
+// location. Files inside the working copy are shown relative to its top.
+func printIgnoreFile(cc *cmdContext, top, path string) error {
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	name := path
+	if rel, err := filepath.Rel(top, path); err == nil && !strings.HasPrefix(rel, "..") {
+		name = filepath.ToSlash(rel)
+	}
+	s := bufio.NewScanner(f)
+	for lineno := 1; s.Scan(); lineno++ {
+		line := strings.TrimRight(s.Text(), " \t\r")
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if _, err := fmt.Fprintf(cc.stdout, "%s:%d: %s\n", name, lineno, line); err != nil {
+			return err
+		}
+	}
+	return s.Err()
+}
+
+// checkIgnore implements `gg ignore --check`.
+func checkIgnore(ctx context.Context, cc *cmdContext, files []string) error {
+	args := []string{"check-ignore", "--verbose", "--non-matching", "-z", "--"}
+	args = append(args, files...)
+	stdout := new(bytes.Buffer)
+	stderr := new(bytes.Buffer)
+	err := cc.git.Runner().RunGit(ctx, &git.Invocation{
+		Args:   args,
+		Dir:    cc.dir,
+		Stdout: stdout,
+		Stderr: stderr,
+	})
+	// check-ignore exits with 1 if none of the files are ignored, so only
+	// treat it as a failure if it complained.
+	if err != nil && stderr.Len() > 0 {
+		return fmt.Errorf("git check-ignore: %w: %s", err, bytes.TrimSpace(stderr.Bytes()))
+	}
+	// Each record is "SOURCE NUL LINENUM NUL PATTERN NUL PATHNAME NUL".
+	// SOURCE is empty if the file is not ignored.
+	fields := strings.Split(strings.TrimSuffix(stdout.String(), "\x00"), "\x00")
+	if len(fields)%4 != 0 {
+		return fmt.Errorf("git check-ignore: unexpected output %q", stdout)
+	}
+	for i := 0; i+3 < len(fields); i += 4 {
+		source, lineno, pattern, name := fields[i], fields[i+1], fields[i+2], fields[i+3]
+		var err error
+		switch {
+		case source == "":
+			_, err = fmt.Fprintf(cc.stdout, "%s is not ignored\n", name)
+		case strings.HasPrefix(pattern, "!"):
+			_, err = fmt.Fprintf(cc.stdout, "%s is not ignored because of %s (%s:%s)\n", name, pattern, source, lineno)
+		default:
+			_, err = fmt.Fprintf(cc.stdout, "%s is ignored by %s (%s:%s)\n", name, pattern, source, lineno)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
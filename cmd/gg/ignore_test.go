@@ -0,0 +1,73 @@
+// Copyright 2021 The gg Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"gg-scm.io/tool/internal/filesystem"
+)
+
+func TestIgnore(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	env, err := newTestEnv(ctx, t)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := env.initEmptyRepo(ctx, "."); err != nil {
+		t.Fatal(err)
+	}
+	if err := env.root.Apply(filesystem.Write(".gitignore", "/build")); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := env.gg(ctx, env.root.String(), "ignore", "*.log", "/build"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := env.gg(ctx, env.root.String(), "ignore", "*.log", "!keep.log"); err != nil {
+		t.Fatal(err)
+	}
+	const wantIgnore = "/build\n*.log\n!keep.log\n"
+	if got, err := env.root.ReadFile(".gitignore"); err != nil {
+		t.Fatal(err)
+	} else if got != wantIgnore {
+		t.Errorf(".gitignore = %q; want %q", got, wantIgnore)
+	}
+
+	out, err := env.gg(ctx, env.root.String(), "ignore", "--list")
+	if err != nil {
+		t.Fatal(err)
+	}
+	const wantList = ".gitignore:1: /build\n" +
+		".gitignore:2: *.log\n" +
+		".gitignore:3: !keep.log\n"
+	if !strings.HasSuffix(string(out), wantList) {
+		t.Errorf("ignore --list = %q; want to end with %q", out, wantList)
+	}
+
+	out, err = env.gg(ctx, env.root.String(), "ignore", "--check", "debug.log", "keep.log", "main.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	const wantCheck = "debug.log is ignored by *.log (.gitignore:2)\n" +
+		"keep.log is not ignored because of !keep.log (.gitignore:3)\n" +
+		"main.go is not ignored\n"
+	if got := string(out); got != wantCheck {
+		t.Errorf("ignore --check output:\n%s\nwant:\n%s", got, wantCheck)
+	}
+}
@@ -0,0 +1,172 @@
+// Copyright 2021 The gg Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gg-scm.io/pkg/git"
+	"gg-scm.io/tool/internal/flag"
+)
+
+const importSynopsis = "apply patches as commits"
+
+func import_(ctx context.Context, cc *cmdContext, args []string) error {
+	f := flag.NewFlagSet(true, "gg import [--3way=false] [FILE [...]]\n"+
+		"gg import --continue | --skip | --abort\n"+
+		"gg import --review [-b BRANCH] DIR", importSynopsis+`
+
+	Apply patches in the mbox format written by `+"`gg export`"+` or
+	`+"`git format-patch`"+` as new commits on top of the working copy's
+	parent, keeping each patch's author and message. If no files are
+	given, the patches are read from stdin. When a patch does not apply
+	cleanly, import falls back to a three-way merge using the blobs named
+	in the patch, if they are present in the repository.
+
+	If a patch cannot be applied, import stops. Fix the conflicts and run
+	`+"`gg import --continue`"+`, run `+"`gg import --skip`"+` to drop
+	the patch, or run `+"`gg import --abort`"+` to return to where the
+	import started.
+
+	With `+"`--review`"+`, create a branch from a directory written by `+"`gg export --review`"+`
+	and apply its patches in order on top of the commit they were exported
+	from. The base commit must already be present in the repository, so
+	fetch it first if needed. The branch is named after the exported
+	branch unless `+"`-b`"+` is given, and must not already exist.`)
+	branch := f.String("b", "", "`name` of the branch to create")
+	f.Alias("b", "branch")
+	review := f.Bool("review", false, "apply a review bundle")
+	threeWay := f.Bool("3way", true, "fall back to a three-way merge if a patch does not apply cleanly")
+	continue_ := f.Bool("continue", false, "continue an interrupted import")
+	skip := f.Bool("skip", false, "skip the current patch of an interrupted import")
+	abort := f.Bool("abort", false, "abort an interrupted import")
+	if err := f.Parse(args); flag.IsHelp(err) {
+		f.Help(cc.stdout)
+		return nil
+	} else if err != nil {
+		return usagef("%v", err)
+	}
+	resumeFlags := 0
+	for _, b := range []bool{*continue_, *skip, *abort} {
+		if b {
+			resumeFlags++
+		}
+	}
+	switch {
+	case resumeFlags > 1:
+		return usagef("can only pass one of --continue, --skip, or --abort")
+	case resumeFlags == 1 && (*review || *branch != "" || f.NArg() > 0 || !*threeWay):
+		return usagef("can't specify other options with --continue, --skip, or --abort")
+	case *continue_:
+		return continueImport(ctx, cc)
+	case *skip:
+		return cc.interactiveGit(ctx, "am", "--skip")
+	case *abort:
+		return cc.interactiveGit(ctx, "am", "--abort")
+	case !*review:
+		if *branch != "" {
+			return usagef("-b can only be used with --review")
+		}
+		amArgs := []string{"am"}
+		if *threeWay {
+			amArgs = append(amArgs, "--3way")
+		}
+		amArgs = append(amArgs, "--")
+		for _, arg := range f.Args() {
+			amArgs = append(amArgs, cc.abs(arg))
+		}
+		return cc.interactiveGit(ctx, amArgs...)
+	}
+	if f.NArg() != 1 {
+		return usagef("must pass exactly one bundle directory")
+	}
+	dir := cc.abs(f.Arg(0))
+	manifestJSON, err := ioutil.ReadFile(filepath.Join(dir, reviewManifestFilename))
+	if err != nil {
+		return fmt.Errorf("import: %w", err)
+	}
+	manifest := new(reviewManifest)
+	if err := json.Unmarshal(manifestJSON, manifest); err != nil {
+		return fmt.Errorf("import: %s: %w", reviewManifestFilename, err)
+	}
+	if manifest.Version != 1 {
+		return fmt.Errorf("import: unsupported bundle version %d", manifest.Version)
+	}
+	if len(manifest.Commits) == 0 {
+		return fmt.Errorf("import: bundle has no commits")
+	}
+	if *branch == "" {
+		*branch = manifest.Branch
+		if *branch == "" {
+			return usagef("bundle does not name a branch; please specify one with -b")
+		}
+	}
+	base, err := git.ParseHash(manifest.Base)
+	if err != nil {
+		return fmt.Errorf("import: %s: base: %w", reviewManifestFilename, err)
+	}
+	if _, err := cc.git.ParseRev(ctx, base.String()+"^{commit}"); err != nil {
+		return fmt.Errorf("import: base commit %v not found (fetch it first)", base.Short())
+	}
+	patches := make([]string, 0, len(manifest.Commits))
+	for _, c := range manifest.Commits {
+		p := filepath.FromSlash(c.Patch)
+		if c.Patch == "" || filepath.IsAbs(p) || strings.HasPrefix(filepath.Clean(p), "..") {
+			return fmt.Errorf("import: %s: invalid patch path %q", reviewManifestFilename, c.Patch)
+		}
+		patches = append(patches, filepath.Join(dir, p))
+	}
+
+	err = cc.git.NewBranch(ctx, *branch, git.BranchOptions{
+		StartPoint: base.String(),
+		Checkout:   true,
+	})
+	if err != nil {
+		return err
+	}
+	if err := cc.interactiveGit(ctx, append([]string{"am", "--3way", "--"}, patches...)...); err != nil {
+		return err
+	}
+	msgPath := filepath.Join(dir, reviewPullRequestFilename)
+	if _, err := os.Stat(msgPath); err == nil {
+		fmt.Fprintf(cc.stdout, "Pull request message is in %s\n", msgPath)
+	}
+	return nil
+}
+
+// continueImport adds any modified files to the index and then runs
+// `git am --continue`.
+func continueImport(ctx context.Context, cc *cmdContext) error {
+	status, err := cc.git.Status(ctx, git.StatusOptions{})
+	if err != nil {
+		return err
+	}
+	hasChanges, err := verifyNoMissingOrUnmerged(status)
+	if err != nil {
+		return err
+	}
+	if hasChanges {
+		if err := cc.git.StageTracked(ctx); err != nil {
+			return err
+		}
+	}
+	return cc.interactiveGit(ctx, "am", "--continue")
+}
@@ -0,0 +1,216 @@
+// Copyright 2021 The gg Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"gg-scm.io/pkg/git"
+	"gg-scm.io/tool/internal/flag"
+	"gg-scm.io/tool/internal/template"
+)
+
+const (
+	incomingSynopsis = "show new commits in the upstream branch"
+	outgoingSynopsis = "show commits that have not been pushed"
+)
+
+func incoming(ctx context.Context, cc *cmdContext, args []string) error {
+	f := flag.NewFlagSet(true, "gg incoming [--no-fetch] [--stat | -T TEMPLATE] [SOURCE]", incomingSynopsis+`
+
+	incoming fetches from the source and lists the commits on the current
+	branch's upstream branch that are not in the working copy's commit.
+	If no source is given, the branch's upstream remote is used, falling
+	back to `+"`origin`"+`. With `+"`--no-fetch`"+`, the remote-tracking
+	branches are used as they are.`)
+	noFetch := f.Bool("no-fetch", false, "use remote-tracking branches without fetching")
+	stat := f.Bool("stat", false, "include diffstat-style summary of each commit")
+	templateFlag := f.String("T", "", "display each commit using the given `template`")
+	f.Alias("T", "template")
+	if err := f.Parse(args); flag.IsHelp(err) {
+		f.Help(cc.stdout)
+		return nil
+	} else if err != nil {
+		return usagef("%v", err)
+	}
+	if f.NArg() > 1 {
+		return usagef("can't pass multiple sources")
+	}
+	branch := currentBranch(ctx, cc)
+	if branch == "" {
+		return errors.New("no branch checked out")
+	}
+	cfg, err := cc.git.ReadConfig(ctx)
+	if err != nil {
+		return err
+	}
+	remoteName := f.Arg(0)
+	upstreamRef := git.BranchRef(branch)
+	if cfgRemote := cfg.Value("branch." + branch + ".remote"); remoteName == "" || remoteName == cfgRemote {
+		remoteName = cfgRemote
+		if merge := cfg.Value("branch." + branch + ".merge"); merge != "" {
+			upstreamRef = git.Ref(merge)
+		}
+	}
+	if remoteName == "" {
+		remoteName = "origin"
+	}
+	remote := cfg.ListRemotes()[remoteName]
+	if remote == nil {
+		return fmt.Errorf("no remote named %q found", remoteName)
+	}
+	if !*noFetch {
+		if err := cc.interactiveGit(ctx, "fetch", "--", remoteName); err != nil {
+			return err
+		}
+	}
+	target := remote.MapFetch(upstreamRef)
+	if target == "" {
+		return fmt.Errorf("%s is not fetched from %s", upstreamRef, remoteName)
+	}
+	if _, err := cc.git.ParseRev(ctx, target.String()); err != nil {
+		return fmt.Errorf("%s not found on %s", upstreamRef.Branch(), remoteName)
+	}
+	revs := []string{target.String()}
+	if _, err := cc.git.Head(ctx); err == nil {
+		revs = append(revs, "^"+git.Head.String())
+	}
+	return logChanges(ctx, cc, revs, *stat, *templateFlag)
+}
+
+func outgoing(ctx context.Context, cc *cmdContext, args []string) error {
+	f := flag.NewFlagSet(true, "gg outgoing [--stat | -T TEMPLATE] [DST]", outgoingSynopsis+`
+
+	outgoing lists the commits on the current branch that are not in any
+	of the destination's remote-tracking branches. If no destination is
+	given, the branch's push remote is used. outgoing does not contact the
+	remote: run `+"`gg pull`"+` first to update the remote-tracking
+	branches.`)
+	stat := f.Bool("stat", false, "include diffstat-style summary of each commit")
+	templateFlag := f.String("T", "", "display each commit using the given `template`")
+	f.Alias("T", "template")
+	if err := f.Parse(args); flag.IsHelp(err) {
+		f.Help(cc.stdout)
+		return nil
+	} else if err != nil {
+		return usagef("%v", err)
+	}
+	if f.NArg() > 1 {
+		return usagef("can't pass multiple destinations")
+	}
+	branch := currentBranch(ctx, cc)
+	if branch == "" {
+		return errors.New("no branch checked out")
+	}
+	if _, err := cc.git.Head(ctx); err != nil {
+		fmt.Fprintf(cc.stderr, "gg: %s has no commits yet\n", branch)
+		return nil
+	}
+	cfg, err := cc.git.ReadConfig(ctx)
+	if err != nil {
+		return err
+	}
+	remoteName := f.Arg(0)
+	if remoteName == "" {
+		remoteName, err = inferPushRepo(cfg, branch)
+		if err != nil {
+			return err
+		}
+	}
+	if cfg.ListRemotes()[remoteName] == nil {
+		return fmt.Errorf("no remote named %q found", remoteName)
+	}
+	refs, err := cc.git.ListRefs(ctx)
+	if err != nil {
+		return err
+	}
+	revs := []string{git.BranchRef(branch).String()}
+	prefix := "refs/remotes/" + remoteName + "/"
+	for ref, h := range refs {
+		if strings.HasPrefix(ref.String(), prefix) {
+			revs = append(revs, "^"+h.String())
+		}
+	}
+	return logChanges(ctx, cc, revs, *stat, *templateFlag)
+}
+
+// logChanges shows the commits reachable from revs like log does,
+// or reports that there are none.
+func logChanges(ctx context.Context, cc *cmdContext, revs []string, stat bool, templateText string) error {
+	if stat && templateText != "" {
+		return usagef("can't pass both -T and --stat")
+	}
+	commits, err := revList(ctx, cc.git, revs)
+	if err != nil {
+		return err
+	}
+	if len(commits) == 0 {
+		_, err := fmt.Fprintln(cc.stdout, "no changes found")
+		return err
+	}
+	cfg, err := cc.git.ReadConfig(ctx)
+	if err != nil {
+		return err
+	}
+	flags := &logFlags{
+		rev:  revs,
+		stat: stat,
+	}
+	flags.date, err = dateStyle(cfg, "")
+	if err != nil {
+		return err
+	}
+	if templateText != "" {
+		tmpl, err := template.Parse(templateText)
+		if err != nil {
+			return usagef("-T: %v", err)
+		}
+		return logWithTemplate(ctx, cc, flags, tmpl, "")
+	}
+	return logWithGit(ctx, cc, flags, "")
+}
+
+// revList returns the commits reachable from the given revisions,
+// newest first. Revisions prefixed with "^" exclude the commits reachable
+// from them.
+func revList(ctx context.Context, g *git.Git, revs []string) ([]git.Hash, error) {
+	args := []string{"rev-list"}
+	for _, r := range revs {
+		if strings.HasPrefix(r, "-") {
+			return nil, fmt.Errorf("rev-list: revision %q must not start with '-'", r)
+		}
+		args = append(args, r)
+	}
+	args = append(args, "--")
+	out, err := g.Output(ctx, args...)
+	if err != nil {
+		return nil, err
+	}
+	var hashes []git.Hash
+	for _, line := range strings.Split(strings.TrimSuffix(out, "\n"), "\n") {
+		if line == "" {
+			continue
+		}
+		h, err := git.ParseHash(line)
+		if err != nil {
+			return nil, fmt.Errorf("rev-list: %w", err)
+		}
+		hashes = append(hashes, h)
+	}
+	return hashes, nil
+}
@@ -0,0 +1,85 @@
+// Copyright 2021 The gg Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"testing"
+
+	"gg-scm.io/pkg/git"
+	"gg-scm.io/tool/internal/filesystem"
+)
+
+func TestIncomingOutgoing(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	env, err := newTestEnv(ctx, t)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := env.initRepoWithHistory(ctx, "repoA"); err != nil {
+		t.Fatal(err)
+	}
+	if err := env.git.Run(ctx, "clone", "--quiet", "repoA", "repoB"); err != nil {
+		t.Fatal(err)
+	}
+	repoBPath := env.root.FromSlash("repoB")
+
+	out, err := env.gg(ctx, repoBPath, "incoming", "-T", "{desc|firstline}\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := string(out), "no changes found\n"; got != want {
+		t.Errorf("gg incoming before commit = %q; want %q", got, want)
+	}
+
+	// Commit to repoA.
+	if err := env.root.Apply(filesystem.Write("repoA/upstream.txt", dummyContent)); err != nil {
+		t.Fatal(err)
+	}
+	if err := env.addFiles(ctx, "repoA/upstream.txt"); err != nil {
+		t.Fatal(err)
+	}
+	gitA := env.git.WithDir(env.root.FromSlash("repoA"))
+	if err := gitA.Commit(ctx, "upstream change", git.CommitOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	// Commit to repoB.
+	if err := env.root.Apply(filesystem.Write("repoB/local.txt", dummyContent)); err != nil {
+		t.Fatal(err)
+	}
+	if err := env.addFiles(ctx, "repoB/local.txt"); err != nil {
+		t.Fatal(err)
+	}
+	gitB := env.git.WithDir(repoBPath)
+	if err := gitB.Commit(ctx, "local change", git.CommitOptions{}); err != nil {
+		t.Fatal(err)
+	}
+
+	out, err = env.gg(ctx, repoBPath, "incoming", "-T", "{desc|firstline}\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := string(out), "upstream change\n"; got != want {
+		t.Errorf("gg incoming = %q; want %q", got, want)
+	}
+	out, err = env.gg(ctx, repoBPath, "outgoing", "-T", "{desc|firstline}\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := string(out), "local change\n"; got != want {
+		t.Errorf("gg outgoing = %q; want %q", got, want)
+	}
+}
@@ -0,0 +1,585 @@
+// Copyright 2021 The gg Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+
+	"gg-scm.io/pkg/git"
+	"gg-scm.io/tool/internal/flag"
+)
+
+const landSynopsis = "merge a pull request and clean up its branch"
+
+func land(ctx context.Context, cc *cmdContext, args []string) error {
+	f := flag.NewFlagSet(true, "gg land [--method=squash|merge|rebase] [BRANCH]", landSynopsis+`
+
+	Merge the open pull request for the given branch (defaults to the one
+	currently checked out) once its CI checks have passed. land works with
+	GitHub pull requests, GitLab merge requests, and Gitea pull requests,
+	although GitLab does not support the rebase method. After the
+	merge, land pulls the updated base branch, checks it out, rebases any
+	local branches stacked on top of the landed branch onto it, and
+	deletes the landed branch both locally and on the remote.
+
+	The local branch must be in sync with the pull request: push any new
+	commits with `+"`gg push`"+` before landing.`)
+	method := f.String("method", "squash", "merge `method`: one of squash, merge, or rebase")
+	force := f.Bool("force", false, "merge even if CI checks are not successful")
+	if err := f.Parse(args); flag.IsHelp(err) {
+		f.Help(cc.stdout)
+		return nil
+	} else if err != nil {
+		return usagef("%v", err)
+	}
+	if f.NArg() > 1 {
+		return usagef("only one branch allowed")
+	}
+	switch *method {
+	case "squash", "merge", "rebase":
+	default:
+		return usagef("unknown merge method %q", *method)
+	}
+	cfg, err := cc.git.ReadConfig(ctx)
+	if err != nil {
+		return err
+	}
+	target, err := findPullRequestTarget(ctx, cc, cfg, f.Arg(0), "")
+	if err != nil {
+		return err
+	}
+	forge, ok := target.forge.(landingForge)
+	if !ok {
+		return fmt.Errorf("land does not support %v repositories", target.forge)
+	}
+	token, err := forge.readToken(ctx, cc)
+	if err != nil {
+		return err
+	}
+	pr, err := forge.findOpenPullRequest(ctx, cc.httpClient, string(token), target)
+	if err != nil {
+		return err
+	}
+	tip, err := cc.git.ParseRev(ctx, git.BranchRef(target.branch).String())
+	if err != nil {
+		return err
+	}
+	if pr.headSHA != "" && pr.headSHA != tip.Commit.String() {
+		return fmt.Errorf("%s does not match pull request %s (run `gg push` first)", target.branch, pr.url)
+	}
+
+	// Verify CI before merging.
+	if !*force {
+		checks, err := forge.commitChecks(ctx, cc.httpClient, string(token), target, tip.Commit.String())
+		if err != nil {
+			return err
+		}
+		if failures := failingCommitChecks(checks); len(failures) > 0 {
+			return fmt.Errorf("pull request %s has checks that have not passed: %s (use --force to merge anyway)",
+				pr.url, strings.Join(failures, ", "))
+		}
+	}
+
+	err = forge.mergePullRequest(ctx, cc.httpClient, string(token), target, pr, *method, tip.Commit.String())
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(cc.stderr, "gg: merged %s\n", pr.url)
+
+	// Bring the local repository up to date.
+	baseBranch := pr.baseRef
+	if baseBranch == "" {
+		baseBranch = target.baseBranch
+	}
+	if err := cc.interactiveGit(ctx, "fetch", "--", target.baseRemote); err != nil {
+		return err
+	}
+	newBase := target.baseRemote + "/" + baseBranch
+	if err := checkoutLandedBase(ctx, cc, baseBranch, newBase); err != nil {
+		return err
+	}
+	if err := restackLandedBranch(ctx, cc, target.branch, tip.Commit, newBase); err != nil {
+		return err
+	}
+
+	// Clean up the topic branch. It lives on the remote it was pushed to,
+	// which is a fork rather than the base repository in a fork workflow.
+	remoteRefs, err := cc.git.ListRemoteRefs(ctx, target.headRemote)
+	if err != nil {
+		fmt.Fprintln(cc.stderr, "gg:", err)
+	} else if _, exists := remoteRefs[git.BranchRef(target.branch)]; exists {
+		if err := cc.interactiveGit(ctx, "push", "--", target.headRemote, ":"+git.BranchRef(target.branch).String()); err != nil {
+			return err
+		}
+	}
+	if err := cc.git.Run(ctx, "branch", "-D", "--", target.branch); err != nil {
+		return err
+	}
+	return nil
+}
+
+// checkoutLandedBase checks out the local branch named baseBranch and
+// fast-forwards it to newBase, creating the branch if necessary.
+func checkoutLandedBase(ctx context.Context, cc *cmdContext, baseBranch string, newBase string) error {
+	if _, err := cc.git.ParseRev(ctx, git.BranchRef(baseBranch).String()); err != nil {
+		return cc.git.NewBranch(ctx, baseBranch, git.BranchOptions{
+			StartPoint: newBase,
+			Track:      true,
+			Checkout:   true,
+		})
+	}
+	if err := cc.git.CheckoutBranch(ctx, baseBranch, git.CheckoutOptions{}); err != nil {
+		return err
+	}
+	if err := cc.git.Run(ctx, "merge", "--quiet", "--ff-only", newBase); err != nil {
+		return fmt.Errorf("update %s: %w", baseBranch, err)
+	}
+	return nil
+}
+
+// restackLandedBranch rebases the local branches that descend from the
+// landed branch's tip onto newBase, preserving the order of the stack.
+// The original checked out branch is restored afterward.
+func restackLandedBranch(ctx context.Context, cc *cmdContext, landed string, oldTip git.Hash, newBase string) error {
+	branches, err := findLandStack(ctx, cc.git, landed, oldTip, newBase)
+	if err != nil {
+		return err
+	}
+	if len(branches) == 0 {
+		return nil
+	}
+	headRef, err := cc.git.HeadRef(ctx)
+	if err != nil {
+		return err
+	}
+	// newTips maps old branch tips to their rebased counterparts.
+	newTips := map[git.Hash]string{oldTip: newBase}
+	for _, b := range branches {
+		if newTip, processed := newTips[b.tip]; processed {
+			// Another branch at the same commit has already been rebased.
+			if err := cc.git.Run(ctx, "branch", "--force", "--", b.branch, newTip); err != nil {
+				return err
+			}
+			continue
+		}
+		fmt.Fprintf(cc.stderr, "gg: rebasing %s onto %s\n", b.branch, newTips[b.parent])
+		if err := cc.interactiveGit(ctx, "rebase", "--onto", newTips[b.parent], b.parent.String(), b.branch); err != nil {
+			return fmt.Errorf("rebase %s: %w (resolve conflicts, then run `gg rebase --continue`)", b.branch, err)
+		}
+		newTips[b.tip] = git.BranchRef(b.branch).String()
+	}
+	if headRef.IsBranch() {
+		return cc.git.CheckoutBranch(ctx, headRef.Branch(), git.CheckoutOptions{})
+	}
+	return nil
+}
+
+// A landStack describes a local branch stacked on top of a landed branch.
+type landStack struct {
+	branch string
+	tip    git.Hash
+	// parent is the tip of the nearest stacked branch that this branch
+	// descends from or the landed branch's old tip.
+	parent git.Hash
+	// depth is the number of other stacked branch tips below tip.
+	depth int
+}
+
+// findLandStack returns the local branches other than landed that
+// descend from oldTip and have commits not in newBase, ordered so that
+// each branch comes after the branches it is stacked on. The commit graph
+// is read with a single rev-list.
+func findLandStack(ctx context.Context, g *git.Git, landed string, oldTip git.Hash, newBase string) ([]landStack, error) {
+	refs, err := g.ListRefs(ctx)
+	if err != nil {
+		return nil, err
+	}
+	tipBranches := make(map[git.Hash][]string)
+	revListArgs := []string{"rev-list", "--parents"}
+	for ref, commit := range refs {
+		if !ref.IsBranch() || ref.Branch() == landed || commit == oldTip {
+			continue
+		}
+		if len(tipBranches[commit]) == 0 {
+			revListArgs = append(revListArgs, commit.String())
+		}
+		tipBranches[commit] = append(tipBranches[commit], ref.Branch())
+	}
+	if len(tipBranches) == 0 {
+		return nil, nil
+	}
+	revListArgs = append(revListArgs, "--not", oldTip.String(), newBase, "--")
+	out, err := g.Output(ctx, revListArgs...)
+	if err != nil {
+		return nil, err
+	}
+	// parents maps each listed commit to its parents. Commits outside the
+	// map are reachable from oldTip or newBase.
+	parents := make(map[git.Hash][]git.Hash)
+	for _, line := range strings.Split(strings.TrimSuffix(out, "\n"), "\n") {
+		if line == "" {
+			continue
+		}
+		var hashes []git.Hash
+		for _, field := range strings.Fields(line) {
+			h, err := git.ParseHash(field)
+			if err != nil {
+				return nil, fmt.Errorf("find stacked branches: %w", err)
+			}
+			hashes = append(hashes, h)
+		}
+		parents[hashes[0]] = hashes[1:]
+	}
+
+	// For each branch tip, find the other tips it descends from and whether
+	// it descends from oldTip at all.
+	below := make(map[git.Hash][]git.Hash)
+	for tip := range tipBranches {
+		if _, listed := parents[tip]; !listed {
+			continue
+		}
+		var tips []git.Hash
+		descends := false
+		seen := map[git.Hash]bool{tip: true}
+		stack := []git.Hash{tip}
+		for len(stack) > 0 {
+			c := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			for _, p := range parents[c] {
+				if p == oldTip {
+					descends = true
+				}
+				if seen[p] {
+					continue
+				}
+				seen[p] = true
+				if _, listed := parents[p]; !listed {
+					continue
+				}
+				if len(tipBranches[p]) > 0 {
+					tips = append(tips, p)
+				}
+				stack = append(stack, p)
+			}
+		}
+		if descends {
+			below[tip] = tips
+		}
+	}
+
+	var branches []landStack
+	for tip, tips := range below {
+		parent := oldTip
+		depth := 0
+		for _, t := range tips {
+			ts, stacked := below[t]
+			if !stacked {
+				continue
+			}
+			depth++
+			if parent == oldTip || len(ts) > len(below[parent]) {
+				parent = t
+			}
+		}
+		for _, name := range tipBranches[tip] {
+			branches = append(branches, landStack{branch: name, tip: tip, parent: parent, depth: depth})
+		}
+	}
+	sort.Slice(branches, func(i, j int) bool {
+		if branches[i].depth != branches[j].depth {
+			return branches[i].depth < branches[j].depth
+		}
+		return branches[i].branch < branches[j].branch
+	})
+	return branches, nil
+}
+
+// A landingForge is a pullRequestForge that gg land can merge pull
+// requests on.
+type landingForge interface {
+	pullRequestForge
+
+	// findOpenPullRequest returns the open pull request for the target's
+	// branch or an error if there isn't one.
+	findOpenPullRequest(ctx context.Context, client *http.Client, authToken string, t *pullRequestTarget) (*landingPullRequest, error)
+
+	// commitChecks returns the CI results for a commit in the target's
+	// base repository.
+	commitChecks(ctx context.Context, client *http.Client, authToken string, t *pullRequestTarget, sha string) ([]commitCheck, error)
+
+	// mergePullRequest merges the pull request using the given method
+	// ("squash", "merge", or "rebase") as long as its head is still sha.
+	mergePullRequest(ctx context.Context, client *http.Client, authToken string, t *pullRequestTarget, pr *landingPullRequest, method, sha string) error
+}
+
+// landingPullRequest is an open pull request found by a landingForge.
+type landingPullRequest struct {
+	number  uint64
+	url     string
+	headSHA string // may be empty if the forge doesn't report it
+	baseRef string // branch name
+}
+
+func (f gitHubForge) findOpenPullRequest(ctx context.Context, client *http.Client, authToken string, t *pullRequestTarget) (*landingPullRequest, error) {
+	pr, err := findPullRequest(ctx, client, findPullRequestParams{
+		authToken:  authToken,
+		apiRoot:    f.apiRoot,
+		owner:      t.baseOwner,
+		repo:       t.baseRepo,
+		headOwner:  t.headOwner,
+		headBranch: t.branch,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &landingPullRequest{
+		number:  pr.Number,
+		url:     pr.HTMLURL,
+		headSHA: pr.Head.SHA,
+		baseRef: pr.Base.Ref,
+	}, nil
+}
+
+func (f gitHubForge) commitChecks(ctx context.Context, client *http.Client, authToken string, t *pullRequestTarget, sha string) ([]commitCheck, error) {
+	return listCommitChecks(ctx, client, commitChecksParams{
+		authToken: authToken,
+		apiRoot:   f.apiRoot,
+		owner:     t.baseOwner,
+		repo:      t.baseRepo,
+		ref:       sha,
+	})
+}
+
+func (f gitHubForge) mergePullRequest(ctx context.Context, client *http.Client, authToken string, t *pullRequestTarget, pr *landingPullRequest, method, sha string) error {
+	return mergePullRequest(ctx, client, mergePullRequestParams{
+		authToken: authToken,
+		apiRoot:   f.apiRoot,
+		owner:     t.baseOwner,
+		repo:      t.baseRepo,
+		prNum:     pr.number,
+		method:    method,
+		sha:       sha,
+	})
+}
+
+type commitChecksParams struct {
+	authToken string
+	apiRoot   string // empty for github.com
+
+	owner string
+	repo  string
+	ref   string
+}
+
+// A commitCheck is the result of a commit status or check run.
+type commitCheck struct {
+	name string
+	// state is the status of an unfinished check run or the conclusion of
+	// a finished check, like "success", "failure", or "pending".
+	state string
+}
+
+// succeeded reports whether the check finished without a problem.
+func (c commitCheck) succeeded() bool {
+	return c.state == "success" || c.state == "neutral" || c.state == "skipped"
+}
+
+// failingCommitChecks returns the names of the checks that have not
+// completed successfully. A commit without any checks has no failures.
+func failingCommitChecks(checks []commitCheck) []string {
+	var failures []string
+	for _, c := range checks {
+		if !c.succeeded() {
+			failures = append(failures, fmt.Sprintf("%s (%s)", c.name, c.state))
+		}
+	}
+	return failures
+}
+
+// listCommitChecks returns the commit statuses followed by the check runs
+// for a commit.
+func listCommitChecks(ctx context.Context, client *http.Client, params commitChecksParams) ([]commitCheck, error) {
+	if params.authToken == "" {
+		return nil, errors.New("get commit checks: missing authentication token")
+	}
+	if params.owner == "" || params.repo == "" {
+		return nil, errors.New("get commit checks: missing repository owner or name")
+	}
+	// Both endpoints are paginated. A check on a later page must not be
+	// missed, or land could merge a pull request with failing checks.
+	var checks []commitCheck
+	statusURL := gitHubCommitResourceURL(params, "status")
+	for statusURL != "" {
+		var statusDoc struct {
+			State    string
+			Statuses []struct {
+				State   string
+				Context string
+			}
+		}
+		var err error
+		statusURL, err = getGitHubCommitResource(ctx, client, params, "status", statusURL, &statusDoc)
+		if err != nil {
+			return nil, err
+		}
+		for _, status := range statusDoc.Statuses {
+			checks = append(checks, commitCheck{name: status.Context, state: status.State})
+		}
+	}
+
+	checkRunsURL := gitHubCommitResourceURL(params, "check-runs")
+	for checkRunsURL != "" {
+		var checksDoc struct {
+			CheckRuns []struct {
+				Name       string
+				Status     string
+				Conclusion string
+			} `json:"check_runs"`
+		}
+		var err error
+		checkRunsURL, err = getGitHubCommitResource(ctx, client, params, "check-runs", checkRunsURL, &checksDoc)
+		if err != nil {
+			return nil, err
+		}
+		for _, run := range checksDoc.CheckRuns {
+			c := commitCheck{name: run.Name, state: run.Conclusion}
+			if run.Status != "completed" {
+				c.state = run.Status
+			}
+			checks = append(checks, c)
+		}
+	}
+	return checks, nil
+}
+
+// gitHubCommitResourcePerPage is the number of items requested per page
+// of a commit's statuses or check runs. It is GitHub's maximum.
+const gitHubCommitResourcePerPage = 100
+
+// gitHubCommitResourceURL returns the URL of the first page of a commit's
+// statuses or check runs.
+func gitHubCommitResourceURL(params commitChecksParams, resource string) string {
+	return gitHubAPIURL(params.apiRoot, fmt.Sprintf("repos/%s/%s/commits/%s/%s?per_page=%d",
+		url.PathEscape(params.owner), url.PathEscape(params.repo), url.PathEscape(params.ref), resource,
+		gitHubCommitResourcePerPage))
+}
+
+// getGitHubCommitResource fetches one page of a commit's statuses or check
+// runs from apiURL. It returns the URL of the next page, or the empty
+// string if apiURL was the last page.
+func getGitHubCommitResource(ctx context.Context, client *http.Client, params commitChecksParams, resource string, apiURL string, v interface{}) (next string, _ error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, apiURL, nil)
+	if err != nil {
+		return "", fmt.Errorf("get %s for %s/%s@%s: %w", resource, params.owner, params.repo, params.ref, err)
+	}
+	req.Header.Set("User-Agent", userAgentString())
+	req.Header.Set("Accept", "application/vnd.github.v3+json")
+	req.Header.Set("Authorization", "token "+params.authToken)
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("get %s for %s/%s@%s: %w", resource, params.owner, params.repo, params.ref, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		err := parseGitHubErrorResponse(resp)
+		return "", fmt.Errorf("get %s for %s/%s@%s: %w", resource, params.owner, params.repo, params.ref, err)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return "", fmt.Errorf("get %s for %s/%s@%s: parsing response: %w", resource, params.owner, params.repo, params.ref, err)
+	}
+	return nextGitHubPageURL(resp.Header.Get("Link")), nil
+}
+
+// nextGitHubPageURL returns the URL marked rel="next" in a GitHub Link
+// response header or the empty string if there is none.
+func nextGitHubPageURL(link string) string {
+	for _, elem := range strings.Split(link, ",") {
+		parts := strings.Split(elem, ";")
+		target := strings.TrimSpace(parts[0])
+		if len(parts) < 2 || !strings.HasPrefix(target, "<") || !strings.HasSuffix(target, ">") {
+			continue
+		}
+		for _, param := range parts[1:] {
+			if strings.TrimSpace(param) == `rel="next"` {
+				return target[1 : len(target)-1]
+			}
+		}
+	}
+	return ""
+}
+
+type mergePullRequestParams struct {
+	authToken string
+	apiRoot   string // empty for github.com
+
+	owner string
+	repo  string
+	prNum uint64
+
+	// method is one of "merge", "squash", or "rebase".
+	method string
+	// sha is the commit that the pull request's head must match
+	// for the merge to succeed.
+	sha string
+}
+
+// mergePullRequest merges an open pull request.
+func mergePullRequest(ctx context.Context, client *http.Client, params mergePullRequestParams) error {
+	if params.authToken == "" {
+		return errors.New("merge pull request: missing authentication token")
+	}
+	if params.owner == "" || params.repo == "" {
+		return errors.New("merge pull request: missing repository owner or name")
+	}
+
+	reqBody := map[string]interface{}{
+		"merge_method": params.method,
+	}
+	if params.sha != "" {
+		reqBody["sha"] = params.sha
+	}
+	reqBodyJSON, err := json.Marshal(reqBody)
+	if err != nil {
+		return fmt.Errorf("merge pull request %s/%s/pulls/%d: %w", params.owner, params.repo, params.prNum, err)
+	}
+	apiURL := gitHubAPIURL(params.apiRoot, fmt.Sprintf("repos/%s/%s/pulls/%d/merge",
+		url.PathEscape(params.owner), url.PathEscape(params.repo), params.prNum))
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, apiURL, bytes.NewReader(reqBodyJSON))
+	if err != nil {
+		return fmt.Errorf("merge pull request %s/%s/pulls/%d: %w", params.owner, params.repo, params.prNum, err)
+	}
+	req.Header.Set("User-Agent", userAgentString())
+	req.Header.Set("Accept", "application/vnd.github.v3+json")
+	req.Header.Set("Authorization", "token "+params.authToken)
+	req.Header.Set("Content-Type", "application/json; charset=utf-8")
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("merge pull request %s/%s/pulls/%d: %w", params.owner, params.repo, params.prNum, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		err := parseGitHubErrorResponse(resp)
+		return fmt.Errorf("merge pull request %s/%s/pulls/%d: %w", params.owner, params.repo, params.prNum, err)
+	}
+	return nil
+}
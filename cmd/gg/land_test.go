@@ -0,0 +1,375 @@
+// Copyright 2021 The gg Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"gg-scm.io/pkg/git"
+	"gg-scm.io/tool/internal/filesystem"
+)
+
+func TestLand(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	env, api, err := setupLandTest(ctx, t, "success")
+	if err != nil {
+		t.Fatal(err)
+	}
+	originGit := env.git.WithDir(env.root.FromSlash("origin"))
+	api.onMerge = func(pr fakePullRequest) error {
+		squashed, err := originGit.Output(ctx, "commit-tree", "-p", "main", "-m", pr.title, pr.headSHA+"^{tree}")
+		if err != nil {
+			return err
+		}
+		return originGit.Run(ctx, "update-ref", "refs/heads/main", strings.TrimSpace(squashed))
+	}
+
+	localDir := env.root.FromSlash("local")
+	if _, err := env.gg(ctx, localDir, "land"); err != nil {
+		t.Fatal(err)
+	}
+
+	api.mu.Lock()
+	pr := api.prs[0]
+	api.mu.Unlock()
+	if !pr.merged {
+		t.Error("pull request not merged")
+	} else if pr.mergeMethod != "squash" {
+		t.Errorf("merge method = %q; want \"squash\"", pr.mergeMethod)
+	}
+	localGit := env.git.WithDir(localDir)
+	if ref, err := localGit.HeadRef(ctx); err != nil {
+		t.Error(err)
+	} else if ref != git.BranchRef("main") {
+		t.Errorf("HEAD = %v; want refs/heads/main", ref)
+	}
+	originMain, err := originGit.ParseRev(ctx, "main")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if localMain, err := localGit.ParseRev(ctx, "main"); err != nil {
+		t.Error(err)
+	} else if localMain.Commit != originMain.Commit {
+		t.Errorf("local main = %v; want %v (origin main)", localMain.Commit, originMain.Commit)
+	}
+	if _, err := localGit.ParseRev(ctx, "refs/heads/feature"); err == nil {
+		t.Error("local feature branch still exists")
+	}
+	if _, err := originGit.ParseRev(ctx, "refs/heads/feature"); err == nil {
+		t.Error("origin feature branch still exists")
+	}
+	if stackedParent, err := localGit.ParseRev(ctx, "feature2~1"); err != nil {
+		t.Error(err)
+	} else if stackedParent.Commit != originMain.Commit {
+		t.Errorf("feature2~1 = %v; want %v (landed commit)", stackedParent.Commit, originMain.Commit)
+	}
+}
+
+func TestLand_Fork(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	env, api, err := setupLandTest(ctx, t, "success")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The pull request comes from a fork. origin keeps its own branch
+	// named feature, which land must not delete.
+	if err := env.git.Run(ctx, "clone", "--quiet", "--bare", "origin", "fork.git"); err != nil {
+		t.Fatal(err)
+	}
+	localDir := env.root.FromSlash("local")
+	localGit := env.git.WithDir(localDir)
+	const forkURL = "https://github.com/exampleuser/foo.git"
+	if err := localGit.Run(ctx, "remote", "add", "myfork", forkURL); err != nil {
+		t.Fatal(err)
+	}
+	if err := localGit.Run(ctx, "config", "url."+env.root.FromSlash("fork.git")+".insteadOf", forkURL); err != nil {
+		t.Fatal(err)
+	}
+	if err := localGit.Run(ctx, "config", "branch.feature.pushRemote", "myfork"); err != nil {
+		t.Fatal(err)
+	}
+	originGit := env.git.WithDir(env.root.FromSlash("origin"))
+	api.mu.Lock()
+	api.prs[0].headOwner = "exampleuser"
+	api.mu.Unlock()
+	api.onMerge = func(pr fakePullRequest) error {
+		squashed, err := originGit.Output(ctx, "commit-tree", "-p", "main", "-m", pr.title, pr.headSHA+"^{tree}")
+		if err != nil {
+			return err
+		}
+		return originGit.Run(ctx, "update-ref", "refs/heads/main", strings.TrimSpace(squashed))
+	}
+
+	if _, err := env.gg(ctx, localDir, "land"); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := env.git.WithDir(env.root.FromSlash("fork.git")).ParseRev(ctx, "refs/heads/feature"); err == nil {
+		t.Error("fork feature branch still exists")
+	}
+	if _, err := originGit.ParseRev(ctx, "refs/heads/feature"); err != nil {
+		t.Error("origin feature branch deleted:", err)
+	}
+}
+
+func TestLand_FailingChecks(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	env, api, err := setupLandTest(ctx, t, "failure")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	localDir := env.root.FromSlash("local")
+	if _, err := env.gg(ctx, localDir, "land"); err == nil {
+		t.Error("gg land did not return error")
+	} else if isUsage(err) {
+		t.Errorf("gg land returned usage error: %v", err)
+	}
+	api.mu.Lock()
+	merged := api.prs[0].merged
+	api.mu.Unlock()
+	if merged {
+		t.Error("pull request merged despite failing checks")
+	}
+	if _, err := env.git.WithDir(localDir).ParseRev(ctx, "refs/heads/feature"); err != nil {
+		t.Error("local feature branch deleted:", err)
+	}
+}
+
+func TestLand_FailingCheckOnLaterPage(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	env, api, err := setupLandTest(ctx, t, "success")
+	if err != nil {
+		t.Fatal(err)
+	}
+	api.mu.Lock()
+	conclusions := make([]string, 0, 151)
+	for i := 0; i < 150; i++ {
+		conclusions = append(conclusions, "success")
+	}
+	conclusions = append(conclusions, "failure")
+	api.checkRuns = map[string][]string{api.prs[0].headSHA: conclusions}
+	api.mu.Unlock()
+
+	localDir := env.root.FromSlash("local")
+	if _, err := env.gg(ctx, localDir, "land"); err == nil {
+		t.Error("gg land did not return error")
+	} else if isUsage(err) {
+		t.Errorf("gg land returned usage error: %v", err)
+	}
+	api.mu.Lock()
+	merged := api.prs[0].merged
+	api.mu.Unlock()
+	if merged {
+		t.Error("pull request merged despite failing check on a later page")
+	}
+}
+
+func TestLand_Gitea(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	env, err := newTestEnv(ctx, t)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := env.writeConfig([]byte("[gg]\ngiteaHost = git.example.com\n")); err != nil {
+		t.Fatal(err)
+	}
+	const authToken = "xyzzy12345"
+	if err := env.topDir.Apply(filesystem.Write("xdgconfig/gg/gitea_token.git.example.com", authToken+"\n")); err != nil {
+		t.Fatal(err)
+	}
+	feature, err := setupLandRepos(ctx, env, "https://git.example.com/example/foo.git")
+	if err != nil {
+		t.Fatal(err)
+	}
+	originGit := env.git.WithDir(env.root.FromSlash("origin"))
+	api := &fakeGiteaAPI{
+		errorer:        t,
+		permittedToken: authToken,
+		commitStatuses: map[string]string{feature.String(): "success"},
+		prs: []fakeGiteaPullRequest{{
+			repo:    "example/foo",
+			base:    "main",
+			head:    "feature",
+			headSHA: feature.String(),
+			title:   "Add blah",
+		}},
+		onMerge: func(pr fakeGiteaPullRequest) error {
+			return originGit.Run(ctx, "merge", "--quiet", "--no-ff", "-m", pr.title, pr.headSHA)
+		},
+	}
+	fakeGitea := httptest.NewServer(api)
+	t.Cleanup(fakeGitea.Close)
+	fakeGiteaTransport := &http.Transport{
+		DialTLS: func(network, addr string) (net.Conn, error) {
+			hostport := strings.TrimPrefix(fakeGitea.URL, "http://")
+			return net.Dial("tcp", hostport)
+		},
+	}
+	t.Cleanup(fakeGiteaTransport.CloseIdleConnections)
+	env.roundTripper = fakeGiteaTransport
+
+	localDir := env.root.FromSlash("local")
+	if _, err := env.gg(ctx, localDir, "land", "--method=merge"); err != nil {
+		t.Fatal(err)
+	}
+
+	api.mu.Lock()
+	pr := api.prs[0]
+	api.mu.Unlock()
+	if !pr.merged {
+		t.Error("pull request not merged")
+	} else if pr.mergeMethod != "merge" {
+		t.Errorf("merge method = %q; want \"merge\"", pr.mergeMethod)
+	}
+	localGit := env.git.WithDir(localDir)
+	if _, err := localGit.ParseRev(ctx, "refs/heads/feature"); err == nil {
+		t.Error("local feature branch still exists")
+	}
+	originMain, err := originGit.ParseRev(ctx, "main")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stackedParent, err := localGit.ParseRev(ctx, "feature2~1"); err != nil {
+		t.Error(err)
+	} else if stackedParent.Commit != originMain.Commit {
+		t.Errorf("feature2~1 = %v; want %v (merge commit)", stackedParent.Commit, originMain.Commit)
+	}
+}
+
+// setupLandTest creates an "origin" repository and a "local" clone
+// that has a "feature" branch pushed for a pull request and a "feature2"
+// branch stacked on top of it. The feature branch's commit has a CI status
+// with the given state.
+func setupLandTest(ctx context.Context, t *testing.T, ciState string) (*testEnv, *fakeGitHubPullRequestAPI, error) {
+	env, err := newTestEnv(ctx, t)
+	if err != nil {
+		return nil, nil, err
+	}
+	const authToken = "xyzzy12345"
+	if err := env.writeGitHubAuth([]byte(authToken + "\n")); err != nil {
+		return nil, nil, err
+	}
+	feature, err := setupLandRepos(ctx, env, "https://github.com/example/foo.git")
+	if err != nil {
+		return nil, nil, err
+	}
+
+	api := &fakeGitHubPullRequestAPI{
+		logger:         t,
+		errorer:        t,
+		permittedToken: authToken,
+		prs: []fakePullRequest{
+			{
+				id:        12345,
+				num:       1,
+				owner:     "example",
+				repo:      "foo",
+				baseRef:   "main",
+				headOwner: "example",
+				headRef:   "feature",
+				headSHA:   feature.String(),
+				title:     "Add blah",
+			},
+		},
+		commitStatuses: map[string]string{
+			feature.String(): ciState,
+		},
+	}
+	fakeGitHub := httptest.NewServer(api)
+	t.Cleanup(fakeGitHub.Close)
+	fakeGitHubTransport := &http.Transport{
+		DialTLS: func(network, addr string) (net.Conn, error) {
+			hostport := strings.TrimPrefix(fakeGitHub.URL, "http://")
+			return net.Dial("tcp", hostport)
+		},
+	}
+	t.Cleanup(fakeGitHubTransport.CloseIdleConnections)
+	env.roundTripper = fakeGitHubTransport
+	return env, api, nil
+}
+
+// setupLandRepos creates the repositories for setupLandTest, with the
+// local clone's origin pointing at remoteURL, and returns the feature
+// branch's commit.
+func setupLandRepos(ctx context.Context, env *testEnv, remoteURL string) (git.Hash, error) {
+	if err := env.initRepoWithHistory(ctx, "origin"); err != nil {
+		return git.Hash{}, err
+	}
+	if err := env.git.Run(ctx, "clone", "--quiet", "origin", "local"); err != nil {
+		return git.Hash{}, err
+	}
+	localGit := env.git.WithDir(env.root.FromSlash("local"))
+	if err := localGit.Run(ctx, "remote", "set-url", "origin", remoteURL); err != nil {
+		return git.Hash{}, err
+	}
+	if err := localGit.Run(ctx, "config", "url."+env.root.FromSlash("origin")+".insteadOf", remoteURL); err != nil {
+		return git.Hash{}, err
+	}
+	err := localGit.NewBranch(ctx, "feature", git.BranchOptions{
+		StartPoint: "origin/main",
+		Track:      true,
+		Checkout:   true,
+	})
+	if err != nil {
+		return git.Hash{}, err
+	}
+	if err := env.root.Apply(filesystem.Write("local/blah.txt", dummyContent)); err != nil {
+		return git.Hash{}, err
+	}
+	if err := env.addFiles(ctx, "local/blah.txt"); err != nil {
+		return git.Hash{}, err
+	}
+	if err := localGit.Commit(ctx, "Add blah", git.CommitOptions{}); err != nil {
+		return git.Hash{}, err
+	}
+	if err := localGit.Run(ctx, "push", "--quiet", "origin", "feature"); err != nil {
+		return git.Hash{}, err
+	}
+	feature, err := localGit.ParseRev(ctx, "feature")
+	if err != nil {
+		return git.Hash{}, err
+	}
+	err = localGit.NewBranch(ctx, "feature2", git.BranchOptions{
+		StartPoint: "feature",
+		Checkout:   true,
+	})
+	if err != nil {
+		return git.Hash{}, err
+	}
+	if err := env.root.Apply(filesystem.Write("local/stacked.txt", dummyContent)); err != nil {
+		return git.Hash{}, err
+	}
+	if err := env.addFiles(ctx, "local/stacked.txt"); err != nil {
+		return git.Hash{}, err
+	}
+	if err := localGit.Commit(ctx, "Add stacked", git.CommitOptions{}); err != nil {
+		return git.Hash{}, err
+	}
+	if err := localGit.CheckoutBranch(ctx, "feature", git.CheckoutOptions{}); err != nil {
+		return git.Hash{}, err
+	}
+	return feature.Commit, nil
+}
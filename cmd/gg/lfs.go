@@ -0,0 +1,268 @@
+// Copyright 2021 The gg Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"gg-scm.io/pkg/git"
+)
+
+// defaultLFSThreshold is the size at which gg add warns about binary files
+// that Git LFS doesn't track if gg.lfs.threshold is not set.
+const defaultLFSThreshold = 10 << 20
+
+// errLFSNotInstalled is returned when a repository needs Git LFS, but
+// the git-lfs program can't be found.
+var errLFSNotInstalled = errors.New("repository uses Git LFS, but git-lfs is not installed. " +
+	"See https://git-lfs.github.com/ to install it.")
+
+// lfsThreshold returns the gg.lfs.threshold setting: the size in bytes at
+// which gg add warns about binary files that Git LFS doesn't track. Like
+// Git's integer settings, the value may end in k, m, or g. Zero turns off
+// the warning.
+func lfsThreshold(cfg *git.Config) (int64, error) {
+	const key = "gg.lfs.threshold"
+	v := cfg.Value(key)
+	if v == "" {
+		return defaultLFSThreshold, nil
+	}
+	digits, shift := v, 0
+	switch v[len(v)-1] {
+	case 'k', 'K':
+		digits, shift = v[:len(v)-1], 10
+	case 'm', 'M':
+		digits, shift = v[:len(v)-1], 20
+	case 'g', 'G':
+		digits, shift = v[:len(v)-1], 30
+	}
+	n, err := strconv.ParseInt(digits, 10, 64)
+	if err != nil || n < 0 || n > (1<<63-1)>>shift {
+		return 0, fmt.Errorf("config %s: invalid size %q", key, v)
+	}
+	return n << shift, nil
+}
+
+// usesLFS reports whether the top-level .gitattributes file in the working
+// copy sends any files through the Git LFS filter.
+func usesLFS(ctx context.Context, g *git.Git) (bool, error) {
+	top, err := g.WorkTree(ctx)
+	if err != nil {
+		// A bare repository has no working copy to check out files into.
+		return false, nil
+	}
+	f, err := os.Open(filepath.Join(top, ".gitattributes"))
+	if os.IsNotExist(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	defer f.Close()
+	s := bufio.NewScanner(f)
+	for s.Scan() {
+		fields := strings.Fields(s.Text())
+		if len(fields) < 2 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+		for _, attr := range fields[1:] {
+			if attr == "filter=lfs" {
+				return true, nil
+			}
+		}
+	}
+	return false, s.Err()
+}
+
+// lfsInstalled reports whether the git-lfs program is available.
+func lfsInstalled(ctx context.Context, g *git.Git) bool {
+	_, err := g.Output(ctx, "lfs", "version")
+	return err == nil
+}
+
+// lfsFiltered returns the subset of paths that the Git LFS filter applies to.
+func lfsFiltered(ctx context.Context, g *git.Git, paths []git.TopPath) (map[git.TopPath]bool, error) {
+	top, err := g.WorkTree(ctx)
+	if err != nil {
+		return nil, err
+	}
+	args := []string{"check-attr", "-z", "filter", "--"}
+	for _, p := range paths {
+		args = append(args, p.String())
+	}
+	out, err := g.WithDir(top).Output(ctx, args...)
+	if err != nil {
+		return nil, fmt.Errorf("check LFS attributes: %w", err)
+	}
+	// Output is a sequence of "PATH NUL ATTR NUL VALUE NUL" records.
+	fields := strings.Split(strings.TrimSuffix(out, "\x00"), "\x00")
+	if len(fields)%3 != 0 {
+		return nil, fmt.Errorf("check LFS attributes: unexpected output %q", out)
+	}
+	filtered := make(map[git.TopPath]bool)
+	for i := 0; i+2 < len(fields); i += 3 {
+		if fields[i+2] == "lfs" {
+			filtered[git.TopPath(fields[i])] = true
+		}
+	}
+	return filtered, nil
+}
+
+// warnLargeFiles prints a warning for each binary file among the given
+// untracked paths that is at least as large as gg.lfs.threshold and that
+// Git LFS doesn't track. Untracked directories (ending in a slash) are
+// searched for files that aren't ignored.
+func warnLargeFiles(ctx context.Context, cc *cmdContext, paths []git.TopPath) error {
+	cfg, err := cc.git.ReadConfig(ctx)
+	if err != nil {
+		return err
+	}
+	threshold, err := lfsThreshold(cfg)
+	if err != nil || threshold == 0 || len(paths) == 0 {
+		return err
+	}
+	top, err := cc.git.WorkTree(ctx)
+	if err != nil {
+		return err
+	}
+	var files []git.TopPath
+	lsArgs := []string{"ls-files", "--others", "--exclude-standard", "--full-name", "-z", "--"}
+	nDirs := 0
+	for _, p := range paths {
+		if strings.HasSuffix(p.String(), "/") {
+			lsArgs = append(lsArgs, p.Pathspec().String())
+			nDirs++
+		} else {
+			files = append(files, p)
+		}
+	}
+	if nDirs > 0 {
+		out, err := cc.git.Output(ctx, lsArgs...)
+		if err != nil {
+			return err
+		}
+		for _, name := range strings.Split(out, "\x00") {
+			if name != "" {
+				files = append(files, git.TopPath(name))
+			}
+		}
+	}
+
+	var large []git.TopPath
+	sizes := make(map[git.TopPath]int64)
+	for _, p := range files {
+		path := filepath.Join(top, filepath.FromSlash(p.String()))
+		info, err := os.Lstat(path)
+		if err != nil || !info.Mode().IsRegular() || info.Size() < threshold {
+			continue
+		}
+		if binary, err := isBinaryFile(path); err != nil || !binary {
+			continue
+		}
+		large = append(large, p)
+		sizes[p] = info.Size()
+	}
+	if len(large) == 0 {
+		return nil
+	}
+	filtered, err := lfsFiltered(ctx, cc.git, large)
+	if err != nil {
+		return err
+	}
+	for _, p := range large {
+		if !filtered[p] {
+			fmt.Fprintf(cc.stderr, "gg: warning: %s is a %s binary file that Git LFS doesn't track. "+
+				"Consider 'git lfs track'.\n", p, formatSize(sizes[p]))
+		}
+	}
+	return nil
+}
+
+// isBinaryFile reports whether the file at path looks like binary data,
+// using Git's heuristic of looking for a NUL byte near the beginning.
+func isBinaryFile(path string) (bool, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return false, err
+	}
+	defer f.Close()
+	buf := make([]byte, 8000)
+	n, err := io.ReadFull(f, buf)
+	if err != nil && err != io.ErrUnexpectedEOF {
+		return false, err
+	}
+	return bytes.IndexByte(buf[:n], 0) != -1, nil
+}
+
+// pushLFSObjects uploads the Git LFS objects referenced by the given
+// commits to dstRepo if the repository uses Git LFS and the pre-push hook
+// that `git lfs install` sets up to do this is missing.
+func pushLFSObjects(ctx context.Context, cc *cmdContext, dstRepo string, commits []string) error {
+	if len(commits) == 0 {
+		return nil
+	}
+	if uses, err := usesLFS(ctx, cc.git); err != nil || !uses {
+		return err
+	}
+	cfg, err := cc.git.ReadConfig(ctx)
+	if err != nil {
+		return err
+	}
+	hook, err := hookPath(ctx, cfg, cc.git, "pre-push")
+	if err != nil {
+		return err
+	}
+	if _, err := os.Stat(hook); err == nil {
+		return nil
+	}
+	if !lfsInstalled(ctx, cc.git) {
+		return errLFSNotInstalled
+	}
+	return cc.interactiveGit(ctx, append([]string{"lfs", "push", dstRepo}, commits...)...)
+}
+
+// pullLFSObjects downloads and checks out the Git LFS files in the working
+// copy if the repository uses Git LFS and the smudge filter that
+// `git lfs install` sets up to do this is not configured. remote may be
+// empty to use the default remote.
+func pullLFSObjects(ctx context.Context, cc *cmdContext, remote string) error {
+	if uses, err := usesLFS(ctx, cc.git); err != nil || !uses {
+		return err
+	}
+	cfg, err := cc.git.ReadConfig(ctx)
+	if err != nil {
+		return err
+	}
+	if cfg.Value("filter.lfs.smudge") != "" {
+		return nil
+	}
+	if !lfsInstalled(ctx, cc.git) {
+		return errLFSNotInstalled
+	}
+	args := []string{"lfs", "pull"}
+	if remote != "" {
+		args = append(args, remote)
+	}
+	return cc.interactiveGit(ctx, args...)
+}
@@ -0,0 +1,66 @@
+// Copyright 2021 The gg Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"gg-scm.io/tool/internal/filesystem"
+)
+
+func TestAdd_LFSWarning(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	env, err := newTestEnv(ctx, t)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := env.initEmptyRepo(ctx, "."); err != nil {
+		t.Fatal(err)
+	}
+	if err := env.git.Run(ctx, "config", "gg.lfs.threshold", "1k"); err != nil {
+		t.Fatal(err)
+	}
+	binary := strings.Repeat("\x00\x01\x02\x03", 512)
+	err = env.root.Apply(
+		filesystem.Write(".gitattributes", "*.bin filter=lfs diff=lfs merge=lfs -text\n"),
+		filesystem.Write("big.dat", binary),
+		filesystem.Write("tracked.bin", binary),
+		filesystem.Write("small.dat", "\x00\x01"),
+		filesystem.Write("text.txt", strings.Repeat("Hello, World!\n", 200)),
+		filesystem.Write("assets/image.dat", binary),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = env.gg(ctx, env.root.String(), "add", "big.dat", "tracked.bin", "small.dat", "text.txt", "assets")
+	if err != nil {
+		t.Fatal(err)
+	}
+	stderr := env.stderr.String()
+	for _, name := range []string{"big.dat", "assets/image.dat"} {
+		if !strings.Contains(stderr, name) {
+			t.Errorf("add did not warn about %s; stderr:\n%s", name, stderr)
+		}
+	}
+	for _, name := range []string{"tracked.bin", "small.dat", "text.txt"} {
+		if strings.Contains(stderr, name) {
+			t.Errorf("add warned about %s; stderr:\n%s", name, stderr)
+		}
+	}
+}
@@ -0,0 +1,204 @@
+// Copyright 2021 The gg Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"gg-scm.io/pkg/git"
+)
+
+// repoLockFilename is the name of the file in the Git common directory
+// that gg creates while it runs a command that modifies the repository.
+const repoLockFilename = "gg.lock"
+
+// repoLockEnv is the environment variable that gg sets for its subprocesses
+// while it holds the repository lock. A gg started by a hook or a
+// `histedit --exec` command uses it to avoid waiting on its parent.
+const repoLockEnv = "GG_REPO_LOCK"
+
+// repoLockPollInterval is how often gg checks whether the repository lock
+// has been released.
+const repoLockPollInterval = 100 * time.Millisecond
+
+// errRepoLocked is returned when another gg holds the repository lock.
+var errRepoLocked = errors.New("another gg operation is in progress")
+
+// scanArgs returns the names of the flags given in args and the number of
+// positional arguments, following the same syntax as package flag.
+// valueFlags names the flags that take a value, so that a value passed as
+// a separate argument is not counted as a positional argument. Boolean
+// flags explicitly set to false are not returned.
+func scanArgs(args []string, valueFlags ...string) (flags map[string]bool, npos int) {
+	flags = make(map[string]bool)
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if arg == "--" {
+			npos += len(args) - i - 1
+			break
+		}
+		if len(arg) < 2 || arg[0] != '-' {
+			npos++
+			continue
+		}
+		name := strings.TrimPrefix(arg[1:], "-")
+		if eq := strings.IndexByte(name, '='); eq != -1 {
+			if b, err := strconv.ParseBool(name[eq+1:]); err == nil && !b {
+				continue
+			}
+			flags[name[:eq]] = true
+			continue
+		}
+		flags[name] = true
+		for _, vf := range valueFlags {
+			if name == vf {
+				i++
+				break
+			}
+		}
+	}
+	return flags, npos
+}
+
+// A repoLock is an acquired repository lock.
+type repoLock struct {
+	path string
+}
+
+// acquireRepoLock creates the repository lock file, waiting up to timeout
+// for another gg to release it. It returns a nil lock if the working
+// directory is not inside a repository or if the lock is already held
+// by a parent gg process.
+func acquireRepoLock(ctx context.Context, opts git.Options, timeout time.Duration) (*repoLock, error) {
+	g, err := git.New(opts)
+	if err != nil {
+		return nil, nil
+	}
+	commonDir, err := g.CommonDir(ctx)
+	if err != nil {
+		// Not in a repository (or Git is missing, which will be reported later).
+		return nil, nil
+	}
+	if !filepath.IsAbs(commonDir) {
+		commonDir = filepath.Join(opts.Dir, commonDir)
+	}
+	path := filepath.Join(commonDir, repoLockFilename)
+	if getenv(opts.Env, repoLockEnv) == path {
+		return nil, nil
+	}
+	deadline := time.Now().Add(timeout)
+	for {
+		f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o666)
+		if err == nil {
+			_, err = fmt.Fprintf(f, "%d\n", os.Getpid())
+			closeErr := f.Close()
+			if err == nil {
+				err = closeErr
+			}
+			if err != nil {
+				os.Remove(path)
+				return nil, fmt.Errorf("lock repository: %w", err)
+			}
+			return &repoLock{path: path}, nil
+		}
+		if !os.IsExist(err) {
+			return nil, fmt.Errorf("lock repository: %w", err)
+		}
+		if pid := lockHolder(path); pid > 0 && !processExists(pid) {
+			// The gg that held the lock exited without releasing it.
+			if err := breakStaleLock(path, pid); err != nil {
+				return nil, fmt.Errorf("lock repository: remove stale lock: %w", err)
+			}
+			continue
+		}
+		if !time.Now().Before(deadline) {
+			return nil, lockHeldError(path)
+		}
+		t := time.NewTimer(repoLockPollInterval)
+		select {
+		case <-t.C:
+		case <-ctx.Done():
+			t.Stop()
+			return nil, fmt.Errorf("lock repository: %w", ctx.Err())
+		}
+	}
+}
+
+// breakStaleLock removes the lock file at path left behind by the exited
+// process pid. Another gg may have broken the same stale lock and acquired
+// the repository after the holder was read, so the file is first renamed
+// aside and only removed if it still names pid. Otherwise, it is put back.
+func breakStaleLock(path string, pid int) error {
+	aside := path + ".stale" + strconv.Itoa(os.Getpid())
+	if err := os.Rename(path, aside); err != nil {
+		if os.IsNotExist(err) {
+			// Already broken by another gg.
+			return nil
+		}
+		return err
+	}
+	if lockHolder(aside) == pid {
+		return os.Remove(aside)
+	}
+	// Linking fails instead of replacing a lock created in the meantime.
+	if err := os.Link(aside, path); err != nil && !os.IsExist(err) {
+		return err
+	}
+	return os.Remove(aside)
+}
+
+// lockHolder returns the process ID recorded in the lock file at path
+// or zero if it can't be read.
+func lockHolder(path string) int {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return 0
+	}
+	pid, err := strconv.Atoi(strings.TrimSpace(string(data)))
+	if err != nil {
+		return 0
+	}
+	return pid
+}
+
+// lockHeldError returns an error describing the holder of the lock at path.
+func lockHeldError(path string) error {
+	holder := "another process"
+	if pid := lockHolder(path); pid > 0 {
+		holder = "process " + strconv.Itoa(pid)
+	}
+	return fmt.Errorf("%w (%s holds %s). If no other gg is running, remove the file and try again", errRepoLocked, holder, path)
+}
+
+// env returns environ with the lock recorded for subprocesses.
+func (lock *repoLock) env(environ []string) []string {
+	return append(environ[:len(environ):len(environ)], repoLockEnv+"="+lock.path)
+}
+
+// release removes the lock file.
+func (lock *repoLock) release() error {
+	if err := os.Remove(lock.path); err != nil {
+		return fmt.Errorf("unlock repository: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,182 @@
+// Copyright 2021 The gg Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"gg-scm.io/tool/internal/filesystem"
+)
+
+func TestRepoLock(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	env, err := newTestEnv(ctx, t)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := env.initRepoWithHistory(ctx, "repo"); err != nil {
+		t.Fatal(err)
+	}
+	if err := env.root.Apply(filesystem.Write("repo/foo.txt", dummyContent)); err != nil {
+		t.Fatal(err)
+	}
+	repoDir := env.root.FromSlash("repo")
+	lockPath := env.root.FromSlash("repo/.git/" + repoLockFilename)
+
+	// Simulate another gg holding the lock.
+	pidLine := strconv.Itoa(os.Getpid()) + "\n"
+	if err := env.root.Apply(filesystem.Write("repo/.git/"+repoLockFilename, pidLine)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := env.gg(ctx, repoDir, "--no-wait", "add", "foo.txt"); err == nil {
+		t.Error("gg --no-wait add succeeded while repository was locked")
+	} else if !errors.Is(err, errRepoLocked) {
+		t.Errorf("gg --no-wait add error = %v; want %v", err, errRepoLocked)
+	}
+	if _, err := env.gg(ctx, repoDir, "--lock-timeout=0", "add", "foo.txt"); !errors.Is(err, errRepoLocked) {
+		t.Errorf("gg --lock-timeout=0 add error = %v; want %v", err, errRepoLocked)
+	}
+	// Read-only commands don't need the lock.
+	if _, err := env.gg(ctx, repoDir, "status"); err != nil {
+		t.Error("gg status while locked:", err)
+	}
+	if _, err := env.gg(ctx, repoDir, "--no-wait", "branch"); err != nil {
+		t.Error("gg --no-wait branch while locked:", err)
+	}
+	if _, err := env.gg(ctx, repoDir, "--no-wait", "tag"); err != nil {
+		t.Error("gg --no-wait tag while locked:", err)
+	}
+
+	if err := os.Remove(lockPath); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := env.gg(ctx, repoDir, "--no-wait", "add", "foo.txt"); err != nil {
+		t.Fatal("gg --no-wait add:", err)
+	}
+	if _, err := os.Stat(lockPath); !os.IsNotExist(err) {
+		t.Errorf("after gg add, stat %s: %v; want not exist", lockPath, err)
+	}
+}
+
+func TestRepoLock_Stale(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	env, err := newTestEnv(ctx, t)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := env.initRepoWithHistory(ctx, "repo"); err != nil {
+		t.Fatal(err)
+	}
+	if err := env.root.Apply(filesystem.Write("repo/foo.txt", dummyContent)); err != nil {
+		t.Fatal(err)
+	}
+	repoDir := env.root.FromSlash("repo")
+	lockPath := env.root.FromSlash("repo/.git/" + repoLockFilename)
+
+	// Leave behind a lock from a process that has since exited.
+	exited := exec.Command(env.git.Exe(), "--version")
+	if err := exited.Run(); err != nil {
+		t.Fatal(err)
+	}
+	pidLine := strconv.Itoa(exited.Process.Pid) + "\n"
+	if err := env.root.Apply(filesystem.Write("repo/.git/"+repoLockFilename, pidLine)); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := env.gg(ctx, repoDir, "--no-wait", "add", "foo.txt"); err != nil {
+		t.Fatal("gg --no-wait add with stale lock:", err)
+	}
+	if _, err := os.Stat(lockPath); !os.IsNotExist(err) {
+		t.Errorf("after gg add, stat %s: %v; want not exist", lockPath, err)
+	}
+}
+
+func TestRepoLock_Defaults(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	env, err := newTestEnv(ctx, t)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := env.initRepoWithHistory(ctx, "repo"); err != nil {
+		t.Fatal(err)
+	}
+	if err := env.writeConfig([]byte("[gg.defaults]\n\tbranch = -d\n")); err != nil {
+		t.Fatal(err)
+	}
+	repoDir := env.root.FromSlash("repo")
+
+	// Simulate another gg holding the lock.
+	pidLine := strconv.Itoa(os.Getpid()) + "\n"
+	if err := env.root.Apply(filesystem.Write("repo/.git/"+repoLockFilename, pidLine)); err != nil {
+		t.Fatal(err)
+	}
+	// Listing branches doesn't need the lock, but gg.defaults turns this
+	// into a delete.
+	if _, err := env.gg(ctx, repoDir, "--no-wait", "branch"); !errors.Is(err, errRepoLocked) {
+		t.Errorf("gg --no-wait branch with defaults error = %v; want %v", err, errRepoLocked)
+	}
+	if _, err := env.gg(ctx, repoDir, "--no-wait", "--ignore-defaults", "branch"); err != nil {
+		t.Error("gg --no-wait --ignore-defaults branch while locked:", err)
+	}
+}
+
+func TestBreakStaleLock(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	lockPath := filepath.Join(dir, repoLockFilename)
+
+	// Simulate another gg having broken the stale lock and acquired the
+	// repository after the stale holder was read.
+	pidLine := strconv.Itoa(os.Getpid()) + "\n"
+	if err := ioutil.WriteFile(lockPath, []byte(pidLine), 0o666); err != nil {
+		t.Fatal(err)
+	}
+	const stalePID = 1 << 30
+	if err := breakStaleLock(lockPath, stalePID); err != nil {
+		t.Fatal("breakStaleLock:", err)
+	}
+	if got, err := ioutil.ReadFile(lockPath); err != nil {
+		t.Error(err)
+	} else if string(got) != pidLine {
+		t.Errorf("lock file contains %q after breakStaleLock; want %q", got, pidLine)
+	}
+	if names, err := ioutil.ReadDir(dir); err != nil {
+		t.Error(err)
+	} else if len(names) != 1 {
+		t.Errorf("directory has %d files after breakStaleLock; want 1", len(names))
+	}
+
+	// A lock that still names the stale holder is removed.
+	if err := breakStaleLock(lockPath, os.Getpid()); err != nil {
+		t.Fatal("breakStaleLock:", err)
+	}
+	if _, err := os.Stat(lockPath); !os.IsNotExist(err) {
+		t.Errorf("after breakStaleLock, stat %s: %v; want not exist", lockPath, err)
+	}
+	// Breaking an already-broken lock is not an error.
+	if err := breakStaleLock(lockPath, os.Getpid()); err != nil {
+		t.Error("breakStaleLock on missing lock:", err)
+	}
+}
@@ -17,14 +17,20 @@ package main
 import (
 	"bytes"
 	"context"
+	"errors"
 	"fmt"
 	"sort"
 	"strings"
+	"time"
 
+	"gg-scm.io/pkg/git"
 	"gg-scm.io/pkg/git/githash"
 	"gg-scm.io/pkg/git/object"
+	"gg-scm.io/tool/internal/dateformat"
 	"gg-scm.io/tool/internal/flag"
 	"gg-scm.io/tool/internal/repodb"
+	"gg-scm.io/tool/internal/template"
+	"gg-scm.io/tool/internal/terminal"
 	"zombiezen.com/go/sqlite"
 	"zombiezen.com/go/sqlite/sqlitex"
 )
@@ -32,25 +38,66 @@ import (
 const logSynopsis = "show revision history of entire repository or files"
 
 type logFlags struct {
+	commitURL   string // prefix of forge commit URLs, if linking
+	date        dateformat.Style
+	diffRegex   string
 	follow      bool
 	followFirst bool
 	graph       bool
+	merges      bool
+	noMerges    bool
+	pickaxe     string
 	rev         []string
 	reverse     bool
-	stat        bool
+	// showSignature adds the signature and signer template keywords.
+	showSignature bool
+	stat          bool
 }
 
 func log(ctx context.Context, cc *cmdContext, args []string) error {
 	f := flag.NewFlagSet(true, "gg log [OPTION [...]] [FILE]", logSynopsis+`
 
-aliases: history`)
+aliases: history
+
+	`+"`-S`"+` and `+"`--diff-regex`"+` search the changes in each commit
+	rather than the commit messages. `+"`-S TEXT`"+` shows commits that
+	change the number of occurrences of TEXT, so it finds the commits
+	that introduced or removed it. `+"`--diff-regex=REGEX`"+` shows commits
+	with an added or removed line that matches REGEX. Unless `+"`-r`"+` is
+	given, all branches are searched.
+
+	`+"`-r`"+` may be given multiple times to show several revisions or
+	ranges. `+revsetHelp+`
+
+	`+"`-T`"+` formats each commit with a template instead of the default
+	output. Templates are text with keywords in braces, optionally
+	followed by filters: `+"`gg log -T '{node|short} {desc|firstline}\\n'`"+`.
+	Keywords are node, parents, p1node, p2node, author, date, committer,
+	commitdate, desc, branches, and tags. Filters are short, firstline,
+	strip, upper, lower, email, person, user, tabindent, age, date,
+	isodate, isodatesec, localdate, rfc3339date, rfc822date, shortdate,
+	unixtime, count, and join.
+
+	`+"`--show-signature`"+` verifies each commit's signature and shows
+	whether it is good, bad, or unknown along with the signer. With
+	`+"`-T`"+`, it adds the signature and signer keywords.`)
 	flags := new(logFlags)
+	date := f.String("date", "", "date display `style`: 'default', 'relative', 'iso', or 'local' (defaults to gg.dateFormat)")
 	f.BoolVar(&flags.follow, "follow", false, "follow file history across copies and renames")
+	f.StringVar(&flags.diffRegex, "diff-regex", "", "show commits whose added or removed lines match `regex`")
+	forgeLinks := f.Bool("forge-links", false, "link commit hashes to the remote's web view when writing to a terminal")
 	f.BoolVar(&flags.followFirst, "follow-first", false, "only follow the first parent of merge commits")
+	f.Alias("follow-first", "first-parent")
 	f.BoolVar(&flags.graph, "graph", false, "show the revision DAG")
 	f.Alias("graph", "G")
-	f.MultiStringVar(&flags.rev, "r", "show the specified `rev`ision or range")
+	f.BoolVar(&flags.merges, "merges", false, "show only merge commits")
+	f.BoolVar(&flags.noMerges, "no-merges", false, "do not show merge commits")
+	f.MultiStringVar(&flags.rev, "r", "show the specified `rev`ision, range, or revision set")
+	f.StringVar(&flags.pickaxe, "S", "", "show commits that add or remove `text`")
+	templateFlag := f.String("T", "", "display each commit using the given `template`")
+	f.Alias("T", "template")
 	f.BoolVar(&flags.reverse, "reverse", false, "reverse order of commits")
+	f.BoolVar(&flags.showSignature, "show-signature", false, "verify and show the signature of each commit")
 	f.BoolVar(&flags.stat, "stat", false, "include diffstat-style summary of each commit")
 	if err := f.Parse(args); flag.IsHelp(err) {
 		f.Help(cc.stdout)
@@ -61,13 +108,96 @@ aliases: history`)
 	if f.NArg() > 1 {
 		return usagef("only one file allowed")
 	}
+	if flags.merges && flags.noMerges {
+		return usagef("can't pass both --merges and --no-merges")
+	}
+	if flags.pickaxe != "" && flags.diffRegex != "" {
+		return usagef("can't pass both -S and --diff-regex")
+	}
+	var tmpl *template.Template
+	if *templateFlag != "" {
+		if flags.stat {
+			return usagef("can't pass both -T and --stat")
+		}
+		if *forgeLinks {
+			return usagef("can't pass both -T and --forge-links")
+		}
+		if flags.graph && flags.reverse {
+			return usagef("can't pass both --graph and --reverse")
+		}
+		var err error
+		tmpl, err = template.Parse(*templateFlag)
+		if err != nil {
+			return usagef("-T: %v", err)
+		}
+	}
+	if flags.showSignature && tmpl == nil {
+		if flags.stat {
+			return usagef("can't pass both --show-signature and --stat")
+		}
+		if *forgeLinks {
+			return usagef("can't pass both --show-signature and --forge-links")
+		}
+		if flags.graph && flags.reverse {
+			return usagef("can't pass both --graph and --reverse")
+		}
+		var err error
+		tmpl, err = template.Parse(signatureLogTemplate)
+		if err != nil {
+			return err
+		}
+	}
+	cfg, err := cc.git.ReadConfig(ctx)
+	if err != nil {
+		return err
+	}
+	flags.date, err = dateStyle(cfg, *date)
+	if err != nil {
+		return err
+	}
+	if *forgeLinks {
+		gcfg, err := readGGConfig(ctx, cc.git)
+		if err != nil {
+			return err
+		}
+		pages := newForgeWebPages(gcfg, cfg.Value("remote."+defaultForgeRemote(cfg)+".url"))
+		if pages == nil {
+			return errors.New("--forge-links: remote is not hosted on a known forge")
+		}
+		// Links are only useful on a terminal. Redirected output keeps the
+		// plain commit hashes so that scripts don't see escape sequences.
+		if cc.isTerminal() {
+			flags.commitURL = pages.commitPrefix()
+		}
+	}
+	if len(flags.rev) == 0 {
+		unborn, err := unbornBranch(ctx, cc.git)
+		if err != nil {
+			return err
+		}
+		if unborn != "" {
+			refs, err := cc.git.ListRefs(ctx)
+			if err != nil {
+				return err
+			}
+			if len(refs) == 0 {
+				fmt.Fprintf(cc.stderr, "gg: %s has no commits yet\n", unborn)
+				return nil
+			}
+		}
+	}
 	file := f.Arg(0)
-	if file != "" || flags.followFirst || flags.graph || flags.stat {
+	if tmpl != nil {
+		return logWithTemplate(ctx, cc, flags, tmpl, file)
+	}
+	if file != "" || flags.followFirst || flags.graph || flags.stat || flags.merges || flags.noMerges ||
+		flags.pickaxe != "" || flags.diffRegex != "" || hasRevsetExpression(flags.rev) {
 		// If any unsupported options are given, fall back to `git log`.
 		return logWithGit(ctx, cc, flags, file)
 	}
 
-	dir, err := cc.git.GitDir(ctx)
+	// The database is shared by all of the repository's working trees.
+	dir, err := cc.git.CommonDir(ctx)
 	if err != nil {
 		return err
 	}
@@ -82,9 +212,48 @@ aliases: history`)
 	}
 }
 
+// signatureLogTemplate is the template used for --show-signature when -T
+// is not given.
+const signatureLogTemplate = "commit {node}\n" +
+	"Author:    {author}\n" +
+	"Date:      {date}\n" +
+	"Signature: {signature}\n" +
+	"\n" +
+	"\t{desc|tabindent}\n\n"
+
+// dateStyle returns the date display style named by a --date flag,
+// falling back to the gg.dateFormat configuration setting.
+func dateStyle(cfg *git.Config, flagValue string) (dateformat.Style, error) {
+	if flagValue != "" {
+		style, err := dateformat.Parse(flagValue)
+		if err != nil {
+			return dateformat.Default, usagef("--date: %v", err)
+		}
+		return style, nil
+	}
+	style, err := dateformat.Parse(cfg.Value("gg.dateFormat"))
+	if err != nil {
+		return dateformat.Default, fmt.Errorf("gg.dateFormat: %w", err)
+	}
+	return style, nil
+}
+
 func logWithGit(ctx context.Context, cc *cmdContext, flags *logFlags, file string) error {
 	var logArgs []string
 	logArgs = append(logArgs, "log", "--decorate=auto", "--date-order")
+	if flags.date != dateformat.Default {
+		logArgs = append(logArgs, "--date="+flags.date.GitArg())
+	}
+	if flags.commitURL != "" {
+		// Mirror the default "medium" format, but wrap the hash in an
+		// OSC 8 hyperlink.
+		link := strings.ReplaceAll(flags.commitURL, "%", "%%")
+		logArgs = append(logArgs, "--format="+
+			`%C(auto,yellow)commit %x1b]8;;`+link+`%H%x1b\%H%x1b]8;;%x1b\%C(auto,reset)%C(auto)%d%n`+
+			`Author: %an <%ae>%n`+
+			`Date:   %ad%n%n`+
+			`%w(0,4,4)%B`)
+	}
 	if flags.follow {
 		logArgs = append(logArgs, "--follow")
 	}
@@ -94,21 +263,32 @@ func logWithGit(ctx context.Context, cc *cmdContext, flags *logFlags, file strin
 	if flags.graph {
 		logArgs = append(logArgs, "--graph")
 	}
+	if flags.merges {
+		logArgs = append(logArgs, "--merges")
+	}
+	if flags.noMerges {
+		logArgs = append(logArgs, "--no-merges")
+	}
+	if flags.pickaxe != "" {
+		logArgs = append(logArgs, "-S"+flags.pickaxe)
+	}
+	if flags.diffRegex != "" {
+		logArgs = append(logArgs, "-G"+flags.diffRegex)
+	}
 	if flags.reverse {
 		logArgs = append(logArgs, "--reverse")
 	}
 	if flags.stat {
 		logArgs = append(logArgs, "--stat")
 	}
-	for _, r := range flags.rev {
-		if strings.HasPrefix(r, "-") {
-			return usagef("revisions must not start with '-'")
-		}
-	}
 	if len(flags.rev) == 0 {
 		logArgs = append(logArgs, "--all")
 	} else {
-		logArgs = append(logArgs, flags.rev...)
+		revArgs, err := revListArgs(ctx, cc.git, flags.rev)
+		if err != nil {
+			return err
+		}
+		logArgs = append(logArgs, revArgs...)
 	}
 	logArgs = append(logArgs, "--")
 	if file != "" {
@@ -171,6 +351,8 @@ func logWithDB(ctx context.Context, cc *cmdContext, flags *logFlags, dir string,
 	}
 	// TODO(soon): Remove duplicates.
 
+	now := time.Now()
+
 	for _, revno := range revnos {
 		buf := new(bytes.Buffer)
 		err := sqlitex.ExecFS(db, sqlFiles, "log.sql", &sqlitex.ExecOptions{
@@ -192,7 +374,11 @@ func logWithDB(ctx context.Context, cc *cmdContext, flags *logFlags, dir string,
 				}
 
 				buf.Reset()
-				fmt.Fprintf(buf, "\x1b[33mcommit:      %d:%x\x1b[0m\n", revno, id[:6])
+				label := fmt.Sprintf("%d:%x", revno, id[:6])
+				if flags.commitURL != "" {
+					label = terminal.Hyperlink(flags.commitURL+id.String(), label)
+				}
+				fmt.Fprintf(buf, "\x1b[33mcommit:      %s\x1b[0m\n", label)
 				err = sqlitex.ExecFS(db, sqlFiles, "log_labels.sql", &sqlitex.ExecOptions{
 					Named: map[string]interface{}{
 						":revno": revno,
@@ -217,7 +403,7 @@ func logWithDB(ctx context.Context, cc *cmdContext, flags *logFlags, dir string,
 				}
 				// TODO(now): labels
 				fmt.Fprintf(buf, "author:      %s\n", author)
-				fmt.Fprintf(buf, "date:        %s\n", authorDate.Format("Mon Jan 02 15:04:05 2006 -0700"))
+				fmt.Fprintf(buf, "date:        %s\n", flags.date.Format(authorDate, now))
 				fmt.Fprintf(buf, "summary:     %s\n", summary)
 				buf.WriteString("\n")
 				if _, err := cc.stdout.Write(buf.Bytes()); err != nil {
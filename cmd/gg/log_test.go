@@ -57,3 +57,299 @@ func TestLog(t *testing.T) {
 		t.Errorf("log does not contain either %q or %q. Output:\n%s", hex, wantMsg, out)
 	}
 }
+
+func TestLog_Merges(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	env, err := newTestEnv(ctx, t)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := env.initRepoWithHistory(ctx, "."); err != nil {
+		t.Fatal(err)
+	}
+	if err := env.git.NewBranch(ctx, "feature", git.BranchOptions{Checkout: true}); err != nil {
+		t.Fatal(err)
+	}
+	if err := env.root.Apply(filesystem.Write("foo.txt", dummyContent)); err != nil {
+		t.Fatal(err)
+	}
+	if err := env.addFiles(ctx, "foo.txt"); err != nil {
+		t.Fatal(err)
+	}
+	const featureMsg = "Add feature"
+	if err := env.git.Commit(ctx, featureMsg, git.CommitOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := env.git.CheckoutBranch(ctx, "main", git.CheckoutOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	const mergeMsg = "Merge the feature"
+	if err := env.git.Run(ctx, "merge", "--no-ff", "-m", mergeMsg, "feature"); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		flag      string
+		want      string
+		doNotWant string
+	}{
+		{flag: "--merges", want: mergeMsg, doNotWant: featureMsg},
+		{flag: "--no-merges", want: featureMsg, doNotWant: mergeMsg},
+		{flag: "--first-parent", want: mergeMsg, doNotWant: featureMsg},
+	}
+	for _, test := range tests {
+		out, err := env.gg(ctx, env.root.String(), "log", test.flag, "-r", "main")
+		if err != nil {
+			t.Errorf("gg log %s: %v", test.flag, err)
+			continue
+		}
+		if !bytes.Contains(out, []byte(test.want)) {
+			t.Errorf("gg log %s does not contain %q. Output:\n%s", test.flag, test.want, out)
+		}
+		if bytes.Contains(out, []byte(test.doNotWant)) {
+			t.Errorf("gg log %s contains %q. Output:\n%s", test.flag, test.doNotWant, out)
+		}
+	}
+}
+
+func TestLog_ForgeLinks(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	env, err := newTestEnv(ctx, t)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := env.initRepoWithHistory(ctx, "."); err != nil {
+		t.Fatal(err)
+	}
+	if err := env.git.Run(ctx, "remote", "add", "origin", "https://github.com/example/foo.git"); err != nil {
+		t.Fatal(err)
+	}
+	head, err := env.git.Head(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Output is not a terminal, so no links should be written.
+	out, err := env.gg(ctx, env.root.String(), "log", "--forge-links", "-r", "HEAD")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(out, []byte("\x1b]8;")) {
+		t.Errorf("output contains a hyperlink when not writing to a terminal. Output:\n%q", out)
+	}
+	if !bytes.Contains(out, []byte(head.Commit.String())) {
+		t.Errorf("output does not contain commit hash %v. Output:\n%q", head.Commit, out)
+	}
+
+	if err := env.git.Run(ctx, "remote", "set-url", "origin", "https://example.com/foo.git"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := env.gg(ctx, env.root.String(), "log", "--forge-links", "-r", "HEAD"); err == nil {
+		t.Error("gg log --forge-links with an unknown forge did not return an error")
+	}
+}
+
+func TestLog_Pickaxe(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	env, err := newTestEnv(ctx, t)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := env.initRepoWithHistory(ctx, "."); err != nil {
+		t.Fatal(err)
+	}
+	if err := env.git.NewBranch(ctx, "feature", git.BranchOptions{Checkout: true}); err != nil {
+		t.Fatal(err)
+	}
+	if err := env.root.Apply(filesystem.Write("foo.txt", "haystack\nneedle\n")); err != nil {
+		t.Fatal(err)
+	}
+	if err := env.addFiles(ctx, "foo.txt"); err != nil {
+		t.Fatal(err)
+	}
+	const needleMsg = "Add the needle"
+	if err := env.git.Commit(ctx, needleMsg, git.CommitOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := env.git.CheckoutBranch(ctx, "main", git.CheckoutOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := env.root.Apply(filesystem.Write("bar.txt", "haystack\n")); err != nil {
+		t.Fatal(err)
+	}
+	if err := env.addFiles(ctx, "bar.txt"); err != nil {
+		t.Fatal(err)
+	}
+	const haystackMsg = "Add more hay"
+	if err := env.git.Commit(ctx, haystackMsg, git.CommitOptions{}); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, args := range [][]string{{"-S", "needle"}, {"--diff-regex=^nee+dle$"}} {
+		out, err := env.gg(ctx, env.root.String(), append([]string{"log"}, args...)...)
+		if err != nil {
+			t.Errorf("gg log %q: %v", args, err)
+			continue
+		}
+		if !bytes.Contains(out, []byte(needleMsg)) {
+			t.Errorf("gg log %q does not contain %q. Output:\n%s", args, needleMsg, out)
+		}
+		if bytes.Contains(out, []byte(haystackMsg)) {
+			t.Errorf("gg log %q contains %q. Output:\n%s", args, haystackMsg, out)
+		}
+	}
+}
+
+func TestLog_Template(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	env, err := newTestEnv(ctx, t)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := env.initEmptyRepo(ctx, "."); err != nil {
+		t.Fatal(err)
+	}
+	for _, msg := range []string{"First post!!", "Second post"} {
+		if err := env.root.Apply(filesystem.Write("foo.txt", msg)); err != nil {
+			t.Fatal(err)
+		}
+		if err := env.addFiles(ctx, "foo.txt"); err != nil {
+			t.Fatal(err)
+		}
+		if err := env.git.Commit(ctx, msg+"\n\nMore details.", git.CommitOptions{}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	rev, err := env.git.Head(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	out, err := env.gg(ctx, env.root.String(), "log", "-T", `{node|short} {desc|firstline} [{branches}]\n`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := rev.Commit.String()[:12] + " Second post [main]\n"
+	if lines := bytes.SplitAfter(out, []byte("\n")); len(lines) != 3 || string(lines[0]) != want {
+		t.Errorf("log -T output:\n%s\nwant first line %q", out, want)
+	}
+
+	out, err = env.gg(ctx, env.root.String(), "log", "--graph", "-T", `{desc|firstline}\n`, "foo.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	const wantGraph = "@  Second post\n" +
+		"o  First post!!\n"
+	if string(out) != wantGraph {
+		t.Errorf("log --graph -T output:\n%s\nwant:\n%s", out, wantGraph)
+	}
+
+	if _, err := env.gg(ctx, env.root.String(), "log", "-T", "{bogus|filter}"); !isUsage(err) {
+		t.Errorf("log -T with unknown filter error = %v; want usage error", err)
+	}
+}
+
+func TestLog_Revset(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	env, err := newTestEnv(ctx, t)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := env.initEmptyRepo(ctx, "."); err != nil {
+		t.Fatal(err)
+	}
+	for _, msg := range []string{"First", "Second", "Third"} {
+		if err := env.root.Apply(filesystem.Write("foo.txt", msg)); err != nil {
+			t.Fatal(err)
+		}
+		if err := env.addFiles(ctx, "foo.txt"); err != nil {
+			t.Fatal(err)
+		}
+		if err := env.git.Commit(ctx, msg, git.CommitOptions{}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		revset string
+		want   string
+	}{
+		{"ancestors(HEAD~1)", "Second\nFirst\n"},
+		{"::HEAD~1", "Second\nFirst\n"},
+		{"heads()", "Third\n"},
+		{"author(nobody-by-this-name)", ""},
+	}
+	for _, test := range tests {
+		out, err := env.gg(ctx, env.root.String(), "log", "-r", test.revset, "-T", `{desc|firstline}\n`)
+		if err != nil {
+			t.Errorf("log -r %q: %v", test.revset, err)
+			continue
+		}
+		if string(out) != test.want {
+			t.Errorf("log -r %q output:\n%s\nwant:\n%s", test.revset, out, test.want)
+		}
+	}
+
+	if _, err := env.gg(ctx, env.root.String(), "log", "-r", "heads()", "-r", "main"); !isUsage(err) {
+		t.Errorf("log with revset and another -r error = %v; want usage error", err)
+	}
+	if _, err := env.gg(ctx, env.root.String(), "log", "-r", "bogus::heads()"); !isUsage(err) {
+		t.Errorf("log with invalid revset error = %v; want usage error", err)
+	}
+}
+
+func TestLogGraph(t *testing.T) {
+	merge := git.Hash{1}
+	left := git.Hash{2}
+	right := git.Hash{3}
+	root := git.Hash{4}
+	commits := []struct {
+		hash    git.Hash
+		parents []git.Hash
+		text    string
+	}{
+		{hash: merge, parents: []git.Hash{left, right}, text: "merge\n"},
+		{hash: right, parents: []git.Hash{root}, text: "right\n"},
+		{hash: left, parents: []git.Hash{root}, text: "left\nsecond line\n"},
+		{hash: root, text: "root\n"},
+	}
+	buf := new(bytes.Buffer)
+	graph := new(logGraph)
+	for _, c := range commits {
+		graph.write(buf, c.hash, c.parents, 'o', c.text)
+	}
+	const want = "o    merge\n" +
+		"|\\\n" +
+		"| o  right\n" +
+		"o |  left\n" +
+		"|/   second line\n" +
+		"o  root\n"
+	if got := buf.String(); got != want {
+		t.Errorf("graph:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestLog_EmptyRepo(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	env, err := newTestEnv(ctx, t)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := env.initEmptyRepo(ctx, "."); err != nil {
+		t.Fatal(err)
+	}
+
+	out, err := env.gg(ctx, env.root.String(), "log")
+	if err != nil {
+		t.Error(err)
+	}
+	if len(out) > 0 {
+		t.Errorf("stdout = %q; want \"\"", out)
+	}
+}
@@ -0,0 +1,409 @@
+// Copyright 2021 The gg Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"gg-scm.io/pkg/git"
+	"gg-scm.io/pkg/git/object"
+	"gg-scm.io/tool/internal/template"
+)
+
+// logWithTemplate prints the commits selected by flags, formatting each one
+// with tmpl.
+func logWithTemplate(ctx context.Context, cc *cmdContext, flags *logFlags, tmpl *template.Template, file string) error {
+	entries, err := listLogEntries(ctx, cc.git, flags, file)
+	if err != nil {
+		return err
+	}
+	commits, err := readCommits(ctx, cc, entries)
+	if err != nil {
+		return err
+	}
+	var sigs map[git.Hash]commitSignature
+	if flags.showSignature {
+		hashes := make([]git.Hash, 0, len(entries))
+		for _, ent := range entries {
+			hashes = append(hashes, ent.hash)
+		}
+		sigs, err = verifyCommitSignatures(ctx, cc, hashes)
+		if err != nil {
+			return err
+		}
+	}
+	refs, err := cc.git.ListRefs(ctx)
+	if err != nil {
+		return err
+	}
+	labels := make(map[git.Hash][]git.Ref)
+	for ref, hash := range refs {
+		labels[hash] = append(labels[hash], ref)
+	}
+	var head git.Hash
+	if rev, err := cc.git.Head(ctx); err == nil {
+		head = rev.Commit
+	}
+
+	var graph *logGraph
+	shown := make(map[git.Hash]bool, len(entries))
+	if flags.graph {
+		graph = new(logGraph)
+		for _, ent := range entries {
+			shown[ent.hash] = true
+		}
+	}
+	tctx := &template.Context{
+		Now:       time.Now(),
+		DateStyle: flags.date,
+	}
+	out := bufio.NewWriter(cc.stdout)
+	buf := new(bytes.Buffer)
+	for _, ent := range entries {
+		tctx.Keywords = commitKeywords(ent.hash, commits[ent.hash], labels[ent.hash])
+		if sigs != nil {
+			sig := sigs[ent.hash]
+			tctx.Keywords["signature"] = sig.String()
+			tctx.Keywords["signer"] = sig.signer
+		}
+		buf.Reset()
+		if err := tmpl.Execute(buf, tctx); err != nil {
+			return err
+		}
+		if graph == nil {
+			out.Write(buf.Bytes())
+			continue
+		}
+		node := 'o'
+		if ent.hash == head {
+			node = '@'
+		}
+		// Only draw edges to commits that are part of the output.
+		var parents []git.Hash
+		for _, p := range ent.parents {
+			if shown[p] {
+				parents = append(parents, p)
+			}
+		}
+		graph.write(out, ent.hash, parents, node, buf.String())
+	}
+	return out.Flush()
+}
+
+// A logEntry is a commit in the output of logWithTemplate.
+type logEntry struct {
+	hash git.Hash
+	// parents is the list of the commit's parents after Git's history
+	// simplification.
+	parents []git.Hash
+}
+
+// listLogEntries returns the commits that match the log flags in display order.
+func listLogEntries(ctx context.Context, g *git.Git, flags *logFlags, file string) ([]logEntry, error) {
+	logArgs := []string{"log", "--date-order", "--parents", "--format=%H %P"}
+	if flags.follow {
+		logArgs = append(logArgs, "--follow")
+	}
+	if flags.followFirst {
+		logArgs = append(logArgs, "--first-parent")
+	}
+	if flags.merges {
+		logArgs = append(logArgs, "--merges")
+	}
+	if flags.noMerges {
+		logArgs = append(logArgs, "--no-merges")
+	}
+	if flags.pickaxe != "" {
+		logArgs = append(logArgs, "-S"+flags.pickaxe)
+	}
+	if flags.diffRegex != "" {
+		logArgs = append(logArgs, "-G"+flags.diffRegex)
+	}
+	if flags.reverse {
+		logArgs = append(logArgs, "--reverse")
+	}
+	if len(flags.rev) == 0 {
+		logArgs = append(logArgs, "--all")
+	} else {
+		revArgs, err := revListArgs(ctx, g, flags.rev)
+		if err != nil {
+			return nil, err
+		}
+		logArgs = append(logArgs, revArgs...)
+	}
+	logArgs = append(logArgs, "--")
+	if file != "" {
+		logArgs = append(logArgs, file)
+	}
+	out, err := g.Output(ctx, logArgs...)
+	if err != nil {
+		return nil, err
+	}
+	var entries []logEntry
+	for _, line := range strings.Split(strings.TrimSuffix(out, "\n"), "\n") {
+		if line == "" {
+			continue
+		}
+		fields := strings.Fields(line)
+		var ent logEntry
+		for i, f := range fields {
+			h, err := git.ParseHash(f)
+			if err != nil {
+				return nil, fmt.Errorf("log: %w", err)
+			}
+			if i == 0 {
+				ent.hash = h
+			} else {
+				ent.parents = append(ent.parents, h)
+			}
+		}
+		entries = append(entries, ent)
+	}
+	return entries, nil
+}
+
+// readCommits reads the commit objects for the given entries.
+func readCommits(ctx context.Context, cc *cmdContext, entries []logEntry) (map[git.Hash]*object.Commit, error) {
+	commits := make(map[git.Hash]*object.Commit, len(entries))
+	if len(entries) == 0 {
+		return commits, nil
+	}
+	stdin := new(strings.Builder)
+	for _, ent := range entries {
+		stdin.WriteString(ent.hash.String())
+		stdin.WriteString("\n")
+	}
+	stdout := new(bytes.Buffer)
+	stderr := new(bytes.Buffer)
+	err := cc.git.Runner().RunGit(ctx, &git.Invocation{
+		Args:   []string{"cat-file", "--batch"},
+		Dir:    cc.dir,
+		Stdin:  strings.NewReader(stdin.String()),
+		Stdout: stdout,
+		Stderr: stderr,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("read commits: %w: %s", err, bytes.TrimSpace(stderr.Bytes()))
+	}
+	r := bufio.NewReader(stdout)
+	for range entries {
+		header, err := r.ReadString('\n')
+		if err != nil {
+			return nil, fmt.Errorf("read commits: %w", err)
+		}
+		fields := strings.Fields(header)
+		if len(fields) != 3 || fields[1] != "commit" {
+			return nil, fmt.Errorf("read commits: unexpected object %q", strings.TrimSpace(header))
+		}
+		h, err := git.ParseHash(fields[0])
+		if err != nil {
+			return nil, fmt.Errorf("read commits: %w", err)
+		}
+		size, err := strconv.Atoi(fields[2])
+		if err != nil {
+			return nil, fmt.Errorf("read commits: object %v: %w", h, err)
+		}
+		data := make([]byte, size+1) // includes trailing LF
+		if _, err := io.ReadFull(r, data); err != nil {
+			return nil, fmt.Errorf("read commits: object %v: %w", h, err)
+		}
+		c, err := object.ParseCommit(data[:size])
+		if err != nil {
+			return nil, fmt.Errorf("read commits: object %v: %w", h, err)
+		}
+		commits[h] = c
+	}
+	return commits, nil
+}
+
+// commitKeywords returns the template keywords for a commit.
+func commitKeywords(hash git.Hash, c *object.Commit, labels []git.Ref) map[string]interface{} {
+	parents := make([]string, 0, len(c.Parents))
+	for _, p := range c.Parents {
+		parents = append(parents, p.String())
+	}
+	p1, p2 := "", ""
+	if len(parents) > 0 {
+		p1 = parents[0]
+	}
+	if len(parents) > 1 {
+		p2 = parents[1]
+	}
+	branches := []string{}
+	tags := []string{}
+	for _, ref := range labels {
+		if b := ref.Branch(); b != "" {
+			branches = append(branches, b)
+		} else if t := ref.Tag(); t != "" {
+			tags = append(tags, t)
+		}
+	}
+	sort.Strings(branches)
+	sort.Strings(tags)
+	return map[string]interface{}{
+		"node":       hash.String(),
+		"parents":    parents,
+		"p1node":     p1,
+		"p2node":     p2,
+		"author":     string(c.Author),
+		"date":       c.AuthorTime,
+		"committer":  string(c.Committer),
+		"commitdate": c.CommitTime,
+		"desc":       strings.TrimRight(c.Message, "\n"),
+		"branches":   branches,
+		"tags":       tags,
+	}
+}
+
+// logGraph draws an ASCII graph of the commit DAG alongside log output.
+// Commits must be written in an order where children precede their parents.
+type logGraph struct {
+	// columns holds the commits expected in each column of the graph.
+	columns []git.Hash
+}
+
+// write writes the graph lines for a commit with the given text.
+func (lg *logGraph) write(w io.Writer, hash git.Hash, parents []git.Hash, node rune, text string) {
+	idx := -1
+	for i, c := range lg.columns {
+		if c == hash {
+			idx = i
+			break
+		}
+	}
+	if idx == -1 {
+		idx = len(lg.columns)
+		lg.columns = append(lg.columns, hash)
+	}
+	oldColumns := lg.columns
+
+	// Compute the columns after this commit. The commit's column is taken
+	// over by its first parent that is not already shown in another column.
+	// Other new parents are inserted to the right.
+	var edges []graphEdge
+	newColumns := make([]git.Hash, 0, len(oldColumns)+len(parents))
+	newColumns = append(newColumns, oldColumns[:idx]...)
+	var added []git.Hash
+	for _, p := range parents {
+		if indexHash(oldColumns, p) != -1 || indexHash(added, p) != -1 {
+			continue
+		}
+		added = append(added, p)
+	}
+	newColumns = append(newColumns, added...)
+	newColumns = append(newColumns, oldColumns[idx+1:]...)
+	for i, c := range oldColumns {
+		if i == idx {
+			continue
+		}
+		edges = append(edges, graphEdge{from: i, to: indexHash(newColumns, c)})
+	}
+	for _, p := range parents {
+		edges = append(edges, graphEdge{from: idx, to: indexHash(newColumns, p)})
+	}
+	lg.columns = newColumns
+
+	// Build graph lines.
+	width := 2 * len(oldColumns)
+	if n := 2 * len(newColumns); n > width {
+		width = n
+	}
+	nodeLine := []byte(strings.Repeat(" ", width))
+	for i := range oldColumns {
+		nodeLine[2*i] = '|'
+	}
+	graphLines := []string{string(nodeLine[:2*idx]) + string(node) + string(nodeLine[2*idx+1:])}
+	graphLines = append(graphLines, edgeLines(edges, width)...)
+	padding := []byte(strings.Repeat(" ", width))
+	for i := range newColumns {
+		padding[2*i] = '|'
+	}
+
+	textLines := strings.Split(strings.TrimSuffix(text, "\n"), "\n")
+	n := len(textLines)
+	if len(graphLines) > n {
+		n = len(graphLines)
+	}
+	for i := 0; i < n; i++ {
+		g := string(padding)
+		if i < len(graphLines) {
+			g = graphLines[i]
+		}
+		t := ""
+		if i < len(textLines) {
+			t = textLines[i]
+		}
+		io.WriteString(w, strings.TrimRight(g+" "+t, " ")+"\n")
+	}
+}
+
+// A graphEdge connects a column before a commit to a column after it.
+type graphEdge struct {
+	from, to int
+}
+
+// edgeLines returns the lines needed to draw the given edges.
+// Each line moves diagonal edges by one column.
+// No lines are returned if all edges are vertical.
+func edgeLines(edges []graphEdge, width int) []string {
+	pos := make([]int, len(edges))
+	done := true
+	for i, e := range edges {
+		pos[i] = 2 * e.from
+		if e.from != e.to {
+			done = false
+		}
+	}
+	var lines []string
+	for !done {
+		done = true
+		line := []byte(strings.Repeat(" ", width))
+		for i, e := range edges {
+			target := 2 * e.to
+			switch {
+			case pos[i] < target:
+				line[pos[i]+1] = '\\'
+				pos[i] += 2
+			case pos[i] > target:
+				line[pos[i]-1] = '/'
+				pos[i] -= 2
+			default:
+				line[pos[i]] = '|'
+			}
+			if pos[i] != target {
+				done = false
+			}
+		}
+		lines = append(lines, string(line))
+	}
+	return lines
+}
+
+func indexHash(list []git.Hash, h git.Hash) int {
+	for i := range list {
+		if list[i] == h {
+			return i
+		}
+	}
+	return -1
+}
@@ -21,6 +21,7 @@ import (
 	"bytes"
 	"context"
 	"embed"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
@@ -32,10 +33,12 @@ import (
 	"path/filepath"
 	"runtime"
 	"strings"
+	"time"
 
 	"gg-scm.io/pkg/git"
 	"gg-scm.io/tool/internal/flag"
 	"gg-scm.io/tool/internal/sigterm"
+	"gg-scm.io/tool/internal/terminal"
 )
 
 //go:embed *.sql
@@ -72,38 +75,14 @@ func main() {
 
 func run(ctx context.Context, pctx *processContext, args []string) error {
 	const synopsis = "gg [options] COMMAND [ARG [...]]"
-	const description = "Git with less typing\n\n" +
-		"basic commands:\n" +
-		"  add           " + addSynopsis + "\n" +
-		"  branch        " + branchSynopsis + "\n" +
-		"  cat           " + catSynopsis + "\n" +
-		"  clone         " + cloneSynopsis + "\n" +
-		"  commit        " + commitSynopsis + "\n" +
-		"  diff          " + diffSynopsis + "\n" +
-		"  identify      " + identifySynopsis + "\n" +
-		"  init          " + initSynopsis + "\n" +
-		"  log           " + logSynopsis + "\n" +
-		"  merge         " + mergeSynopsis + "\n" +
-		"  pull          " + pullSynopsis + "\n" +
-		"  push          " + pushSynopsis + "\n" +
-		"  remove        " + removeSynopsis + "\n" +
-		"  requestpull   " + requestPullSynopsis + "\n" +
-		"  revert        " + revertSynopsis + "\n" +
-		"  status        " + statusSynopsis + "\n" +
-		"  update        " + updateSynopsis + "\n" +
-		"\nadvanced commands:\n" +
-		"  backout       " + backoutSynopsis + "\n" +
-		"  evolve        " + evolveSynopsis + "\n" +
-		"  gerrithook    " + gerrithookSynopsis + "\n" +
-		"  github-login  " + gitHubLoginSynopsis + "\n" +
-		"  histedit      " + histeditSynopsis + "\n" +
-		"  mail          " + mailSynopsis + "\n" +
-		"  rebase        " + rebaseSynopsis + "\n" +
-		"  upstream      " + upstreamSynopsis
-
-	globalFlags := flag.NewFlagSet(false, synopsis, description)
+	globalFlags := flag.NewFlagSet(false, synopsis, globalDescription())
 	gitPath := globalFlags.String("git", "", "`path` to git executable")
+	ignoreDefaults := globalFlags.Bool("ignore-defaults", false, "ignore gg.defaults.* settings for the command")
+	lockTimeout := globalFlags.Int("lock-timeout", 30, "`seconds` to wait for another gg operation on the repository to finish")
+	noPager := globalFlags.Bool("no-pager", false, "do not pipe output into a pager")
+	noWait := globalFlags.Bool("no-wait", false, "fail immediately if another gg operation on the repository is in progress")
 	showArgs := globalFlags.Bool("show-git", false, "log git invocations")
+	userFlag := globalFlags.String("user", "", "`identity` (\"Name <email>\") to use as the author and committer of new commits")
 	versionFlag := globalFlags.Bool("version", false, "display version information")
 	if err := globalFlags.Parse(args); flag.IsHelp(err) {
 		globalFlags.Help(pctx.stdout)
@@ -119,13 +98,21 @@ func run(ctx context.Context, pctx *processContext, args []string) error {
 		var err error
 		*gitPath, err = pctx.lookPath("git")
 		if err != nil {
-			return fmt.Errorf("gg: %w", err)
+			return fmt.Errorf("gg: %w", &gitEnvError{err: err})
+		}
+	}
+	env := pctx.env
+	if *userFlag != "" {
+		var err error
+		env, err = identityEnv(pctx.env, *userFlag)
+		if err != nil {
+			return usagef("--user: %v", err)
 		}
 	}
 	opts := git.Options{
 		GitExe: *gitPath,
 		Dir:    pctx.dir,
-		Env:    pctx.env,
+		Env:    env,
 	}
 	if *showArgs {
 		opts.LogHook = func(_ context.Context, args []string) {
@@ -145,18 +132,72 @@ func run(ctx context.Context, pctx *processContext, args []string) error {
 			pctx.stderr.Write(buf.Bytes())
 		}
 	}
-	git, err := git.New(opts)
+	g, err := git.New(opts)
 	if err != nil {
 		return fmt.Errorf("gg: %w", err)
 	}
+	cmdArgs := globalFlags.Args()[1:]
+	config := &lazyConfig{g: g}
+	if !*versionFlag {
+		switch globalFlags.Arg(0) {
+		case "version", "doctor", "help":
+			// These commands report on the Git installation themselves.
+		default:
+			if _, err := checkGitVersion(ctx, g); err != nil {
+				return fmt.Errorf("gg: %w", err)
+			}
+		}
+		if !*ignoreDefaults && lookupCommand(globalFlags.Arg(0)) != nil {
+			cfg, err := config.get(ctx)
+			if err != nil {
+				return fmt.Errorf("gg: %w", err)
+			}
+			defaults, err := commandDefaults(cfg, globalFlags.Arg(0))
+			if err != nil {
+				return fmt.Errorf("gg: %w", err)
+			}
+			cmdArgs = append(defaults, cmdArgs...)
+		}
+	}
+	// Whether the command needs the lock depends on its arguments,
+	// including the ones from gg.defaults.
+	if cmd := lookupCommand(globalFlags.Arg(0)); !*versionFlag && cmd != nil && cmd.needsLock(cmdArgs) {
+		if *lockTimeout < 0 {
+			return usagef("--lock-timeout must not be negative")
+		}
+		timeout := time.Duration(*lockTimeout) * time.Second
+		if *noWait {
+			timeout = 0
+		}
+		lock, err := acquireRepoLock(ctx, opts, timeout)
+		if err != nil {
+			return fmt.Errorf("gg: %w", err)
+		}
+		if lock != nil {
+			defer func() {
+				if err := lock.release(); err != nil {
+					fmt.Fprintln(pctx.stderr, "gg:", err)
+				}
+			}()
+			// Git subprocesses (like hooks) need to see the lock too.
+			env = lock.env(env)
+			opts.Env = env
+			g, err = git.New(opts)
+			if err != nil {
+				return fmt.Errorf("gg: %w", err)
+			}
+		}
+	}
 	cc := &cmdContext{
-		dir:     pctx.dir,
-		xdgDirs: newXDGDirs(pctx.env),
-		git:     git,
+		dir:        pctx.dir,
+		env:        env,
+		xdgDirs:    newXDGDirs(pctx.env),
+		git:        g,
+		gitOptions: opts,
 		editor: &editor{
-			git:      git,
+			git:      g,
 			tempRoot: pctx.tempDir,
-			env:      pctx.env,
+			env:      env,
 			stdin:    pctx.stdin,
 			stdout:   pctx.stdout,
 			stderr:   pctx.stderr,
@@ -171,29 +212,127 @@ func run(ctx context.Context, pctx *processContext, args []string) error {
 		stderr:     pctx.stderr,
 	}
 	if *versionFlag {
-		if err := showVersion(ctx, cc); err != nil {
+		if err := showVersion(ctx, cc, nil); err != nil {
 			return fmt.Errorf("gg: %w", err)
 		}
 		return nil
 	}
-	err = dispatch(ctx, cc, globalFlags, globalFlags.Arg(0), globalFlags.Args()[1:])
+	var p *pager
+	if !*noPager && terminal.IsTerminal(pctx.stdout) {
+		cfg, err := config.get(ctx)
+		if err != nil {
+			return fmt.Errorf("gg: %w", err)
+		}
+		line, err := pagerCommand(ctx, g, cfg, env, globalFlags.Arg(0))
+		if err != nil {
+			return fmt.Errorf("gg: %w", err)
+		}
+		if line != "" {
+			p, err = startPager(g.Exe(), line, env, pctx.stdout, pctx.stderr)
+			if err != nil {
+				return fmt.Errorf("gg: %w", err)
+			}
+			cc.stdout = p.w
+			cc.pagerActive = true
+			cc.pagerTerminal = pctx.stdout
+		}
+	}
+	err = dispatch(ctx, cc, globalFlags, globalFlags.Arg(0), cmdArgs)
+	if p != nil {
+		quit := p.quit()
+		closeErr := p.close()
+		if quit {
+			// The user stopped reading output. Any error is likely from
+			// writing to the closed pager.
+			err = nil
+		} else if err == nil {
+			err = closeErr
+		}
+	}
 	if err != nil {
 		return fmt.Errorf("gg: %w", err)
 	}
 	return nil
 }
 
+// globalDescription returns the description shown by gg --help,
+// which lists the commands in the registry that have a synopsis.
+func globalDescription() string {
+	sb := new(strings.Builder)
+	sb.WriteString("Git with less typing\n")
+	for _, basic := range []bool{true, false} {
+		if basic {
+			sb.WriteString("\nbasic commands:")
+		} else {
+			sb.WriteString("\nadvanced commands:")
+		}
+		for _, cmd := range commands {
+			if cmd.synopsis == "" || cmd.basic != basic {
+				continue
+			}
+			if len(cmd.name) > 12 {
+				fmt.Fprintf(sb, "\n  %s\n%16s%s", cmd.name, "", cmd.synopsis)
+			} else {
+				fmt.Fprintf(sb, "\n  %-14s%s", cmd.name, cmd.synopsis)
+			}
+		}
+		sb.WriteString("\n")
+	}
+	return strings.TrimSuffix(sb.String(), "\n")
+}
+
 type cmdContext struct {
 	dir     string
+	env     []string // environment for subprocesses
 	xdgDirs *xdgDirs
 
 	git        *git.Git
+	gitOptions git.Options // options that git was created with
 	editor     *editor
 	httpClient *http.Client
 
 	stdin  io.Reader
 	stdout io.Writer
 	stderr io.Writer
+
+	// pagerActive is true if stdout is a pager that gg started
+	// because the original stdout was a terminal.
+	pagerActive bool
+	// pagerTerminal is the original stdout when pagerActive is true.
+	pagerTerminal io.Writer
+}
+
+// lazyConfig reads the Git configuration the first time it is needed, so
+// that startup doesn't pay for it unless a setting is consulted.
+type lazyConfig struct {
+	g   *git.Git
+	cfg *git.Config
+}
+
+func (lc *lazyConfig) get(ctx context.Context) (*git.Config, error) {
+	if lc.cfg == nil {
+		cfg, err := lc.g.ReadConfig(ctx)
+		if err != nil {
+			return nil, err
+		}
+		lc.cfg = cfg
+	}
+	return lc.cfg, nil
+}
+
+// isTerminal reports whether cc.stdout is displayed on a terminal,
+// either directly or through a pager.
+func (cc *cmdContext) isTerminal() bool {
+	return cc.pagerActive || terminal.IsTerminal(cc.stdout)
+}
+
+// supportsHyperlinks reports whether cc.stdout is displayed on a terminal
+// that shows OSC 8 hyperlinks, either directly or through a pager.
+func (cc *cmdContext) supportsHyperlinks() bool {
+	if cc.pagerActive {
+		return terminal.SupportsHyperlinks(cc.pagerTerminal, cc.env)
+	}
+	return terminal.SupportsHyperlinks(cc.stdout, cc.env)
 }
 
 func (cc *cmdContext) abs(path string) string {
@@ -208,17 +347,23 @@ func (cc *cmdContext) withDir(path string) *cmdContext {
 	*cc2 = *cc
 	cc2.dir = cc.abs(path)
 	cc2.git = cc.git.WithDir(cc2.dir)
+	cc2.gitOptions.Dir = cc2.dir
 	return cc2
 }
 
 func (cc *cmdContext) interactiveGit(ctx context.Context, args ...string) error {
-	err := cc.git.Runner().RunGit(ctx, &git.Invocation{
+	inv := &git.Invocation{
 		Dir:    cc.dir,
 		Args:   args,
 		Stdin:  cc.stdin,
 		Stdout: cc.stdout,
 		Stderr: cc.stderr,
-	})
+	}
+	if cc.pagerActive {
+		// Tell Git to keep using color, as it does for its own pager.
+		inv.Env = append(cc.env[:len(cc.env):len(cc.env)], "GIT_PAGER_IN_USE=true")
+	}
+	err := cc.git.Runner().RunGit(ctx, inv)
 	if err != nil {
 		return fmt.Errorf("git %s: %w", args[0], err)
 	}
@@ -226,82 +371,14 @@ func (cc *cmdContext) interactiveGit(ctx context.Context, args ...string) error
 }
 
 func dispatch(ctx context.Context, cc *cmdContext, globalFlags *flag.FlagSet, name string, args []string) error {
-	switch name {
-	case "add":
-		return add(ctx, cc, args)
-	case "addremove":
-		return addRemove(ctx, cc, args)
-	case "backout":
-		return backout(ctx, cc, args)
-	case "branch":
-		return branch(ctx, cc, args)
-	case "cat":
-		return cat(ctx, cc, args)
-	case "clone":
-		return clone(ctx, cc, args)
-	case "commit", "ci":
-		return commit(ctx, cc, args)
-	case "diff":
-		return diff(ctx, cc, args)
-	case "evolve":
-		return evolve(ctx, cc, args)
-	case "gerrithook":
-		return gerrithook(ctx, cc, args)
-	case "github-login":
-		return gitHubLogin(ctx, cc, args)
-	case "histedit":
-		return histedit(ctx, cc, args)
-	case "identify", "id":
-		return identify(ctx, cc, args)
-	case "init":
-		return init_(ctx, cc, args)
-	case "log", "history":
-		return log(ctx, cc, args)
-	case "mail":
-		return mail(ctx, cc, args)
-	case "merge":
-		return merge(ctx, cc, args)
-	case "pull":
-		return pull(ctx, cc, args)
-	case "push":
-		return push(ctx, cc, args)
-	case "remove", "rm":
-		return remove(ctx, cc, args)
-	case "rebase":
-		return rebase(ctx, cc, args)
-	case "requestpull", "pr":
-		return requestPull(ctx, cc, args)
-	case "revert":
-		return revert(ctx, cc, args)
-	case "status", "st", "check":
-		return status(ctx, cc, args)
-	case "update", "up", "checkout", "co":
-		return update(ctx, cc, args)
-	case "upstream":
-		return upstream(ctx, cc, args)
-	case "version":
-		return showVersion(ctx, cc)
-	case "help":
-		if len(args) == 0 {
-			globalFlags.Help(cc.stdout)
-			return nil
-		}
-		if len(args) > 1 || strings.HasPrefix(args[0], "-") {
-			return usagef("help [command]")
-		}
-		return dispatch(ctx, cc, globalFlags, args[0], []string{"--help"})
-	case "ez":
-		f := flag.NewFlagSet(true, "gg ez [-re=0]", "")
-		re := f.Bool("re", true, "rematch")
-		f.Parse(args)
-		if *re {
-			fmt.Fprintln(cc.stdout, "lol")
-		} else {
-			fmt.Fprintln(cc.stdout, ":(")
-		}
-		return nil
-	default:
+	cmd := lookupCommand(name)
+	switch {
+	case cmd == nil:
 		return usagef("unknown command %s", name)
+	case cmd.name == "help":
+		return help(ctx, cc, globalFlags, args)
+	default:
+		return cmd.run(ctx, cc, args)
 	}
 }
 
@@ -319,12 +396,50 @@ var (
 	buildTime = ""
 )
 
-func showVersion(ctx context.Context, cc *cmdContext) error {
+const versionSynopsis = "show version information"
+
+func showVersion(ctx context.Context, cc *cmdContext, args []string) error {
+	f := flag.NewFlagSet(true, "gg version [--check] [--check-update] [--json]", versionSynopsis+`
+
+	With `+"`--check`"+`, version also verifies that the Git executable
+	is supported by gg and exits with a non-zero status if it is not.
+
+	`+"`--check-update`"+` queries GitHub for the latest gg release and
+	reports whether it is newer than this build. Nothing is downloaded
+	or installed.
+
+	`+"`--json`"+` prints the version information as a JSON object with
+	the gg version, commit, Go version, and the Git version along with
+	the minimum version of Git that gg supports.`)
+	check := f.Bool("check", false, "verify that Git is installed and new enough")
+	checkUpdate := f.Bool("check-update", false, "check whether a newer release of gg is available")
+	jsonFlag := f.Bool("json", false, "print version information as JSON")
+	if err := f.Parse(args); flag.IsHelp(err) {
+		f.Help(cc.stdout)
+		return nil
+	} else if err != nil {
+		return usagef("%v", err)
+	}
+	if f.NArg() > 0 {
+		return usagef("version takes no arguments")
+	}
+
 	commit := buildCommit
 	localMods := strings.HasSuffix(buildCommit, "+")
 	if localMods {
 		commit = commit[:len(commit)-1]
 	}
+	var latest *ggRelease
+	if *checkUpdate {
+		var err error
+		latest, err = fetchLatestRelease(ctx, cc.httpClient)
+		if err != nil {
+			return err
+		}
+	}
+	if *jsonFlag {
+		return showVersionJSON(ctx, cc, commit, localMods, *check, latest)
+	}
 	var err error
 	switch {
 	case versionInfo != "" && buildTime != "":
@@ -349,14 +464,88 @@ func showVersion(ctx context.Context, cc *cmdContext) error {
 	if err != nil {
 		return err
 	}
-	out, err := cc.git.Output(ctx, "--version")
-	if err != nil {
+	if *check {
+		version, err := checkGitVersion(ctx, cc.git)
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintf(cc.stdout, "git: %v (%s), minimum supported version %v\n", version, cc.git.Exe(), minGitVersion)
+		if err != nil {
+			return err
+		}
+	} else {
+		out, err := cc.git.Output(ctx, "--version")
+		if err != nil {
+			return err
+		}
+		if _, err := io.WriteString(cc.stdout, out); err != nil {
+			return err
+		}
+	}
+	if latest != nil {
+		switch {
+		case versionInfo == "":
+			_, err = fmt.Fprintf(cc.stdout, "update: latest release is %s (%s)\n", latest.Version, latest.URL)
+		case isNewerRelease(versionInfo, latest.Version):
+			_, err = fmt.Fprintf(cc.stdout, "update: gg %s is available (%s)\n", latest.Version, latest.URL)
+		default:
+			_, err = fmt.Fprintf(cc.stdout, "update: gg is up to date (latest release is %s)\n", latest.Version)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// versionJSON is the output of `gg version --json`.
+type versionJSON struct {
+	Version            string `json:"version,omitempty"`
+	Commit             string `json:"commit,omitempty"`
+	LocalModifications bool   `json:"localModifications,omitempty"`
+	BuildTime          string `json:"buildTime,omitempty"`
+	Go                 string `json:"go"`
+	Platform           string `json:"platform"`
+	Git                struct {
+		Path           string `json:"path"`
+		Version        string `json:"version,omitempty"`
+		MinimumVersion string `json:"minimumVersion"`
+		Supported      bool   `json:"supported"`
+	} `json:"git"`
+	LatestRelease   *ggRelease `json:"latestRelease,omitempty"`
+	UpdateAvailable bool       `json:"updateAvailable,omitempty"`
+}
+
+func showVersionJSON(ctx context.Context, cc *cmdContext, commit string, localMods bool, check bool, latest *ggRelease) error {
+	v := &versionJSON{
+		Version:            versionInfo,
+		Commit:             commit,
+		LocalModifications: localMods,
+		BuildTime:          buildTime,
+		Go:                 runtime.Version(),
+		Platform:           runtime.GOOS + "/" + runtime.GOARCH,
+		LatestRelease:      latest,
+	}
+	v.Git.Path = cc.git.Exe()
+	v.Git.MinimumVersion = minGitVersion.String()
+	version, err := checkGitVersion(ctx, cc.git)
+	if err != nil && check {
 		return err
 	}
-	if _, err := io.WriteString(cc.stdout, out); err != nil {
+	if version != (gitVersion{}) {
+		v.Git.Version = version.String()
+	}
+	v.Git.Supported = err == nil
+	if latest != nil && versionInfo != "" {
+		v.UpdateAvailable = isNewerRelease(versionInfo, latest.Version)
+	}
+	out, err := json.MarshalIndent(v, "", "\t")
+	if err != nil {
 		return err
 	}
-	return nil
+	out = append(out, '\n')
+	_, err = cc.stdout.Write(out)
+	return err
 }
 
 func userAgentString() string {
@@ -423,6 +612,27 @@ type xdgDirs struct {
 	cacheHome  string
 }
 
+// identityEnv returns a copy of environ with the Git author and committer
+// environment variables set to the given "Name <email>" identity.
+func identityEnv(environ []string, ident string) ([]string, error) {
+	ident = strings.TrimSpace(ident)
+	if !isIdent(ident) {
+		return nil, fmt.Errorf("%q is not of the form \"Name <email>\"", ident)
+	}
+	i := strings.LastIndexByte(ident, '<')
+	name := strings.TrimSpace(ident[:i])
+	email := ident[i+1 : len(ident)-1]
+	newEnv := make([]string, 0, len(environ)+4)
+	newEnv = append(newEnv, environ...)
+	newEnv = append(newEnv,
+		"GIT_AUTHOR_NAME="+name,
+		"GIT_AUTHOR_EMAIL="+email,
+		"GIT_COMMITTER_NAME="+name,
+		"GIT_COMMITTER_EMAIL="+email,
+	)
+	return newEnv, nil
+}
+
 // newXDGDirs reads directory locations from the given environment variables.
 func newXDGDirs(environ []string) *xdgDirs {
 	x := &xdgDirs{
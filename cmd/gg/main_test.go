@@ -36,6 +36,45 @@ import (
 	"github.com/google/go-cmp/cmp/cmpopts"
 )
 
+func TestUserFlag(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	env, err := newTestEnv(ctx, t)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := env.initRepoWithHistory(ctx, "repo"); err != nil {
+		t.Fatal(err)
+	}
+	if err := env.root.Apply(filesystem.Write("repo/foo.txt", "Hello, World!\n")); err != nil {
+		t.Fatal(err)
+	}
+	if err := env.addFiles(ctx, "repo/foo.txt"); err != nil {
+		t.Fatal(err)
+	}
+	repoDir := env.root.FromSlash("repo")
+	if _, err := env.gg(ctx, repoDir, "--user=Build Bot <bot@example.com>", "commit", "-m", "automated"); err != nil {
+		t.Fatal(err)
+	}
+	info, err := env.git.WithDir(repoDir).CommitInfo(ctx, "HEAD")
+	if err != nil {
+		t.Fatal(err)
+	}
+	const want = "Build Bot <bot@example.com>"
+	if got := string(info.Author); got != want {
+		t.Errorf("author = %q; want %q", got, want)
+	}
+	if got := string(info.Committer); got != want {
+		t.Errorf("committer = %q; want %q", got, want)
+	}
+
+	if _, err := env.gg(ctx, repoDir, "--user=bot@example.com", "commit", "--amend", "-m", "bad"); err == nil {
+		t.Error("gg --user=bot@example.com did not return an error")
+	} else if !isUsage(err) {
+		t.Errorf("gg --user=bot@example.com returned non-usage error: %v", err)
+	}
+}
+
 func TestNewXDGDirs(t *testing.T) {
 	t.Parallel()
 	tests := []struct {
@@ -16,16 +16,36 @@ package main
 
 import (
 	"context"
+	"errors"
+	"fmt"
 
+	"gg-scm.io/pkg/git"
 	"gg-scm.io/tool/internal/flag"
+	"gg-scm.io/tool/internal/terminal"
 )
 
 const mergeSynopsis = "merge another revision into working directory"
 
 func merge(ctx context.Context, cc *cmdContext, args []string) error {
-	f := flag.NewFlagSet(true, "gg merge [[-r] REV]", mergeSynopsis)
+	f := flag.NewFlagSet(true, "gg merge [--preview] [-t TOOL] [[-r] REV]\n"+
+		"gg merge --abort", mergeSynopsis+`
+
+	Merge the given revision (defaults to the current branch's upstream)
+	into the working copy. If the merge succeeds without conflicts, the
+	merge is committed. Otherwise, the conflicted files are listed,
+	grouped by the kind of conflict. Resolve them, then run
+	`+"`gg commit`"+` to finish the merge, or run `+"`gg merge --abort`"+`
+	to return to the state before the merge.
+
+	`+"`-t`"+` launches `+"`git mergetool`"+` with the given tool to
+	resolve any conflicts. `+"`--preview`"+` lists the commits that would
+	be merged without merging them.`)
 	rev := f.String("r", "", "`rev`ision to merge")
 	abort := f.Bool("abort", false, "abort the ongoing merge")
+	preview := f.Bool("preview", false, "show the commits that would be merged without merging")
+	f.Alias("preview", "dry-run", "n")
+	tool := f.String("t", "", "resolve conflicts with the given merge `tool`")
+	f.Alias("t", "tool")
 	if err := f.Parse(args); flag.IsHelp(err) {
 		f.Help(cc.stdout)
 		return nil
@@ -33,22 +53,151 @@ func merge(ctx context.Context, cc *cmdContext, args []string) error {
 		return usagef("%v", err)
 	}
 	if *abort {
-		if f.NArg() != 0 || *rev != "" {
-			return usagef("cannot specify revision with --abort")
+		if f.NArg() != 0 || *rev != "" || *preview || *tool != "" {
+			return usagef("cannot specify other options with --abort")
 		}
 		return cc.git.AbortMerge(ctx)
 	}
 	if f.NArg() > 1 || (f.Arg(0) != "" && *rev != "") {
 		return usagef("must pass at most one revision to merge")
 	}
+	if *preview && *tool != "" {
+		return usagef("can't pass both --preview and -t")
+	}
 	if *rev == "" {
 		*rev = f.Arg(0)
 	}
 	if *rev == "" {
 		*rev = "@{upstream}"
 	}
-	if err := cc.git.Merge(ctx, []string{*rev}); err != nil {
+	if *preview {
+		return previewMerge(ctx, cc, *rev)
+	}
+	mergeErr := cc.git.Merge(ctx, []string{*rev})
+	if mergeErr == nil {
+		return nil
+	}
+	conflicts, err := findConflicts(ctx, cc.git, nil)
+	if err != nil || len(conflicts) == 0 {
+		return mergeErr
+	}
+	if err := writeConflictSummary(ctx, cc, conflicts); err != nil {
+		return err
+	}
+	if *tool == "" {
+		return errors.New("merge has conflicts; resolve them with gg resolve and run gg commit (or gg merge --abort)")
+	}
+	if err := cc.interactiveGit(ctx, "mergetool", "--tool="+*tool); err != nil {
+		return err
+	}
+	if remaining, err := findConflicts(ctx, cc.git, nil); err != nil {
+		return err
+	} else if len(remaining) > 0 {
+		return fmt.Errorf("%s still unresolved; see gg resolve -l", pluralize(len(remaining), "file", "files"))
+	}
+	fmt.Fprintln(cc.stderr, "gg: all conflicts resolved; run gg commit to finish the merge")
+	return nil
+}
+
+// previewMerge prints the commits that merging rev would bring into HEAD.
+func previewMerge(ctx context.Context, cc *cmdContext, rev string) error {
+	r, err := cc.git.ParseRev(ctx, rev)
+	if err != nil {
+		return err
+	}
+	commits, err := cc.git.Log(ctx, git.LogOptions{
+		Revs: []string{r.Commit.String(), "^" + git.Head.String()},
+	})
+	if err != nil {
+		return err
+	}
+	n := 0
+	for commits.Next() {
+		c := commits.CommitInfo()
+		if n == 0 {
+			fmt.Fprintf(cc.stdout, "commits to merge from %s:\n", rev)
+		}
+		fmt.Fprintf(cc.stdout, "%s %s\n", c.SHA1().Short(), c.Summary())
+		n++
+	}
+	if err := commits.Close(); err != nil {
+		return err
+	}
+	if n == 0 {
+		_, err := fmt.Fprintf(cc.stdout, "%s is already merged\n", rev)
 		return err
 	}
 	return nil
 }
+
+// conflictKinds lists the descriptions of the unmerged status codes in
+// the order that writeConflictSummary shows them.
+var conflictKinds = []struct {
+	code string
+	desc string
+}{
+	{"UU", "both modified"},
+	{"AA", "both added"},
+	{"UD", "deleted by them"},
+	{"DU", "deleted by us"},
+	{"UA", "added by them"},
+	{"AU", "added by us"},
+	{"DD", "both deleted"},
+}
+
+// describeConflict returns a description of an unmerged status code, like
+// "both modified".
+func describeConflict(code git.StatusCode) string {
+	for _, kind := range conflictKinds {
+		if string(code[:]) == kind.code {
+			return kind.desc
+		}
+	}
+	return "unmerged"
+}
+
+// writeConflictSummary prints the conflicted files grouped by kind of
+// conflict.
+func writeConflictSummary(ctx context.Context, cc *cmdContext, conflicts []git.StatusEntry) error {
+	cfg, err := cc.git.ReadConfig(ctx)
+	if err != nil {
+		return err
+	}
+	var color []byte
+	colorize, err := cfg.ColorBool("color.ggstatus", cc.isTerminal())
+	if err != nil {
+		fmt.Fprintln(cc.stderr, "gg:", err)
+	} else if colorize {
+		color, err = cfg.Color("color.ggstatus.unmerged", "blue")
+		if err != nil {
+			fmt.Fprintln(cc.stderr, "gg:", err)
+		}
+	}
+	byCode := make(map[string][]git.TopPath)
+	for _, ent := range conflicts {
+		code := string(ent.Code[:])
+		byCode[code] = append(byCode[code], ent.Name)
+	}
+	fmt.Fprintf(cc.stdout, "conflicts in %s:\n", pluralize(len(conflicts), "file", "files"))
+	for _, kind := range conflictKinds {
+		names := byCode[kind.code]
+		if len(names) == 0 {
+			continue
+		}
+		fmt.Fprintf(cc.stdout, "  %s:\n", kind.desc)
+		for _, name := range names {
+			if _, err := fmt.Fprintf(cc.stdout, "    %s%s", color, name); err != nil {
+				return err
+			}
+			if colorize {
+				if err := terminal.ResetTextStyle(cc.stdout); err != nil {
+					return err
+				}
+			}
+			if _, err := fmt.Fprintln(cc.stdout); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
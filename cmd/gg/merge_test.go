@@ -16,6 +16,7 @@ package main
 
 import (
 	"context"
+	"strings"
 	"testing"
 
 	"gg-scm.io/pkg/git"
@@ -172,6 +173,9 @@ func TestMerge_Conflict(t *testing.T) {
 	} else if isUsage(err) {
 		t.Errorf("merge returned usage error: %v", err)
 	}
+	if want := "  both modified:\n    foo.txt\n"; !strings.Contains(string(out), want) {
+		t.Errorf("merge output does not contain conflict summary %q", want)
+	}
 
 	// Verify that HEAD is still the upstream commit. gg should not create a new commit.
 	curr, err := env.git.Head(ctx)
@@ -200,3 +204,53 @@ func TestMerge_Conflict(t *testing.T) {
 			prettyCommit(feature, names))
 	}
 }
+
+func TestMerge_Preview(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	env, err := newTestEnv(ctx, t)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := env.initRepoWithHistory(ctx, "."); err != nil {
+		t.Fatal(err)
+	}
+	mainHead, err := env.git.Head(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := env.git.NewBranch(ctx, "feature", git.BranchOptions{Checkout: true}); err != nil {
+		t.Fatal(err)
+	}
+	if err := env.root.Apply(filesystem.Write("foo.txt", "feature content\n")); err != nil {
+		t.Fatal(err)
+	}
+	if err := env.addFiles(ctx, "foo.txt"); err != nil {
+		t.Fatal(err)
+	}
+	feature, err := env.newCommit(ctx, ".")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := env.git.CheckoutBranch(ctx, "main", git.CheckoutOptions{}); err != nil {
+		t.Fatal(err)
+	}
+
+	out, err := env.gg(ctx, env.root.String(), "merge", "--preview", "feature")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(out), feature.Short()) {
+		t.Errorf("merge --preview output = %q; want to contain %s", out, feature.Short())
+	}
+	if curr, err := env.git.Head(ctx); err != nil {
+		t.Fatal(err)
+	} else if curr.Commit != mainHead.Commit {
+		t.Errorf("after merge --preview, HEAD = %v; want %v", curr.Commit, mainHead.Commit)
+	}
+	if exists, err := env.root.Exists("foo.txt"); err != nil {
+		t.Error(err)
+	} else if exists {
+		t.Error("merge --preview changed the working copy")
+	}
+}
@@ -0,0 +1,200 @@
+// Copyright 2021 The gg Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"gg-scm.io/pkg/git"
+	"gg-scm.io/tool/internal/flag"
+)
+
+const migrateDefaultBranchSynopsis = "follow a rename of a remote's default branch"
+
+func migrateDefaultBranch(ctx context.Context, cc *cmdContext, args []string) error {
+	f := flag.NewFlagSet(true, "gg migrate-default-branch [-n] [--from=BRANCH] [REMOTE]", migrateDefaultBranchSynopsis+`
+
+	migrate-default-branch updates the local repository after the remote's
+	default branch has been renamed (for example, from master to main).
+	If no remote is given, `+"`origin`"+` is used. It:
+
+	- fetches from the remote,
+	- points the remote's HEAD at the new default branch,
+	- renames the local branch with the old name if there is no local
+	  branch with the new name yet,
+	- changes every local branch that tracked the old remote branch to
+	  track the new one, and
+	- updates `+"`gg.pullRequestBase`"+` and `+"`gg.protectedBranch`"+` in the
+	  repository's Git configuration.
+
+	The old default branch name is read from the remote's HEAD as it was
+	last fetched. Use `+"`--from`"+` to name it explicitly.`)
+	dryRun := f.Bool("n", false, "show what would change without changing anything")
+	f.Alias("n", "dry-run")
+	from := f.String("from", "", "previous default `branch` of the remote")
+	if err := f.Parse(args); flag.IsHelp(err) {
+		f.Help(cc.stdout)
+		return nil
+	} else if err != nil {
+		return usagef("%v", err)
+	}
+	if f.NArg() > 1 {
+		return usagef("can't pass multiple remotes")
+	}
+	remote := f.Arg(0)
+	if remote == "" {
+		remote = "origin"
+	}
+	cfg, err := cc.git.ReadConfig(ctx)
+	if err != nil {
+		return err
+	}
+	if _, ok := cfg.ListRemotes()[remote]; !ok {
+		return fmt.Errorf("no remote named %q", remote)
+	}
+
+	newBranch, err := remoteDefaultBranch(ctx, cc.git, remote)
+	if err != nil {
+		return err
+	}
+	oldBranch := strings.TrimPrefix(*from, "refs/heads/")
+	if oldBranch == "" {
+		oldBranch = trackedDefaultBranch(ctx, cc.git, remote)
+		if oldBranch == "" {
+			return fmt.Errorf("can't determine previous default branch of %s; pass --from", remote)
+		}
+	}
+	if oldBranch == newBranch {
+		fmt.Fprintf(cc.stdout, "%s's default branch is already %s\n", remote, newBranch)
+		return nil
+	}
+
+	if !*dryRun {
+		if err := cc.interactiveGit(ctx, "fetch", "--prune", "--", remote); err != nil {
+			return err
+		}
+	}
+	remoteHead := "refs/remotes/" + remote + "/HEAD"
+	fmt.Fprintf(cc.stdout, "set %s/HEAD to %s/%s\n", remote, remote, newBranch)
+	if !*dryRun {
+		if err := cc.git.Run(ctx, "symbolic-ref", remoteHead, "refs/remotes/"+remote+"/"+newBranch); err != nil {
+			return err
+		}
+	}
+
+	localConfig, err := cc.git.Output(ctx, "config", "-z", "--local", "--list")
+	if err != nil {
+		return err
+	}
+	entries := parseConfigList(localConfig, false)
+	localRefs, err := cc.git.ListRefsVerbatim(ctx)
+	if err != nil {
+		return err
+	}
+	_, hasOld := localRefs[git.BranchRef(oldBranch)]
+	_, hasNew := localRefs[git.BranchRef(newBranch)]
+	renamed := hasOld && !hasNew
+	var retrack []string
+	for _, ent := range entries {
+		if !strings.HasPrefix(ent.key, "branch.") || !strings.HasSuffix(ent.key, ".merge") ||
+			strings.TrimPrefix(ent.value, "refs/heads/") != oldBranch {
+			continue
+		}
+		branch := strings.TrimSuffix(strings.TrimPrefix(ent.key, "branch."), ".merge")
+		if cfg.Value("branch."+branch+".remote") != remote {
+			continue
+		}
+		if renamed && branch == oldBranch {
+			// Renaming a branch carries over its configuration.
+			branch = newBranch
+		}
+		retrack = append(retrack, branch)
+	}
+
+	if renamed {
+		fmt.Fprintf(cc.stdout, "rename branch %s to %s\n", oldBranch, newBranch)
+		if !*dryRun {
+			if err := cc.git.Run(ctx, "branch", "-m", "--", oldBranch, newBranch); err != nil {
+				return err
+			}
+		}
+	}
+	for _, branch := range retrack {
+		fmt.Fprintf(cc.stdout, "track %s/%s from branch %s\n", remote, newBranch, branch)
+		if !*dryRun {
+			if err := cc.git.Run(ctx, "config", "--local", "branch."+branch+".merge", git.BranchRef(newBranch).String()); err != nil {
+				return err
+			}
+		}
+	}
+	for _, ent := range entries {
+		if ent.key == "gg.pullrequestbase" && strings.TrimPrefix(ent.value, "refs/heads/") == oldBranch {
+			fmt.Fprintf(cc.stdout, "set gg.pullRequestBase to %s\n", newBranch)
+			if !*dryRun {
+				if err := cc.git.Run(ctx, "config", "--local", "gg.pullRequestBase", newBranch); err != nil {
+					return err
+				}
+			}
+			break
+		}
+	}
+	for _, ent := range entries {
+		if ent.key == "gg.protectedbranch" && strings.TrimPrefix(ent.value, "refs/heads/") == oldBranch {
+			fmt.Fprintf(cc.stdout, "protect branch %s instead of %s\n", newBranch, oldBranch)
+			if !*dryRun {
+				err := cc.git.Run(ctx, "config", "--local", "--replace-all", "gg.protectedBranch", newBranch, "^(refs/heads/)?"+regexp.QuoteMeta(oldBranch)+"$")
+				if err != nil {
+					return err
+				}
+			}
+			break
+		}
+	}
+	return nil
+}
+
+// remoteDefaultBranch asks the remote which branch its HEAD points to.
+func remoteDefaultBranch(ctx context.Context, g *git.Git, remote string) (string, error) {
+	out, err := g.Output(ctx, "ls-remote", "--symref", "--", remote, "HEAD")
+	if err != nil {
+		return "", err
+	}
+	for _, line := range strings.Split(out, "\n") {
+		if !strings.HasPrefix(line, "ref: ") || !strings.HasSuffix(line, "\tHEAD") {
+			continue
+		}
+		ref := git.Ref(strings.TrimSuffix(strings.TrimPrefix(line, "ref: "), "\tHEAD"))
+		if !ref.IsBranch() {
+			break
+		}
+		return ref.Branch(), nil
+	}
+	return "", fmt.Errorf("can't determine default branch of %s", remote)
+}
+
+// trackedDefaultBranch returns the branch that the remote-tracking HEAD
+// for the given remote points to, as set by clone or the last call to
+// `git remote set-head`. It returns the empty string if the remote has
+// no tracked HEAD.
+func trackedDefaultBranch(ctx context.Context, g *git.Git, remote string) string {
+	out, err := g.Output(ctx, "symbolic-ref", "-q", "refs/remotes/"+remote+"/HEAD")
+	if err != nil {
+		return ""
+	}
+	return strings.TrimPrefix(strings.TrimSuffix(out, "\n"), "refs/remotes/"+remote+"/")
+}
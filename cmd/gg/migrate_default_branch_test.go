@@ -0,0 +1,92 @@
+// Copyright 2021 The gg Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestMigrateDefaultBranch(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	env, err := newTestEnv(ctx, t)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := env.initRepoWithHistory(ctx, "repoA"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := env.gg(ctx, env.root.String(), "clone", "repoA", "repoB"); err != nil {
+		t.Fatal(err)
+	}
+	gitA := env.git.WithDir(env.root.FromSlash("repoA"))
+	if err := gitA.Run(ctx, "branch", "-m", "main", "trunk"); err != nil {
+		t.Fatal(err)
+	}
+	gitB := env.git.WithDir(env.root.FromSlash("repoB"))
+	if err := gitB.Run(ctx, "config", "gg.pullRequestBase", "main"); err != nil {
+		t.Fatal(err)
+	}
+	if err := gitB.Run(ctx, "config", "gg.protectedBranch", "main"); err != nil {
+		t.Fatal(err)
+	}
+
+	env.stderr.Reset()
+	if _, err := env.gg(ctx, env.root.FromSlash("repoB"), "pull"); err != nil {
+		t.Fatal(err)
+	}
+	if want := "origin's default branch has changed from main to trunk"; !strings.Contains(env.stderr.String(), want) {
+		t.Errorf("gg pull stderr:\n%s\nwant to contain %q", env.stderr.String(), want)
+	}
+
+	if _, err := env.gg(ctx, env.root.FromSlash("repoB"), "migrate-default-branch"); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := gitB.HeadRef(ctx); err != nil {
+		t.Error(err)
+	} else if got != "refs/heads/trunk" {
+		t.Errorf("HEAD = %v; want refs/heads/trunk", got)
+	}
+	tests := []struct {
+		args []string
+		want string
+	}{
+		{[]string{"symbolic-ref", "refs/remotes/origin/HEAD"}, "refs/remotes/origin/trunk\n"},
+		{[]string{"config", "branch.trunk.remote"}, "origin\n"},
+		{[]string{"config", "branch.trunk.merge"}, "refs/heads/trunk\n"},
+		{[]string{"config", "gg.pullRequestBase"}, "trunk\n"},
+		{[]string{"config", "--get-all", "gg.protectedBranch"}, "trunk\n"},
+	}
+	for _, test := range tests {
+		got, err := gitB.Output(ctx, test.args...)
+		if err != nil {
+			t.Errorf("git %s: %v", strings.Join(test.args, " "), err)
+			continue
+		}
+		if got != test.want {
+			t.Errorf("git %s = %q; want %q", strings.Join(test.args, " "), got, test.want)
+		}
+	}
+
+	out, err := env.gg(ctx, env.root.FromSlash("repoB"), "migrate-default-branch", "--from=trunk")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "origin's default branch is already trunk\n"; string(out) != want {
+		t.Errorf("second migrate-default-branch output = %q; want %q", out, want)
+	}
+}
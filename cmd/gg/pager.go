@@ -0,0 +1,140 @@
+// Copyright 2021 The gg Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"gg-scm.io/pkg/git"
+)
+
+// pagerCommand returns the shell command to page the output of the named
+// command or the empty string if the output should not be paged.
+// The pager is taken from the GG_PAGER environment variable if set,
+// otherwise it is the pager Git would use (GIT_PAGER, core.pager, PAGER,
+// or less). A pager.<command> setting of false disables paging for the
+// command and any other non-boolean value overrides the pager. cfg is the
+// Git configuration of g.
+func pagerCommand(ctx context.Context, g *git.Git, cfg *git.Config, env []string, name string) (string, error) {
+	cmd := lookupCommand(name)
+	if cmd != nil {
+		name = cmd.name
+	}
+	enabled := cmd != nil && cmd.paged
+	var pager string
+	if v := cfg.Value("pager." + name); v != "" {
+		if b, err := cfg.Bool("pager." + name); err == nil {
+			enabled = b
+		} else {
+			enabled = true
+			pager = v
+		}
+	}
+	if !enabled {
+		return "", nil
+	}
+	if pager == "" {
+		pager = getenv(env, "GG_PAGER")
+	}
+	if pager == "" {
+		var err error
+		pager, err = g.Output(ctx, "var", "GIT_PAGER")
+		if err != nil {
+			return "", fmt.Errorf("find pager: %w", err)
+		}
+		pager = strings.TrimSuffix(pager, "\n")
+	}
+	if pager == "cat" {
+		return "", nil
+	}
+	return pager, nil
+}
+
+// pager is a running pager process.
+type pager struct {
+	w    *os.File
+	done chan struct{}
+	err  error
+}
+
+// startPager starts the given pager command with its output connected
+// to stdout. The caller must call close when it is done writing to the
+// returned pager.
+func startPager(gitExe string, line string, env []string, stdout, stderr io.Writer) (*pager, error) {
+	c, err := bashCommand(gitExe, line)
+	if err != nil {
+		return nil, fmt.Errorf("start pager: %w", err)
+	}
+	r, w, err := os.Pipe()
+	if err != nil {
+		return nil, fmt.Errorf("start pager: %w", err)
+	}
+	// Same defaults as Git: quit if the output fits on one screen, pass
+	// through color escapes, and don't clear the screen.
+	if getenv(env, "LESS") == "" {
+		env = append(env[:len(env):len(env)], "LESS=FRX")
+	}
+	if getenv(env, "LV") == "" {
+		env = append(env[:len(env):len(env)], "LV=-c")
+	}
+	c.Env = env
+	if len(c.Env) == 0 {
+		c.Env = []string{} // force empty
+	}
+	c.Stdin = r
+	c.Stdout = stdout
+	c.Stderr = stderr
+	if err := c.Start(); err != nil {
+		r.Close()
+		w.Close()
+		return nil, fmt.Errorf("start pager: %w", err)
+	}
+	r.Close()
+	p := &pager{
+		w:    w,
+		done: make(chan struct{}),
+	}
+	go func() {
+		p.err = c.Wait()
+		close(p.done)
+	}()
+	return p, nil
+}
+
+// quit reports whether the pager exited before close was called,
+// which usually means the user quit the pager.
+func (p *pager) quit() bool {
+	select {
+	case <-p.done:
+		return true
+	default:
+		return false
+	}
+}
+
+// close signals the end of output and waits for the user to
+// exit the pager.
+func (p *pager) close() error {
+	p.w.Close()
+	<-p.done
+	if p.err != nil {
+		return fmt.Errorf("pager: %w", p.err)
+	}
+	return nil
+}
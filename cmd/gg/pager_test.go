@@ -0,0 +1,69 @@
+// Copyright 2021 The gg Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"testing"
+)
+
+func TestPagerCommand(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	env, err := newTestEnv(ctx, t)
+	if err != nil {
+		t.Fatal(err)
+	}
+	config := "[pager]\n" +
+		"\tstatus = false\n" +
+		"\tbranch = true\n" +
+		"\tshow = more\n"
+	if err := env.writeConfig([]byte(config)); err != nil {
+		t.Fatal(err)
+	}
+	if err := env.initEmptyRepo(ctx, "."); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := env.git.ReadConfig(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ggPager := []string{"GG_PAGER=mypager -x"}
+
+	tests := []struct {
+		name string
+		env  []string
+		want string
+	}{
+		{name: "log", env: ggPager, want: "mypager -x"},
+		{name: "history", env: ggPager, want: "mypager -x"},
+		{name: "diff", env: []string{"GG_PAGER=cat"}, want: ""},
+		{name: "status", env: ggPager, want: ""},
+		{name: "st", env: ggPager, want: ""},
+		{name: "branch", env: ggPager, want: "mypager -x"},
+		{name: "commit", env: ggPager, want: ""},
+		{name: "show", env: ggPager, want: "more"},
+	}
+	for _, test := range tests {
+		got, err := pagerCommand(ctx, env.git, cfg, test.env, test.name)
+		if err != nil {
+			t.Errorf("pagerCommand(ctx, g, cfg, %q, %q): %v", test.env, test.name, err)
+			continue
+		}
+		if got != test.want {
+			t.Errorf("pagerCommand(ctx, g, cfg, %q, %q) = %q; want %q", test.env, test.name, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,172 @@
+// Copyright 2021 The gg Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"gg-scm.io/pkg/git"
+	"gg-scm.io/tool/internal/escape"
+	"gg-scm.io/tool/internal/flag"
+	"gg-scm.io/tool/internal/sigterm"
+)
+
+const precommitSynopsis = "run configured checks on changed files"
+
+func precommit(ctx context.Context, cc *cmdContext, args []string) error {
+	f := flag.NewFlagSet(true, "gg precommit run [FILE [...]]", precommitSynopsis+`
+
+	Runs each command configured in the `+"`[gg \"check\"]`"+` section of the
+	Git configuration or the workspace `+"`.ggconfig`"+` file against the
+	files that would be committed by `+"`gg commit`"+` with the same
+	arguments. For example:
+
+		[gg "check"]
+		vet = go vet ./...
+		spelling = misspell -error $@
+
+	Each command is run by the shell from the top of the working copy. The
+	changed files are available to the command as positional arguments.
+	Checks run in the order they appear in the configuration, and all
+	checks are run even if an earlier one fails. Setting a check's command
+	to the empty string in your Git configuration disables a check from
+	the workspace file. `+"`gg commit`"+` runs the same checks before
+	committing unless `+"`--no-check`"+` is given.
+
+	Since checks run arbitrary commands, checks from the workspace file
+	are ignored until you trust the repository with
+	`+"`git config gg.trustWorkspace true`"+`.`)
+	if err := f.Parse(args); flag.IsHelp(err) {
+		f.Help(cc.stdout)
+		return nil
+	} else if err != nil {
+		return usagef("%v", err)
+	}
+	if f.NArg() == 0 {
+		return usagef("missing subcommand")
+	}
+	if f.Arg(0) != "run" {
+		return usagef("unknown subcommand %q", f.Arg(0))
+	}
+	var pathspecs []git.Pathspec
+	for _, arg := range f.Args()[1:] {
+		pathspecs = append(pathspecs, git.LiteralPath(arg))
+	}
+	return runChecks(ctx, cc, pathspecs)
+}
+
+// A check is a command configured to run before committing.
+type check struct {
+	name    string
+	command string
+}
+
+// readChecks returns the commands configured in the gg.check
+// configuration subsection in the order they are defined.
+func readChecks(cfg *ggConfig) []check {
+	const prefix = "gg.check."
+	var checks []check
+	for _, ent := range cfg.entries {
+		if !strings.HasPrefix(ent.key, prefix) {
+			continue
+		}
+		name := ent.key[len(prefix):]
+		replaced := false
+		for i := range checks {
+			if checks[i].name == name {
+				// Later definitions override earlier ones.
+				checks[i].command = ent.value
+				replaced = true
+				break
+			}
+		}
+		if !replaced {
+			checks = append(checks, check{name: name, command: ent.value})
+		}
+	}
+	// An empty command disables a check.
+	n := 0
+	for _, chk := range checks {
+		if chk.command != "" {
+			checks[n] = chk
+			n++
+		}
+	}
+	return checks[:n]
+}
+
+// runChecks runs the configured checks against the files that
+// would be committed by the given pathspecs. It returns an error
+// if any of the checks fail.
+func runChecks(ctx context.Context, cc *cmdContext, pathspecs []git.Pathspec) error {
+	cfg, err := readGGConfig(ctx, cc.git)
+	if err != nil {
+		return err
+	}
+	checks := readChecks(cfg.trusted())
+	if len(checks) == 0 {
+		return nil
+	}
+	status, err := cc.git.Status(ctx, git.StatusOptions{
+		Pathspecs: pathspecs,
+	})
+	if err != nil {
+		return err
+	}
+	var files []string
+	for _, ent := range status {
+		if ent.Code.IsAdded() || ent.Code.IsModified() || ent.Code.IsCopied() || ent.Code.IsRenamed() {
+			files = append(files, string(ent.Name))
+		}
+	}
+	if len(files) == 0 {
+		return nil
+	}
+	topLevel, err := cc.git.WorkTree(ctx)
+	if err != nil {
+		return err
+	}
+	line := new(strings.Builder)
+	for _, file := range files {
+		line.WriteString(" ")
+		line.WriteString(escape.Bash(file))
+	}
+	var failed []string
+	for _, chk := range checks {
+		// Wrap the command in a shell function so that it receives the
+		// files as its positional arguments.
+		c, err := bashCommand(cc.git.Exe(), "gg_check() {\n"+chk.command+"\n}\ngg_check"+line.String())
+		if err != nil {
+			return fmt.Errorf("check %s: %w", chk.name, err)
+		}
+		c.Dir = topLevel
+		c.Env = cc.env
+		if len(c.Env) == 0 {
+			c.Env = []string{} // force empty
+		}
+		c.Stdout = cc.stdout
+		c.Stderr = cc.stderr
+		if err := sigterm.Run(ctx, c); err != nil {
+			fmt.Fprintf(cc.stderr, "gg: check %s: %v\n", chk.name, err)
+			failed = append(failed, chk.name)
+		}
+	}
+	if len(failed) > 0 {
+		return fmt.Errorf("failed checks: %s", strings.Join(failed, ", "))
+	}
+	return nil
+}
@@ -0,0 +1,97 @@
+// Copyright 2021 The gg Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"gg-scm.io/tool/internal/filesystem"
+)
+
+func TestPrecommitRun(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	env, err := newTestEnv(ctx, t)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := env.initRepoWithHistory(ctx, "."); err != nil {
+		t.Fatal(err)
+	}
+	if err := env.writeConfig([]byte("[gg \"check\"]\nlist = echo checked $@\n")); err != nil {
+		t.Fatal(err)
+	}
+	if err := env.root.Apply(filesystem.Write("foo.txt", dummyContent)); err != nil {
+		t.Fatal(err)
+	}
+	if err := env.trackFiles(ctx, "foo.txt"); err != nil {
+		t.Fatal(err)
+	}
+
+	out, err := env.gg(ctx, env.root.String(), "precommit", "run")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "checked foo.txt\n"; !bytes.Contains(out, []byte(want)) {
+		t.Errorf("output = %q; want to contain %q", out, want)
+	}
+}
+
+func TestCommit_FailedCheck(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	env, err := newTestEnv(ctx, t)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := env.initRepoWithHistory(ctx, "."); err != nil {
+		t.Fatal(err)
+	}
+	if err := env.writeConfig([]byte("[gg \"check\"]\nfail = exit 1\n")); err != nil {
+		t.Fatal(err)
+	}
+	r1, err := env.git.Head(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := env.root.Apply(filesystem.Write("foo.txt", dummyContent)); err != nil {
+		t.Fatal(err)
+	}
+	if err := env.trackFiles(ctx, "foo.txt"); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := env.gg(ctx, env.root.String(), "commit", "-m", "failed check"); err == nil {
+		t.Error("gg commit did not return an error")
+	} else if isUsage(err) {
+		t.Error(err)
+	}
+	if r, err := env.git.Head(ctx); err != nil {
+		t.Fatal(err)
+	} else if r.Commit != r1.Commit {
+		t.Errorf("HEAD = %v after failed check; want %v", r.Commit, r1.Commit)
+	}
+
+	if _, err := env.gg(ctx, env.root.String(), "commit", "--no-check", "-m", "skipped check"); err != nil {
+		t.Fatal(err)
+	}
+	if r, err := env.git.Head(ctx); err != nil {
+		t.Fatal(err)
+	} else if r.Commit == r1.Commit {
+		t.Error("commit --no-check did not create a new commit")
+	}
+}
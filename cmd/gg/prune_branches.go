@@ -0,0 +1,288 @@
+// Copyright 2021 The gg Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"gg-scm.io/pkg/git"
+	"gg-scm.io/tool/internal/flag"
+)
+
+const pruneBranchesSynopsis = "delete branches that are finished"
+
+func pruneBranches(ctx context.Context, cc *cmdContext, args []string) error {
+	f := flag.NewFlagSet(true, "gg prune-branches [--dry-run] [-y] [--force]", pruneBranchesSynopsis+`
+
+	prune-branches finds local branches that are no longer needed:
+
+	- branches whose upstream branch no longer exists on the remote, and
+	- branches whose GitHub pull request has been merged or closed
+	  without any commits added since. This requires logging in with
+	  `+"`gg github-login`"+`.
+
+	prune-branches lists the branches along with why each can be pruned,
+	then asks for confirmation before deleting them. `+"`-y`"+` skips the
+	confirmation and `+"`--dry-run`"+` only lists the branches. The
+	currently checked out branch and branches listed in
+	`+"`gg.protectedBranch`"+` are never deleted.
+
+	A branch whose upstream is gone is only deleted if its commit is
+	reachable from the upstream's last known commit or from the remote's
+	default branch. Other such branches are listed as unmerged and are
+	only deleted with `+"`--force`"+`.`)
+	dryRun := f.Bool("dry-run", false, "list the branches that would be deleted without deleting them")
+	f.Alias("dry-run", "n")
+	yes := f.Bool("y", false, "delete without asking for confirmation")
+	f.Alias("y", "yes")
+	force := f.Bool("force", false, "also delete unmerged branches whose upstream is gone")
+	f.Alias("force", "f")
+	if err := f.Parse(args); flag.IsHelp(err) {
+		f.Help(cc.stdout)
+		return nil
+	} else if err != nil {
+		return usagef("%v", err)
+	}
+	if f.NArg() > 0 {
+		return usagef("prune-branches takes no arguments")
+	}
+	if *dryRun && *yes {
+		return usagef("can't pass both --dry-run and -y")
+	}
+
+	prunable, err := findPrunableBranches(ctx, cc)
+	if err != nil {
+		return err
+	}
+	if len(prunable) == 0 {
+		fmt.Fprintln(cc.stdout, "no branches to prune")
+		return nil
+	}
+	n := 0
+	for _, b := range prunable {
+		if b.unmerged && !*force {
+			fmt.Fprintf(cc.stdout, "branch %s (was %v): %s, but is not merged (pass --force to delete)\n", b.name, b.commit.Short(), b.reason)
+			continue
+		}
+		fmt.Fprintf(cc.stdout, "branch %s (was %v): %s\n", b.name, b.commit.Short(), b.reason)
+		prunable[n] = b
+		n++
+	}
+	prunable = prunable[:n]
+	if len(prunable) == 0 {
+		fmt.Fprintln(cc.stdout, "no branches to prune")
+		return nil
+	}
+	summary := pluralize(len(prunable), "branch", "branches")
+	if *dryRun {
+		fmt.Fprintf(cc.stdout, "would delete %s\n", summary)
+		return nil
+	}
+	if !*yes {
+		ok, err := confirm(cc, fmt.Sprintf("delete %s?", summary))
+		if err != nil {
+			return err
+		}
+		if !ok {
+			fmt.Fprintln(cc.stdout, "no branches deleted")
+			return nil
+		}
+	}
+	// Deleting with git branch also removes the branches' configuration.
+	deleteArgs := []string{"branch", "-D", "--"}
+	for _, b := range prunable {
+		deleteArgs = append(deleteArgs, b.name)
+	}
+	if err := cc.git.Run(ctx, deleteArgs...); err != nil {
+		return err
+	}
+	fmt.Fprintf(cc.stdout, "deleted %s\n", summary)
+	return nil
+}
+
+// A prunableBranch is a local branch that prune-branches can delete.
+type prunableBranch struct {
+	name   string
+	commit git.Hash
+	reason string
+	// unmerged is true if the branch's upstream is gone and its commit
+	// isn't reachable from anywhere the upstream could have merged it.
+	unmerged bool
+}
+
+// findPrunableBranches returns the local branches whose upstream is gone
+// or whose GitHub pull request is no longer open, sorted by name.
+func findPrunableBranches(ctx context.Context, cc *cmdContext) ([]prunableBranch, error) {
+	cfg, err := cc.git.ReadConfig(ctx)
+	if err != nil {
+		return nil, err
+	}
+	gcfg, err := readGGConfig(ctx, cc.git)
+	if err != nil {
+		return nil, err
+	}
+	localRefs, err := cc.git.ListRefs(ctx)
+	if err != nil {
+		return nil, err
+	}
+	head, err := cc.git.Head(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var branches []string
+	for ref := range localRefs {
+		if ref.IsBranch() && ref != head.Ref && !gcfg.isProtectedBranch(ref.Branch()) {
+			branches = append(branches, ref.Branch())
+		}
+	}
+	sort.Strings(branches)
+
+	remoteRefs := make(map[string]map[git.Ref]git.Hash)
+	var prunable []prunableBranch
+	for _, branch := range branches {
+		commit := localRefs[git.BranchRef(branch)]
+		remote := cfg.Value("branch." + branch + ".remote")
+		upstream := git.Ref(cfg.Value("branch." + branch + ".merge"))
+		if remote != "" && remote != "." && upstream.IsBranch() {
+			refs := remoteRefs[remote]
+			if refs == nil {
+				refs, err = cc.git.ListRemoteRefs(ctx, remote)
+				if err != nil {
+					return nil, err
+				}
+				remoteRefs[remote] = refs
+			}
+			if _, exists := refs[upstream]; !exists {
+				merged, err := mergedIntoRemote(ctx, cc.git, cfg, localRefs, remote, upstream, commit)
+				if err != nil {
+					return nil, err
+				}
+				prunable = append(prunable, prunableBranch{
+					name:     branch,
+					commit:   commit,
+					reason:   fmt.Sprintf("upstream %s/%s is gone", remote, upstream.Branch()),
+					unmerged: !merged,
+				})
+				continue
+			}
+		}
+		if reason := closedPullRequestReason(ctx, cc, cfg, branch, commit); reason != "" {
+			prunable = append(prunable, prunableBranch{
+				name:   branch,
+				commit: commit,
+				reason: reason,
+			})
+		}
+	}
+	return prunable, nil
+}
+
+// mergedIntoRemote reports whether commit is reachable from the
+// remote-tracking branch for upstream (if it hasn't been pruned yet) or
+// from the remote's default branch.
+func mergedIntoRemote(ctx context.Context, g *git.Git, cfg *git.Config, localRefs map[git.Ref]git.Hash, remote string, upstream git.Ref, commit git.Hash) (bool, error) {
+	var candidates []git.Ref
+	if r := cfg.ListRemotes()[remote]; r != nil {
+		if tracking := r.MapFetch(upstream); tracking != "" {
+			candidates = append(candidates, tracking)
+		}
+	}
+	if def := trackedDefaultBranch(ctx, g, remote); def != "" {
+		candidates = append(candidates, git.Ref("refs/remotes/"+remote+"/"+def))
+	}
+	for _, ref := range candidates {
+		if _, exists := localRefs[ref]; !exists {
+			continue
+		}
+		merged, err := g.IsAncestor(ctx, commit.String(), ref.String())
+		if err != nil {
+			return false, err
+		}
+		if merged {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// closedPullRequestReason returns a description of the most recent merged
+// or closed GitHub pull request for the branch whose head is the
+// branch's current commit. It returns the empty string if there is no
+// such pull request or the branch's repository is not on GitHub.
+func closedPullRequestReason(ctx context.Context, cc *cmdContext, cfg *git.Config, branch string, commit git.Hash) string {
+	target, err := findPullRequestTarget(ctx, cc, cfg, branch, "")
+	if err != nil {
+		return ""
+	}
+	gh, ok := target.forge.(gitHubForge)
+	if !ok {
+		return ""
+	}
+	token, err := cc.xdgDirs.readConfig(gh.tokenFilename())
+	if err != nil {
+		return ""
+	}
+	prs, err := listOpenPullRequests(ctx, cc.httpClient, listPullRequestsParams{
+		authToken: string(bytes.TrimSpace(token)),
+		apiRoot:   gh.apiRoot,
+		owner:     target.baseOwner,
+		repo:      target.baseRepo,
+		head:      target.headOwner + ":" + branch,
+		state:     "all",
+	})
+	if err != nil {
+		fmt.Fprintf(cc.stderr, "gg: branch %s: %v\n", branch, err)
+		return ""
+	}
+	for _, pr := range prs {
+		if pr.State == "open" {
+			return ""
+		}
+	}
+	for _, pr := range prs {
+		if pr.Head.SHA != commit.String() {
+			// The branch has changed since the pull request was closed.
+			continue
+		}
+		if pr.MergedAt != "" {
+			return fmt.Sprintf("pull request #%d was merged", pr.Number)
+		}
+		return fmt.Sprintf("pull request #%d was closed", pr.Number)
+	}
+	return ""
+}
+
+// confirm asks the user a yes or no question on stderr and reports
+// whether the answer from stdin was yes. Anything other than "y" or "yes"
+// is treated as no.
+func confirm(cc *cmdContext, question string) (bool, error) {
+	fmt.Fprintf(cc.stderr, "%s [y/N] ", question)
+	line, err := bufio.NewReader(cc.stdin).ReadString('\n')
+	if err != nil && err != io.EOF {
+		return false, fmt.Errorf("read answer: %w", err)
+	}
+	switch strings.ToLower(strings.TrimSpace(line)) {
+	case "y", "yes":
+		return true, nil
+	default:
+		return false, nil
+	}
+}
@@ -0,0 +1,149 @@
+// Copyright 2021 The gg Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"gg-scm.io/pkg/git"
+	"gg-scm.io/tool/internal/filesystem"
+)
+
+func TestPruneBranches(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	env, err := newTestEnv(ctx, t)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := env.initRepoWithHistory(ctx, "repoA"); err != nil {
+		t.Fatal(err)
+	}
+	gitA := env.git.WithDir(env.root.FromSlash("repoA"))
+	for _, name := range []string{"gone", "kept"} {
+		if err := gitA.NewBranch(ctx, name, git.BranchOptions{}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := env.gg(ctx, env.root.String(), "clone", "repoA", "repoB"); err != nil {
+		t.Fatal(err)
+	}
+	gitB := env.git.WithDir(env.root.FromSlash("repoB"))
+	for _, name := range []string{"gone", "kept"} {
+		if err := gitB.Run(ctx, "branch", "--track", name, "origin/"+name); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := gitA.MutateRefs(ctx, map[git.Ref]git.RefMutation{"refs/heads/gone": git.DeleteRef()}); err != nil {
+		t.Fatal(err)
+	}
+
+	out, err := env.gg(ctx, env.root.FromSlash("repoB"), "prune-branches", "--dry-run")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"branch gone (was ",
+		"upstream origin/gone is gone\n",
+		"would delete 1 branch\n",
+	} {
+		if !bytes.Contains(out, []byte(want)) {
+			t.Errorf("prune-branches --dry-run output does not contain %q. Output:\n%s", want, out)
+		}
+	}
+	if bytes.Contains(out, []byte("branch kept")) {
+		t.Errorf("prune-branches --dry-run lists branch kept. Output:\n%s", out)
+	}
+
+	if _, err := env.gg(ctx, env.root.FromSlash("repoB"), "prune-branches", "-y"); err != nil {
+		t.Fatal(err)
+	}
+	refs, err := gitB.ListRefs(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, exists := refs["refs/heads/gone"]; exists {
+		t.Error("refs/heads/gone exists after prune-branches -y")
+	}
+	if _, exists := refs["refs/heads/kept"]; !exists {
+		t.Error("refs/heads/kept was deleted by prune-branches -y")
+	}
+}
+
+func TestPruneBranches_Unmerged(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	env, err := newTestEnv(ctx, t)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := env.initRepoWithHistory(ctx, "repoA"); err != nil {
+		t.Fatal(err)
+	}
+	gitA := env.git.WithDir(env.root.FromSlash("repoA"))
+	if err := gitA.NewBranch(ctx, "feature", git.BranchOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := env.gg(ctx, env.root.String(), "clone", "repoA", "repoB"); err != nil {
+		t.Fatal(err)
+	}
+	gitB := env.git.WithDir(env.root.FromSlash("repoB"))
+	if err := gitB.Run(ctx, "branch", "--track", "feature", "origin/feature"); err != nil {
+		t.Fatal(err)
+	}
+	// Add a commit to feature that was never pushed, then delete the
+	// upstream branch and prune its remote-tracking branch.
+	if err := gitB.CheckoutBranch(ctx, "feature", git.CheckoutOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := env.root.Apply(filesystem.Write("repoB/foo.txt", dummyContent)); err != nil {
+		t.Fatal(err)
+	}
+	if err := env.addFiles(ctx, "repoB/foo.txt"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := env.newCommit(ctx, "repoB"); err != nil {
+		t.Fatal(err)
+	}
+	if err := gitB.CheckoutBranch(ctx, "main", git.CheckoutOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := gitA.MutateRefs(ctx, map[git.Ref]git.RefMutation{"refs/heads/feature": git.DeleteRef()}); err != nil {
+		t.Fatal(err)
+	}
+	if err := gitB.Run(ctx, "fetch", "--prune", "origin"); err != nil {
+		t.Fatal(err)
+	}
+
+	out, err := env.gg(ctx, env.root.FromSlash("repoB"), "prune-branches", "-y")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "is not merged"; !bytes.Contains(out, []byte(want)) {
+		t.Errorf("prune-branches -y output does not contain %q. Output:\n%s", want, out)
+	}
+	if _, err := gitB.ParseRev(ctx, "refs/heads/feature"); err != nil {
+		t.Error("unmerged branch deleted without --force:", err)
+	}
+
+	if _, err := env.gg(ctx, env.root.FromSlash("repoB"), "prune-branches", "-y", "--force"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := gitB.ParseRev(ctx, "refs/heads/feature"); err == nil {
+		t.Error("refs/heads/feature exists after prune-branches -y --force")
+	}
+}
@@ -0,0 +1,223 @@
+// Copyright 2021 The gg Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"gg-scm.io/pkg/git"
+	"gg-scm.io/tool/internal/flag"
+)
+
+const pruneRefsSynopsis = "delete stale remote-tracking branches, branches, and shelves"
+
+// defaultShelfMaxAgeDays is the number of days after which prune-refs
+// deletes shelves if gg.pruneShelfDays is not set.
+const defaultShelfMaxAgeDays = 90
+
+func pruneRefs(ctx context.Context, cc *cmdContext, args []string) error {
+	f := flag.NewFlagSet(true, "gg prune-refs [--apply] [--shelf-age=DAYS] [REMOTE [...]]", pruneRefsSynopsis+`
+
+	prune-refs finds:
+
+	- remote-tracking branches whose branch no longer exists on the remote,
+	- local branches whose upstream branch no longer exists on the remote
+	  and that are fully merged into the working copy's parent or the
+	  remote's default branch, and
+	- shelves older than a number of days, set by `+"`--shelf-age`"+` or the
+	  `+"`gg.pruneShelfDays`"+` configuration setting (90 by default).
+	  An age of 0 keeps all shelves.
+
+	By default, prune-refs only prints what it would delete. Pass
+	`+"`--apply`"+` to delete them. If no remotes are given, all remotes
+	are checked. The currently checked out branch is never deleted.`)
+	apply := f.Bool("apply", false, "delete the refs instead of printing them")
+	shelfAge := f.Int("shelf-age", -1, "delete shelves older than `days`")
+	if err := f.Parse(args); flag.IsHelp(err) {
+		f.Help(cc.stdout)
+		return nil
+	} else if err != nil {
+		return usagef("%v", err)
+	}
+	if *shelfAge < -1 {
+		return usagef("--shelf-age must not be negative")
+	}
+	gcfg, err := readGGConfig(ctx, cc.git)
+	if err != nil {
+		return err
+	}
+	if *shelfAge == -1 {
+		*shelfAge = defaultShelfMaxAgeDays
+		if v := gcfg.Value("gg.pruneShelfDays"); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil || n < 0 {
+				return fmt.Errorf("gg.pruneShelfDays = %q is not a non-negative number", v)
+			}
+			*shelfAge = n
+		}
+	}
+	cfg, err := cc.git.ReadConfig(ctx)
+	if err != nil {
+		return err
+	}
+	remotes := cfg.ListRemotes()
+	remoteNames := f.Args()
+	if len(remoteNames) == 0 {
+		for name := range remotes {
+			remoteNames = append(remoteNames, name)
+		}
+		sort.Strings(remoteNames)
+	}
+	localRefs, err := cc.git.ListRefsVerbatim(ctx)
+	if err != nil {
+		return err
+	}
+	head, err := cc.git.Head(ctx)
+	if err != nil {
+		return err
+	}
+
+	var staleTracking []git.Ref
+	var staleBranches []string
+	for _, name := range remoteNames {
+		remote := remotes[name]
+		if remote == nil {
+			return fmt.Errorf("no remote named %q", name)
+		}
+		remoteRefs, err := cc.git.ListRemoteRefs(ctx, name)
+		if err != nil {
+			return err
+		}
+		live := make(map[git.Ref]bool)
+		for ref := range remoteRefs {
+			if ref.IsBranch() {
+				if tracking := remote.MapFetch(ref); tracking != "" {
+					live[tracking] = true
+				}
+			}
+		}
+		prefix := "refs/remotes/" + name + "/"
+		for ref := range localRefs {
+			if strings.HasPrefix(ref.String(), prefix) && ref.String() != prefix+"HEAD" && !live[ref] {
+				staleTracking = append(staleTracking, ref)
+			}
+		}
+
+		mergeTargets := []string{head.Commit.String()}
+		if b := trackedDefaultBranch(ctx, cc.git, name); b != "" && live[git.Ref(prefix+b)] {
+			if _, exists := localRefs[git.Ref(prefix+b)]; exists {
+				mergeTargets = append(mergeTargets, prefix+b)
+			}
+		}
+		for ref := range localRefs {
+			if !ref.IsBranch() || ref == head.Ref || gcfg.isProtectedBranch(ref.Branch()) {
+				continue
+			}
+			branch := ref.Branch()
+			if cfg.Value("branch."+branch+".remote") != name {
+				continue
+			}
+			upstream := git.Ref(cfg.Value("branch." + branch + ".merge"))
+			if !upstream.IsBranch() {
+				continue
+			}
+			if _, exists := remoteRefs[upstream]; exists {
+				continue
+			}
+			merged := false
+			for _, target := range mergeTargets {
+				merged, err = cc.git.IsAncestor(ctx, ref.String(), target)
+				if err != nil {
+					return err
+				}
+				if merged {
+					break
+				}
+			}
+			if merged {
+				staleBranches = append(staleBranches, branch)
+			}
+		}
+	}
+	sort.Slice(staleTracking, func(i, j int) bool { return staleTracking[i] < staleTracking[j] })
+	sort.Strings(staleBranches)
+
+	var staleShelves []shelf
+	if *shelfAge > 0 {
+		shelves, err := listShelves(ctx, cc.git)
+		if err != nil {
+			return err
+		}
+		cutoff := time.Now().Add(-time.Duration(*shelfAge) * 24 * time.Hour)
+		for _, s := range shelves {
+			if s.time.Before(cutoff) {
+				staleShelves = append(staleShelves, s)
+			}
+		}
+	}
+
+	for _, ref := range staleTracking {
+		fmt.Fprintf(cc.stdout, "remote-tracking branch %s\n", strings.TrimPrefix(ref.String(), "refs/remotes/"))
+	}
+	for _, branch := range staleBranches {
+		fmt.Fprintf(cc.stdout, "branch %s (was %v)\n", branch, localRefs[git.BranchRef(branch)].Short())
+	}
+	if len(staleShelves) > 0 {
+		style, err := dateStyle(cfg, "")
+		if err != nil {
+			return err
+		}
+		now := time.Now()
+		for _, s := range staleShelves {
+			fmt.Fprintf(cc.stdout, "shelf %s (%s)\n", s.name, style.Format(s.time, now))
+		}
+	}
+	summary := fmt.Sprintf("%s, %s, and %s",
+		pluralize(len(staleTracking), "remote-tracking branch", "remote-tracking branches"),
+		pluralize(len(staleBranches), "branch", "branches"),
+		pluralize(len(staleShelves), "shelf", "shelves"))
+	if !*apply {
+		fmt.Fprintf(cc.stdout, "would delete %s (pass --apply to delete)\n", summary)
+		return nil
+	}
+
+	if len(staleTracking) > 0 {
+		muts := make(map[git.Ref]git.RefMutation, len(staleTracking))
+		for _, ref := range staleTracking {
+			muts[ref] = git.DeleteRef()
+		}
+		if err := cc.git.MutateRefs(ctx, muts); err != nil {
+			return fmt.Errorf("delete remote-tracking branches: %w", err)
+		}
+	}
+	if len(staleBranches) > 0 {
+		// Deleting with git branch also removes the branches' configuration.
+		if err := cc.git.Run(ctx, append([]string{"branch", "-D", "--"}, staleBranches...)...); err != nil {
+			return err
+		}
+	}
+	for _, s := range staleShelves {
+		if err := deleteShelf(ctx, cc.git, s.name); err != nil {
+			return err
+		}
+	}
+	fmt.Fprintf(cc.stdout, "deleted %s\n", summary)
+	return nil
+}
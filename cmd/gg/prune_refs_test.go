@@ -0,0 +1,88 @@
+// Copyright 2021 The gg Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"gg-scm.io/pkg/git"
+)
+
+func TestPruneRefs(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	env, err := newTestEnv(ctx, t)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := env.initRepoWithHistory(ctx, "repoA"); err != nil {
+		t.Fatal(err)
+	}
+	gitA := env.git.WithDir(env.root.FromSlash("repoA"))
+	for _, name := range []string{"merged", "kept"} {
+		if err := gitA.NewBranch(ctx, name, git.BranchOptions{}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := env.gg(ctx, env.root.String(), "clone", "repoA", "repoB"); err != nil {
+		t.Fatal(err)
+	}
+	gitB := env.git.WithDir(env.root.FromSlash("repoB"))
+	if err := gitB.Run(ctx, "branch", "--track", "merged", "origin/merged"); err != nil {
+		t.Fatal(err)
+	}
+	if err := gitA.MutateRefs(ctx, map[git.Ref]git.RefMutation{"refs/heads/merged": git.DeleteRef()}); err != nil {
+		t.Fatal(err)
+	}
+
+	out, err := env.gg(ctx, env.root.FromSlash("repoB"), "prune-refs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"remote-tracking branch origin/merged\n",
+		"branch merged (was ",
+		"would delete 1 remote-tracking branch, 1 branch, and 0 shelves",
+	} {
+		if !bytes.Contains(out, []byte(want)) {
+			t.Errorf("prune-refs output does not contain %q. Output:\n%s", want, out)
+		}
+	}
+	if refs, err := gitB.ListRefsVerbatim(ctx); err != nil {
+		t.Fatal(err)
+	} else if _, exists := refs["refs/heads/merged"]; !exists {
+		t.Error("prune-refs without --apply deleted refs/heads/merged")
+	}
+
+	if _, err := env.gg(ctx, env.root.FromSlash("repoB"), "prune-refs", "--apply"); err != nil {
+		t.Fatal(err)
+	}
+	refs, err := gitB.ListRefsVerbatim(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, ref := range []git.Ref{"refs/heads/merged", "refs/remotes/origin/merged"} {
+		if _, exists := refs[ref]; exists {
+			t.Errorf("%v exists after prune-refs --apply", ref)
+		}
+	}
+	for _, ref := range []git.Ref{"refs/heads/main", "refs/remotes/origin/kept"} {
+		if _, exists := refs[ref]; !exists {
+			t.Errorf("%v deleted by prune-refs --apply", ref)
+		}
+	}
+}
@@ -18,6 +18,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"sort"
 	"strings"
 
 	"gg-scm.io/pkg/git"
@@ -27,7 +28,7 @@ import (
 const pullSynopsis = "pull changes from the specified source"
 
 func pull(ctx context.Context, cc *cmdContext, args []string) error {
-	f := flag.NewFlagSet(true, "gg pull [-u] [-r REV [...]] [SOURCE]", pullSynopsis+`
+	f := flag.NewFlagSet(true, "gg pull [-u] [--rebase | --no-rebase] [-r REV [...]] [--depth N | --deepen N | --shallow-since DATE | --unshallow] [--filter SPEC] [--all | SOURCE]", pullSynopsis+`
 
 	If no source repository is given, the remote called `+"`origin`"+` is used.
 	If the source repository is not a named remote, then the branches will be
@@ -35,28 +36,95 @@ func pull(ctx context.Context, cc *cmdContext, args []string) error {
 
 	Local branches with the same name as a remote branch will be
 	fast-forwarded if possible. The currently checked out branch will not be
-	fast-forwarded unless `+"`-u`"+` is passed.
+	fast-forwarded unless `+"`-u`"+` is passed. If `+"`gg.updateSubmodules`"+`
+	is set to true in the Git configuration, `+"`-u`"+` also initializes and
+	updates submodules.
+
+	With `+"`--rebase`"+`, the commits on the currently checked out branch
+	that are not in its upstream branch are rebased onto the newly pulled
+	upstream branch. Local modifications are stashed during the rebase and
+	restored afterward. If the rebase stops on a conflict, resolve the
+	conflicts and run `+"`gg rebase --continue`"+`, or run
+	`+"`gg rebase --abort`"+` to return the branch to where it was. If the
+	`+"`pull.rebase`"+` setting in the Git configuration is true, then
+	`+"`-u`"+` rebases instead of fast-forwarding; `+"`--no-rebase`"+`
+	overrides it.
 
 	If no revisions are specified, then all the remote's branches and tags
 	will be fetched. If the source is a named remote, then its remote
-	tracking branches will be pruned.`)
+	tracking branches will be pruned.
+
+	`+"`--all`"+` fetches from every configured remote at once and updates
+	their remote-tracking branches. Local branches are not fast-forwarded
+	unless `+"`-u`"+` or `+"`--rebase`"+` is passed, in which case the
+	current branch is updated from its upstream branch.
+
+	In a shallow clone, `+"`--deepen`"+` fetches more history and
+	`+"`--unshallow`"+` fetches all of it. `+"`--filter`"+` fetches only
+	the objects matching the filter, as in a partial clone.`)
 	remoteRefArgs := f.MultiString("r", "`ref`s to pull")
 	update := f.Bool("u", false, "update to new head if new descendants were pulled")
+	all := f.Bool("all", false, "fetch from all remotes")
+	rebaseFlag := f.Bool("rebase", false, "rebase the current branch onto its upstream after pulling")
+	noRebase := f.Bool("no-rebase", false, "do not rebase even if pull.rebase is set")
+	var fetchOpts fetchOptions
+	f.IntVar(&fetchOpts.depth, "depth", 0, "limit history to the given `number` of commits from each branch tip")
+	f.IntVar(&fetchOpts.deepen, "deepen", 0, "fetch the given `number` of additional commits of history in a shallow clone")
+	f.StringVar(&fetchOpts.shallowSince, "shallow-since", "", "limit history to commits after `date`")
+	f.BoolVar(&fetchOpts.unshallow, "unshallow", false, "fetch all history in a shallow clone")
+	f.StringVar(&fetchOpts.filter, "filter", "", "only fetch objects matching the partial clone filter `spec` (e.g. blob:none)")
 	if err := f.Parse(args); flag.IsHelp(err) {
 		f.Help(cc.stdout)
 		return nil
 	} else if err != nil {
 		return usagef("%v", err)
 	}
+	if err := fetchOpts.validate(); err != nil {
+		return usagef("%v", err)
+	}
 	if f.NArg() > 1 {
 		return usagef("can't pass multiple sources")
 	}
+	if *all && (f.NArg() > 0 || len(*remoteRefArgs) > 0) {
+		return usagef("can't pass a source or -r with --all")
+	}
+	if *rebaseFlag && *noRebase {
+		return usagef("can't pass both --rebase and --no-rebase")
+	}
 	cfg, err := cc.git.ReadConfig(ctx)
 	if err != nil {
 		return err
 	}
+	useRebase := *rebaseFlag
+	if *update && !*rebaseFlag && !*noRebase {
+		useRebase, err = pullRebaseDefault(cfg)
+		if err != nil {
+			return err
+		}
+	}
 	remotes := cfg.ListRemotes()
 	headBranch := currentBranch(ctx, cc)
+	if *all {
+		if err := fetchAll(ctx, cc, remotes, fetchOpts); err != nil {
+			return err
+		}
+		if (!*update && !useRebase) || headBranch == "" {
+			return nil
+		}
+		target := targetForUpdate(cfg, headBranch)
+		if useRebase {
+			err = rebaseOntoUpstream(ctx, cc, headBranch, target)
+		} else {
+			err = updateToBranch(ctx, cc.git, headBranch, target, git.MergeLocal)
+		}
+		if err != nil {
+			return err
+		}
+		if err := updateSubmodules(ctx, cc); err != nil {
+			return err
+		}
+		return pullLFSObjects(ctx, cc, cfg.Value("branch."+headBranch+".remote"))
+	}
 	repo := f.Arg(0)
 	if repo == "" {
 		if _, ok := remotes["origin"]; !ok {
@@ -74,7 +142,7 @@ func pull(ctx context.Context, cc *cmdContext, args []string) error {
 	}
 
 	_, isNamedRemote := remotes[repo]
-	gitArgs, branches, err := buildFetchArgs(repo, isNamedRemote, allLocalRefs, allRemoteRefs, *remoteRefArgs)
+	gitArgs, branches, err := buildFetchArgs(repo, isNamedRemote, allLocalRefs, allRemoteRefs, *remoteRefArgs, fetchOpts)
 	if err != nil {
 		return err
 	}
@@ -93,6 +161,10 @@ func pull(ctx context.Context, cc *cmdContext, args []string) error {
 		}
 	}
 
+	if isNamedRemote {
+		warnIfDefaultBranchRenamed(ctx, cc, repo, allRemoteRefs)
+	}
+
 	err = cc.interactiveGit(ctx, gitArgs...)
 	if err != nil {
 		return err
@@ -104,7 +176,7 @@ func pull(ctx context.Context, cc *cmdContext, args []string) error {
 	if err := reconcileBranches(ctx, cc.git, headBranch, remoteName, allLocalRefs, allRemoteRefs, branches); err != nil {
 		return err
 	}
-	if *update && headBranch != "" {
+	if (*update || useRebase) && headBranch != "" {
 		var target git.Ref
 		if isNamedRemote {
 			headRef := git.BranchRef(headBranch)
@@ -117,15 +189,171 @@ func pull(ctx context.Context, cc *cmdContext, args []string) error {
 		} else {
 			target = git.Ref("refs/ggpull/" + headBranch)
 		}
-		if err := updateToBranch(ctx, cc.git, headBranch, target, git.MergeLocal); err != nil {
+		if useRebase {
+			err = rebaseOntoUpstream(ctx, cc, headBranch, target)
+		} else {
+			err = updateToBranch(ctx, cc.git, headBranch, target, git.MergeLocal)
+		}
+		if err != nil {
+			return err
+		}
+		if err := updateSubmodules(ctx, cc); err != nil {
+			return err
+		}
+		if err := pullLFSObjects(ctx, cc, remoteName); err != nil {
 			return err
 		}
 	}
 	return nil
 }
 
-func buildFetchArgs(repo string, isNamedRemote bool, localRefs, remoteRefs map[git.Ref]git.Hash, remoteRefArgs []string) (gitArgs []string, branches []git.Ref, _ error) {
+// fetchAllConcurrency is the maximum number of remotes that
+// pull --all fetches from at the same time.
+const fetchAllConcurrency = 4
+
+// fetchJobsVersion is the first version of Git whose fetch --jobs flag
+// fetches from several remotes at the same time.
+var fetchJobsVersion = gitVersion{2, 24, 0}
+
+// fetchAll fetches from every remote, pruning their remote-tracking
+// branches. A single git fetch --multiple does all the fetching, so
+// remotes are fetched in parallel (if Git supports it) without racing
+// on FETCH_HEAD or packed-refs.
+func fetchAll(ctx context.Context, cc *cmdContext, remotes map[string]*git.Remote, opts fetchOptions) error {
+	if len(remotes) == 0 {
+		return errors.New("no remotes configured")
+	}
+	names := make([]string, 0, len(remotes))
+	for name := range remotes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	// --tags is omitted so that pruning one remote does not delete tags
+	// from another.
+	args := []string{"fetch", "--multiple", "--prune"}
+	if v, err := queryGitVersion(ctx, cc.git); err == nil && !v.less(fetchJobsVersion) {
+		args = append(args, fmt.Sprintf("--jobs=%d", fetchAllConcurrency))
+	}
+	args = append(args, opts.args()...)
+	args = append(args, "--")
+	args = append(args, names...)
+	return cc.interactiveGit(ctx, args...)
+}
+
+// pullRebaseDefault reports whether pull.rebase asks pull -u to rebase
+// the current branch instead of fast-forwarding it.
+func pullRebaseDefault(cfg *git.Config) (bool, error) {
+	switch v := strings.ToLower(cfg.Value("pull.rebase")); v {
+	case "":
+		return false, nil
+	case "merges", "interactive", "m", "i":
+		// gg does not preserve merges or edit the rebase plan during a pull,
+		// but the intent to rebase is clear.
+		return true, nil
+	default:
+		return cfg.Bool("pull.rebase")
+	}
+}
+
+// rebaseOntoUpstream moves the commits on branch that are not in target
+// on top of target. branch must be checked out. If branch is behind target,
+// it is fast-forwarded instead.
+func rebaseOntoUpstream(ctx context.Context, cc *cmdContext, branch string, target git.Ref) error {
+	if target == "" {
+		return nil
+	}
+	if _, err := cc.git.ParseRev(ctx, target.String()); err != nil {
+		// Remote-tracking branch does not exist, so there is nothing to
+		// rebase onto.
+		return nil
+	}
+	branchRef := git.BranchRef(branch)
+	if _, err := cc.git.ParseRev(ctx, branchRef.String()); err != nil {
+		return updateUnbornBranch(ctx, cc, branch, target)
+	}
+	if isBehind, err := cc.git.IsAncestor(ctx, branchRef.String(), target.String()); err != nil {
+		return err
+	} else if isBehind {
+		return updateToBranch(ctx, cc.git, branch, target, git.MergeLocal)
+	}
+	if isAhead, err := cc.git.IsAncestor(ctx, target.String(), branchRef.String()); err != nil {
+		return err
+	} else if isAhead {
+		return nil
+	}
+	err := cc.interactiveGit(ctx, "rebase", "--autostash", "--no-fork-point", target.String())
+	if err == nil {
+		return nil
+	}
+	if op, opErr := readWorkTreeOperation(ctx, cc.git); opErr == nil && op == rebaseOperation {
+		return fmt.Errorf("%s has diverged from %s and the rebase stopped on a conflict. "+
+			"Resolve the conflicts and run 'gg rebase --continue', "+
+			"or run 'gg rebase --abort' to undo the rebase", branch, target)
+	}
+	return err
+}
+
+// fetchOptions limit the history transferred by a fetch.
+type fetchOptions struct {
+	depth        int    // if positive, number of commits from each tip
+	deepen       int    // if positive, number of commits to add to a shallow history
+	shallowSince string // if not empty, only fetch commits after this date
+	unshallow    bool   // convert a shallow repository to a complete one
+	filter       string // if not empty, a partial clone filter spec
+}
+
+// validate returns an error if opts has conflicting options.
+func (opts fetchOptions) validate() error {
+	switch {
+	case opts.depth < 0:
+		return errors.New("--depth must not be negative")
+	case opts.deepen < 0:
+		return errors.New("--deepen must not be negative")
+	}
+	n := 0
+	if opts.depth > 0 {
+		n++
+	}
+	if opts.deepen > 0 {
+		n++
+	}
+	if opts.shallowSince != "" {
+		n++
+	}
+	if opts.unshallow {
+		n++
+	}
+	if n > 1 {
+		return errors.New("can only pass one of --depth, --deepen, --shallow-since, or --unshallow")
+	}
+	return nil
+}
+
+// args returns the git fetch arguments for opts.
+func (opts fetchOptions) args() []string {
+	var args []string
+	if opts.depth > 0 {
+		args = append(args, fmt.Sprintf("--depth=%d", opts.depth))
+	}
+	if opts.deepen > 0 {
+		args = append(args, fmt.Sprintf("--deepen=%d", opts.deepen))
+	}
+	if opts.shallowSince != "" {
+		args = append(args, "--shallow-since="+opts.shallowSince)
+	}
+	if opts.unshallow {
+		args = append(args, "--unshallow")
+	}
+	if opts.filter != "" {
+		args = append(args, "--filter="+opts.filter)
+	}
+	return args
+}
+
+func buildFetchArgs(repo string, isNamedRemote bool, localRefs, remoteRefs map[git.Ref]git.Hash, remoteRefArgs []string, opts fetchOptions) (gitArgs []string, branches []git.Ref, _ error) {
 	gitArgs = []string{"fetch"}
+	gitArgs = append(gitArgs, opts.args()...)
 	if !isNamedRemote {
 		gitArgs = append(gitArgs, "--refmap=+refs/heads/*:refs/ggpull/*")
 	}
@@ -244,6 +472,28 @@ func reconcileBranches(ctx context.Context, g *git.Git, headBranch, remoteName s
 	return nil
 }
 
+// warnIfDefaultBranchRenamed prints a message to cc.stderr if the
+// remote's default branch that was recorded locally no longer exists on
+// the remote and the remote's HEAD now names a different branch.
+// It must be called before fetching, since pruning removes the old
+// remote-tracking branch.
+func warnIfDefaultBranchRenamed(ctx context.Context, cc *cmdContext, remote string, remoteRefs map[git.Ref]git.Hash) {
+	oldBranch := trackedDefaultBranch(ctx, cc.git, remote)
+	if oldBranch == "" {
+		return
+	}
+	if _, exists := remoteRefs[git.BranchRef(oldBranch)]; exists {
+		return
+	}
+	newBranch, err := remoteDefaultBranch(ctx, cc.git, remote)
+	if err != nil || newBranch == oldBranch {
+		return
+	}
+	fmt.Fprintf(cc.stderr, "gg: %s's default branch has changed from %s to %s.\n"+
+		"gg: Run `gg migrate-default-branch %s` to update local branches.\n",
+		remote, oldBranch, newBranch, remote)
+}
+
 func currentBranch(ctx context.Context, cc *cmdContext) string {
 	ref, err := cc.git.HeadRef(ctx)
 	if err != nil {
@@ -251,3 +501,42 @@ func currentBranch(ctx context.Context, cc *cmdContext) string {
 	}
 	return ref.Branch()
 }
+
+// checkNotInUpstream returns an error if h is already in the current
+// branch's upstream, since rewriting it would rewrite published history.
+// desc names h in the error message.
+func checkNotInUpstream(ctx context.Context, cc *cmdContext, h git.Hash, desc string) error {
+	b := currentBranch(ctx, cc)
+	if b == "" {
+		return nil
+	}
+	upstream := b + "@{upstream}"
+	if _, err := cc.git.ParseRev(ctx, upstream); err != nil {
+		return nil
+	}
+	inUpstream, err := cc.git.IsAncestor(ctx, h.String(), upstream)
+	if err != nil {
+		return err
+	}
+	if inUpstream {
+		return fmt.Errorf("%s is already in %s's upstream", desc, b)
+	}
+	return nil
+}
+
+// unbornBranch returns the name of the branch that HEAD points to if the
+// branch does not have any commits yet, as in a newly created repository.
+// It returns the empty string if HEAD refers to a commit or is detached.
+func unbornBranch(ctx context.Context, g *git.Git) (string, error) {
+	ref, err := g.HeadRef(ctx)
+	if err != nil {
+		return "", err
+	}
+	if !ref.IsBranch() {
+		return "", nil
+	}
+	if _, err := g.ParseRev(ctx, ref.String()); err == nil {
+		return "", nil
+	}
+	return ref.Branch(), nil
+}
@@ -16,6 +16,9 @@ package main
 
 import (
 	"context"
+	"net/url"
+	"path/filepath"
+	"strings"
 	"testing"
 
 	"gg-scm.io/pkg/git"
@@ -447,6 +450,219 @@ func TestPullRev(t *testing.T) {
 	}
 }
 
+func TestPullUnshallow(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	env, err := newTestEnv(ctx, t)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := env.initRepoWithHistory(ctx, "repoA"); err != nil {
+		t.Fatal(err)
+	}
+	if err := env.root.Apply(filesystem.Write("repoA/foo.txt", "more\n")); err != nil {
+		t.Fatal(err)
+	}
+	if err := env.addFiles(ctx, "repoA/foo.txt"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := env.newCommit(ctx, "repoA"); err != nil {
+		t.Fatal(err)
+	}
+	// Local clones ignore --depth, so use a file URL.
+	srcURL := &url.URL{Scheme: "file", Path: filepath.ToSlash(env.root.FromSlash("repoA"))}
+	if !strings.HasPrefix(srcURL.Path, "/") {
+		// Windows drive letter paths.
+		srcURL.Path = "/" + srcURL.Path
+	}
+	if _, err := env.gg(ctx, env.root.String(), "clone", "--depth=1", srcURL.String(), "repoB"); err != nil {
+		t.Fatal(err)
+	}
+	gitB := env.git.WithDir(env.root.FromSlash("repoB"))
+	if shallow, err := gitB.Output(ctx, "rev-parse", "--is-shallow-repository"); err != nil {
+		t.Fatal(err)
+	} else if strings.TrimSpace(shallow) != "true" {
+		t.Fatal("repository is not shallow after clone --depth=1")
+	}
+
+	if _, err := env.gg(ctx, env.root.FromSlash("repoB"), "pull", "--unshallow"); err != nil {
+		t.Fatal(err)
+	}
+	if shallow, err := gitB.Output(ctx, "rev-parse", "--is-shallow-repository"); err != nil {
+		t.Fatal(err)
+	} else if strings.TrimSpace(shallow) != "false" {
+		t.Error("repository is still shallow after pull --unshallow")
+	}
+}
+
+func TestPullRebase(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	env, err := newTestEnv(ctx, t)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := env.initRepoWithHistory(ctx, "repoA"); err != nil {
+		t.Fatal(err)
+	}
+	if err := env.git.Run(ctx, "clone", "--quiet", "repoA", "repoB"); err != nil {
+		t.Fatal(err)
+	}
+	if err := env.root.Apply(filesystem.Write("repoA/upstream.txt", dummyContent)); err != nil {
+		t.Fatal(err)
+	}
+	if err := env.addFiles(ctx, "repoA/upstream.txt"); err != nil {
+		t.Fatal(err)
+	}
+	upstreamCommit, err := env.newCommit(ctx, "repoA")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := env.root.Apply(filesystem.Write("repoB/local.txt", dummyContent)); err != nil {
+		t.Fatal(err)
+	}
+	if err := env.addFiles(ctx, "repoB/local.txt"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := env.newCommit(ctx, "repoB"); err != nil {
+		t.Fatal(err)
+	}
+
+	repoBPath := env.root.FromSlash("repoB")
+	if _, err := env.gg(ctx, repoBPath, "pull", "--rebase"); err != nil {
+		t.Fatal(err)
+	}
+	gitB := env.git.WithDir(repoBPath)
+	parent, err := gitB.ParseRev(ctx, "main~")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if parent.Commit != upstreamCommit {
+		t.Errorf("main~ = %v; want %v (upstream commit)", parent.Commit, upstreamCommit)
+	}
+	if head, err := gitB.Head(ctx); err != nil {
+		t.Fatal(err)
+	} else if head.Ref != "refs/heads/main" {
+		t.Errorf("HEAD = %v; want refs/heads/main", head.Ref)
+	}
+}
+
+func TestPullRebase_Config(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	env, err := newTestEnv(ctx, t)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := env.writeConfig([]byte("[pull]\nrebase = true\n")); err != nil {
+		t.Fatal(err)
+	}
+	if err := env.initRepoWithHistory(ctx, "repoA"); err != nil {
+		t.Fatal(err)
+	}
+	if err := env.git.Run(ctx, "clone", "--quiet", "repoA", "repoB"); err != nil {
+		t.Fatal(err)
+	}
+	if err := env.root.Apply(filesystem.Write("repoA/upstream.txt", dummyContent)); err != nil {
+		t.Fatal(err)
+	}
+	if err := env.addFiles(ctx, "repoA/upstream.txt"); err != nil {
+		t.Fatal(err)
+	}
+	upstreamCommit, err := env.newCommit(ctx, "repoA")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := env.root.Apply(filesystem.Write("repoB/local.txt", dummyContent)); err != nil {
+		t.Fatal(err)
+	}
+	if err := env.addFiles(ctx, "repoB/local.txt"); err != nil {
+		t.Fatal(err)
+	}
+	localCommit, err := env.newCommit(ctx, "repoB")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Without -u, pull only fetches.
+	repoBPath := env.root.FromSlash("repoB")
+	if _, err := env.gg(ctx, repoBPath, "pull"); err != nil {
+		t.Fatal(err)
+	}
+	gitB := env.git.WithDir(repoBPath)
+	if r, err := gitB.ParseRev(ctx, "main"); err != nil {
+		t.Fatal(err)
+	} else if r.Commit != localCommit {
+		t.Errorf("after pull, main = %v; want %v (unchanged)", r.Commit, localCommit)
+	}
+
+	if _, err := env.gg(ctx, repoBPath, "pull", "-u"); err != nil {
+		t.Fatal(err)
+	}
+	if parent, err := gitB.ParseRev(ctx, "main~"); err != nil {
+		t.Fatal(err)
+	} else if parent.Commit != upstreamCommit {
+		t.Errorf("after pull -u, main~ = %v; want %v (upstream commit)", parent.Commit, upstreamCommit)
+	}
+}
+
+func TestPullAll(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	env, err := newTestEnv(ctx, t)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := env.initRepoWithHistory(ctx, "repoA"); err != nil {
+		t.Fatal(err)
+	}
+	if err := env.git.Run(ctx, "clone", "--quiet", "repoA", "repoB"); err != nil {
+		t.Fatal(err)
+	}
+	if err := env.git.Run(ctx, "clone", "--quiet", "repoA", "repoC"); err != nil {
+		t.Fatal(err)
+	}
+	repoBPath := env.root.FromSlash("repoB")
+	gitB := env.git.WithDir(repoBPath)
+	if err := gitB.Run(ctx, "remote", "add", "fork", env.root.FromSlash("repoC")); err != nil {
+		t.Fatal(err)
+	}
+	if err := env.root.Apply(filesystem.Write("repoA/a.txt", dummyContent)); err != nil {
+		t.Fatal(err)
+	}
+	if err := env.addFiles(ctx, "repoA/a.txt"); err != nil {
+		t.Fatal(err)
+	}
+	commitA, err := env.newCommit(ctx, "repoA")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := env.root.Apply(filesystem.Write("repoC/c.txt", dummyContent)); err != nil {
+		t.Fatal(err)
+	}
+	if err := env.addFiles(ctx, "repoC/c.txt"); err != nil {
+		t.Fatal(err)
+	}
+	commitC, err := env.newCommit(ctx, "repoC")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := env.gg(ctx, repoBPath, "pull", "--all"); err != nil {
+		t.Fatal(err)
+	}
+	refs, err := gitB.ListRefs(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := refs["refs/remotes/origin/main"]; got != commitA {
+		t.Errorf("refs/remotes/origin/main = %v; want %v", got, commitA)
+	}
+	if got := refs["refs/remotes/fork/main"]; got != commitC {
+		t.Errorf("refs/remotes/fork/main = %v; want %v", got, commitC)
+	}
+}
+
 func TestPullRevTag(t *testing.T) {
 	t.Parallel()
 	ctx := context.Background()
@@ -0,0 +1,140 @@
+// Copyright 2021 The gg Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"gg-scm.io/pkg/git"
+	"gg-scm.io/tool/internal/flag"
+)
+
+const purgeSynopsis = "remove untracked files from the working copy"
+
+func purge(ctx context.Context, cc *cmdContext, args []string) error {
+	f := flag.NewFlagSet(true, "gg purge [--all] [--dry-run] [-y] [-k PATTERN [...]] [PATHSPEC [...]]", purgeSynopsis+`
+
+	purge lists the untracked files and directories in the working copy
+	(optionally limited to the given pathspecs), then asks for
+	confirmation before deleting them. `+"`-y`"+` skips the confirmation
+	and `+"`--dry-run`"+` only lists the files. Ignored files are kept
+	unless `+"`--all`"+` is given. Nested repositories are always kept.
+
+	Files matching a `+"`-k`"+` pattern or a `+"`gg.purge.keep`"+` setting
+	are kept too. The patterns use `+"`.gitignore`"+` syntax, and
+	`+"`gg.purge.keep`"+` may be given more than once, including in the
+	`+"`.ggconfig`"+` file. `+"`gg clean`"+` is an alias for purge.`)
+	all := f.Bool("all", false, "also remove ignored files")
+	dryRun := f.Bool("dry-run", false, "list the files that would be removed without removing them")
+	f.Alias("dry-run", "n", "print")
+	yes := f.Bool("y", false, "remove without asking for confirmation")
+	f.Alias("y", "yes")
+	keep := f.MultiString("k", "keep files matching `pattern`")
+	f.Alias("k", "keep")
+	if err := f.Parse(args); flag.IsHelp(err) {
+		f.Help(cc.stdout)
+		return nil
+	} else if err != nil {
+		return usagef("%v", err)
+	}
+	if *dryRun && *yes {
+		return usagef("can't pass both --dry-run and -y")
+	}
+	gcfg, err := readGGConfig(ctx, cc.git)
+	if err != nil {
+		return err
+	}
+	cleanArgs := []string{"-d"}
+	if *all {
+		cleanArgs = append(cleanArgs, "-x")
+	}
+	for _, pattern := range append(gcfg.Values("gg.purge.keep"), *keep...) {
+		cleanArgs = append(cleanArgs, "--exclude="+pattern)
+	}
+	dryRunArgs := append([]string{"clean", "--dry-run"}, cleanArgs...)
+	dryRunArgs = append(dryRunArgs, "--")
+	if f.NArg() == 0 {
+		// git clean only looks in the current directory by default.
+		dryRunArgs = append(dryRunArgs, ":/")
+	} else {
+		dryRunArgs = append(dryRunArgs, f.Args()...)
+	}
+
+	// Git translates its messages, so force the untranslated ones
+	// to parse them.
+	out := new(strings.Builder)
+	err = cc.git.Runner().RunGit(ctx, &git.Invocation{
+		Dir:    cc.dir,
+		Args:   dryRunArgs,
+		Env:    append(cc.env[:len(cc.env):len(cc.env)], "LC_ALL=C"),
+		Stdout: out,
+		Stderr: cc.stderr,
+	})
+	if err != nil {
+		return fmt.Errorf("git clean: %w", err)
+	}
+	var paths []string
+	for _, line := range strings.Split(out.String(), "\n") {
+		const prefix = "Would remove "
+		if !strings.HasPrefix(line, prefix) {
+			continue
+		}
+		p := line[len(prefix):]
+		if strings.HasPrefix(p, `"`) {
+			// Git quotes unusual file names like a C string.
+			if unquoted, err := strconv.Unquote(p); err == nil {
+				p = unquoted
+			}
+		}
+		paths = append(paths, p)
+	}
+	if len(paths) == 0 {
+		fmt.Fprintln(cc.stdout, "no untracked files")
+		return nil
+	}
+	for _, p := range paths {
+		fmt.Fprintf(cc.stdout, "remove %s\n", p)
+	}
+	summary := pluralize(len(paths), "file or directory", "files and directories")
+	if *dryRun {
+		fmt.Fprintf(cc.stdout, "would remove %s\n", summary)
+		return nil
+	}
+	if !*yes {
+		ok, err := confirm(cc, fmt.Sprintf("remove %s?", summary))
+		if err != nil {
+			return err
+		}
+		if !ok {
+			fmt.Fprintln(cc.stdout, "no files removed")
+			return nil
+		}
+	}
+	// Only remove the paths that were confirmed, in case the working copy
+	// changed in the meantime.
+	removeArgs := append([]string{"clean", "--force", "--quiet"}, cleanArgs...)
+	removeArgs = append(removeArgs, "--")
+	for _, p := range paths {
+		removeArgs = append(removeArgs, git.LiteralPath(p).String())
+	}
+	if err := cc.git.Run(ctx, removeArgs...); err != nil {
+		return err
+	}
+	fmt.Fprintf(cc.stdout, "removed %s\n", summary)
+	return nil
+}
@@ -0,0 +1,99 @@
+// Copyright 2021 The gg Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"gg-scm.io/tool/internal/filesystem"
+)
+
+func TestPurge(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	env, err := newTestEnv(ctx, t)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := env.initEmptyRepo(ctx, "."); err != nil {
+		t.Fatal(err)
+	}
+	if err := env.root.Apply(filesystem.Write(".gitignore", "*.log\n")); err != nil {
+		t.Fatal(err)
+	}
+	if err := env.addFiles(ctx, ".gitignore"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := env.newCommit(ctx, "."); err != nil {
+		t.Fatal(err)
+	}
+	if err := env.git.Run(ctx, "config", "gg.purge.keep", "*.keep"); err != nil {
+		t.Fatal(err)
+	}
+	err = env.root.Apply(
+		filesystem.Write("untracked.txt", dummyContent),
+		filesystem.Write("sub/untracked.txt", dummyContent),
+		filesystem.Write("debug.log", dummyContent),
+		filesystem.Write("notes.keep", dummyContent),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Mkdir(env.root.FromSlash("empty"), 0o777); err != nil {
+		t.Fatal(err)
+	}
+	checkExists := func(when string, want map[string]bool) {
+		t.Helper()
+		for name, wantExists := range want {
+			if exists, err := env.root.Exists(name); err != nil {
+				t.Error(err)
+			} else if exists != wantExists {
+				t.Errorf("after %s, exists(%q) = %t; want %t", when, name, exists, wantExists)
+			}
+		}
+	}
+
+	if _, err := env.gg(ctx, env.root.FromSlash("sub"), "purge", "--dry-run"); err != nil {
+		t.Fatal(err)
+	}
+	checkExists("purge --dry-run", map[string]bool{
+		"untracked.txt":     true,
+		"sub/untracked.txt": true,
+		"empty":             true,
+	})
+
+	if _, err := env.gg(ctx, env.root.FromSlash("sub"), "purge", "-y"); err != nil {
+		t.Fatal(err)
+	}
+	checkExists("purge -y", map[string]bool{
+		".gitignore":        true,
+		"untracked.txt":     false,
+		"sub/untracked.txt": false,
+		"empty":             false,
+		"debug.log":         true,
+		"notes.keep":        true,
+	})
+
+	if _, err := env.gg(ctx, env.root.String(), "purge", "-y", "--all"); err != nil {
+		t.Fatal(err)
+	}
+	checkExists("purge -y --all", map[string]bool{
+		".gitignore": true,
+		"debug.log":  false,
+		"notes.keep": true,
+	})
+}
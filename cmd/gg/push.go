@@ -15,27 +15,36 @@
 package main
 
 import (
+	"bytes"
 	"context"
 	"errors"
 	"fmt"
+	"io"
 	"sort"
 	"strings"
 
 	"gg-scm.io/pkg/git"
 	"gg-scm.io/tool/internal/flag"
+	"gg-scm.io/tool/internal/terminal"
 )
 
 const pushSynopsis = "push changes to the specified destination"
 
 func push(ctx context.Context, cc *cmdContext, args []string) error {
-	f := flag.NewFlagSet(true, "gg push [-f] [-r REF [...]] [--new-branch] [DST]", pushSynopsis+`
+	f := flag.NewFlagSet(true, "gg push [-f] [-r REF [...]] [--new-branch] [--preview] [DST]\n"+
+		"gg push [-f] [-r REV] -d BRANCH [--new-branch] [--preview] [DST]\n"+
+		"gg push --delete=BRANCH [...] [DST]", pushSynopsis+`
 
 	`+"`gg push`"+` pushes branches and tags to mirror the local repository in the
 	destination repository. It does not permit diverging commits unless `+"`-f`"+`
 	is passed. If the `+"`-r`"+` is not given, `+"`gg push`"+` will push all
 	branches that exist in both the local and destination repository as well as
 	all tags. The argument to `+"`-r`"+` must name a ref: it cannot be an
-	arbitrary commit.
+	arbitrary commit, unless `+"`-d`"+` is given.
+
+	`+"`-d BRANCH`"+` pushes the revision given by `+"`-r`"+` (or HEAD
+	if `+"`-r`"+` is not given) and its ancestors to the named branch in the
+	destination repository.
 
 	When no destination repository is given, tries to use the remote specified by
 	the configuration value of `+"`remote.pushDefault`"+` or the remoted called
@@ -44,11 +53,41 @@ func push(ctx context.Context, cc *cmdContext, args []string) error {
 	By default, `+"`gg push`"+` will fail instead of creating a new ref in the
 	destination repository. If this is desired (e.g. you are creating a new
 	branch), then you can pass `+"`--new-branch`"+` to override this check.
-	`+"`-f`"+` will also skip this check.`)
+	`+"`-f`"+` will also skip this check.
+
+	`+"`--preview`"+` (or `+"`--dry-run`"+`) lists the refs that would be
+	updated and the commits that would be sent to the destination repository
+	without pushing anything.
+
+	`+"`--delete`"+` removes the named branches from the destination repository
+	along with the corresponding remote-tracking branches. If the destination
+	is a GitHub repository and you have logged in with `+"`gg github-login`"+`,
+	`+"`gg push`"+` will warn about any open pull requests that use the
+	branches being deleted.
+
+	`+"`-f`"+` only overwrites a ref in the destination if it still points
+	to the commit in its remote-tracking branch, so a force push can't
+	discard commits that others pushed since the last `+"`gg pull`"+`.
+	If a ref that exists in the destination has no remote-tracking branch,
+	`+"`-f`"+` refuses to overwrite it. `+"`--no-lease`"+` skips this check.
+
+	`+"`--atomic`"+` requests that the destination update either all of the
+	refs or none of them. When writing to a terminal, `+"`gg push`"+` shows
+	Git's progress while objects are sent.
+
+	Branches listed in the `+"`gg.protectedBranch`"+` configuration setting
+	cannot be force pushed or deleted.`)
 	create := f.Bool("new-branch", false, "allow pushing a new ref")
+	deleteBranches := f.MultiString("delete", "delete `branch` in the destination repository")
 	force := f.Bool("f", false, "allow overwriting ref if it is not an ancestor, as long as it matches the remote-tracking branch")
 	f.Alias("f", "force")
+	noLease := f.Bool("no-lease", false, "with -f, overwrite refs even if they have changed since the last pull")
+	atomic := f.Bool("atomic", false, "update all refs in the destination or none of them")
 	refArgs := f.MultiString("r", "source `ref`s")
+	dstBranch := f.String("d", "", "destination `branch` for the revision given by -r")
+	f.Alias("d", "dest")
+	preview := f.Bool("preview", false, "show the refs and commits that would be pushed without pushing")
+	f.Alias("preview", "dry-run", "n")
 	if err := f.Parse(args); flag.IsHelp(err) {
 		f.Help(cc.stdout)
 		return nil
@@ -58,8 +97,17 @@ func push(ctx context.Context, cc *cmdContext, args []string) error {
 	if f.NArg() > 1 {
 		return usagef("can't pass multiple destinations")
 	}
+	if *noLease && !*force {
+		return usagef("can't pass --no-lease without --force")
+	}
 	refsImplicit := len(*refArgs) == 0
-	if refsImplicit && (*force || *create) {
+	if len(*deleteBranches) > 0 && (!refsImplicit || *force || *create || *dstBranch != "") {
+		return usagef("can't pass --delete with -r, -d, --force, or --new-branch")
+	}
+	if *dstBranch != "" && len(*refArgs) > 1 {
+		return usagef("can only pass one -r with -d")
+	}
+	if refsImplicit && *dstBranch == "" && (*force || *create) {
 		return usagef("can't pass --force or --new-branch without specifying refs")
 	}
 	dstRepo := f.Arg(0)
@@ -73,6 +121,22 @@ func push(ctx context.Context, cc *cmdContext, args []string) error {
 			return err
 		}
 	}
+	if len(*deleteBranches) > 0 {
+		return deleteRemoteBranches(ctx, cc, dstRepo, *deleteBranches)
+	}
+	if *dstBranch != "" {
+		rev := git.Head.String()
+		if !refsImplicit {
+			rev = (*refArgs)[0]
+		}
+		return pushRevToBranch(ctx, cc, dstRepo, rev, *dstBranch, &pushOptions{
+			force:   *force,
+			noLease: *noLease,
+			create:  *create,
+			preview: *preview,
+			atomic:  *atomic,
+		})
+	}
 	var refsToPush []git.Ref
 	if refsImplicit {
 		localRefs, err := cc.git.ListRefs(ctx)
@@ -98,11 +162,15 @@ func push(ctx context.Context, cc *cmdContext, args []string) error {
 		}
 	}
 
-	if !*force && !*create {
-		remoteRefs, err := cc.git.ListRemoteRefs(ctx, dstRepo)
+	var remoteRefs map[git.Ref]git.Hash
+	if !*force && !*create || *preview {
+		var err error
+		remoteRefs, err = cc.git.ListRemoteRefs(ctx, dstRepo)
 		if err != nil {
 			return err
 		}
+	}
+	if !*force && !*create {
 		n := 0
 		conflicts := false
 		for _, ref := range refsToPush {
@@ -127,27 +195,364 @@ func push(ctx context.Context, cc *cmdContext, args []string) error {
 	if len(refsToPush) == 0 {
 		return errors.New("no refs to push")
 	}
-
-	var pushArgs []string
-	pushArgs = append(pushArgs, "push")
 	if *force {
-		pushArgs = append(pushArgs, "--force-with-lease")
+		if err := checkForcePushAllowed(ctx, cc.git, refsToPush); err != nil {
+			return err
+		}
 	}
-	pushArgs = append(pushArgs, "--", dstRepo)
+	if *preview {
+		localRefs, err := cc.git.ListRefs(ctx)
+		if err != nil {
+			return err
+		}
+		return printPushPreview(ctx, cc, refsToPush, localRefs, remoteRefs)
+	}
+
+	var refspecs []string
 	for _, ref := range refsToPush {
 		if tag := ref.Tag(); tag != "" {
-			pushArgs = append(pushArgs, "tag", tag)
+			refspecs = append(refspecs, "tag", tag)
 		} else {
-			pushArgs = append(pushArgs, ref.String()+":"+ref.String())
+			refspecs = append(refspecs, ref.String()+":"+ref.String())
+		}
+	}
+	return runPush(ctx, cc, dstRepo, refspecs, refsToPush, &pushOptions{
+		force:   *force,
+		noLease: *noLease,
+		atomic:  *atomic,
+	})
+}
+
+// checkForcePushAllowed returns an error if any of the given refs is a
+// protected branch.
+func checkForcePushAllowed(ctx context.Context, g *git.Git, refs []git.Ref) error {
+	gcfg, err := readGGConfig(ctx, g)
+	if err != nil {
+		return err
+	}
+	for _, ref := range refs {
+		if b := ref.Branch(); b != "" && gcfg.isProtectedBranch(b) {
+			return fmt.Errorf("refusing to force push protected branch %s", b)
+		}
+	}
+	return nil
+}
+
+// pushOptions is the set of flags that affect how refs are pushed.
+type pushOptions struct {
+	force   bool // overwrite refs that are not ancestors of the new commit
+	noLease bool // with force, overwrite refs without checking their old values
+	create  bool // allow creating refs
+	preview bool // only print what would be pushed
+	atomic  bool // update all refs or none
+}
+
+// runPush runs git push with the given refspecs, which update the refs in
+// dsts. If opts.force is set, the push uses leases so that a destination
+// ref is only overwritten if it matches its remote-tracking branch.
+func runPush(ctx context.Context, cc *cmdContext, dstRepo string, refspecs []string, dsts []git.Ref, opts *pushOptions) error {
+	pushArgs := []string{"push"}
+	if terminal.IsTerminal(cc.stderr) {
+		// Git only reports progress on its own terminal, and its stderr is
+		// a pipe so that lease failures can be detected.
+		pushArgs = append(pushArgs, "--progress")
+	}
+	if opts.atomic {
+		pushArgs = append(pushArgs, "--atomic")
+	}
+	if opts.force && opts.noLease {
+		pushArgs = append(pushArgs, "--force")
+	} else if opts.force {
+		leases, err := pushLeases(ctx, cc.git, dstRepo, dsts)
+		if err != nil {
+			return err
+		}
+		pushArgs = append(pushArgs, leases...)
+	}
+	pushArgs = append(pushArgs, "--", dstRepo)
+	pushArgs = append(pushArgs, refspecs...)
+	var srcs []string
+	for _, spec := range refspecs {
+		src := strings.TrimPrefix(spec, "+")
+		if i := strings.IndexByte(src, ':'); i != -1 {
+			src = src[:i]
+		}
+		if src != "" {
+			srcs = append(srcs, src)
+		}
+	}
+	if err := pushLFSObjects(ctx, cc, dstRepo, srcs); err != nil {
+		return err
+	}
+	stderr := new(bytes.Buffer)
+	err := cc.git.Runner().RunGit(ctx, &git.Invocation{
+		Dir:    cc.dir,
+		Args:   pushArgs,
+		Stdin:  cc.stdin,
+		Stdout: cc.stdout,
+		Stderr: io.MultiWriter(cc.stderr, stderr),
+	})
+	if err != nil && opts.force && !opts.noLease && strings.Contains(stderr.String(), "(stale info)") {
+		return errors.New("destination has changed since it was last pulled; " +
+			"run 'gg pull' and check the new commits, or pass --no-lease to overwrite them")
+	}
+	if err != nil {
+		return fmt.Errorf("git push: %w", err)
+	}
+	return nil
+}
+
+// pushLeases returns the --force-with-lease arguments for overwriting the
+// given refs in the destination repository. The expected value of each ref
+// is its remote-tracking branch. A ref without a remote-tracking branch may
+// only be pushed if it does not exist in the destination yet, since there
+// is nothing to check its current value against.
+func pushLeases(ctx context.Context, g *git.Git, dstRepo string, dsts []git.Ref) ([]string, error) {
+	cfg, err := g.ReadConfig(ctx)
+	if err != nil {
+		return nil, err
+	}
+	remote := cfg.ListRemotes()[dstRepo]
+	localRefs, err := g.ListRefs(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var remoteRefs map[git.Ref]git.Hash
+	var leases []string
+	for _, ref := range dsts {
+		if remote != nil {
+			if tracking := remote.MapFetch(ref); tracking != "" {
+				if h, ok := localRefs[tracking]; ok {
+					leases = append(leases, "--force-with-lease="+ref.String()+":"+h.String())
+					continue
+				}
+			}
+		}
+		if remoteRefs == nil {
+			remoteRefs, err = g.ListRemoteRefs(ctx, dstRepo)
+			if err != nil {
+				return nil, err
+			}
+		}
+		if _, ok := remoteRefs[ref]; ok {
+			return nil, fmt.Errorf("%s has no remote-tracking branch to check before overwriting it; "+
+				"run 'gg pull' and check the new commits, or pass --no-lease to overwrite it", ref)
+		}
+		// Empty expected value: ref must not exist.
+		leases = append(leases, "--force-with-lease="+ref.String()+":")
+	}
+	return leases, nil
+}
+
+// pushRevToBranch pushes the revision rev to branch in the destination
+// repository. The push goes through runPush, so it honors the same
+// options as pushing refs.
+func pushRevToBranch(ctx context.Context, cc *cmdContext, dstRepo string, rev string, branch string, opts *pushOptions) error {
+	dst := git.BranchRef(strings.TrimPrefix(branch, "refs/heads/"))
+	if !dst.IsValid() {
+		return fmt.Errorf("%q is not a valid branch name", branch)
+	}
+	resolved, err := cc.git.ParseRev(ctx, rev)
+	if err != nil {
+		return err
+	}
+	remoteRefs, err := cc.git.ListRemoteRefs(ctx, dstRepo)
+	if err != nil {
+		return err
+	}
+	if _, exists := remoteRefs[dst]; !exists && !opts.create && !opts.force {
+		return fmt.Errorf("push would create branch %s (if this is what you want, run again with --new-branch)", dst.Branch())
+	}
+	if opts.force {
+		if err := checkForcePushAllowed(ctx, cc.git, []git.Ref{dst}); err != nil {
+			return err
+		}
+	}
+	if opts.preview {
+		localRefs := map[git.Ref]git.Hash{dst: resolved.Commit}
+		return printPushPreview(ctx, cc, []git.Ref{dst}, localRefs, remoteRefs)
+	}
+	refspecs := []string{resolved.Commit.String() + ":" + dst.String()}
+	return runPush(ctx, cc, dstRepo, refspecs, []git.Ref{dst}, opts)
+}
+
+// printPushPreview prints the refs that would be updated by pushing the
+// given refs and the commits that the destination does not have.
+func printPushPreview(ctx context.Context, cc *cmdContext, refsToPush []git.Ref, localRefs, remoteRefs map[git.Ref]git.Hash) error {
+	var revs []string
+	for _, ref := range refsToPush {
+		local := localRefs[ref]
+		remote, exists := remoteRefs[ref]
+		switch {
+		case exists && remote == local:
+			continue
+		case exists:
+			fmt.Fprintf(cc.stdout, "update %s: %s -> %s\n", ref, remote.Short(), local.Short())
+		case ref.IsTag():
+			fmt.Fprintf(cc.stdout, "new tag %s: %s\n", ref.Tag(), local.Short())
+		default:
+			fmt.Fprintf(cc.stdout, "new branch %s: %s\n", ref.Branch(), local.Short())
+		}
+		revs = append(revs, local.String())
+	}
+	if len(revs) == 0 {
+		_, err := fmt.Fprintln(cc.stdout, "no changes")
+		return err
+	}
+
+	// Exclude any commits that the destination already has. Remote commits
+	// that aren't present locally can't be ancestors of local commits, so
+	// --ignore-missing skips them without looking each one up. The
+	// revisions are sent on stdin, since the destination may have more refs
+	// than fit on a command line.
+	stdin := new(strings.Builder)
+	for _, rev := range revs {
+		stdin.WriteString(rev + "\n")
+	}
+	for _, h := range remoteRefs {
+		stdin.WriteString("^" + h.String() + "\n")
+	}
+	stdout := new(strings.Builder)
+	stderr := new(bytes.Buffer)
+	err := cc.git.Runner().RunGit(ctx, &git.Invocation{
+		Args:   []string{"log", "--ignore-missing", "--format=%H%x00%s", "--stdin", "--"},
+		Dir:    cc.dir,
+		Env:    cc.env,
+		Stdin:  strings.NewReader(stdin.String()),
+		Stdout: stdout,
+		Stderr: stderr,
+	})
+	if err != nil {
+		return fmt.Errorf("git log: %w: %s", err, bytes.TrimSpace(stderr.Bytes()))
+	}
+	out := stdout.String()
+	header := false
+	for _, line := range strings.Split(strings.TrimSuffix(out, "\n"), "\n") {
+		i := strings.IndexByte(line, 0)
+		if i == -1 {
+			continue
+		}
+		h, err := git.ParseHash(line[:i])
+		if err != nil {
+			return err
+		}
+		if !header {
+			fmt.Fprintln(cc.stdout, "\ncommits:")
+			header = true
+		}
+		fmt.Fprintf(cc.stdout, "%s %s\n", h.Short(), line[i+1:])
+	}
+	return nil
+}
+
+// deleteRemoteBranches deletes the given branches from the destination
+// repository.
+func deleteRemoteBranches(ctx context.Context, cc *cmdContext, dstRepo string, branches []string) error {
+	remoteRefs, err := cc.git.ListRemoteRefs(ctx, dstRepo)
+	if err != nil {
+		return err
+	}
+	gcfg, err := readGGConfig(ctx, cc.git)
+	if err != nil {
+		return err
+	}
+	var refs []git.Ref
+	missing := false
+	for _, b := range branches {
+		ref := git.BranchRef(strings.TrimPrefix(b, "refs/heads/"))
+		if !ref.IsValid() {
+			return fmt.Errorf("%q is not a valid branch name", b)
+		}
+		if gcfg.isProtectedBranch(ref.Branch()) {
+			return fmt.Errorf("refusing to delete protected branch %s", ref.Branch())
 		}
+		if _, exists := remoteRefs[ref]; !exists {
+			fmt.Fprintf(cc.stderr, "gg: push: %q does not exist on remote\n", ref)
+			missing = true
+			continue
+		}
+		refs = append(refs, ref)
+	}
+	if missing {
+		return errors.New("cannot delete refs that do not exist")
+	}
+
+	cfg, err := cc.git.ReadConfig(ctx)
+	if err != nil {
+		return err
+	}
+	for _, ref := range refs {
+		warnOpenPullRequests(ctx, cc, cfg, dstRepo, ref.Branch())
+	}
+
+	pushArgs := []string{"push", "--", dstRepo}
+	for _, ref := range refs {
+		pushArgs = append(pushArgs, ":"+ref.String())
 	}
 	return cc.interactiveGit(ctx, pushArgs...)
 }
 
+// warnOpenPullRequests prints a warning for each open GitHub pull request
+// that has the given branch as its head or base. It does nothing if dstRepo
+// is not a GitHub repository or the user has not logged into GitHub.
+func warnOpenPullRequests(ctx context.Context, cc *cmdContext, cfg *git.Config, dstRepo string, branch string) {
+	repoURL := cfg.Value("remote." + dstRepo + ".url")
+	if repoURL == "" {
+		repoURL = dstRepo
+	}
+	gcfg, err := readGGConfig(ctx, cc.git)
+	if err != nil {
+		return
+	}
+	forge, owner, repo := parsePullRequestRemoteURL(gcfg, repoURL)
+	gh, ok := forge.(gitHubForge)
+	if !ok {
+		return
+	}
+	token, err := cc.xdgDirs.readConfig(gh.tokenFilename())
+	if err != nil {
+		return
+	}
+	params := listPullRequestsParams{
+		authToken: string(bytes.TrimSpace(token)),
+		apiRoot:   gh.apiRoot,
+		owner:     owner,
+		repo:      repo,
+	}
+	headParams := params
+	headParams.head = owner + ":" + branch
+	baseParams := params
+	baseParams.base = branch
+	for _, p := range []listPullRequestsParams{headParams, baseParams} {
+		prs, err := listOpenPullRequests(ctx, cc.httpClient, p)
+		if err != nil {
+			fmt.Fprintln(cc.stderr, "gg:", err)
+			continue
+		}
+		for _, pr := range prs {
+			fmt.Fprintf(cc.stderr, "gg: warning: %s is used by open pull request %s\n", branch, pr.HTMLURL)
+		}
+	}
+}
+
 const mailSynopsis = "creates or updates a Gerrit change"
 
 func mail(ctx context.Context, cc *cmdContext, args []string) error {
-	f := flag.NewFlagSet(true, "gg mail [options] [DST]", mailSynopsis)
+	f := flag.NewFlagSet(true, "gg mail [options] [DST]", mailSynopsis+`
+
+	mail pushes the source revision to Gerrit's magic `+"`refs/for/BRANCH`"+`
+	ref, which creates a new change or uploads a new patch set to an
+	existing one. Gerrit matches commits to changes using the Change-Id
+	trailer in the commit message. Setting `+"`gg.gerrit.changeId`"+` to
+	true makes `+"`gg commit`"+` and `+"`gg amend`"+` add a Change-Id
+	trailer to commit messages that don't have one, without needing to
+	install the hook from `+"`gg gerrithook`"+`.
+
+	The destination branch defaults to the upstream of the source branch.
+	`+"`--topic`"+` groups the change with other changes under the given
+	topic name. If `+"`-m`"+` is not given and the source branch has a
+	description (see `+"`gg branch --edit-description`"+`), the
+	description is used as the comment message.`)
 	allowDirty := f.Bool("allow-dirty", false, "allow mailing when working copy has uncommitted changes")
 	dstBranch := f.String("d", "", "destination `branch`")
 	f.Alias("d", "dest", "for")
@@ -164,6 +569,7 @@ func mail(ctx context.Context, cc *cmdContext, args []string) error {
 	f.StringVar(&gopts.message, "m", "", "use text as comment `message`")
 	f.BoolVar(&gopts.publishComments, "p", false, "publish draft comments")
 	f.Alias("p", "publish-comments")
+	f.StringVar(&gopts.topic, "topic", "", "set the change's `topic`")
 	if err := f.Parse(args); flag.IsHelp(err) {
 		f.Help(cc.stdout)
 		return nil
@@ -180,10 +586,19 @@ func mail(ctx context.Context, cc *cmdContext, args []string) error {
 	if gopts.notify != "" && gopts.notify != "NONE" && gopts.notify != "OWNER" && gopts.notify != "OWNER_REVIEWERS" && gopts.notify != "ALL" {
 		return usagef(`--notify must be one of "none", "owner", "owner_reviewers", or "all"`)
 	}
+	if strings.ContainsAny(gopts.topic, ",% ") {
+		return usagef("--topic must not contain commas, percent signs, or spaces")
+	}
 	src, err := cc.git.ParseRev(ctx, *rev)
 	if err != nil {
 		return err
 	}
+	if c, err := cc.git.CommitInfo(ctx, src.Commit.String()); err != nil {
+		return err
+	} else if findChangeID(c.Message) == "" {
+		fmt.Fprintf(cc.stderr, "gg: warning: %v has no Change-Id trailer; Gerrit may reject it. "+
+			"Set gg.gerrit.changeId or run `gg gerrithook` and amend the commit.\n", src.Commit.Short())
+	}
 	srcBranch := src.Ref.Branch()
 	if srcBranch == "" {
 		possible, err := branchesContaining(ctx, cc.git, src.Commit.String())
@@ -203,7 +618,7 @@ func mail(ctx context.Context, cc *cmdContext, args []string) error {
 	}
 	dstRepo := f.Arg(0)
 	var cfg *git.Config
-	if dstRepo == "" || *dstBranch == "" {
+	if dstRepo == "" || *dstBranch == "" || gopts.message == "" && srcBranch != "" {
 		var err error
 		cfg, err = cc.git.ReadConfig(ctx)
 		if err != nil {
@@ -230,6 +645,9 @@ func mail(ctx context.Context, cc *cmdContext, args []string) error {
 	} else {
 		*dstBranch = strings.TrimPrefix(*dstBranch, "refs/for/")
 	}
+	if gopts.message == "" {
+		gopts.message = branchDescription(cfg, srcBranch)
+	}
 	ref := gerritPushRef(*dstBranch, gopts)
 	return cc.interactiveGit(ctx, "push", "--", dstRepo, src.Commit.String()+":"+ref.String())
 }
@@ -239,6 +657,7 @@ type gerritOptions struct {
 	cc              []string // unflattened (may contain comma-separated elements)
 	publishComments bool
 	message         string
+	topic           string
 
 	notify    string // one of "", "NONE", "OWNER", "OWNER_REVIEWERS", or "ALL"
 	notifyTo  []string
@@ -273,6 +692,10 @@ func gerritPushRef(branch string, opts *gerritOptions) git.Ref {
 			sb.WriteString(",m=")
 			escapeGerritMessage(sb, opts.message)
 		}
+		if opts.topic != "" {
+			sb.WriteString(",topic=")
+			sb.WriteString(opts.topic)
+		}
 		if opts.notify != "" {
 			sb.WriteString(",notify=")
 			sb.WriteString(opts.notify)
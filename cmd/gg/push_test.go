@@ -322,6 +322,197 @@ func TestPush_CreateRef(t *testing.T) {
 	}
 }
 
+func TestPush_DestBranch(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	env, err := newTestEnv(ctx, t)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := env.initRepoWithHistory(ctx, "repoA"); err != nil {
+		t.Fatal(err)
+	}
+	repoAPath := env.root.FromSlash("repoA")
+	gitA := env.git.WithDir(repoAPath)
+	rev1, err := gitA.Head(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := env.git.InitBare(ctx, "repoB"); err != nil {
+		t.Fatal(err)
+	}
+	repoBPath := env.root.FromSlash("repoB")
+	if err := gitA.Run(ctx, "remote", "add", "origin", repoBPath); err != nil {
+		t.Fatal(err)
+	}
+	if err := gitA.Run(ctx, "push", "--set-upstream", "origin", "main"); err != nil {
+		t.Fatal(err)
+	}
+	if err := env.root.Apply(filesystem.Write("repoA/foo.txt", dummyContent)); err != nil {
+		t.Fatal(err)
+	}
+	if err := env.addFiles(ctx, "repoA/foo.txt"); err != nil {
+		t.Fatal(err)
+	}
+	commit2, err := env.newCommit(ctx, "repoA")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Pushing to a new branch requires --new-branch.
+	if _, err := env.gg(ctx, repoAPath, "push", "-r", "HEAD", "-d", "review"); err == nil {
+		t.Error("push -d to a new branch without --new-branch did not return an error")
+	}
+	if _, err := env.gg(ctx, repoAPath, "push", "-r", "HEAD", "-d", "review", "--new-branch"); err != nil {
+		t.Fatal(err)
+	}
+
+	commit1 := rev1.Commit
+	commitNames := map[git.Hash]string{
+		commit1: "shared commit",
+		commit2: "local commit",
+	}
+	gitB := env.git.WithDir(repoBPath)
+	if r, err := gitB.ParseRev(ctx, "refs/heads/review"); err != nil {
+		t.Error(err)
+	} else if r.Commit != commit2 {
+		t.Errorf("refs/heads/review = %s; want %s",
+			prettyCommit(r.Commit, commitNames),
+			prettyCommit(commit2, commitNames))
+	}
+	if r, err := gitB.ParseRev(ctx, "refs/heads/main"); err != nil {
+		t.Error(err)
+	} else if r.Commit != commit1 {
+		t.Errorf("refs/heads/main = %s; want %s",
+			prettyCommit(r.Commit, commitNames),
+			prettyCommit(commit1, commitNames))
+	}
+}
+
+func TestPush_Preview(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	env, err := newTestEnv(ctx, t)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Create repository with some junk history.
+	if err := env.initRepoWithHistory(ctx, "repoA"); err != nil {
+		t.Fatal(err)
+	}
+	repoAPath := env.root.FromSlash("repoA")
+	gitA := env.git.WithDir(repoAPath)
+	rev1, err := gitA.Head(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Push from repo A to repo B.
+	if err := env.git.InitBare(ctx, "repoB"); err != nil {
+		t.Fatal(err)
+	}
+	repoBPath := env.root.FromSlash("repoB")
+	if err := gitA.Run(ctx, "remote", "add", "origin", repoBPath); err != nil {
+		t.Fatal(err)
+	}
+	if err := gitA.Run(ctx, "push", "--set-upstream", "origin", "main"); err != nil {
+		t.Fatal(err)
+	}
+
+	// Create a new commit in repo A.
+	if err := env.root.Apply(filesystem.Write("repoA/foo.txt", dummyContent)); err != nil {
+		t.Fatal(err)
+	}
+	if err := env.addFiles(ctx, "repoA/foo.txt"); err != nil {
+		t.Fatal(err)
+	}
+	commit2, err := env.newCommit(ctx, "repoA")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	out, err := env.gg(ctx, repoAPath, "push", "--preview")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "update refs/heads/main: " + rev1.Commit.Short() + " -> " + commit2.Short() + "\n"; !strings.Contains(string(out), want) {
+		t.Errorf("output = %q; want to contain %q", out, want)
+	}
+	if !strings.Contains(string(out), "\n"+commit2.Short()+" ") {
+		t.Errorf("output = %q; want to list commit %s", out, commit2.Short())
+	}
+	if strings.Contains(string(out), "\n"+rev1.Commit.Short()+" ") {
+		t.Errorf("output = %q; lists already pushed commit %s", out, rev1.Commit.Short())
+	}
+
+	// Verify that repo B has not changed.
+	gitB := env.git.WithDir(repoBPath)
+	if r, err := gitB.ParseRev(ctx, "refs/heads/main"); err != nil {
+		t.Error(err)
+	} else if r.Commit != rev1.Commit {
+		t.Errorf("refs/heads/main = %v; want %v", r.Commit, rev1.Commit)
+	}
+}
+
+func TestPush_Delete(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	env, err := newTestEnv(ctx, t)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Create repository with some junk history.
+	if err := env.initRepoWithHistory(ctx, "repoA"); err != nil {
+		t.Fatal(err)
+	}
+	repoAPath := env.root.FromSlash("repoA")
+	gitA := env.git.WithDir(repoAPath)
+
+	// Push main and foo from repo A to repo B.
+	if err := env.git.InitBare(ctx, "repoB"); err != nil {
+		t.Fatal(err)
+	}
+	repoBPath := env.root.FromSlash("repoB")
+	if err := gitA.Run(ctx, "remote", "add", "origin", repoBPath); err != nil {
+		t.Fatal(err)
+	}
+	if err := gitA.NewBranch(ctx, "foo", git.BranchOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := gitA.Run(ctx, "push", "origin", "main", "foo"); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := env.gg(ctx, repoAPath, "push", "--delete", "foo"); err != nil {
+		t.Error(err)
+	}
+
+	// Verify that repo B's foo branch is gone but main remains.
+	gitB := env.git.WithDir(repoBPath)
+	if r, err := gitB.ParseRev(ctx, "refs/heads/foo"); err == nil {
+		t.Errorf("refs/heads/foo = %v in destination; should not exist", r.Commit)
+	}
+	if _, err := gitB.ParseRev(ctx, "refs/heads/main"); err != nil {
+		t.Error(err)
+	}
+	// Verify that the remote-tracking branch is gone but the local branch remains.
+	if r, err := gitA.ParseRev(ctx, "refs/remotes/origin/foo"); err == nil {
+		t.Errorf("refs/remotes/origin/foo = %v; should not exist", r.Commit)
+	}
+	if _, err := gitA.ParseRev(ctx, "refs/heads/foo"); err != nil {
+		t.Error(err)
+	}
+
+	// Deleting a branch that doesn't exist on the remote should fail.
+	if _, err := env.gg(ctx, repoAPath, "push", "--delete", "foo"); err == nil {
+		t.Error("gg push --delete of missing branch did not return an error")
+	} else if isUsage(err) {
+		t.Error(err)
+	}
+}
+
 func TestPush_RewindFails(t *testing.T) {
 	t.Parallel()
 	ctx := context.Background()
@@ -474,6 +665,183 @@ func TestPush_RewindForce(t *testing.T) {
 	}
 }
 
+func TestPush_ForceLeaseFails(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	env, err := newTestEnv(ctx, t)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Create repository with some junk history and push it to repo B.
+	if err := env.initRepoWithHistory(ctx, "repoA"); err != nil {
+		t.Fatal(err)
+	}
+	repoAPath := env.root.FromSlash("repoA")
+	gitA := env.git.WithDir(repoAPath)
+	rev1, err := gitA.Head(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := env.git.InitBare(ctx, "repoB"); err != nil {
+		t.Fatal(err)
+	}
+	repoBPath := env.root.FromSlash("repoB")
+	if err := gitA.Run(ctx, "remote", "add", "origin", repoBPath); err != nil {
+		t.Fatal(err)
+	}
+	if err := gitA.Run(ctx, "push", "--set-upstream", "origin", "main"); err != nil {
+		t.Fatal(err)
+	}
+
+	// Push a new commit to repo B from repo C without repo A seeing it.
+	if err := env.git.Run(ctx, "clone", "--quiet", "repoB", "repoC"); err != nil {
+		t.Fatal(err)
+	}
+	if err := env.root.Apply(filesystem.Write("repoC/foo.txt", dummyContent)); err != nil {
+		t.Fatal(err)
+	}
+	if err := env.addFiles(ctx, "repoC/foo.txt"); err != nil {
+		t.Fatal(err)
+	}
+	commit2, err := env.newCommit(ctx, "repoC")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := env.git.WithDir(env.root.FromSlash("repoC")).Run(ctx, "push", "origin", "main"); err != nil {
+		t.Fatal(err)
+	}
+
+	// Amend the commit in repo A so that pushing requires -f.
+	if err := env.root.Apply(filesystem.Write("repoA/bar.txt", dummyContent)); err != nil {
+		t.Fatal(err)
+	}
+	if err := env.addFiles(ctx, "repoA/bar.txt"); err != nil {
+		t.Fatal(err)
+	}
+	if err := gitA.Run(ctx, "commit", "--amend", "--quiet", "--no-edit"); err != nil {
+		t.Fatal(err)
+	}
+	commit3, err := gitA.Head(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	commitNames := map[git.Hash]string{
+		rev1.Commit:    "shared commit",
+		commit2:        "commit from repo C",
+		commit3.Commit: "amended commit",
+	}
+	gitB := env.git.WithDir(repoBPath)
+
+	// The lease should fail because repo B has moved.
+	if _, err := env.gg(ctx, repoAPath, "push", "-f", "-r", "main"); err == nil {
+		t.Error("push -f succeeded after destination changed")
+	}
+	if r, err := gitB.ParseRev(ctx, "refs/heads/main"); err != nil {
+		t.Error(err)
+	} else if r.Commit != commit2 {
+		t.Errorf("after push -f, refs/heads/main = %s; want %s",
+			prettyCommit(r.Commit, commitNames),
+			prettyCommit(commit2, commitNames))
+	}
+
+	// Pushing the revision with -d checks the same lease.
+	if _, err := env.gg(ctx, repoAPath, "push", "-f", "-d", "main"); err == nil {
+		t.Error("push -f -d succeeded after destination changed")
+	}
+	if r, err := gitB.ParseRev(ctx, "refs/heads/main"); err != nil {
+		t.Error(err)
+	} else if r.Commit != commit2 {
+		t.Errorf("after push -f -d, refs/heads/main = %s; want %s",
+			prettyCommit(r.Commit, commitNames),
+			prettyCommit(commit2, commitNames))
+	}
+
+	// --no-lease overwrites the branch anyway.
+	if _, err := env.gg(ctx, repoAPath, "push", "-f", "--no-lease", "-r", "main"); err != nil {
+		t.Error(err)
+	}
+	if r, err := gitB.ParseRev(ctx, "refs/heads/main"); err != nil {
+		t.Error(err)
+	} else if r.Commit != commit3.Commit {
+		t.Errorf("after push -f --no-lease, refs/heads/main = %s; want %s",
+			prettyCommit(r.Commit, commitNames),
+			prettyCommit(commit3.Commit, commitNames))
+	}
+}
+
+func TestPush_ForceWithoutTrackingBranch(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	env, err := newTestEnv(ctx, t)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Push some history to repo B by URL, so that repo A has no
+	// remote-tracking branch for it.
+	if err := env.initRepoWithHistory(ctx, "repoA"); err != nil {
+		t.Fatal(err)
+	}
+	repoAPath := env.root.FromSlash("repoA")
+	gitA := env.git.WithDir(repoAPath)
+	rev1, err := gitA.Head(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := env.git.InitBare(ctx, "repoB"); err != nil {
+		t.Fatal(err)
+	}
+	repoBPath := env.root.FromSlash("repoB")
+	if err := gitA.Run(ctx, "push", repoBPath, "main"); err != nil {
+		t.Fatal(err)
+	}
+
+	// Amend the commit in repo A so that pushing requires -f.
+	if err := env.root.Apply(filesystem.Write("repoA/bar.txt", dummyContent)); err != nil {
+		t.Fatal(err)
+	}
+	if err := env.addFiles(ctx, "repoA/bar.txt"); err != nil {
+		t.Fatal(err)
+	}
+	if err := gitA.Run(ctx, "commit", "--amend", "--quiet", "--no-edit"); err != nil {
+		t.Fatal(err)
+	}
+	rev2, err := gitA.Head(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	commitNames := map[git.Hash]string{
+		rev1.Commit: "shared commit",
+		rev2.Commit: "amended commit",
+	}
+	gitB := env.git.WithDir(repoBPath)
+
+	// Without a remote-tracking branch, there's nothing to lease against.
+	if _, err := env.gg(ctx, repoAPath, "push", "-f", "-r", "main", repoBPath); err == nil {
+		t.Error("push -f succeeded without a remote-tracking branch")
+	}
+	if r, err := gitB.ParseRev(ctx, "refs/heads/main"); err != nil {
+		t.Error(err)
+	} else if r.Commit != rev1.Commit {
+		t.Errorf("after push -f, refs/heads/main = %s; want %s",
+			prettyCommit(r.Commit, commitNames),
+			prettyCommit(rev1.Commit, commitNames))
+	}
+
+	// --no-lease overwrites the branch anyway.
+	if _, err := env.gg(ctx, repoAPath, "push", "-f", "--no-lease", "-r", "main", repoBPath); err != nil {
+		t.Error(err)
+	}
+	if r, err := gitB.ParseRev(ctx, "refs/heads/main"); err != nil {
+		t.Error(err)
+	} else if r.Commit != rev2.Commit {
+		t.Errorf("after push -f --no-lease, refs/heads/main = %s; want %s",
+			prettyCommit(r.Commit, commitNames),
+			prettyCommit(rev2.Commit, commitNames))
+	}
+}
+
 func TestPush_DistinctPushURL(t *testing.T) {
 	t.Parallel()
 	ctx := context.Background()
@@ -820,6 +1188,19 @@ func TestGerritPushRef(t *testing.T) {
 				"no-publish-comments": nil,
 			},
 		},
+		{
+			branch: "main",
+			opts: &gerritOptions{
+				reviewers: []string{"a@a.com"},
+				topic:     "fix-widgets",
+			},
+			wantRef: "refs/for/main",
+			wantOpts: map[string][]string{
+				"r":                   {"a@a.com"},
+				"topic":               {"fix-widgets"},
+				"no-publish-comments": nil,
+			},
+		},
 	}
 	for _, test := range tests {
 		out := gerritPushRef(test.branch, test.opts)
@@ -0,0 +1,82 @@
+// Copyright 2021 The gg Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+//go:build !windows
+// +build !windows
+
+package main
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"gg-scm.io/pkg/git"
+	"gg-scm.io/tool/internal/filesystem"
+)
+
+func TestPush_Atomic(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	env, err := newTestEnv(ctx, t)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := env.initRepoWithHistory(ctx, "repoA"); err != nil {
+		t.Fatal(err)
+	}
+	repoAPath := env.root.FromSlash("repoA")
+	gitA := env.git.WithDir(repoAPath)
+	for _, name := range []string{"good", "bad"} {
+		if err := gitA.NewBranch(ctx, name, git.BranchOptions{}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Create a destination that refuses to update the "bad" branch.
+	if err := env.git.InitBare(ctx, "repoB"); err != nil {
+		t.Fatal(err)
+	}
+	repoBPath := env.root.FromSlash("repoB")
+	if err := gitA.Run(ctx, "remote", "add", "origin", repoBPath); err != nil {
+		t.Fatal(err)
+	}
+	const hook = "#!/bin/sh\ntest \"$1\" != refs/heads/bad\n"
+	if err := env.root.Apply(filesystem.Write("repoB/hooks/update", hook)); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chmod(env.root.FromSlash("repoB/hooks/update"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	gitB := env.git.WithDir(repoBPath)
+	if _, err := env.gg(ctx, repoAPath, "push", "--atomic", "--new-branch", "-r", "good", "-r", "bad"); err == nil {
+		t.Error("push --atomic with a rejected ref did not return error")
+	} else if isUsage(err) {
+		t.Errorf("push --atomic with a rejected ref returned usage error: %v", err)
+	}
+	if r, err := gitB.ParseRev(ctx, "refs/heads/good"); err == nil {
+		t.Errorf("refs/heads/good = %v after failed push --atomic; want to not exist", r.Commit)
+	}
+
+	// Without --atomic, the accepted ref is updated.
+	if _, err := env.gg(ctx, repoAPath, "push", "--new-branch", "-r", "good", "-r", "bad"); err == nil {
+		t.Error("push with a rejected ref did not return error")
+	}
+	if _, err := gitB.ParseRev(ctx, "refs/heads/good"); err != nil {
+		t.Error("refs/heads/good not pushed without --atomic:", err)
+	}
+}
@@ -15,12 +15,19 @@
 package main
 
 import (
+	"bytes"
 	"context"
 	"errors"
 	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
 	"strings"
 
 	"gg-scm.io/pkg/git"
+	"gg-scm.io/pkg/git/object"
 	"gg-scm.io/tool/internal/escape"
 	"gg-scm.io/tool/internal/flag"
 )
@@ -29,31 +36,74 @@ const rebaseSynopsis = "move revision (and descendants) to a different branch"
 
 func rebase(ctx context.Context, cc *cmdContext, args []string) error {
 	const upstreamRev = "@{upstream}"
-	f := flag.NewFlagSet(true, "gg rebase [--src REV | --base REV] [--dst REV] [options]", rebaseSynopsis+`
+	f := flag.NewFlagSet(true, "gg rebase [--src REV | --base REV] [--dst REV] [options]\n"+
+		"gg rebase [--src REV | --base REV] [--dst REV] --onto REV [options]\n"+
+		"gg rebase -r REVSET [--dst REV] [options]", rebaseSynopsis+`
 
 	Rebasing will replay a set of changes on top of the destination
 	revision and set the current branch to the final revision.
 
 	If neither `+"`--src`"+` or `+"`--base`"+` is specified, it acts as if
-	`+"`--base="+upstreamRev+"`"+` was specified.`)
+	`+"`--base="+upstreamRev+"`"+` was specified.
+
+	`+"`--onto`"+` transplants an arbitrary range of commits, even between
+	unrelated branches. The range starts at `+"`--src`"+` (or after the
+	branching point of `+"`--base`"+`) and ends at `+"`--dst`"+`, which
+	defaults to the current commit when `+"`--onto`"+` is given. The commits
+	are replayed on top of the `+"`--onto`"+` revision, and if `+"`--dst`"+`
+	names a branch, the branch is moved to the last replayed commit.
+
+	`+"`-r`"+` moves only the commits selected by a revision or revision set
+	expression (see `+"`gg help log`"+`), oldest first, leaving out any
+	merge commits. The selected commits must form a single line of
+	history. Each branch that points to a selected commit is moved to the
+	commit's copy, and other branches are left alone, so a branch is only
+	moved if every one of its commits that isn't in the destination is
+	selected or still on another branch. If the current branch isn't
+	moved, HEAD is left detached at the last moved commit. For example,
+	`+"`gg rebase -r 'draft()' --dst main`"+` moves a stack of unpushed
+	branches onto main.
+
+	`+"`--preview`"+` shows the commits that would be moved, where they would
+	be moved to, and which branch would be updated, without rebasing.
+
+	`+"`--autosquash`"+` moves each commit whose subject starts with "fixup!"
+	or "squash!" (like those created by `+"`gg commit --fixup`"+`) after the
+	commit it names and combines the two. The default is taken from the
+	`+"`rebase.autosquash`"+` setting.
+
+	`+"`-S`"+` signs the rebased commits, as if the `+"`commit.gpgSign`"+`
+	setting were true. Pass it again with `+"`--continue`"+` to keep
+	signing after resolving conflicts.`)
 	base := f.String("base", "", "rebase everything from branching point of specified `rev`ision")
 	dst := f.String("dst", upstreamRev, "rebase onto the specified `rev`ision")
 	src := f.String("src", "", "rebase the specified `rev`ision and descendants")
+	onto := f.String("onto", "", "move the range ending at --dst onto the specified `rev`ision")
+	rev := f.String("r", "", "rebase only the commits selected by `revset`")
+	preview := f.Bool("preview", false, "show the commits that would be moved without rebasing")
 	abort := f.Bool("abort", false, "abort an interrupted rebase")
 	continue_ := f.Bool("continue", false, "continue an interrupted rebase")
+	autosquashFlag := f.Bool("autosquash", false, "squash fixup! and squash! commits into the commits they name")
+	noAutosquash := f.Bool("no-autosquash", false, "do not squash fixup! and squash! commits")
+	sign := f.Bool("S", false, "sign the rebased commits")
+	f.Alias("S", "sign")
 	if err := f.Parse(args); flag.IsHelp(err) {
 		f.Help(cc.stdout)
 		return nil
 	} else if err != nil {
 		return usagef("%v", err)
 	}
+	cc, err := withSigning(cc, *sign)
+	if err != nil {
+		return err
+	}
 	if f.NArg() != 0 {
 		return usagef("no arguments expected")
 	}
 	if *abort && *continue_ {
 		return usagef("can't specify both --abort and --continue")
 	}
-	if (*abort || *continue_) && (*base != "" || *dst != upstreamRev || *src != "") {
+	if (*abort || *continue_) && (*base != "" || *dst != upstreamRev || *src != "" || *onto != "" || *rev != "" || *preview || *autosquashFlag || *noAutosquash) {
 		return usagef("can't specify other options with --abort or --continue")
 	}
 	if *abort {
@@ -62,16 +112,36 @@ func rebase(ctx context.Context, cc *cmdContext, args []string) error {
 	if *continue_ {
 		return continueRebase(ctx, cc)
 	}
+	if *rev != "" {
+		if *base != "" || *src != "" || *onto != "" {
+			return usagef("can't specify -r with --src, --base, or --onto")
+		}
+		if *autosquashFlag {
+			return usagef("can't use --autosquash with -r")
+		}
+	}
+	autosquash, err := useAutosquash(ctx, cc.git, *autosquashFlag, *noAutosquash, false)
+	if err != nil {
+		return err
+	}
+	if *onto != "" {
+		return rebaseOnto(ctx, cc, *src, *base, *dst, *onto, *preview, autosquash)
+	}
 	// Verify that -dst exists to give the user a better error message.
 	// See https://github.com/gg-scm/gg/issues/127
 	if _, err := cc.git.ParseRev(ctx, *dst); err != nil {
 		return fmt.Errorf("destination: %w", err)
 	}
 	switch {
+	case *rev != "":
+		return rebaseRevset(ctx, cc, *rev, *dst, *preview)
 	case *base != "" && *src != "":
 		return usagef("can't specify both -s and -b")
 	case *base != "":
-		return cc.interactiveGit(ctx, "rebase", "--onto="+*dst, "--no-fork-point", "--", *base)
+		if *preview {
+			return previewRebase(ctx, cc, *base, git.Head.String(), *dst)
+		}
+		return runRebase(ctx, cc, autosquash, "--onto="+*dst, "--no-fork-point", "--", *base)
 	case *src != "":
 		if strings.HasPrefix(*src, "-") {
 			return fmt.Errorf("revision cannot start with '-'")
@@ -82,7 +152,10 @@ func rebase(ctx context.Context, cc *cmdContext, args []string) error {
 		}
 		if ancestor {
 			// Simple case: this is an ancestor revision.
-			return cc.interactiveGit(ctx, "rebase", "--onto="+*dst, "--no-fork-point", "--", *src+"~")
+			if *preview {
+				return previewRebase(ctx, cc, *src+"~", git.Head.String(), *dst)
+			}
+			return runRebase(ctx, cc, autosquash, "--onto="+*dst, "--no-fork-point", "--", *src+"~")
 		}
 
 		// More complicated: this is on an unrelated branch.
@@ -99,6 +172,12 @@ func rebase(ctx context.Context, cc *cmdContext, args []string) error {
 		if len(descend) > 1 {
 			return fmt.Errorf("%s is in multiple branches", *src)
 		}
+		if *preview {
+			return previewRebase(ctx, cc, *src+"~", descend[0].String(), *dst)
+		}
+		if *autosquashFlag {
+			return usagef("can't use --autosquash when --src is not an ancestor of the working copy")
+		}
 		editorCmd := fmt.Sprintf(
 			"%s log --reverse --first-parent --pretty='tformat:pick %%H' %s~..%s >",
 			escape.Bash(cc.git.Exe()), escape.Bash(*src), escape.Bash(descend[0].String()))
@@ -110,38 +189,424 @@ func rebase(ctx context.Context, cc *cmdContext, args []string) error {
 			"--no-fork-point",
 			git.Head.String())
 	default:
-		return cc.interactiveGit(ctx, "rebase", "--onto="+*dst, "--no-fork-point")
+		if *preview {
+			return previewRebase(ctx, cc, upstreamRev, git.Head.String(), *dst)
+		}
+		return runRebase(ctx, cc, autosquash, "--onto="+*dst, "--no-fork-point")
+	}
+}
+
+// rebaseRevset replays the commits selected by a revision set on top of
+// dst, oldest first. The commits are replayed on a detached HEAD. Each
+// local branch that points to a selected commit is moved to that
+// commit's copy, and all other branches stay where they are. If the
+// current branch is moved, it is checked out at the end; otherwise HEAD
+// is left detached at the last replayed commit.
+func rebaseRevset(ctx context.Context, cc *cmdContext, expr, dst string, preview bool) error {
+	commits, err := listRevset(ctx, cc.git, expr, true, dst)
+	if err != nil {
+		return err
+	}
+	if len(commits) == 0 {
+		return fmt.Errorf("%s: no commits selected that are not already in %s", expr, dst)
+	}
+	selected := make(map[git.Hash]bool, len(commits))
+	for _, c := range commits {
+		selected[c] = true
+	}
+
+	// The selected commits are replayed as a single line of history, so
+	// they must all be ancestors of the newest one.
+	newest := commits[len(commits)-1]
+	line, err := revListHashes(ctx, cc.git, []string{"rev-list", "--no-merges", newest.String(), "^" + dst, "--"})
+	if err != nil {
+		return err
+	}
+	inLine := make(map[git.Hash]bool, len(line))
+	for _, c := range line {
+		inLine[c] = true
+	}
+	for _, c := range commits {
+		if !inLine[c] {
+			return fmt.Errorf("%v is not an ancestor of %v; selected commits must form a single line of history", c.Short(), newest.Short())
+		}
+	}
+
+	// Find the branches to move. Moving a branch must not leave any
+	// unselected commits without a branch pointing to them.
+	refs, err := cc.git.ListRefs(ctx)
+	if err != nil {
+		return err
+	}
+	moved := make(map[git.Hash][]string)
+	var movedTips, keptTips []string
+	for ref, c := range refs {
+		if !ref.IsBranch() {
+			continue
+		}
+		if selected[c] {
+			moved[c] = append(moved[c], ref.Branch())
+			movedTips = append(movedTips, c.String())
+		} else {
+			keptTips = append(keptTips, c.String())
+		}
+	}
+	if len(movedTips) > 0 {
+		args := []string{"rev-list", "--no-merges"}
+		args = append(args, movedTips...)
+		args = append(args, "--not", dst)
+		args = append(args, keptTips...)
+		args = append(args, "--")
+		left, err := revListHashes(ctx, cc.git, args)
+		if err != nil {
+			return err
+		}
+		for _, c := range left {
+			if !selected[c] {
+				return fmt.Errorf("%v is not selected, but would no longer be on any branch after the rebase", c.Short())
+			}
+		}
+	}
+	for _, branches := range moved {
+		sort.Strings(branches)
+	}
+	var currBranch string
+	if ref, err := cc.git.HeadRef(ctx); err == nil {
+		currBranch = ref.Branch()
+	}
+	currMoved := currBranch != "" && selected[refs[git.BranchRef(currBranch)]]
+
+	if preview {
+		plan := new(rebasePlan)
+		if currMoved {
+			plan.branch = currBranch
+		}
+		plan.onto, err = cc.git.CommitInfo(ctx, dst)
+		if err != nil {
+			return fmt.Errorf("destination: %w", err)
+		}
+		for i := len(commits) - 1; i >= 0; i-- {
+			c, err := cc.git.CommitInfo(ctx, commits[i].String())
+			if err != nil {
+				return err
+			}
+			plan.commits = append(plan.commits, c)
+			for _, b := range moved[commits[i]] {
+				if b != plan.branch {
+					plan.otherBranches = append(plan.otherBranches, movedBranch{b, commits[i]})
+				}
+			}
+		}
+		if oldest := plan.commits[len(plan.commits)-1]; len(oldest.Parents) > 0 {
+			plan.oldBase = oldest.Parents[0]
+		}
+		return plan.write(cc.stdout)
+	}
+
+	gitExe := escape.Bash(cc.git.Exe())
+	todo := make([]string, 0, len(commits)+len(movedTips)+1)
+	for _, c := range commits {
+		todo = append(todo, "pick "+c.String())
+		for _, b := range moved[c] {
+			todo = append(todo, fmt.Sprintf("exec %s update-ref %s HEAD %v",
+				gitExe, escape.Bash(git.BranchRef(b).String()), c))
+		}
+	}
+	if currMoved {
+		todo = append(todo, fmt.Sprintf("exec %s checkout --quiet %s", gitExe, escape.Bash(currBranch)))
+	}
+	editorCmd := "printf '%s\\n'"
+	for _, line := range todo {
+		editorCmd += " " + escape.Bash(line)
+	}
+	editorCmd += " >"
+	if err := cc.git.Run(ctx, "checkout", "--quiet", "--detach"); err != nil {
+		return err
+	}
+	err = cc.interactiveGit(ctx,
+		"-c", "sequence.editor="+editorCmd,
+		"rebase",
+		"-i",
+		"--onto="+dst,
+		"--no-fork-point",
+		git.Head.String())
+	if err != nil && currBranch != "" {
+		// If the rebase didn't start, go back to the branch.
+		if op, opErr := readWorkTreeOperation(ctx, cc.git); opErr == nil && op == noOperation {
+			cc.git.Run(ctx, "symbolic-ref", "HEAD", git.BranchRef(currBranch).String())
+		}
+	}
+	return err
+}
+
+// useAutosquash reports whether a rebase should squash fixup! and squash!
+// commits. The --autosquash and --no-autosquash flags take precedence over
+// the rebase.autosquash setting, which takes precedence over defaultValue.
+func useAutosquash(ctx context.Context, g *git.Git, autosquash, noAutosquash, defaultValue bool) (bool, error) {
+	switch {
+	case autosquash && noAutosquash:
+		return false, usagef("can't pass both --autosquash and --no-autosquash")
+	case autosquash:
+		return true, nil
+	case noAutosquash:
+		return false, nil
+	}
+	cfg, err := g.ReadConfig(ctx)
+	if err != nil {
+		return false, err
+	}
+	if cfg.Value("rebase.autosquash") == "" {
+		return defaultValue, nil
+	}
+	return cfg.Bool("rebase.autosquash")
+}
+
+// runRebase runs git rebase with the given arguments. Git only reorders
+// fixup! and squash! commits in an interactive rebase, so an autosquash
+// rebase is run interactively with an editor that accepts the generated
+// plan unchanged.
+func runRebase(ctx context.Context, cc *cmdContext, autosquash bool, args ...string) error {
+	if !autosquash {
+		return cc.interactiveGit(ctx, append([]string{"rebase"}, args...)...)
+	}
+	cc, err := cc.withGitConfig("sequence.editor", ":")
+	if err != nil {
+		return err
+	}
+	return cc.interactiveGit(ctx, append([]string{"rebase", "-i", "--autosquash"}, args...)...)
+}
+
+// rebaseOnto moves the commits in a range onto the onto revision.
+// The range ends at dst and starts at src or after the branching point
+// of base. If neither src nor base is given, the range starts after
+// dst's upstream.
+func rebaseOnto(ctx context.Context, cc *cmdContext, src, base, dst, onto string, preview, autosquash bool) error {
+	tip := dst
+	if tip == "@{upstream}" {
+		// --dst was not given: the range ends at the current commit.
+		tip = git.Head.String()
+	}
+	for _, rev := range []string{src, base, tip, onto} {
+		if strings.HasPrefix(rev, "-") {
+			return fmt.Errorf("revision cannot start with '-'")
+		}
+	}
+	var exclude string
+	switch {
+	case base != "" && src != "":
+		return usagef("can't specify both -s and -b")
+	case base != "":
+		exclude = base
+	case src != "":
+		if isAncestor, err := cc.git.IsAncestor(ctx, src, tip); err != nil {
+			return err
+		} else if !isAncestor {
+			return fmt.Errorf("--src %s is not an ancestor of --dst %s", src, tip)
+		}
+		exclude = src + "~"
+	default:
+		exclude = tip + "@{upstream}"
+	}
+	plan, err := planRebase(ctx, cc.git, exclude, tip, onto)
+	if err != nil {
+		return err
+	}
+	if preview {
+		return plan.write(cc.stdout)
+	}
+	rebaseArgs := []string{"--onto=" + onto, "--no-fork-point", "--", exclude}
+	if tip != git.Head.String() {
+		rebaseArgs = append(rebaseArgs, tip)
 	}
+	return runRebase(ctx, cc, autosquash, rebaseArgs...)
+}
+
+// previewRebase prints the plan for moving the commits reachable from tip
+// but not exclude onto the onto revision.
+func previewRebase(ctx context.Context, cc *cmdContext, exclude, tip, onto string) error {
+	plan, err := planRebase(ctx, cc.git, exclude, tip, onto)
+	if err != nil {
+		return err
+	}
+	return plan.write(cc.stdout)
+}
+
+// A rebasePlan describes the effect of a rebase.
+type rebasePlan struct {
+	commits []*object.Commit // newest first
+	merges  int
+	oldBase git.Hash
+	onto    *object.Commit
+	// branch is the branch that will be moved to the last rebased commit.
+	// If empty, the rebase will leave HEAD detached.
+	branch string
+	// otherBranches are branches that will be moved to the new copies of
+	// the commits they point to.
+	otherBranches []movedBranch
+}
+
+type movedBranch struct {
+	name   string
+	commit git.Hash
+}
+
+// planRebase validates and describes a rebase of the commits reachable
+// from tip but not exclude onto the onto revision.
+func planRebase(ctx context.Context, g *git.Git, exclude, tip, onto string) (*rebasePlan, error) {
+	ontoRev, err := g.ParseRev(ctx, onto)
+	if err != nil {
+		return nil, fmt.Errorf("onto: %w", err)
+	}
+	tipRev, err := g.ParseRev(ctx, tip)
+	if err != nil {
+		return nil, err
+	}
+	plan := new(rebasePlan)
+	if tip == git.Head.String() {
+		if ref, err := g.HeadRef(ctx); err == nil {
+			plan.branch = ref.Branch()
+		}
+	} else {
+		plan.branch = tipRev.Ref.Branch()
+	}
+	plan.oldBase, err = g.MergeBase(ctx, exclude, tipRev.Commit.String())
+	if err != nil {
+		return nil, err
+	}
+	plan.onto, err = g.CommitInfo(ctx, ontoRev.Commit.String())
+	if err != nil {
+		return nil, err
+	}
+
+	// The destination must not be one of the commits being moved.
+	inTip, err := g.IsAncestor(ctx, ontoRev.Commit.String(), tipRev.Commit.String())
+	if err != nil {
+		return nil, err
+	}
+	if inTip {
+		inExclude, err := g.IsAncestor(ctx, ontoRev.Commit.String(), exclude)
+		if err != nil {
+			return nil, err
+		}
+		if !inExclude {
+			return nil, fmt.Errorf("cannot move commits onto %s: it is one of the commits being moved", onto)
+		}
+	}
+
+	commits, err := g.Log(ctx, git.LogOptions{
+		Revs: []string{tipRev.Commit.String(), "^" + exclude},
+	})
+	if err != nil {
+		return nil, err
+	}
+	for commits.Next() {
+		c := commits.CommitInfo()
+		plan.commits = append(plan.commits, c)
+		if len(c.Parents) > 1 {
+			plan.merges++
+		}
+	}
+	if err := commits.Close(); err != nil {
+		return nil, err
+	}
+	if len(plan.commits) == 0 {
+		return nil, fmt.Errorf("no commits in %s..%s to move", exclude, tip)
+	}
+	return plan, nil
+}
+
+func (plan *rebasePlan) write(w io.Writer) error {
+	buf := new(bytes.Buffer)
+	noun := "commits"
+	if len(plan.commits) == 1 {
+		noun = "commit"
+	}
+	fmt.Fprintf(buf, "move %d %s from %s onto %s %s:\n",
+		len(plan.commits), noun, plan.oldBase.Short(), plan.onto.SHA1().Short(), plan.onto.Summary())
+	for _, c := range plan.commits {
+		fmt.Fprintf(buf, "%s %s\n", c.SHA1().Short(), c.Summary())
+	}
+	if plan.merges > 0 {
+		fmt.Fprintf(buf, "%d merge commit(s) will be dropped and their changes linearized\n", plan.merges)
+	}
+	if plan.branch != "" {
+		fmt.Fprintf(buf, "branch %s will point to the new %s\n", plan.branch, plan.commits[0].SHA1().Short())
+	} else {
+		fmt.Fprintf(buf, "HEAD will be detached at the new %s\n", plan.commits[0].SHA1().Short())
+	}
+	for _, b := range plan.otherBranches {
+		fmt.Fprintf(buf, "branch %s will point to the new %s\n", b.name, b.commit.Short())
+	}
+	_, err := w.Write(buf.Bytes())
+	return err
 }
 
 const histeditSynopsis = "interactively edit revision history"
 
 func histedit(ctx context.Context, cc *cmdContext, args []string) error {
-	f := flag.NewFlagSet(true, "gg histedit [options] [UPSTREAM]", histeditSynopsis+`
+	f := flag.NewFlagSet(true, "gg histedit [options] [UPSTREAM]\n"+
+		"gg histedit [options] -r REVSET", histeditSynopsis+`
 
 	This command lets you interactively edit a linear series of commits.
 	When starting `+"`histedit`"+`, it will open your editor to plan the series
 	of changes you want to make. You can reorder commits, or use the
-	actions listed in the plan comments.
+	actions listed in the plan comments. The plan comments also show
+	a graph of the commits being edited and the branches that point
+	to them.
 
 	Unlike `+"`git rebase -i`"+`, continuing a `+"`histedit`"+` will automatically
 	amend the current commit if any changes are made. In most cases,
-	you do not need to run `+"`commit --amend`"+` yourself.`)
+	you do not need to run `+"`commit --amend`"+` yourself.
+
+	`+"`-r`"+` starts the edit at the oldest commit selected by a revision or
+	revision set expression (see `+"`gg help log`"+`) instead of after the
+	branching point of UPSTREAM. For example, `+"`gg histedit -r HEAD~2`"+`
+	edits the last three commits.
+
+	`+"`--abort`"+` returns the branch to where it was before the edit
+	started, and `+"`--edit-plan`"+` (or `+"`--edit-todo`"+`) reopens the
+	remaining actions in your editor. Both only work on an edit started
+	by `+"`gg histedit`"+`.
+
+	`+"`--autosquash`"+` moves each commit whose subject starts with "fixup!"
+	or "squash!" (like those created by `+"`gg commit --fixup`"+`) after the
+	commit it names in the initial plan. The default comes from the
+	`+"`rebase.autosquash`"+` setting.
+
+	`+"`-S`"+` signs the edited commits, as if the `+"`commit.gpgSign`"+`
+	setting were true. Pass it again with `+"`--continue`"+` to keep
+	signing after stopping for an edit.`)
 	abort := f.Bool("abort", false, "abort an edit already in progress")
 	continue_ := f.Bool("continue", false, "continue an edit already in progress")
 	editPlan := f.Bool("edit-plan", false, "edit remaining actions list")
+	f.Alias("edit-plan", "edit-todo")
+	rev := f.String("r", "", "edit from the oldest commit selected by `revset`")
 	exec := f.MultiString("exec", "execute the shell `command` after each line creating a commit (can be specified multiple times)")
+	autosquashFlag := f.Bool("autosquash", false, "move fixup! and squash! commits after the commits they name")
+	noAutosquash := f.Bool("no-autosquash", false, "do not move fixup! and squash! commits")
+	sign := f.Bool("S", false, "sign the edited commits")
+	f.Alias("S", "sign")
 	if err := f.Parse(args); flag.IsHelp(err) {
 		f.Help(cc.stdout)
 		return nil
 	} else if err != nil {
 		return usagef("%v", err)
 	}
+	cc, err := withSigning(cc, *sign)
+	if err != nil {
+		return err
+	}
+	if *rev != "" && (*abort || *continue_ || *editPlan) {
+		return usagef("can't pass -r with --abort, --continue, or --edit-plan")
+	}
 	switch {
 	case !*abort && !*continue_ && !*editPlan:
 		if f.NArg() > 1 {
 			return usagef("no more than one ancestor should be given")
 		}
+		if *rev != "" && f.NArg() > 0 {
+			return usagef("can't pass both -r and UPSTREAM")
+		}
 		upstream := f.Arg(0)
 		if strings.HasPrefix(upstream, "-") {
 			return errors.New("upstream ref cannot start with a dash")
@@ -149,29 +614,58 @@ func histedit(ctx context.Context, cc *cmdContext, args []string) error {
 		if upstream == "" {
 			upstream = "@{upstream}"
 		}
-		mergeBase, err := cc.git.MergeBase(ctx, upstream, git.Head.String())
+		autosquash, err := useAutosquash(ctx, cc.git, *autosquashFlag, *noAutosquash, false)
+		if err != nil {
+			return err
+		}
+		var mergeBase git.Hash
+		if *rev != "" {
+			mergeBase, err = histeditRevsetBase(ctx, cc.git, *rev)
+		} else {
+			mergeBase, err = cc.git.MergeBase(ctx, upstream, git.Head.String())
+		}
 		if err != nil {
 			return err
 		}
-		rebaseArgs := []string{"rebase", "-i", "--onto=" + mergeBase.String(), "--no-fork-point", "--autosquash"}
+		rebaseArgs := []string{"rebase", "-i", "--onto=" + mergeBase.String(), "--no-fork-point"}
+		if autosquash {
+			rebaseArgs = append(rebaseArgs, "--autosquash")
+		} else {
+			rebaseArgs = append(rebaseArgs, "--no-autosquash")
+		}
 		for _, cmd := range *exec {
 			rebaseArgs = append(rebaseArgs, "--exec="+cmd)
 		}
 		rebaseArgs = append(rebaseArgs, "--", mergeBase.String())
-		return cc.interactiveGit(ctx, rebaseArgs...)
+		if err := markHisteditStart(ctx, cc.git); err != nil {
+			return err
+		}
+		err = histeditWithPreview(ctx, cc, mergeBase, rebaseArgs)
+		cleanupHisteditMarker(ctx, cc.git)
+		return err
 	case *abort && !*continue_ && !*editPlan:
 		if f.NArg() != 0 {
 			return usagef("can't pass arguments with --abort")
 		}
-		return cc.interactiveGit(ctx, "rebase", "--abort")
+		if err := checkHisteditInProgress(ctx, cc.git); err != nil {
+			return err
+		}
+		err := cc.interactiveGit(ctx, "rebase", "--abort")
+		cleanupHisteditMarker(ctx, cc.git)
+		return err
 	case !*abort && *continue_ && !*editPlan:
 		if f.NArg() != 0 {
 			return usagef("can't pass arguments with --continue")
 		}
-		return continueRebase(ctx, cc)
+		err := continueRebase(ctx, cc)
+		cleanupHisteditMarker(ctx, cc.git)
+		return err
 	case !*abort && !*continue_ && *editPlan:
 		if f.NArg() != 0 {
-			return usagef("can't pass arguments with --edit-todo")
+			return usagef("can't pass arguments with --edit-plan")
+		}
+		if err := checkHisteditInProgress(ctx, cc.git); err != nil {
+			return err
 		}
 		return cc.interactiveGit(ctx, "rebase", "--edit-todo")
 	default:
@@ -179,6 +673,95 @@ func histedit(ctx context.Context, cc *cmdContext, args []string) error {
 	}
 }
 
+// histeditRevsetBase returns the commit to start a history edit after so
+// that every commit selected by the revision set expr is edited: the
+// parent of the oldest selected commit. The selected commits must all be
+// ancestors of HEAD.
+func histeditRevsetBase(ctx context.Context, g *git.Git, expr string) (git.Hash, error) {
+	commits, err := listRevset(ctx, g, expr, false)
+	if err != nil {
+		return git.Hash{}, err
+	}
+	if len(commits) == 0 {
+		return git.Hash{}, fmt.Errorf("%s: no commits selected", expr)
+	}
+	outside, err := listRevset(ctx, g, expr, false, git.Head.String())
+	if err != nil {
+		return git.Hash{}, err
+	}
+	if len(outside) > 0 {
+		return git.Hash{}, fmt.Errorf("%v is not an ancestor of the working copy", outside[0].Short())
+	}
+	oldest, err := g.CommitInfo(ctx, commits[0].String())
+	if err != nil {
+		return git.Hash{}, err
+	}
+	if len(oldest.Parents) == 0 {
+		return git.Hash{}, fmt.Errorf("can't edit the root commit %v", commits[0].Short())
+	}
+	return oldest.Parents[0], nil
+}
+
+// histeditMarkerFilename is the name of the file in the Git directory where
+// histedit records the commit that an edit started from. Git records the same
+// commit in rebase-merge/orig-head, so a match means that gg started the
+// interactive rebase in progress.
+const histeditMarkerFilename = "gg-histedit"
+
+// markHisteditStart records that gg is starting a history edit from HEAD.
+func markHisteditStart(ctx context.Context, g *git.Git) error {
+	gitDir, err := g.GitDir(ctx)
+	if err != nil {
+		return err
+	}
+	head, err := g.Head(ctx)
+	if err != nil {
+		return err
+	}
+	err = ioutil.WriteFile(filepath.Join(gitDir, histeditMarkerFilename), []byte(head.Commit.String()+"\n"), 0o666)
+	if err != nil {
+		return fmt.Errorf("start history edit: %w", err)
+	}
+	return nil
+}
+
+// cleanupHisteditMarker removes the record written by markHisteditStart
+// if no rebase is in progress.
+func cleanupHisteditMarker(ctx context.Context, g *git.Git) {
+	gitDir, err := g.GitDir(ctx)
+	if err != nil {
+		return
+	}
+	if _, err := os.Stat(filepath.Join(gitDir, "rebase-merge")); os.IsNotExist(err) {
+		os.Remove(filepath.Join(gitDir, histeditMarkerFilename))
+	}
+}
+
+// checkHisteditInProgress returns an error if the working copy is not
+// in the middle of a history edit started by gg histedit.
+func checkHisteditInProgress(ctx context.Context, g *git.Git) error {
+	gitDir, err := g.GitDir(ctx)
+	if err != nil {
+		return err
+	}
+	stateDir := filepath.Join(gitDir, "rebase-merge")
+	origHead, err := ioutil.ReadFile(filepath.Join(stateDir, "orig-head"))
+	if os.IsNotExist(err) {
+		return errors.New("no history edit in progress")
+	}
+	if err != nil {
+		return err
+	}
+	if _, err := os.Stat(filepath.Join(stateDir, "interactive")); err != nil {
+		return errors.New("rebase in progress is not a history edit (use gg rebase --abort or --continue)")
+	}
+	marker, err := ioutil.ReadFile(filepath.Join(gitDir, histeditMarkerFilename))
+	if err != nil || strings.TrimSpace(string(marker)) != strings.TrimSpace(string(origHead)) {
+		return errors.New("interactive rebase in progress was not started by gg histedit (use git rebase to manage it)")
+	}
+	return nil
+}
+
 // continueRebase adds any modified files to the index and then runs
 // `git rebase --continue`.
 func continueRebase(ctx context.Context, cc *cmdContext) error {
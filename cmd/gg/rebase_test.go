@@ -15,8 +15,10 @@
 package main
 
 import (
+	"bytes"
 	"context"
 	"fmt"
+	"io/ioutil"
 	"testing"
 
 	"gg-scm.io/pkg/git"
@@ -593,6 +595,318 @@ func TestRebase_NoUpstream(t *testing.T) {
 	}
 }
 
+func TestRebase_Onto(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	env, err := newTestEnv(ctx, t)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Create a "topic" branch with two commits and an unrelated "other"
+	// branch with one commit, both starting from main.
+	if err := env.initRepoWithHistory(ctx, "."); err != nil {
+		t.Fatal(err)
+	}
+	if err := env.git.NewBranch(ctx, "other", git.BranchOptions{Checkout: true}); err != nil {
+		t.Fatal(err)
+	}
+	if err := env.root.Apply(filesystem.Write("other.txt", dummyContent)); err != nil {
+		t.Fatal(err)
+	}
+	if err := env.addFiles(ctx, "other.txt"); err != nil {
+		t.Fatal(err)
+	}
+	other, err := env.newCommit(ctx, ".")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := env.git.NewBranch(ctx, "topic", git.BranchOptions{StartPoint: "main", Checkout: true}); err != nil {
+		t.Fatal(err)
+	}
+	if err := env.root.Apply(filesystem.Write("foo.txt", dummyContent)); err != nil {
+		t.Fatal(err)
+	}
+	if err := env.addFiles(ctx, "foo.txt"); err != nil {
+		t.Fatal(err)
+	}
+	c1, err := env.newCommit(ctx, ".")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := env.root.Apply(filesystem.Write("bar.txt", dummyContent)); err != nil {
+		t.Fatal(err)
+	}
+	if err := env.addFiles(ctx, "bar.txt"); err != nil {
+		t.Fatal(err)
+	}
+	c2, err := env.newCommit(ctx, ".")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := env.git.CheckoutBranch(ctx, "main", git.CheckoutOptions{}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Moving commits onto one of the moved commits is rejected.
+	if _, err := env.gg(ctx, env.root.String(), "rebase", "--src="+c1.String(), "--dst=topic", "--onto="+c2.String()); err == nil {
+		t.Error("rebase --onto inside the range did not return an error")
+	}
+
+	// Preview does not change anything.
+	out, err := env.gg(ctx, env.root.String(), "rebase", "--src="+c2.String(), "--dst=topic", "--onto=other", "--preview")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"move 1 commit from " + c1.Short() + " onto " + other.Short(), c2.Short(), "branch topic will point"} {
+		if !bytes.Contains(out, []byte(want)) {
+			t.Errorf("rebase --preview output does not contain %q. Output:\n%s", want, out)
+		}
+	}
+	if r, err := env.git.ParseRev(ctx, "topic"); err != nil {
+		t.Fatal(err)
+	} else if r.Commit != c2 {
+		t.Errorf("after preview, topic = %v; want %v", r.Commit, c2)
+	}
+
+	// Transplant only the second commit onto the other branch.
+	if _, err := env.gg(ctx, env.root.String(), "rebase", "--src="+c2.String(), "--dst=topic", "--onto=other"); err != nil {
+		t.Fatal(err)
+	}
+	topic, err := env.git.CommitInfo(ctx, "topic")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if topic.SHA1() == c2 {
+		t.Error("topic was not moved")
+	}
+	if len(topic.Parents) != 1 || topic.Parents[0] != other {
+		t.Errorf("topic parents = %v; want [%v]", topic.Parents, other)
+	}
+	if err := env.git.CheckoutBranch(ctx, "topic", git.CheckoutOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if exists, err := env.root.Exists("foo.txt"); err != nil {
+		t.Fatal(err)
+	} else if exists {
+		t.Error("foo.txt from the first topic commit is present after transplant")
+	}
+}
+
+func TestRebase_Autosquash(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	env, err := newTestEnv(ctx, t)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Create a "topic" branch with two commits, then a fixup for the first.
+	if err := env.initRepoWithHistory(ctx, "."); err != nil {
+		t.Fatal(err)
+	}
+	if err := env.git.NewBranch(ctx, "topic", git.BranchOptions{Checkout: true}); err != nil {
+		t.Fatal(err)
+	}
+	if err := env.root.Apply(filesystem.Write("foo.txt", "Hello\n")); err != nil {
+		t.Fatal(err)
+	}
+	if err := env.addFiles(ctx, "foo.txt"); err != nil {
+		t.Fatal(err)
+	}
+	if err := env.git.CommitAll(ctx, "add foo", git.CommitOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := env.root.Apply(filesystem.Write("bar.txt", dummyContent)); err != nil {
+		t.Fatal(err)
+	}
+	if err := env.addFiles(ctx, "bar.txt"); err != nil {
+		t.Fatal(err)
+	}
+	if err := env.git.CommitAll(ctx, "add bar", git.CommitOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := env.root.Apply(filesystem.Write("foo.txt", "Hello, World!\n")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := env.gg(ctx, env.root.String(), "commit", "--fixup=HEAD~"); err != nil {
+		t.Fatal(err)
+	}
+	if info, err := env.git.CommitInfo(ctx, "HEAD"); err != nil {
+		t.Fatal(err)
+	} else if got, want := info.Message, "fixup! add foo\n"; got != want {
+		t.Errorf("commit --fixup message = %q; want %q", got, want)
+	}
+
+	if _, err := env.gg(ctx, env.root.String(), "rebase", "--base=main", "--dst=main", "--autosquash"); err != nil {
+		t.Fatal(err)
+	}
+	head, err := env.git.CommitInfo(ctx, "HEAD")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if head.Message != "add bar\n" {
+		t.Errorf("HEAD message = %q; want \"add bar\\n\"", head.Message)
+	}
+	parent, err := env.git.CommitInfo(ctx, "HEAD~")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if parent.Message != "add foo\n" {
+		t.Errorf("HEAD~ message = %q; want \"add foo\\n\"", parent.Message)
+	}
+	mainRev, err := env.git.ParseRev(ctx, "main")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(parent.Parents) != 1 || parent.Parents[0] != mainRev.Commit {
+		t.Errorf("HEAD~ parents = %v; want [%v]", parent.Parents, mainRev.Commit)
+	}
+	if got, err := env.root.ReadFile("foo.txt"); err != nil {
+		t.Error(err)
+	} else if want := "Hello, World!\n"; got != want {
+		t.Errorf("foo.txt = %q; want %q", got, want)
+	}
+}
+
+func TestRebase_Revset(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	env, err := newTestEnv(ctx, t)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v, err := queryGitVersion(ctx, env.git); err != nil {
+		t.Fatal(err)
+	} else if v.less(ancestryPathVersion) {
+		t.Skipf("Git %v does not support --ancestry-path=COMMIT", v)
+	}
+
+	// Create a repository with a commit on a branch called "topic" and
+	// two more commits on a branch called "topic2" stacked on top of it.
+	if err := env.initRepoWithHistory(ctx, "."); err != nil {
+		t.Fatal(err)
+	}
+	baseRev, err := env.git.Head(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	names := map[git.Hash]string{baseRev.Commit: "initial import"}
+	var commits []git.Hash
+	for i, name := range []string{"foo.txt", "bar.txt", "baz.txt"} {
+		switch i {
+		case 0:
+			if err := env.git.NewBranch(ctx, "topic", git.BranchOptions{Checkout: true, Track: true}); err != nil {
+				t.Fatal(err)
+			}
+		case 1:
+			if err := env.git.NewBranch(ctx, "topic2", git.BranchOptions{Checkout: true}); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err := env.root.Apply(filesystem.Write(name, dummyContent)); err != nil {
+			t.Fatal(err)
+		}
+		if err := env.addFiles(ctx, name); err != nil {
+			t.Fatal(err)
+		}
+		c, err := env.newCommit(ctx, ".")
+		if err != nil {
+			t.Fatal(err)
+		}
+		names[c] = fmt.Sprintf("change %d", i+1)
+		commits = append(commits, c)
+	}
+
+	// Call gg to move topic2's commits onto main.
+	revset := commits[1].String() + "::" + commits[2].String()
+	if _, err := env.gg(ctx, env.root.String(), "rebase", "-r", revset, "-dst=main"); err != nil {
+		t.Error(err)
+	}
+
+	curr, err := env.git.Head(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, existedBefore := names[curr.Commit]; existedBefore {
+		t.Fatalf("rebase HEAD = %s; want new commit", prettyCommit(curr.Commit, names))
+	}
+	if want := git.Ref("refs/heads/topic2"); curr.Ref != want {
+		t.Errorf("rebase changed ref to %s; want %s", curr.Ref, want)
+	}
+	if err := objectExists(ctx, env.git, curr.Commit.String(), "foo.txt"); err == nil {
+		t.Error("foo.txt in rebased change")
+	}
+	for _, name := range []string{"bar.txt", "baz.txt"} {
+		if err := objectExists(ctx, env.git, curr.Commit.String(), name); err != nil {
+			t.Errorf("%s not in rebased change: %v", name, err)
+		}
+	}
+	grandparent, err := env.git.ParseRev(ctx, "HEAD~2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if grandparent.Commit != baseRev.Commit {
+		t.Errorf("HEAD~2 = %s; want %s", prettyCommit(grandparent.Commit, names), prettyCommit(baseRev.Commit, names))
+	}
+	// The unselected commit must stay on topic.
+	if r, err := env.git.ParseRev(ctx, "topic"); err != nil {
+		t.Error(err)
+	} else if r.Commit != commits[0] {
+		t.Errorf("topic = %s; want %s", prettyCommit(r.Commit, names), prettyCommit(commits[0], names))
+	}
+	if r, err := env.git.ParseRev(ctx, "main"); err != nil {
+		t.Error(err)
+	} else if r.Commit != baseRev.Commit {
+		t.Errorf("main = %s; want %s", prettyCommit(r.Commit, names), prettyCommit(baseRev.Commit, names))
+	}
+}
+
+func TestRebase_RevsetWouldOrphan(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	env, err := newTestEnv(ctx, t)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Create a repository with two commits on a branch called "topic".
+	if err := env.initRepoWithHistory(ctx, "."); err != nil {
+		t.Fatal(err)
+	}
+	if err := env.git.NewBranch(ctx, "topic", git.BranchOptions{Checkout: true, Track: true}); err != nil {
+		t.Fatal(err)
+	}
+	var commits []git.Hash
+	for _, name := range []string{"foo.txt", "bar.txt"} {
+		if err := env.root.Apply(filesystem.Write(name, dummyContent)); err != nil {
+			t.Fatal(err)
+		}
+		if err := env.addFiles(ctx, name); err != nil {
+			t.Fatal(err)
+		}
+		c, err := env.newCommit(ctx, ".")
+		if err != nil {
+			t.Fatal(err)
+		}
+		commits = append(commits, c)
+	}
+
+	// Moving only the last commit would drop the first one from topic.
+	if _, err := env.gg(ctx, env.root.String(), "rebase", "-r", commits[1].String(), "-dst=main"); err == nil {
+		t.Error("gg rebase did not return an error")
+	} else if isUsage(err) {
+		t.Error(err)
+	}
+	curr, err := env.git.Head(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if curr.Commit != commits[1] || curr.Ref != "refs/heads/topic" {
+		t.Errorf("after failed rebase, HEAD = %v (%s); want %v (refs/heads/topic)", curr.Commit, curr.Ref, commits[1])
+	}
+}
+
 func TestHistedit(t *testing.T) {
 	t.Parallel()
 	runRebaseArgVariants(t, func(t *testing.T, argFunc rebaseArgFunc) {
@@ -1049,6 +1363,213 @@ func TestHistedit_NoUpstream(t *testing.T) {
 	}
 }
 
+func TestHistedit_PlanPreview(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	env, err := newTestEnv(ctx, t)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := env.initRepoWithHistory(ctx, "."); err != nil {
+		t.Fatal(err)
+	}
+	if err := env.git.NewBranch(ctx, "foo", git.BranchOptions{Checkout: true}); err != nil {
+		t.Fatal(err)
+	}
+	if err := env.root.Apply(filesystem.Write("foo.txt", dummyContent)); err != nil {
+		t.Fatal(err)
+	}
+	if err := env.addFiles(ctx, "foo.txt"); err != nil {
+		t.Fatal(err)
+	}
+	c1, err := env.newCommit(ctx, ".")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := env.git.NewBranch(ctx, "bar", git.BranchOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := env.root.Apply(filesystem.Write("foo.txt", "Hello, World!\n")); err != nil {
+		t.Fatal(err)
+	}
+	c2, err := env.newCommit(ctx, ".")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Save a copy of the plan and leave it unchanged.
+	planCopy := env.topDir.FromSlash("plan")
+	config := fmt.Sprintf("[sequence]\neditor = %s\n",
+		escape.GitConfig(`cat "$1" > `+escape.Bash(planCopy)+`; :`))
+	if err := env.writeConfig([]byte(config)); err != nil {
+		t.Fatal(err)
+	}
+	if out, err := env.gg(ctx, env.root.String(), "histedit", "main"); err != nil {
+		t.Fatalf("failed: %v; output:\n%s", err, out)
+	}
+	plan, err := ioutil.ReadFile(planCopy)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"# @  " + c2.Short() + " ",
+		"# o  " + c1.Short() + " ",
+		"(bar)",
+		"(foo)",
+		"# These branches will keep pointing to the old commits:\n#   bar\n",
+	} {
+		if !bytes.Contains(plan, []byte(want)) {
+			t.Errorf("plan does not contain %q. Plan:\n%s", want, plan)
+		}
+	}
+}
+
+func TestHistedit_Autosquash(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	env, err := newTestEnv(ctx, t)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := env.initRepoWithHistory(ctx, "."); err != nil {
+		t.Fatal(err)
+	}
+	if err := env.git.NewBranch(ctx, "foo", git.BranchOptions{Checkout: true}); err != nil {
+		t.Fatal(err)
+	}
+	if err := env.root.Apply(filesystem.Write("foo.txt", dummyContent)); err != nil {
+		t.Fatal(err)
+	}
+	if err := env.addFiles(ctx, "foo.txt"); err != nil {
+		t.Fatal(err)
+	}
+	if err := env.git.CommitAll(ctx, "add foo", git.CommitOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := env.root.Apply(filesystem.Write("bar.txt", dummyContent)); err != nil {
+		t.Fatal(err)
+	}
+	if err := env.addFiles(ctx, "bar.txt"); err != nil {
+		t.Fatal(err)
+	}
+	if err := env.git.CommitAll(ctx, "add bar", git.CommitOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := env.root.Apply(filesystem.Write("foo.txt", "Hello, World!\n")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := env.gg(ctx, env.root.String(), "commit", "--fixup=HEAD~"); err != nil {
+		t.Fatal(err)
+	}
+
+	// Save a copy of the plan and leave it unchanged.
+	planCopy := env.topDir.FromSlash("plan")
+	config := fmt.Sprintf("[sequence]\neditor = %s\n",
+		escape.GitConfig(`cat "$1" > `+escape.Bash(planCopy)+`; :`))
+	if err := env.writeConfig([]byte(config)); err != nil {
+		t.Fatal(err)
+	}
+
+	// Without --autosquash, the fixup commit stays where it is.
+	if out, err := env.gg(ctx, env.root.String(), "histedit", "main"); err != nil {
+		t.Fatalf("failed: %v; output:\n%s", err, out)
+	}
+	plan, err := ioutil.ReadFile(planCopy)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.HasPrefix(plan, []byte("fixup ")) || bytes.Contains(plan, []byte("\nfixup ")) {
+		t.Errorf("histedit plan moved fixup commit without --autosquash. Plan:\n%s", plan)
+	}
+
+	if out, err := env.gg(ctx, env.root.String(), "histedit", "--autosquash", "main"); err != nil {
+		t.Fatalf("failed: %v; output:\n%s", err, out)
+	}
+	plan, err = ioutil.ReadFile(planCopy)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Contains(plan, []byte("\nfixup ")) {
+		t.Errorf("histedit --autosquash plan does not squash fixup commit. Plan:\n%s", plan)
+	}
+	head, err := env.git.CommitInfo(ctx, "HEAD")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if head.Message != "add bar\n" {
+		t.Errorf("HEAD message = %q; want \"add bar\\n\"", head.Message)
+	}
+}
+
+func TestHistedit_Abort(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	env, err := newTestEnv(ctx, t)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := env.initRepoWithHistory(ctx, "."); err != nil {
+		t.Fatal(err)
+	}
+	if err := env.git.NewBranch(ctx, "foo", git.BranchOptions{Checkout: true}); err != nil {
+		t.Fatal(err)
+	}
+	if err := env.root.Apply(filesystem.Write("foo.txt", dummyContent)); err != nil {
+		t.Fatal(err)
+	}
+	if err := env.addFiles(ctx, "foo.txt"); err != nil {
+		t.Fatal(err)
+	}
+	c, err := env.newCommit(ctx, ".")
+	if err != nil {
+		t.Fatal(err)
+	}
+	rebaseEditor, err := env.editorCmd([]byte("edit " + c.String() + "\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	config := fmt.Sprintf("[sequence]\neditor = %s\n", escape.GitConfig(rebaseEditor))
+	if err := env.writeConfig([]byte(config)); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := env.gg(ctx, env.root.String(), "histedit", "--abort"); err == nil {
+		t.Error("gg histedit --abort succeeded without an edit in progress")
+	}
+
+	// Stop at the commit, then abort.
+	if out, err := env.gg(ctx, env.root.String(), "histedit", "main"); err != nil {
+		t.Fatalf("gg histedit main: %v; output:\n%s", err, out)
+	}
+	if out, err := env.gg(ctx, env.root.String(), "histedit", "--edit-todo"); err != nil {
+		t.Errorf("gg histedit --edit-todo: %v; output:\n%s", err, out)
+	}
+	if out, err := env.gg(ctx, env.root.String(), "histedit", "--abort"); err != nil {
+		t.Fatalf("gg histedit --abort: %v; output:\n%s", err, out)
+	}
+	curr, err := env.git.Head(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if curr.Commit != c || curr.Ref != git.BranchRef("foo") {
+		t.Errorf("after abort, HEAD = %v (%v); want %v (refs/heads/foo)", curr.Commit, curr.Ref, c)
+	}
+
+	// An interactive rebase started outside of gg is left alone.
+	if err := env.git.Run(ctx, "rebase", "-i", "main"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := env.gg(ctx, env.root.String(), "histedit", "--abort"); err == nil {
+		t.Error("gg histedit --abort succeeded on a rebase started by git")
+	}
+	if _, err := env.gg(ctx, env.root.String(), "histedit", "--edit-todo"); err == nil {
+		t.Error("gg histedit --edit-todo succeeded on a rebase started by git")
+	}
+	if err := env.git.Run(ctx, "rebase", "--abort"); err != nil {
+		t.Fatal(err)
+	}
+}
+
 type rebaseArgFunc = func(mainCommit git.Hash) string
 
 func runRebaseArgVariants(t *testing.T, f func(*testing.T, rebaseArgFunc)) {
@@ -0,0 +1,89 @@
+// Copyright 2021 The gg Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"gg-scm.io/pkg/git"
+	"gg-scm.io/tool/internal/flag"
+)
+
+const renameSynopsis = "rename files on the next commit"
+
+func rename(ctx context.Context, cc *cmdContext, args []string) error {
+	f := flag.NewFlagSet(true, "gg rename [--after] [-f] SOURCE [...] DEST", renameSynopsis+`
+
+	Move files or directories and record the move in the index, so the
+	next commit removes the source and adds the destination. If more than
+	one source is given, the destination must be an existing directory.
+
+	`+"`--after`"+` records a move that already happened, for example
+	with `+"`mv`"+`: the sources must be missing and the destination must
+	exist. Like Git, gg detects renames by comparing file contents, so
+	the renamed file shows up as a rename once it's committed.
+
+	aliases: mv, move`)
+	after := f.Bool("after", false, "record a move that has already occurred")
+	force := f.Bool("f", false, "overwrite existing files")
+	f.Alias("f", "force")
+	if err := f.Parse(args); flag.IsHelp(err) {
+		f.Help(cc.stdout)
+		return nil
+	} else if err != nil {
+		return usagef("%v", err)
+	}
+	if f.NArg() < 2 {
+		return usagef("must pass a source and a destination")
+	}
+	srcs, dst := f.Args()[:f.NArg()-1], f.Arg(f.NArg()-1)
+	if !*after {
+		mvArgs := []string{"mv"}
+		if *force {
+			mvArgs = append(mvArgs, "--force")
+		}
+		mvArgs = append(mvArgs, "--")
+		mvArgs = append(mvArgs, f.Args()...)
+		return cc.git.Run(ctx, mvArgs...)
+	}
+
+	if *force {
+		return usagef("can't pass -f with --after")
+	}
+	targets, err := copyTargets(ctx, cc, srcs, dst, true)
+	if err != nil {
+		return err
+	}
+	rmArgs := []string{"rm", "-r", "--cached", "--quiet", "--"}
+	for i, src := range srcs {
+		if _, err := os.Lstat(cc.abs(src)); err == nil {
+			return fmt.Errorf("%s still exists; rename without --after to move it", src)
+		}
+		if _, err := os.Lstat(cc.abs(targets[i])); err != nil {
+			return fmt.Errorf("%s was not moved to %s: %w", src, targets[i], err)
+		}
+		rmArgs = append(rmArgs, git.LiteralPath(src).String())
+	}
+	if err := cc.git.Run(ctx, rmArgs...); err != nil {
+		return err
+	}
+	pathspecs := make([]git.Pathspec, 0, len(targets))
+	for _, t := range targets {
+		pathspecs = append(pathspecs, git.LiteralPath(t))
+	}
+	return cc.git.Add(ctx, pathspecs, git.AddOptions{})
+}
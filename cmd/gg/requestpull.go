@@ -32,10 +32,13 @@ import (
 	"gg-scm.io/tool/internal/flag"
 )
 
-const requestPullSynopsis = "create a GitHub pull request"
+const requestPullSynopsis = "create a pull request on GitHub, GitLab, or Gitea"
 
 func requestPull(ctx context.Context, cc *cmdContext, args []string) error {
-	f := flag.NewFlagSet(true, "gg requestpull [-n] [-e=0] [--title=MSG [--body=MSG]] [--draft] [-R user1[,user2]] [BRANCH]", requestPullSynopsis+`
+	f := flag.NewFlagSet(true, "gg requestpull [-n] [-e=0] [--base=BRANCH] [--title=MSG [--body=MSG]] [--draft] [-R user1[,user2]] [BRANCH]\n"+
+		"gg requestpull --ready [BRANCH]\n"+
+		"gg requestpull --sync [-n] [-e=0] [BRANCH]\n"+
+		"gg requestpull --status [BRANCH]", requestPullSynopsis+`
 
 aliases: pr
 
@@ -45,15 +48,57 @@ aliases: pr
 	from upstream fetch information. This command does not push any new
 	commits; it just creates a pull request.
 
+	If the branch is stacked on top of another local branch that has not
+	been merged upstream, then the pull request will target the nearest
+	such branch instead of the upstream branch. Otherwise, the
+	`+"`gg.pullRequestBase`"+` configuration setting can name a default
+	destination branch. Use `+"`--base`"+` to pick the destination branch
+	explicitly.
+
+	After rewording commits on a branch with an open pull request, run
+	`+"`gg requestpull --sync`"+` to update the pull request's title and body
+	from the branch's commits since the pull request's base branch.
+	`+"`gg requestpull --status`"+` shows the reviews, mergeability, and CI
+	results of the branch's open pull request. Its output is colorized when
+	writing to a terminal. Set `+"`color.ggpr`"+` to "always" or "never" to
+	override the detection. `+"`--sync`"+`, `+"`--ready`"+`, and `+"`--status`"+`
+	only support GitHub.
+
+	Use `+"`--draft`"+` to open the pull request as a draft. Once it is ready,
+	`+"`gg requestpull --ready`"+` marks the branch's open draft pull request
+	as ready for review.
+
 	Before sending the pull request, gg will open an editor with a summary
 	of the commits it knows about. The first line will be the pull request
 	title, and any subsequent lines will be used as the body. You can exit
-	your editor without modifications to accept the default summary.
+	your editor without modifications to accept the default summary. If
+	the branch has a description (see `+"`gg branch --edit-description`"+`),
+	it is placed at the start of the body.
 
 	The first time you run requestpull, it will ask you to authorize access to
 	GitHub. A token will be saved to `+"`$XDG_CONFIG_HOME/gg/github_token`"+`
 	(usually `+"`~/.config/gg/github_token`"+`). gg never sees your password,
-	and you can revoke access at any time by visiting your GitHub settings.`)
+	and you can revoke access at any time by visiting your GitHub settings.
+
+	To use a GitHub Enterprise Server instance, set
+	`+"`gg.github.<host>.apiurl`"+` to its REST API base URL (usually
+	`+"`https://<host>/api/v3`"+`) and save a personal access token to
+	`+"`$XDG_CONFIG_HOME/gg/github_token.<host>`"+`.
+
+	Remotes on gitlab.com get merge requests instead. Add the host names of
+	self-hosted GitLab instances to the multi-valued `+"`gg.gitlabHost`"+`
+	setting in your Git configuration. GitLab requires a personal access
+	token with the "api" scope saved to `+"`$XDG_CONFIG_HOME/gg/gitlab_token`"+`
+	(or `+"`$XDG_CONFIG_HOME/gg/gitlab_token.<host>`"+` for a self-hosted
+	instance). Drafts are marked with a "Draft:" title prefix and reviewers
+	are GitLab usernames.
+
+	Pull requests can also be sent to self-hosted Gitea or Forgejo instances
+	whose host names are listed in the multi-valued `+"`gg.giteaHost`"+`
+	setting in your Git configuration. Save an access token to
+	`+"`$XDG_CONFIG_HOME/gg/gitea_token.<host>`"+`. Drafts are marked with a
+	"WIP:" title prefix.`)
+	baseFlag := f.String("base", "", "`branch` to merge into (defaults to the branch this one is stacked on or its upstream)")
 	bodyFlag := f.String("body", "", "pull request `description` (requires --title)")
 	draft := f.Bool("draft", false, "create a pull request as draft")
 	edit := f.Bool("e", true, "invoke editor on pull request message (ignored if --title is specified)")
@@ -61,7 +106,10 @@ aliases: pr
 	dryRun := f.Bool("n", false, "prints the pull request instead of creating it")
 	f.Alias("n", "dry-run")
 	maintainerEdits := f.Bool("maintainer-edits", true, "allow maintainers to edit this branch")
-	reviewers := f.MultiString("R", "GitHub `user`names of reviewers to add")
+	ready := f.Bool("ready", false, "mark the branch's open draft pull request as ready for review")
+	reviewers := f.MultiString("R", "`user`names of reviewers to add")
+	status := f.Bool("status", false, "show the reviews, mergeability, and CI results of the branch's open pull request")
+	sync := f.Bool("sync", false, "update the title and body of the branch's open pull request from its commits")
 	f.Alias("R", "reviewer")
 	titleFlag := f.String("title", "", "pull request title")
 	if err := f.Parse(args); flag.IsHelp(err) {
@@ -73,6 +121,24 @@ aliases: pr
 	if f.NArg() > 1 {
 		return usagef("only one branch allowed")
 	}
+	if *status {
+		if *draft || *titleFlag != "" || *bodyFlag != "" || *baseFlag != "" || *dryRun || len(*reviewers) > 0 || *sync || *ready {
+			return usagef("--status cannot be used with --sync, --ready, or flags for creating a pull request")
+		}
+		return requestPullStatus(ctx, cc, f.Arg(0))
+	}
+	if *ready {
+		if *draft || *titleFlag != "" || *bodyFlag != "" || *baseFlag != "" || *dryRun || len(*reviewers) > 0 || *sync {
+			return usagef("--ready cannot be used with --sync or flags for creating a pull request")
+		}
+		return requestPullReady(ctx, cc, f.Arg(0))
+	}
+	if *sync {
+		if *draft || *titleFlag != "" || *bodyFlag != "" || *baseFlag != "" || len(*reviewers) > 0 {
+			return usagef("--sync cannot be used with flags for creating a pull request")
+		}
+		return requestPullSync(ctx, cc, f.Arg(0), *edit, *dryRun)
+	}
 	*titleFlag = strings.TrimSpace(*titleFlag)
 	if *bodyFlag != "" && *titleFlag == "" {
 		return usagef("cannot specify --body without specifying --title")
@@ -81,79 +147,22 @@ aliases: pr
 	if err != nil {
 		return err
 	}
+	target, err := findPullRequestTarget(ctx, cc, cfg, f.Arg(0), *baseFlag)
+	if err != nil {
+		return err
+	}
 	var token []byte
 	if !*dryRun {
 		var err error
-		token, err = cc.xdgDirs.readConfig(gitHubTokenFilename)
-		if os.IsNotExist(err) {
-			newToken, err := gitHubDeviceFlow(ctx, cc.httpClient, firstTimeLogin, cc.stderr)
-			if err != nil {
-				return err
-			}
-			token = append([]byte(newToken), '\n')
-			if err := cc.xdgDirs.writeSecret(gitHubTokenFilename, token); err != nil {
-				fmt.Fprintln(cc.stderr, "gg is authorized, but failed to save the authorization:", err)
-				fmt.Fprintln(cc.stderr, "You will need to connect again the next time you run requestpull.")
-			} else {
-				fmt.Fprintln(cc.stderr, "Success! Your account will remembered in the future.")
-			}
-		} else if err != nil {
-			return err
-		}
-		token = bytes.TrimSpace(token)
-	}
-
-	// Find local branch name.
-	var branch string
-	if branchArg := f.Arg(0); branchArg == "" {
-		branch = currentBranch(ctx, cc)
-		if branch == "" {
-			return errors.New("no branch currently checked out")
-		}
-	} else {
-		rev, err := cc.git.ParseRev(ctx, branchArg)
+		token, err = target.forge.readToken(ctx, cc)
 		if err != nil {
 			return err
 		}
-		branch = rev.Ref.Branch()
-		if branch == "" {
-			return fmt.Errorf("%s is not a branch", branchArg)
-		}
-	}
-
-	// Find base repository and ref.
-	baseRemote := cfg.Value("branch." + branch + ".remote")
-	if baseRemote == "" {
-		remotes := cfg.ListRemotes()
-		if _, ok := remotes["origin"]; !ok {
-			return errors.New("branch has no remote and no remote named \"origin\" found")
-		}
-		baseRemote = "origin"
-	}
-	baseURL := cfg.Value("remote." + baseRemote + ".url")
-	baseOwner, baseRepo := parseGitHubRemoteURL(baseURL)
-	if baseOwner == "" || baseRepo == "" {
-		return fmt.Errorf("%s is not a GitHub repository", baseURL)
-	}
-	baseBranch := inferUpstream(cfg, branch).Branch()
-
-	// Find head repository and ref.
-	headRemote, err := inferPushRepo(cfg, branch)
-	if err != nil {
-		return err
-	}
-	headURL := cfg.Value("remote." + headRemote + ".pushurl")
-	if headURL == "" {
-		headURL = cfg.Value("remote." + headRemote + ".url")
-	}
-	headOwner, _ := parseGitHubRemoteURL(headURL)
-	if headOwner == "" {
-		return fmt.Errorf("%s is not a GitHub repository", headURL)
 	}
 
 	// Create pull request. Run message inference no matter what, since it
 	// has the side effect of detecting no change.
-	title, body, err := inferPullRequestMessage(ctx, cc.git, branch+"@{upstream}", branch)
+	title, body, err := inferPullRequestTargetMessage(ctx, cc.git, cfg, target)
 	if err != nil {
 		return err
 	}
@@ -166,7 +175,7 @@ aliases: pr
 			draftText = "[DRAFT] "
 		}
 		_, err := fmt.Fprintf(cc.stdout, "%s%s/%s: %s\nMerge into %s:%s from %s:%s\n",
-			draftText, baseOwner, baseRepo, title, baseOwner, baseBranch, headOwner, branch)
+			draftText, target.baseOwner, target.baseRepo, title, target.baseOwner, target.baseBranch, target.headOwner, target.branch)
 		if err != nil {
 			return err
 		}
@@ -179,62 +188,182 @@ aliases: pr
 		return nil
 	}
 	if *edit && *titleFlag == "" {
-		editorInit := new(bytes.Buffer)
-		editorInit.WriteString(title)
-		if body != "" {
-			editorInit.WriteString("\n\n")
-			editorInit.WriteString(body)
-		}
-		editorInit.WriteString("\n# Please enter the pull request message. Lines starting with '#' will\n" +
-			"# be ignored, and an empty message aborts the pull request. The first\n" +
-			"# line will be used as the title and must not be empty.\n")
-		fmt.Fprintf(editorInit, "# %s/%s: merge into %s:%s from %s:%s\n",
-			baseOwner, baseRepo, baseOwner, baseBranch, headOwner, branch)
-		newMsg, err := cc.editor.open(ctx, "PR_EDITMSG.md", editorInit.Bytes())
-		if err != nil {
-			return err
-		}
-		title, body, err = parseEditedPullRequestMessage(newMsg)
+		title, body, err = editPullRequestMessage(ctx, cc, target, title, body)
 		if err != nil {
 			return err
 		}
 	}
-	prNum, prURL, err := createPullRequest(ctx, cc.httpClient, pullRequestParams{
+	var fullReviewers []string
+	for _, r := range *reviewers {
+		fullReviewers = append(fullReviewers, strings.Split(r, ",")...)
+	}
+	prURL, err := target.forge.createPullRequest(ctx, cc.httpClient, pullRequestParams{
 		authToken:              string(token),
-		baseOwner:              baseOwner,
-		baseRepo:               baseRepo,
-		baseBranch:             baseBranch,
-		headOwner:              headOwner,
-		headBranch:             branch,
+		baseOwner:              target.baseOwner,
+		baseRepo:               target.baseRepo,
+		baseBranch:             target.baseBranch,
+		headOwner:              target.headOwner,
+		headRepo:               target.headRepo,
+		headBranch:             target.branch,
 		title:                  title,
 		body:                   body,
 		draft:                  *draft,
 		disableMaintainerEdits: !*maintainerEdits,
+		reviewers:              fullReviewers,
 	})
-	if err != nil {
-		return err
+	if prURL != "" {
+		if _, err := fmt.Fprintf(cc.stdout, "Created pull request at %s\n", prURL); err != nil {
+			return err
+		}
+	}
+	return err
+}
+
+// pullRequestTarget describes the repositories and branches involved
+// in a pull request.
+type pullRequestTarget struct {
+	forge  pullRequestForge
+	branch string // local branch name
+
+	baseRemote string
+	baseOwner  string
+	baseRepo   string
+	baseBranch string
+
+	headRemote string // remote that the branch is pushed to
+	headOwner  string
+	headRepo   string
+
+	// msgBase is the local revision that the branch's commits are compared
+	// against when inferring a pull request message.
+	msgBase string
+}
+
+// findPullRequestTarget determines where a pull request for the given
+// branch (or the current branch if branchArg is empty) should be sent.
+// baseFlag overrides the inferred base branch if it is not empty.
+func findPullRequestTarget(ctx context.Context, cc *cmdContext, cfg *git.Config, branchArg string, baseFlag string) (*pullRequestTarget, error) {
+	// Find local branch name.
+	t := new(pullRequestTarget)
+	if branchArg == "" {
+		t.branch = currentBranch(ctx, cc)
+		if t.branch == "" {
+			return nil, errors.New("no branch currently checked out")
+		}
+	} else {
+		rev, err := cc.git.ParseRev(ctx, branchArg)
+		if err != nil {
+			return nil, err
+		}
+		t.branch = rev.Ref.Branch()
+		if t.branch == "" {
+			return nil, fmt.Errorf("%s is not a branch", branchArg)
+		}
 	}
-	_, err = fmt.Fprintf(cc.stdout, "Created pull request at %s\n", prURL)
+
+	// Find base repository and ref.
+	gcfg, err := readGGConfig(ctx, cc.git)
 	if err != nil {
-		return err
+		return nil, err
 	}
-	if len(*reviewers) > 0 {
-		var fullReviewers []string
-		for _, r := range *reviewers {
-			fullReviewers = append(fullReviewers, strings.Split(r, ",")...)
+	t.baseRemote = cfg.Value("branch." + t.branch + ".remote")
+	if t.baseRemote == "" {
+		remotes := cfg.ListRemotes()
+		if _, ok := remotes["origin"]; !ok {
+			return nil, errors.New("branch has no remote and no remote named \"origin\" found")
 		}
-		err := addPullRequestReviewers(ctx, cc.httpClient, pullRequestReviewParams{
-			authToken: string(token),
-			owner:     baseOwner,
-			repo:      baseRepo,
-			prNum:     prNum,
-			users:     fullReviewers,
-		})
+		t.baseRemote = "origin"
+	}
+	baseURL := cfg.Value("remote." + t.baseRemote + ".url")
+	t.forge, t.baseOwner, t.baseRepo = parsePullRequestRemoteURL(gcfg, baseURL)
+	if t.forge == nil {
+		return nil, fmt.Errorf("%s is not a GitHub, GitLab, or Gitea repository", baseURL)
+	}
+	t.baseBranch = inferUpstream(cfg, t.branch).Branch()
+	t.msgBase = t.branch + "@{upstream}"
+	if baseFlag != "" {
+		t.setBase(ctx, cc.git, baseFlag)
+	} else {
+		stackBase, err := inferStackBase(ctx, cc.git, cfg, t.branch, t.baseRemote)
 		if err != nil {
-			return err
+			return nil, err
+		}
+		if stackBase != "" {
+			t.baseBranch = stackBase
+			t.msgBase = git.BranchRef(stackBase).String()
+		} else {
+			if defaultBase := gcfg.Value("gg.pullRequestBase"); defaultBase != "" {
+				t.baseBranch = strings.TrimPrefix(defaultBase, "refs/heads/")
+				t.msgBase = t.baseRemote + "/" + t.baseBranch
+			}
 		}
 	}
-	return nil
+
+	// Find head repository and ref.
+	t.headRemote, err = inferPushRepo(cfg, t.branch)
+	if err != nil {
+		return nil, err
+	}
+	headURL := cfg.Value("remote." + t.headRemote + ".pushurl")
+	if headURL == "" {
+		headURL = cfg.Value("remote." + t.headRemote + ".url")
+	}
+	var headForge pullRequestForge
+	headForge, t.headOwner, t.headRepo = parsePullRequestRemoteURL(gcfg, headURL)
+	if headForge == nil {
+		return nil, fmt.Errorf("%s is not a %v repository", headURL, t.forge)
+	}
+	if headForge != t.forge {
+		return nil, fmt.Errorf("%s and %s are not hosted on the same %v instance", headURL, baseURL, t.forge)
+	}
+	return t, nil
+}
+
+// setBase changes the branch that the pull request merges into.
+// The branch's commits are compared against the local branch of the same
+// name if there is one, or the remote-tracking branch otherwise.
+func (t *pullRequestTarget) setBase(ctx context.Context, g *git.Git, branch string) {
+	t.baseBranch = strings.TrimPrefix(branch, "refs/heads/")
+	t.msgBase = git.BranchRef(t.baseBranch).String()
+	if _, err := g.ParseRev(ctx, t.msgBase); err != nil {
+		// Not a local branch. Compare against the remote-tracking branch.
+		t.msgBase = t.baseRemote + "/" + t.baseBranch
+	}
+}
+
+// inferPullRequestTargetMessage infers a pull request title and body
+// from the target branch's commits and description.
+func inferPullRequestTargetMessage(ctx context.Context, g *git.Git, cfg *git.Config, t *pullRequestTarget) (title, body string, _ error) {
+	title, body, err := inferPullRequestMessage(ctx, g, t.msgBase, t.branch)
+	if err != nil {
+		return "", "", err
+	}
+	if desc := branchDescription(cfg, t.branch); desc != "" {
+		body = strings.TrimSpace(desc + "\n\n" + body)
+	}
+	return title, body, nil
+}
+
+// readGitHubToken reads the saved GitHub authorization token, running
+// the device flow to obtain a new token if one has not been saved.
+func readGitHubToken(ctx context.Context, cc *cmdContext) ([]byte, error) {
+	token, err := cc.xdgDirs.readConfig(gitHubTokenFilename)
+	if os.IsNotExist(err) {
+		newToken, err := gitHubDeviceFlow(ctx, cc.httpClient, firstTimeLogin, cc.stderr)
+		if err != nil {
+			return nil, err
+		}
+		token = append([]byte(newToken), '\n')
+		if err := cc.xdgDirs.writeSecret(gitHubTokenFilename, token); err != nil {
+			fmt.Fprintln(cc.stderr, "gg is authorized, but failed to save the authorization:", err)
+			fmt.Fprintln(cc.stderr, "You will need to connect again the next time you run requestpull.")
+		} else {
+			fmt.Fprintln(cc.stderr, "Success! Your account will remembered in the future.")
+		}
+	} else if err != nil {
+		return nil, err
+	}
+	return bytes.TrimSpace(token), nil
 }
 
 func inferPullRequestMessage(ctx context.Context, g *git.Git, base, head string) (title, body string, _ error) {
@@ -305,6 +434,27 @@ func readPullRequestTemplate(ctx context.Context, g *git.Git) string {
 	return ""
 }
 
+// editPullRequestMessage opens an editor with the given pull request
+// title and body and returns the edited title and body.
+func editPullRequestMessage(ctx context.Context, cc *cmdContext, t *pullRequestTarget, title, body string) (newTitle, newBody string, _ error) {
+	editorInit := new(bytes.Buffer)
+	editorInit.WriteString(title)
+	if body != "" {
+		editorInit.WriteString("\n\n")
+		editorInit.WriteString(body)
+	}
+	editorInit.WriteString("\n# Please enter the pull request message. Lines starting with '#' will\n" +
+		"# be ignored, and an empty message aborts the pull request. The first\n" +
+		"# line will be used as the title and must not be empty.\n")
+	fmt.Fprintf(editorInit, "# %s/%s: merge into %s:%s from %s:%s\n",
+		t.baseOwner, t.baseRepo, t.baseOwner, t.baseBranch, t.headOwner, t.branch)
+	newMsg, err := cc.editor.open(ctx, "PR_EDITMSG.md", editorInit.Bytes())
+	if err != nil {
+		return "", "", err
+	}
+	return parseEditedPullRequestMessage(newMsg)
+}
+
 func parseEditedPullRequestMessage(b []byte) (title, body string, _ error) {
 	// Split into lines.
 	lines := bytes.Split(b, []byte{'\n'})
@@ -338,12 +488,14 @@ func parseEditedPullRequestMessage(b []byte) (title, body string, _ error) {
 
 type pullRequestParams struct {
 	authToken string
+	apiRoot   string // empty for github.com
 
 	baseOwner  string
 	baseRepo   string
 	baseBranch string
 
 	headOwner  string
+	headRepo   string
 	headBranch string
 
 	title string
@@ -351,6 +503,66 @@ type pullRequestParams struct {
 
 	draft                  bool
 	disableMaintainerEdits bool
+	reviewers              []string
+}
+
+// gitHubForge is github.com or a GitHub Enterprise Server instance.
+type gitHubForge struct {
+	host string
+	// apiRoot is the base URL of a GitHub Enterprise Server instance's
+	// REST API. It is empty for github.com.
+	apiRoot string
+}
+
+func (f gitHubForge) String() string {
+	return "GitHub"
+}
+
+// tokenFilename returns the name of the file in the gg configuration
+// directory that stores the token for the instance.
+func (f gitHubForge) tokenFilename() string {
+	if f.apiRoot == "" {
+		return gitHubTokenFilename
+	}
+	return gitHubTokenFilename + "." + f.host
+}
+
+func (f gitHubForge) readToken(ctx context.Context, cc *cmdContext) ([]byte, error) {
+	if f.apiRoot == "" {
+		return readGitHubToken(ctx, cc)
+	}
+	token, err := cc.xdgDirs.readConfig(f.tokenFilename())
+	if os.IsNotExist(err) {
+		return nil, fmt.Errorf("no token found for %s. Create a personal access token with the \"repo\" scope "+
+			"at https://%s/settings/tokens and save it to $XDG_CONFIG_HOME/gg/%s",
+			f.host, f.host, f.tokenFilename())
+	}
+	if err != nil {
+		return nil, err
+	}
+	return bytes.TrimSpace(token), nil
+}
+
+func (f gitHubForge) createPullRequest(ctx context.Context, client *http.Client, params pullRequestParams) (prURL string, _ error) {
+	params.apiRoot = f.apiRoot
+	prNum, prURL, err := createPullRequest(ctx, client, params)
+	if err != nil {
+		return "", err
+	}
+	if len(params.reviewers) > 0 {
+		err := addPullRequestReviewers(ctx, client, pullRequestReviewParams{
+			authToken: params.authToken,
+			apiRoot:   params.apiRoot,
+			owner:     params.baseOwner,
+			repo:      params.baseRepo,
+			prNum:     prNum,
+			users:     params.reviewers,
+		})
+		if err != nil {
+			return prURL, err
+		}
+	}
+	return prURL, nil
 }
 
 func createPullRequest(ctx context.Context, client *http.Client, params pullRequestParams) (prNum uint64, prURL string, _ error) {
@@ -387,8 +599,8 @@ func createPullRequest(ctx context.Context, client *http.Client, params pullRequ
 		return 0, "", fmt.Errorf("create pull request for %s/%s: %w", params.baseOwner, params.baseRepo, err)
 	}
 
-	apiURL := fmt.Sprintf("https://api.github.com/repos/%s/%s/pulls",
-		url.PathEscape(params.baseOwner), url.PathEscape(params.baseRepo))
+	apiURL := gitHubAPIURL(params.apiRoot, fmt.Sprintf("repos/%s/%s/pulls",
+		url.PathEscape(params.baseOwner), url.PathEscape(params.baseRepo)))
 	req, err := http.NewRequestWithContext(ctx, http.MethodPost, apiURL, bytes.NewReader(reqBodyJSON))
 	if err != nil {
 		return 0, "", fmt.Errorf("create pull request for %s/%s: %w", params.baseOwner, params.baseRepo, err)
@@ -419,6 +631,7 @@ func createPullRequest(ctx context.Context, client *http.Client, params pullRequ
 
 type pullRequestReviewParams struct {
 	authToken string
+	apiRoot   string // empty for github.com
 
 	owner string
 	repo  string
@@ -437,8 +650,8 @@ func addPullRequestReviewers(ctx context.Context, client *http.Client, params pu
 		return errors.New("add pull request reviewers: no reviewers to add")
 	}
 
-	apiURL := fmt.Sprintf("https://api.github.com/repos/%s/%s/pulls/%d/requested_reviewers",
-		url.PathEscape(params.owner), url.PathEscape(params.repo), params.prNum)
+	apiURL := gitHubAPIURL(params.apiRoot, fmt.Sprintf("repos/%s/%s/pulls/%d/requested_reviewers",
+		url.PathEscape(params.owner), url.PathEscape(params.repo), params.prNum))
 	req, err := http.NewRequest("POST", apiURL, nil)
 	if err != nil {
 		return fmt.Errorf("add pull request reviewers to %s/%s/pulls/%d: %w", params.owner, params.repo, params.prNum, err)
@@ -469,6 +682,64 @@ func addPullRequestReviewers(ctx context.Context, client *http.Client, params pu
 	return nil
 }
 
+// inferStackBase returns the name of the nearest local branch that the
+// given branch is stacked on top of. A branch is only considered if its
+// tip is an ancestor of the given branch, it has commits that are not
+// in the given branch's upstream, and it is pushed to baseRemote.
+// If no such branch exists, then inferStackBase returns the empty string.
+func inferStackBase(ctx context.Context, g *git.Git, cfg *git.Config, branch string, baseRemote string) (string, error) {
+	upstream, err := g.ParseRev(ctx, branch+"@{upstream}")
+	if err != nil {
+		// Without an upstream, there's nothing to compare against.
+		return "", nil
+	}
+	refs, err := g.ListRefs(ctx)
+	if err != nil {
+		return "", fmt.Errorf("infer base of %s: %w", branch, err)
+	}
+	tip, ok := refs[git.BranchRef(branch)]
+	if !ok {
+		return "", fmt.Errorf("infer base of %s: branch does not exist", branch)
+	}
+	var best git.Ref
+	for ref, commit := range refs {
+		if !ref.IsBranch() || ref.Branch() == branch || commit == tip || commit == upstream.Commit {
+			continue
+		}
+		if r, err := inferPushRepo(cfg, ref.Branch()); err != nil || r != baseRemote {
+			continue
+		}
+		if isBase, err := g.IsAncestor(ctx, commit.String(), tip.String()); err != nil {
+			return "", fmt.Errorf("infer base of %s: %w", branch, err)
+		} else if !isBase {
+			continue
+		}
+		if merged, err := g.IsAncestor(ctx, commit.String(), upstream.Commit.String()); err != nil {
+			return "", fmt.Errorf("infer base of %s: %w", branch, err)
+		} else if merged {
+			continue
+		}
+		switch {
+		case best == "":
+		case refs[best] == commit:
+			// Break ties by name so the result is stable.
+			if ref > best {
+				continue
+			}
+		default:
+			closer, err := g.IsAncestor(ctx, refs[best].String(), commit.String())
+			if err != nil {
+				return "", fmt.Errorf("infer base of %s: %w", branch, err)
+			}
+			if !closer {
+				continue
+			}
+		}
+		best = ref
+	}
+	return best.Branch(), nil
+}
+
 // inferUpstream returns the default remote ref to pull from.
 // localBranch may be empty.
 func inferUpstream(cfg *git.Config, localBranch string) git.Ref {
@@ -500,6 +771,56 @@ func parseGitHubErrorResponse(resp *http.Response) error {
 	return fmt.Errorf("GitHub API HTTP %s: %s", resp.Status, payload.Message)
 }
 
+// gitHubAPIURL returns the URL of a GitHub REST API endpoint. apiRoot is the
+// base URL of a GitHub Enterprise Server API or empty for github.com.
+func gitHubAPIURL(apiRoot string, path string) string {
+	if apiRoot == "" {
+		return "https://api.github.com/" + path
+	}
+	return strings.TrimSuffix(apiRoot, "/") + "/" + path
+}
+
+// parseGitHubEnterpriseRemoteURL returns the owner and name of a repository
+// hosted on a GitHub Enterprise Server instance configured with a
+// gg.github.<host>.apiurl setting. Settings in a workspace configuration
+// file are ignored, since they would direct the user's token elsewhere.
+func parseGitHubEnterpriseRemoteURL(cfg *ggConfig, u string) (_ gitHubForge, owner, repo string) {
+	host, path := splitRemoteURL(u)
+	if host == "" {
+		return gitHubForge{}, "", ""
+	}
+	var apiRoot string
+	for _, ent := range cfg.entries {
+		if ent.workspace || !strings.HasPrefix(ent.key, "gg.github.") || !strings.HasSuffix(ent.key, ".apiurl") {
+			continue
+		}
+		entHost := strings.TrimSuffix(strings.TrimPrefix(ent.key, "gg.github."), ".apiurl")
+		if strings.EqualFold(entHost, host) {
+			apiRoot = ent.value
+		}
+	}
+	if apiRoot == "" {
+		return gitHubForge{}, "", ""
+	}
+	path = strings.TrimSuffix(path, ".git")
+	i := strings.IndexByte(path, '/')
+	if i <= 0 || i == len(path)-1 || strings.Contains(path[i+1:], "/") {
+		return gitHubForge{}, "", ""
+	}
+	return gitHubForge{host: strings.ToLower(host), apiRoot: apiRoot}, path[:i], path[i+1:]
+}
+
+// gitHubGraphQLURL returns the URL of the GitHub GraphQL API endpoint
+// for the REST API at apiRoot (empty for github.com).
+func gitHubGraphQLURL(apiRoot string) string {
+	if apiRoot == "" {
+		return "https://api.github.com/graphql"
+	}
+	// GitHub Enterprise Server serves REST at /api/v3
+	// and GraphQL at /api/graphql.
+	return strings.TrimSuffix(strings.TrimSuffix(apiRoot, "/"), "/v3") + "/graphql"
+}
+
 func parseGitHubRemoteURL(u string) (owner, repo string) {
 	var path string
 	switch {
@@ -0,0 +1,277 @@
+// Copyright 2021 The gg Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+
+	"gg-scm.io/tool/internal/flag"
+	"gg-scm.io/tool/internal/terminal"
+)
+
+// requestPullStatus shows the open pull request for the given branch (or
+// the current branch if branchArg is empty) along with its reviews, whether
+// it can be merged, and the results of the commit statuses and check runs
+// for its head commit.
+func requestPullStatus(ctx context.Context, cc *cmdContext, branchArg string) error {
+	cfg, err := cc.git.ReadConfig(ctx)
+	if err != nil {
+		return err
+	}
+	target, err := findPullRequestTarget(ctx, cc, cfg, branchArg, "")
+	if err != nil {
+		return err
+	}
+	gh, ok := target.forge.(gitHubForge)
+	if !ok {
+		return fmt.Errorf("requestpull --status does not support %v repositories", target.forge)
+	}
+	token, err := gh.readToken(ctx, cc)
+	if err != nil {
+		return err
+	}
+	openPR, err := findPullRequest(ctx, cc.httpClient, findPullRequestParams{
+		authToken:  string(token),
+		apiRoot:    gh.apiRoot,
+		owner:      target.baseOwner,
+		repo:       target.baseRepo,
+		headOwner:  target.headOwner,
+		headBranch: target.branch,
+	})
+	if err != nil {
+		return err
+	}
+	prParams := pullRequestResourceParams{
+		authToken: string(token),
+		apiRoot:   gh.apiRoot,
+		owner:     target.baseOwner,
+		repo:      target.baseRepo,
+		prNum:     openPR.Number,
+	}
+	var pr gitHubPullRequestDetails
+	if err := getGitHubPullRequestResource(ctx, cc.httpClient, prParams, "", &pr); err != nil {
+		return err
+	}
+	var reviews []gitHubReview
+	if err := getGitHubPullRequestResource(ctx, cc.httpClient, prParams, "reviews", &reviews); err != nil {
+		return err
+	}
+	checks, err := listCommitChecks(ctx, cc.httpClient, commitChecksParams{
+		authToken: string(token),
+		apiRoot:   gh.apiRoot,
+		owner:     target.baseOwner,
+		repo:      target.baseRepo,
+		ref:       pr.Head.SHA,
+	})
+	if err != nil {
+		return err
+	}
+
+	var goodColor, badColor, pendingColor []byte
+	colorize, err := cfg.ColorBool("color.ggpr", cc.isTerminal())
+	if err != nil {
+		fmt.Fprintln(cc.stderr, "gg:", err)
+	} else if colorize {
+		goodColor, err = cfg.Color("color.ggpr.good", "green")
+		if err != nil {
+			fmt.Fprintln(cc.stderr, "gg:", err)
+		}
+		badColor, err = cfg.Color("color.ggpr.bad", "red")
+		if err != nil {
+			fmt.Fprintln(cc.stderr, "gg:", err)
+		}
+		pendingColor, err = cfg.Color("color.ggpr.pending", "yellow")
+		if err != nil {
+			fmt.Fprintln(cc.stderr, "gg:", err)
+		}
+	}
+	out := bufio.NewWriter(cc.stdout)
+	if colorize {
+		if err := terminal.ResetTextStyle(out); err != nil {
+			return err
+		}
+	}
+	// colored returns s wrapped in the given color, if any.
+	colored := func(color []byte, s string) string {
+		if len(color) == 0 {
+			return s
+		}
+		sb := new(strings.Builder)
+		sb.Write(color)
+		sb.WriteString(s)
+		terminal.ResetTextStyle(sb)
+		return sb.String()
+	}
+
+	fmt.Fprintf(out, "#%d %s\n%s\n", pr.Number, pr.Title, pr.HTMLURL)
+	if pr.Draft {
+		fmt.Fprintf(out, "Draft: %s\n", colored(pendingColor, "yes"))
+	}
+
+	fmt.Fprintf(out, "Mergeable: ")
+	switch {
+	case pr.Mergeable == nil:
+		fmt.Fprintln(out, colored(pendingColor, "unknown"))
+	case *pr.Mergeable && (pr.MergeableState == "clean" || pr.MergeableState == "has_hooks"):
+		fmt.Fprintln(out, colored(goodColor, "yes"))
+	case *pr.Mergeable:
+		fmt.Fprintf(out, "%s (%s)\n", colored(pendingColor, "yes"), strings.ReplaceAll(pr.MergeableState, "_", " "))
+	default:
+		fmt.Fprintf(out, "%s (%s)\n", colored(badColor, "no"), strings.ReplaceAll(pr.MergeableState, "_", " "))
+	}
+
+	reviewStates := latestReviewStates(reviews)
+	for _, r := range pr.RequestedReviewers {
+		if _, reviewed := reviewStates[r.Login]; !reviewed {
+			reviewStates[r.Login] = "PENDING"
+		}
+	}
+	if len(reviewStates) == 0 {
+		fmt.Fprintln(out, "Reviews: none")
+	} else {
+		fmt.Fprintln(out, "Reviews:")
+		users := make([]string, 0, len(reviewStates))
+		for user := range reviewStates {
+			users = append(users, user)
+		}
+		sort.Strings(users)
+		for _, user := range users {
+			state := reviewStates[user]
+			color := pendingColor
+			switch state {
+			case "APPROVED":
+				color = goodColor
+			case "CHANGES_REQUESTED":
+				color = badColor
+			}
+			fmt.Fprintf(out, "  %s %s\n", user, colored(color, strings.ToLower(strings.ReplaceAll(state, "_", " "))))
+		}
+	}
+
+	if len(checks) == 0 {
+		fmt.Fprintln(out, "Checks: none")
+	} else {
+		fmt.Fprintln(out, "Checks:")
+		for _, c := range checks {
+			color := pendingColor
+			switch {
+			case c.succeeded():
+				color = goodColor
+			case c.state != "pending" && c.state != "queued" && c.state != "in_progress":
+				color = badColor
+			}
+			fmt.Fprintf(out, "  %s %s\n", c.name, colored(color, strings.ReplaceAll(c.state, "_", " ")))
+		}
+	}
+	return out.Flush()
+}
+
+// gitHubPullRequestDetails is the subset of a single pull request resource
+// from the GitHub API that requestpull --status displays.
+type gitHubPullRequestDetails struct {
+	Number  uint64
+	Title   string
+	HTMLURL string `json:"html_url"`
+	Draft   bool
+	// Mergeable is nil while GitHub is computing mergeability.
+	Mergeable          *bool
+	MergeableState     string `json:"mergeable_state"`
+	RequestedReviewers []struct {
+		Login string
+	} `json:"requested_reviewers"`
+	Head struct {
+		SHA string
+	}
+}
+
+// A gitHubReview is a pull request review returned from the GitHub API.
+type gitHubReview struct {
+	User struct {
+		Login string
+	}
+	State string
+}
+
+// latestReviewStates returns the state of each user's most recent review
+// that approved or requested changes. Users who only left comments are
+// reported as "COMMENTED". reviews must be in chronological order.
+func latestReviewStates(reviews []gitHubReview) map[string]string {
+	states := make(map[string]string)
+	for _, r := range reviews {
+		switch r.State {
+		case "APPROVED", "CHANGES_REQUESTED", "DISMISSED":
+			states[r.User.Login] = r.State
+		case "COMMENTED":
+			if _, ok := states[r.User.Login]; !ok {
+				states[r.User.Login] = r.State
+			}
+		}
+	}
+	return states
+}
+
+type pullRequestResourceParams struct {
+	authToken string
+	apiRoot   string // empty for github.com
+
+	owner string
+	repo  string
+	prNum uint64
+}
+
+// getGitHubPullRequestResource decodes the JSON response of a GET request
+// for a pull request or one of its subresources (like "reviews") into v.
+func getGitHubPullRequestResource(ctx context.Context, client *http.Client, params pullRequestResourceParams, resource string, v interface{}) error {
+	if params.authToken == "" {
+		return errors.New("get pull request: missing authentication token")
+	}
+	if params.owner == "" || params.repo == "" {
+		return errors.New("get pull request: missing repository owner or name")
+	}
+	path := fmt.Sprintf("repos/%s/%s/pulls/%d", url.PathEscape(params.owner), url.PathEscape(params.repo), params.prNum)
+	name := fmt.Sprintf("%s/%s/pulls/%d", params.owner, params.repo, params.prNum)
+	if resource != "" {
+		path += "/" + resource
+		name += "/" + resource
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, gitHubAPIURL(params.apiRoot, path), nil)
+	if err != nil {
+		return fmt.Errorf("get %s: %w", name, err)
+	}
+	req.Header.Set("User-Agent", userAgentString())
+	req.Header.Set("Accept", "application/vnd.github.v3+json")
+	req.Header.Set("Authorization", "token "+params.authToken)
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("get %s: %w", name, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		err := parseGitHubErrorResponse(resp)
+		return fmt.Errorf("get %s: %w", name, err)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return fmt.Errorf("get %s: parsing response: %w", name, err)
+	}
+	return nil
+}
@@ -0,0 +1,405 @@
+// Copyright 2021 The gg Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+)
+
+// requestPullSync updates the title and body of the open pull request for
+// the given branch (or the current branch if branchArg is empty) from the
+// branch's commits. The commits are compared against the pull request's
+// base branch.
+func requestPullSync(ctx context.Context, cc *cmdContext, branchArg string, edit, dryRun bool) error {
+	cfg, err := cc.git.ReadConfig(ctx)
+	if err != nil {
+		return err
+	}
+	target, err := findPullRequestTarget(ctx, cc, cfg, branchArg, "")
+	if err != nil {
+		return err
+	}
+	gh, ok := target.forge.(gitHubForge)
+	if !ok {
+		return fmt.Errorf("requestpull --sync does not support %v repositories", target.forge)
+	}
+	token, err := gh.readToken(ctx, cc)
+	if err != nil {
+		return err
+	}
+	pr, err := findPullRequest(ctx, cc.httpClient, findPullRequestParams{
+		authToken:  string(token),
+		apiRoot:    gh.apiRoot,
+		owner:      target.baseOwner,
+		repo:       target.baseRepo,
+		headOwner:  target.headOwner,
+		headBranch: target.branch,
+	})
+	if err != nil {
+		return err
+	}
+	target.setBase(ctx, cc.git, pr.Base.Ref)
+	title, body, err := inferPullRequestTargetMessage(ctx, cc.git, cfg, target)
+	if err != nil {
+		return err
+	}
+	if dryRun {
+		_, err := fmt.Fprintf(cc.stdout, "%s: %s\n", pr.HTMLURL, title)
+		if err != nil {
+			return err
+		}
+		if body != "" {
+			_, err = fmt.Fprintf(cc.stdout, "\n%s\n", body)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if edit {
+		title, body, err = editPullRequestMessage(ctx, cc, target, title, body)
+		if err != nil {
+			return err
+		}
+	}
+	prURL, err := updatePullRequest(ctx, cc.httpClient, updatePullRequestParams{
+		authToken: string(token),
+		apiRoot:   gh.apiRoot,
+		owner:     target.baseOwner,
+		repo:      target.baseRepo,
+		prNum:     pr.Number,
+		title:     title,
+		body:      body,
+	})
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(cc.stdout, "Updated pull request at %s\n", prURL)
+	return err
+}
+
+type listPullRequestsParams struct {
+	authToken string
+	apiRoot   string // empty for github.com
+
+	owner string
+	repo  string
+
+	// head filters the pull requests by head branch.
+	// It must be in the form "owner:branch".
+	head string
+	// base filters the pull requests by base branch name.
+	base string
+	// state is "open" (the default), "closed", or "all".
+	state string
+}
+
+// A gitHubPullRequest is a pull request returned from the GitHub API.
+type gitHubPullRequest struct {
+	Number  uint64
+	NodeID  string `json:"node_id"`
+	HTMLURL string `json:"html_url"`
+	Draft   bool
+	State   string
+	// MergedAt is empty if the pull request has not been merged.
+	MergedAt string `json:"merged_at"`
+	Head     struct {
+		Ref   string
+		SHA   string
+		Label string // "owner:branch"
+	}
+	Base struct {
+		Ref string
+	}
+}
+
+// listPullRequestsPerPage is the number of pull requests that
+// listOpenPullRequests asks GitHub for in each request, the maximum that
+// the API allows.
+const listPullRequestsPerPage = 100
+
+// listOpenPullRequests returns the open pull requests in a repository
+// that match the given filters. If params.state is set, it returns the
+// pull requests in that state instead.
+func listOpenPullRequests(ctx context.Context, client *http.Client, params listPullRequestsParams) ([]gitHubPullRequest, error) {
+	if params.authToken == "" {
+		return nil, errors.New("list pull requests: missing authentication token")
+	}
+	if params.owner == "" || params.repo == "" {
+		return nil, errors.New("list pull requests: missing repository owner or name")
+	}
+
+	query := url.Values{
+		"state":    {"open"},
+		"per_page": {strconv.Itoa(listPullRequestsPerPage)},
+	}
+	if params.state != "" {
+		query.Set("state", params.state)
+	}
+	if params.head != "" {
+		query.Set("head", params.head)
+	}
+	if params.base != "" {
+		query.Set("base", params.base)
+	}
+	var prs []gitHubPullRequest
+	for page := 1; ; page++ {
+		query.Set("page", strconv.Itoa(page))
+		pagePRs, err := listPullRequestsPage(ctx, client, params, query)
+		if err != nil {
+			return nil, fmt.Errorf("list pull requests for %s/%s: %w", params.owner, params.repo, err)
+		}
+		prs = append(prs, pagePRs...)
+		if len(pagePRs) < listPullRequestsPerPage {
+			return prs, nil
+		}
+	}
+}
+
+// listPullRequestsPage fetches a single page of GitHub's pull request
+// listing.
+func listPullRequestsPage(ctx context.Context, client *http.Client, params listPullRequestsParams, query url.Values) ([]gitHubPullRequest, error) {
+	apiURL := gitHubAPIURL(params.apiRoot, fmt.Sprintf("repos/%s/%s/pulls?%s",
+		url.PathEscape(params.owner), url.PathEscape(params.repo), query.Encode()))
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, apiURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", userAgentString())
+	req.Header.Set("Accept", "application/vnd.github.v3+json")
+	req.Header.Set("Authorization", "token "+params.authToken)
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, parseGitHubErrorResponse(resp)
+	}
+	var respDoc []gitHubPullRequest
+	if err := json.NewDecoder(resp.Body).Decode(&respDoc); err != nil {
+		return nil, fmt.Errorf("parsing response: %w", err)
+	}
+	return respDoc, nil
+}
+
+type findPullRequestParams struct {
+	authToken string
+	apiRoot   string // empty for github.com
+
+	owner string
+	repo  string
+
+	headOwner  string
+	headBranch string
+}
+
+// findPullRequest returns the open pull request for the given head branch.
+func findPullRequest(ctx context.Context, client *http.Client, params findPullRequestParams) (*gitHubPullRequest, error) {
+	if params.headOwner == "" || params.headBranch == "" {
+		return nil, errors.New("find pull request: missing head branch or owner")
+	}
+	prs, err := listOpenPullRequests(ctx, client, listPullRequestsParams{
+		authToken: params.authToken,
+		apiRoot:   params.apiRoot,
+		owner:     params.owner,
+		repo:      params.repo,
+		head:      params.headOwner + ":" + params.headBranch,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("find pull request for %s:%s: %w", params.headOwner, params.headBranch, err)
+	}
+	if len(prs) == 0 {
+		return nil, fmt.Errorf("find pull request for %s:%s: no open pull request in %s/%s", params.headOwner, params.headBranch, params.owner, params.repo)
+	}
+	return &prs[0], nil
+}
+
+type updatePullRequestParams struct {
+	authToken string
+	apiRoot   string // empty for github.com
+
+	owner string
+	repo  string
+	prNum uint64
+
+	title string
+	body  string
+	// base is the branch that the pull request merges into.
+	// If empty, the base is not changed.
+	base string
+}
+
+// updatePullRequest changes the title, body, and optionally the base
+// branch of an existing pull request.
+func updatePullRequest(ctx context.Context, client *http.Client, params updatePullRequestParams) (prURL string, _ error) {
+	if params.authToken == "" {
+		return "", errors.New("update pull request: missing authentication token")
+	}
+	if params.owner == "" || params.repo == "" {
+		return "", errors.New("update pull request: missing repository owner or name")
+	}
+	if params.title == "" {
+		return "", errors.New("update pull request: missing title")
+	}
+
+	reqBody := map[string]interface{}{
+		"title": params.title,
+		"body":  params.body,
+	}
+	if params.base != "" {
+		reqBody["base"] = params.base
+	}
+	reqBodyJSON, err := json.Marshal(reqBody)
+	if err != nil {
+		return "", fmt.Errorf("update pull request %s/%s/pulls/%d: %w", params.owner, params.repo, params.prNum, err)
+	}
+	apiURL := gitHubAPIURL(params.apiRoot, fmt.Sprintf("repos/%s/%s/pulls/%d",
+		url.PathEscape(params.owner), url.PathEscape(params.repo), params.prNum))
+	req, err := http.NewRequestWithContext(ctx, http.MethodPatch, apiURL, bytes.NewReader(reqBodyJSON))
+	if err != nil {
+		return "", fmt.Errorf("update pull request %s/%s/pulls/%d: %w", params.owner, params.repo, params.prNum, err)
+	}
+	req.Header.Set("User-Agent", userAgentString())
+	req.Header.Set("Accept", "application/vnd.github.v3+json")
+	req.Header.Set("Authorization", "token "+params.authToken)
+	req.Header.Set("Content-Type", "application/json; charset=utf-8")
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("update pull request %s/%s/pulls/%d: %w", params.owner, params.repo, params.prNum, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		err := parseGitHubErrorResponse(resp)
+		return "", fmt.Errorf("update pull request %s/%s/pulls/%d: %w", params.owner, params.repo, params.prNum, err)
+	}
+	var respDoc struct {
+		HTMLURL string `json:"html_url"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&respDoc); err != nil {
+		return "", fmt.Errorf("update pull request %s/%s/pulls/%d: parsing response: %w", params.owner, params.repo, params.prNum, err)
+	}
+	return respDoc.HTMLURL, nil
+}
+
+// requestPullReady marks the open draft pull request for the given branch
+// (or the current branch if branchArg is empty) as ready for review.
+func requestPullReady(ctx context.Context, cc *cmdContext, branchArg string) error {
+	cfg, err := cc.git.ReadConfig(ctx)
+	if err != nil {
+		return err
+	}
+	target, err := findPullRequestTarget(ctx, cc, cfg, branchArg, "")
+	if err != nil {
+		return err
+	}
+	gh, ok := target.forge.(gitHubForge)
+	if !ok {
+		return fmt.Errorf("requestpull --ready does not support %v repositories", target.forge)
+	}
+	token, err := gh.readToken(ctx, cc)
+	if err != nil {
+		return err
+	}
+	pr, err := findPullRequest(ctx, cc.httpClient, findPullRequestParams{
+		authToken:  string(token),
+		apiRoot:    gh.apiRoot,
+		owner:      target.baseOwner,
+		repo:       target.baseRepo,
+		headOwner:  target.headOwner,
+		headBranch: target.branch,
+	})
+	if err != nil {
+		return err
+	}
+	if !pr.Draft {
+		_, err := fmt.Fprintf(cc.stdout, "Pull request %s is already ready for review\n", pr.HTMLURL)
+		return err
+	}
+	err = markPullRequestReady(ctx, cc.httpClient, markPullRequestReadyParams{
+		authToken: string(token),
+		apiRoot:   gh.apiRoot,
+		nodeID:    pr.NodeID,
+	})
+	if err != nil {
+		return fmt.Errorf("mark %s ready for review: %w", pr.HTMLURL, err)
+	}
+	_, err = fmt.Fprintf(cc.stdout, "Marked pull request %s as ready for review\n", pr.HTMLURL)
+	return err
+}
+
+type markPullRequestReadyParams struct {
+	authToken string
+	apiRoot   string // empty for github.com
+
+	// nodeID is the pull request's GraphQL node ID.
+	nodeID string
+}
+
+// markPullRequestReady converts a draft pull request into one that is
+// ready for review. The REST API does not support this operation,
+// so it uses the GraphQL API.
+func markPullRequestReady(ctx context.Context, client *http.Client, params markPullRequestReadyParams) error {
+	if params.authToken == "" {
+		return errors.New("missing authentication token")
+	}
+	if params.nodeID == "" {
+		return errors.New("missing pull request node ID")
+	}
+	reqBodyJSON, err := json.Marshal(map[string]interface{}{
+		"query": "mutation($id: ID!) { markPullRequestReadyForReview(input: {pullRequestId: $id}) { pullRequest { isDraft } } }",
+		"variables": map[string]interface{}{
+			"id": params.nodeID,
+		},
+	})
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, gitHubGraphQLURL(params.apiRoot), bytes.NewReader(reqBodyJSON))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("User-Agent", userAgentString())
+	req.Header.Set("Authorization", "bearer "+params.authToken)
+	req.Header.Set("Content-Type", "application/json; charset=utf-8")
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return parseGitHubErrorResponse(resp)
+	}
+	// GraphQL reports errors in the response body with a 200 status.
+	var respDoc struct {
+		Errors []struct {
+			Message string
+		}
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&respDoc); err != nil {
+		return fmt.Errorf("parsing response: %w", err)
+	}
+	if len(respDoc.Errors) > 0 {
+		return fmt.Errorf("GitHub GraphQL API: %s", respDoc.Errors[0].Message)
+	}
+	return nil
+}
@@ -347,6 +347,108 @@ func TestRequestPull_BodyWithoutTitleUsageError(t *testing.T) {
 	}
 }
 
+func TestRequestPullSync(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	env, err := newTestEnv(ctx, t)
+	if err != nil {
+		t.Fatal(err)
+	}
+	const authToken = "xyzzy12345"
+	if err := env.writeGitHubAuth([]byte(authToken + "\n")); err != nil {
+		t.Fatal(err)
+	}
+	if err := env.initRepoWithHistory(ctx, "origin"); err != nil {
+		t.Fatal(err)
+	}
+	if err := env.git.Run(ctx, "clone", "--quiet", "origin", "local"); err != nil {
+		t.Fatal(err)
+	}
+	localDir := env.root.FromSlash("local")
+	localGit := env.git.WithDir(localDir)
+	if err := localGit.Run(ctx, "remote", "set-url", "origin", "https://github.com/example/foo.git"); err != nil {
+		t.Fatal(err)
+	}
+	err = localGit.NewBranch(ctx, "feature", git.BranchOptions{
+		StartPoint: "origin/main",
+		Track:      true,
+		Checkout:   true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The pull request is based on a remote "develop" branch that already
+	// has the branch's first commit, so only the second commit is new.
+	if err := env.root.Apply(filesystem.Write("local/base.txt", dummyContent)); err != nil {
+		t.Fatal(err)
+	}
+	if err := env.addFiles(ctx, "local/base.txt"); err != nil {
+		t.Fatal(err)
+	}
+	if err := localGit.Commit(ctx, "Work on develop", git.CommitOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := localGit.Run(ctx, "update-ref", "refs/remotes/origin/develop", "HEAD"); err != nil {
+		t.Fatal(err)
+	}
+	if err := env.root.Apply(filesystem.Write("local/blah.txt", dummyContent)); err != nil {
+		t.Fatal(err)
+	}
+	if err := env.addFiles(ctx, "local/blah.txt"); err != nil {
+		t.Fatal(err)
+	}
+	const wantTitle = "Reworded title"
+	const wantBody = "New explanation."
+	if err := localGit.Commit(ctx, wantTitle+"\n\n"+wantBody, git.CommitOptions{}); err != nil {
+		t.Fatal(err)
+	}
+
+	api := &fakeGitHubPullRequestAPI{
+		logger:         t,
+		errorer:        t,
+		permittedToken: authToken,
+		prs: []fakePullRequest{
+			{
+				id:        12345,
+				num:       1,
+				owner:     "example",
+				repo:      "foo",
+				baseRef:   "develop",
+				headOwner: "example",
+				headRef:   "feature",
+				title:     "Original title",
+				body:      "Original body.",
+			},
+		},
+	}
+	fakeGitHub := httptest.NewServer(api)
+	defer fakeGitHub.Close()
+	fakeGitHubTransport := &http.Transport{
+		DialTLS: func(network, addr string) (net.Conn, error) {
+			hostport := strings.TrimPrefix(fakeGitHub.URL, "http://")
+			return net.Dial("tcp", hostport)
+		},
+	}
+	defer fakeGitHubTransport.CloseIdleConnections()
+	env.roundTripper = fakeGitHubTransport
+
+	if _, err := env.gg(ctx, localDir, "requestpull", "--sync", "-e=0"); err != nil {
+		t.Error(err)
+	}
+	api.mu.Lock()
+	prs := api.prs
+	api.mu.Unlock()
+	if len(prs) != 1 {
+		t.Fatalf("Have %d PRs; want 1", len(prs))
+	}
+	if prs[0].title != wantTitle {
+		t.Errorf("title = %q; want %q", prs[0].title, wantTitle)
+	}
+	if prs[0].body != wantBody {
+		t.Errorf("body = %q; want %q", prs[0].body, wantBody)
+	}
+}
+
 func TestRequestPull_Editor(t *testing.T) {
 	t.Parallel()
 	ctx := context.Background()
@@ -516,6 +618,208 @@ func TestRequestPull_Editor(t *testing.T) {
 	}
 }
 
+func TestRequestPull_GitHubEnterprise(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	env, err := newTestEnv(ctx, t)
+	if err != nil {
+		t.Fatal(err)
+	}
+	config := "[gg \"github.ghe.example.com\"]\n" +
+		"\tapiurl = https://ghe.example.com/api/v3\n"
+	if err := env.writeConfig([]byte(config)); err != nil {
+		t.Fatal(err)
+	}
+	// Write a github.com token to verify that it is not used.
+	if err := env.writeGitHubAuth([]byte("wrongtoken\n")); err != nil {
+		t.Fatal(err)
+	}
+	const authToken = "xyzzy12345"
+	if err := env.topDir.Apply(filesystem.Write("xdgconfig/gg/github_token.ghe.example.com", authToken+"\n")); err != nil {
+		t.Fatal(err)
+	}
+	api := &fakeGitHubPullRequestAPI{
+		logger:         t,
+		errorer:        t,
+		permittedToken: authToken,
+		enterpriseHost: "ghe.example.com",
+	}
+	fakeGitHub := httptest.NewServer(api)
+	t.Cleanup(fakeGitHub.Close)
+	fakeGitHubTransport := &http.Transport{
+		DialTLS: func(network, addr string) (net.Conn, error) {
+			hostport := strings.TrimPrefix(fakeGitHub.URL, "http://")
+			return net.Dial("tcp", hostport)
+		},
+	}
+	t.Cleanup(fakeGitHubTransport.CloseIdleConnections)
+	env.roundTripper = fakeGitHubTransport
+
+	if err := env.initRepoWithHistory(ctx, "origin"); err != nil {
+		t.Fatal(err)
+	}
+	if err := env.git.Run(ctx, "clone", "--quiet", "origin", "local"); err != nil {
+		t.Fatal(err)
+	}
+	localDir := env.root.FromSlash("local")
+	localGit := env.git.WithDir(localDir)
+	err = localGit.NewBranch(ctx, "feature", git.BranchOptions{
+		StartPoint: "origin/main",
+		Track:      true,
+		Checkout:   true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := localGit.Run(ctx, "remote", "set-url", "origin", "git@ghe.example.com:example/foo.git"); err != nil {
+		t.Fatal(err)
+	}
+	if err := env.root.Apply(filesystem.Write("local/blah.txt", dummyContent)); err != nil {
+		t.Fatal(err)
+	}
+	if err := env.addFiles(ctx, "local/blah.txt"); err != nil {
+		t.Fatal(err)
+	}
+	if err := localGit.Commit(ctx, "Commit title", git.CommitOptions{}); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := env.gg(ctx, localDir, "requestpull", "--edit=0"); err != nil {
+		t.Fatal(err)
+	}
+	api.mu.Lock()
+	prs := api.prs
+	api.mu.Unlock()
+	if len(prs) != 1 {
+		t.Fatalf("created %d pull requests; want 1", len(prs))
+	}
+	if prs[0].owner != "example" || prs[0].repo != "foo" {
+		t.Errorf("Opened on %s/%s; want example/foo", prs[0].owner, prs[0].repo)
+	}
+	if got, want := prs[0].headRef, "feature"; got != want {
+		t.Errorf("Head ref = %q; want %q", got, want)
+	}
+}
+
+func TestRequestPull_Ready(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	env, api, err := setupLandTest(ctx, t, "success")
+	if err != nil {
+		t.Fatal(err)
+	}
+	api.mu.Lock()
+	api.prs[0].draft = true
+	api.mu.Unlock()
+
+	localDir := env.root.FromSlash("local")
+	out, err := env.gg(ctx, localDir, "requestpull", "--ready")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(out), "ready for review") {
+		t.Errorf("output = %q; want to mention \"ready for review\"", out)
+	}
+	api.mu.Lock()
+	draft := api.prs[0].draft
+	api.mu.Unlock()
+	if draft {
+		t.Error("pull request is still a draft")
+	}
+
+	// Running again on a ready pull request is not an error.
+	if _, err := env.gg(ctx, localDir, "requestpull", "--ready"); err != nil {
+		t.Error(err)
+	}
+	if _, err := env.gg(ctx, localDir, "requestpull", "--ready", "--draft"); err == nil || !isUsage(err) {
+		t.Errorf("gg requestpull --ready --draft = %v; want usage error", err)
+	}
+}
+
+func TestRequestPull_Status(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	env, api, err := setupLandTest(ctx, t, "failure")
+	if err != nil {
+		t.Fatal(err)
+	}
+	api.mu.Lock()
+	api.prs[0].reviewers = []string{"carol"}
+	api.prs[0].reviews = []fakeReview{
+		{user: "alice", state: "CHANGES_REQUESTED"},
+		{user: "alice", state: "APPROVED"},
+		{user: "bob", state: "COMMENTED"},
+	}
+	api.mu.Unlock()
+
+	out, err := env.gg(ctx, env.root.FromSlash("local"), "requestpull", "--status")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"https://github.com/example/foo/pull/1\n",
+		"Mergeable: yes\n",
+		"  alice approved\n",
+		"  bob commented\n",
+		"  carol pending\n",
+		"  ci failure\n",
+	} {
+		if !strings.Contains(string(out), want) {
+			t.Errorf("output does not contain %q. Output:\n%s", want, out)
+		}
+	}
+}
+
+func TestInferStackBase(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	env, err := newTestEnv(ctx, t)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := env.initRepoWithHistory(ctx, "origin"); err != nil {
+		t.Fatal(err)
+	}
+	if err := env.git.Run(ctx, "clone", "--quiet", "origin", "local"); err != nil {
+		t.Fatal(err)
+	}
+	localDir := env.root.FromSlash("local")
+	localGit := env.git.WithDir(localDir)
+	for _, b := range []string{"a", "b"} {
+		if _, err := env.gg(ctx, localDir, "branch", b); err != nil {
+			t.Fatal(err)
+		}
+		if err := env.root.Apply(filesystem.Write("local/"+b+".txt", dummyContent)); err != nil {
+			t.Fatal(err)
+		}
+		if err := env.addFiles(ctx, "local/"+b+".txt"); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := env.newCommit(ctx, "local"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	cfg, err := localGit.ReadConfig(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		branch string
+		want   string
+	}{
+		{branch: "main", want: ""},
+		{branch: "a", want: ""},
+		{branch: "b", want: "a"},
+	}
+	for _, test := range tests {
+		got, err := inferStackBase(ctx, localGit, cfg, test.branch, "origin")
+		if got != test.want || err != nil {
+			t.Errorf("inferStackBase(ctx, localGit, cfg, %q, \"origin\") = %q, %v; want %q, <nil>", test.branch, got, err, test.want)
+		}
+	}
+}
+
 func TestInferUpstream(t *testing.T) {
 	t.Parallel()
 	tests := []struct {
@@ -570,13 +874,23 @@ type fakePullRequest struct {
 	baseRef   string
 	headOwner string
 	headRef   string
+	headSHA   string
 
 	title     string
 	body      string
 	reviewers []string
+	reviews   []fakeReview
 
 	draft               bool
 	maintainerCanModify bool
+
+	merged      bool
+	mergeMethod string
+}
+
+type fakeReview struct {
+	user  string
+	state string
 }
 
 type fakeGitHubPullRequestAPI struct {
@@ -586,20 +900,39 @@ type fakeGitHubPullRequestAPI struct {
 
 	mu  sync.Mutex
 	prs []fakePullRequest
+
+	// commitStatuses maps commit hashes to the state of a "ci" status.
+	commitStatuses map[string]string
+	// checkRuns maps commit hashes to the conclusions of its completed
+	// check runs, which are served in pages like the real API.
+	checkRuns map[string][]string
+	// onMerge is called when a pull request is merged, if not nil.
+	onMerge func(pr fakePullRequest) error
+	// enterpriseHost is the host name of a GitHub Enterprise Server instance
+	// whose API is served at /api/v3 instead of api.github.com, if not empty.
+	enterpriseHost string
 }
 
 func (api *fakeGitHubPullRequestAPI) ServeHTTP(w http.ResponseWriter, r *http.Request) {
-	if r.Host == "api.github.com" {
-		if got, want := r.Header.Get("Authorization"), "token "+api.permittedToken; got != want {
+	apiHost, urlPath := "api.github.com", r.URL.Path
+	if api.enterpriseHost != "" {
+		apiHost, urlPath = api.enterpriseHost, strings.TrimPrefix(urlPath, "/api/v3")
+	}
+	if r.Host == apiHost {
+		if got, want := r.Header.Get("Authorization"), "token "+api.permittedToken; got != want && got != "bearer "+api.permittedToken {
 			api.errorer.Errorf("Authorization header = %q; want %q", got, want)
 			w.Header().Set("Content-Type", "application/json; charset=utf-8")
 			http.Error(w, `{"message":"Bad auth token"}`, http.StatusUnauthorized)
 			return
 		}
+		if r.Method == "POST" && (urlPath == "/graphql" || urlPath == "/api/graphql") {
+			api.graphQL(w, r)
+			return
+		}
 		if got, want := r.Header.Get("Accept"), "application/vnd.github.v3+json"; got != want && got != draftPRAPIAccept {
 			api.errorer.Errorf("Accept header = %q; want %q or %q", got, want, draftPRAPIAccept)
 		}
-		pathParts := strings.Split(strings.TrimPrefix(path.Clean(r.URL.Path), "/"), "/")
+		pathParts := strings.Split(strings.TrimPrefix(path.Clean(urlPath), "/"), "/")
 		switch {
 		case r.Method == "POST" && len(pathParts) == 4 && pathParts[0] == "repos" && pathParts[3] == "pulls":
 			api.createPullRequest(w, r, pathParts)
@@ -607,6 +940,27 @@ func (api *fakeGitHubPullRequestAPI) ServeHTTP(w http.ResponseWriter, r *http.Re
 		case r.Method == "POST" && len(pathParts) == 6 && pathParts[0] == "repos" && pathParts[3] == "pulls" && pathParts[5] == "requested_reviewers":
 			api.createReviewRequest(w, r, pathParts)
 			return
+		case r.Method == "GET" && len(pathParts) == 4 && pathParts[0] == "repos" && pathParts[3] == "pulls":
+			api.listPullRequests(w, r, pathParts)
+			return
+		case r.Method == "GET" && len(pathParts) == 5 && pathParts[0] == "repos" && pathParts[3] == "pulls":
+			api.getPullRequest(w, r, pathParts)
+			return
+		case r.Method == "GET" && len(pathParts) == 6 && pathParts[0] == "repos" && pathParts[3] == "pulls" && pathParts[5] == "reviews":
+			api.listReviews(w, r, pathParts)
+			return
+		case r.Method == "PATCH" && len(pathParts) == 5 && pathParts[0] == "repos" && pathParts[3] == "pulls":
+			api.updatePullRequest(w, r, pathParts)
+			return
+		case r.Method == "PUT" && len(pathParts) == 6 && pathParts[0] == "repos" && pathParts[3] == "pulls" && pathParts[5] == "merge":
+			api.mergePullRequest(w, r, pathParts)
+			return
+		case r.Method == "GET" && len(pathParts) == 6 && pathParts[0] == "repos" && pathParts[3] == "commits" && pathParts[5] == "status":
+			api.getCommitStatus(w, r, pathParts)
+			return
+		case r.Method == "GET" && len(pathParts) == 6 && pathParts[0] == "repos" && pathParts[3] == "commits" && pathParts[5] == "check-runs":
+			api.listCheckRuns(w, r, pathParts)
+			return
 		}
 	}
 	api.logger.Logf("%s received unhandled API request %s %s", r.Host, r.Method, r.URL.Path)
@@ -669,7 +1023,8 @@ func (api *fakeGitHubPullRequestAPI) createPullRequest(w http.ResponseWriter, r
 		"title":    title,
 		"body":     body,
 		"head": map[string]interface{}{
-			"ref": headRef,
+			"ref":   headRef,
+			"label": headOwner + ":" + headRef,
 			"user": map[string]interface{}{
 				"login": headOwner,
 			},
@@ -750,6 +1105,356 @@ func (api *fakeGitHubPullRequestAPI) createReviewRequest(w http.ResponseWriter,
 	}
 }
 
+func (api *fakeGitHubPullRequestAPI) listPullRequests(w http.ResponseWriter, r *http.Request, pathParts []string) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	owner := pathParts[1]
+	repo := pathParts[2]
+	if state := r.URL.Query().Get("state"); state != "open" {
+		api.errorer.Errorf("state = %q; want \"open\"", state)
+	}
+	head := r.URL.Query().Get("head")
+	base := r.URL.Query().Get("base")
+	list := []map[string]interface{}{}
+	api.mu.Lock()
+	for _, pr := range api.prs {
+		if pr.owner == owner && pr.repo == repo && !pr.merged &&
+			(head == "" || pr.headOwner+":"+pr.headRef == head) &&
+			(base == "" || pr.baseRef == base) {
+			list = append(list, map[string]interface{}{
+				"id":       pr.id,
+				"node_id":  fmt.Sprintf("PR_%d", pr.id),
+				"number":   pr.num,
+				"url":      fmt.Sprintf("https://api.github.com/repos/%s/%s/pulls/%d", owner, repo, pr.num),
+				"html_url": fmt.Sprintf("https://github.com/%s/%s/pull/%d", owner, repo, pr.num),
+				"state":    "open",
+				"draft":    pr.draft,
+				"title":    pr.title,
+				"body":     pr.body,
+				"head": map[string]interface{}{
+					"ref":   pr.headRef,
+					"sha":   pr.headSHA,
+					"label": pr.headOwner + ":" + pr.headRef,
+				},
+				"base": map[string]interface{}{
+					"ref": pr.baseRef,
+				},
+			})
+		}
+	}
+	api.mu.Unlock()
+
+	response, err := json.Marshal(list)
+	if err != nil {
+		api.errorer.Errorf("Failed to marshal API response: %v", err)
+		http.Error(w, `{"message":"Server errror"}`, http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Length", fmt.Sprint(len(response)))
+	w.WriteHeader(http.StatusOK)
+	if _, err := w.Write(response); err != nil {
+		api.errorer.Errorf("Writing response: %v", err)
+	}
+}
+
+// findPullRequest returns a copy of the pull request named by the
+// repository and number in the API path.
+func (api *fakeGitHubPullRequestAPI) findPullRequest(pathParts []string) (fakePullRequest, bool) {
+	num, err := strconv.Atoi(pathParts[4])
+	if err != nil {
+		return fakePullRequest{}, false
+	}
+	api.mu.Lock()
+	defer api.mu.Unlock()
+	for _, pr := range api.prs {
+		if pr.owner == pathParts[1] && pr.repo == pathParts[2] && pr.num == num {
+			return pr, true
+		}
+	}
+	return fakePullRequest{}, false
+}
+
+func (api *fakeGitHubPullRequestAPI) getPullRequest(w http.ResponseWriter, r *http.Request, pathParts []string) {
+	pr, ok := api.findPullRequest(pathParts)
+	if !ok {
+		api.writeJSON(w, http.StatusNotFound, map[string]interface{}{"message": "Not found"})
+		return
+	}
+	requested := []interface{}{}
+	for _, user := range pr.reviewers {
+		requested = append(requested, map[string]interface{}{"login": user})
+	}
+	api.writeJSON(w, http.StatusOK, map[string]interface{}{
+		"id":                  pr.id,
+		"number":              pr.num,
+		"html_url":            fmt.Sprintf("https://github.com/%s/%s/pull/%d", pr.owner, pr.repo, pr.num),
+		"state":               "open",
+		"draft":               pr.draft,
+		"title":               pr.title,
+		"mergeable":           true,
+		"mergeable_state":     "clean",
+		"requested_reviewers": requested,
+		"head": map[string]interface{}{
+			"ref":   pr.headRef,
+			"sha":   pr.headSHA,
+			"label": pr.headOwner + ":" + pr.headRef,
+		},
+		"base": map[string]interface{}{
+			"ref": pr.baseRef,
+		},
+	})
+}
+
+func (api *fakeGitHubPullRequestAPI) listReviews(w http.ResponseWriter, r *http.Request, pathParts []string) {
+	pr, ok := api.findPullRequest(pathParts)
+	if !ok {
+		api.writeJSON(w, http.StatusNotFound, map[string]interface{}{"message": "Not found"})
+		return
+	}
+	list := []interface{}{}
+	for _, review := range pr.reviews {
+		list = append(list, map[string]interface{}{
+			"user":  map[string]interface{}{"login": review.user},
+			"state": review.state,
+		})
+	}
+	api.writeJSON(w, http.StatusOK, list)
+}
+
+func (api *fakeGitHubPullRequestAPI) updatePullRequest(w http.ResponseWriter, r *http.Request, pathParts []string) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	if got, want := r.Header.Get("Content-Type"), "application/json"; parseContentType(got) != want {
+		api.errorer.Errorf("Content-Type header = %q; want %q", got, want)
+	}
+	var body map[string]interface{} // Struct field matches are always case-insensitive.
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		api.errorer.Errorf("Decode body: %v", err)
+		http.Error(w, `{"message":"Could not parse body"}`, http.StatusBadRequest)
+		return
+	}
+	owner := pathParts[1]
+	repo := pathParts[2]
+	pathNum := pathParts[4]
+	num, err := strconv.ParseUint(pathNum, 10, 64)
+	if err != nil {
+		api.errorer.Errorf("PR # = %q; error: %v", pathNum, err)
+		http.Error(w, `{"message":"Invalid pull request #"}`, http.StatusNotFound)
+		return
+	}
+	found := false
+	api.mu.Lock()
+	for i := range api.prs {
+		pr := &api.prs[i]
+		if pr.owner == owner && pr.repo == repo && uint64(pr.num) == num {
+			if title, ok := body["title"]; ok {
+				pr.title = jsonString(title)
+			}
+			if prBody, ok := body["body"]; ok {
+				pr.body = jsonString(prBody)
+			}
+			found = true
+			break
+		}
+	}
+	api.mu.Unlock()
+	if !found {
+		http.Error(w, `{"message":"Not found"}`, http.StatusNotFound)
+		return
+	}
+
+	response, err := json.Marshal(map[string]interface{}{
+		"number":   num,
+		"url":      fmt.Sprintf("https://api.github.com/repos/%s/%s/pulls/%d", owner, repo, num),
+		"html_url": fmt.Sprintf("https://github.com/%s/%s/pull/%d", owner, repo, num),
+		"state":    "open",
+	})
+	if err != nil {
+		api.errorer.Errorf("Failed to marshal API response: %v", err)
+		http.Error(w, `{"message":"Server errror"}`, http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Length", fmt.Sprint(len(response)))
+	w.WriteHeader(http.StatusOK)
+	if _, err := w.Write(response); err != nil {
+		api.errorer.Errorf("Writing response: %v", err)
+	}
+}
+
+func (api *fakeGitHubPullRequestAPI) mergePullRequest(w http.ResponseWriter, r *http.Request, pathParts []string) {
+	var body map[string]interface{} // Struct field matches are always case-insensitive.
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		api.errorer.Errorf("Decode body: %v", err)
+		api.writeJSON(w, http.StatusBadRequest, map[string]interface{}{"message": "Could not parse body"})
+		return
+	}
+	owner := pathParts[1]
+	repo := pathParts[2]
+	pathNum := pathParts[4]
+	num, err := strconv.ParseUint(pathNum, 10, 64)
+	if err != nil {
+		api.errorer.Errorf("PR # = %q; error: %v", pathNum, err)
+		api.writeJSON(w, http.StatusNotFound, map[string]interface{}{"message": "Invalid pull request #"})
+		return
+	}
+	api.mu.Lock()
+	var pr *fakePullRequest
+	for i := range api.prs {
+		if p := &api.prs[i]; p.owner == owner && p.repo == repo && uint64(p.num) == num {
+			pr = p
+			break
+		}
+	}
+	if pr == nil || pr.merged {
+		api.mu.Unlock()
+		api.writeJSON(w, http.StatusNotFound, map[string]interface{}{"message": "Not found"})
+		return
+	}
+	if sha := jsonString(body["sha"]); sha != "" && sha != pr.headSHA {
+		api.mu.Unlock()
+		api.writeJSON(w, http.StatusConflict, map[string]interface{}{"message": "Head branch was modified"})
+		return
+	}
+	pr.merged = true
+	pr.mergeMethod = jsonString(body["merge_method"])
+	merged := *pr
+	api.mu.Unlock()
+	if api.onMerge != nil {
+		if err := api.onMerge(merged); err != nil {
+			api.errorer.Errorf("Merging PR #%d: %v", num, err)
+			api.writeJSON(w, http.StatusInternalServerError, map[string]interface{}{"message": "Server error"})
+			return
+		}
+	}
+	api.writeJSON(w, http.StatusOK, map[string]interface{}{
+		"merged":  true,
+		"message": "Pull Request successfully merged",
+	})
+}
+
+func (api *fakeGitHubPullRequestAPI) listCheckRuns(w http.ResponseWriter, r *http.Request, pathParts []string) {
+	api.mu.Lock()
+	conclusions := append([]string(nil), api.checkRuns[pathParts[4]]...)
+	api.mu.Unlock()
+	perPage := 30
+	if n, err := strconv.Atoi(r.URL.Query().Get("per_page")); err == nil && 0 < n && n <= 100 {
+		perPage = n
+	}
+	page := 1
+	if n, err := strconv.Atoi(r.URL.Query().Get("page")); err == nil && n > 0 {
+		page = n
+	}
+	start := (page - 1) * perPage
+	if start > len(conclusions) {
+		start = len(conclusions)
+	}
+	end := start + perPage
+	if end > len(conclusions) {
+		end = len(conclusions)
+	}
+	runs := []interface{}{}
+	for i, conclusion := range conclusions[start:end] {
+		runs = append(runs, map[string]interface{}{
+			"name":       fmt.Sprintf("check%d", start+i),
+			"status":     "completed",
+			"conclusion": conclusion,
+		})
+	}
+	if end < len(conclusions) {
+		next := *r.URL
+		q := next.Query()
+		q.Set("page", strconv.Itoa(page+1))
+		next.RawQuery = q.Encode()
+		w.Header().Set("Link", fmt.Sprintf(`<https://%s%s>; rel="next"`, r.Host, next.RequestURI()))
+	}
+	api.writeJSON(w, http.StatusOK, map[string]interface{}{
+		"total_count": len(conclusions),
+		"check_runs":  runs,
+	})
+}
+
+func (api *fakeGitHubPullRequestAPI) getCommitStatus(w http.ResponseWriter, r *http.Request, pathParts []string) {
+	api.mu.Lock()
+	state := api.commitStatuses[pathParts[4]]
+	api.mu.Unlock()
+	if state == "" {
+		api.writeJSON(w, http.StatusOK, map[string]interface{}{
+			"state":       "pending",
+			"total_count": 0,
+			"statuses":    []interface{}{},
+		})
+		return
+	}
+	api.writeJSON(w, http.StatusOK, map[string]interface{}{
+		"state":       state,
+		"total_count": 1,
+		"statuses": []interface{}{
+			map[string]interface{}{
+				"state":   state,
+				"context": "ci",
+			},
+		},
+	})
+}
+
+// graphQL implements the markPullRequestReadyForReview mutation
+// of the GitHub GraphQL API.
+func (api *fakeGitHubPullRequestAPI) graphQL(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		Query     string
+		Variables map[string]interface{}
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		api.errorer.Errorf("Decode body: %v", err)
+		api.writeJSON(w, http.StatusBadRequest, map[string]string{"message": "Problems parsing JSON"})
+		return
+	}
+	if !strings.Contains(body.Query, "markPullRequestReadyForReview") {
+		api.errorer.Errorf("unhandled GraphQL query %q", body.Query)
+		api.writeJSON(w, http.StatusOK, map[string]interface{}{
+			"errors": []interface{}{map[string]string{"message": "unhandled query"}},
+		})
+		return
+	}
+	id := jsonString(body.Variables["id"])
+	api.mu.Lock()
+	found := false
+	for i := range api.prs {
+		if fmt.Sprintf("PR_%d", api.prs[i].id) == id {
+			api.prs[i].draft = false
+			found = true
+			break
+		}
+	}
+	api.mu.Unlock()
+	if !found {
+		api.writeJSON(w, http.StatusOK, map[string]interface{}{
+			"errors": []interface{}{map[string]string{"message": "Could not resolve to a node with the global id of '" + id + "'"}},
+		})
+		return
+	}
+	api.writeJSON(w, http.StatusOK, map[string]interface{}{
+		"data": map[string]interface{}{
+			"markPullRequestReadyForReview": map[string]interface{}{
+				"pullRequest": map[string]interface{}{"isDraft": false},
+			},
+		},
+	})
+}
+
+func (api *fakeGitHubPullRequestAPI) writeJSON(w http.ResponseWriter, statusCode int, v interface{}) {
+	response, err := json.Marshal(v)
+	if err != nil {
+		api.errorer.Errorf("Failed to marshal API response: %v", err)
+		http.Error(w, `{"message":"Server errror"}`, http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.Header().Set("Content-Length", fmt.Sprint(len(response)))
+	w.WriteHeader(statusCode)
+	if _, err := w.Write(response); err != nil {
+		api.errorer.Errorf("Writing response: %v", err)
+	}
+}
+
 func parseContentType(s string) string {
 	t, _, err := mime.ParseMediaType(s)
 	if err != nil {
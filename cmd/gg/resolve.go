@@ -0,0 +1,338 @@
+// Copyright 2021 The gg Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"gg-scm.io/pkg/git"
+	"gg-scm.io/tool/internal/flag"
+	"gg-scm.io/tool/internal/terminal"
+)
+
+const resolveSynopsis = "redo merges or mark files as resolved"
+
+func resolve(ctx context.Context, cc *cmdContext, args []string) error {
+	f := flag.NewFlagSet(true, "gg resolve [-t TOOL] {--all | FILE [...]}\n"+
+		"gg resolve -m {--all | FILE [...]}\n"+
+		"gg resolve -u FILE [...]\n"+
+		"gg resolve -l [FILE [...]]\n"+
+		"gg resolve --show FILE [...]", resolveSynopsis+`
+
+	Manage the files left with conflicts by a merge, rebase, graft, or
+	other operation. By default, resolve runs `+"`git mergetool`"+` on
+	the given conflicted files, which uses the merge tool configured by
+	the `+"`merge.tool`"+` setting unless `+"`-t`"+` is given.
+
+	`+"`-l`"+` lists the conflicted files and the kind of conflict in
+	each. `+"`-m`"+` marks files as resolved, like `+"`gg add`"+`.
+	`+"`-u`"+` marks files as unresolved by re-creating the conflicted
+	merge in them, which discards any resolution made in the file.
+
+	`+"`--show`"+` prints each of the given conflicted files as merged from
+	the versions in the index, with every conflict showing our version,
+	the common ancestor's version, and their version in diff3 style. The
+	working copy file is not changed.`)
+	list := f.Bool("l", false, "list conflicted files")
+	f.Alias("l", "list")
+	mark := f.Bool("m", false, "mark files as resolved")
+	f.Alias("m", "mark")
+	unmark := f.Bool("u", false, "mark files as unresolved")
+	f.Alias("u", "unmark")
+	show := f.Bool("show", false, "show the versions of conflicted files")
+	all := f.Bool("all", false, "select all conflicted files")
+	f.Alias("all", "a")
+	tool := f.String("t", "", "resolve conflicts with the given merge `tool`")
+	f.Alias("t", "tool")
+	if err := f.Parse(args); flag.IsHelp(err) {
+		f.Help(cc.stdout)
+		return nil
+	} else if err != nil {
+		return usagef("%v", err)
+	}
+	modes := 0
+	for _, b := range []bool{*list, *mark, *unmark, *show} {
+		if b {
+			modes++
+		}
+	}
+	if modes > 1 {
+		return usagef("can only pass one of -l, -m, -u, or --show")
+	}
+	if *tool != "" && modes > 0 {
+		return usagef("-t can't be used with -l, -m, -u, or --show")
+	}
+	if *all && f.NArg() > 0 {
+		return usagef("can't pass files with --all")
+	}
+	switch {
+	case *list:
+		if *all {
+			return usagef("--all can't be used with -l")
+		}
+		return listResolve(ctx, cc, f.Args())
+	case *unmark:
+		if *all || f.NArg() == 0 {
+			return usagef("must pass one or more files to unmark")
+		}
+		return unmarkResolve(ctx, cc, f.Args())
+	case *show:
+		if *all || f.NArg() == 0 {
+			return usagef("must pass one or more files to show")
+		}
+		return showConflicts(ctx, cc, f.Args())
+	}
+	if !*all && f.NArg() == 0 {
+		return usagef("must pass --all or one or more files")
+	}
+	conflicts, err := findConflicts(ctx, cc.git, f.Args())
+	if err != nil {
+		return err
+	}
+	if len(conflicts) == 0 {
+		return errors.New("no unresolved files")
+	}
+	pathspecs := make([]git.Pathspec, 0, len(conflicts))
+	for _, ent := range conflicts {
+		pathspecs = append(pathspecs, ent.Name.Pathspec())
+	}
+	if *mark {
+		return cc.git.Add(ctx, pathspecs, git.AddOptions{})
+	}
+	toolArgs := []string{"mergetool"}
+	if *tool != "" {
+		toolArgs = append(toolArgs, "--tool="+*tool)
+	}
+	toolArgs = append(toolArgs, "--")
+	for _, p := range pathspecs {
+		toolArgs = append(toolArgs, p.String())
+	}
+	return cc.interactiveGit(ctx, toolArgs...)
+}
+
+// findConflicts returns the unmerged entries in the working copy's status
+// that match the given files, or all of them if no files are given.
+func findConflicts(ctx context.Context, g *git.Git, files []string) ([]git.StatusEntry, error) {
+	var pathspecs []git.Pathspec
+	for _, f := range files {
+		pathspecs = append(pathspecs, git.LiteralPath(f))
+	}
+	st, err := g.Status(ctx, git.StatusOptions{
+		Pathspecs:      pathspecs,
+		DisableRenames: true,
+	})
+	if err != nil {
+		return nil, err
+	}
+	var conflicts []git.StatusEntry
+	for _, ent := range st {
+		if ent.Code.IsUnmerged() {
+			conflicts = append(conflicts, ent)
+		}
+	}
+	return conflicts, nil
+}
+
+// listResolve implements `gg resolve -l`.
+func listResolve(ctx context.Context, cc *cmdContext, files []string) error {
+	conflicts, err := findConflicts(ctx, cc.git, files)
+	if err != nil {
+		return err
+	}
+	for _, ent := range conflicts {
+		_, err := fmt.Fprintf(cc.stdout, "U %s (%s)\n", ent.Name, describeConflict(ent.Code))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// unmarkResolve implements `gg resolve -u`.
+func unmarkResolve(ctx context.Context, cc *cmdContext, files []string) error {
+	checkoutArgs := []string{"checkout", "--merge", "--"}
+	for _, f := range files {
+		checkoutArgs = append(checkoutArgs, git.LiteralPath(f).String())
+	}
+	return cc.git.Runner().RunGit(ctx, &git.Invocation{
+		Args:   checkoutArgs,
+		Dir:    cc.dir,
+		Stderr: cc.stderr,
+	})
+}
+
+// showConflicts implements `gg resolve --show`.
+func showConflicts(ctx context.Context, cc *cmdContext, files []string) error {
+	cfg, err := cc.git.ReadConfig(ctx)
+	if err != nil {
+		return err
+	}
+	var colors conflictColors
+	colorize, err := cfg.ColorBool("color.ggresolve", cc.isTerminal())
+	if err != nil {
+		fmt.Fprintln(cc.stderr, "gg:", err)
+	} else if colorize {
+		for _, c := range []struct {
+			dst  *[]byte
+			name string
+			def  string
+		}{
+			{&colors.marker, "marker", "yellow"},
+			{&colors.ours, "ours", "green"},
+			{&colors.base, "base", "cyan"},
+			{&colors.theirs, "theirs", "magenta"},
+		} {
+			*c.dst, err = cfg.Color("color.ggresolve."+c.name, c.def)
+			if err != nil {
+				fmt.Fprintln(cc.stderr, "gg:", err)
+			}
+		}
+	}
+	conflicts, err := findConflicts(ctx, cc.git, files)
+	if err != nil {
+		return err
+	}
+	if len(conflicts) == 0 {
+		return errors.New("no unresolved files")
+	}
+	for i, ent := range conflicts {
+		if i > 0 {
+			fmt.Fprintln(cc.stdout)
+		}
+		if err := showConflict(ctx, cc, ent, colorize, &colors); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// conflictColors is the set of colors used by `gg resolve --show`.
+type conflictColors struct {
+	marker []byte
+	ours   []byte
+	base   []byte
+	theirs []byte
+}
+
+// showConflict prints a single conflicted file in diff3 style.
+func showConflict(ctx context.Context, cc *cmdContext, ent git.StatusEntry, colorize bool, colors *conflictColors) error {
+	// Each line of ls-files -u is "MODE SP OBJECT SP STAGE TAB PATH".
+	// Stage 1 is the common ancestor, 2 is ours, and 3 is theirs.
+	out, err := cc.git.Output(ctx, "ls-files", "-u", "-z", "--", ent.Name.Pathspec().String())
+	if err != nil {
+		return err
+	}
+	var stages [4]string
+	for _, rec := range strings.Split(strings.TrimSuffix(out, "\x00"), "\x00") {
+		tab := strings.IndexByte(rec, '\t')
+		if tab == -1 {
+			return fmt.Errorf("show %s: unexpected ls-files output %q", ent.Name, rec)
+		}
+		fields := strings.Fields(rec[:tab])
+		if len(fields) != 3 {
+			return fmt.Errorf("show %s: unexpected ls-files output %q", ent.Name, rec)
+		}
+		stage, err := strconv.Atoi(fields[2])
+		if err != nil || stage < 1 || stage > 3 {
+			return fmt.Errorf("show %s: unexpected ls-files output %q", ent.Name, rec)
+		}
+		stages[stage] = fields[1]
+	}
+
+	tempDir, err := ioutil.TempDir("", "gg-resolve")
+	if err != nil {
+		return fmt.Errorf("show %s: %w", ent.Name, err)
+	}
+	defer os.RemoveAll(tempDir)
+	names := [4]string{1: "base", 2: "ours", 3: "theirs"}
+	for stage := 1; stage <= 3; stage++ {
+		var content string
+		if stages[stage] != "" {
+			content, err = cc.git.Output(ctx, "cat-file", "blob", stages[stage])
+			if err != nil {
+				return fmt.Errorf("show %s: %w", ent.Name, err)
+			}
+		}
+		if err := ioutil.WriteFile(filepath.Join(tempDir, names[stage]), []byte(content), 0o666); err != nil {
+			return fmt.Errorf("show %s: %w", ent.Name, err)
+		}
+	}
+	merged := new(bytes.Buffer)
+	stderr := new(bytes.Buffer)
+	err = cc.git.Runner().RunGit(ctx, &git.Invocation{
+		Args: []string{
+			"merge-file", "-p", "-q", "--diff3",
+			"-L", "ours", "-L", "base", "-L", "theirs",
+			filepath.Join(tempDir, "ours"),
+			filepath.Join(tempDir, "base"),
+			filepath.Join(tempDir, "theirs"),
+		},
+		Dir:    cc.dir,
+		Stdout: merged,
+		Stderr: stderr,
+	})
+	// merge-file exits with the number of conflicts, so only treat it as
+	// a failure if it complained.
+	if err != nil && stderr.Len() > 0 {
+		return fmt.Errorf("show %s: %w: %s", ent.Name, err, bytes.TrimSpace(stderr.Bytes()))
+	}
+
+	fmt.Fprintf(cc.stdout, "%s (%s)\n", ent.Name, describeConflict(ent.Code))
+	for stage := 2; stage <= 3; stage++ {
+		if stages[stage] == "" {
+			fmt.Fprintf(cc.stdout, "(deleted in %s)\n", names[stage])
+		}
+	}
+	// section is the version that the current line belongs to, or zero
+	// outside of a conflict.
+	section := 0
+	sectionColors := [4][]byte{1: colors.base, 2: colors.ours, 3: colors.theirs}
+	for _, line := range strings.SplitAfter(merged.String(), "\n") {
+		if line == "" {
+			continue
+		}
+		lineColor := sectionColors[section]
+		switch {
+		case strings.HasPrefix(line, "<<<<<<< ") && section == 0:
+			lineColor, section = colors.marker, 2
+		case strings.HasPrefix(line, "||||||| ") && section == 2:
+			lineColor, section = colors.marker, 1
+		case strings.HasPrefix(line, "=======") && (section == 1 || section == 2):
+			lineColor, section = colors.marker, 3
+		case strings.HasPrefix(line, ">>>>>>> ") && section == 3:
+			lineColor, section = colors.marker, 0
+		}
+		if _, err := fmt.Fprintf(cc.stdout, "%s%s", lineColor, strings.TrimSuffix(line, "\n")); err != nil {
+			return err
+		}
+		if colorize && len(lineColor) > 0 {
+			if err := terminal.ResetTextStyle(cc.stdout); err != nil {
+				return err
+			}
+		}
+		if _, err := fmt.Fprintln(cc.stdout); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,110 @@
+// Copyright 2021 The gg Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"testing"
+
+	"gg-scm.io/pkg/git"
+	"gg-scm.io/tool/internal/filesystem"
+)
+
+func TestResolve(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	env, err := newTestEnv(ctx, t)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := env.initEmptyRepo(ctx, "."); err != nil {
+		t.Fatal(err)
+	}
+	if err := env.root.Apply(filesystem.Write("foo.txt", "original\n")); err != nil {
+		t.Fatal(err)
+	}
+	if err := env.addFiles(ctx, "foo.txt"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := env.newCommit(ctx, "."); err != nil {
+		t.Fatal(err)
+	}
+	if err := env.git.NewBranch(ctx, "feature", git.BranchOptions{Checkout: true}); err != nil {
+		t.Fatal(err)
+	}
+	if err := env.root.Apply(filesystem.Write("foo.txt", "feature\n")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := env.newCommit(ctx, "."); err != nil {
+		t.Fatal(err)
+	}
+	if err := env.git.CheckoutBranch(ctx, "main", git.CheckoutOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := env.root.Apply(filesystem.Write("foo.txt", "main\n")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := env.newCommit(ctx, "."); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := env.gg(ctx, env.root.String(), "merge", "feature"); err == nil {
+		t.Fatal("merge did not return error")
+	}
+
+	out, err := env.gg(ctx, env.root.String(), "resolve", "-l")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := string(out), "U foo.txt (both modified)\n"; got != want {
+		t.Errorf("resolve -l = %q; want %q", got, want)
+	}
+
+	out, err = env.gg(ctx, env.root.String(), "resolve", "--show", "foo.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	const wantShow = "foo.txt (both modified)\n" +
+		"<<<<<<< ours\n" +
+		"main\n" +
+		"||||||| base\n" +
+		"original\n" +
+		"=======\n" +
+		"feature\n" +
+		">>>>>>> theirs\n"
+	if got := string(out); got != wantShow {
+		t.Errorf("resolve --show output:\n%s\nwant:\n%s", got, wantShow)
+	}
+
+	if err := env.root.Apply(filesystem.Write("foo.txt", "resolved\n")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := env.gg(ctx, env.root.String(), "resolve", "-m", "foo.txt"); err != nil {
+		t.Fatal(err)
+	}
+	if conflicts, err := findConflicts(ctx, env.git, nil); err != nil {
+		t.Fatal(err)
+	} else if len(conflicts) != 0 {
+		t.Errorf("after resolve -m, conflicts = %v; want none", conflicts)
+	}
+
+	if _, err := env.gg(ctx, env.root.String(), "resolve", "-u", "foo.txt"); err != nil {
+		t.Fatal(err)
+	}
+	if conflicts, err := findConflicts(ctx, env.git, nil); err != nil {
+		t.Fatal(err)
+	} else if len(conflicts) != 1 || conflicts[0].Name != "foo.txt" {
+		t.Errorf("after resolve -u, conflicts = %v; want foo.txt", conflicts)
+	}
+}
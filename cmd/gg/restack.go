@@ -0,0 +1,204 @@
+// Copyright 2021 The gg Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"gg-scm.io/pkg/git"
+	"gg-scm.io/tool/internal/flag"
+)
+
+const restackSynopsis = "move branches stacked on a rewritten branch"
+
+func restack(ctx context.Context, cc *cmdContext, args []string) error {
+	f := flag.NewFlagSet(true, "gg restack [--preview] [BRANCH]", restackSynopsis+`
+
+	When a commit in a stack of branches is amended or rebased, the
+	branches built on top of it still contain the old commit. restack
+	finds those branches and rebases each one onto the new tip of the
+	branch it was stacked on, then does the same for any branches stacked
+	on top of those. BRANCH defaults to the current branch.
+
+	A branch is considered stacked on BRANCH if it forked from a commit
+	that was once the tip of BRANCH (according to BRANCH's reflog) but is
+	no longer part of BRANCH. Branches that forked from commits still in
+	BRANCH are left alone.
+
+	If a rebase stops because of conflicts, resolve them and run
+	`+"`gg rebase --continue`"+`, then run `+"`gg restack`"+` again to
+	move the remaining branches. Once done, restack checks out the branch
+	that was checked out when it started.`)
+	preview := f.Bool("preview", false, "show the branches that would be moved without rebasing")
+	f.Alias("preview", "dry-run", "n")
+	if err := f.Parse(args); flag.IsHelp(err) {
+		f.Help(cc.stdout)
+		return nil
+	} else if err != nil {
+		return usagef("%v", err)
+	}
+	if f.NArg() > 1 {
+		return usagef("can only restack one branch")
+	}
+	root := f.Arg(0)
+	if root == "" {
+		root = currentBranch(ctx, cc)
+		if root == "" {
+			return errors.New("no branch currently checked out; please specify a branch name")
+		}
+	} else {
+		root = strings.TrimPrefix(root, "refs/heads/")
+	}
+	if !git.BranchRef(root).IsValid() {
+		return fmt.Errorf("invalid branch name %q", root)
+	}
+	head, err := cc.git.Head(ctx)
+	if err != nil {
+		return err
+	}
+	if !*preview {
+		if clean, err := isClean(ctx, cc.git); err != nil {
+			return err
+		} else if !clean {
+			return errors.New("working copy has uncommitted changes; commit or shelve them first")
+		}
+	}
+
+	visited := map[string]bool{root: true}
+	queue := []string{root}
+	moved := 0
+	for len(queue) > 0 {
+		parent := queue[0]
+		queue = queue[1:]
+		stacked, err := findStackedBranches(ctx, cc.git, parent, visited)
+		if err != nil {
+			return err
+		}
+		for _, s := range stacked {
+			visited[s.branch] = true
+			queue = append(queue, s.branch)
+			if s.forkPoint == (git.Hash{}) {
+				// Already on top of parent. Its own children may still
+				// need to move.
+				continue
+			}
+			n, err := countCommits(ctx, cc.git, s.forkPoint.String()+".."+git.BranchRef(s.branch).String())
+			if err != nil {
+				return err
+			}
+			if *preview {
+				fmt.Fprintf(cc.stdout, "would move %s onto %s (%s)\n", s.branch, parent, pluralize(n, "commit", "commits"))
+				moved++
+				continue
+			}
+			err = cc.interactiveGit(ctx, "rebase", "--onto="+git.BranchRef(parent).String(), "--no-fork-point", "--", s.forkPoint.String(), s.branch)
+			if err != nil {
+				return fmt.Errorf("restack %s onto %s: %w", s.branch, parent, err)
+			}
+			fmt.Fprintf(cc.stdout, "moved %s onto %s (%s)\n", s.branch, parent, pluralize(n, "commit", "commits"))
+			moved++
+		}
+	}
+	if moved == 0 {
+		fmt.Fprintln(cc.stdout, "no branches to restack")
+		return nil
+	}
+	if *preview || !head.Ref.IsBranch() {
+		return nil
+	}
+	if err := cc.git.CheckoutBranch(ctx, head.Ref.Branch(), git.CheckoutOptions{}); err != nil {
+		return fmt.Errorf("restack: return to %s: %w", head.Ref.Branch(), err)
+	}
+	return nil
+}
+
+// A stackedBranch is a local branch that was created on top of another
+// local branch.
+type stackedBranch struct {
+	branch string
+	// forkPoint is the former tip of the parent branch that the branch
+	// should be moved off of. It is zero if the branch already contains
+	// the parent branch's tip.
+	forkPoint git.Hash
+}
+
+// findStackedBranches returns the local branches not in skip that were
+// created from parent, sorted by name.
+func findStackedBranches(ctx context.Context, g *git.Git, parent string, skip map[string]bool) ([]stackedBranch, error) {
+	refs, err := g.ListRefs(ctx)
+	if err != nil {
+		return nil, err
+	}
+	parentRef := git.BranchRef(parent)
+	parentTip, ok := refs[parentRef]
+	if !ok {
+		return nil, fmt.Errorf("branch %q does not exist", parent)
+	}
+	var names []string
+	for ref := range refs {
+		if ref.IsBranch() && !skip[ref.Branch()] {
+			names = append(names, ref.Branch())
+		}
+	}
+	sort.Strings(names)
+	var stacked []stackedBranch
+	for _, name := range names {
+		tip := refs[git.BranchRef(name)]
+		if tip == parentTip {
+			continue
+		}
+		fp, err := g.Output(ctx, "merge-base", "--fork-point", parentRef.String(), git.BranchRef(name).String())
+		if err != nil {
+			// No fork point: the branch was not created from parent.
+			continue
+		}
+		forkPoint, err := git.ParseHash(strings.TrimSpace(fp))
+		if err != nil {
+			return nil, fmt.Errorf("fork point of %s: %w", name, err)
+		}
+		if forkPoint == parentTip {
+			stacked = append(stacked, stackedBranch{branch: name})
+			continue
+		}
+		stillInParent, err := g.IsAncestor(ctx, forkPoint.String(), parentTip.String())
+		if err != nil {
+			return nil, err
+		}
+		if stillInParent {
+			// Forked from an older commit that's still in parent, like a
+			// feature branch from a main branch that has moved on.
+			continue
+		}
+		stacked = append(stacked, stackedBranch{branch: name, forkPoint: forkPoint})
+	}
+	return stacked, nil
+}
+
+// countCommits returns the number of commits in the given revision range.
+func countCommits(ctx context.Context, g *git.Git, revRange string) (int, error) {
+	out, err := g.Output(ctx, "rev-list", "--count", revRange, "--")
+	if err != nil {
+		return 0, err
+	}
+	var n int
+	if _, err := fmt.Sscan(out, &n); err != nil {
+		return 0, fmt.Errorf("parse rev-list count: %w", err)
+	}
+	return n, nil
+}
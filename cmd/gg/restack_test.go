@@ -0,0 +1,87 @@
+// Copyright 2021 The gg Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"gg-scm.io/pkg/git"
+	"gg-scm.io/tool/internal/filesystem"
+)
+
+func TestRestack(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	env, err := newTestEnv(ctx, t)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := env.initRepoWithHistory(ctx, "."); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"feature1", "feature2", "feature3"} {
+		if err := env.git.NewBranch(ctx, name, git.BranchOptions{Checkout: true}); err != nil {
+			t.Fatal(err)
+		}
+		if err := env.root.Apply(filesystem.Write(name+".txt", dummyContent)); err != nil {
+			t.Fatal(err)
+		}
+		if err := env.addFiles(ctx, name+".txt"); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := env.newCommit(ctx, "."); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := env.git.CheckoutBranch(ctx, "feature1", git.CheckoutOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := env.git.Run(ctx, "commit", "--amend", "--quiet", "--message=Amended feature1"); err != nil {
+		t.Fatal(err)
+	}
+
+	out, err := env.gg(ctx, env.root.String(), "restack")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"moved feature2 onto feature1 (1 commit)\n",
+		"moved feature3 onto feature2 (1 commit)\n",
+	} {
+		if !bytes.Contains(out, []byte(want)) {
+			t.Errorf("restack output does not contain %q. Output:\n%s", want, out)
+		}
+	}
+	for _, pair := range [][2]string{{"feature1", "feature2"}, {"feature2", "feature3"}} {
+		parent, err := env.git.ParseRev(ctx, pair[0])
+		if err != nil {
+			t.Fatal(err)
+		}
+		child, err := env.git.ParseRev(ctx, pair[1]+"~")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if child.Commit != parent.Commit {
+			t.Errorf("%s~ = %v; want %s (%v)", pair[1], child.Commit, pair[0], parent.Commit)
+		}
+	}
+	if head, err := env.git.Head(ctx); err != nil {
+		t.Error(err)
+	} else if head.Ref != "refs/heads/feature1" {
+		t.Errorf("HEAD = %v after restack; want refs/heads/feature1", head.Ref)
+	}
+}
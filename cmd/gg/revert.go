@@ -16,7 +16,12 @@ package main
 
 import (
 	"context"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
+	"hash"
+	"io"
 	"os"
 	"path/filepath"
 	"strings"
@@ -34,7 +39,9 @@ func revert(ctx context.Context, cc *cmdContext, args []string) error {
 	to the contents they had at HEAD.
 	
 	Modified files are saved with a .orig suffix before reverting. To
-	disable these backups, use `+"`--no-backup`.")
+	disable these backups, use `+"`--no-backup`"+`.
+
+	`+filePatternHelp)
 	all := f.Bool("all", false, "revert all changes when no arguments given")
 	noBackups := f.Bool("C", false, "do not save backup copies of files")
 	f.Alias("C", "no-backup")
@@ -48,14 +55,20 @@ func revert(ctx context.Context, cc *cmdContext, args []string) error {
 	if f.NArg() == 0 && !*all {
 		return usagef("no arguments given.  Use -all to revert entire repository.")
 	}
+	fileArgs, err := expandFilePatterns(ctx, cc, f.Args())
+	if err != nil {
+		return err
+	}
+	files := fileArgs.files()
+	pathspecs := fileArgs.pathspecs()
 
 	revObj, err := cc.git.ParseRev(ctx, *rev)
 	if err != nil {
 		if *rev == git.Head.String() {
 			// If HEAD fails to parse (empty repo), then just use reset.
 			rmArgs := []string{"reset", "--"}
-			for _, f := range f.Args() {
-				rmArgs = append(rmArgs, git.LiteralPath(f).String())
+			for _, p := range pathspecs {
+				rmArgs = append(rmArgs, p.String())
 			}
 			return cc.git.Run(ctx, rmArgs...)
 		}
@@ -64,7 +77,7 @@ func revert(ctx context.Context, cc *cmdContext, args []string) error {
 
 	// Check whether files are known to Git or exist in the working tree.
 	var unknowns []int
-	for i, arg := range f.Args() {
+	for i, arg := range files {
 		if _, err := os.Stat(cc.abs(arg)); err != nil {
 			unknowns = append(unknowns, i)
 		}
@@ -72,7 +85,7 @@ func revert(ctx context.Context, cc *cmdContext, args []string) error {
 	if len(unknowns) > 0 {
 		unknownPathspecs := make([]git.Pathspec, 0, len(unknowns))
 		for _, i := range unknowns {
-			unknownPathspecs = append(unknownPathspecs, git.LiteralPath(f.Arg(i)))
+			unknownPathspecs = append(unknownPathspecs, git.LiteralPath(files[i]))
 		}
 		workRoot, err := cc.git.WorkTree(ctx)
 		if err != nil {
@@ -87,7 +100,7 @@ func revert(ctx context.Context, cc *cmdContext, args []string) error {
 			return err
 		}
 		for _, i := range unknowns {
-			arg := f.Arg(i)
+			arg := files[i]
 			argPath, err := worktreeRelativePath(cc, workRoot, arg)
 			if err != nil {
 				return err
@@ -100,38 +113,72 @@ func revert(ctx context.Context, cc *cmdContext, args []string) error {
 
 	// Find the list of files that have changed between the revision and
 	// the working tree.
-	var pathspecs []git.Pathspec
-	for _, f := range f.Args() {
-		pathspecs = append(pathspecs, git.LiteralPath(f))
-	}
-	st, err := cc.git.DiffStatus(ctx, git.DiffStatusOptions{
-		Commit1:        revObj.Commit.String(),
-		Pathspecs:      pathspecs,
-		DisableRenames: true,
-	})
-	if err != nil {
-		return err
-	}
 	var adds, deletes, mods, chmods []git.Pathspec
-	for _, ent := range st {
-		switch ent.Code {
-		case git.DiffStatusAdded:
-			adds = append(adds, ent.Name.Pathspec())
-		case git.DiffStatusDeleted:
-			deletes = append(deletes, ent.Name.Pathspec())
-		case git.DiffStatusModified:
-			mods = append(mods, ent.Name.Pathspec())
-		case git.DiffStatusChangedMode:
-			chmods = append(chmods, ent.Name.Pathspec())
-		}
-	}
-
-	// Find the list of files that need to be backed up: these are
-	// modified locally beyond what's in HEAD.
-	if !*noBackups {
-		if err := backupForRevert(ctx, cc, mods); err != nil {
+	var backups []git.TopPath
+	if *rev == git.Head.String() {
+		// Reverting to HEAD: a single diff tells us both which files to
+		// revert and which files have local contents to back up.
+		changes, err := diffRawWorkTree(ctx, cc.git, git.Head.String(), pathspecs)
+		if err != nil {
+			return err
+		}
+		for _, ent := range changes {
+			switch ent.code {
+			case git.DiffStatusAdded:
+				adds = append(adds, ent.name.Pathspec())
+			case git.DiffStatusDeleted:
+				deletes = append(deletes, ent.name.Pathspec())
+			case git.DiffStatusModified:
+				mods = append(mods, ent.name.Pathspec())
+			case git.DiffStatusChangedMode:
+				chmods = append(chmods, ent.name.Pathspec())
+			}
+		}
+		if !*noBackups {
+			backups, err = contentChanges(ctx, cc.git, changes)
+			if err != nil {
+				return fmt.Errorf("backing up files: %w", err)
+			}
+		}
+	} else {
+		st, err := cc.git.DiffStatus(ctx, git.DiffStatusOptions{
+			Commit1:        revObj.Commit.String(),
+			Pathspecs:      pathspecs,
+			DisableRenames: true,
+		})
+		if err != nil {
 			return err
 		}
+		for _, ent := range st {
+			switch ent.Code {
+			case git.DiffStatusAdded:
+				adds = append(adds, ent.Name.Pathspec())
+			case git.DiffStatusDeleted:
+				deletes = append(deletes, ent.Name.Pathspec())
+			case git.DiffStatusModified:
+				mods = append(mods, ent.Name.Pathspec())
+			case git.DiffStatusChangedMode:
+				chmods = append(chmods, ent.Name.Pathspec())
+			}
+		}
+		if !*noBackups && len(mods)+len(chmods) > 0 {
+			// Find the list of files that need to be backed up: these are
+			// modified locally beyond what's in HEAD.
+			candidates := make([]git.Pathspec, 0, len(mods)+len(chmods))
+			candidates = append(candidates, mods...)
+			candidates = append(candidates, chmods...)
+			changes, err := diffRawWorkTree(ctx, cc.git, git.Head.String(), candidates)
+			if err != nil {
+				return fmt.Errorf("backing up files: %w", err)
+			}
+			backups, err = contentChanges(ctx, cc.git, changes)
+			if err != nil {
+				return fmt.Errorf("backing up files: %w", err)
+			}
+		}
+	}
+	if err := backupForRevert(ctx, cc, backups); err != nil {
+		return err
 	}
 
 	// Now revert files.
@@ -159,28 +206,116 @@ func revert(ctx context.Context, cc *cmdContext, args []string) error {
 	return nil
 }
 
-// backupForRevert creates ".orig" files for any modified files that
-// have local modifications.
-func backupForRevert(ctx context.Context, cc *cmdContext, modified []git.Pathspec) error {
-	if len(modified) == 0 {
-		return nil
+// A rawDiffEntry is a file that differs between a commit and the working
+// copy, as reported by git diff --raw.
+type rawDiffEntry struct {
+	code    git.DiffStatusCode
+	name    git.TopPath
+	oldMode string
+	newMode string
+	oldHash string // hash of the file's blob in the commit
+}
+
+// diffRawWorkTree compares the working copy to rev, excluding renames.
+func diffRawWorkTree(ctx context.Context, g *git.Git, rev string, pathspecs []git.Pathspec) ([]rawDiffEntry, error) {
+	args := []string{"diff", "--raw", "-z", "--no-abbrev", "--no-renames", rev, "--"}
+	for _, p := range pathspecs {
+		args = append(args, p.String())
 	}
-	st, err := cc.git.Status(ctx, git.StatusOptions{
-		DisableRenames: true,
-		Pathspecs:      modified,
-	})
+	out, err := g.Output(ctx, args...)
 	if err != nil {
-		return fmt.Errorf("backing up files: %w", err)
+		return nil, err
 	}
+	var entries []rawDiffEntry
+	for len(out) > 0 {
+		// Each entry is ":oldmode newmode oldhash newhash status\x00path\x00".
+		i := strings.IndexByte(out, 0)
+		if i == -1 {
+			return nil, fmt.Errorf("parse diff: missing path")
+		}
+		fields := strings.Fields(strings.TrimPrefix(out[:i], ":"))
+		out = out[i+1:]
+		j := strings.IndexByte(out, 0)
+		if len(fields) != 5 || fields[4] == "" || j == -1 {
+			return nil, fmt.Errorf("parse diff: malformed entry")
+		}
+		entries = append(entries, rawDiffEntry{
+			code:    git.DiffStatusCode(fields[4][0]),
+			name:    git.TopPath(out[:j]),
+			oldMode: fields[0],
+			newMode: fields[1],
+			oldHash: fields[2],
+		})
+		out = out[j+1:]
+	}
+	return entries, nil
+}
+
+// contentChanges returns the names of the modified files in changes whose
+// contents in the working copy differ from the commit. Changes to a file's
+// mode alone are ignored.
+func contentChanges(ctx context.Context, g *git.Git, changes []rawDiffEntry) ([]git.TopPath, error) {
 	var names []git.TopPath
-	for _, ent := range st {
-		names = append(names, ent.Name)
+	var top string
+	for _, ent := range changes {
+		if ent.code != git.DiffStatusModified && ent.code != git.DiffStatusChangedMode {
+			continue
+		}
+		if ent.code == git.DiffStatusModified && ent.oldMode == ent.newMode {
+			// Git only reports a file with the same mode if its contents changed.
+			names = append(names, ent.name)
+			continue
+		}
+		if top == "" {
+			var err error
+			top, err = g.WorkTree(ctx)
+			if err != nil {
+				return nil, err
+			}
+		}
+		if !sameBlob(filepath.Join(top, filepath.FromSlash(ent.name.String())), ent.oldHash) {
+			names = append(names, ent.name)
+		}
 	}
+	return names, nil
+}
+
+// sameBlob reports whether the regular file at path has the contents of
+// the Git blob with the hex-encoded object hash want. It returns false if
+// the file can't be read, so that callers err on the side of treating the
+// file as changed.
+func sameBlob(path string, want string) bool {
+	var h hash.Hash
+	switch len(want) {
+	case sha1.Size * 2:
+		h = sha1.New()
+	case sha256.Size * 2:
+		h = sha256.New()
+	default:
+		return false
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return false
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil || !info.Mode().IsRegular() {
+		return false
+	}
+	fmt.Fprintf(h, "blob %d\x00", info.Size())
+	if _, err := io.Copy(h, f); err != nil {
+		return false
+	}
+	return hex.EncodeToString(h.Sum(nil)) == want
+}
+
+// backupForRevert renames the given files to have a ".orig" suffix.
+func backupForRevert(ctx context.Context, cc *cmdContext, names []git.TopPath) error {
 	if len(names) == 0 {
 		// Nothing to back up.
 		return nil
 	}
-
 	top, err := cc.git.WorkTree(ctx)
 	if err != nil {
 		return fmt.Errorf("backing up files: %w", err)
@@ -445,6 +445,56 @@ func TestRevert_Missing(t *testing.T) {
 	}
 }
 
+func TestRevert_RevBackup(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	env, err := newTestEnv(ctx, t)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Create a repository that has two commits of foo.txt and a local
+	// modification on top.
+	if err := env.initEmptyRepo(ctx, "."); err != nil {
+		t.Fatal(err)
+	}
+	if err := env.root.Apply(filesystem.Write("foo.txt", "original content")); err != nil {
+		t.Fatal(err)
+	}
+	if err := env.addFiles(ctx, "foo.txt"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := env.newCommit(ctx, "."); err != nil {
+		t.Fatal(err)
+	}
+	if err := env.root.Apply(filesystem.Write("foo.txt", "super-fresh content")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := env.newCommit(ctx, "."); err != nil {
+		t.Fatal(err)
+	}
+	if err := env.root.Apply(filesystem.Write("foo.txt", "tears in rain")); err != nil {
+		t.Fatal(err)
+	}
+
+	// Call gg to revert foo.txt to the first commit's content.
+	if _, err := env.gg(ctx, env.root.String(), "revert", "-r", "HEAD^", "foo.txt"); err != nil {
+		t.Fatal(err)
+	}
+
+	if got, err := env.root.ReadFile("foo.txt"); err != nil {
+		t.Error(err)
+	} else if want := "original content"; got != want {
+		t.Errorf("foo.txt content = %q after revert; want %q", got, want)
+	}
+	// Verify that the local modification was saved to foo.txt.orig.
+	if got, err := env.root.ReadFile("foo.txt.orig"); err != nil {
+		t.Error(err)
+	} else if want := "tears in rain"; got != want {
+		t.Errorf("foo.txt.orig content = %q after revert; want %q", got, want)
+	}
+}
+
 func TestRevert_NoBackup(t *testing.T) {
 	t.Parallel()
 	ctx := context.Background()
@@ -19,6 +19,7 @@
 package main
 
 import (
+	"context"
 	"io/ioutil"
 	"os"
 	"path/filepath"
@@ -65,3 +66,91 @@ func TestEvalSymlinksSloppy(t *testing.T) {
 		}
 	}
 }
+
+func TestRevert_ModeOnly(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	env, err := newTestEnv(ctx, t)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Create a repository with a committed foo.txt, then make it
+	// executable without changing its contents.
+	if err := env.initEmptyRepo(ctx, "."); err != nil {
+		t.Fatal(err)
+	}
+	if err := env.root.Apply(filesystem.Write("foo.txt", "original content")); err != nil {
+		t.Fatal(err)
+	}
+	if err := env.addFiles(ctx, "foo.txt"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := env.newCommit(ctx, "."); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chmod(env.root.FromSlash("foo.txt"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := env.gg(ctx, env.root.String(), "revert", "foo.txt"); err != nil {
+		t.Fatal(err)
+	}
+
+	if info, err := os.Stat(env.root.FromSlash("foo.txt")); err != nil {
+		t.Error(err)
+	} else if info.Mode()&0o111 != 0 {
+		t.Errorf("foo.txt mode = %v after revert; want not executable", info.Mode())
+	}
+	// There are no local contents to lose, so there is nothing to back up.
+	if exists, err := env.root.Exists("foo.txt.orig"); err != nil {
+		t.Error(err)
+	} else if exists {
+		t.Error("foo.txt.orig was created")
+	}
+}
+
+func TestRevert_ModeAndContent(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	env, err := newTestEnv(ctx, t)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Create a repository with a committed foo.txt, then make it
+	// executable and change its contents.
+	if err := env.initEmptyRepo(ctx, "."); err != nil {
+		t.Fatal(err)
+	}
+	if err := env.root.Apply(filesystem.Write("foo.txt", "original content")); err != nil {
+		t.Fatal(err)
+	}
+	if err := env.addFiles(ctx, "foo.txt"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := env.newCommit(ctx, "."); err != nil {
+		t.Fatal(err)
+	}
+	if err := env.root.Apply(filesystem.Write("foo.txt", "local content")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chmod(env.root.FromSlash("foo.txt"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := env.gg(ctx, env.root.String(), "revert", "foo.txt"); err != nil {
+		t.Fatal(err)
+	}
+
+	if got, err := env.root.ReadFile("foo.txt"); err != nil {
+		t.Error(err)
+	} else if want := "original content"; got != want {
+		t.Errorf("foo.txt content = %q after revert; want %q", got, want)
+	}
+	if got, err := env.root.ReadFile("foo.txt.orig"); err != nil {
+		t.Error(err)
+	} else if want := "local content"; got != want {
+		t.Errorf("foo.txt.orig content = %q; want %q", got, want)
+	}
+}
@@ -0,0 +1,146 @@
+// Copyright 2021 The gg Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"gg-scm.io/pkg/git"
+	"gg-scm.io/tool/internal/revset"
+)
+
+// revsetHelp describes the revision set syntax for commands' help text.
+const revsetHelp = `Revisions may also be given as revision set expressions: ` + "`A::B`" + `
+	selects the descendants of A that are ancestors of B, ` + "`A::`" + ` and
+	` + "`::B`" + ` leave one end open, ` + "`ancestors(REV)`" + ` selects REV and its
+	ancestors, ` + "`heads()`" + ` selects the tips of local branches,
+	` + "`draft()`" + ` selects commits that have not been pushed to any
+	remote, and ` + "`author(PATTERN)`" + ` selects commits whose author
+	matches the regular expression PATTERN. ` + "`A::B`" + ` and ` + "`A::`" + ` require
+	Git 2.38 or later.`
+
+// ancestryPathVersion is the first version of Git that accepts a commit
+// argument to --ancestry-path, which A::B and A:: need.
+var ancestryPathVersion = gitVersion{2, 38, 0}
+
+// parseRevset parses a revision set expression and checks that the
+// installed Git can evaluate it.
+func parseRevset(ctx context.Context, g *git.Git, expr string) (*revset.Set, error) {
+	set, err := revset.Parse(expr)
+	if err != nil {
+		return nil, usagef("%v", err)
+	}
+	if set.UsesAncestryPathCommit() {
+		v, err := queryGitVersion(ctx, g)
+		if err != nil {
+			return nil, err
+		}
+		if v.less(ancestryPathVersion) {
+			return nil, fmt.Errorf("%s: A::B ranges require Git %v or later (found %v)", expr, ancestryPathVersion, v)
+		}
+	}
+	return set, nil
+}
+
+// revListArgs converts -r flag values into arguments for git rev-list
+// or git log. Plain revisions and ranges are passed to Git unchanged. A
+// revision set expression must be the only value, since Git can't take
+// the union of several sets in one invocation.
+func revListArgs(ctx context.Context, g *git.Git, revs []string) ([]string, error) {
+	var args []string
+	for _, r := range revs {
+		if !revset.IsExpression(r) {
+			if strings.HasPrefix(r, "-") {
+				return nil, usagef("revisions must not start with '-'")
+			}
+			args = append(args, r)
+			continue
+		}
+		if len(revs) > 1 {
+			return nil, usagef("can't combine revision set %q with other revisions", r)
+		}
+		set, err := parseRevset(ctx, g, r)
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, set.Args()...)
+	}
+	return args, nil
+}
+
+// hasRevsetExpression reports whether any of revs is a revision set
+// expression.
+func hasRevsetExpression(revs []string) bool {
+	for _, r := range revs {
+		if revset.IsExpression(r) {
+			return true
+		}
+	}
+	return false
+}
+
+// listRevset returns the commits selected by a revision set expression,
+// oldest first. A plain revision selects only that commit, and a range
+// like A..B selects the commits in the range. If noMerges is true, merge
+// commits are left out. Commits reachable from any of the exclude
+// revisions are left out too.
+func listRevset(ctx context.Context, g *git.Git, expr string, noMerges bool, exclude ...string) ([]git.Hash, error) {
+	args := []string{"rev-list", "--reverse", "--topo-order"}
+	if noMerges {
+		args = append(args, "--no-merges")
+	}
+	// Exclusions go before the set's arguments, since those may end
+	// with --not.
+	for _, x := range exclude {
+		args = append(args, "^"+x)
+	}
+	if revset.IsExpression(expr) {
+		set, err := parseRevset(ctx, g, expr)
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, set.Args()...)
+	} else {
+		if strings.HasPrefix(expr, "-") {
+			return nil, usagef("revisions must not start with '-'")
+		}
+		args = append(args, "--no-walk", expr)
+	}
+	args = append(args, "--")
+	return revListHashes(ctx, g, args)
+}
+
+// revListHashes runs git with the given rev-list arguments and parses
+// the commit hashes it prints, one per line.
+func revListHashes(ctx context.Context, g *git.Git, args []string) ([]git.Hash, error) {
+	out, err := g.Output(ctx, args...)
+	if err != nil {
+		return nil, err
+	}
+	var commits []git.Hash
+	for _, line := range strings.Split(strings.TrimSuffix(out, "\n"), "\n") {
+		if line == "" {
+			continue
+		}
+		h, err := git.ParseHash(line)
+		if err != nil {
+			return nil, err
+		}
+		commits = append(commits, h)
+	}
+	return commits, nil
+}
@@ -0,0 +1,111 @@
+// Copyright 2021 The gg Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strings"
+
+	"gg-scm.io/pkg/git"
+)
+
+// selectItem is a file presented to the user in an interactive selection.
+type selectItem struct {
+	name   git.TopPath
+	code   byte   // status letter, as shown by gg status
+	action string // action chosen for the file
+}
+
+// selectAction describes a choice the user can make for a file.
+type selectAction struct {
+	name        string
+	description string
+}
+
+// selectFiles opens the user's editor with a list of files, one per line,
+// each prefixed by an action. The user picks an action for each file by
+// changing the first word of its line (or its first letter). The first
+// action in actions is the default and is assumed for any file whose line
+// is deleted. selectFiles returns the items with their chosen actions.
+func selectFiles(ctx context.Context, cc *cmdContext, filename string, actions []selectAction, items []selectItem) ([]selectItem, error) {
+	if len(items) == 0 {
+		return nil, nil
+	}
+	cfg, err := cc.git.ReadConfig(ctx)
+	if err != nil {
+		return nil, err
+	}
+	commentChar, err := cfg.CommentChar()
+	if err != nil {
+		return nil, err
+	}
+	buf := new(bytes.Buffer)
+	for _, item := range items {
+		action := item.action
+		if action == "" {
+			action = actions[0].name
+		}
+		fmt.Fprintf(buf, "%s %c %s\n", action, item.code, item.name)
+	}
+	fmt.Fprintf(buf, "\n%s Change the first word of a line to choose what happens to the file:\n", commentChar)
+	for _, a := range actions {
+		fmt.Fprintf(buf, "%s %s, %c = %s\n", commentChar, a.name, a.name[0], a.description)
+	}
+	fmt.Fprintf(buf, "%s Removing a line is the same as %q. Lines starting with '%s' are ignored.\n", commentChar, actions[0].name, commentChar)
+	edited, err := cc.editor.open(ctx, filename, buf.Bytes())
+	if err != nil {
+		return nil, err
+	}
+	return parseSelection(string(edited), commentChar, actions, items)
+}
+
+// parseSelection parses the output of the editor in selectFiles.
+func parseSelection(s string, commentChar string, actions []selectAction, items []selectItem) ([]selectItem, error) {
+	index := make(map[git.TopPath]int, len(items))
+	result := make([]selectItem, len(items))
+	for i, item := range items {
+		index[item.name] = i
+		result[i] = item
+		result[i].action = actions[0].name
+	}
+	for lineno, line := range strings.Split(s, "\n") {
+		line = strings.TrimRight(line, "\r")
+		if strings.TrimSpace(line) == "" || strings.HasPrefix(line, commentChar) {
+			continue
+		}
+		parts := strings.SplitN(line, " ", 3)
+		if len(parts) != 3 {
+			return nil, fmt.Errorf("line %d: expected action, status, and file name", lineno+1)
+		}
+		i, ok := index[git.TopPath(parts[2])]
+		if !ok {
+			return nil, fmt.Errorf("line %d: unknown file %q", lineno+1, parts[2])
+		}
+		action := ""
+		for _, a := range actions {
+			if parts[0] == a.name || parts[0] == a.name[:1] {
+				action = a.name
+				break
+			}
+		}
+		if action == "" {
+			return nil, fmt.Errorf("line %d: unknown action %q", lineno+1, parts[0])
+		}
+		result[i].action = action
+	}
+	return result, nil
+}
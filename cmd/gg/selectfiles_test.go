@@ -0,0 +1,53 @@
+// Copyright 2021 The gg Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestParseSelection(t *testing.T) {
+	actions := []selectAction{
+		{"skip", "leave alone"},
+		{"add", "add it"},
+		{"patch", "add some of it"},
+	}
+	items := []selectItem{
+		{name: "a.txt", code: '?'},
+		{name: "b c.txt", code: 'M'},
+		{name: "d.txt", code: '?'},
+	}
+	got, err := parseSelection("add ? a.txt\np M b c.txt\n\n# add ? d.txt\n", "#", actions, items)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []selectItem{
+		{name: "a.txt", code: '?', action: "add"},
+		{name: "b c.txt", code: 'M', action: "patch"},
+		{name: "d.txt", code: '?', action: "skip"},
+	}
+	if diff := cmp.Diff(want, got, cmp.AllowUnexported(selectItem{})); diff != "" {
+		t.Errorf("parseSelection(...) (-want +got):\n%s", diff)
+	}
+
+	if _, err := parseSelection("frobnicate ? a.txt\n", "#", actions, items); err == nil {
+		t.Error("parseSelection accepted unknown action")
+	}
+	if _, err := parseSelection("add ? nope.txt\n", "#", actions, items); err == nil {
+		t.Error("parseSelection accepted unknown file")
+	}
+}
@@ -0,0 +1,277 @@
+// Copyright 2021 The gg Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"gg-scm.io/pkg/git"
+	"gg-scm.io/tool/internal/flag"
+)
+
+const (
+	shelveSynopsis   = "save and set aside changes from the working directory"
+	unshelveSynopsis = "restore a shelved change to the working directory"
+)
+
+// shelfPrefix is the prefix of the stash message used for shelves.
+const shelfPrefix = "gg-shelve: "
+
+func shelve(ctx context.Context, cc *cmdContext, args []string) error {
+	f := flag.NewFlagSet(true, "gg shelve [-n NAME] [-u] [FILE [...]]\n"+
+		"gg shelve --list\n"+
+		"gg shelve --delete NAME [...]", shelveSynopsis+`
+
+	Shelving takes the outstanding changes to the given files (or all
+	changes if no files are given), saves them as a shelf, and reverts
+	the files to their state in the working directory's parent. Use
+	`+"`gg unshelve`"+` to bring the changes back.
+
+	Shelves are stored as Git stashes. Each shelf has a name, which
+	defaults to the name of the current branch. If a shelf with that
+	name already exists, a numeric suffix is added. `+"`--list`"+` shows
+	when each shelf was created in the style given by the
+	`+"`gg.dateFormat`"+` setting.`)
+	name := f.String("n", "", "use the given `name` for the shelved commit")
+	f.Alias("n", "name")
+	untracked := f.Bool("u", false, "also shelve untracked files")
+	f.Alias("u", "unknown")
+	list := f.Bool("list", false, "list current shelves")
+	f.Alias("list", "l")
+	del := f.Bool("delete", false, "delete the named shelves")
+	f.Alias("delete", "d")
+	if err := f.Parse(args); flag.IsHelp(err) {
+		f.Help(cc.stdout)
+		return nil
+	} else if err != nil {
+		return usagef("%v", err)
+	}
+	switch {
+	case *list && *del:
+		return usagef("can't pass both --list and --delete")
+	case *list:
+		if f.NArg() > 0 || *name != "" || *untracked {
+			return usagef("--list takes no other arguments")
+		}
+		return listShelvesCommand(ctx, cc)
+	case *del:
+		if f.NArg() == 0 {
+			return usagef("must pass one or more shelves to delete")
+		}
+		if *name != "" || *untracked {
+			return usagef("--delete takes only shelf names")
+		}
+		for _, arg := range f.Args() {
+			if err := deleteShelf(ctx, cc.git, arg); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	shelves, err := listShelves(ctx, cc.git)
+	if err != nil {
+		return err
+	}
+	if *name == "" {
+		*name = "default"
+		if head, err := cc.git.Head(ctx); err == nil && head.Ref.IsBranch() {
+			*name = head.Ref.Branch()
+		}
+		*name = uniqueShelfName(shelves, *name)
+	} else if strings.ContainsAny(*name, "\n") || strings.TrimSpace(*name) != *name {
+		return fmt.Errorf("invalid shelf name %q", *name)
+	} else if findShelf(shelves, *name) != nil {
+		return fmt.Errorf("a shelf named %q already exists", *name)
+	}
+	stashArgs := []string{"stash", "push", "--quiet", "--message=" + shelfPrefix + *name}
+	if *untracked {
+		stashArgs = append(stashArgs, "--include-untracked")
+	}
+	stashArgs = append(stashArgs, "--")
+	for _, arg := range f.Args() {
+		stashArgs = append(stashArgs, git.LiteralPath(arg).String())
+	}
+	before := len(shelves)
+	if err := cc.git.Run(ctx, stashArgs...); err != nil {
+		return fmt.Errorf("shelve: %w", err)
+	}
+	if after, err := listShelves(ctx, cc.git); err != nil {
+		return err
+	} else if len(after) == before {
+		return errors.New("nothing changed")
+	}
+	_, err = fmt.Fprintf(cc.stdout, "shelved as %s\n", *name)
+	return err
+}
+
+func unshelve(ctx context.Context, cc *cmdContext, args []string) error {
+	f := flag.NewFlagSet(true, "gg unshelve [-k] [NAME]", unshelveSynopsis+`
+
+	Applies the changes in the named shelf (or the most recently created
+	shelf) to the working directory and deletes the shelf.
+
+	If applying the changes produces conflicts, the shelf is kept. Resolve
+	the conflicts, mark them resolved with `+"`gg add`"+`, then delete the
+	shelf with `+"`gg shelve --delete NAME`"+`.`)
+	keep := f.Bool("k", false, "keep the shelf after unshelving")
+	f.Alias("k", "keep")
+	if err := f.Parse(args); flag.IsHelp(err) {
+		f.Help(cc.stdout)
+		return nil
+	} else if err != nil {
+		return usagef("%v", err)
+	}
+	if f.NArg() > 1 {
+		return usagef("can only unshelve one shelf at a time")
+	}
+	shelves, err := listShelves(ctx, cc.git)
+	if err != nil {
+		return err
+	}
+	var s *shelf
+	if f.NArg() == 0 {
+		if len(shelves) == 0 {
+			return errors.New("no shelves found")
+		}
+		s = &shelves[0]
+	} else {
+		s = findShelf(shelves, f.Arg(0))
+		if s == nil {
+			return fmt.Errorf("shelf %q not found", f.Arg(0))
+		}
+	}
+	if err := cc.interactiveGit(ctx, "stash", "apply", "--quiet", s.ref); err != nil {
+		st, statusErr := cc.git.Status(ctx, git.StatusOptions{})
+		if statusErr == nil {
+			for _, ent := range st {
+				if ent.Code.IsUnmerged() {
+					return fmt.Errorf("unshelving %s produced conflicts; resolve them, then run `gg shelve --delete %s`", s.name, s.name)
+				}
+			}
+		}
+		return fmt.Errorf("unshelve %s: %w", s.name, err)
+	}
+	if *keep {
+		return nil
+	}
+	return cc.git.Run(ctx, "stash", "drop", "--quiet", s.ref)
+}
+
+func listShelvesCommand(ctx context.Context, cc *cmdContext) error {
+	shelves, err := listShelves(ctx, cc.git)
+	if err != nil {
+		return err
+	}
+	cfg, err := cc.git.ReadConfig(ctx)
+	if err != nil {
+		return err
+	}
+	style, err := dateStyle(cfg, "")
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	for _, s := range shelves {
+		if _, err := fmt.Fprintf(cc.stdout, "%-20s (%s)\n", s.name, style.Format(s.time, now)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func deleteShelf(ctx context.Context, g *git.Git, name string) error {
+	// Look up the shelf each time, since dropping a stash renumbers the
+	// ones after it.
+	shelves, err := listShelves(ctx, g)
+	if err != nil {
+		return err
+	}
+	s := findShelf(shelves, name)
+	if s == nil {
+		return fmt.Errorf("shelf %q not found", name)
+	}
+	if err := g.Run(ctx, "stash", "drop", "--quiet", s.ref); err != nil {
+		return fmt.Errorf("delete shelf %s: %w", name, err)
+	}
+	return nil
+}
+
+// shelf is a stash entry created by gg shelve.
+type shelf struct {
+	name string
+	ref  string    // stash reflog selector, like "stash@{0}"
+	time time.Time // time the shelf was created
+}
+
+// listShelves returns the shelves in the repository, most recent first.
+// Stashes not created by gg shelve are skipped.
+func listShelves(ctx context.Context, g *git.Git) ([]shelf, error) {
+	out, err := g.Output(ctx, "stash", "list", "--format=%gd%x00%gs%x00%ct")
+	if err != nil {
+		return nil, fmt.Errorf("list shelves: %w", err)
+	}
+	var shelves []shelf
+	for _, line := range strings.Split(out, "\n") {
+		fields := strings.Split(line, "\x00")
+		if len(fields) != 3 {
+			continue
+		}
+		// Stash subjects are "On BRANCH: MESSAGE".
+		i := strings.Index(fields[1], ": "+shelfPrefix)
+		if i == -1 {
+			continue
+		}
+		unix, err := strconv.ParseInt(fields[2], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("list shelves: parse time of %s: %w", fields[0], err)
+		}
+		shelves = append(shelves, shelf{
+			name: fields[1][i+len(": "+shelfPrefix):],
+			ref:  fields[0],
+			time: time.Unix(unix, 0),
+		})
+	}
+	return shelves, nil
+}
+
+// findShelf returns the shelf with the given name or nil if none exists.
+func findShelf(shelves []shelf, name string) *shelf {
+	for i := range shelves {
+		if shelves[i].name == name {
+			return &shelves[i]
+		}
+	}
+	return nil
+}
+
+// uniqueShelfName returns base if no shelf is named base, otherwise it
+// returns base with the smallest numeric suffix that is not taken.
+func uniqueShelfName(shelves []shelf, base string) string {
+	if findShelf(shelves, base) == nil {
+		return base
+	}
+	for i := 1; ; i++ {
+		name := fmt.Sprintf("%s-%02d", base, i)
+		if findShelf(shelves, name) == nil {
+			return name
+		}
+	}
+}
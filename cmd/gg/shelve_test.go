@@ -0,0 +1,89 @@
+// Copyright 2021 The gg Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"gg-scm.io/tool/internal/filesystem"
+)
+
+func TestShelve(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	env, err := newTestEnv(ctx, t)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := env.initEmptyRepo(ctx, "."); err != nil {
+		t.Fatal(err)
+	}
+	if err := env.root.Apply(filesystem.Write("foo.txt", "original\n")); err != nil {
+		t.Fatal(err)
+	}
+	if err := env.addFiles(ctx, "foo.txt"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := env.newCommit(ctx, "."); err != nil {
+		t.Fatal(err)
+	}
+	if err := env.root.Apply(filesystem.Write("foo.txt", "work in progress\n")); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := env.gg(ctx, env.root.String(), "shelve"); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := env.root.ReadFile("foo.txt"); err != nil {
+		t.Fatal(err)
+	} else if got != "original\n" {
+		t.Errorf("after shelve, foo.txt = %q; want \"original\\n\"", got)
+	}
+	out, err := env.gg(ctx, env.root.String(), "shelve", "--list")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(string(out), "main ") {
+		t.Errorf("shelve --list = %q; want to start with \"main \"", out)
+	}
+
+	if _, err := env.gg(ctx, env.root.String(), "unshelve", "main"); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := env.root.ReadFile("foo.txt"); err != nil {
+		t.Fatal(err)
+	} else if got != "work in progress\n" {
+		t.Errorf("after unshelve, foo.txt = %q; want \"work in progress\\n\"", got)
+	}
+	out, err = env.gg(ctx, env.root.String(), "shelve", "--list")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(out) > 0 {
+		t.Errorf("shelve --list after unshelve = %q; want empty", out)
+	}
+}
+
+func TestUniqueShelfName(t *testing.T) {
+	shelves := []shelf{{name: "main"}, {name: "main-01"}}
+	if got := uniqueShelfName(shelves, "feature"); got != "feature" {
+		t.Errorf("uniqueShelfName(shelves, \"feature\") = %q; want \"feature\"", got)
+	}
+	if got := uniqueShelfName(shelves, "main"); got != "main-02" {
+		t.Errorf("uniqueShelfName(shelves, \"main\") = %q; want \"main-02\"", got)
+	}
+}
@@ -0,0 +1,145 @@
+// Copyright 2021 The gg Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"time"
+
+	"gg-scm.io/pkg/git"
+	"gg-scm.io/tool/internal/flag"
+	"gg-scm.io/tool/internal/template"
+)
+
+const showSynopsis = "show a commit and its changes"
+
+// showTemplate is the template used to print a commit's metadata when
+// -T is not given.
+const showTemplate = "commit:      {node}\n" +
+	"author:      {author}\n" +
+	"date:        {date}\n" +
+	"\n" +
+	"\t{desc|tabindent}\n\n"
+
+// showSignatureTemplate is showTemplate with the signature keyword.
+const showSignatureTemplate = "commit:      {node}\n" +
+	"author:      {author}\n" +
+	"date:        {date}\n" +
+	"signature:   {signature}\n" +
+	"\n" +
+	"\t{desc|tabindent}\n\n"
+
+func show(ctx context.Context, cc *cmdContext, args []string) error {
+	f := flag.NewFlagSet(true, "gg show [-r REV] [--stat] [-T TEMPLATE] [FILE [...]]", showSynopsis+`
+
+	show prints the author, date, and message of a commit (the working
+	copy's parent by default), followed by the changes it made. If files
+	are given, only the changes to those files are shown. Changes in a
+	merge commit are shown relative to its first parent.
+
+	`+"`-T`"+` formats the commit's metadata with a template, using the
+	same keywords and filters as `+"`gg log -T`"+`. `+"`--show-signature`"+`
+	verifies the commit's signature and adds the signature and signer
+	keywords.`)
+	rev := f.String("r", git.Head.String(), "`rev`ision to show")
+	stat := f.Bool("stat", false, "show a diffstat-style summary instead of the full diff")
+	templateFlag := f.String("T", "", "display the commit's metadata using the given `template`")
+	f.Alias("T", "template")
+	showSignature := f.Bool("show-signature", false, "verify and show the commit's signature")
+	if err := f.Parse(args); flag.IsHelp(err) {
+		f.Help(cc.stdout)
+		return nil
+	} else if err != nil {
+		return usagef("%v", err)
+	}
+	if *rev == "" || (*rev)[0] == '-' {
+		return usagef("revisions must not start with '-'")
+	}
+	tmplText := showTemplate
+	switch {
+	case *templateFlag != "":
+		tmplText = *templateFlag
+	case *showSignature:
+		tmplText = showSignatureTemplate
+	}
+	tmpl, err := template.Parse(tmplText)
+	if err != nil {
+		return usagef("-T: %v", err)
+	}
+
+	c, err := cc.git.CommitInfo(ctx, *rev)
+	if err != nil {
+		return err
+	}
+	hash := c.SHA1()
+	cfg, err := cc.git.ReadConfig(ctx)
+	if err != nil {
+		return err
+	}
+	style, err := dateStyle(cfg, "")
+	if err != nil {
+		return err
+	}
+	refs, err := cc.git.ListRefs(ctx)
+	if err != nil {
+		return err
+	}
+	var labels []git.Ref
+	for ref, h := range refs {
+		if h == hash {
+			labels = append(labels, ref)
+		}
+	}
+	tctx := &template.Context{
+		Now:       time.Now(),
+		DateStyle: style,
+		Keywords:  commitKeywords(hash, c, labels),
+	}
+	if *showSignature {
+		sigs, err := verifyCommitSignatures(ctx, cc, []git.Hash{hash})
+		if err != nil {
+			return err
+		}
+		sig := sigs[hash]
+		tctx.Keywords["signature"] = sig.String()
+		tctx.Keywords["signer"] = sig.signer
+	}
+	buf := new(bytes.Buffer)
+	if err := tmpl.Execute(buf, tctx); err != nil {
+		return err
+	}
+	if _, err := cc.stdout.Write(buf.Bytes()); err != nil {
+		return err
+	}
+
+	var base string
+	if len(c.Parents) > 0 {
+		base = c.Parents[0].String()
+	} else {
+		nullTree, err := cc.git.NullTreeHash(ctx)
+		if err != nil {
+			return err
+		}
+		base = nullTree.String()
+	}
+	diffArgs := []string{"diff", "--find-renames"}
+	if *stat {
+		diffArgs = append(diffArgs, "--stat")
+	}
+	diffArgs = append(diffArgs, base, hash.String(), "--")
+	diffArgs = append(diffArgs, f.Args()...)
+	return cc.interactiveGit(ctx, diffArgs...)
+}
@@ -0,0 +1,85 @@
+// Copyright 2021 The gg Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"gg-scm.io/pkg/git"
+	"gg-scm.io/tool/internal/filesystem"
+)
+
+func TestShow(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	env, err := newTestEnv(ctx, t)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := env.initRepoWithHistory(ctx, "."); err != nil {
+		t.Fatal(err)
+	}
+	err = env.root.Apply(
+		filesystem.Write("foo.txt", "Hello, World!\n"),
+		filesystem.Write("bar.txt", "unchanged\n"),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := env.addFiles(ctx, "foo.txt", "bar.txt"); err != nil {
+		t.Fatal(err)
+	}
+	if err := env.git.CommitAll(ctx, "add foo and bar\n\nMore details.", git.CommitOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	// Uncommitted changes should not appear in the output.
+	if err := env.root.Apply(filesystem.Write("foo.txt", "Uncommitted\n")); err != nil {
+		t.Fatal(err)
+	}
+
+	out, err := env.gg(ctx, env.root.String(), "show")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"author:      ", "\tadd foo and bar\n\n\tMore details.\n", "+Hello, World!\n", "+unchanged\n"} {
+		if !bytes.Contains(out, []byte(want)) {
+			t.Errorf("gg show output does not contain %q. Output:\n%s", want, out)
+		}
+	}
+	if bytes.Contains(out, []byte("Uncommitted")) {
+		t.Errorf("gg show output contains working copy changes. Output:\n%s", out)
+	}
+
+	out, err = env.gg(ctx, env.root.String(), "show", "-T", "{desc|firstline}\n", "foo.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.HasPrefix(out, []byte("add foo and bar\ndiff --git")) {
+		t.Errorf("gg show -T output does not start with template. Output:\n%s", out)
+	}
+	if bytes.Contains(out, []byte("bar.txt")) {
+		t.Errorf("gg show foo.txt output mentions bar.txt. Output:\n%s", out)
+	}
+
+	out, err = env.gg(ctx, env.root.String(), "show", "--stat", "-r", "HEAD~1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(out, []byte("foo.txt")) || !bytes.Contains(out, []byte(".dummy")) {
+		t.Errorf("gg show --stat -r HEAD~1 output does not describe HEAD~1. Output:\n%s", out)
+	}
+}
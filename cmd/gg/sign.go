@@ -0,0 +1,95 @@
+// Copyright 2021 The gg Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"gg-scm.io/pkg/git"
+)
+
+// withSigning returns a copy of cc whose Git subprocesses sign the
+// commits they create, as if commit.gpgSign were set. If sign is false,
+// withSigning returns cc unchanged.
+func withSigning(cc *cmdContext, sign bool) (*cmdContext, error) {
+	if !sign {
+		return cc, nil
+	}
+	return cc.withGitConfig("commit.gpgSign", "true")
+}
+
+// withGitConfig returns a copy of cc whose Git subprocesses see the
+// given configuration setting, like `git -c key=value`.
+func (cc *cmdContext) withGitConfig(key, value string) (*cmdContext, error) {
+	// GIT_CONFIG_PARAMETERS is what `git -c` uses to pass settings to
+	// subprocesses. It is understood by all supported versions of Git.
+	const paramsVar = "GIT_CONFIG_PARAMETERS"
+	param := sqQuote(key) + "=" + sqQuote(value)
+	env := make([]string, 0, len(cc.env)+1)
+	found := false
+	for _, kv := range cc.env {
+		if strings.HasPrefix(kv, paramsVar+"=") {
+			kv += " " + param
+			found = true
+		}
+		env = append(env, kv)
+	}
+	if !found {
+		env = append(env, paramsVar+"="+param)
+	}
+
+	cc2 := new(cmdContext)
+	*cc2 = *cc
+	cc2.env = env
+	cc2.gitOptions.Env = env
+	g, err := git.New(cc2.gitOptions)
+	if err != nil {
+		return nil, err
+	}
+	cc2.git = g
+	return cc2, nil
+}
+
+// sqQuote quotes s in the way that Git expects in GIT_CONFIG_PARAMETERS.
+func sqQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// signingEnabled reports whether commits created in the repository
+// should be signed.
+func signingEnabled(ctx context.Context, cc *cmdContext) (bool, error) {
+	cfg, err := cc.git.ReadConfig(ctx)
+	if err != nil {
+		return false, err
+	}
+	if cfg.Value("commit.gpgSign") == "" {
+		return false, nil
+	}
+	return cfg.Bool("commit.gpgSign")
+}
+
+// signingError adds a hint about signing configuration to an error from
+// creating a commit if signing is enabled.
+func signingError(ctx context.Context, cc *cmdContext, err error) error {
+	if err == nil {
+		return nil
+	}
+	if sign, _ := signingEnabled(ctx, cc); !sign {
+		return err
+	}
+	return fmt.Errorf("%w (commit signing is enabled: check user.signingKey and gpg.format)", err)
+}
@@ -0,0 +1,96 @@
+// Copyright 2021 The gg Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strings"
+
+	"gg-scm.io/pkg/git"
+)
+
+// A commitSignature is the result of verifying a commit's signature.
+type commitSignature struct {
+	// status is one of "good", "bad", "unknown" (a good signature from a key
+	// of unknown validity), "expired", "expired key", "revoked key",
+	// "missing key", "error", or "none".
+	status string
+	signer string
+}
+
+// String returns the status followed by the signer in parentheses.
+func (sig commitSignature) String() string {
+	if sig.signer == "" {
+		return sig.status
+	}
+	return sig.status + " (" + sig.signer + ")"
+}
+
+// signatureStatuses maps the codes from Git's %G? format placeholder to
+// commitSignature statuses.
+var signatureStatuses = map[string]string{
+	"G": "good",
+	"B": "bad",
+	"U": "unknown",
+	"X": "expired",
+	"Y": "expired key",
+	"R": "revoked key",
+	"E": "missing key",
+	"N": "none",
+}
+
+// verifyCommitSignatures verifies the signatures of the given commits
+// using Git's configured signing programs.
+func verifyCommitSignatures(ctx context.Context, cc *cmdContext, hashes []git.Hash) (map[git.Hash]commitSignature, error) {
+	sigs := make(map[git.Hash]commitSignature, len(hashes))
+	if len(hashes) == 0 {
+		return sigs, nil
+	}
+	stdin := new(strings.Builder)
+	for _, h := range hashes {
+		stdin.WriteString(h.String())
+		stdin.WriteString("\n")
+	}
+	stdout := new(bytes.Buffer)
+	stderr := new(bytes.Buffer)
+	err := cc.git.Runner().RunGit(ctx, &git.Invocation{
+		Args:   []string{"log", "--no-walk=unsorted", "--stdin", "--format=%H%x00%G?%x00%GS"},
+		Dir:    cc.dir,
+		Stdin:  strings.NewReader(stdin.String()),
+		Stdout: stdout,
+		Stderr: stderr,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("verify signatures: %w: %s", err, bytes.TrimSpace(stderr.Bytes()))
+	}
+	for _, line := range strings.Split(strings.TrimSuffix(stdout.String(), "\n"), "\n") {
+		fields := strings.SplitN(line, "\x00", 3)
+		if len(fields) != 3 {
+			continue
+		}
+		h, err := git.ParseHash(fields[0])
+		if err != nil {
+			return nil, fmt.Errorf("verify signatures: %w", err)
+		}
+		status := signatureStatuses[fields[1]]
+		if status == "" {
+			status = "error"
+		}
+		sigs[h] = commitSignature{status: status, signer: fields[2]}
+	}
+	return sigs, nil
+}
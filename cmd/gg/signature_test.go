@@ -0,0 +1,81 @@
+// Copyright 2021 The gg Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"testing"
+)
+
+func TestLog_ShowSignature(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	env, err := newTestEnv(ctx, t)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := env.initRepoWithHistory(ctx, "."); err != nil {
+		t.Fatal(err)
+	}
+
+	out, err := env.gg(ctx, env.root.String(), "log", "--show-signature")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Contains(out, []byte("Signature: none\n")) {
+		t.Errorf("gg log --show-signature output:\n%s\nwant to contain \"Signature: none\"", out)
+	}
+
+	out, err = env.gg(ctx, env.root.String(), "log", "--show-signature", "-T", "{signature}|{signer}\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := string(out), "none|\n"; got != want {
+		t.Errorf("gg log --show-signature -T '{signature}|{signer}' = %q; want %q", got, want)
+	}
+
+	if _, err := env.gg(ctx, env.root.String(), "log", "--show-signature", "--stat"); err == nil {
+		t.Error("gg log --show-signature --stat did not return an error")
+	} else if !isUsage(err) {
+		t.Errorf("gg log --show-signature --stat: %v; want usage error", err)
+	}
+}
+
+func TestBranch_Verify(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	env, err := newTestEnv(ctx, t)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := env.initRepoWithHistory(ctx, "."); err != nil {
+		t.Fatal(err)
+	}
+
+	out, err := env.gg(ctx, env.root.String(), "branch", "--verify")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Contains(out, []byte("    signature:   none\n")) {
+		t.Errorf("gg branch --verify output:\n%s\nwant to contain signature line", out)
+	}
+
+	if _, err := env.gg(ctx, env.root.String(), "branch", "--verify", "foo"); err == nil {
+		t.Error("gg branch --verify foo did not return an error")
+	} else if !isUsage(err) {
+		t.Errorf("gg branch --verify foo: %v; want usage error", err)
+	}
+}
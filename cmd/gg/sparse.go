@@ -0,0 +1,208 @@
+// Copyright 2021 The gg Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"gg-scm.io/pkg/git"
+	"gg-scm.io/tool/internal/flag"
+)
+
+const sparseSynopsis = "check out only some directories"
+
+// sparseCheckoutVersion is the first version of Git with the
+// sparse-checkout command.
+var sparseCheckoutVersion = gitVersion{2, 25, 0}
+
+// sparseIndexVersion is the first version of Git that can keep a sparse
+// index, which only lists the directories outside of a sparse checkout.
+var sparseIndexVersion = gitVersion{2, 32, 0}
+
+func sparse(ctx context.Context, cc *cmdContext, args []string) error {
+	f := flag.NewFlagSet(true, "gg sparse [--list]\n"+
+		"gg sparse [--include DIR [...]] [--exclude DIR [...]]\n"+
+		"gg sparse --reset", sparseSynopsis+`
+
+	A sparse checkout only has the files in the top directory and the
+	chosen directories (and everything below them) in the working copy,
+	which makes working in a large repository faster. Other files are
+	still tracked and committed as usual, but are not shown by `+"`gg status`"+`.
+
+	`+"`--include`"+` adds directories to the checkout, turning on sparse
+	checkout if needed. On Git 2.32 and later, the index is made sparse
+	too, which keeps commands fast in large repositories.
+	`+"`--exclude`"+` removes directories that were
+	previously included. `+"`--reset`"+` turns off sparse checkout and
+	checks out every file. With no options, sparse lists the included
+	directories.`)
+	include := f.MultiString("include", "check out the files in `dir`ectory")
+	exclude := f.MultiString("exclude", "stop checking out the files in `dir`ectory")
+	reset := f.Bool("reset", false, "check out all files")
+	list := f.Bool("list", false, "list the included directories")
+	if err := f.Parse(args); flag.IsHelp(err) {
+		f.Help(cc.stdout)
+		return nil
+	} else if err != nil {
+		return usagef("%v", err)
+	}
+	if f.NArg() > 0 {
+		return usagef("sparse takes no arguments")
+	}
+	change := len(*include) > 0 || len(*exclude) > 0
+	if *reset && (change || *list) {
+		return usagef("can't pass --reset with other options")
+	}
+	if *list && change {
+		return usagef("can't pass --list with --include or --exclude")
+	}
+	version, err := queryGitVersion(ctx, cc.git)
+	if err != nil {
+		return err
+	}
+	if version.less(sparseCheckoutVersion) {
+		return fmt.Errorf("sparse requires Git %v or later (found %v)", sparseCheckoutVersion, version)
+	}
+	cfg, err := cc.git.ReadConfig(ctx)
+	if err != nil {
+		return err
+	}
+	if *reset {
+		if enabled, err := cfg.Bool("core.sparseCheckout"); err != nil {
+			return err
+		} else if !enabled {
+			return nil
+		}
+		return cc.interactiveGit(ctx, "sparse-checkout", "disable")
+	}
+	dirs, enabled, err := readSparseDirs(ctx, cc.git, cfg)
+	if err != nil {
+		return err
+	}
+	if !change {
+		if !enabled {
+			fmt.Fprintln(cc.stderr, "gg: not a sparse checkout; all files are checked out")
+			return nil
+		}
+		for _, dir := range dirs {
+			if _, err := fmt.Fprintln(cc.stdout, dir); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	top, err := cc.git.WorkTree(ctx)
+	if err != nil {
+		return err
+	}
+	set := make(map[string]bool)
+	for _, dir := range dirs {
+		set[dir] = true
+	}
+	for _, arg := range *include {
+		dir, err := worktreeRelativePath(cc, top, arg)
+		if err != nil {
+			return err
+		}
+		set[dir.String()] = true
+	}
+	for _, arg := range *exclude {
+		dir, err := worktreeRelativePath(cc, top, arg)
+		if err != nil {
+			return err
+		}
+		if !set[dir.String()] {
+			return fmt.Errorf("can't exclude %s: only directories listed by gg sparse can be excluded", arg)
+		}
+		delete(set, dir.String())
+	}
+	newDirs := make([]string, 0, len(set))
+	for dir := range set {
+		newDirs = append(newDirs, dir)
+	}
+	sort.Strings(newDirs)
+	if !enabled {
+		initArgs := []string{"sparse-checkout", "init", "--cone"}
+		if !version.less(sparseIndexVersion) {
+			initArgs = append(initArgs, "--sparse-index")
+		}
+		if err := cc.git.Run(ctx, initArgs...); err != nil {
+			return err
+		}
+	}
+	// Directories are read from stdin so that names starting with a dash
+	// aren't mistaken for options.
+	return cc.git.Runner().RunGit(ctx, &git.Invocation{
+		Args:   []string{"sparse-checkout", "set", "--stdin"},
+		Dir:    cc.dir,
+		Stdin:  strings.NewReader(strings.Join(newDirs, "\n") + "\n"),
+		Stdout: cc.stdout,
+		Stderr: cc.stderr,
+	})
+}
+
+// readSparseDirs returns the directories included in the working copy's
+// sparse checkout. enabled is false if the working copy is not sparse.
+func readSparseDirs(ctx context.Context, g *git.Git, cfg *git.Config) (dirs []string, enabled bool, err error) {
+	if enabled, err := cfg.Bool("core.sparseCheckout"); err != nil {
+		return nil, false, err
+	} else if !enabled {
+		return nil, false, nil
+	}
+	if cone, err := cfg.Bool("core.sparseCheckoutCone"); err != nil {
+		return nil, true, err
+	} else if !cone {
+		return nil, true, errors.New("sparse checkout is not in cone mode; run gg sparse --reset to start over")
+	}
+	out, err := g.Output(ctx, "sparse-checkout", "list")
+	if err != nil {
+		return nil, true, err
+	}
+	for _, line := range strings.Split(out, "\n") {
+		if line != "" {
+			dirs = append(dirs, line)
+		}
+	}
+	return dirs, true, nil
+}
+
+// isSparseCheckout reports whether the working copy is a sparse checkout.
+func isSparseCheckout(ctx context.Context, g *git.Git) bool {
+	cfg, err := g.ReadConfig(ctx)
+	if err != nil {
+		return false
+	}
+	enabled, err := cfg.Bool("core.sparseCheckout")
+	return err == nil && enabled
+}
+
+// writeSparseBanner prints a notice that the working copy is a sparse
+// checkout, if it is one.
+func writeSparseBanner(w io.Writer, cfg *git.Config) error {
+	if enabled, err := cfg.Bool("core.sparseCheckout"); err != nil || !enabled {
+		return err
+	}
+	_, err := io.WriteString(w, "\n"+
+		"# The working copy only has some directories checked out.\n"+
+		"# To list them:   gg sparse\n"+
+		"# To undo:        gg sparse --reset\n")
+	return err
+}
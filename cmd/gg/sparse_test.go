@@ -0,0 +1,125 @@
+// Copyright 2021 The gg Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"gg-scm.io/tool/internal/filesystem"
+)
+
+func TestSparse(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	env, err := newTestEnv(ctx, t)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v, err := queryGitVersion(ctx, env.git); err != nil {
+		t.Fatal(err)
+	} else if v.less(sparseCheckoutVersion) {
+		t.Skipf("Git %v does not support sparse-checkout", v)
+	}
+	if err := env.initEmptyRepo(ctx, "."); err != nil {
+		t.Fatal(err)
+	}
+	err = env.root.Apply(
+		filesystem.Write("top.txt", "top\n"),
+		filesystem.Write("a/foo.txt", "foo\n"),
+		filesystem.Write("b/bar.txt", "bar\n"),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := env.addFiles(ctx, "top.txt", "a/foo.txt", "b/bar.txt"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := env.newCommit(ctx, "."); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := env.gg(ctx, env.root.String(), "sparse", "--include", "a"); err != nil {
+		t.Fatal(err)
+	}
+	checkExists := func(when string, want map[string]bool) {
+		t.Helper()
+		for name, wantExists := range want {
+			if exists, err := env.root.Exists(name); err != nil {
+				t.Error(err)
+			} else if exists != wantExists {
+				t.Errorf("after %s, exists(%q) = %t; want %t", when, name, exists, wantExists)
+			}
+		}
+	}
+	checkExists("sparse --include a", map[string]bool{
+		"top.txt":   true,
+		"a/foo.txt": true,
+		"b/bar.txt": false,
+	})
+	if out, err := env.gg(ctx, env.root.String(), "sparse"); err != nil {
+		t.Fatal(err)
+	} else if got, want := string(out), "a\n"; got != want {
+		t.Errorf("sparse = %q; want %q", got, want)
+	}
+	if out, err := env.gg(ctx, env.root.String(), "status"); err != nil {
+		t.Fatal(err)
+	} else if len(out) > 0 {
+		t.Errorf("status = %q; want empty", out)
+	}
+	if out, err := env.gg(ctx, env.root.String(), "status", "--verbose"); err != nil {
+		t.Fatal(err)
+	} else if !strings.Contains(string(out), "gg sparse --reset") {
+		t.Errorf("status --verbose = %q; want sparse checkout banner", out)
+	}
+
+	// Commit a change inside the sparse checkout.
+	if err := env.root.Apply(filesystem.Write("a/foo.txt", "changed\n")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := env.gg(ctx, env.root.String(), "commit", "-m", "change foo"); err != nil {
+		t.Fatal(err)
+	}
+	if out, err := env.gg(ctx, env.root.String(), "status"); err != nil {
+		t.Fatal(err)
+	} else if len(out) > 0 {
+		t.Errorf("status after commit = %q; want empty", out)
+	}
+	if _, err := env.gg(ctx, env.root.String(), "commit", "-m", "change bar", "b/bar.txt"); err == nil {
+		t.Error("commit of a file outside the sparse checkout did not return an error")
+	} else if !strings.Contains(err.Error(), "sparse") {
+		t.Errorf("commit of a file outside the sparse checkout returned %q; want it to mention the sparse checkout", err)
+	}
+
+	if _, err := env.gg(ctx, env.root.String(), "sparse", "--include", "b", "--exclude", "a"); err != nil {
+		t.Fatal(err)
+	}
+	checkExists("sparse --include b --exclude a", map[string]bool{
+		"a/foo.txt": false,
+		"b/bar.txt": true,
+	})
+	if _, err := env.gg(ctx, env.root.String(), "sparse", "--exclude", "a"); err == nil {
+		t.Error("sparse --exclude of a directory not included did not return an error")
+	}
+
+	if _, err := env.gg(ctx, env.root.String(), "sparse", "--reset"); err != nil {
+		t.Fatal(err)
+	}
+	checkExists("sparse --reset", map[string]bool{
+		"a/foo.txt": true,
+		"b/bar.txt": true,
+	})
+}
@@ -0,0 +1,430 @@
+// Copyright 2021 The gg Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gg-scm.io/pkg/git"
+	"gg-scm.io/tool/internal/flag"
+)
+
+const stackSynopsis = "send a stack of changes as GitHub pull requests"
+
+func stack(ctx context.Context, cc *cmdContext, args []string) error {
+	if len(args) > 0 && args[0] == "submit" {
+		return stackSubmit(ctx, cc, args[1:])
+	}
+	f := flag.NewFlagSet(true, "gg stack submit [options] [BRANCH]", stackSynopsis+`
+
+	A stack is a series of changes where each one builds on the one
+	before it. `+"`gg stack submit`"+` sends each change in the stack as
+	its own GitHub pull request that merges into the pull request below
+	it, so the changes can be reviewed one at a time.`)
+	if err := f.Parse(args); flag.IsHelp(err) {
+		f.Help(cc.stdout)
+		return nil
+	} else if err != nil {
+		return usagef("%v", err)
+	}
+	if f.NArg() == 0 {
+		return usagef("missing subcommand")
+	}
+	return usagef("unknown subcommand %q", f.Arg(0))
+}
+
+const stackSubmitSynopsis = "create or update one pull request per change in a stack"
+
+// stackStateFilename is the name of the file in the Git common directory
+// where gg records the pull requests it created for stacks. Branches are
+// shared by all working trees, so the state is too.
+const stackStateFilename = "gg-stack"
+
+// stackTableMarker starts the navigation table that gg stack submit adds
+// to the end of pull request descriptions.
+const stackTableMarker = "<!-- gg stack -->"
+
+func stackSubmit(ctx context.Context, cc *cmdContext, args []string) error {
+	f := flag.NewFlagSet(true, "gg stack submit [-n] [--commits] [--draft] [BRANCH]", stackSubmitSynopsis+`
+
+	By default, the stack is made up of the given branch (or the one
+	currently checked out) and the local branches it is stacked on, as
+	determined by `+"`gg requestpull`"+`. With `+"`--commits`"+`, each commit
+	on the branch that is not in its upstream becomes its own change,
+	pushed to branches named `+"`BRANCH-1`"+`, `+"`BRANCH-2`"+`, and so on.
+
+	stack submit pushes every change in the stack, then creates a pull
+	request for each change that doesn't have one yet. Every pull request
+	in the stack is updated to merge into the change below it and to have
+	a title and description inferred from its commits, followed by a
+	table that links to the other pull requests in the stack. Changes are
+	pushed like `+"`gg push -f`"+`: rewritten changes replace the previous
+	versions as long as the branches still match their remote-tracking
+	branches, and protected branches are never overwritten.
+
+	The pull request numbers are recorded in the Git directory so that
+	later runs update the same pull requests. stack submit only supports
+	GitHub.`)
+	commits := f.Bool("commits", false, "send each commit on the branch as its own pull request")
+	draft := f.Bool("draft", false, "create new pull requests as drafts")
+	dryRun := f.Bool("n", false, "print the stack instead of submitting it")
+	f.Alias("n", "dry-run")
+	if err := f.Parse(args); flag.IsHelp(err) {
+		f.Help(cc.stdout)
+		return nil
+	} else if err != nil {
+		return usagef("%v", err)
+	}
+	if f.NArg() > 1 {
+		return usagef("only one branch allowed")
+	}
+	cfg, err := cc.git.ReadConfig(ctx)
+	if err != nil {
+		return err
+	}
+	var entries []*stackEntry
+	if *commits {
+		entries, err = commitStack(ctx, cc, cfg, f.Arg(0))
+	} else {
+		entries, err = branchStack(ctx, cc, cfg, f.Arg(0))
+	}
+	if err != nil {
+		return err
+	}
+	if *dryRun {
+		for i, e := range entries {
+			_, err := fmt.Fprintf(cc.stdout, "%d. %s -> %s: %s\n", i+1, e.head, e.base, e.title)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	target := entries[0].target
+	gh, ok := target.forge.(gitHubForge)
+	if !ok {
+		return errors.New("stack submit only supports GitHub repositories")
+	}
+	token, err := gh.readToken(ctx, cc)
+	if err != nil {
+		return err
+	}
+
+	// Push every change first so that each pull request's base exists.
+	var refspecs []string
+	var dsts []git.Ref
+	for _, e := range entries {
+		if e.headRemote != entries[0].headRemote {
+			return fmt.Errorf("%s and %s push to different remotes", entries[0].head, e.head)
+		}
+		dst := git.BranchRef(e.head)
+		refspecs = append(refspecs, e.commit.String()+":"+dst.String())
+		dsts = append(dsts, dst)
+	}
+	if err := checkForcePushAllowed(ctx, cc.git, dsts); err != nil {
+		return err
+	}
+	err = runPush(ctx, cc, entries[0].headRemote, refspecs, dsts, &pushOptions{force: true})
+	if err != nil {
+		return err
+	}
+
+	commonDir, err := cc.git.CommonDir(ctx)
+	if err != nil {
+		return err
+	}
+	statePath := filepath.Join(commonDir, stackStateFilename)
+	state, err := readStackState(statePath)
+	if err != nil {
+		return err
+	}
+	created := make(map[string]bool)
+	for _, e := range entries {
+		open, err := listOpenPullRequests(ctx, cc.httpClient, listPullRequestsParams{
+			authToken: string(token),
+			apiRoot:   gh.apiRoot,
+			owner:     target.baseOwner,
+			repo:      target.baseRepo,
+			head:      target.headOwner + ":" + e.head,
+		})
+		if err != nil {
+			return err
+		}
+		if pr := matchStackPullRequest(open, target.headOwner, e.head, state[e.head]); pr != nil {
+			e.prNum, e.prURL = pr.Number, pr.HTMLURL
+			continue
+		}
+		e.prNum, e.prURL, err = createPullRequest(ctx, cc.httpClient, pullRequestParams{
+			authToken:  string(token),
+			apiRoot:    gh.apiRoot,
+			baseOwner:  target.baseOwner,
+			baseRepo:   target.baseRepo,
+			baseBranch: e.base,
+			headOwner:  target.headOwner,
+			headRepo:   target.headRepo,
+			headBranch: e.head,
+			title:      e.title,
+			body:       e.body,
+			draft:      *draft,
+		})
+		if err != nil {
+			return err
+		}
+		created[e.head] = true
+		state[e.head] = e.prNum
+	}
+	if err := writeStackState(statePath, state); err != nil {
+		return err
+	}
+	for i, e := range entries {
+		_, err := updatePullRequest(ctx, cc.httpClient, updatePullRequestParams{
+			authToken: string(token),
+			apiRoot:   gh.apiRoot,
+			owner:     target.baseOwner,
+			repo:      target.baseRepo,
+			prNum:     e.prNum,
+			title:     e.title,
+			body:      stackDescription(e.body, entries, i),
+			base:      e.base,
+		})
+		if err != nil {
+			return err
+		}
+		verb := "Updated"
+		if created[e.head] {
+			verb = "Created"
+		}
+		if _, err := fmt.Fprintf(cc.stdout, "%s pull request for %s at %s\n", verb, e.head, e.prURL); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// matchStackPullRequest returns the pull request in open whose head is
+// the given branch of headOwner's repository, or nil if there is none.
+// If several pull requests match, it prefers the one numbered recorded,
+// which gg stack submit created for the branch before.
+func matchStackPullRequest(open []gitHubPullRequest, headOwner, head string, recorded uint64) *gitHubPullRequest {
+	var match *gitHubPullRequest
+	for i := range open {
+		pr := &open[i]
+		// GitHub logins are case-insensitive, but branch names are not.
+		if pr.Head.Ref != head || !strings.EqualFold(pr.Head.Label, headOwner+":"+head) {
+			continue
+		}
+		if match == nil || pr.Number == recorded {
+			match = pr
+		}
+	}
+	return match
+}
+
+// A stackEntry is a single change in a stack.
+type stackEntry struct {
+	target     *pullRequestTarget
+	head       string // branch name in head repository
+	headRemote string
+	commit     git.Hash
+	base       string // branch name in base repository
+	title      string
+	body       string
+
+	prNum uint64
+	prURL string
+}
+
+// branchStack returns the stack of branches that ends with the given
+// branch (or the current branch if branchArg is empty), from the bottom
+// of the stack to the top.
+func branchStack(ctx context.Context, cc *cmdContext, cfg *git.Config, branchArg string) ([]*stackEntry, error) {
+	refs, err := cc.git.ListRefs(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var entries []*stackEntry
+	seen := make(map[string]bool)
+	for {
+		t, err := findPullRequestTarget(ctx, cc, cfg, branchArg, "")
+		if err != nil {
+			return nil, err
+		}
+		if seen[t.branch] {
+			return nil, fmt.Errorf("branch %s is stacked on itself", t.branch)
+		}
+		seen[t.branch] = true
+		headRemote, err := inferPushRepo(cfg, t.branch)
+		if err != nil {
+			return nil, err
+		}
+		title, body, err := inferPullRequestTargetMessage(ctx, cc.git, cfg, t)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", t.branch, err)
+		}
+		entries = append(entries, &stackEntry{
+			target:     t,
+			head:       t.branch,
+			headRemote: headRemote,
+			commit:     refs[git.BranchRef(t.branch)],
+			base:       t.baseBranch,
+			title:      title,
+			body:       body,
+		})
+		next, err := inferStackBase(ctx, cc.git, cfg, t.branch, t.baseRemote)
+		if err != nil {
+			return nil, err
+		}
+		if next == "" {
+			break
+		}
+		branchArg = next
+	}
+	for i, j := 0, len(entries)-1; i < j; i, j = i+1, j-1 {
+		entries[i], entries[j] = entries[j], entries[i]
+	}
+	return entries, nil
+}
+
+// commitStack returns a stack with one change per commit on the given
+// branch (or the current branch if branchArg is empty), from the oldest
+// commit to the newest.
+func commitStack(ctx context.Context, cc *cmdContext, cfg *git.Config, branchArg string) ([]*stackEntry, error) {
+	t, err := findPullRequestTarget(ctx, cc, cfg, branchArg, "")
+	if err != nil {
+		return nil, err
+	}
+	headRemote, err := inferPushRepo(cfg, t.branch)
+	if err != nil {
+		return nil, err
+	}
+	log, err := cc.git.Log(ctx, git.LogOptions{
+		Revs:        []string{t.msgBase + ".." + git.BranchRef(t.branch).String()},
+		Reverse:     true,
+		MaxParents:  1,
+		FirstParent: true,
+	})
+	if err != nil {
+		return nil, err
+	}
+	var entries []*stackEntry
+	base := t.baseBranch
+	for log.Next() {
+		info := log.CommitInfo()
+		head := t.branch + "-" + strconv.Itoa(len(entries)+1)
+		title, body := info.Summary(), ""
+		if i := strings.IndexByte(info.Message, '\n'); i != -1 {
+			body = strings.TrimSpace(info.Message[i+1:])
+		}
+		entries = append(entries, &stackEntry{
+			target:     t,
+			head:       head,
+			headRemote: headRemote,
+			commit:     info.SHA1(),
+			base:       base,
+			title:      title,
+			body:       body,
+		})
+		base = head
+	}
+	if err := log.Close(); err != nil {
+		return nil, err
+	}
+	if len(entries) == 0 {
+		return nil, fmt.Errorf("%s has no commits that are not in %s", t.branch, t.msgBase)
+	}
+	return entries, nil
+}
+
+// stackDescription returns the body of the i'th pull request in the
+// stack followed by a table of the pull requests in the stack. Any table
+// already in body is replaced.
+func stackDescription(body string, entries []*stackEntry, i int) string {
+	if j := strings.Index(body, stackTableMarker); j != -1 {
+		body = body[:j]
+	}
+	sb := new(strings.Builder)
+	if body = strings.TrimSpace(body); body != "" {
+		sb.WriteString(body)
+		sb.WriteString("\n\n")
+	}
+	sb.WriteString(stackTableMarker + "\n")
+	sb.WriteString("| | Stack |\n|---|---|\n")
+	// List the top of the stack first, like a log.
+	for k := len(entries) - 1; k >= 0; k-- {
+		marker := ""
+		if k == i {
+			marker = "→"
+		}
+		fmt.Fprintf(sb, "| %s | #%d |\n", marker, entries[k].prNum)
+	}
+	return sb.String()
+}
+
+// readStackState reads the pull request numbers that gg has recorded for
+// stack branches. If the file does not exist, readStackState returns an
+// empty map.
+func readStackState(path string) (map[string]uint64, error) {
+	state := make(map[string]uint64)
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return state, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read stack state: %w", err)
+	}
+	defer f.Close()
+	s := bufio.NewScanner(f)
+	for s.Scan() {
+		fields := strings.Fields(s.Text())
+		if len(fields) != 2 {
+			continue
+		}
+		n, err := strconv.ParseUint(fields[1], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("read stack state: %s: %w", fields[0], err)
+		}
+		state[fields[0]] = n
+	}
+	if err := s.Err(); err != nil {
+		return nil, fmt.Errorf("read stack state: %w", err)
+	}
+	return state, nil
+}
+
+// writeStackState replaces the stack state file.
+func writeStackState(path string, state map[string]uint64) error {
+	branches := make([]string, 0, len(state))
+	for b := range state {
+		branches = append(branches, b)
+	}
+	sort.Strings(branches)
+	var sb strings.Builder
+	for _, b := range branches {
+		fmt.Fprintf(&sb, "%s %d\n", b, state[b])
+	}
+	if err := ioutil.WriteFile(path, []byte(sb.String()), 0o666); err != nil {
+		return fmt.Errorf("write stack state: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,258 @@
+// Copyright 2021 The gg Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"gg-scm.io/pkg/git"
+	"gg-scm.io/tool/internal/filesystem"
+)
+
+func TestStackSubmit_DryRun(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	env, err := newTestEnv(ctx, t)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := env.initRepoWithHistory(ctx, "origin"); err != nil {
+		t.Fatal(err)
+	}
+	if err := env.git.Run(ctx, "clone", "--quiet", "origin", "local"); err != nil {
+		t.Fatal(err)
+	}
+	localDir := env.root.FromSlash("local")
+	localGit := env.git.WithDir(localDir)
+	for _, b := range []string{"a", "b"} {
+		if _, err := env.gg(ctx, localDir, "branch", b); err != nil {
+			t.Fatal(err)
+		}
+		if err := env.root.Apply(filesystem.Write("local/"+b+".txt", dummyContent)); err != nil {
+			t.Fatal(err)
+		}
+		if err := env.addFiles(ctx, "local/"+b+".txt"); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := env.newCommit(ctx, "local"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := localGit.Run(ctx, "remote", "set-url", "origin", "https://github.com/example/foo.git"); err != nil {
+		t.Fatal(err)
+	}
+
+	out, err := env.gg(ctx, localDir, "stack", "submit", "--dry-run")
+	if err != nil {
+		t.Fatal(err)
+	}
+	const want = "1. a -> main: did stuff\n2. b -> a: did stuff\n"
+	if got := string(out); got != want {
+		t.Errorf("gg stack submit --dry-run output:\n%s\nwant:\n%s", got, want)
+	}
+
+	out, err = env.gg(ctx, localDir, "stack", "submit", "--dry-run", "--commits", "a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := string(out); !strings.HasPrefix(got, "1. a-1 -> main: did stuff\n") {
+		t.Errorf("gg stack submit --dry-run --commits a output:\n%s\nwant a-1 based on main", got)
+	}
+}
+
+func TestStackSubmit(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	env, err := newTestEnv(ctx, t)
+	if err != nil {
+		t.Fatal(err)
+	}
+	const authToken = "xyzzy12345"
+	if err := env.writeGitHubAuth([]byte(authToken + "\n")); err != nil {
+		t.Fatal(err)
+	}
+	api := &fakeGitHubPullRequestAPI{
+		logger:         t,
+		errorer:        t,
+		permittedToken: authToken,
+	}
+	fakeGitHub := httptest.NewServer(api)
+	defer fakeGitHub.Close()
+	fakeGitHubTransport := &http.Transport{
+		DialTLS: func(network, addr string) (net.Conn, error) {
+			hostport := strings.TrimPrefix(fakeGitHub.URL, "http://")
+			return net.Dial("tcp", hostport)
+		},
+	}
+	defer fakeGitHubTransport.CloseIdleConnections()
+	env.roundTripper = fakeGitHubTransport
+
+	if err := env.initRepoWithHistory(ctx, "origin"); err != nil {
+		t.Fatal(err)
+	}
+	if err := env.git.Run(ctx, "clone", "--quiet", "origin", "local"); err != nil {
+		t.Fatal(err)
+	}
+	localDir := env.root.FromSlash("local")
+	localGit := env.git.WithDir(localDir)
+	for _, b := range []string{"a", "b"} {
+		if _, err := env.gg(ctx, localDir, "branch", b); err != nil {
+			t.Fatal(err)
+		}
+		if err := env.root.Apply(filesystem.Write("local/"+b+".txt", dummyContent)); err != nil {
+			t.Fatal(err)
+		}
+		if err := env.addFiles(ctx, "local/"+b+".txt"); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := env.newCommit(ctx, "local"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	const remoteURL = "https://github.com/example/foo.git"
+	if err := localGit.Run(ctx, "remote", "set-url", "origin", remoteURL); err != nil {
+		t.Fatal(err)
+	}
+	if err := localGit.Run(ctx, "config", "url."+env.root.FromSlash("origin")+".insteadOf", remoteURL); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := env.gg(ctx, localDir, "stack", "submit"); err != nil {
+		t.Fatal(err)
+	}
+	originGit := env.git.WithDir(env.root.FromSlash("origin"))
+	for _, b := range []string{"a", "b"} {
+		want, err := localGit.ParseRev(ctx, git.BranchRef(b).String())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got, err := originGit.ParseRev(ctx, git.BranchRef(b).String()); err != nil {
+			t.Error(err)
+		} else if got.Commit != want.Commit {
+			t.Errorf("origin %s = %v; want %v", b, got.Commit, want.Commit)
+		}
+	}
+	api.mu.Lock()
+	prs := append([]fakePullRequest(nil), api.prs...)
+	api.mu.Unlock()
+	if len(prs) != 2 {
+		t.Fatalf("created %d pull requests; want 2", len(prs))
+	}
+	for i, want := range []struct{ head, base string }{{"a", "main"}, {"b", "a"}} {
+		if prs[i].headOwner != "example" || prs[i].headRef != want.head || prs[i].baseRef != want.base {
+			t.Errorf("pull request #%d = %s:%s -> %s; want example:%s -> %s",
+				prs[i].num, prs[i].headOwner, prs[i].headRef, prs[i].baseRef, want.head, want.base)
+		}
+	}
+
+	// Submitting again updates the same pull requests.
+	out, err := env.gg(ctx, localDir, "stack", "submit")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(out), "Updated pull request for b") {
+		t.Errorf("second gg stack submit output:\n%s\nwant to update pull request for b", out)
+	}
+	api.mu.Lock()
+	n := len(api.prs)
+	api.mu.Unlock()
+	if n != 2 {
+		t.Errorf("after second gg stack submit, there are %d pull requests; want 2", n)
+	}
+
+	// Someone else moves b, so the lease on it fails.
+	if err := originGit.Run(ctx, "branch", "--force", "b", "main"); err != nil {
+		t.Fatal(err)
+	}
+	if err := env.root.Apply(filesystem.Write("local/b.txt", "rewritten\n")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := env.gg(ctx, localDir, "commit", "--amend", "-m", "rewritten"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := env.gg(ctx, localDir, "stack", "submit"); err == nil {
+		t.Error("gg stack submit overwrote a branch that changed on the remote")
+	} else if !strings.Contains(err.Error(), "has changed since it was last pulled") {
+		t.Errorf("gg stack submit error = %v; want stale lease error", err)
+	}
+}
+
+func TestMatchStackPullRequest(t *testing.T) {
+	newPR := func(num uint64, label string) gitHubPullRequest {
+		pr := gitHubPullRequest{Number: num}
+		pr.Head.Label = label
+		pr.Head.Ref = label[strings.IndexByte(label, ':')+1:]
+		return pr
+	}
+	tests := []struct {
+		name     string
+		open     []gitHubPullRequest
+		recorded uint64
+		want     uint64
+	}{
+		{
+			name: "Empty",
+			want: 0,
+		},
+		{
+			name: "Single",
+			open: []gitHubPullRequest{newPR(3, "example:feature")},
+			want: 3,
+		},
+		{
+			name: "OwnerCase",
+			open: []gitHubPullRequest{newPR(3, "Example:feature")},
+			want: 3,
+		},
+		{
+			name: "OtherOwner",
+			open: []gitHubPullRequest{newPR(3, "someone:feature")},
+			want: 0,
+		},
+		{
+			name: "OtherBranch",
+			open: []gitHubPullRequest{newPR(3, "example:Feature")},
+			want: 0,
+		},
+		{
+			name:     "PrefersRecorded",
+			open:     []gitHubPullRequest{newPR(3, "example:feature"), newPR(5, "example:feature")},
+			recorded: 5,
+			want:     5,
+		},
+		{
+			name:     "RecordedFromOtherOwner",
+			open:     []gitHubPullRequest{newPR(3, "example:feature"), newPR(5, "someone:feature")},
+			recorded: 5,
+			want:     3,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var got uint64
+			if pr := matchStackPullRequest(test.open, "example", "feature", test.recorded); pr != nil {
+				got = pr.Number
+			}
+			if got != test.want {
+				t.Errorf("matchStackPullRequest(...) = #%d; want #%d", got, test.want)
+			}
+		})
+	}
+}
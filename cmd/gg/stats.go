@@ -0,0 +1,269 @@
+// Copyright 2021 The gg Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gg-scm.io/pkg/git"
+	"gg-scm.io/tool/internal/flag"
+)
+
+const statsSynopsis = "show repository size statistics"
+
+func stats(ctx context.Context, cc *cmdContext, args []string) error {
+	f := flag.NewFlagSet(true, "gg stats [--top=N]", statsSynopsis+`
+
+	Reports the number and size of objects in the repository, the size
+	of each pack file, the number of refs, and whether the index and
+	commit-graph files are present.
+
+	`+"`--top`"+` additionally lists the N largest files in any commit
+	reachable from a ref, which is useful for finding what is making a
+	repository large. This reads every object in the repository, so it
+	may be slow on large repositories.`)
+	top := f.Int("top", 0, "list the `N` largest files in history")
+	if err := f.Parse(args); flag.IsHelp(err) {
+		f.Help(cc.stdout)
+		return nil
+	} else if err != nil {
+		return usagef("%v", err)
+	}
+	if f.NArg() > 0 {
+		return usagef("stats takes no arguments")
+	}
+	if *top < 0 {
+		return usagef("--top must be non-negative")
+	}
+	gitDir, err := cc.git.GitDir(ctx)
+	if err != nil {
+		return err
+	}
+	// Objects are shared by all of the repository's working trees, but
+	// each one has its own index.
+	commonDir, err := cc.git.CommonDir(ctx)
+	if err != nil {
+		return err
+	}
+
+	// Objects
+	counts, err := countObjects(ctx, cc.git)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(cc.stdout, "objects:      %d loose (%s), %d packed in %d packs (%s)\n",
+		counts["count"], formatSize(counts["size"]*1024),
+		counts["in-pack"], counts["packs"], formatSize(counts["size-pack"]*1024))
+	if err != nil {
+		return err
+	}
+	if counts["garbage"] > 0 {
+		if _, err := fmt.Fprintf(cc.stdout, "garbage:      %d files (%s)\n", counts["garbage"], formatSize(counts["size-garbage"]*1024)); err != nil {
+			return err
+		}
+	}
+
+	// Refs
+	refs, err := cc.git.ListRefs(ctx)
+	if err != nil {
+		return err
+	}
+	var nbranches, ntags, nother int
+	for ref := range refs {
+		switch {
+		case ref.IsBranch():
+			nbranches++
+		case ref.IsTag():
+			ntags++
+		default:
+			nother++
+		}
+	}
+	if _, err := fmt.Fprintf(cc.stdout, "refs:         %d branches, %d tags, %d other\n", nbranches, ntags, nother); err != nil {
+		return err
+	}
+
+	// Auxiliary files
+	if _, err := fmt.Fprintf(cc.stdout, "index:        %s\n", presence(filepath.Join(gitDir, "index"))); err != nil {
+		return err
+	}
+	commitGraph := presence(filepath.Join(commonDir, "objects", "info", "commit-graph"))
+	if commitGraph == "missing" {
+		commitGraph = presence(filepath.Join(commonDir, "objects", "info", "commit-graphs"))
+	}
+	if _, err := fmt.Fprintf(cc.stdout, "commit-graph: %s\n", commitGraph); err != nil {
+		return err
+	}
+
+	// Packs
+	packs, err := filepath.Glob(filepath.Join(commonDir, "objects", "pack", "*.pack"))
+	if err != nil {
+		return err
+	}
+	if len(packs) > 0 {
+		if _, err := fmt.Fprintln(cc.stdout, "packs:"); err != nil {
+			return err
+		}
+		for _, pack := range packs {
+			info, err := os.Stat(pack)
+			if err != nil {
+				return err
+			}
+			if _, err := fmt.Fprintf(cc.stdout, "  %10s  %s\n", formatSize(info.Size()), filepath.Base(pack)); err != nil {
+				return err
+			}
+		}
+	}
+
+	if *top == 0 {
+		return nil
+	}
+	blobs, err := largestBlobs(ctx, cc.git, *top)
+	if err != nil {
+		return err
+	}
+	if len(blobs) > 0 {
+		if _, err := fmt.Fprintln(cc.stdout, "largest files:"); err != nil {
+			return err
+		}
+		for _, b := range blobs {
+			if _, err := fmt.Fprintf(cc.stdout, "  %10s  %s (%v)\n", formatSize(b.size), b.path, b.hash.Short()); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// countObjects returns the fields reported by `git count-objects -v`.
+// Sizes are in KiB.
+func countObjects(ctx context.Context, g *git.Git) (map[string]int64, error) {
+	out, err := g.Output(ctx, "count-objects", "-v")
+	if err != nil {
+		return nil, err
+	}
+	counts := make(map[string]int64)
+	for _, line := range strings.Split(out, "\n") {
+		i := strings.Index(line, ": ")
+		if i == -1 {
+			continue
+		}
+		n, err := strconv.ParseInt(line[i+2:], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("count objects: parse %q: %w", line, err)
+		}
+		counts[line[:i]] = n
+	}
+	return counts, nil
+}
+
+// blobInfo describes a file in the repository's history.
+type blobInfo struct {
+	hash git.Hash
+	size int64
+	path string
+}
+
+// largestBlobs returns the n largest blobs reachable from any ref along
+// with a path that they were found at, largest first.
+func largestBlobs(ctx context.Context, g *git.Git, n int) ([]blobInfo, error) {
+	out, err := g.Output(ctx, "cat-file", "--batch-all-objects", "--batch-check=%(objecttype) %(objectname) %(objectsize)")
+	if err != nil {
+		return nil, fmt.Errorf("find largest files: %w", err)
+	}
+	var blobs []blobInfo
+	for _, line := range strings.Split(out, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) != 3 || fields[0] != "blob" {
+			continue
+		}
+		h, err := git.ParseHash(fields[1])
+		if err != nil {
+			return nil, fmt.Errorf("find largest files: %w", err)
+		}
+		size, err := strconv.ParseInt(fields[2], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("find largest files: %w", err)
+		}
+		blobs = append(blobs, blobInfo{hash: h, size: size})
+	}
+	sort.Slice(blobs, func(i, j int) bool {
+		return blobs[i].size > blobs[j].size
+	})
+
+	// Find paths for blobs that are reachable. Unreachable blobs are
+	// skipped, since they will be removed by garbage collection.
+	out, err = g.Output(ctx, "rev-list", "--objects", "--all")
+	if err != nil {
+		return nil, fmt.Errorf("find largest files: %w", err)
+	}
+	paths := make(map[git.Hash]string)
+	for _, line := range strings.Split(out, "\n") {
+		i := strings.IndexByte(line, ' ')
+		if i == -1 {
+			continue
+		}
+		h, err := git.ParseHash(line[:i])
+		if err != nil {
+			continue
+		}
+		if _, exists := paths[h]; !exists {
+			paths[h] = line[i+1:]
+		}
+	}
+	result := make([]blobInfo, 0, n)
+	for _, b := range blobs {
+		if len(result) >= n {
+			break
+		}
+		path, ok := paths[b.hash]
+		if !ok {
+			continue
+		}
+		b.path = path
+		result = append(result, b)
+	}
+	return result, nil
+}
+
+// presence returns "present" if the file exists or "missing" otherwise.
+func presence(path string) string {
+	if _, err := os.Stat(path); err != nil {
+		return "missing"
+	}
+	return "present"
+}
+
+// formatSize formats a number of bytes using binary units.
+func formatSize(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	value := float64(n) / unit
+	for _, suffix := range []string{"KiB", "MiB", "GiB"} {
+		if value < unit {
+			return fmt.Sprintf("%.1f %s", value, suffix)
+		}
+		value /= unit
+	}
+	return fmt.Sprintf("%.1f TiB", value)
+}
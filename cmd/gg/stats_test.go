@@ -0,0 +1,82 @@
+// Copyright 2021 The gg Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"gg-scm.io/tool/internal/filesystem"
+)
+
+func TestStats(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	env, err := newTestEnv(ctx, t)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := env.initRepoWithHistory(ctx, "."); err != nil {
+		t.Fatal(err)
+	}
+	err = env.root.Apply(
+		filesystem.Write("big.bin", strings.Repeat("x", 4096)),
+		filesystem.Write("small.txt", "hi\n"),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := env.addFiles(ctx, "big.bin", "small.txt"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := env.newCommit(ctx, "."); err != nil {
+		t.Fatal(err)
+	}
+
+	out, err := env.gg(ctx, env.root.String(), "stats", "--top=1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"objects:", "refs:         1 branches", "index:        present", "largest files:\n     4.0 KiB  big.bin ("} {
+		if !bytes.Contains(out, []byte(want)) {
+			t.Errorf("output does not contain %q. Output:\n%s", want, out)
+		}
+	}
+	if bytes.Contains(out, []byte("small.txt")) {
+		t.Errorf("output lists small.txt with --top=1. Output:\n%s", out)
+	}
+}
+
+func TestFormatSize(t *testing.T) {
+	tests := []struct {
+		n    int64
+		want string
+	}{
+		{0, "0 B"},
+		{1023, "1023 B"},
+		{1024, "1.0 KiB"},
+		{1536, "1.5 KiB"},
+		{5 << 20, "5.0 MiB"},
+		{3 << 30, "3.0 GiB"},
+		{2 << 40, "2.0 TiB"},
+	}
+	for _, test := range tests {
+		if got := formatSize(test.n); got != test.want {
+			t.Errorf("formatSize(%d) = %q; want %q", test.n, got, test.want)
+		}
+	}
+}
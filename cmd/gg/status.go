@@ -16,8 +16,15 @@ package main
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode/utf8"
 
 	"gg-scm.io/pkg/git"
 	"gg-scm.io/tool/internal/flag"
@@ -27,15 +34,69 @@ import (
 const statusSynopsis = "show changed files in the working directory"
 
 func status(ctx context.Context, cc *cmdContext, args []string) error {
-	f := flag.NewFlagSet(true, "gg status [FILE [...]]", statusSynopsis+`
+	f := flag.NewFlagSet(true, "gg status [-v | --json] [-0] [--terse] [--rev REV1 [--rev REV2]] [FILE [...]]", statusSynopsis+`
+
+	When writing to a terminal that supports hyperlinks, file names link
+	to the files on disk. Set `+"`gg.hyperlinks`"+` to true or false in the
+	Git configuration to override the detection.
+
+	`+"`--json`"+` prints a JSON array with an object for each changed file.
+	Each object has the file's name, its status, the file it was copied or
+	renamed from, whether it has staged and unstaged changes, Git's
+	two-letter status code, and whether it is a submodule. `+"`-0`"+`
+	prints the usual output without color, terminating each line with a
+	NUL byte instead of a newline.
+
+	With `+"`--rev`"+`, status shows the files changed between REV1 and the
+	working copy, or between REV1 and REV2 if `+"`--rev`"+` is given twice.
+	`+"`--terse`"+` shows a directory that contains only untracked files as
+	a single line. `+"`--verbose`"+` also notes when the working copy is a
+	sparse checkout (see `+"`gg sparse`"+`).
+
+	`+filePatternHelp+`
 
 aliases: st, check`)
+	verbose := f.Bool("verbose", false, "show the number of lines added and removed in each file")
+	f.Alias("verbose", "v")
+	jsonFlag := f.Bool("json", false, "print status as JSON")
+	nul := f.Bool("0", false, "end each line with a NUL byte instead of a newline")
+	f.Alias("0", "print0")
+	var rev revFlag
+	f.Var(&rev, "rev", "show changes since `rev`ision")
+	terse := f.Bool("terse", false, "show directories of untracked files as a single entry")
 	if err := f.Parse(args); flag.IsHelp(err) {
 		f.Help(cc.stdout)
 		return nil
 	} else if err != nil {
 		return usagef("%v", err)
 	}
+	if *jsonFlag && *verbose {
+		return usagef("can't pass both --json and --verbose")
+	}
+	if *jsonFlag && *nul {
+		return usagef("can't pass both --json and -0")
+	}
+	if rev.r1 != "" && *jsonFlag {
+		return usagef("can't pass both --json and --rev")
+	}
+	if rev.r1 != "" && *verbose {
+		return usagef("can't pass both --verbose and --rev")
+	}
+	fileArgs, err := expandFilePatterns(ctx, cc, f.Args())
+	if err != nil {
+		return err
+	}
+	pathspecs := make([]git.Pathspec, 0, f.NArg())
+	for _, arg := range fileArgs.paths {
+		pathspecs = append(pathspecs, git.Pathspec(arg))
+	}
+	for _, name := range fileArgs.matches {
+		pathspecs = append(pathspecs, git.LiteralPath(name))
+	}
+	pathspecs = append(pathspecs, fileArgs.globs...)
+	if *jsonFlag {
+		return statusJSON(ctx, cc, pathspecs)
+	}
 	var (
 		addedColor     []byte
 		modifiedColor  []byte
@@ -48,7 +109,7 @@ aliases: st, check`)
 	if err != nil {
 		return err
 	}
-	colorize, err := cfg.ColorBool("color.ggstatus", terminal.IsTerminal(cc.stdout))
+	colorize, err := cfg.ColorBool("color.ggstatus", cc.isTerminal() && !*nul)
 	if err != nil {
 		fmt.Fprintln(cc.stderr, "gg:", err)
 	} else if colorize {
@@ -77,13 +138,86 @@ aliases: st, check`)
 			fmt.Fprintln(cc.stderr, "gg:", err)
 		}
 	}
-	pathspecs := make([]git.Pathspec, f.NArg())
-	for i, arg := range f.Args() {
-		pathspecs[i] = git.Pathspec(arg)
+	links := cc.supportsHyperlinks()
+	if cfg.Value("gg.hyperlinks") != "" {
+		links, err = cfg.Bool("gg.hyperlinks")
+		if err != nil {
+			fmt.Fprintln(cc.stderr, "gg:", err)
+		}
+	}
+	if *nul {
+		links = false
+	}
+	p := &statusPrinter{w: cc.stdout, eol: "\n"}
+	if *nul {
+		p.eol = "\x00"
+	}
+	if links {
+		p.top, err = cc.git.WorkTree(ctx)
+		if err != nil {
+			return err
+		}
+		p.host, _ = os.Hostname()
+	}
+	if rev.r1 != "" {
+		diffStatus, err := cc.git.DiffStatus(ctx, git.DiffStatusOptions{
+			Commit1:        rev.r1,
+			Commit2:        rev.r2,
+			Pathspecs:      pathspecs,
+			DisableRenames: true,
+		})
+		if err != nil {
+			return err
+		}
+		if colorize {
+			if err := terminal.ResetTextStyle(cc.stdout); err != nil {
+				return err
+			}
+		}
+		for _, ent := range diffStatus {
+			switch ent.Code {
+			case git.DiffStatusAdded:
+				err = p.entry(addedColor, 'A', ent.Name)
+			case git.DiffStatusDeleted:
+				err = p.entry(removedColor, 'R', ent.Name)
+			case git.DiffStatusModified, git.DiffStatusChangedMode:
+				err = p.entry(modifiedColor, 'M', ent.Name)
+			case git.DiffStatusUnmerged:
+				err = p.entry(unmergedColor, 'U', ent.Name)
+			default:
+				return fmt.Errorf("unrecognized status for %s: '%v'", ent.Name, ent.Code)
+			}
+			if err != nil {
+				return err
+			}
+			if colorize {
+				if err := terminal.ResetTextStyle(cc.stdout); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
 	}
 	st, statusErr := cc.git.Status(ctx, git.StatusOptions{
 		Pathspecs: pathspecs,
 	})
+	if *terse && statusErr == nil {
+		st, err = terseUntracked(ctx, cc.git, st, pathspecs)
+		if err != nil {
+			return err
+		}
+	}
+	if *verbose {
+		p.stats, err = diffLineCounts(ctx, cc.git, pathspecs)
+		if err != nil {
+			return err
+		}
+		for _, ent := range st {
+			if n := utf8.RuneCountInString(string(ent.Name)); n > p.width {
+				p.width = n
+			}
+		}
+	}
 	if colorize {
 		if err := terminal.ResetTextStyle(cc.stdout); err != nil {
 			return err
@@ -94,7 +228,7 @@ aliases: st, check`)
 	for _, ent := range st {
 		switch {
 		case ent.Code.IsModified():
-			_, err = fmt.Fprintf(cc.stdout, "%sM %s\n", modifiedColor, ent.Name)
+			err = p.entry(modifiedColor, 'M', ent.Name)
 		case ent.Code.IsAdded():
 			name := ent.Name
 			if name == "" {
@@ -102,7 +236,7 @@ aliases: st, check`)
 				name = "???"
 				hitRenameBug = true
 			}
-			_, err = fmt.Fprintf(cc.stdout, "%sA %s\n", addedColor, name)
+			err = p.entry(addedColor, 'A', name)
 			if ent.Code.IsOriginalMissing() {
 				// See https://github.com/gg-scm/gg/issues/44 for explanation.
 				if colorize {
@@ -110,12 +244,12 @@ aliases: st, check`)
 						return err
 					}
 				}
-				_, err = fmt.Fprintf(cc.stdout, "%s! %s\n", missingColor, ent.From)
+				err = p.entry(missingColor, '!', ent.From)
 			}
 		case ent.Code.IsRemoved():
-			_, err = fmt.Fprintf(cc.stdout, "%sR %s\n", removedColor, ent.Name)
+			err = p.entry(removedColor, 'R', ent.Name)
 		case ent.Code.IsCopied():
-			if _, err := fmt.Fprintf(cc.stdout, "%sA %s\n", addedColor, ent.Name); err != nil {
+			if err := p.entry(addedColor, 'A', ent.Name); err != nil {
 				return err
 			}
 			if colorize {
@@ -123,21 +257,24 @@ aliases: st, check`)
 					return err
 				}
 			}
-			_, err = fmt.Fprintf(cc.stdout, "  %s\n", ent.From)
+			err = p.source(ent.From)
 		case ent.Code.IsRenamed():
-			fmt.Fprintf(cc.stdout, "%sA %s\n", addedColor, ent.Name)
+			p.entry(addedColor, 'A', ent.Name)
 			if colorize {
 				if err := terminal.ResetTextStyle(cc.stdout); err != nil {
 					return err
 				}
 			}
-			_, err = fmt.Fprintf(cc.stdout, "  %s\n%sR %s\n", ent.From, removedColor, ent.From)
+			if err := p.source(ent.From); err != nil {
+				return err
+			}
+			err = p.entry(removedColor, 'R', ent.From)
 		case ent.Code.IsMissing():
-			_, err = fmt.Fprintf(cc.stdout, "%s! %s\n", missingColor, ent.Name)
+			err = p.entry(missingColor, '!', ent.Name)
 		case ent.Code.IsUntracked():
-			_, err = fmt.Fprintf(cc.stdout, "%s? %s\n", untrackedColor, ent.Name)
+			err = p.entry(untrackedColor, '?', ent.Name)
 		case ent.Code.IsUnmerged():
-			_, err = fmt.Fprintf(cc.stdout, "%sU %s\n", unmergedColor, ent.Name)
+			err = p.entry(unmergedColor, 'U', ent.Name)
 		default:
 			fmt.Fprintf(cc.stderr, "gg: unrecognized status for %s: '%v'\n", ent.Name, ent.Code)
 			foundUnrecognized = true
@@ -160,5 +297,252 @@ aliases: st, check`)
 	if statusErr != nil {
 		return statusErr
 	}
+	if !*nul {
+		op, err := readWorkTreeOperation(ctx, cc.git)
+		if err != nil {
+			return err
+		}
+		if err := writeOperationBanner(cc.stdout, op); err != nil {
+			return err
+		}
+		if *verbose {
+			if err := writeSparseBanner(cc.stdout, cfg); err != nil {
+				return err
+			}
+		}
+	}
 	return nil
 }
+
+// statusPrinter formats status entries.
+type statusPrinter struct {
+	w io.Writer
+
+	// If top is not empty, then file names are hyperlinked to their
+	// location in the working copy at top on host.
+	top  string
+	host string
+
+	// If stats is not nil, then lines are followed by the line counts in
+	// stats, aligned to a column after width characters of file name.
+	stats map[git.TopPath]string
+	width int
+
+	// eol is the string written at the end of each line.
+	eol string
+}
+
+// entry writes a single status line.
+func (p *statusPrinter) entry(color []byte, code byte, name git.TopPath) error {
+	text := p.link(name)
+	if s, ok := p.stats[name]; ok {
+		pad := p.width - utf8.RuneCountInString(string(name))
+		if pad < 0 {
+			pad = 0
+		}
+		text += strings.Repeat(" ", pad) + "  " + s
+	}
+	_, err := fmt.Fprintf(p.w, "%s%c %s%s", color, code, text, p.eol)
+	return err
+}
+
+// source writes the line naming the file that an entry was copied or
+// renamed from.
+func (p *statusPrinter) source(from git.TopPath) error {
+	_, err := fmt.Fprintf(p.w, "  %s%s", p.link(from), p.eol)
+	return err
+}
+
+// link returns the name to display for the given file.
+func (p *statusPrinter) link(name git.TopPath) string {
+	if p.top == "" || name == "???" {
+		return string(name)
+	}
+	u := &url.URL{
+		Scheme: "file",
+		Host:   p.host,
+		Path:   filepath.ToSlash(filepath.Join(p.top, filepath.FromSlash(string(name)))),
+	}
+	if !strings.HasPrefix(u.Path, "/") {
+		// Windows drive letter paths.
+		u.Path = "/" + u.Path
+	}
+	return terminal.Hyperlink(u.String(), string(name))
+}
+
+// emptyTreeHash is the hash of the tree object with no entries.
+const emptyTreeHash = "4b825dc642cb6eb9a060e54bf8d69288fbee4904"
+
+// diffLineCounts returns a summary of the number of lines added and
+// removed in each tracked file since HEAD.
+func diffLineCounts(ctx context.Context, g *git.Git, pathspecs []git.Pathspec) (map[git.TopPath]string, error) {
+	base := git.Head.String()
+	if _, err := g.Head(ctx); err != nil {
+		// No commits yet: everything is an addition.
+		base = emptyTreeHash
+	}
+	args := []string{"diff", "--numstat", "-z", "--no-renames", base, "--"}
+	for _, p := range pathspecs {
+		args = append(args, p.String())
+	}
+	out, err := g.Output(ctx, args...)
+	if err != nil {
+		return nil, fmt.Errorf("count changed lines: %w", err)
+	}
+	stats := make(map[git.TopPath]string)
+	for _, rec := range strings.Split(out, "\x00") {
+		if rec == "" {
+			continue
+		}
+		fields := strings.SplitN(rec, "\t", 3)
+		if len(fields) != 3 {
+			return nil, fmt.Errorf("count changed lines: unexpected record %q", rec)
+		}
+		if fields[0] == "-" {
+			stats[git.TopPath(fields[2])] = "binary"
+			continue
+		}
+		stats[git.TopPath(fields[2])] = "+" + fields[0] + " -" + fields[1]
+	}
+	return stats, nil
+}
+
+// terseUntracked replaces the untracked entries in st that are inside
+// directories containing only untracked files with a single entry for the
+// outermost such directory. The directory's name ends in a slash.
+func terseUntracked(ctx context.Context, g *git.Git, st []git.StatusEntry, pathspecs []git.Pathspec) ([]git.StatusEntry, error) {
+	args := []string{"ls-files", "--others", "--exclude-standard", "--directory", "--no-empty-directory", "--full-name", "-z", "--"}
+	for _, p := range pathspecs {
+		args = append(args, p.String())
+	}
+	out, err := g.Output(ctx, args...)
+	if err != nil {
+		return nil, fmt.Errorf("list untracked directories: %w", err)
+	}
+	var dirs []string
+	for _, name := range strings.Split(out, "\x00") {
+		if strings.HasSuffix(name, "/") {
+			dirs = append(dirs, name)
+		}
+	}
+	if len(dirs) == 0 {
+		return st, nil
+	}
+	var result []git.StatusEntry
+	added := make(map[string]bool)
+	for _, ent := range st {
+		if !ent.Code.IsUntracked() {
+			result = append(result, ent)
+			continue
+		}
+		dir := ""
+		for _, d := range dirs {
+			if strings.HasPrefix(string(ent.Name), d) {
+				dir = d
+				break
+			}
+		}
+		switch {
+		case dir == "":
+			result = append(result, ent)
+		case !added[dir]:
+			added[dir] = true
+			result = append(result, git.StatusEntry{
+				Code: ent.Code,
+				Name: git.TopPath(dir),
+			})
+		}
+	}
+	return result, nil
+}
+
+// statusJSONEntry is the JSON representation of a file in the output of
+// status --json.
+type statusJSONEntry struct {
+	Name      git.TopPath `json:"name"`
+	Status    string      `json:"status"`
+	From      git.TopPath `json:"from,omitempty"`
+	Staged    bool        `json:"staged"`
+	Unstaged  bool        `json:"unstaged"`
+	Code      string      `json:"code"`
+	Submodule bool        `json:"submodule,omitempty"`
+}
+
+// statusJSON writes the output of status --json.
+func statusJSON(ctx context.Context, cc *cmdContext, pathspecs []git.Pathspec) error {
+	st, err := cc.git.Status(ctx, git.StatusOptions{
+		Pathspecs: pathspecs,
+	})
+	if err != nil {
+		return err
+	}
+	submodules, err := submodulePaths(ctx, cc.git, pathspecs)
+	if err != nil {
+		return err
+	}
+	entries := make([]statusJSONEntry, 0, len(st))
+	for _, ent := range st {
+		jsonEnt := statusJSONEntry{
+			Name:      ent.Name,
+			From:      ent.From,
+			Staged:    ent.Code[0] != ' ' && ent.Code[0] != '?' && ent.Code[0] != '!',
+			Unstaged:  ent.Code[1] != ' ' && ent.Code[1] != '!',
+			Code:      string(ent.Code[:]),
+			Submodule: submodules[ent.Name],
+		}
+		switch {
+		case ent.Code.IsModified():
+			jsonEnt.Status = "modified"
+		case ent.Code.IsAdded():
+			jsonEnt.Status = "added"
+		case ent.Code.IsRemoved():
+			jsonEnt.Status = "removed"
+		case ent.Code.IsCopied():
+			jsonEnt.Status = "copied"
+		case ent.Code.IsRenamed():
+			jsonEnt.Status = "renamed"
+		case ent.Code.IsMissing():
+			jsonEnt.Status = "missing"
+		case ent.Code.IsUntracked():
+			jsonEnt.Status = "untracked"
+		case ent.Code.IsUnmerged():
+			jsonEnt.Status = "unmerged"
+		default:
+			return fmt.Errorf("unrecognized status for %s: '%v'", ent.Name, ent.Code)
+		}
+		entries = append(entries, jsonEnt)
+	}
+	out, err := json.MarshalIndent(entries, "", "\t")
+	if err != nil {
+		return err
+	}
+	out = append(out, '\n')
+	_, err = cc.stdout.Write(out)
+	return err
+}
+
+// submodulePaths returns the set of paths in the index matching the
+// pathspecs that are submodules.
+func submodulePaths(ctx context.Context, g *git.Git, pathspecs []git.Pathspec) (map[git.TopPath]bool, error) {
+	args := []string{"ls-files", "--stage", "--full-name", "-z", "--"}
+	for _, p := range pathspecs {
+		args = append(args, p.String())
+	}
+	out, err := g.Output(ctx, args...)
+	if err != nil {
+		return nil, fmt.Errorf("list submodules: %w", err)
+	}
+	paths := make(map[git.TopPath]bool)
+	for _, rec := range strings.Split(out, "\x00") {
+		// Records are of the form "MODE HASH STAGE\tPATH".
+		if !strings.HasPrefix(rec, "160000 ") {
+			continue
+		}
+		i := strings.IndexByte(rec, '\t')
+		if i == -1 {
+			return nil, fmt.Errorf("list submodules: unexpected record %q", rec)
+		}
+		paths[git.TopPath(rec[i+1:])] = true
+	}
+	return paths, nil
+}
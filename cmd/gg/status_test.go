@@ -17,6 +17,7 @@ package main
 import (
 	"bytes"
 	"context"
+	"encoding/json"
 	"testing"
 
 	"gg-scm.io/pkg/git"
@@ -91,6 +92,243 @@ func TestStatus(t *testing.T) {
 	}
 }
 
+func TestStatus_Verbose(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	env, err := newTestEnv(ctx, t)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := env.initRepoWithHistory(ctx, "."); err != nil {
+		t.Fatal(err)
+	}
+	if err := env.root.Apply(filesystem.Write("a.txt", "1\n")); err != nil {
+		t.Fatal(err)
+	}
+	if err := env.addFiles(ctx, "a.txt"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := env.newCommit(ctx, "."); err != nil {
+		t.Fatal(err)
+	}
+	err = env.root.Apply(
+		filesystem.Write("a.txt", "1\n2\n3\n"),
+		filesystem.Write("longer-name.txt", "x\n"),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := env.trackFiles(ctx, "longer-name.txt"); err != nil {
+		t.Fatal(err)
+	}
+
+	out, err := env.gg(ctx, env.root.String(), "status", "-v")
+	if err != nil {
+		t.Fatal(err)
+	}
+	const want = "M a.txt            +2 -0\n" +
+		"A longer-name.txt  +1 -0\n"
+	if string(out) != want {
+		t.Errorf("output = %q; want %q", out, want)
+	}
+}
+
+func TestStatus_JSON(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	env, err := newTestEnv(ctx, t)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := env.initEmptyRepo(ctx, "."); err != nil {
+		t.Fatal(err)
+	}
+	if err := env.root.Apply(filesystem.Write("modified.txt", "The Larch\n")); err != nil {
+		t.Fatal(err)
+	}
+	if err := env.addFiles(ctx, "modified.txt"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := env.newCommit(ctx, "."); err != nil {
+		t.Fatal(err)
+	}
+	err = env.root.Apply(
+		filesystem.Write("modified.txt", "The Chestnut\n"),
+		filesystem.Write("added.txt", "And now...\n"),
+		filesystem.Write("untracked.txt", "?\n"),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := env.addFiles(ctx, "added.txt"); err != nil {
+		t.Fatal(err)
+	}
+
+	out, err := env.gg(ctx, env.root.String(), "status", "--json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []statusJSONEntry
+	if err := json.Unmarshal(out, &got); err != nil {
+		t.Fatalf("%v; output:\n%s", err, out)
+	}
+	want := []statusJSONEntry{
+		{Name: "added.txt", Status: "added", Staged: true, Code: "A "},
+		{Name: "modified.txt", Status: "modified", Unstaged: true, Code: " M"},
+		{Name: "untracked.txt", Status: "untracked", Unstaged: true, Code: "??"},
+	}
+	diff := cmp.Diff(want, got, cmpopts.SortSlices(func(e1, e2 statusJSONEntry) bool {
+		return e1.Name < e2.Name
+	}))
+	if diff != "" {
+		t.Errorf("Output differs (-want +got):\n%s", diff)
+	}
+
+	out, err = env.gg(ctx, env.root.String(), "status", "-0", "modified.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "M modified.txt\x00"; string(out) != want {
+		t.Errorf("gg status -0 modified.txt = %q; want %q", out, want)
+	}
+}
+
+func TestStatus_Rev(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	env, err := newTestEnv(ctx, t)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := env.initEmptyRepo(ctx, "."); err != nil {
+		t.Fatal(err)
+	}
+	err = env.root.Apply(
+		filesystem.Write("modified.txt", "The Larch\n"),
+		filesystem.Write("deleted.txt", dummyContent),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := env.addFiles(ctx, "modified.txt", "deleted.txt"); err != nil {
+		t.Fatal(err)
+	}
+	rev1, err := env.newCommit(ctx, ".")
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = env.root.Apply(
+		filesystem.Write("modified.txt", "The Chestnut\n"),
+		filesystem.Write("added.txt", "And now...\n"),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := env.addFiles(ctx, "modified.txt", "added.txt"); err != nil {
+		t.Fatal(err)
+	}
+	if err := env.git.Remove(ctx, []git.Pathspec{"deleted.txt"}, git.RemoveOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	rev2, err := env.newCommit(ctx, ".")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := env.root.Apply(filesystem.Write("local.txt", "local\n")); err != nil {
+		t.Fatal(err)
+	}
+	if err := env.addFiles(ctx, "local.txt"); err != nil {
+		t.Fatal(err)
+	}
+
+	out, err := env.gg(ctx, env.root.String(), "status", "--rev", rev1.String(), "--rev", rev2.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	const want = "A added.txt\n" +
+		"R deleted.txt\n" +
+		"M modified.txt\n"
+	if string(out) != want {
+		t.Errorf("gg status --rev REV1 --rev REV2 = %q; want %q", out, want)
+	}
+
+	out, err = env.gg(ctx, env.root.String(), "status", "--rev", rev2.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "A local.txt\n"; string(out) != want {
+		t.Errorf("gg status --rev REV2 = %q; want %q", out, want)
+	}
+}
+
+func TestStatus_Terse(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	env, err := newTestEnv(ctx, t)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := env.initEmptyRepo(ctx, "."); err != nil {
+		t.Fatal(err)
+	}
+	if err := env.root.Apply(filesystem.Write("mixed/tracked.txt", dummyContent)); err != nil {
+		t.Fatal(err)
+	}
+	if err := env.addFiles(ctx, "mixed/tracked.txt"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := env.newCommit(ctx, "."); err != nil {
+		t.Fatal(err)
+	}
+	err = env.root.Apply(
+		filesystem.Write("mixed/untracked.txt", dummyContent),
+		filesystem.Write("new/a.txt", dummyContent),
+		filesystem.Write("new/sub/b.txt", dummyContent),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	out, err := env.gg(ctx, env.root.String(), "status", "--terse")
+	if err != nil {
+		t.Fatal(err)
+	}
+	const want = "? mixed/untracked.txt\n" +
+		"? new/\n"
+	if string(out) != want {
+		t.Errorf("gg status --terse = %q; want %q", out, want)
+	}
+}
+
+func TestStatus_Hyperlinks(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	env, err := newTestEnv(ctx, t)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := env.writeConfig([]byte("[gg]\nhyperlinks = true\n")); err != nil {
+		t.Fatal(err)
+	}
+	if err := env.initRepoWithHistory(ctx, "."); err != nil {
+		t.Fatal(err)
+	}
+	if err := env.root.Apply(filesystem.Write("foo.txt", dummyContent)); err != nil {
+		t.Fatal(err)
+	}
+
+	out, err := env.gg(ctx, env.root.String(), "status")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.HasPrefix(out, []byte("? \x1b]8;;file://")) {
+		t.Errorf("output does not start with a file link. Output:\n%q", out)
+	}
+	if want := "/foo.txt\x1b\\foo.txt\x1b]8;;\x1b\\\n"; !bytes.HasSuffix(out, []byte(want)) {
+		t.Errorf("output does not end with %q. Output:\n%q", want, out)
+	}
+}
+
 // TestStatus_RenamedLocally is a regression test for
 // https://github.com/gg-scm/gg/issues/44.
 func TestStatus_RenamedLocally(t *testing.T) {
@@ -0,0 +1,112 @@
+// Copyright 2021 The gg Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"gg-scm.io/pkg/git"
+)
+
+// submodule describes a submodule of a working copy.
+type submodule struct {
+	// Path is the location of the submodule relative to the top of the
+	// superproject's working copy.
+	Path git.TopPath
+
+	// Commit is the commit checked out in the submodule or the commit
+	// recorded in the superproject's index if the submodule is not
+	// initialized.
+	Commit git.Hash
+
+	State submoduleState
+}
+
+// submoduleState is the one-character prefix that git submodule status
+// uses to describe a submodule's state.
+type submoduleState byte
+
+// Submodule states.
+const (
+	submoduleClean         submoduleState = ' '
+	submoduleUninitialized submoduleState = '-'
+	submoduleModified      submoduleState = '+' // checked out commit differs from index
+	submoduleUnmerged      submoduleState = 'U'
+)
+
+// listSubmodules returns the submodules of the working copy, including
+// nested submodules.
+func listSubmodules(ctx context.Context, g *git.Git) ([]submodule, error) {
+	top, err := g.WorkTree(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("list submodules: %w", err)
+	}
+	// Paths in the output are relative to the working directory.
+	out, err := g.WithDir(top).Output(ctx, "submodule", "status", "--recursive")
+	if err != nil {
+		return nil, fmt.Errorf("list submodules: %w", err)
+	}
+	var subs []submodule
+	for _, line := range strings.Split(out, "\n") {
+		if line == "" {
+			continue
+		}
+		// Lines are of the form "SHASH PATH (DESCRIBE)", where S is the
+		// state character. The describe suffix is omitted for
+		// uninitialized submodules.
+		fields := strings.SplitN(line[1:], " ", 2)
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("list submodules: unexpected line %q", line)
+		}
+		h, err := git.ParseHash(fields[0])
+		if err != nil {
+			return nil, fmt.Errorf("list submodules: %w", err)
+		}
+		path := fields[1]
+		if i := strings.LastIndex(path, " ("); i != -1 && strings.HasSuffix(path, ")") {
+			path = path[:i]
+		}
+		subs = append(subs, submodule{
+			Path:   git.TopPath(path),
+			Commit: h,
+			State:  submoduleState(line[0]),
+		})
+	}
+	return subs, nil
+}
+
+// updateSubmodules initializes and updates the submodules of the working
+// copy if the gg.updateSubmodules setting is true.
+func updateSubmodules(ctx context.Context, cc *cmdContext) error {
+	cfg, err := cc.git.ReadConfig(ctx)
+	if err != nil {
+		return err
+	}
+	if cfg.Value("gg.updateSubmodules") == "" {
+		return nil
+	}
+	if enabled, err := cfg.Bool("gg.updateSubmodules"); err != nil {
+		return err
+	} else if !enabled {
+		return nil
+	}
+	top, err := cc.git.WorkTree(ctx)
+	if err != nil {
+		return err
+	}
+	return cc.withDir(top).interactiveGit(ctx, "submodule", "update", "--init", "--recursive")
+}
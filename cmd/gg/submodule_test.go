@@ -0,0 +1,154 @@
+// Copyright 2021 The gg Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"testing"
+
+	"gg-scm.io/tool/internal/filesystem"
+)
+
+func TestSubmodules(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	env, err := newTestEnv(ctx, t)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := env.writeConfig([]byte("[protocol \"file\"]\n\tallow = always\n")); err != nil {
+		t.Fatal(err)
+	}
+	if err := env.initRepoWithHistory(ctx, "sub"); err != nil {
+		t.Fatal(err)
+	}
+	if err := env.initEmptyRepo(ctx, "super"); err != nil {
+		t.Fatal(err)
+	}
+	superGit := env.git.WithDir(env.root.FromSlash("super"))
+	if err := superGit.Run(ctx, "submodule", "--quiet", "add", "../sub", "sub"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := env.newCommit(ctx, "super"); err != nil {
+		t.Fatal(err)
+	}
+
+	subs, err := listSubmodules(ctx, superGit)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(subs) != 1 || subs[0].Path != "sub" || subs[0].State != submoduleClean {
+		t.Fatalf("listSubmodules(...) = %+v; want a single clean submodule at sub", subs)
+	}
+
+	// Create a new commit in the submodule.
+	if err := env.root.Apply(filesystem.Write("super/sub/new.txt", dummyContent)); err != nil {
+		t.Fatal(err)
+	}
+	if err := env.addFiles(ctx, "super/sub/new.txt"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := env.newCommit(ctx, "super/sub"); err != nil {
+		t.Fatal(err)
+	}
+	out, err := env.gg(ctx, env.root.FromSlash("super"), "status")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "M sub\n"; string(out) != want {
+		t.Errorf("gg status = %q; want %q", out, want)
+	}
+}
+
+func TestSubmodules_Update(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	env, err := newTestEnv(ctx, t)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := env.writeConfig([]byte("[protocol \"file\"]\n\tallow = always\n")); err != nil {
+		t.Fatal(err)
+	}
+	if err := env.initRepoWithHistory(ctx, "sub"); err != nil {
+		t.Fatal(err)
+	}
+	if err := env.initRepoWithHistory(ctx, "super"); err != nil {
+		t.Fatal(err)
+	}
+	superGit := env.git.WithDir(env.root.FromSlash("super"))
+	if err := env.git.Run(ctx, "clone", "--quiet", "super", "clone"); err != nil {
+		t.Fatal(err)
+	}
+	cloneGit := env.git.WithDir(env.root.FromSlash("clone"))
+	if err := cloneGit.Run(ctx, "config", "gg.updateSubmodules", "true"); err != nil {
+		t.Fatal(err)
+	}
+
+	// Add the submodule upstream and pull it into the clone.
+	if err := superGit.Run(ctx, "submodule", "--quiet", "add", "../sub", "sub"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := env.newCommit(ctx, "super"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := env.gg(ctx, env.root.FromSlash("clone"), "pull", "-u"); err != nil {
+		t.Fatal(err)
+	}
+	if subs, err := listSubmodules(ctx, cloneGit); err != nil {
+		t.Error(err)
+	} else if len(subs) != 1 || subs[0].State != submoduleClean {
+		t.Errorf("after gg pull -u, submodules = %+v; want a single clean submodule", subs)
+	}
+
+	// Create a new commit in the submodule's repository and record it
+	// upstream.
+	if err := env.root.Apply(filesystem.Write("sub/new.txt", dummyContent)); err != nil {
+		t.Fatal(err)
+	}
+	if err := env.addFiles(ctx, "sub/new.txt"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := env.newCommit(ctx, "sub"); err != nil {
+		t.Fatal(err)
+	}
+	superSubGit := env.git.WithDir(env.root.FromSlash("super/sub"))
+	if err := superSubGit.Run(ctx, "fetch", "--quiet", "origin"); err != nil {
+		t.Fatal(err)
+	}
+	if err := superSubGit.Run(ctx, "checkout", "--quiet", "origin/main"); err != nil {
+		t.Fatal(err)
+	}
+	if err := env.addFiles(ctx, "super/sub"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := env.newCommit(ctx, "super"); err != nil {
+		t.Fatal(err)
+	}
+
+	// Fetching leaves the working copy alone, and gg update then checks
+	// out the new submodule commit.
+	if _, err := env.gg(ctx, env.root.FromSlash("clone"), "pull"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := env.gg(ctx, env.root.FromSlash("clone"), "update"); err != nil {
+		t.Fatal(err)
+	}
+	if exists, err := env.root.Exists("clone/sub/new.txt"); err != nil {
+		t.Error(err)
+	} else if !exists {
+		t.Error("clone/sub/new.txt does not exist after gg update")
+	}
+}
@@ -0,0 +1,114 @@
+// Copyright 2021 The gg Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+
+	"gg-scm.io/tool/internal/flag"
+)
+
+const summarySynopsis = "summarize the working copy state"
+
+func summary(ctx context.Context, cc *cmdContext, args []string) error {
+	f := flag.NewFlagSet(true, "gg summary [--remote]", summarySynopsis+`
+
+	summary shows the commit the working copy is based on, the current
+	branch and its description, counts of changed files, any merge, rebase, or other operation
+	in progress (along with the commands to finish it), and how far the
+	branch is ahead of or behind its upstream. If the branch
+	has an open GitHub pull request that gg already knows about from
+	`+"`gg branch -v`"+`, its link is shown as well. summary does not
+	contact GitHub.
+
+	The upstream comparison uses the remote-tracking branch from the last
+	pull. `+"`--remote`"+` fetches from the upstream's remote first.
+
+aliases: sum`)
+	remote := f.Bool("remote", false, "fetch from the upstream's remote before comparing")
+	if err := f.Parse(args); flag.IsHelp(err) {
+		f.Help(cc.stdout)
+		return nil
+	} else if err != nil {
+		return usagef("%v", err)
+	}
+	if f.NArg() > 0 {
+		return usagef("summary takes no arguments")
+	}
+	cfg, err := cc.git.ReadConfig(ctx)
+	if err != nil {
+		return err
+	}
+	ws, err := readWorkingState(ctx, cc.git)
+	if err != nil {
+		return err
+	}
+	upstream := ""
+	if ws.branch != "" {
+		upstream = branchUpstream(cfg, ws.branch)
+	}
+	if *remote && upstream != "" {
+		if r := cfg.Value("branch." + ws.branch + ".remote"); r != "" && r != "." {
+			if err := cc.interactiveGit(ctx, "fetch", "--quiet", "--", r); err != nil {
+				return err
+			}
+		}
+	}
+
+	out := new(strings.Builder)
+	if ws.parent == nil {
+		out.WriteString("parent: (none)\n")
+	} else {
+		fmt.Fprintf(out, "parent: %v %s\n", ws.parent.SHA1().Short(), ws.parent.Summary())
+	}
+	if ws.branch == "" {
+		out.WriteString("branch: (detached)\n")
+	} else {
+		fmt.Fprintf(out, "branch: %s\n", ws.branch)
+		if desc := branchDescriptionSummary(cfg, ws.branch); desc != "" {
+			fmt.Fprintf(out, "description: %s\n", desc)
+		}
+	}
+	fmt.Fprintf(out, "commit: %s", ws.describeChanges())
+	if ws.op != noOperation {
+		fmt.Fprintf(out, " (%v in progress)", ws.op)
+	}
+	out.WriteString("\n")
+	if upstream != "" && ws.parent != nil {
+		status, err := describeUpstreamDistance(ctx, cc.git, ws.branch)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(out, "upstream: %s (%s)\n", upstream, status)
+	}
+	if ws.branch != "" {
+		gcfg, err := readGGConfig(ctx, cc.git)
+		if err != nil {
+			return err
+		}
+		prs := cachedOpenPullRequests(ctx, cc, cfg, gcfg, false)
+		if pr, ok := branchPullRequest(cfg, gcfg, ws.branch, prs); ok {
+			fmt.Fprintf(out, "pull: #%d %s\n", pr.Number, pr.HTMLURL)
+		}
+	}
+	if err := writeOperationBanner(out, ws.op); err != nil {
+		return err
+	}
+	_, err = io.WriteString(cc.stdout, out.String())
+	return err
+}
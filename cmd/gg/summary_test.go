@@ -0,0 +1,143 @@
+// Copyright 2021 The gg Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"gg-scm.io/pkg/git"
+	"gg-scm.io/tool/internal/filesystem"
+)
+
+func TestSummary(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	env, err := newTestEnv(ctx, t)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := env.initRepoWithHistory(ctx, "repo1"); err != nil {
+		t.Fatal(err)
+	}
+	if err := env.git.Run(ctx, "clone", "repo1", "repo2"); err != nil {
+		t.Fatal(err)
+	}
+	if err := env.root.Apply(filesystem.Write("repo2/foo.txt", "local change\n")); err != nil {
+		t.Fatal(err)
+	}
+	if err := env.addFiles(ctx, "repo2/foo.txt"); err != nil {
+		t.Fatal(err)
+	}
+	if err := env.git.WithDir(env.root.FromSlash("repo2")).Commit(ctx, "Change foo", git.CommitOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	head, err := env.git.WithDir(env.root.FromSlash("repo2")).Head(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = env.root.Apply(
+		filesystem.Write("repo2/foo.txt", "uncommitted change\n"),
+		filesystem.Write("repo2/bar.txt", "untracked\n"),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	out, err := env.gg(ctx, env.root.FromSlash("repo2"), "summary")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "parent: " + head.Commit.Short() + " Change foo\n" +
+		"branch: main\n" +
+		"commit: 1 modified, 1 untracked\n" +
+		"upstream: origin/main (1 ahead, 0 behind)\n"
+	if string(out) != want {
+		t.Errorf("summary output:\n%s\nwant:\n%s", out, want)
+	}
+
+	if err := env.git.WithDir(env.root.FromSlash("repo2")).Run(ctx, "config", "branch.main.description", "Make foo better\n\nMore details."); err != nil {
+		t.Fatal(err)
+	}
+	out, err = env.gg(ctx, env.root.FromSlash("repo2"), "summary")
+	if err != nil {
+		t.Fatal(err)
+	}
+	const wantDesc = "branch: main\ndescription: Make foo better\n"
+	if !strings.Contains(string(out), wantDesc) {
+		t.Errorf("summary output:\n%s\nwant to contain %q", out, wantDesc)
+	}
+}
+
+func TestSummary_PullRequestFromCache(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	env, err := newTestEnv(ctx, t)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var requests countingRoundTripper
+	env.roundTripper = &requests
+	if err := env.initRepoWithHistory(ctx, "."); err != nil {
+		t.Fatal(err)
+	}
+	if err := env.git.Run(ctx, "remote", "add", "origin", "https://github.com/example/foo.git"); err != nil {
+		t.Fatal(err)
+	}
+	if err := env.writeGitHubAuth([]byte("xyzzy\n")); err != nil {
+		t.Fatal(err)
+	}
+	// Write an out-of-date list of open pull requests, as if from a much
+	// earlier gg branch -v.
+	cacheKey := sha256.Sum256([]byte("github.com/example/foo"))
+	const cache = `{"Fetched":"2001-01-01T00:00:00Z","Pulls":[` +
+		`{"Number":42,"html_url":"https://github.com/example/foo/pull/42","Head":{"Ref":"main","Label":"example:main"}}` +
+		`]}`
+	if err := env.topDir.Apply(filesystem.Write(".cache/gg/pulls/"+hex.EncodeToString(cacheKey[:]), cache)); err != nil {
+		t.Fatal(err)
+	}
+
+	out, err := env.gg(ctx, env.root.String(), "summary")
+	if err != nil {
+		t.Fatal(err)
+	}
+	const want = "pull: #42 https://github.com/example/foo/pull/42\n"
+	if !strings.Contains(string(out), want) {
+		t.Errorf("summary output:\n%s\nwant to contain %q", out, want)
+	}
+	if n := requests.count(); n > 0 {
+		t.Errorf("summary made %d HTTP requests; want 0", n)
+	}
+}
+
+// countingRoundTripper counts the requests it receives and otherwise
+// behaves like stubRoundTripper.
+type countingRoundTripper struct {
+	n int32
+}
+
+func (rt *countingRoundTripper) RoundTrip(r *http.Request) (*http.Response, error) {
+	atomic.AddInt32(&rt.n, 1)
+	return stubRoundTripper{}.RoundTrip(r)
+}
+
+func (rt *countingRoundTripper) count() int {
+	return int(atomic.LoadInt32(&rt.n))
+}
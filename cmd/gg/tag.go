@@ -0,0 +1,346 @@
+// Copyright 2021 The gg Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"gg-scm.io/pkg/git"
+	"gg-scm.io/tool/internal/flag"
+)
+
+const tagSynopsis = "list or manage tags"
+
+func tag(ctx context.Context, cc *cmdContext, args []string) error {
+	f := flag.NewFlagSet(true, "gg tag [-f] [-r REV] [-m MSG] [-s] NAME [...]\n"+
+		"gg tag --list [--sort=ORDER] [--date=STYLE] [PATTERN [...]]\n"+
+		"gg tag --delete [--remote=DST] NAME [...]", tagSynopsis+`
+
+	Tags are names for specific commits, usually releases. Unlike
+	branches, tags are not expected to move once they are shared.
+
+	Without `+"`-m`"+` or `+"`-s`"+`, `+"`gg tag`"+` creates lightweight
+	tags that are just references to the commit. `+"`-m`"+` creates an
+	annotated tag, which records the tagger, the date, and the message.
+	`+"`-s`"+` creates an annotated tag signed with Git's configured
+	signing key, opening an editor for the message if `+"`-m`"+` is not
+	given.
+
+	`+"`--list`"+` shows the tags matching any of the given shell-style
+	patterns, or all tags if no patterns are given. Running `+"`gg tag`"+`
+	with no arguments also lists tags. The date shown is the date the tag
+	was created for annotated tags or the commit date for lightweight
+	tags.
+
+	`+"`--delete`"+` removes the named tags from the local repository. If
+	`+"`--remote`"+` is given, the tags are also deleted from the named
+	destination repository.`)
+	list := f.Bool("list", false, "list tags")
+	f.Alias("list", "l")
+	delete := f.Bool("delete", false, "delete the given tags")
+	f.Alias("delete", "d")
+	remote := f.String("remote", "", "with --delete, also delete the tags from the `dst` repository")
+	force := f.Bool("f", false, "replace existing tags")
+	f.Alias("f", "force")
+	rev := f.String("r", "", "`rev`ision to tag")
+	msg := f.String("m", "", "create an annotated tag with the given `message`")
+	sign := f.Bool("s", false, "create a signed annotated tag")
+	f.Alias("s", "sign")
+	ord := branchSortOrder{key: branchSortDate, dir: descending}
+	f.Var(&ord, "sort", "sort `order` when listing: 'name' or 'date'. May be prefixed by '-' for descending.")
+	date := f.String("date", "", "date display `style` when listing: 'default', 'relative', 'iso', or 'local' (defaults to gg.dateFormat)")
+	if err := f.Parse(args); flag.IsHelp(err) {
+		f.Help(cc.stdout)
+		return nil
+	} else if err != nil {
+		return usagef("%v", err)
+	}
+	creating := *force || *rev != "" || *msg != "" || *sign
+	switch {
+	case *list && *delete:
+		return usagef("can't pass both --list and --delete")
+	case *delete:
+		if creating {
+			return usagef("can't pass -f, -r, -m, or -s with --delete")
+		}
+		if f.NArg() == 0 {
+			return usagef("must pass tag names to delete")
+		}
+		return deleteTags(ctx, cc, f.Args(), *remote)
+	case *remote != "":
+		return usagef("can't pass --remote without --delete")
+	case *list || f.NArg() == 0:
+		if creating {
+			return usagef("can't pass -f, -r, -m, or -s when listing")
+		}
+		return listTags(ctx, cc, f.Args(), ord, *date)
+	default:
+		return createTags(ctx, cc, f.Args(), *rev, *msg, *sign, *force)
+	}
+}
+
+// tagListsOnly reports whether the arguments to gg tag only list tags.
+func tagListsOnly(args []string) bool {
+	flags, npos := scanArgs(args, "remote", "r", "m", "sort", "date")
+	if flags["d"] || flags["delete"] {
+		return false
+	}
+	return npos == 0 || flags["l"] || flags["list"]
+}
+
+// createTags points the given tags at rev. If msg is not empty or sign
+// is true, then the tags are annotated.
+func createTags(ctx context.Context, cc *cmdContext, names []string, rev string, msg string, sign bool, force bool) error {
+	for _, name := range names {
+		if strings.HasPrefix(name, "-") || !git.TagRef(name).IsValid() {
+			return fmt.Errorf("invalid tag name %q", name)
+		}
+	}
+	if rev == "" {
+		rev = git.Head.String()
+	}
+	r, err := cc.git.ParseRev(ctx, rev)
+	if err != nil {
+		return err
+	}
+	if !force {
+		refs, err := cc.git.ListRefs(ctx)
+		if err != nil {
+			return err
+		}
+		for _, name := range names {
+			if _, exists := refs[git.TagRef(name)]; exists {
+				return fmt.Errorf("tag %q already exists (use -f to replace it)", name)
+			}
+		}
+	}
+	if sign && msg == "" {
+		msg, err = editTagMessage(ctx, cc, names)
+		if err != nil {
+			return err
+		}
+		if msg == "" {
+			return errors.New("empty tag message; aborting")
+		}
+	}
+	for _, name := range names {
+		tagArgs := []string{"tag"}
+		if force {
+			tagArgs = append(tagArgs, "--force")
+		}
+		if sign {
+			tagArgs = append(tagArgs, "--sign")
+		} else if msg != "" {
+			tagArgs = append(tagArgs, "--annotate")
+		}
+		if msg != "" {
+			tagArgs = append(tagArgs, "--cleanup=verbatim", "--message="+msg)
+		}
+		tagArgs = append(tagArgs, "--", name, r.Commit.String())
+		if sign {
+			// The signing program may need to prompt for a passphrase.
+			err = cc.interactiveGit(ctx, tagArgs...)
+		} else {
+			err = cc.git.Run(ctx, tagArgs...)
+		}
+		if err != nil {
+			return fmt.Errorf("tag %q: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// editTagMessage opens an editor for the message of new annotated tags.
+func editTagMessage(ctx context.Context, cc *cmdContext, names []string) (string, error) {
+	cfg, err := cc.git.ReadConfig(ctx)
+	if err != nil {
+		return "", err
+	}
+	commentChar, err := cfg.CommentChar()
+	if err != nil {
+		return "", err
+	}
+	msgBuf := new(bytes.Buffer)
+	fmt.Fprintf(msgBuf, "\n%s Please enter a message for the tag\n", commentChar)
+	for _, name := range names {
+		fmt.Fprintf(msgBuf, "%s   %s\n", commentChar, name)
+	}
+	fmt.Fprintf(msgBuf, "%s Lines starting with '%s' will be ignored.\n", commentChar, commentChar)
+	edited, err := cc.editor.open(ctx, "TAG_EDITMSG", msgBuf.Bytes())
+	if err != nil {
+		return "", err
+	}
+	return cleanupMessage(string(edited), commentChar), nil
+}
+
+// tagInfo is a summary of a tag for listing.
+type tagInfo struct {
+	name      string
+	commit    git.Hash
+	annotated bool
+	created   time.Time
+	summary   string // first line of the tag message or commit message
+}
+
+func listTags(ctx context.Context, cc *cmdContext, patterns []string, ord branchSortOrder, dateFlag string) error {
+	cfg, err := cc.git.ReadConfig(ctx)
+	if err != nil {
+		return err
+	}
+	style, err := dateStyle(cfg, dateFlag)
+	if err != nil {
+		return err
+	}
+	tags, err := readTags(ctx, cc.git, patterns)
+	if err != nil {
+		return err
+	}
+	switch ord {
+	case branchSortOrder{branchSortName, ascending}:
+		sort.Slice(tags, func(i, j int) bool {
+			return tags[i].name < tags[j].name
+		})
+	case branchSortOrder{branchSortName, descending}:
+		sort.Slice(tags, func(i, j int) bool {
+			return tags[i].name > tags[j].name
+		})
+	case branchSortOrder{branchSortDate, ascending}:
+		sort.SliceStable(tags, func(i, j int) bool {
+			return tags[i].created.Before(tags[j].created)
+		})
+	case branchSortOrder{branchSortDate, descending}:
+		sort.SliceStable(tags, func(i, j int) bool {
+			return tags[j].created.Before(tags[i].created)
+		})
+	default:
+		panic("unknown sort order")
+	}
+	now := time.Now()
+	for _, t := range tags {
+		_, err := fmt.Fprintf(cc.stdout, "%-30s %s %s  %s\n", t.name, t.commit.Short(), style.Format(t.created, now), t.summary)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// readTags returns the tags whose names match any of the given patterns,
+// or all tags if no patterns are given. The tags are sorted by name.
+func readTags(ctx context.Context, g *git.Git, patterns []string) ([]*tagInfo, error) {
+	// %(*objectname) is the peeled commit for annotated tags and empty for
+	// lightweight tags. %(creatordate) is the tagger date for annotated
+	// tags and the committer date for lightweight tags.
+	args := []string{
+		"for-each-ref",
+		"--sort=refname",
+		"--format=%(refname)%00%(objectname)%00%(*objectname)%00%(creatordate:unix)%00%(contents:subject)",
+		"--",
+	}
+	if len(patterns) == 0 {
+		args = append(args, "refs/tags/")
+	}
+	for _, pat := range patterns {
+		args = append(args, git.TagRef(pat).String())
+	}
+	out, err := g.Output(ctx, args...)
+	if err != nil {
+		return nil, err
+	}
+	var tags []*tagInfo
+	for _, line := range strings.Split(strings.TrimSuffix(out, "\n"), "\n") {
+		if line == "" {
+			continue
+		}
+		fields := strings.SplitN(line, "\x00", 5)
+		if len(fields) != 5 {
+			return nil, fmt.Errorf("read tags: unexpected output %q", line)
+		}
+		t := &tagInfo{
+			name:      git.Ref(fields[0]).Tag(),
+			annotated: fields[2] != "",
+			summary:   fields[4],
+		}
+		commit := fields[1]
+		if t.annotated {
+			commit = fields[2]
+		}
+		t.commit, err = git.ParseHash(commit)
+		if err != nil {
+			return nil, fmt.Errorf("read tags: %s: %w", t.name, err)
+		}
+		secs, err := strconv.ParseInt(fields[3], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("read tags: %s: date: %w", t.name, err)
+		}
+		t.created = time.Unix(secs, 0)
+		tags = append(tags, t)
+	}
+	return tags, nil
+}
+
+// deleteTags deletes the given tags from the local repository and, if
+// dstRepo is not empty, the destination repository.
+func deleteTags(ctx context.Context, cc *cmdContext, names []string, dstRepo string) error {
+	allRefs, err := cc.git.ListRefs(ctx)
+	if err != nil {
+		return err
+	}
+	var remoteRefs map[git.Ref]git.Hash
+	if dstRepo != "" {
+		remoteRefs, err = cc.git.ListRemoteRefs(ctx, dstRepo)
+		if err != nil {
+			return err
+		}
+	}
+	muts := make(map[git.Ref]git.RefMutation, len(names))
+	var pushArgs []string
+	for _, name := range names {
+		ref := git.TagRef(strings.TrimPrefix(name, "refs/tags/"))
+		if !ref.IsValid() {
+			return fmt.Errorf("invalid tag name %q", name)
+		}
+		_, localExists := allRefs[ref]
+		_, remoteExists := remoteRefs[ref]
+		if !localExists && !remoteExists {
+			return fmt.Errorf("tag %q does not exist", ref.Tag())
+		}
+		if localExists {
+			muts[ref] = git.DeleteRefIfMatches(allRefs[ref].String())
+		}
+		if remoteExists {
+			pushArgs = append(pushArgs, ":"+ref.String())
+		}
+	}
+	if len(muts) > 0 {
+		if err := cc.git.MutateRefs(ctx, muts); err != nil {
+			return err
+		}
+	}
+	if len(pushArgs) > 0 {
+		pushArgs = append([]string{"push", "--", dstRepo}, pushArgs...)
+		if err := cc.interactiveGit(ctx, pushArgs...); err != nil {
+			return err
+		}
+	}
+	return nil
+}
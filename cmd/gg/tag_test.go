@@ -0,0 +1,86 @@
+// Copyright 2021 The gg Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestTag(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	env, err := newTestEnv(ctx, t)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := env.initRepoWithHistory(ctx, "."); err != nil {
+		t.Fatal(err)
+	}
+	head, err := env.git.Head(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := env.gg(ctx, env.root.String(), "tag", "v1.0.0"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := env.gg(ctx, env.root.String(), "tag", "-m", "Release 1.1", "v1.1.0"); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"v1.0.0", "v1.1.0"} {
+		r, err := env.git.ParseRev(ctx, "refs/tags/"+name)
+		if err != nil {
+			t.Error(err)
+			continue
+		}
+		if r.Commit != head.Commit {
+			t.Errorf("%s = %v; want %v", name, r.Commit, head.Commit)
+		}
+	}
+	if typ, err := env.git.Output(ctx, "cat-file", "-t", "refs/tags/v1.1.0"); err != nil {
+		t.Error(err)
+	} else if strings.TrimSpace(typ) != "tag" {
+		t.Errorf("v1.1.0 is a %s; want annotated tag", strings.TrimSpace(typ))
+	}
+	if _, err := env.gg(ctx, env.root.String(), "tag", "v1.0.0"); err == nil {
+		t.Error("gg tag with existing name did not return an error")
+	}
+
+	out, err := env.gg(ctx, env.root.String(), "tag", "--list", "--sort=name", "v1.1.*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(string(out), "v1.1.0 ") || strings.Count(string(out), "\n") != 1 {
+		t.Errorf("gg tag --list v1.1.* output:\n%s\nwant only v1.1.0", out)
+	}
+	if !strings.Contains(string(out), "Release 1.1") {
+		t.Errorf("gg tag --list v1.1.* output:\n%s\nwant to contain tag message", out)
+	}
+
+	if _, err := env.gg(ctx, env.root.String(), "tag", "--delete", "v1.0.0", "v1.1.0"); err != nil {
+		t.Fatal(err)
+	}
+	refs, err := env.git.ListRefs(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for ref := range refs {
+		if ref.IsTag() {
+			t.Errorf("found %v after deleting tags", ref)
+		}
+	}
+}
@@ -0,0 +1,80 @@
+// Copyright 2021 The gg Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gg-scm.io/pkg/git"
+)
+
+// A tempIndex is a Git index file in a temporary directory. Commands use
+// it to build trees without touching the repository's index.
+type tempIndex struct {
+	cc   *cmdContext
+	g    *git.Git
+	name string // command name used in error messages
+	dir  string
+	env  []string
+}
+
+// newTempIndex creates a directory for a temporary index under the
+// editor's temporary root. The index starts out empty. The caller is
+// responsible for calling close.
+func newTempIndex(cc *cmdContext, g *git.Git, name string) (*tempIndex, error) {
+	dir, err := ioutil.TempDir(cc.editor.tempRoot, "gg-"+name)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+	return &tempIndex{
+		cc:   cc,
+		g:    g,
+		name: name,
+		dir:  dir,
+		env:  append(cc.env[:len(cc.env):len(cc.env)], "GIT_INDEX_FILE="+filepath.Join(dir, "index")),
+	}, nil
+}
+
+// run runs Git with the temporary index and returns its output with
+// surrounding whitespace removed. If stdin is not nil, it is sent to
+// Git's standard input.
+func (idx *tempIndex) run(ctx context.Context, stdin io.Reader, args ...string) (string, error) {
+	stdout := new(strings.Builder)
+	stderr := new(bytes.Buffer)
+	err := idx.g.Runner().RunGit(ctx, &git.Invocation{
+		Args:   args,
+		Dir:    idx.cc.dir,
+		Env:    idx.env,
+		Stdin:  stdin,
+		Stdout: stdout,
+		Stderr: stderr,
+	})
+	if err != nil {
+		return "", fmt.Errorf("%s: git %s: %w: %s", idx.name, args[0], err, bytes.TrimSpace(stderr.Bytes()))
+	}
+	return strings.TrimSpace(stdout.String()), nil
+}
+
+// close removes the temporary index.
+func (idx *tempIndex) close() error {
+	return os.RemoveAll(idx.dir)
+}
@@ -0,0 +1,168 @@
+// Copyright 2021 The gg Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"gg-scm.io/pkg/git"
+	"gg-scm.io/pkg/git/object"
+	"gg-scm.io/tool/internal/flag"
+)
+
+const (
+	reviewedBySynopsis = "add a Reviewed-by trailer to a commit"
+	testedBySynopsis   = "add a Tested-by trailer to a commit"
+)
+
+func reviewedBy(ctx context.Context, cc *cmdContext, args []string) error {
+	return trailerCommand(ctx, cc, args, "reviewed-by", reviewedBySynopsis, "Reviewed-by")
+}
+
+func testedBy(ctx context.Context, cc *cmdContext, args []string) error {
+	return trailerCommand(ctx, cc, args, "tested-by", testedBySynopsis, "Tested-by")
+}
+
+// trailerCommand implements a command that adds a trailer with the given
+// key to a commit for each person named on the command line.
+func trailerCommand(ctx context.Context, cc *cmdContext, args []string, name, synopsis, key string) error {
+	f := flag.NewFlagSet(true, "gg "+name+" [-r REV] PERSON [...]", synopsis+`
+
+	`+name+` appends a `+key+` trailer for each person to the message of the
+	working copy's parent or the given revision. If the revision is not
+	the working copy's parent, then the commits after it are rebased onto
+	the reworded commit. People that the message already credits with a
+	`+key+` trailer are skipped.
+
+	Each person is either of the form "Name <email>" or an alias or email
+	address from the co-author roster described in `+"`gg help commit`"+`.`)
+	rev := f.String("r", git.Head.String(), "`rev`ision to add trailers to")
+	if err := f.Parse(args); flag.IsHelp(err) {
+		f.Help(cc.stdout)
+		return nil
+	} else if err != nil {
+		return usagef("%v", err)
+	}
+	if f.NArg() == 0 {
+		return usagef("must pass at least one person")
+	}
+	idents, err := resolveCoauthors(ctx, cc, f.Args())
+	if err != nil {
+		return err
+	}
+	return addTrailersToCommit(ctx, cc, *rev, key, idents)
+}
+
+// addTrailersToCommit rewords rev, which must be HEAD or one of its
+// ancestors, to add a trailer with the given key for each identity.
+func addTrailersToCommit(ctx context.Context, cc *cmdContext, rev string, key string, idents []string) error {
+	if strings.HasPrefix(rev, "-") {
+		return errors.New("revision cannot start with a dash")
+	}
+	c, err := cc.git.CommitInfo(ctx, rev)
+	if err != nil {
+		return err
+	}
+	head, err := cc.git.Head(ctx)
+	if err != nil {
+		return err
+	}
+	hash := c.SHA1()
+	if hash != head.Commit {
+		if isAncestor, err := cc.git.IsAncestor(ctx, hash.String(), head.Commit.String()); err != nil {
+			return err
+		} else if !isAncestor {
+			return fmt.Errorf("%s is not an ancestor of the working copy", rev)
+		}
+		merges, err := cc.git.Output(ctx, "rev-list", "--merges", hash.String()+".."+head.Commit.String())
+		if err != nil {
+			return err
+		}
+		if strings.TrimSpace(merges) != "" {
+			return fmt.Errorf("cannot reword %s: merges exist between it and the working copy", rev)
+		}
+	}
+	msg := addTrailers(c.Message, key, idents)
+	if msg == c.Message {
+		return nil
+	}
+	reworded, err := writeCommit(ctx, cc, &object.Commit{
+		Tree:       c.Tree,
+		Parents:    c.Parents,
+		Author:     c.Author,
+		AuthorTime: c.AuthorTime,
+		Message:    msg,
+	})
+	if err != nil {
+		return err
+	}
+	return replaceCommit(ctx, cc, hash, reworded)
+}
+
+// addTrailers appends a trailer with the given key to the message for
+// each value that the message does not already have a trailer for.
+// Keys are compared case-insensitively. The trailers are added to the
+// message's trailer block if its last paragraph is one, or in a new
+// paragraph otherwise.
+func addTrailers(msg string, key string, values []string) string {
+	if len(values) == 0 {
+		return msg
+	}
+	trimmed := strings.TrimRight(msg, "\n")
+	lines := strings.Split(trimmed, "\n")
+	// Find the last paragraph to see whether it is already a trailer block.
+	start := len(lines)
+	for start > 0 && lines[start-1] != "" {
+		start--
+	}
+	existing := make(map[string]bool)
+	inTrailers := start > 0
+	for _, line := range lines[start:] {
+		i := strings.Index(line, ": ")
+		if i <= 0 || strings.ContainsAny(line[:i], " \t") {
+			inTrailers = false
+			continue
+		}
+		if strings.EqualFold(line[:i], key) {
+			existing[strings.TrimSpace(line[i+2:])] = true
+		}
+	}
+	var add []string
+	for _, v := range values {
+		if !existing[v] {
+			existing[v] = true
+			add = append(add, v)
+		}
+	}
+	if len(add) == 0 {
+		return msg
+	}
+	sb := new(strings.Builder)
+	sb.WriteString(trimmed)
+	sb.WriteString("\n")
+	if !inTrailers {
+		sb.WriteString("\n")
+	}
+	for _, v := range add {
+		sb.WriteString(key)
+		sb.WriteString(": ")
+		sb.WriteString(v)
+		sb.WriteString("\n")
+	}
+	return sb.String()
+}
@@ -0,0 +1,115 @@
+// Copyright 2021 The gg Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"testing"
+
+	"gg-scm.io/pkg/git"
+	"gg-scm.io/tool/internal/filesystem"
+)
+
+func TestReviewedBy(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	env, err := newTestEnv(ctx, t)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := env.initRepoWithHistory(ctx, "."); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"a.txt", "b.txt"} {
+		if err := env.root.Apply(filesystem.Write(name, dummyContent)); err != nil {
+			t.Fatal(err)
+		}
+		if err := env.addFiles(ctx, name); err != nil {
+			t.Fatal(err)
+		}
+		if err := env.git.CommitAll(ctx, "add "+name, git.CommitOptions{}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	before, err := env.git.CommitInfo(ctx, "HEAD~1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	const jane = "Jane Doe <jane@example.com>"
+	if _, err := env.gg(ctx, env.root.String(), "reviewed-by", jane); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := env.gg(ctx, env.root.String(), "reviewed-by", jane); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := env.gg(ctx, env.root.String(), "tested-by", "-r", "HEAD~1", jane); err != nil {
+		t.Fatal(err)
+	}
+
+	head, err := env.git.CommitInfo(ctx, "HEAD")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "add b.txt\n\nReviewed-by: " + jane + "\n"; head.Message != want {
+		t.Errorf("HEAD message = %q; want %q", head.Message, want)
+	}
+	parent, err := env.git.CommitInfo(ctx, "HEAD~1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "add a.txt\n\nTested-by: " + jane + "\n"; parent.Message != want {
+		t.Errorf("HEAD~1 message = %q; want %q", parent.Message, want)
+	}
+	if parent.Tree != before.Tree || !parent.AuthorTime.Equal(before.AuthorTime) {
+		t.Error("tested-by -r HEAD~1 changed the commit's tree or author time")
+	}
+	if _, err := catBlob(ctx, env.git, "HEAD", "b.txt"); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestAddTrailers(t *testing.T) {
+	tests := []struct {
+		msg    string
+		key    string
+		values []string
+		want   string
+	}{
+		{
+			msg:    "Summary\n",
+			key:    "Reviewed-by",
+			values: []string{"Jane Doe <jane@example.com>"},
+			want:   "Summary\n\nReviewed-by: Jane Doe <jane@example.com>\n",
+		},
+		{
+			msg:    "Summary\n\nReviewed-by: Jane Doe <jane@example.com>\n",
+			key:    "Tested-by",
+			values: []string{"Jane Doe <jane@example.com>"},
+			want:   "Summary\n\nReviewed-by: Jane Doe <jane@example.com>\nTested-by: Jane Doe <jane@example.com>\n",
+		},
+		{
+			msg:    "Summary\n\nreviewed-by: Jane Doe <jane@example.com>\n",
+			key:    "Reviewed-by",
+			values: []string{"Jane Doe <jane@example.com>"},
+			want:   "Summary\n\nreviewed-by: Jane Doe <jane@example.com>\n",
+		},
+	}
+	for _, test := range tests {
+		if got := addTrailers(test.msg, test.key, test.values); got != test.want {
+			t.Errorf("addTrailers(%q, %q, %q) = %q; want %q", test.msg, test.key, test.values, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,123 @@
+// Copyright 2021 The gg Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"errors"
+
+	"gg-scm.io/pkg/git"
+	"gg-scm.io/pkg/git/object"
+	"gg-scm.io/tool/internal/flag"
+)
+
+const uncommitSynopsis = "undo the last commit, keeping its changes"
+
+func uncommit(ctx context.Context, cc *cmdContext, args []string) error {
+	f := flag.NewFlagSet(true, "gg uncommit [FILE [...]]", uncommitSynopsis+`
+
+	Without arguments, uncommit removes the working directory's parent
+	commit and leaves its changes in the working copy, like
+	`+"`git reset --soft HEAD~`"+`. If files are given, only the changes to
+	those files are removed from the commit: the commit is kept with the
+	rest of its changes and the same message. If that would leave the
+	commit empty, it is removed entirely.
+
+	The working copy is not modified. uncommit refuses to remove changes
+	from a merge commit or from a commit that is already in the current
+	branch's upstream.`)
+	if err := f.Parse(args); flag.IsHelp(err) {
+		f.Help(cc.stdout)
+		return nil
+	} else if err != nil {
+		return usagef("%v", err)
+	}
+	var pathspecs []git.Pathspec
+	for _, arg := range f.Args() {
+		pathspecs = append(pathspecs, git.LiteralPath(arg))
+	}
+
+	head, err := cc.git.CommitInfo(ctx, git.Head.String())
+	if err != nil {
+		return err
+	}
+	switch len(head.Parents) {
+	case 0:
+		return errors.New("cannot uncommit the root commit")
+	case 1:
+	default:
+		return errors.New("cannot uncommit a merge")
+	}
+	headHash := head.SHA1()
+	if err := checkNotInUpstream(ctx, cc, headHash, "working directory's parent"); err != nil {
+		return err
+	}
+	parent := head.Parents[0]
+	if len(pathspecs) == 0 {
+		return cc.git.Run(ctx, "reset", "--soft", parent.String())
+	}
+
+	tree, err := treeWithoutChanges(ctx, cc, headHash, parent, pathspecs)
+	if err != nil {
+		return err
+	}
+	if tree == head.Tree {
+		return errors.New("no changes to uncommit in the given files")
+	}
+	parentInfo, err := cc.git.CommitInfo(ctx, parent.String())
+	if err != nil {
+		return err
+	}
+	if tree == parentInfo.Tree {
+		return cc.git.Run(ctx, "reset", "--soft", parent.String())
+	}
+	amended, err := writeCommit(ctx, cc, &object.Commit{
+		Tree:       tree,
+		Parents:    head.Parents,
+		Author:     head.Author,
+		AuthorTime: head.AuthorTime,
+		Message:    head.Message,
+	})
+	if err != nil {
+		return err
+	}
+	return cc.git.Run(ctx, "update-ref", "-m", "gg uncommit", git.Head.String(), amended.String(), headHash.String())
+}
+
+// treeWithoutChanges returns the tree of commit with the files matching
+// pathspecs replaced by their versions in parent. The repository's index
+// is not modified.
+func treeWithoutChanges(ctx context.Context, cc *cmdContext, commit, parent git.Hash, pathspecs []git.Pathspec) (git.Hash, error) {
+	idx, err := newTempIndex(cc, cc.git, "uncommit")
+	if err != nil {
+		return git.Hash{}, err
+	}
+	defer idx.close()
+	if _, err := idx.run(ctx, nil, "read-tree", commit.String()); err != nil {
+		return git.Hash{}, err
+	}
+	resetArgs := []string{"reset", "-q", parent.String(), "--"}
+	for _, p := range pathspecs {
+		resetArgs = append(resetArgs, p.String())
+	}
+	if _, err := idx.run(ctx, nil, resetArgs...); err != nil {
+		return git.Hash{}, err
+	}
+	tree, err := idx.run(ctx, nil, "write-tree")
+	if err != nil {
+		return git.Hash{}, err
+	}
+	return git.ParseHash(tree)
+}
@@ -0,0 +1,109 @@
+// Copyright 2021 The gg Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"testing"
+
+	"gg-scm.io/pkg/git"
+	"gg-scm.io/tool/internal/filesystem"
+)
+
+func TestUncommit(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	env, err := newTestEnv(ctx, t)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := env.initRepoWithHistory(ctx, "."); err != nil {
+		t.Fatal(err)
+	}
+	base, err := env.git.Head(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = env.git.NewBranch(ctx, "topic", git.BranchOptions{
+		StartPoint: "main",
+		Track:      true,
+		Checkout:   true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Published commits can't be uncommitted.
+	if _, err := env.gg(ctx, env.root.String(), "uncommit"); err == nil {
+		t.Error("uncommit of upstream commit did not return an error")
+	}
+
+	if err := env.root.Apply(filesystem.Write("a.txt", dummyContent), filesystem.Write("b.txt", dummyContent)); err != nil {
+		t.Fatal(err)
+	}
+	if err := env.addFiles(ctx, "a.txt", "b.txt"); err != nil {
+		t.Fatal(err)
+	}
+	if err := env.git.CommitAll(ctx, "add a and b", git.CommitOptions{}); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := env.gg(ctx, env.root.String(), "uncommit", "b.txt"); err != nil {
+		t.Fatal(err)
+	}
+	head, err := env.git.CommitInfo(ctx, "HEAD")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if head.Message != "add a and b\n" {
+		t.Errorf("HEAD message = %q; want \"add a and b\\n\"", head.Message)
+	}
+	if len(head.Parents) != 1 || head.Parents[0] != base.Commit {
+		t.Errorf("HEAD parents = %v; want [%v]", head.Parents, base.Commit)
+	}
+	if _, err := catBlob(ctx, env.git, "HEAD", "a.txt"); err != nil {
+		t.Error(err)
+	}
+	if _, err := catBlob(ctx, env.git, "HEAD", "b.txt"); err == nil {
+		t.Error("b.txt still present in HEAD after uncommit b.txt")
+	}
+	if got, err := env.root.ReadFile("b.txt"); err != nil {
+		t.Error(err)
+	} else if got != dummyContent {
+		t.Errorf("b.txt in working copy = %q; want %q", got, dummyContent)
+	}
+
+	if _, err := env.gg(ctx, env.root.String(), "uncommit"); err != nil {
+		t.Fatal(err)
+	}
+	if r, err := env.git.Head(ctx); err != nil {
+		t.Fatal(err)
+	} else if r.Commit != base.Commit {
+		t.Errorf("HEAD after uncommit = %v; want %v", r.Commit, base.Commit)
+	}
+	st, err := env.git.Status(ctx, git.StatusOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	added := make(map[git.TopPath]bool)
+	for _, ent := range st {
+		if ent.Code.IsAdded() {
+			added[ent.Name] = true
+		}
+	}
+	if !added["a.txt"] || !added["b.txt"] {
+		t.Errorf("status after uncommit = %v; want a.txt and b.txt added", st)
+	}
+}
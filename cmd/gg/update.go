@@ -18,6 +18,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"strings"
 
 	"gg-scm.io/pkg/git"
 	"gg-scm.io/tool/internal/flag"
@@ -26,7 +27,8 @@ import (
 const updateSynopsis = "update working directory (or switch revisions)"
 
 func update(ctx context.Context, cc *cmdContext, args []string) error {
-	f := flag.NewFlagSet(true, "gg update [--clean] [[-r] REV]", updateSynopsis+`
+	f := flag.NewFlagSet(true, "gg update [--clean | --check] [[-r] REV]\n"+
+		"gg update [--clean | --check] --date=DATE [[-r] REV]", updateSynopsis+`
 
 aliases: up, checkout, co
 
@@ -36,23 +38,104 @@ aliases: up, checkout, co
 	branch otherwise.
 
 	If the commit is not a descendant or ancestor of the HEAD commit,
-	the update is aborted.`)
+	the update is aborted.
+
+	By default, uncommitted changes are merged into the revision being
+	updated to and carried along. `+"`--check`"+` aborts the update
+	instead if there are any uncommitted changes. `+"`--clean`"+`
+	discards uncommitted changes to tracked files without making a
+	backup. Untracked files are left alone in every mode.
+
+	`+"`--date`"+` updates to the last commit made before the given date
+	on the current branch (or on REV, if given), following only first
+	parents. The date can be in any format Git accepts, like
+	"2023-05-01" or "2 weeks ago". Updating to a date or to a tag leaves
+	no branch checked out.
+
+	If `+"`gg.updateSubmodules`"+` is set to true in the Git configuration,
+	submodules are initialized and updated afterward.`)
 	rev := f.String("r", "", "`rev`ision")
 	clean := f.Bool("clean", false, "discard uncommitted changes (no backup)")
 	f.Alias("clean", "C")
+	check := f.Bool("check", false, "abort if there are uncommitted changes")
+	f.Alias("check", "c")
+	date := f.String("date", "", "update to the last commit before `date`")
+	f.Alias("date", "d")
 	if err := f.Parse(args); flag.IsHelp(err) {
 		f.Help(cc.stdout)
 		return nil
 	} else if err != nil {
 		return usagef("%v", err)
 	}
+	if *clean && *check {
+		return usagef("can't pass both --clean and --check")
+	}
+	if *check {
+		if unchanged, err := isClean(ctx, cc.git); err != nil {
+			return err
+		} else if !unchanged {
+			return errors.New("uncommitted changes (commit or shelve them, or use gg update --clean to discard them)")
+		}
+	}
 	behavior := git.MergeLocal
 	if *clean {
 		behavior = git.DiscardLocal
 	}
+	if *date != "" {
+		if err := updateToDate(ctx, cc, *rev, f.Args(), *date, behavior); err != nil {
+			return err
+		}
+		return updateSubmodules(ctx, cc)
+	}
+	if err := updateWorkingCopy(ctx, cc, *rev, f.Args(), behavior); err != nil {
+		return err
+	}
+	return updateSubmodules(ctx, cc)
+}
+
+// updateToDate checks out the last first-parent ancestor of the given
+// revision (or HEAD) committed before date.
+func updateToDate(ctx context.Context, cc *cmdContext, rev string, args []string, date string, behavior git.CheckoutConflictBehavior) error {
+	switch {
+	case len(args) == 0 && rev == "":
+		rev = git.Head.String()
+	case len(args) == 1 && rev == "":
+		rev = args[0]
+	case len(args) > 0:
+		return usagef("can pass only one revision")
+	}
+	if strings.HasPrefix(rev, "-") {
+		return usagef("revision must not start with '-'")
+	}
+	out, err := cc.git.Output(ctx, "rev-list", "--max-count=1", "--first-parent", "--before="+date, rev, "--")
+	if err != nil {
+		return err
+	}
+	out = strings.TrimSpace(out)
+	if out == "" {
+		return fmt.Errorf("no commits in %s before %s", rev, date)
+	}
+	c, err := cc.git.CommitInfo(ctx, out)
+	if err != nil {
+		return err
+	}
+	err = cc.git.CheckoutRev(ctx, c.SHA1().String(), git.CheckoutOptions{
+		ConflictBehavior: behavior,
+	})
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(cc.stderr, "gg: updated to %v %q from %s (no branch checked out)\n",
+		c.SHA1().Short(), c.Summary(), c.CommitTime.Format("2006-01-02 15:04"))
+	return nil
+}
+
+// updateWorkingCopy implements the update command without submodule
+// handling.
+func updateWorkingCopy(ctx context.Context, cc *cmdContext, rev string, args []string, behavior git.CheckoutConflictBehavior) error {
 	var r *git.Rev
 	switch {
-	case f.NArg() == 0 && *rev == "":
+	case len(args) == 0 && rev == "":
 		cfg, err := cc.git.ReadConfig(ctx)
 		if err != nil {
 			return err
@@ -66,16 +149,21 @@ aliases: up, checkout, co
 			return errors.New("can't update with no branch checked out; run 'gg update BRANCH'")
 		}
 		target := targetForUpdate(cfg, branch)
+		if unborn, err := unbornBranch(ctx, cc.git); err != nil {
+			return err
+		} else if unborn != "" {
+			return updateUnbornBranch(ctx, cc, branch, target)
+		}
 		return updateToBranch(ctx, cc.git, branch, target, behavior)
-	case f.NArg() == 0 && *rev != "":
+	case len(args) == 0 && rev != "":
 		var err error
-		r, err = cc.git.ParseRev(ctx, *rev)
+		r, err = cc.git.ParseRev(ctx, rev)
 		if err != nil {
 			return err
 		}
-	case f.NArg() == 1 && *rev == "":
+	case len(args) == 1 && rev == "":
 		var err error
-		r, err = cc.git.ParseRev(ctx, f.Arg(0))
+		r, err = cc.git.ParseRev(ctx, args[0])
 		if err != nil {
 			return err
 		}
@@ -84,9 +172,16 @@ aliases: up, checkout, co
 	}
 	b := r.Ref.Branch()
 	if b == "" {
-		return cc.git.CheckoutRev(ctx, r.Commit.String(), git.CheckoutOptions{
+		err := cc.git.CheckoutRev(ctx, r.Commit.String(), git.CheckoutOptions{
 			ConflictBehavior: behavior,
 		})
+		if err != nil {
+			return err
+		}
+		if r.Ref.IsTag() {
+			fmt.Fprintf(cc.stderr, "gg: updated to tag %s (no branch checked out; use `gg branch NAME` to start one)\n", r.Ref.Tag())
+		}
+		return nil
 	}
 	cfg, err := cc.git.ReadConfig(ctx)
 	if err != nil {
@@ -145,6 +240,23 @@ func updateToBranch(ctx context.Context, g *git.Git, branch string, target git.R
 	return nil
 }
 
+// updateUnbornBranch creates a branch that does not have any commits yet
+// at the given target, if it exists.
+func updateUnbornBranch(ctx context.Context, cc *cmdContext, branch string, target git.Ref) error {
+	if target == "" {
+		fmt.Fprintf(cc.stderr, "gg: %s has no commits yet and no upstream to update to\n", branch)
+		return nil
+	}
+	if _, err := cc.git.ParseRev(ctx, target.String()); err != nil {
+		fmt.Fprintf(cc.stderr, "gg: %s has no commits yet and %s does not exist (run `gg pull` first)\n", branch, target)
+		return nil
+	}
+	return cc.git.NewBranch(ctx, branch, git.BranchOptions{
+		StartPoint: target.String(),
+		Checkout:   true,
+	})
+}
+
 // targetForUpdate returns the revision to use for fast-forwarding a
 // branch. If targetForUpdate returns an empty string, it means that no
 // target could be found. The ref returned may not exist.
@@ -797,3 +797,159 @@ func TestUpdate_Clean(t *testing.T) {
 		t.Errorf("foo.txt = %q; want %q", got, want)
 	}
 }
+
+func TestUpdate_Check(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	env, err := newTestEnv(ctx, t)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Create a repository with two commits.
+	if err := env.initEmptyRepo(ctx, "."); err != nil {
+		t.Fatal(err)
+	}
+	if err := env.root.Apply(filesystem.Write("foo.txt", "Apple\n")); err != nil {
+		t.Fatal(err)
+	}
+	if err := env.addFiles(ctx, "foo.txt"); err != nil {
+		t.Fatal(err)
+	}
+	h1, err := env.newCommit(ctx, ".")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := env.root.Apply(filesystem.Write("foo.txt", "Banana\n")); err != nil {
+		t.Fatal(err)
+	}
+	h2, err := env.newCommit(ctx, ".")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Introduce local changes in a different file.
+	if err := env.root.Apply(filesystem.Write("bar.txt", "Coconut\n")); err != nil {
+		t.Fatal(err)
+	}
+	if err := env.addFiles(ctx, "bar.txt"); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := env.gg(ctx, env.root.String(), "update", "--check", h1.String()); err == nil {
+		t.Error("update --check with local changes did not return an error")
+	} else if isUsage(err) {
+		t.Errorf("update --check returned usage error: %v", err)
+	}
+	if _, err := env.gg(ctx, env.root.String(), "update", "--check", "--clean", h1.String()); err == nil {
+		t.Error("update --check --clean did not return an error")
+	} else if !isUsage(err) {
+		t.Errorf("update --check --clean returned non-usage error: %v", err)
+	}
+
+	// Verify that HEAD is still the second commit.
+	if r, err := env.git.Head(ctx); err != nil {
+		t.Fatal(err)
+	} else if r.Commit != h2 {
+		names := map[git.Hash]string{
+			h1: "first commit",
+			h2: "second commit",
+		}
+		t.Errorf("after update --check, HEAD = %s; want %s",
+			prettyCommit(r.Commit, names),
+			prettyCommit(h2, names))
+	}
+}
+
+func TestUpdate_Date(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	env, err := newTestEnv(ctx, t)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := env.initEmptyRepo(ctx, "."); err != nil {
+		t.Fatal(err)
+	}
+	if err := env.root.Apply(filesystem.Write("foo.txt", "Apple\n")); err != nil {
+		t.Fatal(err)
+	}
+	if err := env.addFiles(ctx, "foo.txt"); err != nil {
+		t.Fatal(err)
+	}
+	h1, err := env.newCommit(ctx, ".")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := env.root.Apply(filesystem.Write("foo.txt", "Banana\n")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := env.newCommit(ctx, "."); err != nil {
+		t.Fatal(err)
+	}
+
+	// Both commits were made just now, so a date in the future selects the
+	// given revision and a date in the past has no commits.
+	if _, err := env.gg(ctx, env.root.String(), "update", "--date=2100-01-01", "HEAD~"); err != nil {
+		t.Fatal(err)
+	}
+	if r, err := env.git.Head(ctx); err != nil {
+		t.Fatal(err)
+	} else {
+		if r.Commit != h1 {
+			t.Errorf("after update --date, HEAD = %v; want %v", r.Commit, h1)
+		}
+		if r.Ref != git.Head {
+			t.Errorf("after update --date, HEAD ref = %s; want %s", r.Ref, git.Head)
+		}
+	}
+	if _, err := env.gg(ctx, env.root.String(), "update", "--date=1990-01-01", "main"); err == nil {
+		t.Error("update --date before first commit did not return an error")
+	}
+}
+
+func TestUpdate_EmptyRepo(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	env, err := newTestEnv(ctx, t)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := env.initRepoWithHistory(ctx, "origin"); err != nil {
+		t.Fatal(err)
+	}
+	if err := env.initEmptyRepo(ctx, "local"); err != nil {
+		t.Fatal(err)
+	}
+	localDir := env.root.FromSlash("local")
+
+	// Without an upstream, update is a no-op.
+	if _, err := env.gg(ctx, localDir, "update"); err != nil {
+		t.Error("before fetch:", err)
+	}
+
+	localGit := env.git.WithDir(localDir)
+	if err := localGit.Run(ctx, "remote", "add", "origin", env.root.FromSlash("origin")); err != nil {
+		t.Fatal(err)
+	}
+	if err := localGit.Run(ctx, "fetch", "--quiet", "origin"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := env.gg(ctx, localDir, "update"); err != nil {
+		t.Fatal(err)
+	}
+	originMain, err := env.git.WithDir(env.root.FromSlash("origin")).ParseRev(ctx, "main")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if head, err := localGit.Head(ctx); err != nil {
+		t.Error(err)
+	} else if head.Commit != originMain.Commit || head.Ref != git.BranchRef("main") {
+		t.Errorf("HEAD = %v (%v); want %v (refs/heads/main)", head.Commit, head.Ref, originMain.Commit)
+	}
+	if exists, err := env.root.Exists("local/.dummy"); err != nil {
+		t.Error(err)
+	} else if !exists {
+		t.Error("local/.dummy does not exist after update")
+	}
+}
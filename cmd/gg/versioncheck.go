@@ -0,0 +1,109 @@
+// Copyright 2021 The gg Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// latestReleaseURL is the GitHub API endpoint for gg's latest release.
+const latestReleaseURL = "https://api.github.com/repos/gg-scm/gg/releases/latest"
+
+// ggRelease describes a published gg release.
+type ggRelease struct {
+	Version string `json:"version"`
+	URL     string `json:"url"`
+}
+
+// fetchLatestRelease queries the GitHub releases API for the latest
+// release of gg.
+func fetchLatestRelease(ctx context.Context, client *http.Client) (*ggRelease, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, latestReleaseURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("check for update: %w", err)
+	}
+	req.Header.Set("User-Agent", userAgentString())
+	req.Header.Set("Accept", "application/vnd.github.v3+json")
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("check for update: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("check for update: %w", parseGitHubErrorResponse(resp))
+	}
+	var payload struct {
+		TagName string `json:"tag_name"`
+		HTMLURL string `json:"html_url"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&payload); err != nil {
+		return nil, fmt.Errorf("check for update: parsing response: %w", err)
+	}
+	if payload.TagName == "" {
+		return nil, fmt.Errorf("check for update: release has no tag")
+	}
+	return &ggRelease{
+		Version: strings.TrimPrefix(payload.TagName, "v"),
+		URL:     payload.HTMLURL,
+	}, nil
+}
+
+// isNewerRelease reports whether the release version latest is newer
+// than current. Versions are dotted numbers like "1.2.3", optionally
+// followed by a pre-release suffix like "-rc.1", which sorts before the
+// release itself. Versions that cannot be parsed are never newer.
+func isNewerRelease(current, latest string) bool {
+	cur, curPre, ok := parseReleaseVersion(current)
+	if !ok {
+		return false
+	}
+	lat, latPre, ok := parseReleaseVersion(latest)
+	if !ok {
+		return false
+	}
+	for i := 0; i < len(cur) || i < len(lat); i++ {
+		var c, l int
+		if i < len(cur) {
+			c = cur[i]
+		}
+		if i < len(lat) {
+			l = lat[i]
+		}
+		if c != l {
+			return l > c
+		}
+	}
+	return curPre != "" && latPre == ""
+}
+
+func parseReleaseVersion(v string) (nums []int, prerelease string, ok bool) {
+	v = strings.TrimPrefix(v, "v")
+	if i := strings.IndexByte(v, '-'); i != -1 {
+		v, prerelease = v[:i], v[i+1:]
+	}
+	for _, part := range strings.Split(v, ".") {
+		n, err := strconv.Atoi(part)
+		if err != nil || n < 0 {
+			return nil, "", false
+		}
+		nums = append(nums, n)
+	}
+	return nums, prerelease, true
+}
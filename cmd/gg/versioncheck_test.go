@@ -0,0 +1,93 @@
+// Copyright 2021 The gg Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestVersionCheckUpdate(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	env, err := newTestEnv(ctx, t)
+	if err != nil {
+		t.Fatal(err)
+	}
+	env.roundTripper = fakeReleaseTransport{}
+	out, err := env.gg(ctx, env.root.String(), "version", "--check-update")
+	if err != nil {
+		t.Fatal(err)
+	}
+	const want = "update: latest release is 99.0.0 (https://github.com/gg-scm/gg/releases/tag/v99.0.0)\n"
+	if !bytes.Contains(out, []byte(want)) {
+		t.Errorf("output does not contain %q. Output:\n%s", want, out)
+	}
+
+	env.roundTripper = stubRoundTripper{}
+	if _, err := env.gg(ctx, env.root.String(), "version", "--check-update"); err == nil {
+		t.Error("version --check-update with failing API did not return an error")
+	}
+}
+
+// fakeReleaseTransport serves a latest release from the GitHub API.
+type fakeReleaseTransport struct{}
+
+func (fakeReleaseTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	if r.Body != nil {
+		r.Body.Close()
+	}
+	if r.URL.String() != latestReleaseURL {
+		return stubRoundTripper{}.RoundTrip(r)
+	}
+	body := `{"tag_name": "v99.0.0", "html_url": "https://github.com/gg-scm/gg/releases/tag/v99.0.0"}`
+	return &http.Response{
+		StatusCode:    http.StatusOK,
+		Status:        "200 OK",
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        http.Header{"Content-Type": {"application/json"}},
+		Body:          ioutil.NopCloser(strings.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       r,
+	}, nil
+}
+
+func TestIsNewerRelease(t *testing.T) {
+	tests := []struct {
+		current, latest string
+		want            bool
+	}{
+		{"1.0.0", "1.0.0", false},
+		{"1.0.0", "1.0.1", true},
+		{"1.0.1", "1.0.0", false},
+		{"1.2.0", "1.10.0", true},
+		{"1.0", "1.0.1", true},
+		{"v1.0.0", "1.1.0", true},
+		{"1.1.0-rc.1", "1.1.0", true},
+		{"1.1.0", "1.1.0-rc.1", false},
+		{"dev", "1.0.0", false},
+	}
+	for _, test := range tests {
+		if got := isNewerRelease(test.current, test.latest); got != test.want {
+			t.Errorf("isNewerRelease(%q, %q) = %t; want %t", test.current, test.latest, got, test.want)
+		}
+	}
+}
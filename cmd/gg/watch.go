@@ -0,0 +1,136 @@
+// Copyright 2021 The gg Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"gg-scm.io/pkg/git"
+	"gg-scm.io/tool/internal/flag"
+	"gg-scm.io/tool/internal/terminal"
+)
+
+const watchSynopsis = "show a live-updating summary of the working copy"
+
+func watch(ctx context.Context, cc *cmdContext, args []string) error {
+	f := flag.NewFlagSet(true, "gg watch [--interval=SECONDS] [--check] [--once]", watchSynopsis+`
+
+	watch polls the working copy and prints the current branch, how far
+	it is ahead of or behind its upstream, and the output of `+"`gg status`"+`.
+	The summary is redrawn whenever it changes. Press Ctrl-C to stop.
+
+	With `+"`--check`"+`, the checks configured for `+"`gg precommit`"+` are run
+	against the changed files every time the summary changes and their
+	result is shown below the summary.`)
+	interval := f.Int("interval", 1, "`seconds` to wait between polls")
+	check := f.Bool("check", false, "run precommit checks on every change")
+	once := f.Bool("once", false, "print the summary once and exit")
+	if err := f.Parse(args); flag.IsHelp(err) {
+		f.Help(cc.stdout)
+		return nil
+	} else if err != nil {
+		return usagef("%v", err)
+	}
+	if f.NArg() > 0 {
+		return usagef("watch takes no arguments")
+	}
+	if *interval < 1 {
+		return usagef("--interval must be at least 1")
+	}
+	clearScreen := !*once && terminal.IsTerminal(cc.stdout)
+	var prev []byte
+	for first := true; ; first = false {
+		summary, err := watchSummary(ctx, cc)
+		if err != nil {
+			return err
+		}
+		if first || !bytes.Equal(summary, prev) {
+			out := new(bytes.Buffer)
+			if clearScreen {
+				out.WriteString("\x1b[H\x1b[2J")
+			} else if !first {
+				out.WriteString("\n")
+			}
+			out.Write(summary)
+			if *check {
+				out.WriteString("\n")
+				checkCC := *cc
+				checkCC.stdout = out
+				checkCC.stderr = out
+				if err := runChecks(ctx, &checkCC, nil); err != nil {
+					fmt.Fprintf(out, "checks: %v\n", err)
+				} else {
+					out.WriteString("checks: passed\n")
+				}
+			}
+			if _, err := cc.stdout.Write(out.Bytes()); err != nil {
+				return err
+			}
+			prev = summary
+		}
+		if *once {
+			return nil
+		}
+		select {
+		case <-time.After(time.Duration(*interval) * time.Second):
+		case <-ctx.Done():
+			return nil
+		}
+	}
+}
+
+// watchSummary returns the text that watch displays for the current
+// state of the working copy, not including check results.
+func watchSummary(ctx context.Context, cc *cmdContext) ([]byte, error) {
+	buf := new(bytes.Buffer)
+	if b := currentBranch(ctx, cc); b != "" {
+		fmt.Fprintf(buf, "branch: %s", b)
+		upstream := b + "@{upstream}"
+		if _, err := cc.git.ParseRev(ctx, upstream); err == nil {
+			counts, err := cc.git.Output(ctx, "rev-list", "--left-right", "--count", upstream+"..."+git.Head.String())
+			if err != nil {
+				return nil, err
+			}
+			var behind, ahead int
+			if _, err := fmt.Sscan(counts, &behind, &ahead); err != nil {
+				return nil, fmt.Errorf("parse rev-list counts: %w", err)
+			}
+			fmt.Fprintf(buf, " (%d ahead, %d behind %s)", ahead, behind, upstream)
+		}
+		buf.WriteString("\n")
+	} else if head, err := cc.git.Head(ctx); err == nil {
+		fmt.Fprintf(buf, "branch: (detached at %v)\n", head.Commit.Short())
+	} else {
+		buf.WriteString("branch: (no commits)\n")
+	}
+
+	statusOut := new(bytes.Buffer)
+	statusCC := *cc
+	statusCC.stdout = statusOut
+	if err := status(ctx, &statusCC, nil); err != nil {
+		return nil, err
+	}
+	if statusOut.Len() == 0 {
+		buf.WriteString("working copy clean\n")
+	} else {
+		buf.WriteString(strings.TrimRight(statusOut.String(), "\n"))
+		buf.WriteString("\n")
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,253 @@
+// Copyright 2021 The gg Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"gg-scm.io/pkg/git"
+	"gg-scm.io/pkg/git/object"
+)
+
+// workingState is an overview of the working copy: what is checked out,
+// what has changed, and what operation is in progress.
+type workingState struct {
+	// branch is the checked out branch. It is empty if HEAD is detached.
+	branch string
+	// parent is the commit that the working copy is based on. It is nil
+	// if the branch does not have any commits yet.
+	parent *object.Commit
+
+	added     int
+	modified  int
+	removed   int
+	missing   int
+	unmerged  int
+	untracked int
+
+	// op is the multi-step operation that has stopped partway through,
+	// if any.
+	op workTreeOperation
+}
+
+// readWorkingState inspects the working copy's HEAD, status, and in-progress
+// operations.
+func readWorkingState(ctx context.Context, g *git.Git) (*workingState, error) {
+	ws := new(workingState)
+	headRef, err := g.HeadRef(ctx)
+	if err != nil {
+		return nil, err
+	}
+	ws.branch = headRef.Branch()
+	if unborn, err := unbornBranch(ctx, g); err != nil {
+		return nil, err
+	} else if unborn == "" {
+		ws.parent, err = g.CommitInfo(ctx, git.Head.String())
+		if err != nil {
+			return nil, err
+		}
+	}
+	st, err := g.Status(ctx, git.StatusOptions{})
+	if err != nil {
+		return nil, err
+	}
+	for _, ent := range st {
+		switch {
+		case ent.Code.IsUnmerged():
+			ws.unmerged++
+		case ent.Code.IsMissing():
+			ws.missing++
+		case ent.Code.IsUntracked():
+			ws.untracked++
+		case ent.Code.IsAdded() || ent.Code.IsCopied() || ent.Code.IsRenamed():
+			ws.added++
+		case ent.Code.IsRemoved():
+			ws.removed++
+		case ent.Code.IsModified():
+			ws.modified++
+		}
+	}
+	ws.op, err = readWorkTreeOperation(ctx, g)
+	if err != nil {
+		return nil, err
+	}
+	return ws, nil
+}
+
+// describeChanges returns a summary of the changed files, like
+// "2 modified, 1 untracked", or "(clean)" if nothing has changed.
+func (ws *workingState) describeChanges() string {
+	var parts []string
+	for _, c := range []struct {
+		n    int
+		desc string
+	}{
+		{ws.modified, "modified"},
+		{ws.added, "added"},
+		{ws.removed, "removed"},
+		{ws.missing, "deleted"},
+		{ws.unmerged, "unresolved"},
+		{ws.untracked, "untracked"},
+	} {
+		if c.n > 0 {
+			parts = append(parts, strconv.Itoa(c.n)+" "+c.desc)
+		}
+	}
+	if len(parts) == 0 {
+		return "(clean)"
+	}
+	return strings.Join(parts, ", ")
+}
+
+// A workTreeOperation is a multi-step operation that can stop partway
+// through to let the user resolve conflicts or test a commit.
+type workTreeOperation int
+
+const (
+	noOperation workTreeOperation = iota
+	mergeOperation
+	rebaseOperation
+	histeditOperation
+	amOperation
+	cherryPickOperation
+	revertOperation
+	graftOperation
+	bisectOperation
+)
+
+// readWorkTreeOperation determines which operation is in progress in the
+// working copy by looking for the state files that Git and gg leave in
+// the Git directory. If more than one is in progress (for example, a
+// merge while bisecting), then the operation started last is returned.
+func readWorkTreeOperation(ctx context.Context, g *git.Git) (workTreeOperation, error) {
+	gitDir, err := g.GitDir(ctx)
+	if err != nil {
+		return noOperation, err
+	}
+	exists := func(name string) (bool, error) {
+		_, err := os.Stat(filepath.Join(gitDir, filepath.FromSlash(name)))
+		if os.IsNotExist(err) {
+			return false, nil
+		}
+		return err == nil, err
+	}
+	checks := []struct {
+		op   workTreeOperation
+		file string
+	}{
+		// graft uses git cherry-pick, so check for it before cherry-pick.
+		{graftOperation, graftStateFilename},
+		{histeditOperation, histeditMarkerFilename},
+		{rebaseOperation, "rebase-merge"},
+		{amOperation, "rebase-apply/applying"},
+		{rebaseOperation, "rebase-apply"},
+		{cherryPickOperation, "CHERRY_PICK_HEAD"},
+		{revertOperation, "REVERT_HEAD"},
+		{cherryPickOperation, "sequencer"},
+		{mergeOperation, "MERGE_HEAD"},
+		{bisectOperation, "BISECT_START"},
+	}
+	for _, c := range checks {
+		found, err := exists(c.file)
+		if err != nil {
+			return noOperation, err
+		}
+		if !found {
+			continue
+		}
+		if c.op == histeditOperation {
+			// The marker is only cleaned up lazily, so make sure the
+			// rebase is still going.
+			if found, err = exists("rebase-merge/interactive"); err != nil {
+				return noOperation, err
+			} else if !found {
+				continue
+			}
+		}
+		return c.op, nil
+	}
+	return noOperation, nil
+}
+
+// String returns the operation's name, like "rebase".
+func (op workTreeOperation) String() string {
+	switch op {
+	case noOperation:
+		return "none"
+	case mergeOperation:
+		return "merge"
+	case rebaseOperation:
+		return "rebase"
+	case histeditOperation:
+		return "histedit"
+	case amOperation:
+		return "import"
+	case cherryPickOperation:
+		return "cherry-pick"
+	case revertOperation:
+		return "revert"
+	case graftOperation:
+		return "graft"
+	case bisectOperation:
+		return "bisect"
+	default:
+		return fmt.Sprintf("workTreeOperation(%d)", int(op))
+	}
+}
+
+// nextCommands returns the commands that finish or abandon the operation.
+func (op workTreeOperation) nextCommands() (continueCmd, abortCmd string) {
+	switch op {
+	case mergeOperation:
+		return "gg commit", "gg merge --abort"
+	case rebaseOperation:
+		return "gg rebase --continue", "gg rebase --abort"
+	case histeditOperation:
+		return "gg histedit --continue", "gg histedit --abort"
+	case amOperation:
+		return "gg import --continue", "gg import --abort"
+	case cherryPickOperation:
+		return "git cherry-pick --continue", "git cherry-pick --abort"
+	case revertOperation:
+		return "gg commit", "git revert --abort"
+	case graftOperation:
+		return "gg graft --continue", "gg graft --abort"
+	case bisectOperation:
+		return "gg bisect --good HEAD or gg bisect --bad HEAD", "gg bisect --reset"
+	default:
+		return "", ""
+	}
+}
+
+// writeOperationBanner prints a notice about the operation in progress
+// and how to finish it, if there is one.
+func writeOperationBanner(w io.Writer, op workTreeOperation) error {
+	if op == noOperation {
+		return nil
+	}
+	continueCmd, abortCmd := op.nextCommands()
+	_, err := fmt.Fprintf(w, "\n"+
+		"# The working copy is in an unfinished %s state.\n"+
+		"# To continue:    %s\n"+
+		"# To abort:       %s\n",
+		op, continueCmd, abortCmd)
+	return err
+}
@@ -0,0 +1,103 @@
+// Copyright 2021 The gg Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"gg-scm.io/pkg/git"
+	"gg-scm.io/tool/internal/filesystem"
+)
+
+func TestReadWorkTreeOperation(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	env, err := newTestEnv(ctx, t)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := env.initEmptyRepo(ctx, "."); err != nil {
+		t.Fatal(err)
+	}
+	if err := env.root.Apply(filesystem.Write("foo.txt", "original\n")); err != nil {
+		t.Fatal(err)
+	}
+	if err := env.addFiles(ctx, "foo.txt"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := env.newCommit(ctx, "."); err != nil {
+		t.Fatal(err)
+	}
+	if err := env.git.NewBranch(ctx, "topic", git.BranchOptions{Checkout: true}); err != nil {
+		t.Fatal(err)
+	}
+	if err := env.root.Apply(filesystem.Write("foo.txt", "topic\n")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := env.newCommit(ctx, "."); err != nil {
+		t.Fatal(err)
+	}
+	if err := env.git.CheckoutBranch(ctx, "main", git.CheckoutOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := env.root.Apply(filesystem.Write("foo.txt", "main\n")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := env.newCommit(ctx, "."); err != nil {
+		t.Fatal(err)
+	}
+
+	if op, err := readWorkTreeOperation(ctx, env.git); err != nil {
+		t.Fatal(err)
+	} else if op != noOperation {
+		t.Errorf("before merge, operation = %v; want %v", op, noOperation)
+	}
+	if err := env.git.Run(ctx, "merge", "topic"); err == nil {
+		t.Fatal("merge did not produce conflicts")
+	}
+	if op, err := readWorkTreeOperation(ctx, env.git); err != nil {
+		t.Fatal(err)
+	} else if op != mergeOperation {
+		t.Errorf("during merge, operation = %v; want %v", op, mergeOperation)
+	}
+	out, err := env.gg(ctx, env.root.String(), "status")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(out), "# The working copy is in an unfinished merge state.") {
+		t.Errorf("status output:\n%s\nwant merge banner", out)
+	}
+	if err := env.git.Run(ctx, "merge", "--abort"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := env.git.Run(ctx, "rebase", "topic"); err == nil {
+		t.Fatal("rebase did not produce conflicts")
+	}
+	if op, err := readWorkTreeOperation(ctx, env.git); err != nil {
+		t.Fatal(err)
+	} else if op != rebaseOperation {
+		t.Errorf("during rebase, operation = %v; want %v", op, rebaseOperation)
+	}
+	out, err = env.gg(ctx, env.root.String(), "summary")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(out), "gg rebase --continue") {
+		t.Errorf("summary output:\n%s\nwant rebase instructions", out)
+	}
+}
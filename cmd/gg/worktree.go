@@ -0,0 +1,230 @@
+// Copyright 2021 The gg Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"gg-scm.io/pkg/git"
+	"gg-scm.io/tool/internal/flag"
+)
+
+const worktreeSynopsis = "manage additional working trees"
+
+func worktree(ctx context.Context, cc *cmdContext, args []string) error {
+	if len(args) > 0 {
+		switch args[0] {
+		case "add":
+			return worktreeAdd(ctx, cc, args[1:])
+		case "list":
+			return worktreeList(ctx, cc, args[1:])
+		case "remove":
+			return worktreeRemove(ctx, cc, args[1:])
+		}
+	}
+	f := flag.NewFlagSet(true, "gg worktree add [-b BRANCH] [-r REV] DIR\n"+
+		"gg worktree list\n"+
+		"gg worktree remove [-f] DIR", worktreeSynopsis+`
+
+	A repository can have more than one working tree, each with its own
+	checked out branch, index, and in-progress operations, while sharing
+	commits, branches, and configuration. This lets you work on several
+	branches at once without stashing or cloning. gg commands work the
+	same in any of the working trees.`)
+	if err := f.Parse(args); flag.IsHelp(err) {
+		f.Help(cc.stdout)
+		return nil
+	} else if err != nil {
+		return usagef("%v", err)
+	}
+	if f.NArg() == 0 {
+		return usagef("missing subcommand")
+	}
+	return usagef("unknown subcommand %q", f.Arg(0))
+}
+
+const worktreeAddSynopsis = "create a new working tree"
+
+func worktreeAdd(ctx context.Context, cc *cmdContext, args []string) error {
+	f := flag.NewFlagSet(true, "gg worktree add [-b BRANCH] [-r REV] DIR", worktreeAddSynopsis+`
+
+	Create a working tree at the given directory. With `+"`-b`"+`, a new
+	branch is created at the given revision (defaults to HEAD) and checked
+	out in the new working tree. Otherwise, the given revision is checked
+	out: a branch is checked out directly and anything else leaves the new
+	working tree with a detached HEAD. If neither option is given, a new
+	branch named after the directory is created at HEAD.
+
+	A branch can only be checked out in one working tree at a time.`)
+	branch := f.String("b", "", "create a new `branch`")
+	f.Alias("b", "branch")
+	rev := f.String("r", "", "`rev`ision to check out")
+	if err := f.Parse(args); flag.IsHelp(err) {
+		f.Help(cc.stdout)
+		return nil
+	} else if err != nil {
+		return usagef("%v", err)
+	}
+	if f.NArg() != 1 {
+		return usagef("must pass exactly one directory")
+	}
+	addArgs := []string{"worktree", "add"}
+	if *branch != "" {
+		addArgs = append(addArgs, "-b", *branch)
+	}
+	addArgs = append(addArgs, "--", cc.abs(f.Arg(0)))
+	if *rev != "" {
+		addArgs = append(addArgs, *rev)
+	}
+	return cc.interactiveGit(ctx, addArgs...)
+}
+
+const worktreeListSynopsis = "list working trees"
+
+func worktreeList(ctx context.Context, cc *cmdContext, args []string) error {
+	f := flag.NewFlagSet(true, "gg worktree list", worktreeListSynopsis+`
+
+	List the repository's working trees with the commit and branch that
+	each one has checked out. The current working tree is marked with
+	an asterisk.`)
+	if err := f.Parse(args); flag.IsHelp(err) {
+		f.Help(cc.stdout)
+		return nil
+	} else if err != nil {
+		return usagef("%v", err)
+	}
+	if f.NArg() > 0 {
+		return usagef("worktree list takes no arguments")
+	}
+	out, err := cc.git.Output(ctx, "worktree", "list", "--porcelain")
+	if err != nil {
+		return err
+	}
+	trees, err := parseWorktreeList(out)
+	if err != nil {
+		return err
+	}
+	current := ""
+	if top, err := cc.git.WorkTree(ctx); err == nil {
+		current = filepath.Clean(top)
+	}
+	width := 0
+	for _, wt := range trees {
+		if len(wt.path) > width {
+			width = len(wt.path)
+		}
+	}
+	for _, wt := range trees {
+		marker := " "
+		if filepath.Clean(wt.path) == current {
+			marker = "*"
+		}
+		desc := wt.head.Short()
+		switch {
+		case wt.bare:
+			desc = "(bare)"
+		case wt.branch != "":
+			desc += " " + wt.branch
+		default:
+			desc += " (detached)"
+		}
+		if wt.locked {
+			desc += " (locked)"
+		}
+		if _, err := fmt.Fprintf(cc.stdout, "%s %-*s  %s\n", marker, width, wt.path, desc); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// worktreeInfo is an entry in the output of `git worktree list --porcelain`.
+type worktreeInfo struct {
+	path   string
+	head   git.Hash
+	branch string
+	bare   bool
+	locked bool
+}
+
+// parseWorktreeList parses the output of `git worktree list --porcelain`.
+func parseWorktreeList(out string) ([]*worktreeInfo, error) {
+	var trees []*worktreeInfo
+	var curr *worktreeInfo
+	for _, line := range strings.Split(out, "\n") {
+		if line == "" {
+			curr = nil
+			continue
+		}
+		key, value := line, ""
+		if i := strings.IndexByte(line, ' '); i != -1 {
+			key, value = line[:i], line[i+1:]
+		}
+		if key == "worktree" {
+			curr = &worktreeInfo{path: value}
+			trees = append(trees, curr)
+			continue
+		}
+		if curr == nil {
+			return nil, fmt.Errorf("parse worktree list: %q before worktree", line)
+		}
+		switch key {
+		case "HEAD":
+			h, err := git.ParseHash(value)
+			if err != nil {
+				return nil, fmt.Errorf("parse worktree list: %w", err)
+			}
+			curr.head = h
+		case "branch":
+			curr.branch = git.Ref(value).Branch()
+		case "bare":
+			curr.bare = true
+		case "locked":
+			curr.locked = true
+		}
+	}
+	return trees, nil
+}
+
+const worktreeRemoveSynopsis = "remove a working tree"
+
+func worktreeRemove(ctx context.Context, cc *cmdContext, args []string) error {
+	f := flag.NewFlagSet(true, "gg worktree remove [-f] DIR", worktreeRemoveSynopsis+`
+
+	Remove a working tree created by `+"`gg worktree add`"+`. The working
+	tree must not have any uncommitted changes or untracked files unless
+	`+"`-f`"+` is given. The branch checked out in the working tree is
+	left alone.`)
+	force := f.Bool("f", false, "remove the working tree even if it has changes")
+	f.Alias("f", "force")
+	if err := f.Parse(args); flag.IsHelp(err) {
+		f.Help(cc.stdout)
+		return nil
+	} else if err != nil {
+		return usagef("%v", err)
+	}
+	if f.NArg() != 1 {
+		return usagef("must pass exactly one directory")
+	}
+	removeArgs := []string{"worktree", "remove"}
+	if *force {
+		removeArgs = append(removeArgs, "--force")
+	}
+	removeArgs = append(removeArgs, "--", cc.abs(f.Arg(0)))
+	return cc.git.Run(ctx, removeArgs...)
+}
@@ -0,0 +1,92 @@
+// Copyright 2021 The gg Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"gg-scm.io/tool/internal/filesystem"
+)
+
+func TestWorktree(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	env, err := newTestEnv(ctx, t)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := env.initRepoWithHistory(ctx, "repo"); err != nil {
+		t.Fatal(err)
+	}
+	repoPath := env.root.FromSlash("repo")
+	wtPath := env.root.FromSlash("wt")
+	if _, err := env.gg(ctx, repoPath, "worktree", "add", "-b", "feature", "../wt"); err != nil {
+		t.Fatal(err)
+	}
+
+	// Commit in the linked working tree.
+	if err := env.root.Apply(filesystem.Write("wt/foo.txt", "Hello, World!\n")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := env.gg(ctx, wtPath, "add", "foo.txt"); err != nil {
+		t.Fatal(err)
+	}
+	if out, err := env.gg(ctx, wtPath, "status"); err != nil {
+		t.Fatal(err)
+	} else if !strings.Contains(string(out), "foo.txt") {
+		t.Errorf("status in linked working tree = %q; want to contain foo.txt", out)
+	}
+	if _, err := env.gg(ctx, wtPath, "commit", "-m", "commit in worktree"); err != nil {
+		t.Fatal(err)
+	}
+	feature, err := env.git.WithDir(repoPath).CommitInfo(ctx, "feature")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := feature.Summary(), "commit in worktree"; got != want {
+		t.Errorf("feature summary = %q; want %q", got, want)
+	}
+	if exists, err := env.root.Exists("repo/foo.txt"); err != nil {
+		t.Error(err)
+	} else if exists {
+		t.Error("commit in linked working tree changed main working tree")
+	}
+
+	out, err := env.gg(ctx, wtPath, "worktree", "list")
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSuffix(string(out), "\n"), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("worktree list = %q; want 2 lines", out)
+	}
+	if !strings.HasPrefix(lines[0], " ") || !strings.HasSuffix(lines[0], " main") {
+		t.Errorf("worktree list line 1 = %q; want unmarked main", lines[0])
+	}
+	if !strings.HasPrefix(lines[1], "*") || !strings.HasSuffix(lines[1], " feature") {
+		t.Errorf("worktree list line 2 = %q; want marked feature", lines[1])
+	}
+
+	if _, err := env.gg(ctx, repoPath, "worktree", "remove", "../wt"); err != nil {
+		t.Fatal(err)
+	}
+	if exists, err := env.root.Exists("wt"); err != nil {
+		t.Error(err)
+	} else if exists {
+		t.Error("wt exists after worktree remove")
+	}
+}
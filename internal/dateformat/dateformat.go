@@ -0,0 +1,124 @@
+// Copyright 2021 The gg Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package dateformat formats timestamps for display in gg's output.
+package dateformat
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Style is a way of displaying a timestamp.
+type Style int
+
+// Date styles.
+const (
+	// Default displays the time in the timezone it was recorded in,
+	// like "Mon Jan 02 15:04:05 2006 -0700".
+	Default Style = iota
+	// Relative displays the time relative to the current time,
+	// like "3 hours ago".
+	Relative
+	// ISO displays the time in an ISO 8601-like format,
+	// like "2006-01-02 15:04:05 -0700".
+	ISO
+	// Local displays the time like Default, but in the local timezone.
+	Local
+)
+
+var styleNames = [...]string{
+	Default:  "default",
+	Relative: "relative",
+	ISO:      "iso",
+	Local:    "local",
+}
+
+// Parse parses a style name. The empty string is treated as Default.
+func Parse(s string) (Style, error) {
+	if s == "" {
+		return Default, nil
+	}
+	for style, name := range styleNames {
+		if strings.EqualFold(s, name) {
+			return Style(style), nil
+		}
+	}
+	return Default, fmt.Errorf("unknown date format %q (must be one of %s)", s, strings.Join(styleNames[:], ", "))
+}
+
+// String returns the style's name as accepted by Parse.
+func (style Style) String() string {
+	if style < 0 || int(style) >= len(styleNames) {
+		return fmt.Sprintf("Style(%d)", int(style))
+	}
+	return styleNames[style]
+}
+
+// GitArg returns the argument to Git's --date option that produces
+// the same style.
+func (style Style) GitArg() string {
+	return style.String()
+}
+
+// Format formats t in the style. now is used as the reference point
+// for relative times.
+func (style Style) Format(t, now time.Time) string {
+	switch style {
+	case Relative:
+		return formatRelative(now.Sub(t))
+	case ISO:
+		return t.Format("2006-01-02 15:04:05 -0700")
+	case Local:
+		return t.Local().Format("Mon Jan 02 15:04:05 2006")
+	default:
+		return t.Format("Mon Jan 02 15:04:05 2006 -0700")
+	}
+}
+
+// formatRelative formats a duration in the past using the same
+// thresholds as Git's relative dates.
+func formatRelative(d time.Duration) string {
+	if d < 0 {
+		return "in the future"
+	}
+	seconds := int64(d / time.Second)
+	switch {
+	case seconds < 90:
+		return plural(seconds, "second")
+	case seconds < 90*60:
+		return plural((seconds+30)/60, "minute")
+	case seconds < 36*60*60:
+		return plural((seconds+30*60)/(60*60), "hour")
+	}
+	days := (seconds + 12*60*60) / (24 * 60 * 60)
+	switch {
+	case days < 14:
+		return plural(days, "day")
+	case days < 70:
+		return plural((days+3)/7, "week")
+	case days < 365:
+		return plural((days+15)/30, "month")
+	default:
+		return plural((days+183)/365, "year")
+	}
+}
+
+func plural(n int64, unit string) string {
+	if n == 1 {
+		return "1 " + unit + " ago"
+	}
+	return fmt.Sprintf("%d %ss ago", n, unit)
+}
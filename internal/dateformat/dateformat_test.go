@@ -0,0 +1,78 @@
+// Copyright 2021 The gg Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dateformat
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		s       string
+		want    Style
+		wantErr bool
+	}{
+		{s: "", want: Default},
+		{s: "default", want: Default},
+		{s: "relative", want: Relative},
+		{s: "ISO", want: ISO},
+		{s: "local", want: Local},
+		{s: "rfc2822", wantErr: true},
+	}
+	for _, test := range tests {
+		got, err := Parse(test.s)
+		if err != nil {
+			if !test.wantErr {
+				t.Errorf("Parse(%q) = _, %v; want %v, <nil>", test.s, err, test.want)
+			}
+			continue
+		}
+		if test.wantErr {
+			t.Errorf("Parse(%q) = %v, <nil>; want error", test.s, got)
+			continue
+		}
+		if got != test.want {
+			t.Errorf("Parse(%q) = %v, <nil>; want %v, <nil>", test.s, got, test.want)
+		}
+	}
+}
+
+func TestFormat(t *testing.T) {
+	tz := time.FixedZone("UTC-7", -7*60*60)
+	when := time.Date(2021, time.March, 4, 15, 4, 5, 0, tz)
+	tests := []struct {
+		style Style
+		now   time.Time
+		want  string
+	}{
+		{style: Default, now: when, want: "Thu Mar 04 15:04:05 2021 -0700"},
+		{style: ISO, now: when, want: "2021-03-04 15:04:05 -0700"},
+		{style: Relative, now: when.Add(30 * time.Second), want: "30 seconds ago"},
+		{style: Relative, now: when.Add(1 * time.Second), want: "1 second ago"},
+		{style: Relative, now: when.Add(10 * time.Minute), want: "10 minutes ago"},
+		{style: Relative, now: when.Add(5 * time.Hour), want: "5 hours ago"},
+		{style: Relative, now: when.Add(3 * 24 * time.Hour), want: "3 days ago"},
+		{style: Relative, now: when.Add(21 * 24 * time.Hour), want: "3 weeks ago"},
+		{style: Relative, now: when.Add(120 * 24 * time.Hour), want: "4 months ago"},
+		{style: Relative, now: when.Add(800 * 24 * time.Hour), want: "2 years ago"},
+		{style: Relative, now: when.Add(-time.Hour), want: "in the future"},
+	}
+	for _, test := range tests {
+		if got := test.style.Format(when, test.now); got != test.want {
+			t.Errorf("%v.Format(%v, %v) = %q; want %q", test.style, when, test.now, got, test.want)
+		}
+	}
+}
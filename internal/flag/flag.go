@@ -237,8 +237,13 @@ func (f *FlagSet) Arg(i int) string {
 	return f.args[i]
 }
 
-// Help prints the help.
+// Help prints the help. If w implements HelpRecorder, then Help passes
+// the flag set's description to w.RecordHelp instead.
 func (f *FlagSet) Help(w io.Writer) {
+	if r, ok := w.(HelpRecorder); ok {
+		r.RecordHelp(f.Describe())
+		return
+	}
 	var buf bytes.Buffer
 	if f.usage != "" {
 		buf.WriteString("usage: ")
@@ -264,6 +269,66 @@ func (f *FlagSet) Help(w io.Writer) {
 	}
 }
 
+// A HelpRecorder is a writer that receives help in structured form.
+type HelpRecorder interface {
+	io.Writer
+	RecordHelp(*Description)
+}
+
+// A Description is a structured form of a flag set's help.
+type Description struct {
+	Usage       string
+	Description string
+	// Flags is the list of defined flags, sorted by name.
+	Flags []*FlagDescription
+}
+
+// A FlagDescription describes a single flag.
+type FlagDescription struct {
+	Name    string
+	Aliases []string
+	// Arg is the name of the flag's argument. It is empty for boolean flags.
+	Arg   string
+	Usage string
+	// Default is the flag's default value. It is empty if the default is
+	// the zero value.
+	Default string
+	// Repeated is true if the flag may be given more than once.
+	Repeated bool
+}
+
+// Describe returns the flag set's usage information and flags.
+func (f *FlagSet) Describe() *Description {
+	desc := &Description{
+		Usage:       f.usage,
+		Description: f.description,
+	}
+	names := make([]string, 0, len(f.flags))
+	for name, ff := range f.flags {
+		if ff.name == name {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	for _, k := range names {
+		ff := f.flags[k]
+		arg, usage := unquoteUsage(ff.value, ff.usage)
+		fd := &FlagDescription{
+			Name:    ff.name,
+			Aliases: append([]string(nil), ff.aliases...),
+			Arg:     arg,
+			Usage:   usage,
+		}
+		sort.Strings(fd.Aliases)
+		if ff.defValue != "0" && ff.defValue != "false" {
+			fd.Default = ff.defValue
+		}
+		_, fd.Repeated = ff.value.(*multiStringValue)
+		desc.Flags = append(desc.Flags, fd)
+	}
+	return desc
+}
+
 // printDefaults prints the default values of all defined command-line
 // flags in the set to the given writer.
 func (f *FlagSet) printDefaults(w io.Writer) {
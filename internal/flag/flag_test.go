@@ -16,6 +16,8 @@ package flag
 
 import (
 	"testing"
+
+	"github.com/google/go-cmp/cmp"
 )
 
 func TestParse(t *testing.T) {
@@ -178,3 +180,26 @@ func stringsEqual(a, b []string) bool {
 	}
 	return true
 }
+
+func TestDescribe(t *testing.T) {
+	f := NewFlagSet(true, "foo [-x] [-o FILE]", "do foo")
+	f.Bool("x", false, "boolean flag")
+	f.String("o", "", "write to `file`")
+	f.Alias("o", "output")
+	f.String("rev", "HEAD", "revision")
+	f.MultiString("exclude", "exclude `pattern`")
+	got := f.Describe()
+	want := &Description{
+		Usage:       "foo [-x] [-o FILE]",
+		Description: "do foo",
+		Flags: []*FlagDescription{
+			{Name: "exclude", Arg: "pattern", Usage: "exclude pattern", Repeated: true},
+			{Name: "o", Aliases: []string{"output"}, Arg: "file", Usage: "write to file"},
+			{Name: "rev", Arg: "string", Usage: "revision", Default: "HEAD"},
+			{Name: "x", Usage: "boolean flag"},
+		},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("Describe() (-want +got):\n%s", diff)
+	}
+}
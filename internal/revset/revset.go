@@ -0,0 +1,290 @@
+// Copyright 2021 The gg Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package revset parses revision sets, a small language for selecting
+// commits modeled after Mercurial's revsets, and compiles them into
+// arguments for git rev-list.
+//
+// The supported expressions are:
+//
+//	REV            the commit REV
+//	A::B           descendants of A that are ancestors of B, including A and B
+//	A::            descendants of A on any local branch, including A
+//	::B            ancestors of B, including B
+//	ancestors(X)   ancestors of X, including X, where X is a revision or heads()
+//	heads()        the tips of the local branches
+//	draft()        commits on local branches that are not on any
+//	               remote-tracking branch, i.e. commits not pushed upstream
+//	author(PAT)    commits on local branches whose author matches the
+//	               regular expression PAT, which may be quoted
+//
+// A::B and A:: use the commit argument to --ancestry-path, which
+// requires Git 2.38 or later.
+package revset
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// A Set is a parsed revision set expression.
+type Set struct {
+	noWalk       bool
+	ancestryPath string
+	author       string
+	include      []string
+	exclude      []string
+}
+
+// IsExpression reports whether s uses revision set syntax, as opposed to
+// being a plain Git revision like "main" or "HEAD~2..HEAD". Git
+// revisions that start with a single colon (like ":/fix bug") are never
+// expressions.
+func IsExpression(s string) bool {
+	s = strings.TrimSpace(s)
+	if strings.HasPrefix(s, ":") && !strings.HasPrefix(s, "::") {
+		return false
+	}
+	if strings.Contains(s, "::") {
+		return true
+	}
+	i := strings.IndexByte(s, '(')
+	return i > 0 && isFunction(strings.TrimSpace(s[:i]))
+}
+
+func isFunction(name string) bool {
+	switch name {
+	case "ancestors", "author", "draft", "heads":
+		return true
+	default:
+		return false
+	}
+}
+
+// Parse parses a revision set expression.
+func Parse(expr string) (*Set, error) {
+	p := &parser{s: expr}
+	set, err := p.expr()
+	if err != nil {
+		return nil, fmt.Errorf("revset %q: %w", expr, err)
+	}
+	p.skipSpace()
+	if p.pos < len(p.s) {
+		return nil, fmt.Errorf("revset %q: unexpected %q", expr, p.s[p.pos:])
+	}
+	return set, nil
+}
+
+// UsesAncestryPathCommit reports whether the set's arguments pass a
+// commit to --ancestry-path, which requires Git 2.38 or later.
+func (set *Set) UsesAncestryPathCommit() bool {
+	return set.ancestryPath != ""
+}
+
+// Args returns the arguments to pass to git rev-list (or git log) to
+// list the commits in the set.
+func (set *Set) Args() []string {
+	var args []string
+	if set.noWalk {
+		args = append(args, "--no-walk")
+	}
+	if set.ancestryPath != "" {
+		args = append(args, "--ancestry-path="+set.ancestryPath)
+	}
+	if set.author != "" {
+		args = append(args, "--author="+set.author)
+	}
+	args = append(args, set.include...)
+	if len(set.exclude) > 0 {
+		args = append(args, "--not")
+		args = append(args, set.exclude...)
+	}
+	return args
+}
+
+type parser struct {
+	s   string
+	pos int
+}
+
+func (p *parser) expr() (*Set, error) {
+	p.skipSpace()
+	if p.consume("::") {
+		to, err := p.revision()
+		if err != nil {
+			return nil, err
+		}
+		return &Set{include: []string{to}}, nil
+	}
+	name := p.word()
+	if name == "" {
+		return nil, p.unexpected("revision")
+	}
+	p.skipSpace()
+	if p.consume("(") {
+		set, err := p.call(name)
+		if err != nil {
+			return nil, err
+		}
+		p.skipSpace()
+		if strings.HasPrefix(p.s[p.pos:], "::") {
+			return nil, fmt.Errorf("range endpoints must be revisions, not %s()", name)
+		}
+		return set, nil
+	}
+	if err := checkRevision(name); err != nil {
+		return nil, err
+	}
+	if !p.consume("::") {
+		return &Set{noWalk: true, include: []string{name}}, nil
+	}
+	p.skipSpace()
+	if p.pos == len(p.s) || p.s[p.pos] == ')' {
+		// Open-ended range: all descendants.
+		return &Set{
+			ancestryPath: name,
+			include:      []string{"--branches"},
+			exclude:      []string{name + "^@"},
+		}, nil
+	}
+	to, err := p.revision()
+	if err != nil {
+		return nil, err
+	}
+	return &Set{
+		ancestryPath: name,
+		include:      []string{to},
+		exclude:      []string{name + "^@"},
+	}, nil
+}
+
+func (p *parser) call(name string) (*Set, error) {
+	var set *Set
+	switch name {
+	case "ancestors":
+		arg, err := p.expr()
+		if err != nil {
+			return nil, err
+		}
+		if !arg.noWalk {
+			return nil, fmt.Errorf("ancestors() argument must be a revision or heads()")
+		}
+		set = &Set{include: arg.include}
+	case "author":
+		pattern, err := p.pattern()
+		if err != nil {
+			return nil, err
+		}
+		set = &Set{author: pattern, include: []string{"--branches"}}
+	case "draft":
+		set = &Set{include: []string{"--branches"}, exclude: []string{"--remotes"}}
+	case "heads":
+		set = &Set{noWalk: true, include: []string{"--branches"}}
+	default:
+		return nil, fmt.Errorf("unknown function %s()", name)
+	}
+	p.skipSpace()
+	if !p.consume(")") {
+		return nil, p.unexpected("')'")
+	}
+	return set, nil
+}
+
+// revision parses a plain revision operand.
+func (p *parser) revision() (string, error) {
+	p.skipSpace()
+	rev := p.word()
+	if rev == "" {
+		return "", p.unexpected("revision")
+	}
+	if strings.HasPrefix(p.s[p.pos:], "(") {
+		return "", fmt.Errorf("range endpoints must be revisions, not %s()", rev)
+	}
+	if err := checkRevision(rev); err != nil {
+		return "", err
+	}
+	return rev, nil
+}
+
+// pattern parses a function's string argument: either a quoted string or
+// everything up to the closing parenthesis.
+func (p *parser) pattern() (string, error) {
+	p.skipSpace()
+	if p.pos < len(p.s) && (p.s[p.pos] == '"' || p.s[p.pos] == '\'') {
+		quote := p.s[p.pos]
+		end := strings.IndexByte(p.s[p.pos+1:], quote)
+		if end == -1 {
+			return "", fmt.Errorf("missing closing %c", quote)
+		}
+		s := p.s[p.pos+1 : p.pos+1+end]
+		p.pos += end + 2
+		if s == "" {
+			return "", fmt.Errorf("empty pattern")
+		}
+		return s, nil
+	}
+	end := strings.IndexByte(p.s[p.pos:], ')')
+	if end == -1 {
+		end = len(p.s) - p.pos
+	}
+	s := strings.TrimSpace(p.s[p.pos : p.pos+end])
+	p.pos += end
+	if s == "" {
+		return "", fmt.Errorf("empty pattern")
+	}
+	return s, nil
+}
+
+// word consumes a function name or revision. Revisions end at
+// whitespace, a parenthesis, a comma, or "::".
+func (p *parser) word() string {
+	start := p.pos
+	for p.pos < len(p.s) {
+		c := rune(p.s[p.pos])
+		if unicode.IsSpace(c) || c == '(' || c == ')' || c == ',' || strings.HasPrefix(p.s[p.pos:], "::") {
+			break
+		}
+		p.pos++
+	}
+	return p.s[start:p.pos]
+}
+
+func (p *parser) skipSpace() {
+	for p.pos < len(p.s) && unicode.IsSpace(rune(p.s[p.pos])) {
+		p.pos++
+	}
+}
+
+func (p *parser) consume(tok string) bool {
+	if !strings.HasPrefix(p.s[p.pos:], tok) {
+		return false
+	}
+	p.pos += len(tok)
+	return true
+}
+
+func (p *parser) unexpected(want string) error {
+	if p.pos == len(p.s) {
+		return fmt.Errorf("expected %s, found end of expression", want)
+	}
+	return fmt.Errorf("expected %s, found %q", want, p.s[p.pos:])
+}
+
+func checkRevision(rev string) error {
+	if strings.HasPrefix(rev, "-") {
+		return fmt.Errorf("revision %q must not start with '-'", rev)
+	}
+	return nil
+}
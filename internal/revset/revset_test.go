@@ -0,0 +1,106 @@
+// Copyright 2021 The gg Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package revset
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestIsExpression(t *testing.T) {
+	tests := []struct {
+		s    string
+		want bool
+	}{
+		{"main", false},
+		{"HEAD~2..HEAD", false},
+		{"main@{upstream}", false},
+		{":/fix (bug)", false},
+		{":/a::b", false},
+		{"A::B", true},
+		{"::B", true},
+		{"A::", true},
+		{"heads()", true},
+		{" draft() ", true},
+		{"author(alice)", true},
+		{"foo(bar)", false},
+	}
+	for _, test := range tests {
+		if got := IsExpression(test.s); got != test.want {
+			t.Errorf("IsExpression(%q) = %t; want %t", test.s, got, test.want)
+		}
+	}
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		expr    string
+		want    []string
+		wantErr bool
+	}{
+		{expr: "main", want: []string{"--no-walk", "main"}},
+		{expr: "A::B", want: []string{"--ancestry-path=A", "B", "--not", "A^@"}},
+		{expr: " A :: B ", want: []string{"--ancestry-path=A", "B", "--not", "A^@"}},
+		{expr: "A::", want: []string{"--ancestry-path=A", "--branches", "--not", "A^@"}},
+		{expr: "::B", want: []string{"B"}},
+		{expr: "ancestors(main)", want: []string{"main"}},
+		{expr: "ancestors(heads())", want: []string{"--branches"}},
+		{expr: "heads()", want: []string{"--no-walk", "--branches"}},
+		{expr: "draft()", want: []string{"--branches", "--not", "--remotes"}},
+		{expr: "author(alice)", want: []string{"--author=alice", "--branches"}},
+		{expr: "author(Alice Smith)", want: []string{"--author=Alice Smith", "--branches"}},
+		{expr: `author("a(b)")`, want: []string{"--author=a(b)", "--branches"}},
+		{expr: "", wantErr: true},
+		{expr: "::", wantErr: true},
+		{expr: "-A::B", wantErr: true},
+		{expr: "A::-B", wantErr: true},
+		{expr: "heads()::B", wantErr: true},
+		{expr: "A::heads()", wantErr: true},
+		{expr: "ancestors(A::B)", wantErr: true},
+		{expr: "heads(", wantErr: true},
+		{expr: "heads(x)", wantErr: true},
+		{expr: "author()", wantErr: true},
+		{expr: `author("alice)`, wantErr: true},
+		{expr: "foo()", wantErr: true},
+		{expr: "draft() main", wantErr: true},
+	}
+	for _, test := range tests {
+		set, err := Parse(test.expr)
+		if err != nil {
+			if !test.wantErr {
+				t.Errorf("Parse(%q) = _, %v; want %q, <nil>", test.expr, err, test.want)
+			}
+			continue
+		}
+		if test.wantErr {
+			t.Errorf("Parse(%q) = %q, <nil>; want error", test.expr, set.Args())
+			continue
+		}
+		if diff := cmp.Diff(test.want, set.Args()); diff != "" {
+			t.Errorf("Parse(%q).Args() (-want +got):\n%s", test.expr, diff)
+		}
+		wantAncestryPath := false
+		for _, arg := range test.want {
+			if strings.HasPrefix(arg, "--ancestry-path=") {
+				wantAncestryPath = true
+			}
+		}
+		if got := set.UsesAncestryPathCommit(); got != wantAncestryPath {
+			t.Errorf("Parse(%q).UsesAncestryPathCommit() = %t; want %t", test.expr, got, wantAncestryPath)
+		}
+	}
+}
@@ -0,0 +1,293 @@
+// Copyright 2021 The gg Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package template implements a small Mercurial-style template language.
+//
+// A template is literal text interspersed with expressions in braces.
+// An expression names a keyword followed by zero or more filters,
+// like "{node|short}" or "{desc|firstline|upper}". Backslash escapes
+// "\n", "\t", "\\", "\{", and "\}" are recognized in literal text.
+package template
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"gg-scm.io/tool/internal/dateformat"
+)
+
+// Template is a parsed template.
+type Template struct {
+	parts []part
+}
+
+type part struct {
+	literal string
+	keyword string // if empty, then part is a literal
+	filters []string
+}
+
+// Parse parses a template.
+func Parse(s string) (*Template, error) {
+	t := new(Template)
+	sb := new(strings.Builder)
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; c {
+		case '\\':
+			if i+1 >= len(s) {
+				return nil, fmt.Errorf("parse template: trailing backslash")
+			}
+			i++
+			switch s[i] {
+			case 'n':
+				sb.WriteByte('\n')
+			case 't':
+				sb.WriteByte('\t')
+			case '\\', '{', '}':
+				sb.WriteByte(s[i])
+			default:
+				return nil, fmt.Errorf("parse template: unknown escape \\%c", s[i])
+			}
+		case '{':
+			end := strings.IndexByte(s[i+1:], '}')
+			if end == -1 {
+				return nil, fmt.Errorf("parse template: unterminated %q", s[i:])
+			}
+			p, err := parseExpr(s[i+1 : i+1+end])
+			if err != nil {
+				return nil, fmt.Errorf("parse template: %w", err)
+			}
+			if sb.Len() > 0 {
+				t.parts = append(t.parts, part{literal: sb.String()})
+				sb.Reset()
+			}
+			t.parts = append(t.parts, p)
+			i += 1 + end
+		default:
+			sb.WriteByte(c)
+		}
+	}
+	if sb.Len() > 0 {
+		t.parts = append(t.parts, part{literal: sb.String()})
+	}
+	return t, nil
+}
+
+func parseExpr(expr string) (part, error) {
+	fields := strings.Split(expr, "|")
+	p := part{keyword: strings.TrimSpace(fields[0])}
+	if !isIdent(p.keyword) {
+		return part{}, fmt.Errorf("invalid keyword %q", p.keyword)
+	}
+	for _, name := range fields[1:] {
+		name = strings.TrimSpace(name)
+		if _, known := filters[name]; !known {
+			return part{}, fmt.Errorf("unknown filter %q", name)
+		}
+		p.filters = append(p.filters, name)
+	}
+	return p, nil
+}
+
+func isIdent(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, c := range s {
+		if !('a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || '0' <= c && c <= '9' || c == '_') {
+			return false
+		}
+	}
+	return true
+}
+
+// Context supplies the values for a template's keywords.
+type Context struct {
+	// Keywords maps keyword names to values.
+	// Values must be strings, time.Time, or []string.
+	Keywords map[string]interface{}
+
+	// Now is the reference point for relative dates.
+	Now time.Time
+	// DateStyle is the style used to display dates
+	// that have not been formatted by a filter.
+	DateStyle dateformat.Style
+}
+
+// Execute writes the template expanded with the given keywords to w.
+func (t *Template) Execute(w io.Writer, ctx *Context) error {
+	sb := new(strings.Builder)
+	for _, p := range t.parts {
+		if p.keyword == "" {
+			sb.WriteString(p.literal)
+			continue
+		}
+		v, ok := ctx.Keywords[p.keyword]
+		if !ok {
+			return fmt.Errorf("template: unknown keyword %q", p.keyword)
+		}
+		for _, name := range p.filters {
+			var err error
+			v, err = applyFilter(ctx, name, v)
+			if err != nil {
+				return fmt.Errorf("template: {%s}: %w", p.keyword, err)
+			}
+		}
+		switch v := v.(type) {
+		case string:
+			sb.WriteString(v)
+		case time.Time:
+			sb.WriteString(ctx.DateStyle.Format(v, ctx.Now))
+		case []string:
+			sb.WriteString(strings.Join(v, " "))
+		default:
+			return fmt.Errorf("template: {%s}: unsupported type %T", p.keyword, v)
+		}
+	}
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
+
+type filter struct {
+	str  func(string) string
+	date func(*Context, time.Time) interface{}
+	list func([]string) interface{}
+}
+
+var filters = map[string]filter{
+	"short":     {str: shortHash},
+	"firstline": {str: firstLine},
+	"strip":     {str: strings.TrimSpace},
+	"upper":     {str: strings.ToUpper},
+	"lower":     {str: strings.ToLower},
+	"email":     {str: email},
+	"person":    {str: person},
+	"user":      {str: user},
+	"tabindent": {str: tabIndent},
+
+	"age":         {date: func(ctx *Context, t time.Time) interface{} { return dateformat.Relative.Format(t, ctx.Now) }},
+	"date":        {date: func(ctx *Context, t time.Time) interface{} { return dateformat.Default.Format(t, ctx.Now) }},
+	"isodate":     {date: func(ctx *Context, t time.Time) interface{} { return t.Format("2006-01-02 15:04 -0700") }},
+	"isodatesec":  {date: func(ctx *Context, t time.Time) interface{} { return t.Format("2006-01-02 15:04:05 -0700") }},
+	"localdate":   {date: func(ctx *Context, t time.Time) interface{} { return t.Local() }},
+	"rfc3339date": {date: func(ctx *Context, t time.Time) interface{} { return t.Format(time.RFC3339) }},
+	"rfc822date":  {date: func(ctx *Context, t time.Time) interface{} { return t.Format(time.RFC1123Z) }},
+	"shortdate":   {date: func(ctx *Context, t time.Time) interface{} { return t.Format("2006-01-02") }},
+	"unixtime":    {date: func(ctx *Context, t time.Time) interface{} { return strconv.FormatInt(t.Unix(), 10) }},
+
+	"count": {list: func(list []string) interface{} { return strconv.Itoa(len(list)) }},
+	"join":  {list: func(list []string) interface{} { return strings.Join(list, ", ") }},
+}
+
+func applyFilter(ctx *Context, name string, v interface{}) (interface{}, error) {
+	f := filters[name]
+	switch v := v.(type) {
+	case string:
+		if f.str != nil {
+			return f.str(v), nil
+		}
+	case time.Time:
+		if f.date != nil {
+			return f.date(ctx, v), nil
+		}
+	case []string:
+		if f.list != nil {
+			return f.list(v), nil
+		}
+		if f.str != nil {
+			// String filters apply to each element of a list.
+			newList := make([]string, len(v))
+			for i := range v {
+				newList[i] = f.str(v[i])
+			}
+			return newList, nil
+		}
+	}
+	return nil, fmt.Errorf("filter %q cannot be applied to %s", name, typeName(v))
+}
+
+func typeName(v interface{}) string {
+	switch v.(type) {
+	case string:
+		return "text"
+	case time.Time:
+		return "a date"
+	case []string:
+		return "a list"
+	default:
+		return fmt.Sprintf("%T", v)
+	}
+}
+
+func shortHash(s string) string {
+	const n = 12
+	if len(s) <= n {
+		return s
+	}
+	return s[:n]
+}
+
+func firstLine(s string) string {
+	if i := strings.IndexByte(s, '\n'); i != -1 {
+		return s[:i]
+	}
+	return s
+}
+
+// email returns the email address in a "Name <email>" string.
+func email(s string) string {
+	start := strings.LastIndexByte(s, '<')
+	if start == -1 {
+		return s
+	}
+	end := strings.IndexByte(s[start:], '>')
+	if end == -1 {
+		return s[start+1:]
+	}
+	return s[start+1 : start+end]
+}
+
+// person returns the name in a "Name <email>" string.
+func person(s string) string {
+	i := strings.LastIndexByte(s, '<')
+	if i == -1 {
+		return s
+	}
+	if name := strings.TrimSpace(s[:i]); name != "" {
+		return name
+	}
+	return user(s)
+}
+
+// user returns the part of an email address before the "@".
+func user(s string) string {
+	addr := email(s)
+	if i := strings.IndexByte(addr, '@'); i != -1 {
+		return addr[:i]
+	}
+	return addr
+}
+
+func tabIndent(s string) string {
+	lines := strings.Split(s, "\n")
+	for i := 1; i < len(lines); i++ {
+		if lines[i] != "" {
+			lines[i] = "\t" + lines[i]
+		}
+	}
+	return strings.Join(lines, "\n")
+}
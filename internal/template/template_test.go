@@ -0,0 +1,108 @@
+// Copyright 2021 The gg Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package template
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTemplate(t *testing.T) {
+	now := time.Date(2021, time.March, 4, 12, 0, 0, 0, time.UTC)
+	ctx := &Context{
+		Keywords: map[string]interface{}{
+			"node":    "7b5bc9e5f3e4b1d8bb9e0f6f2f0e48b9b5e1a2c3",
+			"desc":    "Fix the frobnicator\n\nIt was broken.\n",
+			"author":  "Octo Cat <octocat@example.com>",
+			"date":    time.Date(2021, time.March, 1, 12, 0, 0, 0, time.UTC),
+			"parents": []string{"1111111111111111111111111111111111111111", "2222222222222222222222222222222222222222"},
+			"tags":    []string{},
+		},
+		Now: now,
+	}
+	tests := []struct {
+		template string
+		want     string
+	}{
+		{template: "", want: ""},
+		{template: "hello", want: "hello"},
+		{template: `{node|short} {desc|firstline}\n`, want: "7b5bc9e5f3e4 Fix the frobnicator\n"},
+		{template: "{ node | short }", want: "7b5bc9e5f3e4"},
+		{template: "{desc|firstline|upper}", want: "FIX THE FROBNICATOR"},
+		{template: "{author|person} <{author|email}> ({author|user})", want: "Octo Cat <octocat@example.com> (octocat)"},
+		{template: "{date|shortdate}", want: "2021-03-01"},
+		{template: "{date|isodate}", want: "2021-03-01 12:00 +0000"},
+		{template: "{date|age}", want: "3 days ago"},
+		{template: "{date}", want: "Mon Mar 01 12:00:00 2021 +0000"},
+		{template: "{parents|short}", want: "111111111111 222222222222"},
+		{template: "{parents|count}", want: "2"},
+		{template: "[{tags}]", want: "[]"},
+		{template: `\{node\}\t\\`, want: "{node}\t\\"},
+	}
+	for _, test := range tests {
+		tmpl, err := Parse(test.template)
+		if err != nil {
+			t.Errorf("Parse(%q): %v", test.template, err)
+			continue
+		}
+		sb := new(strings.Builder)
+		if err := tmpl.Execute(sb, ctx); err != nil {
+			t.Errorf("Parse(%q).Execute(...): %v", test.template, err)
+			continue
+		}
+		if got := sb.String(); got != test.want {
+			t.Errorf("Parse(%q).Execute(...) = %q; want %q", test.template, got, test.want)
+		}
+	}
+}
+
+func TestTemplateErrors(t *testing.T) {
+	parseErrors := []string{
+		"{node",
+		"{node|bogus}",
+		"{}",
+		"{two words}",
+		`trailing\`,
+		`\q`,
+	}
+	for _, s := range parseErrors {
+		if _, err := Parse(s); err == nil {
+			t.Errorf("Parse(%q) did not return an error", s)
+		}
+	}
+
+	ctx := &Context{
+		Keywords: map[string]interface{}{
+			"node": "abc",
+			"date": time.Unix(0, 0),
+		},
+	}
+	executeErrors := []string{
+		"{missing}",
+		"{node|shortdate}",
+		"{date|short}",
+	}
+	for _, s := range executeErrors {
+		tmpl, err := Parse(s)
+		if err != nil {
+			t.Errorf("Parse(%q): %v", s, err)
+			continue
+		}
+		if err := tmpl.Execute(new(strings.Builder), ctx); err == nil {
+			t.Errorf("Parse(%q).Execute(...) did not return an error", s)
+		}
+	}
+}
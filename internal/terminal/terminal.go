@@ -19,6 +19,8 @@ package terminal
 import (
 	"io"
 	"os"
+	"strconv"
+	"strings"
 )
 
 // IsTerminal reports whether w writes directly to a terminal.
@@ -36,3 +38,54 @@ func ResetTextStyle(w io.Writer) error {
 	_, err := w.Write([]byte("\x1b[m"))
 	return err
 }
+
+// SupportsHyperlinks reports whether w writes directly to a terminal
+// that is known to display OSC 8 hyperlinks. environ is the list of
+// environment variables of the process, as returned by os.Environ.
+func SupportsHyperlinks(w io.Writer, environ []string) bool {
+	if !IsTerminal(w) {
+		return false
+	}
+	switch getenv(environ, "FORCE_HYPERLINK") {
+	case "0":
+		return false
+	case "":
+	default:
+		return true
+	}
+	if getenv(environ, "TERM") == "dumb" {
+		return false
+	}
+	if getenv(environ, "WT_SESSION") != "" ||
+		getenv(environ, "KITTY_WINDOW_ID") != "" ||
+		getenv(environ, "KONSOLE_VERSION") != "" ||
+		getenv(environ, "DOMTERM") != "" {
+		return true
+	}
+	switch getenv(environ, "TERM_PROGRAM") {
+	case "iTerm.app", "WezTerm", "vscode", "ghostty":
+		return true
+	}
+	// VTE-based terminals (GNOME Terminal and friends) added support in 0.50.
+	if v, err := strconv.Atoi(getenv(environ, "VTE_VERSION")); err == nil && v >= 5000 {
+		return true
+	}
+	return false
+}
+
+// getenv returns the value of the last entry for name in environ.
+func getenv(environ []string, name string) string {
+	for i := len(environ) - 1; i >= 0; i-- {
+		if e := environ[i]; strings.HasPrefix(e, name+"=") {
+			return e[len(name)+1:]
+		}
+	}
+	return ""
+}
+
+// Hyperlink returns text wrapped in an OSC 8 escape sequence that makes
+// it a link to the given URL in terminals that support it. Other
+// terminals display just the text.
+func Hyperlink(url, text string) string {
+	return "\x1b]8;;" + url + "\x1b\\" + text + "\x1b]8;;\x1b\\"
+}
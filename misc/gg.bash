@@ -35,9 +35,13 @@ _gg_complete() {
 
   if [[ $COMP_CWORD -eq $subcmd_idx && "$curr_word" != -* ]]; then
     local commands=( \
+      absorb \
       add \
       addremove \
+      amend \
+      annotate \
       backout \
+      blame \
       branch \
       check \
       checkout \
@@ -46,18 +50,26 @@ _gg_complete() {
       co \
       commit \
       diff \
+      doctor \
       evolve \
+      export \
+      fold \
       gerrithook \
       github-login \
       histedit \
       history \
       id \
       identify \
+      import \
       init \
+      land \
       log \
       mail \
       merge \
+      migrate-default-branch \
       pr \
+      precommit \
+      prune-refs \
       pull \
       push \
       rebase \
@@ -65,11 +77,20 @@ _gg_complete() {
       rm \
       requestpull \
       revert \
+      reviewed-by \
+      shelve \
+      show \
+      squash \
       st \
+      stats \
+      tested-by \
       status \
+      uncommit \
+      unshelve \
       up \
       update \
       upstream \
+      watch \
     )
     COMPREPLY=( $(compgen -W "${commands[*]}" -- "$curr_word") )
     return 0
@@ -86,12 +107,28 @@ _gg_complete() {
   if [[ "$curr_word" == -* ]]; then
     # An option.
     case "$subcmd" in
+      absorb)
+        COMPREPLY=( $(compgen -W '-n -dry-run --dry-run' -- "$curr_word") )
+        return 0
+        ;;
+      add)
+        COMPREPLY=( $(compgen -W '-i -interactive --interactive' -- "$curr_word") )
+        return 0
+        ;;
+      amend)
+        COMPREPLY=( $(compgen -W '-author --author -coauthor --coauthor -date --date -e -edit --edit -m -no-edit --no-edit -S -sign --sign -to --to' -- "$curr_word") )
+        return 0
+        ;;
+      annotate|blame)
+        COMPREPLY=( $(compgen -W '-ignore-rev --ignore-rev -no-ignore-revs --no-ignore-revs -r' -- "$curr_word") )
+        return 0
+        ;;
       backout)
         COMPREPLY=( $(compgen -W '-e -edit --edit -n -no-commit --no-commit -r' -- "$curr_word") )
         return 0
         ;;
       branch)
-        COMPREPLY=( $(compgen -W '-d -delete --delete -f -force --force -r -sort --sort' -- "$curr_word") )
+        COMPREPLY=( $(compgen -W '-d -date --date -delete --delete -edit-description --edit-description -f -force --force -r -sort --sort -verify --verify' -- "$curr_word") )
         return 0
         ;;
       clone)
@@ -99,7 +136,7 @@ _gg_complete() {
         return 0
         ;;
       ci|commit)
-        COMPREPLY=( $(compgen -W '-amend --amend -m' -- "$curr_word") )
+        COMPREPLY=( $(compgen -W '-amend --amend -coauthor --coauthor -exclude --exclude -fixup --fixup -m -no-check --no-check -only --only -S -sign --sign -squash --squash' -- "$curr_word") )
         return 0
         ;;
       diff)
@@ -110,20 +147,36 @@ _gg_complete() {
         COMPREPLY=( $(compgen -W '-d -dst --dst -l -list --list' -- "$curr_word") )
         return 0
         ;;
+      export)
+        COMPREPLY=( $(compgen -W '-base --base -o -output --output -review --review' -- "$curr_word") )
+        return 0
+        ;;
+      fold|squash)
+        COMPREPLY=( $(compgen -W '-from --from -r -m' -- "$curr_word") )
+        return 0
+        ;;
       gerrithook)
         COMPREPLY=( $(compgen -W '-url --url -cached --cached' -- "$curr_word") )
         return 0
         ;;
       histedit)
-        COMPREPLY=( $(compgen -W '-abort --abort -continue --continue -edit-plan --edit-plan -exec --exec' -- "$curr_word") )
+        COMPREPLY=( $(compgen -W '-abort --abort -continue --continue -edit-plan --edit-plan -edit-todo --edit-todo -exec --exec -autosquash --autosquash -no-autosquash --no-autosquash -S -sign --sign' -- "$curr_word") )
         return 0
         ;;
       id|identify)
         COMPREPLY=( $(compgen -W '-r' -- "$curr_word") )
         return 0
         ;;
+      import)
+        COMPREPLY=( $(compgen -W '-b -branch --branch -review --review' -- "$curr_word") )
+        return 0
+        ;;
+      land)
+        COMPREPLY=( $(compgen -W '-force --force -method --method' -- "$curr_word") )
+        return 0
+        ;;
       log|history)
-        COMPREPLY=( $(compgen -W '-follow --follow -follow-first --follow-first -G -graph --graph -r -reverse --reverse -stat --stat' -- "$curr_word") )
+        COMPREPLY=( $(compgen -W '-date --date -diff-regex --diff-regex -first-parent --first-parent -follow --follow -follow-first --follow-first -forge-links --forge-links -G -graph --graph -merges --merges -no-merges --no-merges -r -reverse --reverse -S -show-signature --show-signature -stat --stat -T -template --template' -- "$curr_word") )
         return 0
         ;;
       mail)
@@ -139,11 +192,11 @@ _gg_complete() {
         return 0
         ;;
       push)
-        COMPREPLY=( $(compgen -W '-f -force --force -new-branch --new-branch -r' -- "$curr_word") )
+        COMPREPLY=( $(compgen -W '-delete --delete -f -force --force -new-branch --new-branch -preview --preview -r' -- "$curr_word") )
         return 0
         ;;
       rebase)
-        COMPREPLY=( $(compgen -W '-base --base -dst --dst -src --src -abort --abort -continue --continue' -- "$curr_word") )
+        COMPREPLY=( $(compgen -W '-base --base -dst --dst -src --src -onto --onto -preview --preview -abort --abort -continue --continue -autosquash --autosquash -no-autosquash --no-autosquash -S -sign --sign' -- "$curr_word") )
         return 0
         ;;
       remove|rm)
@@ -151,21 +204,57 @@ _gg_complete() {
         return 0
         ;;
       requestpull|pr)
-        COMPREPLY=( $(compgen -W '-body --body -draft --draft -e -edit --edit -n -dry-run --dry-run -maintainer-edits --maintainer-edits -R -reviewer --reviewer -title --title' -- "$curr_word") )
+        COMPREPLY=( $(compgen -W '-base --base -body --body -draft --draft -e -edit --edit -n -dry-run --dry-run -maintainer-edits --maintainer-edits -R -ready --ready -reviewer --reviewer -title --title' -- "$curr_word") )
         return 0
         ;;
       revert)
         COMPREPLY=( $(compgen -W '-all --all -C -no-backup --no-backup -r' -- "$curr_word") )
         return 0
         ;;
+      shelve)
+        COMPREPLY=( $(compgen -W '-d -delete --delete -l -list --list -n -name --name -u -unknown --unknown' -- "$curr_word") )
+        return 0
+        ;;
+      show)
+        COMPREPLY=( $(compgen -W '-r -show-signature --show-signature -stat --stat -T -template --template' -- "$curr_word") )
+        return 0
+        ;;
+      stats)
+        COMPREPLY=( $(compgen -W '-top --top' -- "$curr_word") )
+        return 0
+        ;;
+      st|status|check)
+        COMPREPLY=( $(compgen -W '-v -verbose --verbose' -- "$curr_word") )
+        return 0
+        ;;
+      unshelve)
+        COMPREPLY=( $(compgen -W '-k -keep --keep' -- "$curr_word") )
+        return 0
+        ;;
       update|checkout|co|up)
         COMPREPLY=( $(compgen -W '-r -clean --clean -C' -- "$curr_word") )
         return 0
         ;;
+      migrate-default-branch)
+        COMPREPLY=( $(compgen -W '-n --dry-run -from --from' -- "$curr_word") )
+        return 0
+        ;;
+      prune-refs)
+        COMPREPLY=( $(compgen -W '-apply --apply -shelf-age --shelf-age' -- "$curr_word") )
+        return 0
+        ;;
+      reviewed-by|tested-by)
+        COMPREPLY=( $(compgen -W '-r' -- "$curr_word") )
+        return 0
+        ;;
       upstream)
         COMPREPLY=( $(compgen -W '-b' -- "$curr_word") )
         return 0
         ;;
+      watch)
+        COMPREPLY=( $(compgen -W '-interval --interval -check --check -once --once' -- "$curr_word") )
+        return 0
+        ;;
       *)
         COMPREPLY=()
         return 0
@@ -174,24 +263,28 @@ _gg_complete() {
   else
     # A positional argument.
     case "$subcmd" in
-      add|addremove|check|clone|evolve|init|remove|rm|st|status)
+      add|addremove|annotate|blame|check|clone|evolve|import|init|remove|rm|show|st|status|uncommit)
         # Commands that only deal with files.
         compopt -o nospace -o filenames
         COMPREPLY=( $(compgen -f -- "$curr_word") )
         return 0
         ;;
-      backout|branch|checkout|co|histedit|id|identify|merge|rebase|up|update|upstream)
+      absorb|backout|branch|checkout|co|export|histedit|id|identify|land|merge|rebase|up|update|upstream)
         # Commands that only deal with revisions.
         COMPREPLY=( $(compgen -W "$(named_revs)" -- "$curr_word") )
         return 0
         ;;
-      ci|commit)
+      amend|ci|commit)
         case "$prev_word" in
           -m)
             # Don't complete for message.
             COMPREPLY=()
             return 0
             ;;
+          -to|--to|-fixup|--fixup|-squash|--squash)
+            COMPREPLY=( $(compgen -W "$(named_revs)" -- "$curr_word") )
+            return 0
+            ;;
           *)
             compopt -o nospace -o filenames
             COMPREPLY=( $(compgen -f -- "$curr_word") )
@@ -254,13 +347,13 @@ _gg_complete() {
             ;;
         esac
         ;;
-      pull)
+      migrate-default-branch|prune-refs|pull)
         COMPREPLY=( $(compgen -W "$(git remote)" -- "$curr_word") )
         return 0
         ;;
       push)
         case "$prev_word" in
-          -r)
+          -r|-delete|--delete)
             COMPREPLY=( $(compgen -W "$(named_revs)" -- "$curr_word") )
             return 0
             ;;
@@ -271,7 +364,7 @@ _gg_complete() {
         esac
         ;;
       requestpull|pr)
-        COMPREPLY=( $(compgen -W "$(named_revs)" -- "$curr_word") )
+        COMPREPLY=( $(compgen -W "status sync $(named_revs)" -- "$curr_word") )
         return 0
         ;;
       revert)